@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+)
+
+// BandCapacity is the wavelength usage of one band of a fiber domain
+type BandCapacity struct {
+	Band      string `json:"band"`
+	MinNm     int    `json:"min_nm"`
+	MaxNm     int    `json:"max_nm"` // inclusive
+	Total     int    `json:"total"`
+	InUse     int    `json:"in_use"`
+	Available int    `json:"available"`
+}
+
+// DomainCapacity is the wavelength usage of one fiber domain
+type DomainCapacity struct {
+	Domain    string         `json:"domain"`
+	Bands     []BandCapacity `json:"bands"`
+	Available int            `json:"available_wavelengths"`
+}
+
+// Capacity is the headroom schedulers place corridors against. Domains
+// lists the default domain and every domain holding wavelengths; any other
+// domain is entirely free. Wavelengths and bandwidth held by uncommitted
+// reservations count as in use.
+type Capacity struct {
+	Domains             []DomainCapacity `json:"domains"`
+	CommittedGbps       int              `json:"committed_gbps"` // sum of min_gbps
+	BandwidthBudgetGbps float64          `json:"bandwidth_budget_gbps,omitempty"`
+	RemainingGbps       *float64         `json:"remaining_gbps,omitempty"` // with a budget
+}
+
+// Capacity reports the free wavelengths per band of each fiber domain and
+// the bandwidth left under the budget
+func (s *CorridorService) Capacity() Capacity {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := []string{defaultDomain}
+	for domain := range s.lambdas {
+		if domain != defaultDomain {
+			names = append(names, domain)
+		}
+	}
+	sort.Strings(names)
+
+	capacity := Capacity{Domains: make([]DomainCapacity, 0, len(names)), BandwidthBudgetGbps: s.BandwidthBudgetGbps}
+	for _, domain := range names {
+		dc := DomainCapacity{Domain: domain, Bands: make([]BandCapacity, 0, len(bands))}
+		for _, b := range bands {
+			bc := BandCapacity{Band: b.Name, MinNm: b.MinNm, MaxNm: b.MaxNm - 1, Total: b.MaxNm - b.MinNm}
+			for nm := range s.lambdas[domain] {
+				if nm >= b.MinNm && nm < b.MaxNm {
+					bc.InUse++
+				}
+			}
+			bc.Available = bc.Total - bc.InUse
+			dc.Available += bc.Available
+			dc.Bands = append(dc.Bands, bc)
+		}
+		capacity.Domains = append(capacity.Domains, dc)
+	}
+
+	for _, state := range s.corridors.Values() {
+		capacity.CommittedGbps += state.corridor.MinGbps
+	}
+	s.reservations.Each(func(state *corridorState) {
+		capacity.CommittedGbps += state.corridor.MinGbps
+	})
+	if s.BandwidthBudgetGbps > 0 {
+		remaining := max(s.BandwidthBudgetGbps-float64(capacity.CommittedGbps), 0)
+		capacity.RemainingGbps = &remaining
+	}
+	return capacity
+}
+
+func (s *CorridorService) handleCapacity(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Capacity())
+}
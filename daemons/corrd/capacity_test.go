@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// bandOf returns a domain's capacity in the named band
+func bandOf(t *testing.T, c Capacity, domain, name string) BandCapacity {
+	t.Helper()
+	for _, d := range c.Domains {
+		if d.Domain != domain {
+			continue
+		}
+		for _, b := range d.Bands {
+			if b.Band == name {
+				return b
+			}
+		}
+	}
+	t.Fatalf("no band %s in domain %s: %+v", name, domain, c.Domains)
+	return BandCapacity{}
+}
+
+func TestCapacityTracksAllocations(t *testing.T) {
+	s := NewCorridorService()
+	s.BandwidthBudgetGbps = 1000
+	srv := httptest.NewServer(newRouter(s))
+	defer srv.Close()
+
+	var before Capacity
+	if code := do(t, srv, "GET", "/v1/corridors/capacity", nil, &before); code != http.StatusOK {
+		t.Fatalf("capacity status = %d", code)
+	}
+	c := bandOf(t, before, defaultDomain, "C")
+	if c.Total != 35 || c.InUse != 0 || c.Available != 35 {
+		t.Errorf("C band before allocation = %+v", c)
+	}
+	if before.RemainingGbps == nil || *before.RemainingGbps != 1000 || before.CommittedGbps != 0 {
+		t.Errorf("bandwidth before allocation = %d committed, %v remaining", before.CommittedGbps, before.RemainingGbps)
+	}
+
+	var corridor Corridor
+	if code := do(t, srv, "POST", "/v1/corridors", allocateRequest(), &corridor); code != http.StatusCreated {
+		t.Fatalf("allocate status = %d", code)
+	}
+	var during Capacity
+	do(t, srv, "GET", "/v1/corridors/capacity", nil, &during)
+	if c := bandOf(t, during, defaultDomain, "C"); c.InUse != 4 || c.Available != 31 {
+		t.Errorf("C band after allocation = %+v", c)
+	}
+	if during.CommittedGbps != 150 || *during.RemainingGbps != 850 {
+		t.Errorf("bandwidth after allocation = %d committed, %v remaining", during.CommittedGbps, *during.RemainingGbps)
+	}
+
+	do(t, srv, "DELETE", "/v1/corridors/"+corridor.ID, nil, nil)
+	var after Capacity
+	do(t, srv, "GET", "/v1/corridors/capacity", nil, &after)
+	if c := bandOf(t, after, defaultDomain, "C"); c != bandOf(t, before, defaultDomain, "C") {
+		t.Errorf("C band after free = %+v, want %+v", c, bandOf(t, before, defaultDomain, "C"))
+	}
+	if after.CommittedGbps != 0 || *after.RemainingGbps != 1000 {
+		t.Errorf("bandwidth after free = %d committed, %v remaining", after.CommittedGbps, *after.RemainingGbps)
+	}
+}
+
+func TestCapacityCountsReservations(t *testing.T) {
+	s := NewCorridorService()
+	if _, err := s.Reserve(ReserveRequest{AllocateRequest: allocateRequest()}); err != nil {
+		t.Fatal(err)
+	}
+	c := s.Capacity()
+	if b := bandOf(t, c, defaultDomain, "C"); b.InUse != 4 {
+		t.Errorf("C band with a pending reservation = %+v", b)
+	}
+	if c.CommittedGbps != 150 || c.RemainingGbps != nil {
+		t.Errorf("bandwidth with a pending reservation = %d committed, %v remaining", c.CommittedGbps, c.RemainingGbps)
+	}
+}
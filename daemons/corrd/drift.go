@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"math"
+	mrand "math/rand"
+	"time"
+)
+
+// Bounds of the simulated link drift
+const (
+	driftBERDecadesBelow = 1.0 // BER may improve to baseline/10
+	driftBERDecadesAbove = 2.0 // and worsen to baseline*100
+	driftTempMinC        = 20.0
+	driftTempMaxC        = 85.0
+	driftPowerMinPj      = 0.5
+	driftPowerMaxPj      = 2.0
+	nominalTempC         = 47.5
+)
+
+// startDrift launches the goroutine that evolves a corridor's link state
+// until the corridor is released. The caller must hold the store lock.
+func (s *CorridorService) startDrift(state *corridorState) {
+	ctx, cancel := context.WithCancel(context.Background())
+	state.stopDrift = cancel
+	go s.runDrift(ctx, state)
+}
+
+// runDrift steps the corridor's link state every DriftInterval
+func (s *CorridorService) runDrift(ctx context.Context, state *corridorState) {
+	ticker := time.NewTicker(s.DriftInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if ctx.Err() == nil {
+				state.drift()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// drift takes one bounded random-walk step of BER, temperature and power.
+// BER walks in log space with a slight aging bias towards worse values.
+// The caller must hold the store lock.
+func (state *corridorState) drift() {
+	t := &state.telemetry
+	baseline := math.Log10(state.baselineBER)
+	logBER := math.Log10(t.BER) + mrand.NormFloat64()*0.05 + 0.01
+	logBER = math.Max(baseline-driftBERDecadesBelow, math.Min(baseline+driftBERDecadesAbove, logBER))
+	t.BER = math.Pow(10, logBER)
+
+	t.TempC = math.Max(driftTempMinC, math.Min(driftTempMaxC, t.TempC+mrand.NormFloat64()*0.2))
+	t.PowerPjPerBit = math.Max(driftPowerMinPj, math.Min(driftPowerMaxPj, t.PowerPjPerBit+mrand.NormFloat64()*0.005))
+
+	switch excess := logBER - baseline; {
+	case excess > 1:
+		t.Drift = "high"
+	case excess > 0.3:
+		t.Drift = "medium"
+	default:
+		t.Drift = "low"
+	}
+}
+
+// resetDrift returns the link state to freshly calibrated values at ber.
+// The caller must hold the store lock.
+func (state *corridorState) resetDrift(ber float64) {
+	state.baselineBER = ber
+	state.telemetry.BER = ber
+	state.telemetry.TempC = nominalTempC
+	state.telemetry.PowerPjPerBit = state.baselinePower
+	state.telemetry.Drift = "low"
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDriftStaysBounded(t *testing.T) {
+	state := &corridorState{
+		baselineBER: 1e-12,
+		telemetry:   Telemetry{BER: 1e-12, TempC: nominalTempC, PowerPjPerBit: nominalPjPerBit},
+	}
+	for i := 0; i < 10000; i++ {
+		state.drift()
+		tel := state.telemetry
+		if decades := math.Log10(tel.BER / state.baselineBER); decades < -driftBERDecadesBelow-1e-9 || decades > driftBERDecadesAbove+1e-9 {
+			t.Fatalf("step %d: BER %g is %.2f decades from baseline", i, tel.BER, decades)
+		}
+		if tel.TempC < driftTempMinC || tel.TempC > driftTempMaxC || tel.PowerPjPerBit < driftPowerMinPj || tel.PowerPjPerBit > driftPowerMaxPj {
+			t.Fatalf("step %d: telemetry out of bounds: %+v", i, tel)
+		}
+	}
+
+	state.resetDrift(1e-13)
+	if state.telemetry.BER != 1e-13 || state.telemetry.Drift != "low" || state.telemetry.TempC != nominalTempC {
+		t.Errorf("after reset: %+v", state.telemetry)
+	}
+}
+
+// TestConcurrentAllocateReleaseTelemetry is meant to run under -race.
+func TestConcurrentAllocateReleaseTelemetry(t *testing.T) {
+	s := NewCorridorService()
+	s.DriftInterval = time.Millisecond
+	baseline := runtime.NumGoroutine()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				req := allocateRequest()
+				req.Domain = fmt.Sprintf("fiber-%d", w)
+				corridor, err := s.Allocate(req)
+				if err != nil {
+					t.Errorf("allocate: %v", err)
+					return
+				}
+				for j := 0; j < 3; j++ {
+					if _, err := s.Telemetry(corridor.ID); err != nil {
+						t.Errorf("telemetry: %v", err)
+					}
+					time.Sleep(time.Millisecond)
+				}
+				if err := s.Release(corridor.ID); err != nil {
+					t.Errorf("release: %v", err)
+				}
+			}
+		}(w)
+	}
+	// Poll every corridor's telemetry while the workers churn
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			for _, c := range s.List(nil) {
+				s.Telemetry(c.ID)
+			}
+		}
+	}()
+	wg.Wait()
+	<-done
+
+	if n := len(s.List(nil)); n != 0 {
+		t.Fatalf("%d corridors left after release", n)
+	}
+	// Released corridors stop their drift goroutines
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines running, %d before the test", runtime.NumGoroutine(), baseline)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
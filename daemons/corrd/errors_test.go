@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+// apiError sends a request expected to fail and decodes its error body
+func apiError(t *testing.T, srv *httptest.Server, method, path string, body any) (int, apierr.Error) {
+	t.Helper()
+	buf, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(method, srv.URL+path, bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var e apierr.Error
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		t.Fatalf("%s %s: error body: %v", method, path, err)
+	}
+	return resp.StatusCode, e
+}
+
+func TestErrorCodes(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	var corridor Corridor
+	do(t, srv, "POST", "/v1/corridors", allocateRequest(), &corridor)
+
+	invalid := allocateRequest()
+	invalid.CorridorType = "Copper"
+	infeasible := allocateRequest()
+	infeasible.LambdaNm = []int{1560, 1561, 1562, 1563}
+	infeasible.MinGbps = 1000
+
+	for _, tc := range []struct {
+		name, method, path string
+		body               any
+		code               apierr.Code
+		status             int
+	}{
+		{"malformed body", "POST", "/v1/corridors", "not a request", apierr.CodeBadRequest, http.StatusBadRequest},
+		{"invalid request", "POST", "/v1/corridors", invalid, apierr.CodeValidation, http.StatusBadRequest},
+		{"infeasible", "POST", "/v1/corridors", infeasible, apierr.CodeInfeasible, http.StatusUnprocessableEntity},
+		{"wavelength conflict", "POST", "/v1/corridors", allocateRequest(), apierr.CodeConflict, http.StatusConflict},
+		{"unknown corridor", "GET", "/v1/corridors/" + generateID("SiCorridor"), nil, apierr.CodeNotFound, http.StatusNotFound},
+		{"malformed id", "GET", "/v1/corridors/cor-missing", nil, apierr.CodeBadRequest, http.StatusBadRequest},
+		{"illegal transition", "POST", "/v1/corridors/" + corridor.ID + "/transition", TransitionRequest{Status: StatusFailed}, apierr.CodeConflict, http.StatusConflict},
+	} {
+		status, e := apiError(t, srv, tc.method, tc.path, tc.body)
+		if status != tc.status || e.Code != tc.code || e.Message == "" {
+			t.Errorf("%s: %d %+v, want %d %s", tc.name, status, e, tc.status, tc.code)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/faults"
+	"github.com/corridoros/pkg/jsonbody"
+	"github.com/gorilla/mux"
+)
+
+// FaultBER elevates the BER a corridor's telemetry reports
+const FaultBER = "ber"
+
+// newFaultRegistry accepts the shared delay and error faults plus BER faults
+func newFaultRegistry() *faults.Registry {
+	return faults.NewRegistry(map[string]faults.Validator{
+		FaultBER: func(req faults.Request) error {
+			if req.BER <= 0 || req.BER >= 1 {
+				return fmt.Errorf("ber must be in (0, 1)")
+			}
+			return nil
+		},
+	})
+}
+
+// injectFaults applies delay and error faults targeting the corridor in the
+// request path before the handler runs
+func (s *CorridorService) injectFaults(next http.Handler) http.Handler {
+	return s.faults.Middleware(func(r *http.Request) string { return mux.Vars(r)["id"] })(next)
+}
+
+func (s *CorridorService) handleAddFault(w http.ResponseWriter, r *http.Request) {
+	var req faults.Request
+	if err := jsonbody.Decode(r.Body, &req); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	fault, err := s.faults.Add(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, fault)
+}
+
+func (s *CorridorService) handleListFaults(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.faults.List())
+}
+
+func (s *CorridorService) handleClearFaults(w http.ResponseWriter, r *http.Request) {
+	s.faults.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
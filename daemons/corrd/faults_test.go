@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/faults"
+)
+
+func TestBERFaultRaisesTelemetryThenClears(t *testing.T) {
+	s := NewCorridorService()
+	s.FaultsEnabled = true
+	srv := httptest.NewServer(newRouter(s))
+	defer srv.Close()
+
+	var corridor Corridor
+	do(t, srv, "POST", "/v1/corridors", allocateRequest(), &corridor)
+	path := "/v1/corridors/" + corridor.ID + "/telemetry"
+
+	const count = 3
+	req := faults.Request{Kind: FaultBER, Target: corridor.ID, Count: count, BER: 1e-5}
+	if code := do(t, srv, "POST", "/v1/admin/faults", req, nil); code != http.StatusCreated {
+		t.Fatalf("add fault: status = %d", code)
+	}
+
+	for i := 0; i < count; i++ {
+		var telemetry Telemetry
+		do(t, srv, "GET", path, nil, &telemetry)
+		if telemetry.BER != req.BER {
+			t.Errorf("reading %d: BER = %g, want injected %g", i, telemetry.BER, req.BER)
+		}
+	}
+
+	var active []faults.Fault
+	do(t, srv, "GET", "/v1/admin/faults", nil, &active)
+	if len(active) != 0 {
+		t.Errorf("fault still active after %d requests: %+v", count, active)
+	}
+	var telemetry Telemetry
+	do(t, srv, "GET", path, nil, &telemetry)
+	if telemetry.BER >= 1e-9 {
+		t.Errorf("BER after the fault cleared = %g", telemetry.BER)
+	}
+
+	var got Corridor
+	do(t, srv, "GET", "/v1/corridors/"+corridor.ID, nil, &got)
+	if got.Status != StatusDegraded {
+		t.Errorf("status after injected BER = %q, want %q", got.Status, StatusDegraded)
+	}
+}
+
+func TestFaultAdminDisabledByDefault(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	req := faults.Request{Kind: faults.KindError, Count: 1}
+	if code := do(t, srv, "POST", "/v1/admin/faults", req, nil); code != http.StatusNotFound && code != http.StatusMethodNotAllowed {
+		t.Errorf("admin API without -enable-faults: status = %d", code)
+	}
+}
+
+func TestErrorFaultForcesServerError(t *testing.T) {
+	s := NewCorridorService()
+	s.FaultsEnabled = true
+	srv := httptest.NewServer(newRouter(s))
+	defer srv.Close()
+
+	var corridor Corridor
+	do(t, srv, "POST", "/v1/corridors", allocateRequest(), &corridor)
+	do(t, srv, "POST", "/v1/admin/faults", faults.Request{Kind: faults.KindError, Target: corridor.ID, Count: 1}, nil)
+
+	if status, e := apiError(t, srv, "GET", "/v1/corridors/"+corridor.ID, nil); status != http.StatusInternalServerError || e.Code != apierr.CodeInjectedFault {
+		t.Errorf("first request: %d %+v, want 500 %s", status, e, apierr.CodeInjectedFault)
+	}
+	if code := do(t, srv, "GET", "/v1/corridors/"+corridor.ID, nil, nil); code != http.StatusOK {
+		t.Errorf("second request: status = %d, want 200", code)
+	}
+}
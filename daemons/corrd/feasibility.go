@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Bounds a ConstraintCheck's limit places on the requested value
+const (
+	BoundMin = "min" // requested must be at least the limit
+	BoundMax = "max" // requested must be at most the limit
+)
+
+// ConstraintCheck is the outcome of checking one constraint of an
+// allocation: the requested value against the limit the link model or the
+// fiber allows
+type ConstraintCheck struct {
+	Constraint string  `json:"constraint"`
+	Requested  float64 `json:"requested"`
+	Limit      float64 `json:"limit"`
+	Bound      string  `json:"bound"` // min | max
+	Unit       string  `json:"unit"`
+	Passed     bool    `json:"passed"`
+	Detail     string  `json:"detail,omitempty"` // why it failed, and by how much
+}
+
+// Feasibility is the result of checking an allocation without making it
+type Feasibility struct {
+	Feasible       bool              `json:"feasible"`
+	AchievableGbps int               `json:"achievable_gbps"`
+	Checks         []ConstraintCheck `json:"checks"`
+}
+
+// InfeasibleError rejects an allocation, listing every constraint checked.
+// It unwraps to ErrInfeasible when the link model fails, or to
+// ErrWavelengthConflict when only the wavelengths are taken.
+type InfeasibleError struct {
+	Checks []ConstraintCheck
+	cause  error
+}
+
+func (e *InfeasibleError) Error() string {
+	var failed []string
+	for _, c := range e.Checks {
+		if !c.Passed {
+			failed = append(failed, c.Detail)
+		}
+	}
+	return fmt.Sprintf("%v: %s", e.cause, strings.Join(failed, "; "))
+}
+
+func (e *InfeasibleError) Unwrap() error { return e.cause }
+
+// newInfeasibleError returns the rejection for a set of checks, nil when
+// every one passed
+func newInfeasibleError(checks []ConstraintCheck) error {
+	var cause error
+	for _, c := range checks {
+		switch {
+		case c.Passed:
+		case c.Constraint == "wavelengths":
+			if cause == nil {
+				cause = ErrWavelengthConflict
+			}
+		default:
+			cause = ErrInfeasible
+		}
+	}
+	if cause == nil {
+		return nil
+	}
+	return &InfeasibleError{Checks: checks, cause: cause}
+}
+
+// linkChecks checks a request against its modeled link: the rate, the FEC
+// latency floor when a latency budget is set, and the reach at which the
+// link still carries any rate
+func linkChecks(req AllocateRequest, link linkEstimate) []ConstraintCheck {
+	checks := []ConstraintCheck{{
+		Constraint: "min_gbps",
+		Requested:  float64(req.MinGbps),
+		Limit:      float64(link.AchievableGbps),
+		Bound:      BoundMax,
+		Unit:       "Gbps",
+		Passed:     req.MinGbps <= link.AchievableGbps,
+	}}
+	if !checks[0].Passed {
+		checks[0].Detail = fmt.Sprintf("min_gbps %d exceeds the modeled achievable rate %d Gbps by %d Gbps (%s)",
+			req.MinGbps, link.AchievableGbps, req.MinGbps-link.AchievableGbps, link)
+	}
+
+	if req.LatencyBudgetNs > 0 {
+		c := ConstraintCheck{
+			Constraint: "latency_budget_ns",
+			Requested:  float64(req.LatencyBudgetNs),
+			Limit:      float64(link.MinLatencyNs),
+			Bound:      BoundMin,
+			Unit:       "ns",
+			Passed:     req.LatencyBudgetNs >= link.MinLatencyNs,
+		}
+		if !c.Passed {
+			c.Detail = fmt.Sprintf("latency budget %d ns is %d ns below the %d ns %s FEC latency",
+				req.LatencyBudgetNs, link.MinLatencyNs-req.LatencyBudgetNs, link.MinLatencyNs, link.Modulation)
+		}
+		checks = append(checks, c)
+	}
+
+	c := ConstraintCheck{
+		Constraint: "reach_mm",
+		Requested:  float64(req.ReachMm),
+		Limit:      float64(link.MaxReachMm),
+		Bound:      BoundMax,
+		Unit:       "mm",
+		Passed:     req.ReachMm <= link.MaxReachMm,
+	}
+	if !c.Passed {
+		c.Detail = fmt.Sprintf("reach %d mm is %d mm beyond the %d mm %s can span",
+			req.ReachMm, req.ReachMm-link.MaxReachMm, link.MaxReachMm, link.Modulation)
+	}
+	return append(checks, c)
+}
+
+// wavelengthCheck checks that every requested wavelength is free in the
+// domain of plan
+func wavelengthCheck(plan wavelengthPlan, domain string, lambdas []int) ConstraintCheck {
+	taken := plan.conflicts(domain, lambdas)
+	c := ConstraintCheck{
+		Constraint: "wavelengths",
+		Requested:  float64(len(lambdas)),
+		Limit:      float64(len(lambdas) - len(taken)),
+		Bound:      BoundMax,
+		Unit:       "wavelengths",
+		Passed:     len(taken) == 0,
+	}
+	if !c.Passed {
+		c.Detail = fmt.Sprintf("wavelengths %v nm already in use in domain %s", taken, domain)
+	}
+	return c
+}
+
+// withWavelengthCheck completes a link-model rejection with the state of
+// the requested wavelengths, so one response lists everything to fix
+func (s *CorridorService) withWavelengthCheck(err error, req AllocateRequest) error {
+	var infeasible *InfeasibleError
+	if !errors.As(err, &infeasible) {
+		return err
+	}
+	s.mu.RLock()
+	c := wavelengthCheck(s.lambdas, req.Domain, req.LambdaNm)
+	s.mu.RUnlock()
+	return newInfeasibleError(append(infeasible.Checks, c))
+}
+
+// CheckAllocation runs every constraint check of an allocation against the
+// current wavelength holdings without allocating anything. Malformed
+// requests fail as they would on allocation.
+func (s *CorridorService) CheckAllocation(req AllocateRequest) (*Feasibility, error) {
+	if err := validateAllocation(req); err != nil {
+		return nil, err
+	}
+	if req.Domain == "" {
+		req.Domain = defaultDomain
+	}
+	link, err := s.modelLink(req)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := linkChecks(req, link)
+	s.mu.RLock()
+	checks = append(checks, wavelengthCheck(s.lambdas, req.Domain, req.LambdaNm))
+	s.mu.RUnlock()
+	return &Feasibility{
+		Feasible:       newInfeasibleError(checks) == nil,
+		AchievableGbps: link.AchievableGbps,
+		Checks:         checks,
+	}, nil
+}
+
+func (s *CorridorService) handleCheckAllocation(w http.ResponseWriter, req AllocateRequest) {
+	feasibility, err := s.CheckAllocation(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, feasibility)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+// checksByName indexes a rejection's constraint checks
+func checksByName(t *testing.T, e apierr.Error) map[string]ConstraintCheck {
+	t.Helper()
+	raw, err := json.Marshal(e.Details)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var checks []ConstraintCheck
+	if err := json.Unmarshal(raw, &checks); err != nil {
+		t.Fatalf("details %s: %v", raw, err)
+	}
+	byName := make(map[string]ConstraintCheck, len(checks))
+	for _, c := range checks {
+		byName[c.Constraint] = c
+	}
+	return byName
+}
+
+func TestRejectionReportsEveryFailedConstraint(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+	do(t, srv, "POST", "/v1/corridors", allocateRequest(), nil)
+
+	// Too fast for the link and on wavelengths already held
+	req := allocateRequest()
+	req.MinGbps = 1000
+	status, e := apiError(t, srv, "POST", "/v1/corridors", req)
+	if status != http.StatusUnprocessableEntity || e.Code != apierr.CodeInfeasible {
+		t.Fatalf("rejection = %d %+v", status, e)
+	}
+	checks := checksByName(t, e)
+	if c := checks["min_gbps"]; c.Passed || c.Requested != 1000 || c.Limit != 4*52 || c.Detail == "" {
+		t.Errorf("min_gbps check = %+v", c)
+	}
+	if c := checks["wavelengths"]; c.Passed || c.Requested != 4 || c.Limit != 0 || c.Detail == "" {
+		t.Errorf("wavelengths check = %+v", c)
+	}
+	if c := checks["reach_mm"]; !c.Passed || c.Requested != 50 {
+		t.Errorf("reach_mm check = %+v", c)
+	}
+
+	// Only the wavelengths failing is a conflict, with the same details
+	status, e = apiError(t, srv, "POST", "/v1/corridors", allocateRequest())
+	if status != http.StatusConflict || e.Code != apierr.CodeConflict {
+		t.Fatalf("wavelength-only rejection = %d %+v", status, e)
+	}
+	if c := checksByName(t, e)["wavelengths"]; c.Passed || c.Limit != 0 {
+		t.Errorf("wavelengths check = %+v", c)
+	}
+}
+
+func TestReachAndLatencyChecks(t *testing.T) {
+	req := allocateRequest()
+	req.ReachMm = 1_000_000
+	req.LatencyBudgetNs = 1
+	link, err := NewCorridorService().modelLink(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewCorridorService().CheckAllocation(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Feasible {
+		t.Fatalf("feasibility = %+v", f)
+	}
+	for _, c := range f.Checks {
+		switch c.Constraint {
+		case "reach_mm":
+			if c.Passed || c.Limit != float64(link.MaxReachMm) || c.Bound != BoundMax {
+				t.Errorf("reach check = %+v, want limit %d", c, link.MaxReachMm)
+			}
+		case "latency_budget_ns":
+			if c.Passed || c.Limit != float64(link.MinLatencyNs) || c.Bound != BoundMin {
+				t.Errorf("latency check = %+v, want limit %d", c, link.MinLatencyNs)
+			}
+		}
+	}
+}
+
+func TestDryRunFeasibleRequestPassesAll(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	var f Feasibility
+	if code := do(t, srv, "POST", "/v1/corridors?dry_run=true", allocateRequest(), &f); code != http.StatusOK {
+		t.Fatalf("dry run status = %d", code)
+	}
+	if !f.Feasible || f.AchievableGbps != 4*52 || len(f.Checks) != 4 {
+		t.Fatalf("dry run = %+v", f)
+	}
+	for _, c := range f.Checks {
+		if !c.Passed || c.Detail != "" {
+			t.Errorf("check %+v did not pass", c)
+		}
+	}
+
+	// Nothing was allocated
+	var list []Corridor
+	do(t, srv, "GET", "/v1/corridors", nil, &list)
+	if len(list) != 0 {
+		t.Errorf("dry run allocated %d corridors", len(list))
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/gorilla/mux"
+)
+
+// Corridor IDs read cor-<type>-<random>-<checksum>, e.g.
+// cor-si-f4eejw95-4ms18h8. The random and checksum parts use Crockford's
+// lowercase base32 alphabet, which leaves out i, l, o and u so IDs read
+// back unambiguously. The checksum is the CRC-32 of everything before it,
+// which catches any single-character typo and most transpositions, so a
+// mistyped ID is refused before it reaches the store.
+var idEncoding = base32.NewEncoding("0123456789abcdefghjkmnpqrstvwxyz").WithPadding(base32.NoPadding)
+
+// idTypeCodes abbreviates each corridor type for its IDs
+var idTypeCodes = map[string]string{
+	"SiCorridor":     "si",
+	"CarbonCorridor": "cb",
+}
+
+// idRandomBytes is the entropy of an ID, 8 base32 characters
+const idRandomBytes = 5
+
+// ErrMalformedID marks an ID that cannot have been issued by corrd
+var ErrMalformedID = errors.New("malformed corridor id")
+
+// generateID generates a checksummed ID for a corridor of the given type
+func generateID(corridorType string) string {
+	b := make([]byte, idRandomBytes)
+	if _, err := rand.Read(b); err != nil {
+		binary.BigEndian.PutUint32(b[1:], uint32(time.Now().UnixNano()))
+	}
+	body := "cor-" + idTypeCodes[corridorType] + "-" + idEncoding.EncodeToString(b)
+	return body + "-" + idChecksum(body)
+}
+
+func idChecksum(body string) string {
+	sum := make([]byte, 4)
+	binary.BigEndian.PutUint32(sum, crc32.ChecksumIEEE([]byte(body)))
+	return idEncoding.EncodeToString(sum)
+}
+
+// ValidateID checks an ID's shape and checksum without consulting the store
+func ValidateID(id string) error {
+	parts := strings.Split(id, "-")
+	if len(parts) != 4 || parts[0] != "cor" {
+		return fmt.Errorf("%w: %q is not cor-<type>-<random>-<checksum>", ErrMalformedID, id)
+	}
+	known := false
+	for _, code := range idTypeCodes {
+		known = known || parts[1] == code
+	}
+	if !known {
+		return fmt.Errorf("%w: %q has unknown type code %q", ErrMalformedID, id, parts[1])
+	}
+	if raw, err := idEncoding.DecodeString(parts[2]); err != nil || len(raw) != idRandomBytes {
+		return fmt.Errorf("%w: %q has an invalid random part", ErrMalformedID, id)
+	}
+	body := strings.Join(parts[:3], "-")
+	if parts[3] != idChecksum(body) {
+		return fmt.Errorf("%w: %q fails its checksum (mistyped?)", ErrMalformedID, id)
+	}
+	return nil
+}
+
+// checkIDs refuses requests naming a corridor by a malformed ID with a 400
+// before any handler looks it up
+func checkIDs(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := mux.Vars(r)["id"]; ok {
+			if err := ValidateID(id); err != nil {
+				apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
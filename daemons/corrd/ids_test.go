@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGeneratedIDsValidate(t *testing.T) {
+	seen := map[string]bool{}
+	for _, typ := range []string{"SiCorridor", "CarbonCorridor"} {
+		for i := 0; i < 100; i++ {
+			id := generateID(typ)
+			if err := ValidateID(id); err != nil {
+				t.Fatalf("generated %s fails validation: %v", id, err)
+			}
+			if !strings.HasPrefix(id, "cor-"+idTypeCodes[typ]+"-") {
+				t.Errorf("%s ID %s lacks its type code", typ, id)
+			}
+			if seen[id] {
+				t.Errorf("duplicate ID %s", id)
+			}
+			seen[id] = true
+		}
+	}
+}
+
+func TestSingleCharacterTypoFailsChecksum(t *testing.T) {
+	id := generateID("SiCorridor")
+	const alphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+	// Every substitution within the random and checksum parts, keeping the
+	// ID's shape, must be caught
+	for i := len("cor-si-"); i < len(id); i++ {
+		if id[i] == '-' {
+			continue
+		}
+		for _, c := range alphabet {
+			if byte(c) == id[i] {
+				continue
+			}
+			typo := id[:i] + string(c) + id[i+1:]
+			if err := ValidateID(typo); !errors.Is(err, ErrMalformedID) {
+				t.Fatalf("typo %s of %s validated", typo, id)
+			}
+		}
+	}
+}
+
+func TestValidateIDRejectsBadShapes(t *testing.T) {
+	id := generateID("CarbonCorridor")
+	for _, bad := range []string{
+		"",
+		"cor-1234abcd",
+		strings.Replace(id, "cor-", "crd-", 1),
+		strings.Replace(id, "-cb-", "-xx-", 1),
+		id + "-0",
+		strings.ToUpper(id),
+	} {
+		if err := ValidateID(bad); !errors.Is(err, ErrMalformedID) {
+			t.Errorf("ValidateID(%q) = %v", bad, err)
+		}
+	}
+}
+
+func TestMistypedIDRefusedBeforeLookup(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	var corridor Corridor
+	do(t, srv, "POST", "/v1/corridors", allocateRequest(), &corridor)
+	if err := ValidateID(corridor.ID); err != nil {
+		t.Fatalf("allocated corridor ID: %v", err)
+	}
+	typo := corridor.ID[:len(corridor.ID)-1] + "0"
+	if typo == corridor.ID {
+		typo = corridor.ID[:len(corridor.ID)-1] + "1"
+	}
+	for _, path := range []string{"/v1/corridors/" + typo, "/v1/corridors/" + typo + "/telemetry"} {
+		if code := do(t, srv, "GET", path, nil, nil); code != http.StatusBadRequest {
+			t.Errorf("GET %s: status = %d, want 400", path, code)
+		}
+	}
+	if code := do(t, srv, "GET", "/v1/corridors/"+corridor.ID, nil, nil); code != http.StatusOK {
+		t.Errorf("GET with the issued ID: status = %d", code)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/corridoros/pkg/journal"
+	"github.com/corridoros/pkg/linkmodel"
+)
+
+// Journaled operations; each entry carries the corridor after the operation,
+// or nothing once it is released
+const (
+	opAllocate    = "allocate"
+	opTransition  = "transition"
+	opDegrade     = "degrade" // a BER reading above threshold degraded it
+	opRecalibrate = "recalibrate"
+	opRelease     = "release"
+)
+
+// OpenJournal opens the journal at path, rebuilds the corridor store from
+// it and journals every later change. Call it before serving requests.
+func (s *CorridorService) OpenJournal(path string) error {
+	j, err := journal.Open(path)
+	if err != nil {
+		return err
+	}
+	if err := s.replay(j); err != nil {
+		j.Close()
+		return fmt.Errorf("replaying %s: %w", path, err)
+	}
+	s.mu.Lock()
+	s.journal = j
+	s.mu.Unlock()
+	return nil
+}
+
+// replay restores the corridors that were live at the journal's end. Each
+// comes back with its recorded configuration and status, holding its
+// wavelengths and drifting from the BER it was last calibrated to; its
+// telemetry history starts empty.
+func (s *CorridorService) replay(j *journal.Journal) error {
+	live, err := journal.Live[Corridor](j, opRelease)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rec := range live {
+		c := rec.Value
+		state, err := s.restoreCorridor(c)
+		if err != nil {
+			return fmt.Errorf("corridor %s: %w", rec.ID, err)
+		}
+		s.lambdas.reserve(c.Domain, c.LambdaNm, c.ID)
+		s.corridors.Set(c.ID, state)
+		s.startDrift(state)
+	}
+	if len(live) > 0 {
+		log.Printf("journal: restored %d corridors", len(live))
+	}
+	return nil
+}
+
+// restoreCorridor rebuilds the live link state of a journaled corridor the
+// way prepareAllocation does for a new one
+func (s *CorridorService) restoreCorridor(c Corridor) (*corridorState, error) {
+	model, err := linkmodel.Lookup(c.LinkModel)
+	if err != nil {
+		return nil, err
+	}
+	est, err := model.Estimate(linkmodel.Config{
+		Modulation: c.Modulation,
+		BaudGBd:    c.BaudGBd,
+		ReachMm:    c.ReachMm,
+		Lanes:      c.Lanes,
+	}, linkmodel.Conditions{TempC: nominalTempC})
+	if err != nil {
+		return nil, err
+	}
+	return &corridorState{
+		corridor: c,
+		telemetry: Telemetry{
+			BER:           c.BER,
+			TempC:         nominalTempC,
+			PowerPjPerBit: est.PowerPjPerBit,
+			Drift:         "low",
+		},
+		history:       newTelemetryRing(s.HistoryLength),
+		sampledAt:     c.StatusChangedAt,
+		berThreshold:  s.BERThreshold,
+		baselineBER:   c.BER,
+		baselinePower: est.PowerPjPerBit,
+	}, nil
+}
+
+// journalLocked records an operation on a corridor. The caller must hold
+// the store lock.
+func (s *CorridorService) journalLocked(op string, state *corridorState) {
+	var data any
+	if op != opRelease {
+		data = state.corridor
+	}
+	s.journal.Log(op, state.corridor.ID, data)
+}
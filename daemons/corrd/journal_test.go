@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJournalReplayRebuildsCorridors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corrd.journal")
+	s := NewCorridorService()
+	if err := s.OpenJournal(path); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for i, base := range []int{1550, 1560, 1570} {
+		req := allocateRequest()
+		req.LambdaNm = []int{base, base + 1, base + 2, base + 3}
+		req.Labels = map[string]string{"slot": string(rune('a' + i))}
+		c, err := s.Allocate(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, c.ID)
+	}
+	if _, err := s.Recalibrate(ids[0], RecalibrateRequest{TargetBER: 1e-12}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Transition(ids[1], StatusDegraded); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Release(ids[2]); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(s.List(nil))
+	s.journal.Close()
+
+	restarted := NewCorridorService()
+	if err := restarted.OpenJournal(path); err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.journal.Close()
+	if got, _ := json.Marshal(restarted.List(nil)); string(got) != string(want) {
+		t.Errorf("replayed corridors:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Restored corridors hold their wavelengths; the released one's are free
+	req := allocateRequest()
+	if _, err := restarted.Allocate(req); err == nil {
+		t.Error("allocated wavelengths a restored corridor holds")
+	}
+	req.LambdaNm = []int{1570, 1571, 1572, 1573}
+	if _, err := restarted.Allocate(req); err != nil {
+		t.Errorf("released corridor's wavelengths: %v", err)
+	}
+
+	entries, err := restarted.journal.Read(1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(entries); n < 7 || entries[n-1].Op != opAllocate {
+		t.Errorf("journal after restart has %d entries ending %+v", n, entries[n-1])
+	}
+}
+
+func TestJournalReplayRejectsCorruption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corrd.journal")
+	s := NewCorridorService()
+	if err := s.OpenJournal(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.journal.Append(opAllocate, "cor-bad", Corridor{ID: "cor-bad", LinkModel: "no-such-model"}); err != nil {
+		t.Fatal(err)
+	}
+	s.journal.Close()
+
+	err := NewCorridorService().OpenJournal(path)
+	if err == nil || !strings.Contains(err.Error(), "corridor cor-bad") {
+		t.Fatalf("replaying a corridor with an unknown link model: err = %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/corridoros/pkg/labels"
+)
+
+func TestListFiltersByLabel(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	for i, tags := range []map[string]string{
+		{"team": "alpha", "env": "prod"},
+		{"team": "alpha", "env": "dev"},
+		{"team": "beta"},
+		nil,
+	} {
+		req := allocateRequest()
+		req.LambdaNm = []int{1550 + 4*i, 1551 + 4*i, 1552 + 4*i, 1553 + 4*i}
+		req.Labels = tags
+		if code := do(t, srv, "POST", "/v1/corridors", req, nil); code != http.StatusCreated {
+			t.Fatalf("allocate %d: status %d", i, code)
+		}
+	}
+
+	for query, want := range map[string]int{
+		"":                                 4,
+		"?label.team=alpha":                2,
+		"?label.team=alpha&label.env=prod": 1,
+		"?label.team=gamma":                0,
+		"?label.env=dev":                   1,
+	} {
+		var list []Corridor
+		if code := do(t, srv, "GET", "/v1/corridors"+query, nil, &list); code != http.StatusOK {
+			t.Fatalf("%s: status %d", query, code)
+		}
+		if len(list) != want {
+			t.Errorf("%q: %d corridors, want %d", query, len(list), want)
+		}
+		for _, c := range list {
+			if query != "" && c.Labels == nil {
+				t.Errorf("%q: unlabeled corridor %s matched", query, c.ID)
+			}
+		}
+	}
+	if code := do(t, srv, "GET", "/v1/corridors?label.bad%20key=x", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("invalid selector: status %d, want 400", code)
+	}
+}
+
+func TestAllocateRejectsInvalidLabels(t *testing.T) {
+	s := NewCorridorService()
+	req := allocateRequest()
+	req.Labels = map[string]string{"team": strings.Repeat("x", labels.MaxValueLength+1)}
+	if _, err := s.Allocate(req); err == nil {
+		t.Fatal("oversized label value accepted")
+	}
+
+	// The stored labels are a copy of the request's
+	req.Labels = map[string]string{"team": "alpha"}
+	corridor, err := s.Allocate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Labels["team"] = "mutated"
+	if got, _ := s.Get(corridor.ID); got.Labels["team"] != "alpha" {
+		t.Errorf("stored labels alias the request: %v", got.Labels)
+	}
+}
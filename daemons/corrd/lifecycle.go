@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Corridor lifecycle states
+const (
+	StatusAllocating    = "allocating"
+	StatusActive        = "active"
+	StatusDegraded      = "degraded"
+	StatusRecalibrating = "recalibrating"
+	StatusFailed        = "failed"
+	StatusReleased      = "released"
+)
+
+// ErrIllegalTransition marks a lifecycle transition the state machine forbids
+var ErrIllegalTransition = errors.New("illegal status transition")
+
+// legalTransitions lists, per state, the states it may move to
+var legalTransitions = map[string][]string{
+	StatusAllocating:    {StatusActive, StatusFailed},
+	StatusActive:        {StatusDegraded, StatusRecalibrating, StatusReleased},
+	StatusDegraded:      {StatusRecalibrating, StatusFailed, StatusReleased},
+	StatusRecalibrating: {StatusActive, StatusFailed},
+	StatusFailed:        {StatusRecalibrating, StatusReleased},
+	StatusReleased:      {},
+}
+
+// TransitionRequest forces a corridor into a new lifecycle state
+type TransitionRequest struct {
+	Status string `json:"status"`
+}
+
+// canTransition reports whether from → to is a legal lifecycle move
+func canTransition(from, to string) bool {
+	for _, next := range legalTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transition moves a corridor to a new state, recording when it happened.
+// The caller must hold the store lock.
+func (state *corridorState) transition(to string, now time.Time) error {
+	from := state.corridor.Status
+	if _, known := legalTransitions[to]; !known {
+		return fmt.Errorf("unknown status: %s", to)
+	}
+	if !canTransition(from, to) {
+		return fmt.Errorf("%w: %s → %s", ErrIllegalTransition, from, to)
+	}
+	state.corridor.Status = to
+	state.corridor.StatusChangedAt = now
+	return nil
+}
+
+// Transition forces a corridor into a new state if the move is legal
+func (s *CorridorService) Transition(id string, to string) (*Corridor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, exists := s.corridors.Get(id)
+	if !exists {
+		return nil, fmt.Errorf("corridor %s %w", id, ErrNotFound)
+	}
+	if to == StatusReleased {
+		return nil, fmt.Errorf("use DELETE /v1/corridors/%s to release a corridor", id)
+	}
+	if err := state.transition(to, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	s.journalLocked(opTransition, state)
+	corridor := state.corridor
+	return &corridor, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIllegalTransitionRejected(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	var corridor Corridor
+	do(t, srv, "POST", "/v1/corridors", allocateRequest(), &corridor)
+	if corridor.Status != StatusActive || corridor.StatusChangedAt.IsZero() {
+		t.Fatalf("new corridor status = %q at %v", corridor.Status, corridor.StatusChangedAt)
+	}
+	path := "/v1/corridors/" + corridor.ID + "/transition"
+
+	// active → failed skips degraded/recalibrating
+	if code := do(t, srv, "POST", path, TransitionRequest{Status: StatusFailed}, nil); code != http.StatusConflict {
+		t.Errorf("active → failed: status = %d, want 409", code)
+	}
+	if code := do(t, srv, "POST", path, TransitionRequest{Status: "bogus"}, nil); code != http.StatusBadRequest {
+		t.Errorf("unknown status: status = %d, want 400", code)
+	}
+
+	var got Corridor
+	if code := do(t, srv, "POST", path, TransitionRequest{Status: StatusDegraded}, &got); code != http.StatusOK {
+		t.Fatalf("active → degraded: status = %d", code)
+	}
+	if got.Status != StatusDegraded || got.StatusChangedAt.Before(corridor.StatusChangedAt) {
+		t.Errorf("after transition = %q at %v", got.Status, got.StatusChangedAt)
+	}
+}
+
+func TestTransitionTable(t *testing.T) {
+	for from, tos := range legalTransitions {
+		for _, to := range tos {
+			state := &corridorState{corridor: Corridor{Status: from}}
+			if err := state.transition(to, state.corridor.StatusChangedAt); err != nil {
+				t.Errorf("%s → %s: %v", from, to, err)
+			}
+		}
+	}
+	state := &corridorState{corridor: Corridor{Status: StatusReleased}}
+	if err := state.transition(StatusActive, state.corridor.StatusChangedAt); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("released → active error = %v, want ErrIllegalTransition", err)
+	}
+}
+
+func TestBadBERDegradesCorridor(t *testing.T) {
+	s := NewCorridorService()
+	corridor, err := s.Allocate(allocateRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	state, _ := s.corridors.Peek(corridor.ID)
+	state.telemetry.BER = 1e-6
+	s.mu.Unlock()
+
+	if _, err := s.Telemetry(corridor.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := s.Get(corridor.ID)
+	if got.Status != StatusDegraded {
+		t.Fatalf("status after a 1e-6 BER reading = %q, want %q", got.Status, StatusDegraded)
+	}
+
+	// Recalibration brings it back through recalibrating to active.
+	if _, err := s.Recalibrate(corridor.ID, RecalibrateRequest{TargetBER: 1e-12}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = s.Get(corridor.ID); got.Status != StatusActive {
+		t.Errorf("status after recalibration = %q, want %q", got.Status, StatusActive)
+	}
+}
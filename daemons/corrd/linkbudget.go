@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/jsonbody"
+	"github.com/corridoros/pkg/linkmodel"
+)
+
+// defaultSensitivityBER is the BER receiver sensitivities are quoted at
+// when a request does not say
+const defaultSensitivityBER = 1e-12
+
+// LinkBudgetRequest describes an optical link to budget. Powers are in dBm
+// and losses in dB; the receiver sensitivity is the power it needs to hit
+// SensitivityBER.
+type LinkBudgetRequest struct {
+	TxPowerDbm        *float64  `json:"tx_power_dbm"`
+	LossDbPerMm       float64   `json:"loss_db_per_mm"`
+	ConnectorLossesDb []float64 `json:"connector_losses_db,omitempty"`
+	ReachMm           int       `json:"reach_mm"`
+	RxSensitivityDbm  *float64  `json:"rx_sensitivity_dbm"`
+	SensitivityBER    float64   `json:"sensitivity_ber,omitempty"` // default 1e-12
+	TargetBER         float64   `json:"target_ber,omitempty"`      // default sensitivity_ber
+	MinMarginDb       float64   `json:"min_margin_db,omitempty"`
+}
+
+// LinkBudget is a computed optical link budget
+type LinkBudget struct {
+	TxPowerDbm      float64 `json:"tx_power_dbm"`
+	PathLossDb      float64 `json:"path_loss_db"`
+	ConnectorLossDb float64 `json:"connector_loss_db"`
+	TotalLossDb     float64 `json:"total_loss_db"`
+	RxPowerDbm      float64 `json:"rx_power_dbm"`
+	RxPowerMw       float64 `json:"rx_power_mw"`
+	TargetBER       float64 `json:"target_ber"`
+	// Sensitivity at the target BER: the quoted sensitivity plus the penalty
+	// for a lower BER, or less a credit for a higher one
+	RequiredRxPowerDbm   float64 `json:"required_rx_power_dbm"`
+	SensitivityPenaltyDb float64 `json:"sensitivity_penalty_db"`
+	MarginDb             float64 `json:"margin_db"`
+	MinMarginDb          float64 `json:"min_margin_db"`
+	Sufficient           bool    `json:"sufficient"`
+	// Longest reach that still leaves min_margin_db; absent for a lossless
+	// path, which any reach can span, and when not even a zero reach does
+	MaxReachMm *int `json:"max_reach_mm,omitempty"`
+}
+
+// sensitivityPenaltyDb is the extra power a thermal-noise-limited receiver
+// needs to reach target BER instead of the BER its sensitivity is quoted
+// at. Required power scales with Q; negative when target is the easier BER.
+func sensitivityPenaltyDb(quotedBER, targetBER float64) float64 {
+	return 10 * math.Log10(linkmodel.QFactor(targetBER)/linkmodel.QFactor(quotedBER))
+}
+
+func validateLinkBudget(req LinkBudgetRequest) error {
+	if req.TxPowerDbm == nil || req.RxSensitivityDbm == nil {
+		return fmt.Errorf("tx_power_dbm and rx_sensitivity_dbm are required")
+	}
+	if req.LossDbPerMm < 0 || req.ReachMm < 0 || req.MinMarginDb < 0 {
+		return fmt.Errorf("loss_db_per_mm, reach_mm and min_margin_db must not be negative")
+	}
+	for i, loss := range req.ConnectorLossesDb {
+		if loss < 0 {
+			return fmt.Errorf("connector_losses_db[%d] must not be negative", i)
+		}
+	}
+	if req.SensitivityBER < 0 || req.SensitivityBER >= 0.5 || req.TargetBER < 0 || req.TargetBER >= 0.5 {
+		return fmt.Errorf("sensitivity_ber and target_ber must be in (0, 0.5)")
+	}
+	return nil
+}
+
+// ComputeLinkBudget budgets the optical power of a link: what arrives after
+// the path and connector losses, against what the receiver needs for the
+// target BER
+func ComputeLinkBudget(req LinkBudgetRequest) (*LinkBudget, error) {
+	if err := validateLinkBudget(req); err != nil {
+		return nil, err
+	}
+	if req.SensitivityBER == 0 {
+		req.SensitivityBER = defaultSensitivityBER
+	}
+	if req.TargetBER == 0 {
+		req.TargetBER = req.SensitivityBER
+	}
+
+	b := &LinkBudget{
+		TxPowerDbm:           *req.TxPowerDbm,
+		PathLossDb:           req.LossDbPerMm * float64(req.ReachMm),
+		TargetBER:            req.TargetBER,
+		SensitivityPenaltyDb: sensitivityPenaltyDb(req.SensitivityBER, req.TargetBER),
+		MinMarginDb:          req.MinMarginDb,
+	}
+	for _, loss := range req.ConnectorLossesDb {
+		b.ConnectorLossDb += loss
+	}
+	b.TotalLossDb = b.PathLossDb + b.ConnectorLossDb
+	b.RxPowerDbm = b.TxPowerDbm - b.TotalLossDb
+	b.RxPowerMw = math.Pow(10, b.RxPowerDbm/10)
+	b.RequiredRxPowerDbm = *req.RxSensitivityDbm + b.SensitivityPenaltyDb
+	b.MarginDb = b.RxPowerDbm - b.RequiredRxPowerDbm
+	b.Sufficient = b.MarginDb >= b.MinMarginDb
+
+	if req.LossDbPerMm > 0 {
+		spare := b.TxPowerDbm - b.ConnectorLossDb - b.RequiredRxPowerDbm - b.MinMarginDb
+		if spare >= 0 {
+			reach := int(math.Floor(spare / req.LossDbPerMm))
+			b.MaxReachMm = &reach
+		}
+	}
+	return b, nil
+}
+
+func (s *CorridorService) handleLinkBudget(w http.ResponseWriter, r *http.Request) {
+	var req LinkBudgetRequest
+	if err := jsonbody.Decode(r.Body, &req); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	budget, err := ComputeLinkBudget(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, budget)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+// linkBudgetRequest is a 0 dBm link losing 0.05 dB/mm into a -20 dBm receiver
+func linkBudgetRequest(reachMm int) LinkBudgetRequest {
+	return LinkBudgetRequest{
+		TxPowerDbm:        ptr(0),
+		LossDbPerMm:       0.05,
+		ConnectorLossesDb: []float64{0.5, 0.5},
+		ReachMm:           reachMm,
+		RxSensitivityDbm:  ptr(-20),
+		MinMarginDb:       3,
+	}
+}
+
+func TestLinkBudgetMargin(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	var adequate LinkBudget
+	if code := do(t, srv, "POST", "/v1/corridors/link-budget", linkBudgetRequest(50), &adequate); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	// 0 - 2.5 - 1 = -3.5 dBm received, 16.5 dB over the sensitivity
+	if math.Abs(adequate.RxPowerDbm+3.5) > 1e-9 || math.Abs(adequate.MarginDb-16.5) > 1e-9 || !adequate.Sufficient {
+		t.Errorf("50 mm budget = %+v", adequate)
+	}
+	// 20 - 1 - 3 = 16 dB to spend on the path
+	if adequate.MaxReachMm == nil || *adequate.MaxReachMm != 320 {
+		t.Errorf("max reach = %v, want 320", adequate.MaxReachMm)
+	}
+
+	var overlong LinkBudget
+	do(t, srv, "POST", "/v1/corridors/link-budget", linkBudgetRequest(500), &overlong)
+	if overlong.MarginDb >= 0 || overlong.Sufficient {
+		t.Errorf("500 mm budget = %+v, want negative margin and insufficient", overlong)
+	}
+}
+
+func TestLinkBudgetTargetBERShiftsSensitivity(t *testing.T) {
+	req := linkBudgetRequest(50)
+	quoted, _ := ComputeLinkBudget(req)
+	req.TargetBER = 1e-15
+	stricter, err := ComputeLinkBudget(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stricter.SensitivityPenaltyDb <= 0 || stricter.MarginDb >= quoted.MarginDb {
+		t.Errorf("target 1e-15: penalty %g dB, margin %g dB against %g dB at 1e-12",
+			stricter.SensitivityPenaltyDb, stricter.MarginDb, quoted.MarginDb)
+	}
+}
+
+func TestLinkBudgetValidation(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	for name, mutate := range map[string]func(*LinkBudgetRequest){
+		"missing tx power":   func(r *LinkBudgetRequest) { r.TxPowerDbm = nil },
+		"negative loss":      func(r *LinkBudgetRequest) { r.LossDbPerMm = -1 },
+		"negative connector": func(r *LinkBudgetRequest) { r.ConnectorLossesDb[1] = -0.5 },
+		"target ber":         func(r *LinkBudgetRequest) { r.TargetBER = 0.5 },
+	} {
+		req := linkBudgetRequest(50)
+		mutate(&req)
+		if code := do(t, srv, "POST", "/v1/corridors/link-budget", req, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, code)
+		}
+	}
+}
@@ -0,0 +1,761 @@
+// corrd — Photonic Corridor daemon (in-memory implementation)
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	mrand "math/rand"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/bounded"
+	"github.com/corridoros/pkg/faults"
+	"github.com/corridoros/pkg/journal"
+	"github.com/corridoros/pkg/jsonbody"
+	"github.com/corridoros/pkg/labels"
+	"github.com/corridoros/pkg/linkmodel"
+	"github.com/corridoros/pkg/protowire"
+	"github.com/corridoros/pkg/reservations"
+	"github.com/corridoros/pkg/tlsserve"
+	"github.com/corridoros/pkg/trace"
+	"github.com/gorilla/mux"
+)
+
+// QoSConfig represents corridor QoS settings
+type QoSConfig struct {
+	PFC      bool   `json:"pfc"`
+	Priority string `json:"priority"`
+}
+
+// AllocateRequest represents a corridor allocation request
+type AllocateRequest struct {
+	CorridorType        string    `json:"corridor_type"`
+	Lanes               int       `json:"lanes"`
+	LambdaNm            []int     `json:"lambda_nm"`
+	MinGbps             int       `json:"min_gbps"`
+	LatencyBudgetNs     int       `json:"latency_budget_ns"`
+	ReachMm             int       `json:"reach_mm"`
+	Mode                string    `json:"mode"`
+	QoS                 QoSConfig `json:"qos"`
+	AttestationRequired bool      `json:"attestation_required"`
+	AttestationTicket   *string   `json:"attestation_ticket,omitempty"`
+	Modulation          string    `json:"modulation,omitempty"` // NRZ (default) or PAM4
+	BaudGBd             float64   `json:"baud_gbd,omitempty"`   // per-lane symbol rate
+	Domain              string    `json:"domain,omitempty"`     // fiber the lanes share; defaults to "default"
+	LinkModel           string    `json:"link_model,omitempty"` // BER model; defaults to the service's
+	// Labels tag the corridor for filtering and accounting
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Corridor represents an allocated photonic corridor
+type Corridor struct {
+	ID                  string    `json:"id"`
+	CorridorType        string    `json:"corridor_type"`
+	Lanes               int       `json:"lanes"`
+	LambdaNm            []int     `json:"lambda_nm"`
+	Domain              string    `json:"domain"`
+	MinGbps             int       `json:"min_gbps"`
+	LatencyBudgetNs     int       `json:"latency_budget_ns"`
+	ReachMm             int       `json:"reach_mm"`
+	Mode                string    `json:"mode"`
+	QoS                 QoSConfig `json:"qos"`
+	AttestationRequired bool      `json:"attestation_required"`
+	Modulation          string    `json:"modulation"`
+	BaudGBd             float64   `json:"baud_gbd"`
+	LinkModel           string    `json:"link_model"`
+	AchievableGbps      int       `json:"achievable_gbps"`
+	BER                 float64   `json:"ber"`
+	EyeMargin           string    `json:"eye_margin"`
+	CreatedAt           time.Time `json:"created_at"`
+	Status              string    `json:"status"`
+	StatusChangedAt     time.Time `json:"status_changed_at"`
+
+	// Labels are the tags given at allocation
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Telemetry represents live corridor telemetry
+type Telemetry struct {
+	BER                float64 `json:"ber"`
+	TempC              float64 `json:"temp_c"`
+	PowerPjPerBit      float64 `json:"power_pj_per_bit"`
+	Drift              string  `json:"drift"`
+	UtilizationPercent float64 `json:"utilization_percent"`
+	ErrorCount         int     `json:"error_count"`
+
+	// Metrics derived from the corridor's configuration and live state
+	GbpsPerLane        float64 `json:"gbps_per_lane"`
+	SpectralEfficiency float64 `json:"spectral_efficiency_bps_per_hz"`
+	EfficiencyScore    float64 `json:"efficiency_score"` // 0-100
+
+	// Seq numbers the background samples taken of the corridor and
+	// SampledAt is when the latest was taken; both stay put between
+	// sampler ticks, so equal seqs mean the same underlying sample
+	Seq       uint64    `json:"seq"`
+	SampledAt time.Time `json:"sampled_at"`
+}
+
+// RecalibrateRequest represents a HELIOPASS recalibration request
+type RecalibrateRequest struct {
+	TargetBER      float64 `json:"target_ber"`
+	AmbientProfile string  `json:"ambient_profile"`
+}
+
+// RecalibrateResponse represents the recalibration outcome
+type RecalibrateResponse struct {
+	Status            string    `json:"status"`
+	Converged         bool      `json:"converged"`
+	BiasVoltages      []float64 `json:"bias_voltages_mv"`
+	LambdaShifts      []float64 `json:"lambda_shifts_nm"`
+	LaserPowerAdjust  []float64 `json:"laser_power_adjust_db"`
+	ConvergenceTimeMs int64     `json:"convergence_time_ms"`
+	FinalBER          float64   `json:"final_ber"`
+	FinalEyeMargin    float64   `json:"final_eye_margin"`
+	PowerSavings      float64   `json:"power_savings_percent"`
+}
+
+// corridorState is a stored corridor plus its live link state
+type corridorState struct {
+	corridor  Corridor
+	telemetry Telemetry
+	history   *telemetryRing
+	seq       uint64    // background samples taken
+	sampledAt time.Time // when the latest was taken, or allocation time
+
+	berThreshold  float64
+	baselineBER   float64            // calibrated BER the drift walk is bounded around
+	baselinePower float64            // link energy per bit its link model estimated
+	stopDrift     context.CancelFunc // stops the drift goroutine
+	checks        []ConstraintCheck  // the link checks it passed at allocation
+}
+
+// ErrNotFound marks lookups of unknown corridors
+var ErrNotFound = errors.New("not found")
+
+// CorridorService manages corridors in an in-memory, mutex-guarded store
+type CorridorService struct {
+	mu           sync.RWMutex
+	corridors    *bounded.BoundedStore[*corridorState] // only failed corridors are evictable
+	reservations *reservations.Table[*corridorState]
+	lambdas      wavelengthPlan
+	faults       *faults.Registry
+	epoch        time.Time // monotonic origin of sample timestamps
+	presets      map[string]Preset
+	journal      *journal.Journal // nil unless OpenJournal was called
+
+	// HistoryLength bounds the telemetry samples retained per corridor
+	HistoryLength int
+	// SampleInterval is the period of the background telemetry sampler
+	SampleInterval time.Duration
+	// DriftInterval is the period of each corridor's link-state drift
+	DriftInterval time.Duration
+	// BERThreshold is the measured BER above which an active corridor
+	// is marked degraded
+	BERThreshold float64
+	// FaultsEnabled mounts the fault-injection admin API
+	FaultsEnabled bool
+	// PowerBudgetW and BandwidthBudgetGbps bound what Plan accepts;
+	// zero leaves a budget unchecked
+	PowerBudgetW        float64
+	BandwidthBudgetGbps float64
+	// MemQoSURL is the memqosd endpoint Plan prices FFM allocations against
+	MemQoSURL string
+	// ReservationTTL is how long an uncommitted reservation holds its
+	// wavelengths unless the request sets its own TTL
+	ReservationTTL time.Duration
+	// LinkModel estimates BER and energy per bit for requests that do
+	// not choose a model
+	LinkModel string
+}
+
+// NewCorridorService creates a new corridor service
+func NewCorridorService() *CorridorService {
+	s := &CorridorService{
+		corridors:      bounded.New[*corridorState](defaultMaxCorridors),
+		lambdas:        make(wavelengthPlan),
+		faults:         newFaultRegistry(),
+		presets:        make(map[string]Preset),
+		epoch:          time.Now(),
+		HistoryLength:  3600,
+		SampleInterval: time.Second,
+		DriftInterval:  time.Second,
+		BERThreshold:   1e-9,
+		ReservationTTL: 30 * time.Second,
+		LinkModel:      linkmodel.Default,
+	}
+	s.corridors.Pinned = func(state *corridorState) bool { return state.corridor.Status != StatusFailed }
+	s.corridors.OnEvict = s.evictCorridorLocked
+	s.reservations = reservations.New(&s.mu, s.releaseReservation)
+	return s
+}
+
+// defaultMaxCorridors bounds the corridors held before failed ones are
+// evicted, least recently used first
+const defaultMaxCorridors = 4096
+
+// evictCorridorLocked releases a failed corridor the store evicted for
+// space. Corridors are only stored with the store lock held, so the caller
+// holds it.
+func (s *CorridorService) evictCorridorLocked(id string, state *corridorState, reason string) {
+	_ = state.transition(StatusReleased, time.Now().UTC()) // failed -> released is always legal
+	s.lambdas.release(state.corridor.Domain, state.corridor.LambdaNm, id)
+	state.stopDrift()
+	s.journalLocked(opRelease, state)
+	log.Printf("evicted failed corridor %s (%s)", id, reason)
+}
+
+// validateAllocation checks the shape of an allocation request
+func validateAllocation(req AllocateRequest) error {
+	if _, ok := defaultBaudGBd[req.CorridorType]; !ok {
+		return fmt.Errorf("unsupported corridor type: %s", req.CorridorType)
+	}
+	if req.Lanes <= 0 {
+		return fmt.Errorf("lanes must be positive")
+	}
+	if len(req.LambdaNm) != req.Lanes {
+		return fmt.Errorf("lambda_nm must list one wavelength per lane (%d lanes, %d wavelengths)", req.Lanes, len(req.LambdaNm))
+	}
+	if err := duplicateWavelengths(req.LambdaNm); err != nil {
+		return err
+	}
+	if err := outOfBand(req.LambdaNm); err != nil {
+		return err
+	}
+	if req.ReachMm < 0 || req.LatencyBudgetNs < 0 || req.MinGbps < 0 {
+		return fmt.Errorf("reach_mm, latency_budget_ns and min_gbps must not be negative")
+	}
+	if req.AttestationRequired && req.AttestationTicket != nil && *req.AttestationTicket == "" {
+		return fmt.Errorf("attestation_ticket must not be empty")
+	}
+	return labels.Validate(req.Labels)
+}
+
+// Allocate validates a request and creates a new corridor
+func (s *CorridorService) Allocate(req AllocateRequest) (*Corridor, error) {
+	state, err := s.prepareAllocation(req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.holdWavelengthsLocked(state); err != nil {
+		return nil, err
+	}
+	s.activateLocked(state)
+
+	corridor := state.corridor
+	return &corridor, nil
+}
+
+// prepareAllocation validates and models a request into a corridor that is
+// still allocating and not yet stored or holding wavelengths
+func (s *CorridorService) prepareAllocation(req AllocateRequest) (*corridorState, error) {
+	if err := validateAllocation(req); err != nil {
+		return nil, err
+	}
+	if req.Domain == "" {
+		req.Domain = defaultDomain
+	}
+
+	link, err := s.modelLink(req)
+	if err != nil {
+		return nil, err
+	}
+	checks := linkChecks(req, link)
+	if err := newInfeasibleError(checks); err != nil {
+		return nil, s.withWavelengthCheck(err, req)
+	}
+
+	ber := link.BER
+	now := time.Now().UTC()
+	corridor := Corridor{
+		ID:                  generateID(req.CorridorType),
+		CorridorType:        req.CorridorType,
+		Lanes:               req.Lanes,
+		LambdaNm:            append([]int(nil), req.LambdaNm...),
+		Domain:              req.Domain,
+		MinGbps:             req.MinGbps,
+		LatencyBudgetNs:     req.LatencyBudgetNs,
+		ReachMm:             req.ReachMm,
+		Mode:                req.Mode,
+		QoS:                 req.QoS,
+		AttestationRequired: req.AttestationRequired,
+		Modulation:          link.Modulation,
+		BaudGBd:             link.BaudGBd,
+		LinkModel:           link.LinkModel,
+		AchievableGbps:      link.AchievableGbps,
+		BER:                 ber,
+		EyeMargin:           eyeMarginClass(ber),
+		CreatedAt:           now,
+		Status:              StatusAllocating,
+		StatusChangedAt:     now,
+		Labels:              maps.Clone(req.Labels),
+	}
+
+	state := &corridorState{
+		corridor: corridor,
+		telemetry: Telemetry{
+			BER:           ber,
+			TempC:         nominalTempC,
+			PowerPjPerBit: link.PowerPjPerBit,
+			Drift:         "low",
+		},
+		history:       newTelemetryRing(s.HistoryLength),
+		sampledAt:     now,
+		berThreshold:  s.BERThreshold,
+		baselineBER:   ber,
+		baselinePower: link.PowerPjPerBit,
+		checks:        checks,
+	}
+	return state, nil
+}
+
+// holdWavelengthsLocked assigns a corridor its wavelengths, failing if any
+// is already held in its domain. The caller must hold the store lock.
+func (s *CorridorService) holdWavelengthsLocked(state *corridorState) error {
+	c := state.corridor
+	if check := wavelengthCheck(s.lambdas, c.Domain, c.LambdaNm); !check.Passed {
+		return newInfeasibleError(append(slices.Clone(state.checks), check))
+	}
+	s.lambdas.reserve(c.Domain, c.LambdaNm, c.ID)
+	return nil
+}
+
+// activateLocked brings an allocating corridor that holds its wavelengths
+// up and stores it. The caller must hold the store lock.
+func (s *CorridorService) activateLocked(state *corridorState) {
+	// allocating -> active is always legal
+	_ = state.transition(StatusActive, time.Now().UTC())
+	s.corridors.Set(state.corridor.ID, state)
+	s.startDrift(state)
+	s.journalLocked(opAllocate, state)
+}
+
+// Get returns a corridor by ID
+func (s *CorridorService) Get(id string) (*Corridor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state, exists := s.corridors.Get(id)
+	if !exists {
+		return nil, fmt.Errorf("corridor %s %w", id, ErrNotFound)
+	}
+	corridor := state.corridor
+	return &corridor, nil
+}
+
+// List returns the corridors matching sel ordered by creation time
+func (s *CorridorService) List(sel labels.Selector) []Corridor {
+	s.mu.RLock()
+	corridors := make([]Corridor, 0, s.corridors.Len())
+	for _, state := range s.corridors.Values() {
+		if sel.Matches(state.corridor.Labels) {
+			corridors = append(corridors, state.corridor)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(corridors, func(i, j int) bool {
+		if corridors[i].CreatedAt.Equal(corridors[j].CreatedAt) {
+			return corridors[i].ID < corridors[j].ID
+		}
+		return corridors[i].CreatedAt.Before(corridors[j].CreatedAt)
+	})
+	return corridors
+}
+
+// Telemetry returns a telemetry sample for a corridor
+func (s *CorridorService) Telemetry(id string) (*Telemetry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, exists := s.corridors.Get(id)
+	if !exists {
+		return nil, fmt.Errorf("corridor %s %w", id, ErrNotFound)
+	}
+
+	t := state.measure()
+	if fault := s.faults.Take(FaultBER, id); fault != nil {
+		t.BER = fault.BER
+	}
+	if state.observe(&t) {
+		s.journalLocked(opDegrade, state)
+	}
+	t.Seq, t.SampledAt = state.seq, state.sampledAt
+	return &t, nil
+}
+
+// measure reads the live link state, which the drift goroutine evolves.
+// The caller must hold the store lock.
+func (state *corridorState) measure() Telemetry {
+	t := state.telemetry
+	t.UtilizationPercent = math.Min(100, float64(state.corridor.MinGbps)/float64(state.corridor.AchievableGbps)*100*(0.9+mrand.Float64()*0.2))
+	return t
+}
+
+// observe applies a reading to the corridor: errors accumulate above 1e-9
+// and a BER above the threshold degrades an active corridor. It also fills
+// in the reading's derived metrics, and reports whether the reading degraded
+// the corridor. The caller must hold the store lock.
+func (state *corridorState) observe(t *Telemetry) (degraded bool) {
+	if t.BER > 1e-9 {
+		state.telemetry.ErrorCount++
+	}
+	t.ErrorCount = state.telemetry.ErrorCount
+	if t.BER > state.berThreshold && state.corridor.Status == StatusActive {
+		degraded = state.transition(StatusDegraded, time.Now().UTC()) == nil
+	}
+	state.derive(t)
+	return degraded
+}
+
+// Recalibrate runs a HELIOPASS-style calibration loop against the corridor
+func (s *CorridorService) Recalibrate(id string, req RecalibrateRequest) (*RecalibrateResponse, error) {
+	if req.TargetBER <= 0 || req.TargetBER >= 1 {
+		return nil, fmt.Errorf("target_ber must be in (0, 1)")
+	}
+	if req.AmbientProfile == "" {
+		req.AmbientProfile = "lab_default"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, exists := s.corridors.Get(id)
+	if !exists {
+		return nil, fmt.Errorf("corridor %s %w", id, ErrNotFound)
+	}
+
+	start := time.Now()
+	if err := state.transition(StatusRecalibrating, start.UTC()); err != nil {
+		return nil, err
+	}
+	lanes := state.corridor.Lanes
+	biasVoltages := make([]float64, lanes)
+	lambdaShifts := make([]float64, lanes)
+	laserPowerAdjust := make([]float64, lanes)
+
+	// Nudge bias and λ within plan until BER settles near target
+	ber := state.telemetry.BER
+	iterations := 0
+	for ; iterations < 50 && ber > req.TargetBER*1.1; iterations++ {
+		ber = req.TargetBER + (ber-req.TargetBER)*0.5
+	}
+	for i := 0; i < lanes; i++ {
+		biasVoltages[i] = 5.0 + (mrand.Float64()-0.5)*0.5
+		lambdaShifts[i] = 0.05 + (mrand.Float64()-0.5)*0.01
+		laserPowerAdjust[i] = -(mrand.Float64() * 0.3)
+	}
+	converged := ber <= req.TargetBER*1.1
+
+	eyeMargin := linkmodel.EyeMarginFromBER(ber)
+	state.resetDrift(ber)
+	state.corridor.BER = ber
+	state.corridor.EyeMargin = eyeMarginClass(ber)
+
+	status := "converged"
+	next := StatusActive
+	if !converged {
+		status = "partial_convergence"
+		next = StatusFailed
+	}
+	if err := state.transition(next, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	s.journalLocked(opRecalibrate, state)
+
+	savings := 0.0
+	for _, p := range laserPowerAdjust {
+		savings += -p * 5.0
+	}
+	savings /= float64(lanes)
+
+	return &RecalibrateResponse{
+		Status:            status,
+		Converged:         converged,
+		BiasVoltages:      biasVoltages,
+		LambdaShifts:      lambdaShifts,
+		LaserPowerAdjust:  laserPowerAdjust,
+		ConvergenceTimeMs: time.Since(start).Milliseconds() + int64(iterations)*10,
+		FinalBER:          ber,
+		FinalEyeMargin:    eyeMargin,
+		PowerSavings:      savings,
+	}, nil
+}
+
+// Release frees a corridor and removes it from the store
+func (s *CorridorService) Release(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, exists := s.corridors.Get(id)
+	if !exists {
+		return fmt.Errorf("corridor %s %w", id, ErrNotFound)
+	}
+	if err := state.transition(StatusReleased, time.Now().UTC()); err != nil {
+		return err
+	}
+	s.lambdas.release(state.corridor.Domain, state.corridor.LambdaNm, id)
+	state.stopDrift()
+	s.corridors.Delete(id)
+	s.journalLocked(opRelease, state)
+	return nil
+}
+
+// HTTP handlers
+func (s *CorridorService) handleAllocate(w http.ResponseWriter, r *http.Request) {
+	var req AllocateRequest
+	if name := r.URL.Query().Get("preset"); name != "" {
+		preset, err := s.presetRequest(name)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		// Fields the body sets override the template; an empty body
+		// allocates the template as is
+		req = preset
+		if err := jsonbody.Decode(r.Body, &req); err != nil && !errors.Is(err, jsonbody.ErrEmpty) {
+			apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+			return
+		}
+	} else if err := jsonbody.Decode(r.Body, &req); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dryRun {
+		s.handleCheckAllocation(w, req)
+		return
+	}
+
+	corridor, err := s.Allocate(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, corridor)
+}
+
+func (s *CorridorService) handleList(w http.ResponseWriter, r *http.Request) {
+	sel, err := labels.FromQuery(r.URL.Query())
+	if err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, s.List(sel))
+}
+
+func (s *CorridorService) handleGet(w http.ResponseWriter, r *http.Request) {
+	corridor, err := s.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, corridor)
+}
+
+func (s *CorridorService) handleTelemetry(w http.ResponseWriter, r *http.Request) {
+	telemetry, err := s.Telemetry(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if protowire.Accepts(r) {
+		protowire.Write(w, http.StatusOK, telemetry)
+		return
+	}
+	writeJSON(w, http.StatusOK, telemetry)
+}
+
+func (s *CorridorService) handleTelemetryHistory(w http.ResponseWriter, r *http.Request) {
+	var from, to time.Time
+	for name, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			apierr.Respond(w, apierr.CodeBadRequest, name+" must be an RFC3339 timestamp")
+			return
+		}
+		*dst = t
+	}
+
+	samples, err := s.TelemetryHistory(mux.Vars(r)["id"], from, to)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, samples)
+}
+
+func (s *CorridorService) handleRecalibrate(w http.ResponseWriter, r *http.Request) {
+	var req RecalibrateRequest
+	if err := jsonbody.Decode(r.Body, &req); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	resp, err := s.Recalibrate(mux.Vars(r)["id"], req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *CorridorService) handleTransition(w http.ResponseWriter, r *http.Request) {
+	var req TransitionRequest
+	if err := jsonbody.Decode(r.Body, &req); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	corridor, err := s.Transition(mux.Vars(r)["id"], req.Status)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, corridor)
+}
+
+func (s *CorridorService) handleRelease(w http.ResponseWriter, r *http.Request) {
+	if err := s.Release(mux.Vars(r)["id"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *CorridorService) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// writeError maps service errors onto API error codes
+func writeError(w http.ResponseWriter, err error) {
+	code := apierr.CodeValidation
+	var infeasible *InfeasibleError
+	if errors.As(err, &infeasible) {
+		code = apierr.CodeInfeasible
+		if errors.Is(err, ErrWavelengthConflict) {
+			code = apierr.CodeConflict
+		}
+		apierr.Write(w, apierr.New(code, "%s", err.Error()).WithDetails(infeasible.Checks))
+		return
+	}
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, reservations.ErrUnknown):
+		code = apierr.CodeNotFound
+	case errors.Is(err, ErrInfeasible):
+		code = apierr.CodeInfeasible
+	case errors.Is(err, ErrIllegalTransition), errors.Is(err, ErrWavelengthConflict), errors.Is(err, reservations.ErrExpired),
+		errors.Is(err, ErrPresetExists):
+		code = apierr.CodeConflict
+	}
+	apierr.Respond(w, code, err.Error())
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// newRouter wires the corrd HTTP API
+func newRouter(s *CorridorService) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(trace.Middleware("corrd"))
+	api := router.PathPrefix("/v1/corridors").Subrouter()
+	api.Use(checkIDs, s.injectFaults)
+
+	api.HandleFunc("", s.handleAllocate).Methods("POST")
+	api.HandleFunc("", s.handleList).Methods("GET")
+	api.HandleFunc("/capacity", s.handleCapacity).Methods("GET")
+	api.HandleFunc("/link-budget", s.handleLinkBudget).Methods("POST")
+	api.HandleFunc("/presets", s.handleRegisterPreset).Methods("POST")
+	api.HandleFunc("/presets", s.handleListPresets).Methods("GET")
+	api.HandleFunc("/reserve", s.handleReserve).Methods("POST")
+	api.HandleFunc("/reservations/{token}/commit", s.handleCommit).Methods("POST")
+	api.HandleFunc("/reservations/{token}", s.handleAbort).Methods("DELETE")
+	api.HandleFunc("/{id}", s.handleGet).Methods("GET")
+	api.HandleFunc("/{id}", s.handleRelease).Methods("DELETE")
+	api.HandleFunc("/{id}/telemetry", s.handleTelemetry).Methods("GET")
+	api.HandleFunc("/{id}/telemetry/history", s.handleTelemetryHistory).Methods("GET")
+	api.HandleFunc("/{id}/recalibrate", s.handleRecalibrate).Methods("POST")
+	api.HandleFunc("/{id}/transition", s.handleTransition).Methods("POST")
+	router.HandleFunc("/v1/plan", s.handlePlan).Methods("POST")
+
+	admin := router.PathPrefix("/v1/admin").Subrouter()
+	if s.journal != nil {
+		admin.HandleFunc("/journal", journal.Handler(s.journal)).Methods("GET")
+	}
+	if s.FaultsEnabled {
+		admin.HandleFunc("/faults", s.handleAddFault).Methods("POST")
+		admin.HandleFunc("/faults", s.handleListFaults).Methods("GET")
+		admin.HandleFunc("/faults", s.handleClearFaults).Methods("DELETE")
+	}
+
+	router.HandleFunc("/health", s.handleHealth).Methods("GET")
+	return router
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate chain; serves HTTPS with HTTP/2 instead of plaintext HTTP")
+	tlsKey := flag.String("tls-key", "", "PEM private key of -tls-cert")
+	tlsEndorsement := flag.String("tls-endorsement", "", "post-quantum endorsement of -tls-cert, made with tls-endorse, sent on every response")
+	sampleInterval := flag.Duration("sample-interval", time.Second, "telemetry history sample interval")
+	driftInterval := flag.Duration("drift-interval", time.Second, "period of simulated link drift per corridor")
+	maxCorridors := flag.Int("max-corridors", defaultMaxCorridors, "corridors held before failed ones are evicted, least recently used first")
+	historyLength := flag.Int("history-length", 3600, "telemetry samples retained per corridor")
+	powerBudget := flag.Float64("power-budget-w", 0, "power budget plans are checked against (0 = unchecked)")
+	bandwidthBudget := flag.Float64("bandwidth-budget-gbps", 0, "bandwidth budget plans are checked against (0 = unchecked)")
+	memqosURL := flag.String("memqosd-url", "http://localhost:8081", "memqosd endpoint used to price FFM allocations in plans")
+	reservationTTL := flag.Duration("reservation-ttl", 30*time.Second, "default time an uncommitted reservation holds its wavelengths")
+	linkModel := flag.String("link-model", linkmodel.Default, "BER model for requests that do not choose one ("+strings.Join(linkmodel.Names(), "|")+")")
+	enableFaults := flag.Bool("enable-faults", false, "mount the fault-injection admin API (never in production)")
+	journalPath := flag.String("journal", "", "append-only operation journal replayed on startup (empty = none)")
+	flag.Parse()
+	if *sampleInterval <= 0 || *driftInterval <= 0 || *historyLength <= 0 || *reservationTTL <= 0 || *maxCorridors <= 0 {
+		log.Fatal("sample-interval, drift-interval, history-length, reservation-ttl and max-corridors must be positive")
+	}
+	if _, err := linkmodel.Lookup(*linkModel); err != nil {
+		log.Fatal(err)
+	}
+
+	service := NewCorridorService()
+	service.SampleInterval = *sampleInterval
+	service.DriftInterval = *driftInterval
+	service.HistoryLength = *historyLength
+	service.corridors.MaxEntries = *maxCorridors
+	service.PowerBudgetW = *powerBudget
+	service.BandwidthBudgetGbps = *bandwidthBudget
+	service.MemQoSURL = *memqosURL
+	service.ReservationTTL = *reservationTTL
+	service.LinkModel = *linkModel
+	service.FaultsEnabled = *enableFaults
+	if service.FaultsEnabled {
+		log.Println("WARNING: fault injection enabled")
+	}
+	if *journalPath != "" {
+		if err := service.OpenJournal(*journalPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	go service.RunSampler(context.Background())
+
+	tlsCfg := tlsserve.Config{CertFile: *tlsCert, KeyFile: *tlsKey, EndorsementFile: *tlsEndorsement}
+	log.Printf("corrd listening on %s", *addr)
+	log.Fatal(tlsserve.ListenAndServe(*addr, newRouter(service), tlsCfg))
+}
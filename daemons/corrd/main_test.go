@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// do sends a JSON request to the corrd router and decodes the response into out
+func do(t *testing.T, srv *httptest.Server, method, path string, body, out any) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, srv.URL+path, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decode: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// post sends a JSON request expecting status want and returns the response body
+func post(t *testing.T, srv *httptest.Server, path string, body any, want int) string {
+	t.Helper()
+	buf, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Client().Post(srv.URL+path, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != want {
+		t.Fatalf("POST %s: status = %d, want %d: %s", path, resp.StatusCode, want, out)
+	}
+	return string(out)
+}
+
+func allocateRequest() AllocateRequest {
+	return AllocateRequest{
+		CorridorType:    "SiCorridor",
+		Lanes:           4,
+		LambdaNm:        []int{1550, 1551, 1552, 1553},
+		MinGbps:         150,
+		LatencyBudgetNs: 250,
+		ReachMm:         50,
+		Mode:            "waveguide",
+		QoS:             QoSConfig{PFC: true, Priority: "gold"},
+	}
+}
+
+func TestCorridorLifecycle(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	var corridor Corridor
+	if code := do(t, srv, "POST", "/v1/corridors", allocateRequest(), &corridor); code != http.StatusCreated {
+		t.Fatalf("allocate status = %d", code)
+	}
+	if corridor.ID == "" || corridor.AchievableGbps != 4*52 || corridor.Status != "active" {
+		t.Fatalf("allocated corridor = %+v", corridor)
+	}
+	path := "/v1/corridors/" + corridor.ID
+
+	var list []Corridor
+	if code := do(t, srv, "GET", "/v1/corridors", nil, &list); code != http.StatusOK || len(list) != 1 || list[0].ID != corridor.ID {
+		t.Fatalf("list = %d %+v", code, list)
+	}
+
+	var telemetry Telemetry
+	if code := do(t, srv, "GET", path+"/telemetry", nil, &telemetry); code != http.StatusOK {
+		t.Fatalf("telemetry status = %d", code)
+	}
+	if telemetry.BER <= 0 || telemetry.UtilizationPercent <= 0 {
+		t.Errorf("telemetry = %+v", telemetry)
+	}
+
+	var recal RecalibrateResponse
+	req := RecalibrateRequest{TargetBER: 1e-12}
+	if code := do(t, srv, "POST", path+"/recalibrate", req, &recal); code != http.StatusOK {
+		t.Fatalf("recalibrate status = %d", code)
+	}
+	if !recal.Converged || recal.FinalBER > req.TargetBER*1.1 || len(recal.BiasVoltages) != corridor.Lanes {
+		t.Errorf("recalibrate = %+v", recal)
+	}
+
+	var got Corridor
+	if code := do(t, srv, "GET", path, nil, &got); code != http.StatusOK || got.BER != recal.FinalBER {
+		t.Errorf("get after recalibrate = %d %+v, want BER %g", code, got, recal.FinalBER)
+	}
+
+	if code := do(t, srv, "DELETE", path, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("delete status = %d", code)
+	}
+	for _, p := range []string{path, path + "/telemetry"} {
+		if code := do(t, srv, "GET", p, nil, nil); code != http.StatusNotFound {
+			t.Errorf("GET %s after delete = %d, want 404", p, code)
+		}
+	}
+	if code := do(t, srv, "DELETE", path, nil, nil); code != http.StatusNotFound {
+		t.Errorf("second delete = %d, want 404", code)
+	}
+}
+
+func TestAllocateRejectsInvalidRequests(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	for name, mutate := range map[string]func(*AllocateRequest){
+		"unknown type":   func(r *AllocateRequest) { r.CorridorType = "Copper" },
+		"lambda count":   func(r *AllocateRequest) { r.LambdaNm = r.LambdaNm[:2] },
+		"negative reach": func(r *AllocateRequest) { r.ReachMm = -1 },
+	} {
+		req := allocateRequest()
+		mutate(&req)
+		if code := do(t, srv, "POST", "/v1/corridors", req, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, code)
+		}
+	}
+
+	var list []Corridor
+	do(t, srv, "GET", "/v1/corridors", nil, &list)
+	if len(list) != 0 {
+		t.Errorf("rejected requests left %d corridors", len(list))
+	}
+}
+
+func TestRecalibrateRejectsBadTarget(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	var corridor Corridor
+	do(t, srv, "POST", "/v1/corridors", allocateRequest(), &corridor)
+	if code := do(t, srv, "POST", "/v1/corridors/"+corridor.ID+"/recalibrate", RecalibrateRequest{TargetBER: 2}, nil); code != http.StatusBadRequest {
+		t.Errorf("target_ber 2: status = %d, want 400", code)
+	}
+	if code := do(t, srv, "POST", "/v1/corridors/"+generateID("SiCorridor")+"/recalibrate", RecalibrateRequest{TargetBER: 1e-12}, nil); code != http.StatusNotFound {
+		t.Errorf("unknown corridor: status = %d, want 404", code)
+	}
+}
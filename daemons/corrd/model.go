@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/corridoros/pkg/linkmodel"
+)
+
+// ErrInfeasible marks allocations the link model cannot satisfy
+var ErrInfeasible = errors.New("infeasible allocation")
+
+// defaultBaudGBd is the nominal per-lane symbol rate for each corridor type
+var defaultBaudGBd = map[string]float64{
+	"SiCorridor":     53.125,
+	"CarbonCorridor": 53.125,
+}
+
+// modulationFormat describes a lane modulation scheme
+type modulationFormat struct {
+	BitsPerSymbol  float64 // raw bits per symbol
+	CodingRate     float64 // line/FEC coding efficiency
+	FullRateReach  int     // reach (mm) achievable at full rate
+	DerateFraction float64 // fractional rate lost per mm beyond full-rate reach
+	MinLatencyNs   int     // serialization + FEC latency floor
+}
+
+var modulationFormats = map[string]modulationFormat{
+	"NRZ": {
+		BitsPerSymbol:  1,
+		CodingRate:     0.98,
+		FullRateReach:  100,
+		DerateFraction: 0.004,
+		MinLatencyNs:   20,
+	},
+	"PAM4": {
+		BitsPerSymbol:  2,
+		CodingRate:     0.98,
+		FullRateReach:  60,
+		DerateFraction: 0.008,
+		MinLatencyNs:   80,
+	},
+}
+
+// linkEstimate is the modeled capability of a requested corridor
+type linkEstimate struct {
+	Modulation     string
+	BaudGBd        float64
+	Lanes          int
+	ReachMm        int
+	PerLaneGbps    float64
+	AchievableGbps int
+	BER            float64
+	PowerPjPerBit  float64
+	LinkModel      string // model BER and power came from
+	MinLatencyNs   int    // the format's FEC latency floor
+	MaxReachMm     int    // longest reach still carrying any rate
+}
+
+func (l linkEstimate) String() string {
+	return fmt.Sprintf("%d lanes × %.3f GBd %s at %d mm reach = %.1f Gbps/lane",
+		l.Lanes, l.BaudGBd, l.Modulation, l.ReachMm, l.PerLaneGbps)
+}
+
+// modelLink models the achievable rate of a corridor from lane count, baud
+// rate and modulation. Reach beyond the format's full-rate reach derates each
+// lane; linkChecks holds the request to the latency and reach limits. BER and
+// energy per bit come from the request's link model, or the service's.
+func (s *CorridorService) modelLink(req AllocateRequest) (linkEstimate, error) {
+	modulation := strings.ToUpper(req.Modulation)
+	if modulation == "" {
+		modulation = "NRZ"
+	}
+	format, ok := modulationFormats[modulation]
+	if !ok {
+		return linkEstimate{}, fmt.Errorf("unsupported modulation: %s (NRZ|PAM4)", req.Modulation)
+	}
+
+	baud := req.BaudGBd
+	if baud == 0 {
+		baud = defaultBaudGBd[req.CorridorType]
+	}
+	if baud < 0 || baud > 200 {
+		return linkEstimate{}, fmt.Errorf("baud_gbd must be in (0, 200]")
+	}
+
+	name := req.LinkModel
+	if name == "" {
+		name = s.LinkModel
+	}
+	model, err := linkmodel.Lookup(name)
+	if err != nil {
+		return linkEstimate{}, err
+	}
+	est, err := model.Estimate(linkmodel.Config{
+		Modulation: modulation,
+		BaudGBd:    baud,
+		ReachMm:    req.ReachMm,
+		Lanes:      req.Lanes,
+	}, linkmodel.Conditions{TempC: nominalTempC})
+	if err != nil {
+		return linkEstimate{}, err
+	}
+
+	derate := 1.0
+	if excess := req.ReachMm - format.FullRateReach; excess > 0 {
+		derate = math.Max(0, 1-float64(excess)*format.DerateFraction)
+	}
+
+	perLane := baud * format.BitsPerSymbol * format.CodingRate * derate
+	return linkEstimate{
+		Modulation:     modulation,
+		BaudGBd:        baud,
+		Lanes:          req.Lanes,
+		ReachMm:        req.ReachMm,
+		PerLaneGbps:    perLane,
+		AchievableGbps: int(math.Floor(perLane * float64(req.Lanes))),
+		BER:            est.BER,
+		PowerPjPerBit:  est.PowerPjPerBit,
+		LinkModel:      model.Name(),
+		MinLatencyNs:   format.MinLatencyNs,
+		MaxReachMm:     format.FullRateReach + int(math.Round(1/format.DerateFraction)) - 1,
+	}, nil
+}
+
+// eyeMarginClass summarizes the eye margin for a BER
+func eyeMarginClass(ber float64) string {
+	switch {
+	case ber <= 1e-12:
+		return "ok"
+	case ber <= 1e-9:
+		return "marginal"
+	default:
+		return "poor"
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPAM4DoublesNRZ(t *testing.T) {
+	req := allocateRequest()
+	req.ReachMm = 40 // within both formats' full-rate reach
+
+	req.Modulation = "NRZ"
+	nrz, err := NewCorridorService().modelLink(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Modulation = "pam4"
+	pam4, err := NewCorridorService().modelLink(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pam4.Modulation != "PAM4" {
+		t.Errorf("modulation = %q, want PAM4", pam4.Modulation)
+	}
+	ratio := float64(pam4.AchievableGbps) / float64(nrz.AchievableGbps)
+	if ratio < 1.95 || ratio > 2.05 {
+		t.Errorf("PAM4/NRZ = %d/%d Gbps = %.3f, want ~2", pam4.AchievableGbps, nrz.AchievableGbps, ratio)
+	}
+}
+
+func TestReachDeratesRate(t *testing.T) {
+	req := allocateRequest()
+	req.Modulation = "PAM4"
+	req.ReachMm = 60
+	short, _ := NewCorridorService().modelLink(req)
+	req.ReachMm = 120
+	long, _ := NewCorridorService().modelLink(req)
+	if long.AchievableGbps >= short.AchievableGbps {
+		t.Errorf("120 mm reach = %d Gbps, not below 60 mm reach = %d Gbps", long.AchievableGbps, short.AchievableGbps)
+	}
+}
+
+func TestLatencyBudgetBelowFECLatencyIsInfeasible(t *testing.T) {
+	req := allocateRequest()
+	req.Modulation = "PAM4"
+	req.LatencyBudgetNs = 50
+	if _, err := NewCorridorService().Allocate(req); !errors.Is(err, ErrInfeasible) {
+		t.Errorf("Allocate error = %v, want ErrInfeasible", err)
+	}
+}
+
+func TestImpossibleMinGbpsRejected(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	req := allocateRequest()
+	req.MinGbps = 1000
+	if _, err := NewCorridorService().Allocate(req); !errors.Is(err, ErrInfeasible) {
+		t.Errorf("Allocate error = %v, want ErrInfeasible", err)
+	}
+	if code := do(t, srv, "POST", "/v1/corridors", req, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", code)
+	}
+}
+
+func TestLinkModelSelection(t *testing.T) {
+	s := NewCorridorService()
+	req := allocateRequest()
+	heuristic, err := s.Allocate(req)
+	if err != nil || heuristic.LinkModel != "heuristic" {
+		t.Fatalf("default model: %+v, %v", heuristic, err)
+	}
+
+	req.LambdaNm = []int{1560, 1561, 1562, 1563}
+	req.LinkModel = "qfactor"
+	qfactor, err := s.Allocate(req)
+	if err != nil || qfactor.LinkModel != "qfactor" || qfactor.BER == heuristic.BER {
+		t.Errorf("requested qfactor model: %+v, %v", qfactor, err)
+	}
+
+	s.LinkModel = "qfactor"
+	if link, err := s.modelLink(allocateRequest()); err != nil || link.LinkModel != "qfactor" {
+		t.Errorf("service default qfactor: %+v, %v", link, err)
+	}
+
+	req.LinkModel = "ideal"
+	if _, err := s.Allocate(req); err == nil {
+		t.Error("unknown link model accepted")
+	}
+}
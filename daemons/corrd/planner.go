@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/jsonbody"
+	"github.com/corridoros/pkg/trace"
+)
+
+// nominalPjPerBit is the link energy of the default link model, the
+// reference telemetry scores a corridor's efficiency against
+const nominalPjPerBit = 0.9
+
+// PlanRequest is a proposed set of corridor and FFM allocations
+type PlanRequest struct {
+	Corridors []AllocateRequest `json:"corridors"`
+	FFM       []json.RawMessage `json:"ffm"` // memqosd allocation requests, forwarded as-is
+}
+
+// CorridorEstimate is the projected cost of one proposed corridor
+type CorridorEstimate struct {
+	CorridorType   string            `json:"corridor_type"`
+	Domain         string            `json:"domain"`
+	AchievableGbps int               `json:"achievable_gbps"`
+	ThroughputGbps int               `json:"throughput_gbps"`
+	PowerW         float64           `json:"power_w"`
+	Feasible       bool              `json:"feasible"`
+	Reason         string            `json:"reason,omitempty"`
+	Checks         []ConstraintCheck `json:"checks,omitempty"`
+}
+
+// FFMEstimate is memqosd's projected cost of one proposed FFM allocation
+type FFMEstimate struct {
+	LatencyClass       string  `json:"latency_class"`
+	Tier               string  `json:"tier,omitempty"`
+	BandwidthFloorGBs  uint64  `json:"bandwidth_floor_GBs"`
+	BandwidthFloorGbps float64 `json:"bandwidth_floor_gbps"`
+	PowerW             float64 `json:"power_w"`
+	Feasible           bool    `json:"feasible"`
+	Reason             string  `json:"reason,omitempty"`
+}
+
+// PlanResult is the projected aggregate of a plan against the budgets
+type PlanResult struct {
+	Corridors           []CorridorEstimate `json:"corridors"`
+	FFM                 []FFMEstimate      `json:"ffm"`
+	TotalPowerW         float64            `json:"total_power_w"`
+	TotalBandwidthGbps  float64            `json:"total_bandwidth_gbps"`
+	PowerBudgetW        float64            `json:"power_budget_w,omitempty"`
+	BandwidthBudgetGbps float64            `json:"bandwidth_budget_gbps,omitempty"`
+	Fits                bool               `json:"fits"`
+	Violations          []string           `json:"violations,omitempty"`
+}
+
+// Plan projects the power and bandwidth of a proposed set of allocations
+// without committing any of them. Corridors are modeled locally; FFM
+// requests are estimated by memqosd. Throughput is taken at each request's
+// floor, memory bandwidth in the gigabits/s memqosd converts it to.
+func (s *CorridorService) Plan(ctx context.Context, req PlanRequest) (*PlanResult, error) {
+	result := &PlanResult{
+		Corridors:           make([]CorridorEstimate, 0, len(req.Corridors)),
+		FFM:                 []FFMEstimate{},
+		PowerBudgetW:        s.PowerBudgetW,
+		BandwidthBudgetGbps: s.BandwidthBudgetGbps,
+		Fits:                true,
+	}
+
+	s.mu.RLock()
+	planned := make(wavelengthPlan)
+	for domain, held := range s.lambdas {
+		for nm, id := range held {
+			planned.reserve(domain, []int{nm}, id)
+		}
+	}
+	s.mu.RUnlock()
+
+	for i, c := range req.Corridors {
+		est := s.estimateCorridor(c, planned, fmt.Sprintf("plan-%d", i))
+		if !est.Feasible {
+			result.Fits = false
+			result.Violations = append(result.Violations, fmt.Sprintf("corridors[%d]: %s", i, est.Reason))
+		}
+		result.TotalPowerW += est.PowerW
+		result.TotalBandwidthGbps += float64(est.ThroughputGbps)
+		result.Corridors = append(result.Corridors, est)
+	}
+
+	if len(req.FFM) > 0 {
+		ffm, err := s.estimateFFM(ctx, req.FFM)
+		if err != nil {
+			return nil, err
+		}
+		for i, est := range ffm {
+			if !est.Feasible {
+				result.Fits = false
+				result.Violations = append(result.Violations, fmt.Sprintf("ffm[%d]: %s", i, est.Reason))
+			}
+			result.TotalPowerW += est.PowerW
+			result.TotalBandwidthGbps += est.BandwidthFloorGbps
+		}
+		result.FFM = ffm
+	}
+
+	if s.PowerBudgetW > 0 && result.TotalPowerW > s.PowerBudgetW {
+		result.Fits = false
+		result.Violations = append(result.Violations, fmt.Sprintf("projected power %.2f W exceeds budget %.2f W", result.TotalPowerW, s.PowerBudgetW))
+	}
+	if s.BandwidthBudgetGbps > 0 && result.TotalBandwidthGbps > s.BandwidthBudgetGbps {
+		result.Fits = false
+		result.Violations = append(result.Violations, fmt.Sprintf("projected bandwidth %.0f Gbps exceeds budget %.0f Gbps", result.TotalBandwidthGbps, s.BandwidthBudgetGbps))
+	}
+	return result, nil
+}
+
+// estimateCorridor models one proposed corridor, reserving its wavelengths
+// in planned so later entries of the same plan see them
+func (s *CorridorService) estimateCorridor(req AllocateRequest, planned wavelengthPlan, planID string) CorridorEstimate {
+	if req.Domain == "" {
+		req.Domain = defaultDomain
+	}
+	est := CorridorEstimate{CorridorType: req.CorridorType, Domain: req.Domain}
+
+	err := validateAllocation(req)
+	var link linkEstimate
+	if err == nil {
+		link, err = s.modelLink(req)
+	}
+	if err == nil {
+		est.Checks = append(linkChecks(req, link), wavelengthCheck(planned, req.Domain, req.LambdaNm))
+		err = newInfeasibleError(est.Checks)
+	}
+	if err != nil {
+		est.Reason = err.Error()
+		return est
+	}
+
+	planned.reserve(req.Domain, req.LambdaNm, planID)
+	est.AchievableGbps = link.AchievableGbps
+	est.ThroughputGbps = req.MinGbps
+	est.PowerW = link.PowerPjPerBit * float64(req.MinGbps) * 1e-3
+	est.Feasible = true
+	return est
+}
+
+// estimateFFM asks memqosd to price the FFM side of a plan, passing on the
+// trace ID in ctx
+func (s *CorridorService) estimateFFM(ctx context.Context, reqs []json.RawMessage) ([]FFMEstimate, error) {
+	if s.MemQoSURL == "" {
+		return nil, fmt.Errorf("plan includes FFM allocations but no memqosd endpoint is configured")
+	}
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.MemQoSURL+"/v1/ffm/estimate", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	trace.Inject(httpReq)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("memqosd estimate: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("memqosd estimate: HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	var estimates []FFMEstimate
+	if err := json.NewDecoder(resp.Body).Decode(&estimates); err != nil {
+		return nil, fmt.Errorf("memqosd estimate: %w", err)
+	}
+	return estimates, nil
+}
+
+func (s *CorridorService) handlePlan(w http.ResponseWriter, r *http.Request) {
+	var req PlanRequest
+	if err := jsonbody.Decode(r.Body, &req); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	result, err := s.Plan(r.Context(), req)
+	if err != nil {
+		trace.Logf(r.Context(), "plan: %v", err)
+		apierr.Respond(w, apierr.CodeUpstream, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/corridoros/pkg/trace"
+)
+
+func TestPlanInBudgetReturnsTotals(t *testing.T) {
+	s := NewCorridorService()
+	s.PowerBudgetW = 1
+	s.BandwidthBudgetGbps = 1000
+
+	second := allocateRequest()
+	second.LambdaNm = []int{1560, 1561, 1562, 1563}
+	second.MinGbps = 100
+	result, err := s.Plan(context.Background(), PlanRequest{Corridors: []AllocateRequest{allocateRequest(), second}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Fits || len(result.Violations) != 0 {
+		t.Fatalf("in-budget plan flagged: %+v", result.Violations)
+	}
+	if result.TotalBandwidthGbps != 250 {
+		t.Errorf("total bandwidth = %g Gbps, want 250", result.TotalBandwidthGbps)
+	}
+	if want := nominalPjPerBit * 250 * 1e-3; math.Abs(result.TotalPowerW-want) > 1e-12 {
+		t.Errorf("total power = %g W, want %g", result.TotalPowerW, want)
+	}
+	if len(s.List(nil)) != 0 {
+		t.Error("planning committed corridors")
+	}
+}
+
+func TestPlanOverBudgetFlagged(t *testing.T) {
+	s := NewCorridorService()
+	s.BandwidthBudgetGbps = 200
+
+	second := allocateRequest()
+	second.LambdaNm = []int{1560, 1561, 1562, 1563}
+	result, err := s.Plan(context.Background(), PlanRequest{Corridors: []AllocateRequest{allocateRequest(), second, allocateRequest()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Fits {
+		t.Fatal("over-budget plan fits")
+	}
+	var budget, conflict bool
+	for _, v := range result.Violations {
+		budget = budget || strings.Contains(v, "exceeds budget")
+		conflict = conflict || strings.HasPrefix(v, "corridors[2]") && strings.Contains(v, "wavelength conflict")
+	}
+	if !budget || !conflict {
+		t.Errorf("violations = %q, want the bandwidth budget and corridors[2]'s wavelength conflict", result.Violations)
+	}
+}
+
+func TestPlanPricesFFMWithMemqosd(t *testing.T) {
+	memqosd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/ffm/estimate" {
+			http.NotFound(w, r)
+			return
+		}
+		var reqs []json.RawMessage
+		json.NewDecoder(r.Body).Decode(&reqs)
+		estimates := make([]FFMEstimate, len(reqs))
+		for i := range estimates {
+			estimates[i] = FFMEstimate{LatencyClass: "T1", BandwidthFloorGBs: 10, BandwidthFloorGbps: 80, PowerW: 3.3, Feasible: true}
+		}
+		json.NewEncoder(w).Encode(estimates)
+	}))
+	defer memqosd.Close()
+
+	s := NewCorridorService()
+	s.MemQoSURL = memqosd.URL
+	s.PowerBudgetW = 5
+
+	ffm := []json.RawMessage{json.RawMessage(`{"bytes":1}`), json.RawMessage(`{"bytes":2}`)}
+	result, err := s.Plan(context.Background(), PlanRequest{FFM: ffm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.FFM) != 2 || result.TotalBandwidthGbps != 160 || math.Abs(result.TotalPowerW-6.6) > 1e-9 {
+		t.Errorf("plan = %+v", result)
+	}
+	if result.Fits {
+		t.Error("6.6 W of FFM fits a 5 W budget")
+	}
+
+	s.MemQoSURL = ""
+	if _, err := s.Plan(context.Background(), PlanRequest{FFM: ffm}); err == nil {
+		t.Error("FFM plan without a memqosd endpoint succeeded")
+	}
+}
+
+func TestPlanPropagatesTraceID(t *testing.T) {
+	var downstream string
+	memqosd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header.Get(trace.Header)
+		json.NewEncoder(w).Encode([]FFMEstimate{{LatencyClass: "T1", Feasible: true}})
+	}))
+	defer memqosd.Close()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	s := NewCorridorService()
+	s.MemQoSURL = memqosd.URL
+	srv := httptest.NewServer(newRouter(s))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("POST", srv.URL+"/v1/plan", strings.NewReader(`{"ffm":[{"bytes":1}]}`))
+	req.Header.Set(trace.Header, "op-1234")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(trace.Header) != "op-1234" {
+		t.Errorf("response %d, trace header %q", resp.StatusCode, resp.Header.Get(trace.Header))
+	}
+	if downstream != "op-1234" {
+		t.Errorf("memqosd saw trace ID %q, want op-1234", downstream)
+	}
+	if !strings.Contains(logs.String(), "corrd trace=op-1234 POST /v1/plan 200") {
+		t.Errorf("corrd log does not carry the trace ID:\n%s", logs.String())
+	}
+}
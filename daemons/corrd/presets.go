@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/jsonbody"
+)
+
+// maxPresetName bounds the length of a preset name
+const maxPresetName = 63
+
+// ErrPresetExists marks registering a preset under a name already in use
+var ErrPresetExists = errors.New("preset already exists")
+
+// Preset is a named allocation template. Allocating from it with
+// POST /v1/corridors?preset=<name> starts from Template and applies the
+// fields set in the request body on top.
+type Preset struct {
+	Name      string          `json:"name"`
+	Template  AllocateRequest `json:"template"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// RegisterPreset validates and models a template as if it were allocated,
+// so a preset that could never be allocated is refused up front, and
+// stores it under its name. Wavelength availability is checked only when
+// allocating from it.
+func (s *CorridorService) RegisterPreset(p Preset) (*Preset, error) {
+	if err := validatePresetName(p.Name); err != nil {
+		return nil, err
+	}
+	if _, err := s.prepareAllocation(p.Template); err != nil {
+		return nil, fmt.Errorf("preset %s: %w", p.Name, err)
+	}
+	p.Template = cloneAllocation(p.Template)
+	p.CreatedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.presets[p.Name]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrPresetExists, p.Name)
+	}
+	s.presets[p.Name] = p
+	return &p, nil
+}
+
+// Presets lists the registered presets by name
+func (s *CorridorService) Presets() []Preset {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Preset, 0, len(s.presets))
+	for _, p := range s.presets {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// presetRequest returns a copy of a preset's template that overrides can
+// be decoded over without touching the stored preset
+func (s *CorridorService) presetRequest(name string) (AllocateRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.presets[name]
+	if !ok {
+		return AllocateRequest{}, fmt.Errorf("preset %s %w", name, ErrNotFound)
+	}
+	return cloneAllocation(p.Template), nil
+}
+
+// cloneAllocation deep-copies a request's slices, maps and pointers.
+// Decoding JSON into a request reuses them, so a template must be cloned
+// before overrides are decoded over it.
+func cloneAllocation(req AllocateRequest) AllocateRequest {
+	req.LambdaNm = slices.Clone(req.LambdaNm)
+	req.Labels = maps.Clone(req.Labels)
+	if req.AttestationTicket != nil {
+		ticket := *req.AttestationTicket
+		req.AttestationTicket = &ticket
+	}
+	return req
+}
+
+func validatePresetName(name string) error {
+	if name == "" || len(name) > maxPresetName {
+		return fmt.Errorf("preset name %q must be 1 to %d bytes", name, maxPresetName)
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.", r)) {
+			return fmt.Errorf("preset name %q may only contain letters, digits and -_.", name)
+		}
+	}
+	return nil
+}
+
+func (s *CorridorService) handleRegisterPreset(w http.ResponseWriter, r *http.Request) {
+	var p Preset
+	if err := jsonbody.Decode(r.Body, &p); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	preset, err := s.RegisterPreset(p)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, preset)
+}
+
+func (s *CorridorService) handleListPresets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Presets())
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestAllocateFromPreset(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	template := allocateRequest()
+	template.Labels = map[string]string{"team": "fabric"}
+	if code := do(t, srv, "POST", "/v1/corridors/presets", Preset{Name: "gold-4", Template: template}, nil); code != http.StatusCreated {
+		t.Fatalf("register status = %d", code)
+	}
+
+	var plain Corridor
+	if code := do(t, srv, "POST", "/v1/corridors?preset=gold-4", nil, &plain); code != http.StatusCreated {
+		t.Fatalf("allocate from preset status = %d", code)
+	}
+	if plain.CorridorType != template.CorridorType || plain.Lanes != template.Lanes || !slices.Equal(plain.LambdaNm, template.LambdaNm) ||
+		plain.MinGbps != template.MinGbps || plain.QoS != template.QoS || plain.Labels["team"] != "fabric" {
+		t.Errorf("corridor from preset = %+v, want template %+v", plain, template)
+	}
+
+	// Body fields override the template; the rest is kept
+	overrides := map[string]any{"lambda_nm": []int{1560, 1561, 1562, 1563}, "min_gbps": 100, "labels": map[string]string{"team": "storage"}}
+	var custom Corridor
+	if code := do(t, srv, "POST", "/v1/corridors?preset=gold-4", overrides, &custom); code != http.StatusCreated {
+		t.Fatalf("allocate with overrides status = %d", code)
+	}
+	if !slices.Equal(custom.LambdaNm, []int{1560, 1561, 1562, 1563}) || custom.MinGbps != 100 || custom.Labels["team"] != "storage" {
+		t.Errorf("overrides not applied: %+v", custom)
+	}
+	if custom.Lanes != template.Lanes || custom.QoS != template.QoS || custom.ReachMm != template.ReachMm {
+		t.Errorf("template fields lost under overrides: %+v", custom)
+	}
+
+	// Overrides do not leak into the stored template
+	var presets []Preset
+	do(t, srv, "GET", "/v1/corridors/presets", nil, &presets)
+	if len(presets) != 1 || !slices.Equal(presets[0].Template.LambdaNm, template.LambdaNm) || presets[0].Template.Labels["team"] != "fabric" {
+		t.Errorf("stored presets = %+v", presets)
+	}
+}
+
+func TestRegisterPresetValidates(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	bad := allocateRequest()
+	bad.LambdaNm = bad.LambdaNm[:1]
+	for name, p := range map[string]Preset{
+		"invalid template": {Name: "bad", Template: bad},
+		"invalid name":     {Name: "gold 4", Template: allocateRequest()},
+		"empty name":       {Template: allocateRequest()},
+	} {
+		if code := do(t, srv, "POST", "/v1/corridors/presets", p, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, code)
+		}
+	}
+
+	p := Preset{Name: "gold-4", Template: allocateRequest()}
+	do(t, srv, "POST", "/v1/corridors/presets", p, nil)
+	if code := do(t, srv, "POST", "/v1/corridors/presets", p, nil); code != http.StatusConflict {
+		t.Errorf("duplicate name: status = %d, want 409", code)
+	}
+	if code := do(t, srv, "POST", "/v1/corridors?preset=missing", nil, nil); code != http.StatusNotFound {
+		t.Errorf("unknown preset: status = %d, want 404", code)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/jsonbody"
+	"github.com/corridoros/pkg/reservations"
+	"github.com/gorilla/mux"
+)
+
+// ReserveRequest is an allocation to hold until it is committed or expires
+type ReserveRequest struct {
+	AllocateRequest
+	TTLSeconds int `json:"ttl_s,omitempty"` // 0 uses the service default
+}
+
+// Reservation is a tentatively held corridor. Its wavelengths are held
+// until the token is committed, aborted or the reservation expires.
+type Reservation struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Corridor  Corridor  `json:"corridor"` // as it will be once committed
+}
+
+// Reserve validates and models an allocation and holds its wavelengths
+// for the TTL without bringing the corridor up
+func (s *CorridorService) Reserve(req ReserveRequest) (*Reservation, error) {
+	ttl, err := reservations.TTL(req.TTLSeconds, s.ReservationTTL)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.prepareAllocation(req.AllocateRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.holdWavelengthsLocked(state); err != nil {
+		return nil, err
+	}
+	token, expiresAt := s.reservations.Hold(state, ttl)
+	return &Reservation{Token: token, ExpiresAt: expiresAt, Corridor: state.corridor}, nil
+}
+
+// Commit brings a reserved corridor up. It fails once the TTL has passed.
+func (s *CorridorService) Commit(token string) (*Corridor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.reservations.Commit(token)
+	if err != nil {
+		return nil, err
+	}
+	s.activateLocked(state)
+
+	corridor := state.corridor
+	return &corridor, nil
+}
+
+// Abort releases a reservation's wavelengths without committing it
+func (s *CorridorService) Abort(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reservations.Abort(token)
+}
+
+// releaseReservation frees the wavelengths of a reservation that expired or
+// was aborted. The reservation table calls it with the store lock held.
+func (s *CorridorService) releaseReservation(state *corridorState) {
+	c := state.corridor
+	s.lambdas.release(c.Domain, c.LambdaNm, c.ID)
+}
+
+func (s *CorridorService) handleReserve(w http.ResponseWriter, r *http.Request) {
+	var req ReserveRequest
+	if err := jsonbody.Decode(r.Body, &req); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	res, err := s.Reserve(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, res)
+}
+
+func (s *CorridorService) handleCommit(w http.ResponseWriter, r *http.Request) {
+	corridor, err := s.Commit(mux.Vars(r)["token"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, corridor)
+}
+
+func (s *CorridorService) handleAbort(w http.ResponseWriter, r *http.Request) {
+	if err := s.Abort(mux.Vars(r)["token"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/corridoros/pkg/reservations"
+)
+
+func TestReserveCommit(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	var res Reservation
+	if code := do(t, srv, "POST", "/v1/corridors/reserve", ReserveRequest{AllocateRequest: allocateRequest()}, &res); code != http.StatusCreated {
+		t.Fatalf("reserve status = %d", code)
+	}
+	// The reservation holds its wavelengths but is not a live corridor
+	post(t, srv, "/v1/corridors", allocateRequest(), http.StatusConflict)
+	if code := do(t, srv, "GET", "/v1/corridors/"+res.Corridor.ID, nil, nil); code != http.StatusNotFound {
+		t.Errorf("reserved corridor visible before commit: %d", code)
+	}
+
+	var corridor Corridor
+	if code := do(t, srv, "POST", "/v1/corridors/reservations/"+res.Token+"/commit", nil, &corridor); code != http.StatusCreated {
+		t.Fatalf("commit status = %d", code)
+	}
+	if corridor.ID != res.Corridor.ID || corridor.Status != StatusActive {
+		t.Errorf("committed corridor = %+v", corridor)
+	}
+	if code := do(t, srv, "POST", "/v1/corridors/reservations/"+res.Token+"/commit", nil, nil); code != http.StatusNotFound {
+		t.Errorf("second commit status = %d, want 404", code)
+	}
+}
+
+func TestReservationExpiresAndReleasesWavelengths(t *testing.T) {
+	s := NewCorridorService()
+	s.ReservationTTL = 20 * time.Millisecond
+	res, err := s.Reserve(ReserveRequest{AllocateRequest: allocateRequest()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := s.Commit(res.Token); !errors.Is(err, reservations.ErrUnknown) {
+		t.Fatalf("commit after TTL = %v, want an expired reservation", err)
+	}
+	if _, err := s.Allocate(allocateRequest()); err != nil {
+		t.Errorf("wavelengths still held after expiry: %v", err)
+	}
+}
+
+func TestAbortReleasesWavelengths(t *testing.T) {
+	s := NewCorridorService()
+	res, err := s.Reserve(ReserveRequest{AllocateRequest: allocateRequest()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Abort(res.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Allocate(allocateRequest()); err != nil {
+		t.Errorf("wavelengths still held after abort: %v", err)
+	}
+	if _, err := s.Reserve(ReserveRequest{AllocateRequest: allocateRequest(), TTLSeconds: 3600}); err == nil {
+		t.Error("TTL above the maximum accepted")
+	}
+}
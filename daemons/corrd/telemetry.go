@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// BERs bounding the signal term of the efficiency score: a link at or
+// below scoreBestBER scores fully, one at or above scoreWorstBER scores zero
+const (
+	scoreBestBER  = 1e-12
+	scoreWorstBER = 1e-6
+)
+
+// TelemetrySample is a timestamped telemetry reading kept in history
+type TelemetrySample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Telemetry
+}
+
+// telemetryRing is a fixed-capacity ring buffer of telemetry samples
+type telemetryRing struct {
+	samples []TelemetrySample
+	next    int
+	full    bool
+}
+
+func newTelemetryRing(capacity int) *telemetryRing {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &telemetryRing{samples: make([]TelemetrySample, capacity)}
+}
+
+// add appends a sample, overwriting the oldest once the ring is full
+func (r *telemetryRing) add(sample TelemetrySample) {
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// between returns the samples within [from, to], oldest first. A zero from
+// or to leaves that side of the range open.
+func (r *telemetryRing) between(from, to time.Time) []TelemetrySample {
+	ordered := r.samples[:r.next]
+	if r.full {
+		ordered = append(append([]TelemetrySample(nil), r.samples[r.next:]...), r.samples[:r.next]...)
+	}
+
+	out := []TelemetrySample{}
+	for _, sample := range ordered {
+		if !from.IsZero() && sample.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && sample.Timestamp.After(to) {
+			continue
+		}
+		out = append(out, sample)
+	}
+	return out
+}
+
+// TelemetryHistory returns a corridor's recorded samples within [from, to]
+func (s *CorridorService) TelemetryHistory(id string, from, to time.Time) ([]TelemetrySample, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state, exists := s.corridors.Get(id)
+	if !exists {
+		return nil, fmt.Errorf("corridor %s %w", id, ErrNotFound)
+	}
+	return state.history.between(from, to), nil
+}
+
+// RunSampler records a telemetry sample for every corridor each
+// SampleInterval until ctx is cancelled
+func (s *CorridorService) RunSampler(ctx context.Context) {
+	ticker := time.NewTicker(s.SampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.sampleAll(s.sampleTime(now))
+		}
+	}
+}
+
+// sampleTime converts a tick to a UTC timestamp measured on the monotonic
+// clock from the service's start, so timestamps keep advancing even if the
+// wall clock is stepped back
+func (s *CorridorService) sampleTime(tick time.Time) time.Time {
+	return s.epoch.Add(tick.Sub(s.epoch)).UTC()
+}
+
+// sampleAll records one sample per corridor, advancing its sequence
+func (s *CorridorService) sampleAll(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, state := range s.corridors.Values() {
+		state.seq++
+		state.sampledAt = now
+		t := state.measure()
+		if state.observe(&t) {
+			s.journalLocked(opDegrade, state)
+		}
+		t.Seq, t.SampledAt = state.seq, state.sampledAt
+		state.history.add(TelemetrySample{Timestamp: now, Telemetry: t})
+	}
+}
+
+// derive fills in a reading's derived metrics from the corridor's
+// configuration. Spectral efficiency is the lane bit rate over a channel
+// bandwidth equal to the symbol rate, so it is bounded by the modulation's
+// bits per symbol. The efficiency score multiplies three 0-1 terms: energy
+// (nominal over measured pJ/bit), spectral (efficiency over bits per symbol)
+// and signal (BER placed on a log scale between scoreWorstBER and
+// scoreBestBER). The caller must hold the store lock.
+func (state *corridorState) derive(t *Telemetry) {
+	c := state.corridor
+	if c.Lanes <= 0 || c.BaudGBd <= 0 {
+		return
+	}
+	t.GbpsPerLane = float64(c.AchievableGbps) / float64(c.Lanes)
+	t.SpectralEfficiency = t.GbpsPerLane / c.BaudGBd
+
+	energy := 1.0
+	if t.PowerPjPerBit > 0 {
+		energy = math.Min(1, nominalPjPerBit/t.PowerPjPerBit)
+	}
+	spectral := 0.0
+	if format, ok := modulationFormats[c.Modulation]; ok {
+		spectral = math.Min(1, t.SpectralEfficiency/format.BitsPerSymbol)
+	}
+	signal := 0.0
+	if t.BER > 0 {
+		best, worst := math.Log10(scoreBestBER), math.Log10(scoreWorstBER)
+		signal = math.Max(0, math.Min(1, (worst-math.Log10(t.BER))/(worst-best)))
+	}
+	t.EfficiencyScore = 100 * energy * spectral * signal
+}
@@ -0,0 +1,52 @@
+package main
+
+import "github.com/corridoros/pkg/protowire"
+
+// MarshalProto encodes a reading as corridoros.v1.CorridorTelemetry
+func (t Telemetry) MarshalProto() []byte {
+	b := make([]byte, 0, 128)
+	b = protowire.AppendDouble(b, 1, t.BER)
+	b = protowire.AppendDouble(b, 2, t.TempC)
+	b = protowire.AppendDouble(b, 3, t.PowerPjPerBit)
+	b = protowire.AppendString(b, 4, t.Drift)
+	b = protowire.AppendDouble(b, 5, t.UtilizationPercent)
+	b = protowire.AppendInt64(b, 6, int64(t.ErrorCount))
+	b = protowire.AppendDouble(b, 7, t.GbpsPerLane)
+	b = protowire.AppendDouble(b, 8, t.SpectralEfficiency)
+	b = protowire.AppendDouble(b, 9, t.EfficiencyScore)
+	b = protowire.AppendUint64(b, 10, t.Seq)
+	return protowire.AppendTime(b, 11, t.SampledAt)
+}
+
+// UnmarshalProto decodes a corridoros.v1.CorridorTelemetry
+func (t *Telemetry) UnmarshalProto(b []byte) error {
+	*t = Telemetry{}
+	return protowire.Range(b, func(f protowire.Field) error {
+		var err error
+		switch f.Num {
+		case 1:
+			t.BER = f.Double()
+		case 2:
+			t.TempC = f.Double()
+		case 3:
+			t.PowerPjPerBit = f.Double()
+		case 4:
+			t.Drift = string(f.Bytes)
+		case 5:
+			t.UtilizationPercent = f.Double()
+		case 6:
+			t.ErrorCount = int(int64(f.Varint))
+		case 7:
+			t.GbpsPerLane = f.Double()
+		case 8:
+			t.SpectralEfficiency = f.Double()
+		case 9:
+			t.EfficiencyScore = f.Double()
+		case 10:
+			t.Seq = f.Varint
+		case 11:
+			t.SampledAt, err = f.Time()
+		}
+		return err
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/corridoros/pkg/protowire"
+)
+
+func reading(i int) Telemetry {
+	return Telemetry{
+		BER:                1.2e-12 * float64(i+1),
+		TempC:              41.5,
+		PowerPjPerBit:      0.85,
+		Drift:              "stable",
+		UtilizationPercent: 63.25,
+		ErrorCount:         i,
+		GbpsPerLane:        52,
+		SpectralEfficiency: 1.04,
+		EfficiencyScore:    87.5,
+		Seq:                uint64(i),
+		SampledAt:          time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC).Add(time.Duration(i) * time.Second),
+	}
+}
+
+func TestTelemetryProtoMatchesJSON(t *testing.T) {
+	for _, want := range []Telemetry{reading(0), reading(41), {}} {
+		var fromProto, fromJSON Telemetry
+		if err := fromProto.UnmarshalProto(want.MarshalProto()); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(want)
+		if err := json.Unmarshal(data, &fromJSON); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(fromProto, want) {
+			t.Errorf("protobuf round trip = %+v, want %+v", fromProto, want)
+		}
+		if !reflect.DeepEqual(fromProto, fromJSON) {
+			t.Errorf("protobuf decoded %+v, JSON decoded %+v", fromProto, fromJSON)
+		}
+	}
+}
+
+func TestTelemetryEndpointNegotiatesProtobuf(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+	var corridor Corridor
+	do(t, srv, "POST", "/v1/corridors", allocateRequest(), &corridor)
+	path := "/v1/corridors/" + corridor.ID + "/telemetry"
+
+	req, _ := http.NewRequest("GET", srv.URL+path, nil)
+	req.Header.Set("Accept", protowire.ContentType)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != protowire.ContentType {
+		t.Fatalf("Content-Type = %q, want %q", ct, protowire.ContentType)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	var fromProto, fromJSON Telemetry
+	if err := fromProto.UnmarshalProto(body); err != nil {
+		t.Fatal(err)
+	}
+	do(t, srv, "GET", path, nil, &fromJSON)
+	if fromProto.Seq != fromJSON.Seq || !fromProto.SampledAt.Equal(fromJSON.SampledAt) || fromProto.GbpsPerLane != fromJSON.GbpsPerLane {
+		t.Errorf("protobuf reading %+v does not match JSON reading %+v", fromProto, fromJSON)
+	}
+}
+
+// BenchmarkTelemetryBatch encodes and decodes a batch of readings, as a
+// poller scraping many corridors does, in each encoding
+func BenchmarkTelemetryBatch(b *testing.B) {
+	batch := make([]Telemetry, 10000)
+	for i := range batch {
+		batch[i] = reading(i)
+	}
+	b.Run("protobuf", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var t Telemetry
+			for _, r := range batch {
+				if err := t.UnmarshalProto(r.MarshalProto()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("json", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var t Telemetry
+			for _, r := range batch {
+				data, err := json.Marshal(r)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := json.Unmarshal(data, &t); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestTelemetryHistoryRange(t *testing.T) {
+	s := NewCorridorService()
+	corridor, err := s.Allocate(allocateRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		s.sampleAll(start.Add(time.Duration(i) * time.Second))
+	}
+
+	srv := httptest.NewServer(newRouter(s))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		from, to time.Time
+		want     int
+	}{
+		{time.Time{}, time.Time{}, 10},
+		{start.Add(3 * time.Second), start.Add(6 * time.Second), 4},
+		{start.Add(8 * time.Second), time.Time{}, 2},
+		{time.Time{}, start.Add(-time.Second), 0},
+	} {
+		q := url.Values{}
+		if !tc.from.IsZero() {
+			q.Set("from", tc.from.Format(time.RFC3339))
+		}
+		if !tc.to.IsZero() {
+			q.Set("to", tc.to.Format(time.RFC3339))
+		}
+		var samples []TelemetrySample
+		if code := do(t, srv, "GET", "/v1/corridors/"+corridor.ID+"/telemetry/history?"+q.Encode(), nil, &samples); code != http.StatusOK {
+			t.Fatalf("%s: status = %d", q.Encode(), code)
+		}
+		if len(samples) != tc.want {
+			t.Errorf("%s: got %d samples, want %d", q.Encode(), len(samples), tc.want)
+		}
+		for i := 1; i < len(samples); i++ {
+			if !samples[i].Timestamp.After(samples[i-1].Timestamp) {
+				t.Errorf("%s: samples out of order at %d", q.Encode(), i)
+			}
+		}
+	}
+
+	if code := do(t, srv, "GET", "/v1/corridors/"+corridor.ID+"/telemetry/history?from=yesterday", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("bad from: status = %d, want 400", code)
+	}
+}
+
+func TestTelemetryHistoryIsBounded(t *testing.T) {
+	s := NewCorridorService()
+	s.HistoryLength = 4
+	corridor, _ := s.Allocate(allocateRequest())
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		s.sampleAll(start.Add(time.Duration(i) * time.Second))
+	}
+
+	samples, err := s.TelemetryHistory(corridor.ID, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 4 {
+		t.Fatalf("got %d samples, want 4", len(samples))
+	}
+	if want := start.Add(6 * time.Second); !samples[0].Timestamp.Equal(want) {
+		t.Errorf("oldest retained sample at %v, want %v", samples[0].Timestamp, want)
+	}
+}
+
+func TestSamplerRecordsEachInterval(t *testing.T) {
+	s := NewCorridorService()
+	s.SampleInterval = 10 * time.Millisecond
+	corridor, _ := s.Allocate(allocateRequest())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.RunSampler(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		samples, _ := s.TelemetryHistory(corridor.ID, time.Time{}, time.Time{})
+		if len(samples) >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d samples after 5s at a 10ms interval", len(samples))
+		}
+		time.Sleep(s.SampleInterval)
+	}
+	cancel()
+	<-done
+}
+
+func TestTelemetryDerivedMetrics(t *testing.T) {
+	s := NewCorridorService()
+	for _, modulation := range []string{"NRZ", "PAM4"} {
+		req := allocateRequest()
+		req.Modulation = modulation
+		req.LambdaNm = []int{1560, 1561, 1562, 1563}
+		if modulation == "PAM4" {
+			req.LambdaNm = []int{1570, 1571, 1572, 1573}
+		}
+		corridor, err := s.Allocate(req)
+		if err != nil {
+			t.Fatalf("%s: %v", modulation, err)
+		}
+		for i := 0; i < 20; i++ {
+			telemetry, err := s.Telemetry(corridor.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := float64(corridor.AchievableGbps) / float64(corridor.Lanes); telemetry.GbpsPerLane != want {
+				t.Errorf("%s: gbps_per_lane = %g, want %g", modulation, telemetry.GbpsPerLane, want)
+			}
+			bits := modulationFormats[corridor.Modulation].BitsPerSymbol
+			if se := telemetry.SpectralEfficiency; se <= 0 || se > bits {
+				t.Errorf("%s: spectral efficiency %g outside (0, %g]", modulation, se, bits)
+			}
+			if score := telemetry.EfficiencyScore; score < 0 || score > 100 {
+				t.Errorf("%s: efficiency score %g outside [0, 100]", modulation, score)
+			}
+		}
+	}
+}
+
+func TestTelemetrySeqAdvancesWithSampler(t *testing.T) {
+	s := NewCorridorService()
+	corridor, err := s.Allocate(allocateRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := s.Telemetry(corridor.ID)
+	again, _ := s.Telemetry(corridor.ID)
+	if first.Seq != 0 || again.Seq != first.Seq || !again.SampledAt.Equal(first.SampledAt) {
+		t.Errorf("polls between samples: seq %d then %d, sampled_at %v then %v", first.Seq, again.Seq, first.SampledAt, again.SampledAt)
+	}
+
+	prev := *again
+	start := time.Now()
+	for i := 1; i <= 3; i++ {
+		s.sampleAll(s.sampleTime(start.Add(time.Duration(i) * time.Second)))
+		got, _ := s.Telemetry(corridor.ID)
+		if got.Seq != uint64(i) || got.Seq < prev.Seq {
+			t.Errorf("after sample %d: seq = %d (previous %d)", i, got.Seq, prev.Seq)
+		}
+		if !got.SampledAt.After(prev.SampledAt) {
+			t.Errorf("after sample %d: sampled_at %v did not advance past %v", i, got.SampledAt, prev.SampledAt)
+		}
+		prev = *got
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// defaultDomain is the fiber assumed when an allocation names none
+const defaultDomain = "default"
+
+// ErrWavelengthConflict marks an allocation reusing wavelengths already lit
+// in the same fiber
+var ErrWavelengthConflict = errors.New("wavelength conflict")
+
+// band is an ITU-T optical band: the whole-nanometre wavelengths in
+// [MinNm, MaxNm), each of which a fiber can carry once
+type band struct {
+	Name  string
+	MinNm int
+	MaxNm int
+}
+
+// bands are the bands a fiber domain offers, in wavelength order
+var bands = []band{
+	{Name: "O", MinNm: 1260, MaxNm: 1360},
+	{Name: "E", MinNm: 1360, MaxNm: 1460},
+	{Name: "S", MinNm: 1460, MaxNm: 1530},
+	{Name: "C", MinNm: 1530, MaxNm: 1565},
+	{Name: "L", MinNm: 1565, MaxNm: 1625},
+	{Name: "U", MinNm: 1625, MaxNm: 1675},
+}
+
+// outOfBand reports the first wavelength in lambdas no band covers
+func outOfBand(lambdas []int) error {
+	first, last := bands[0].MinNm, bands[len(bands)-1].MaxNm
+	for _, nm := range lambdas {
+		if nm < first || nm >= last {
+			return fmt.Errorf("lambda_nm %d nm is outside the %d-%d nm bands", nm, first, last-1)
+		}
+	}
+	return nil
+}
+
+// wavelengthPlan tracks which corridor holds each wavelength, per fiber domain
+type wavelengthPlan map[string]map[int]string
+
+// conflicts returns the wavelengths in lambdas already held in domain, sorted
+func (p wavelengthPlan) conflicts(domain string, lambdas []int) []int {
+	var taken []int
+	seen := make(map[int]bool, len(lambdas))
+	for _, nm := range lambdas {
+		if _, held := p[domain][nm]; held && !seen[nm] {
+			taken = append(taken, nm)
+		}
+		seen[nm] = true
+	}
+	sort.Ints(taken)
+	return taken
+}
+
+// reserve assigns lambdas in domain to a corridor
+func (p wavelengthPlan) reserve(domain string, lambdas []int, corridorID string) {
+	if p[domain] == nil {
+		p[domain] = make(map[int]string)
+	}
+	for _, nm := range lambdas {
+		p[domain][nm] = corridorID
+	}
+}
+
+// release frees the wavelengths a corridor holds in domain
+func (p wavelengthPlan) release(domain string, lambdas []int, corridorID string) {
+	for _, nm := range lambdas {
+		if p[domain][nm] == corridorID {
+			delete(p[domain], nm)
+		}
+	}
+	if len(p[domain]) == 0 {
+		delete(p, domain)
+	}
+}
+
+// duplicateWavelengths reports wavelengths listed more than once in a request
+func duplicateWavelengths(lambdas []int) error {
+	seen := make(map[int]bool, len(lambdas))
+	for _, nm := range lambdas {
+		if seen[nm] {
+			return fmt.Errorf("lambda_nm lists %d nm more than once", nm)
+		}
+		seen[nm] = true
+	}
+	return nil
+}
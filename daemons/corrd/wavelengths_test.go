@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWavelengthCollisions(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	first := allocateRequest()
+	first.Domain = "fiber-a"
+	var held Corridor
+	if code := do(t, srv, "POST", "/v1/corridors", first, &held); code != http.StatusCreated {
+		t.Fatalf("first allocation: status = %d", code)
+	}
+
+	overlap := allocateRequest()
+	overlap.Domain = "fiber-a"
+	overlap.LambdaNm = []int{1553, 1554, 1555, 1551}
+	body := post(t, srv, "/v1/corridors", overlap, http.StatusConflict)
+	if !strings.Contains(body, "[1551 1553]") {
+		t.Errorf("conflict body %q does not list the overlapping wavelengths", body)
+	}
+
+	other := allocateRequest()
+	other.Domain = "fiber-b"
+	if code := do(t, srv, "POST", "/v1/corridors", other, nil); code != http.StatusCreated {
+		t.Errorf("same wavelengths in another fiber: status = %d, want 201", code)
+	}
+
+	// Releasing the holder frees its wavelengths.
+	do(t, srv, "DELETE", "/v1/corridors/"+held.ID, nil, nil)
+	if code := do(t, srv, "POST", "/v1/corridors", overlap, nil); code != http.StatusCreated {
+		t.Errorf("allocation after release: status = %d, want 201", code)
+	}
+}
+
+func TestDuplicateWavelengthsRejected(t *testing.T) {
+	srv := httptest.NewServer(newRouter(NewCorridorService()))
+	defer srv.Close()
+
+	req := allocateRequest()
+	req.LambdaNm = []int{1550, 1550, 1551, 1552}
+	if code := do(t, srv, "POST", "/v1/corridors", req, nil); code != http.StatusBadRequest {
+		t.Errorf("duplicate lambda_nm: status = %d, want 400", code)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Bandwidth is stored and validated in GB/s, decimal gigabytes (10^9 bytes)
+// per second: the unit of every *_GBs field. The *_gbps (gigabits per
+// second) and *_gibps (GiB/s, 2^30 bytes per second) fields are derived from
+// it on output, and a request may give a floor in either of them instead.
+const (
+	bitsPerByte = 8
+	bytesPerGB  = 1e9
+	bytesPerGiB = 1 << 30
+)
+
+// gbpsFromGBs converts GB/s to gigabits per second
+func gbpsFromGBs(gbs uint64) float64 {
+	return float64(gbs) * bitsPerByte
+}
+
+// gibpsFromGBs converts GB/s to GiB/s
+func gibpsFromGBs(gbs uint64) float64 {
+	return float64(gbs) * bytesPerGB / bytesPerGiB
+}
+
+// resolveFloorGBs settles a bandwidth floor given in any of the three units
+// on its GB/s value, rounded to the nearest whole GB/s. Zero means unset;
+// units that are set must agree.
+func resolveFloorGBs(gbs uint64, gbps, gibps float64, field string) (uint64, error) {
+	if gbps < 0 || gibps < 0 {
+		return 0, fmt.Errorf("%s must not be negative", field)
+	}
+	resolved, from := gbs, field+"_GBs"
+	for _, alt := range []struct {
+		name  string
+		value float64
+	}{
+		{field + "_gbps", gbps / bitsPerByte},
+		{field + "_gibps", gibps * bytesPerGiB / bytesPerGB},
+	} {
+		if alt.value == 0 {
+			continue
+		}
+		v := uint64(math.Round(alt.value))
+		if resolved == 0 {
+			resolved, from = v, alt.name
+		} else if v != resolved {
+			return 0, fmt.Errorf("%s (%d GB/s) and %s (%d GB/s) disagree", from, resolved, alt.name, v)
+		}
+	}
+	return resolved, nil
+}
+
+// setDerivedBandwidth fills the handle's gbps and gibps fields from its
+// canonical GB/s values
+func (h *FFMHandle) setDerivedBandwidth() {
+	h.BandwidthFloorGbps = gbpsFromGBs(h.BandwidthFloorGBs)
+	h.BandwidthFloorGiBps = gibpsFromGBs(h.BandwidthFloorGBs)
+	h.AchievedGbps = gbpsFromGBs(h.AchievedGBs)
+	h.AchievedGiBps = gibpsFromGBs(h.AchievedGBs)
+}
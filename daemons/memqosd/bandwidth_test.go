@@ -0,0 +1,61 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDerivedBandwidthIsConsistent(t *testing.T) {
+	s := NewMemQoSService()
+	handle, err := s.Allocate(FFMAllocRequest{Bytes: 1 << 30, LatencyClass: "T1", BandwidthFloorGBs: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handle.BandwidthFloorGbps != 800 || math.Abs(handle.BandwidthFloorGiBps-93.1322574615) > 1e-9 {
+		t.Errorf("floor 100 GB/s = %g Gbps, %g GiB/s", handle.BandwidthFloorGbps, handle.BandwidthFloorGiBps)
+	}
+
+	telemetry, err := s.Telemetry(handle.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if telemetry.AchievedGbps != gbpsFromGBs(telemetry.AchievedGBs) || telemetry.AchievedGiBps != gibpsFromGBs(telemetry.AchievedGBs) {
+		t.Errorf("telemetry = %+v", telemetry)
+	}
+	// The achieved/floor ratio is the same whichever unit both sides use
+	byGBs := float64(telemetry.AchievedGBs) / float64(handle.BandwidthFloorGBs)
+	byGbps := telemetry.AchievedGbps / handle.BandwidthFloorGbps
+	byGiBps := telemetry.AchievedGiBps / handle.BandwidthFloorGiBps
+	if math.Abs(byGBs-byGbps) > 1e-12 || math.Abs(byGBs-byGiBps) > 1e-12 {
+		t.Errorf("ratios disagree: GB/s %g, Gbps %g, GiB/s %g", byGBs, byGbps, byGiBps)
+	}
+}
+
+func TestFloorInAnyUnit(t *testing.T) {
+	s := NewMemQoSService()
+	for name, req := range map[string]FFMAllocRequest{
+		"GB/s":  {BandwidthFloorGBs: 50},
+		"Gbps":  {BandwidthFloorGbps: 400},
+		"GiB/s": {BandwidthFloorGiBps: 46.5661287},
+		"all":   {BandwidthFloorGBs: 50, BandwidthFloorGbps: 400, BandwidthFloorGiBps: 46.5661287},
+	} {
+		req.Bytes, req.LatencyClass = 1<<20, "T2"
+		handle, err := s.Allocate(req)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if handle.BandwidthFloorGBs != 50 {
+			t.Errorf("%s: floor = %d GB/s, want 50", name, handle.BandwidthFloorGBs)
+		}
+	}
+
+	_, err := s.Allocate(FFMAllocRequest{Bytes: 1, LatencyClass: "T2", BandwidthFloorGBs: 50, BandwidthFloorGbps: 50})
+	if err == nil || !strings.Contains(err.Error(), "disagree") {
+		t.Errorf("conflicting units error = %v", err)
+	}
+	if _, err := resolveFloorGBs(0, -1, 0, "floor"); err == nil {
+		t.Error("negative gbps accepted")
+	}
+}
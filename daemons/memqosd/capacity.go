@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrOversubscribed marks allocations that would take a latency class past
+// its configured capacity
+var ErrOversubscribed = errors.New("oversubscribed")
+
+// TierCapacity is the total a latency class can back across all handles;
+// zero means unlimited
+type TierCapacity struct {
+	Bytes uint64 `json:"bytes"`
+	GBs   uint64 `json:"bandwidth_GBs"`
+}
+
+// ClassCapacity is a latency class's committed and remaining capacity.
+// Committed figures count pending reservations; the available fields are
+// omitted when the corresponding capacity is unlimited.
+type ClassCapacity struct {
+	LatencyClass      string       `json:"latency_class"`
+	Tier              string       `json:"tier"`
+	Capacity          TierCapacity `json:"capacity"`
+	Handles           int          `json:"handles"`
+	Bytes             uint64       `json:"bytes"`
+	BandwidthFloorGBs uint64       `json:"bandwidth_floor_GBs"`
+	AvailableBytes    *uint64      `json:"available_bytes,omitempty"`
+	AvailableGBs      *uint64      `json:"available_bandwidth_GBs,omitempty"`
+}
+
+// DomainCapacity is a security domain's headroom under its quota; the
+// available fields are omitted when the corresponding limit is unlimited
+type DomainCapacity struct {
+	DomainUsage
+	AvailableBytes   *uint64 `json:"available_bytes,omitempty"`
+	AvailableHandles *int    `json:"available_handles,omitempty"`
+}
+
+// Capacity is the headroom schedulers place allocations against. Domains
+// lists those holding handles or with a configured quota; any other domain
+// starts from DefaultQuota.
+type Capacity struct {
+	LatencyClasses []ClassCapacity  `json:"latency_classes"`
+	Domains        []DomainCapacity `json:"domains"`
+	DefaultQuota   DomainQuota      `json:"default_quota"`
+}
+
+// CapacityError reports which limit of a latency class an allocation
+// would exceed
+type CapacityError struct {
+	Class  ClassCapacity
+	reason string
+}
+
+func (e *CapacityError) Error() string {
+	return fmt.Sprintf("latency class %s %v: %s", e.Class.LatencyClass, ErrOversubscribed, e.reason)
+}
+
+func (e *CapacityError) Unwrap() error { return ErrOversubscribed }
+
+// classUsageLocked totals the handles and reservations in a latency class,
+// leaving out the handle excludeID. The caller must hold the store lock.
+func (s *MemQoSService) classUsageLocked(class, excludeID string) ClassCapacity {
+	usage := ClassCapacity{LatencyClass: class, Tier: tiers[class].Name, Capacity: s.TierCapacities[class]}
+	for _, state := range s.handles.Values() {
+		if state.handle.ID != excludeID && state.handle.LatencyClass == class {
+			usage.Handles++
+			usage.Bytes += state.handle.Bytes
+			usage.BandwidthFloorGBs += state.handle.BandwidthFloorGBs
+		}
+	}
+	s.reservations.Each(func(h FFMHandle) {
+		if h.LatencyClass == class {
+			usage.Handles++
+			usage.Bytes += h.Bytes
+			usage.BandwidthFloorGBs += h.BandwidthFloorGBs
+		}
+	})
+	if c := usage.Capacity.Bytes; c > 0 {
+		available := c - min(usage.Bytes, c)
+		usage.AvailableBytes = &available
+	}
+	if c := usage.Capacity.GBs; c > 0 {
+		available := c - min(usage.BandwidthFloorGBs, c)
+		usage.AvailableGBs = &available
+	}
+	return usage
+}
+
+// checkCapacityLocked refuses placing bytes with a bandwidth floor in a
+// latency class past its capacity. excludeID names a handle already in the
+// class whose share is being replaced. The caller must hold the store lock.
+func (s *MemQoSService) checkCapacityLocked(class string, bytes, floorGBs uint64, excludeID string) error {
+	usage := s.classUsageLocked(class, excludeID)
+	c := usage.Capacity
+	if c.Bytes > 0 && usage.Bytes+bytes > c.Bytes {
+		return &CapacityError{Class: usage, reason: fmt.Sprintf("%d bytes requested with %d of %d in use", bytes, usage.Bytes, c.Bytes)}
+	}
+	if c.GBs > 0 && usage.BandwidthFloorGBs+floorGBs > c.GBs {
+		return &CapacityError{Class: usage, reason: fmt.Sprintf("%d GB/s floor requested with %d of %d GB/s committed", floorGBs, usage.BandwidthFloorGBs, c.GBs)}
+	}
+	return nil
+}
+
+// Capacity reports the remaining capacity per latency class and the
+// headroom of each known security domain
+func (s *MemQoSService) Capacity() Capacity {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	classes := make([]string, 0, len(tiers))
+	for class := range tiers {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	capacity := Capacity{DefaultQuota: s.DefaultQuota}
+	for _, class := range classes {
+		capacity.LatencyClasses = append(capacity.LatencyClasses, s.classUsageLocked(class, ""))
+	}
+
+	domains := map[string]bool{}
+	for domain := range s.Quotas {
+		domains[domain] = true
+	}
+	for _, state := range s.handles.Values() {
+		domains[state.handle.SecurityDomain] = true
+	}
+	s.reservations.Each(func(h FFMHandle) {
+		domains[h.SecurityDomain] = true
+	})
+	names := make([]string, 0, len(domains))
+	for domain := range domains {
+		names = append(names, domain)
+	}
+	sort.Strings(names)
+	capacity.Domains = make([]DomainCapacity, 0, len(names))
+	for _, domain := range names {
+		dc := DomainCapacity{DomainUsage: s.usageLocked(domain)}
+		if q := dc.Quota.MaxBytes; q > 0 {
+			available := q - min(dc.Bytes, q)
+			dc.AvailableBytes = &available
+		}
+		if q := dc.Quota.MaxHandles; q > 0 {
+			available := max(q-dc.Handles, 0)
+			dc.AvailableHandles = &available
+		}
+		capacity.Domains = append(capacity.Domains, dc)
+	}
+	return capacity
+}
+
+func (s *MemQoSService) handleCapacity(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Capacity())
+}
+
+// capacityFlag collects repeated -tier-capacity class=bytes:GBs flags
+type capacityFlag map[string]TierCapacity
+
+func (f capacityFlag) String() string {
+	classes := make([]string, 0, len(f))
+	for c := range f {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+	parts := make([]string, 0, len(classes))
+	for _, c := range classes {
+		parts = append(parts, fmt.Sprintf("%s=%d:%d", c, f[c].Bytes, f[c].GBs))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f capacityFlag) Set(v string) error {
+	class, capacity, ok := strings.Cut(v, "=")
+	if !ok {
+		return fmt.Errorf("%q is not class=bytes:GBs", v)
+	}
+	if _, ok := tiers[class]; !ok {
+		return fmt.Errorf("unsupported latency_class: %s (T0..T3)", class)
+	}
+	bytesStr, gbsStr, ok := strings.Cut(capacity, ":")
+	if !ok {
+		return fmt.Errorf("capacity %q is not bytes:GBs", capacity)
+	}
+	var c TierCapacity
+	var err error
+	if bytesStr != "" {
+		if c.Bytes, err = strconv.ParseUint(bytesStr, 10, 64); err != nil {
+			return fmt.Errorf("capacity %q: invalid bytes", capacity)
+		}
+	}
+	if gbsStr != "" {
+		if c.GBs, err = strconv.ParseUint(gbsStr, 10, 64); err != nil {
+			return fmt.Errorf("capacity %q: invalid GBs", capacity)
+		}
+	}
+	f[class] = c
+	return nil
+}
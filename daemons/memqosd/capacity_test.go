@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// class returns the capacity of one latency class
+func class(t *testing.T, c Capacity, name string) ClassCapacity {
+	t.Helper()
+	for _, cc := range c.LatencyClasses {
+		if cc.LatencyClass == name {
+			return cc
+		}
+	}
+	t.Fatalf("no latency class %s: %+v", name, c.LatencyClasses)
+	return ClassCapacity{}
+}
+
+func TestCapacityTracksAllocations(t *testing.T) {
+	s := NewMemQoSService()
+	s.TierCapacities["T1"] = TierCapacity{Bytes: 8 << 30, GBs: 100}
+	s.Quotas["lab"] = DomainQuota{MaxBytes: 16 << 30}
+
+	before := s.Capacity()
+	if t1 := class(t, before, "T1"); *t1.AvailableBytes != 8<<30 || *t1.AvailableGBs != 100 {
+		t.Errorf("T1 before allocation = %+v", t1)
+	}
+
+	handle, err := s.Allocate(FFMAllocRequest{Bytes: 3 << 30, LatencyClass: "T1", BandwidthFloorGBs: 40, SecurityDomain: "lab"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	during := s.Capacity()
+	if t1 := class(t, during, "T1"); t1.Handles != 1 || *t1.AvailableBytes != 5<<30 || *t1.AvailableGBs != 60 {
+		t.Errorf("T1 after allocation = %+v", t1)
+	}
+	if t2 := class(t, during, "T2"); t2.Handles != 0 || t2.AvailableBytes != nil {
+		t.Errorf("unlimited T2 after a T1 allocation = %+v", t2)
+	}
+	if len(during.Domains) != 1 || *during.Domains[0].AvailableBytes != 13<<30 {
+		t.Errorf("domains after allocation = %+v", during.Domains)
+	}
+
+	// A second allocation past the class's bandwidth is refused
+	if _, err := s.Allocate(FFMAllocRequest{Bytes: 1 << 30, LatencyClass: "T1", BandwidthFloorGBs: 61, SecurityDomain: "lab"}); !errors.Is(err, ErrOversubscribed) {
+		t.Errorf("allocation past T1 bandwidth: %v", err)
+	}
+
+	if err := s.Free(handle.ID); err != nil {
+		t.Fatal(err)
+	}
+	after := s.Capacity()
+	if t1 := class(t, after, "T1"); t1.Handles != 0 || *t1.AvailableBytes != 8<<30 || *t1.AvailableGBs != 100 {
+		t.Errorf("T1 after free = %+v", t1)
+	}
+	if *after.Domains[0].AvailableBytes != 16<<30 {
+		t.Errorf("lab after free = %+v", after.Domains[0])
+	}
+}
+
+func TestCapacityHTTP(t *testing.T) {
+	s := NewMemQoSService()
+	s.TierCapacities["T0"] = TierCapacity{Bytes: 1 << 30}
+	router := newRouter(s)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/ffm/capacity", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("capacity status = %d: %s", rec.Code, rec.Body)
+	}
+	var c Capacity
+	if err := json.NewDecoder(rec.Body).Decode(&c); err != nil {
+		t.Fatal(err)
+	}
+	if t0 := class(t, c, "T0"); t0.Tier != tiers["T0"].Name || *t0.AvailableBytes != 1<<30 {
+		t.Errorf("T0 = %+v", t0)
+	}
+
+	body := `{"bytes":2147483648,"latency_class":"T0","security_domain":"lab"}`
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/ffm/alloc", strings.NewReader(body)))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "OVERSUBSCRIBED") {
+		t.Errorf("allocation past T0 bytes = %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+func TestErrorCodes(t *testing.T) {
+	s := NewMemQoSService()
+	handle, err := s.Allocate(FFMAllocRequest{Bytes: 1 << 30, LatencyClass: "T1", BandwidthFloorGBs: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newRouter(s)
+
+	for _, tc := range []struct {
+		name, method, path, body string
+		code                     apierr.Code
+		status                   int
+	}{
+		{"malformed body", "POST", "/v1/ffm/alloc", "{", apierr.CodeBadRequest, http.StatusBadRequest},
+		{"two objects", "POST", "/v1/ffm/alloc", `{"bytes":1,"latency_class":"T1"}{"bytes":2}`, apierr.CodeBadRequest, http.StatusBadRequest},
+		{"mistyped field", "POST", "/v1/ffm/alloc", `{"bytes":"1GiB","latency_class":"T1"}`, apierr.CodeBadRequest, http.StatusBadRequest},
+		{"unknown tier", "POST", "/v1/ffm/alloc", `{"bytes":1,"latency_class":"T9"}`, apierr.CodeValidation, http.StatusBadRequest},
+		{"floor above tier maximum", "PATCH", "/v1/ffm/" + handle.ID + "/bandwidth", `{"floor_GBs":5000}`, apierr.CodeValidation, http.StatusBadRequest},
+		{"unknown handle", "GET", "/v1/ffm/ffm-missing", "", apierr.CodeNotFound, http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body)))
+		var e apierr.Error
+		if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+			t.Fatalf("%s: error body %q: %v", tc.name, rec.Body, err)
+		}
+		if rec.Code != tc.status || e.Code != tc.code {
+			t.Errorf("%s: %d %+v, want %d %s", tc.name, rec.Code, e, tc.status, tc.code)
+		}
+	}
+}
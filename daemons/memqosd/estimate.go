@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/jsonbody"
+)
+
+// FFMEstimate is the projected cost of an allocation that is not committed
+type FFMEstimate struct {
+	LatencyClass       string  `json:"latency_class"`
+	Tier               string  `json:"tier,omitempty"`
+	BandwidthFloorGBs  uint64  `json:"bandwidth_floor_GBs"`
+	BandwidthFloorGbps float64 `json:"bandwidth_floor_gbps"`
+	PowerW             float64 `json:"power_w"`
+	Feasible           bool    `json:"feasible"`
+	Reason             string  `json:"reason,omitempty"`
+}
+
+// Estimate projects the power draw of each request at its bandwidth floor
+// without allocating anything. Invalid requests are reported as infeasible
+// rather than failing the batch.
+func (s *MemQoSService) Estimate(reqs []FFMAllocRequest) []FFMEstimate {
+	estimates := make([]FFMEstimate, 0, len(reqs))
+	for _, req := range reqs {
+		est := FFMEstimate{LatencyClass: req.LatencyClass}
+		tier, err := validate(&req)
+		est.BandwidthFloorGBs = req.BandwidthFloorGBs
+		est.BandwidthFloorGbps = gbpsFromGBs(req.BandwidthFloorGBs)
+		if err != nil {
+			est.Reason = err.Error()
+		} else {
+			est.Tier = tier.Name
+			est.PowerW = tier.IdleWatts + float64(req.BandwidthFloorGBs)*tier.WattsPerGBs
+			est.Feasible = true
+		}
+		estimates = append(estimates, est)
+	}
+	return estimates
+}
+
+func (s *MemQoSService) handleEstimate(w http.ResponseWriter, r *http.Request) {
+	var reqs []FFMAllocRequest
+	if err := jsonbody.Decode(r.Body, &reqs); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Estimate(reqs))
+}
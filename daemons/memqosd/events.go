@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+// Event kinds published on the events stream
+const (
+	EventFloorBreach    = "floor_breach"
+	EventFloorRecovered = "floor_recovered"
+	EventLeaseEvicted   = "lease_evicted"
+)
+
+// eventHistory is how many past events the stream retains for replay
+const eventHistory = 256
+
+// Event is a notable change in a handle's service
+type Event struct {
+	Seq               uint64    `json:"seq"`
+	Kind              string    `json:"kind"`
+	HandleID          string    `json:"handle_id"`
+	SecurityDomain    string    `json:"security_domain"`
+	At                time.Time `json:"at"`
+	BandwidthFloorGBs uint64    `json:"bandwidth_floor_GBs"`
+	AchievedGBs       uint64    `json:"achieved_GBs"`
+	// ConsecutiveSamples is how many samples in a row were below the
+	// floor for a breach, or met it again for a recovery
+	ConsecutiveSamples int `json:"consecutive_samples"`
+}
+
+// eventLog numbers published events, retains the latest eventHistory of
+// them and fans each out to the open streams
+type eventLog struct {
+	mu          sync.Mutex
+	seq         uint64
+	recent      []Event // oldest first
+	subscribers map[chan Event]struct{}
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{subscribers: make(map[chan Event]struct{})}
+}
+
+// publish records an event and hands it to every subscriber. A subscriber
+// too slow to keep up misses events rather than stalling the publisher;
+// the seq gap tells it so.
+func (l *eventLog) publish(e Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	e.Seq = l.seq
+	l.recent = append(l.recent, e)
+	if len(l.recent) > eventHistory {
+		l.recent = append(l.recent[:0:0], l.recent[len(l.recent)-eventHistory:]...)
+	}
+	for ch := range l.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// subscribe returns the retained events after since and a channel of the
+// events published from then on
+func (l *eventLog) subscribe(since uint64) ([]Event, chan Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var backlog []Event
+	for _, e := range l.recent {
+		if e.Seq > since {
+			backlog = append(backlog, e)
+		}
+	}
+	ch := make(chan Event, eventHistory)
+	l.subscribers[ch] = struct{}{}
+	return backlog, ch
+}
+
+func (l *eventLog) unsubscribe(ch chan Event) {
+	l.mu.Lock()
+	delete(l.subscribers, ch)
+	l.mu.Unlock()
+}
+
+// handleEvents streams events as server-sent events, each with its seq as
+// the event id. A client resuming after a disconnect sends Last-Event-ID
+// (or ?since=) to replay the retained events it missed.
+func (s *MemQoSService) handleEvents(w http.ResponseWriter, r *http.Request) {
+	since := r.Header.Get("Last-Event-ID")
+	if q := r.URL.Query().Get("since"); q != "" {
+		since = q
+	}
+	var after uint64
+	if since != "" {
+		var err error
+		if after, err = strconv.ParseUint(since, 10, 64); err != nil {
+			apierr.Respond(w, apierr.CodeBadRequest, "since must be an event seq")
+			return
+		}
+	}
+
+	backlog, ch := s.events.subscribe(after)
+	defer s.events.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	for _, e := range backlog {
+		writeEvent(w, e)
+	}
+	_ = rc.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-ch:
+			writeEvent(w, e)
+			_ = rc.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e Event) {
+	data, _ := json.Marshal(e)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Kind, data)
+}
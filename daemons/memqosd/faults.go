@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/faults"
+	"github.com/corridoros/pkg/jsonbody"
+	"github.com/gorilla/mux"
+)
+
+// FaultBandwidthShortfall reduces the bandwidth a handle's telemetry reports
+const FaultBandwidthShortfall = "bandwidth_shortfall"
+
+// newFaultRegistry accepts the shared delay and error faults plus bandwidth
+// shortfall faults
+func newFaultRegistry() *faults.Registry {
+	return faults.NewRegistry(map[string]faults.Validator{
+		FaultBandwidthShortfall: func(req faults.Request) error {
+			if req.ShortfallPercent <= 0 || req.ShortfallPercent > 100 {
+				return fmt.Errorf("shortfall_percent must be in (0, 100]")
+			}
+			return nil
+		},
+	})
+}
+
+// injectFaults applies delay and error faults targeting the handle in the
+// request path before the handler runs
+func (s *MemQoSService) injectFaults(next http.Handler) http.Handler {
+	return s.faults.Middleware(func(r *http.Request) string { return mux.Vars(r)["id"] })(next)
+}
+
+func (s *MemQoSService) handleAddFault(w http.ResponseWriter, r *http.Request) {
+	var req faults.Request
+	if err := jsonbody.Decode(r.Body, &req); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	fault, err := s.faults.Add(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, fault)
+}
+
+func (s *MemQoSService) handleListFaults(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.faults.List())
+}
+
+func (s *MemQoSService) handleClearFaults(w http.ResponseWriter, r *http.Request) {
+	s.faults.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,10 @@
+module github.com/corridoros/memqosd
+
+go 1.27
+
+require (
+	github.com/corridoros/pkg v0.0.0
+	github.com/gorilla/mux v1.8.1
+)
+
+replace github.com/corridoros/pkg => ../../pkg
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLapsedHandlesAreEvictedPastTheCap(t *testing.T) {
+	s := NewMemQoSService()
+	s.handles.MaxEntries = 2
+	req := FFMAllocRequest{Bytes: 1 << 30, LatencyClass: "T1", BandwidthFloorGBs: 10}
+
+	lapsed, err := s.Allocate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leased, err := s.Allocate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	state, _ := s.handles.Peek(lapsed.ID)
+	state.handle.PolicyLeaseTTLsec = 0
+	s.mu.Unlock()
+
+	if _, err := s.Allocate(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(lapsed.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("lapsed handle after eviction: err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Get(leased.ID); err != nil {
+		t.Errorf("leased handle was evicted: %v", err)
+	}
+	if t1 := class(t, s.Capacity(), "T1"); t1.Handles != 2 {
+		t.Errorf("T1 holds %d handles, want 2", t1.Handles)
+	}
+
+	backlog, ch := s.events.subscribe(0)
+	s.events.unsubscribe(ch)
+	if len(backlog) != 1 || backlog[0].Kind != EventLeaseEvicted || backlog[0].HandleID != lapsed.ID {
+		t.Errorf("events = %+v, want one lease_evicted for %s", backlog, lapsed.ID)
+	}
+}
+
+func TestLeasedHandlesGrowPastTheCap(t *testing.T) {
+	s := NewMemQoSService()
+	s.handles.MaxEntries = 1
+	for i := 0; i < 3; i++ {
+		if _, err := s.Allocate(FFMAllocRequest{Bytes: 1 << 20, LatencyClass: "T2", BandwidthFloorGBs: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(s.List(nil)); n != 3 {
+		t.Errorf("%d handles listed, want all 3 leased handles kept", n)
+	}
+	if st := s.handles.Stats(); st.Pinned != 3 || st.Evicted != 0 {
+		t.Errorf("stats = %+v", st)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/corridoros/pkg/journal"
+)
+
+// Journaled operations; each entry carries the handle after the operation,
+// or nothing once it is freed
+const (
+	opAllocate        = "allocate"
+	opAdjustBandwidth = "adjust_bandwidth"
+	opMigrate         = "migrate"
+	opFree            = "free"
+)
+
+// OpenJournal opens the journal at path, rebuilds the handle store from it
+// and journals every later change. Call it before serving requests.
+func (s *MemQoSService) OpenJournal(path string) error {
+	j, err := journal.Open(path)
+	if err != nil {
+		return err
+	}
+	if err := s.replay(j); err != nil {
+		j.Close()
+		return fmt.Errorf("replaying %s: %w", path, err)
+	}
+	s.mu.Lock()
+	s.journal = j
+	s.mu.Unlock()
+	return nil
+}
+
+// replay restores the handles that were live at the journal's end, as
+// they were at their last journaled change. Quota and capacity are
+// charged from the store, so restoring the handles restores them too;
+// SLA history and dirty bytes start afresh.
+func (s *MemQoSService) replay(j *journal.Journal) error {
+	live, err := journal.Live[FFMHandle](j, opFree)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rec := range live {
+		h := rec.Value
+		s.handles.Set(rec.ID, &handleState{handle: h, sampledAt: h.CreatedAt})
+	}
+	if len(live) > 0 {
+		log.Printf("journal: restored %d handles", len(live))
+	}
+	return nil
+}
+
+// journalLocked records an operation on a handle. The caller must hold
+// the store lock.
+func (s *MemQoSService) journalLocked(op string, handle FFMHandle) {
+	var data any
+	if op != opFree {
+		data = handle
+	}
+	s.journal.Log(op, handle.ID, data)
+}
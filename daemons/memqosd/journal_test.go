@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalReplayRebuildsHandles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memqosd.journal")
+	s := NewMemQoSService()
+	s.Quotas["lab"] = DomainQuota{MaxBytes: 8 << 30}
+	if err := s.OpenJournal(path); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, class := range []string{"T1", "T1", "T2"} {
+		h, err := s.Allocate(FFMAllocRequest{Bytes: 2 << 30, LatencyClass: class, BandwidthFloorGBs: 10, SecurityDomain: "lab"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, h.ID)
+	}
+	if _, err := s.AdjustBandwidth(ids[0], 20); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.MigrateLatencyClass(ids[1], "T2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Free(ids[2]); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(s.List(nil))
+	s.journal.Close()
+
+	restarted := NewMemQoSService()
+	restarted.Quotas["lab"] = DomainQuota{MaxBytes: 8 << 30}
+	if err := restarted.OpenJournal(path); err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.journal.Close()
+	if got, _ := json.Marshal(restarted.List(nil)); string(got) != string(want) {
+		t.Errorf("replayed handles:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The restored handles are charged to the domain's quota again
+	usage := restarted.Usage("lab")
+	if usage.Handles != 2 || usage.Bytes != 4<<30 {
+		t.Errorf("lab usage after replay = %+v", usage)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListFiltersByLabel(t *testing.T) {
+	s := NewMemQoSService()
+	for _, tags := range []map[string]string{{"app": "trainer"}, {"app": "cache"}, nil} {
+		if _, err := s.Allocate(FFMAllocRequest{Bytes: 1 << 20, LatencyClass: "T2", Labels: tags}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	router := newRouter(s)
+	for query, want := range map[string]int{"": 3, "?label.app=trainer": 1, "?label.app=other": 0} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/ffm/"+query, nil))
+		var list []FFMHandle
+		if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+			t.Fatalf("%s: %d %s", query, rec.Code, rec.Body)
+		}
+		if len(list) != want {
+			t.Errorf("%q: %d handles, want %d", query, len(list), want)
+		}
+		for _, h := range list {
+			if query != "" && h.Labels == nil {
+				t.Errorf("%q: unlabeled handle %s matched", query, h.ID)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/ffm/?label.=x", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid selector: status %d, want 400", rec.Code)
+	}
+	if _, err := s.Allocate(FFMAllocRequest{Bytes: 1 << 20, LatencyClass: "T2", Labels: map[string]string{"bad key": "x"}}); err == nil {
+		t.Error("invalid label key accepted")
+	}
+}
@@ -0,0 +1,670 @@
+// memqosd — Free-Form Memory daemon (in-memory implementation)
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	mrand "math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/bounded"
+	"github.com/corridoros/pkg/faults"
+	"github.com/corridoros/pkg/journal"
+	"github.com/corridoros/pkg/jsonbody"
+	"github.com/corridoros/pkg/labels"
+	"github.com/corridoros/pkg/protowire"
+	"github.com/corridoros/pkg/reservations"
+	"github.com/corridoros/pkg/tlsserve"
+	"github.com/corridoros/pkg/trace"
+	"github.com/gorilla/mux"
+)
+
+// FFMAllocRequest represents a Free-Form Memory allocation request. The
+// bandwidth floor may be given in GB/s, gigabits/s or GiB/s.
+type FFMAllocRequest struct {
+	Bytes               uint64  `json:"bytes"`
+	LatencyClass        string  `json:"latency_class"` // T0..T3
+	BandwidthFloorGBs   uint64  `json:"bandwidth_floor_GBs"`
+	BandwidthFloorGbps  float64 `json:"bandwidth_floor_gbps,omitempty"`
+	BandwidthFloorGiBps float64 `json:"bandwidth_floor_gibps,omitempty"`
+	Persistence         string  `json:"persistence"` // none|write-back|durable
+	Shareable           bool    `json:"shareable"`
+	SecurityDomain      string  `json:"security_domain"`
+	AttestationRequired bool    `json:"attestation_required,omitempty"`
+	AttestationTicket   string  `json:"attestation_ticket,omitempty"`
+	// Labels tag the handle for filtering and accounting
+	Labels map[string]string `json:"labels,omitempty"`
+	// WaitMs, when set, queues an allocation that does not fit for up to
+	// that many milliseconds instead of refusing it at once
+	WaitMs int `json:"wait_ms,omitempty"`
+}
+
+// FFMHandle represents an FFM allocation. Bandwidth is in GB/s; the gbps
+// and gibps fields are the same values converted for display.
+type FFMHandle struct {
+	ID                  string    `json:"id"`
+	Bytes               uint64    `json:"bytes"`
+	LatencyClass        string    `json:"latency_class"`
+	BandwidthFloorGBs   uint64    `json:"bandwidth_floor_GBs"`
+	BandwidthFloorGbps  float64   `json:"bandwidth_floor_gbps"`
+	BandwidthFloorGiBps float64   `json:"bandwidth_floor_gibps"`
+	Persistence         string    `json:"persistence"`
+	Shareable           bool      `json:"shareable"`
+	SecurityDomain      string    `json:"security_domain"`
+	CreatedAt           time.Time `json:"created_at"`
+	PolicyLeaseTTLsec   int       `json:"policy_lease_ttl_s"`
+	FDs                 []string  `json:"fds"`
+	AchievedGBs         uint64    `json:"achieved_GBs"`
+	AchievedGbps        float64   `json:"achieved_gbps"`
+	AchievedGiBps       float64   `json:"achieved_gibps"`
+	MovedPages          uint64    `json:"moved_pages"`
+	TailP99Ms           float64   `json:"tail_p99_ms"`
+
+	// Labels are the tags given at allocation
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// FFMTelemetry represents live telemetry for an FFM handle
+type FFMTelemetry struct {
+	AchievedGBs   uint64  `json:"achieved_GBs"`
+	AchievedGbps  float64 `json:"achieved_gbps"`
+	AchievedGiBps float64 `json:"achieved_gibps"`
+	MovedPages    uint64  `json:"moved_pages"`
+	TailP99Ms     float64 `json:"tail_p99_ms"`
+	Temperature   float64 `json:"temperature_c"`
+	PowerW        float64 `json:"power_w"`
+	Utilization   float64 `json:"utilization_percent"`
+
+	// Seq numbers the background samples taken of the handle and
+	// SampledAt is when the latest was taken; both stay put between
+	// sampler ticks, so equal seqs mean the same underlying sample
+	Seq       uint64    `json:"seq"`
+	SampledAt time.Time `json:"sampled_at"`
+
+	// SLACompliancePercent is the share of the handle's measurements,
+	// background samples and telemetry reads alike, whose achieved
+	// bandwidth met the floor
+	SLACompliancePercent float64 `json:"sla_compliance_percent"`
+
+	// DirtyBytes are a write-back handle's writes not yet flushed; always
+	// 0 for other persistence modes
+	DirtyBytes uint64 `json:"dirty_bytes"`
+}
+
+// BandwidthRequest adjusts a handle's bandwidth floor, given in any one of
+// the three units
+type BandwidthRequest struct {
+	FloorGBs   uint64  `json:"floor_GBs"`
+	FloorGbps  float64 `json:"floor_gbps,omitempty"`
+	FloorGiBps float64 `json:"floor_gibps,omitempty"`
+}
+
+// LatencyClassRequest migrates a handle to another tier
+type LatencyClassRequest struct {
+	Target string `json:"target"`
+}
+
+// tierInfo describes a memory tier backing a latency class
+type tierInfo struct {
+	Name         string
+	MaxGBs       uint64  // peak bandwidth per handle
+	BaseP99Ms    float64 // tail latency baseline
+	WattsPerGBs  float64 // power cost of delivered bandwidth
+	IdleWatts    float64
+	TempBaseline float64
+}
+
+var tiers = map[string]tierInfo{
+	"T0": {Name: "HBM", MaxGBs: 1000, BaseP99Ms: 0.1, WattsPerGBs: 0.02, IdleWatts: 5, TempBaseline: 55},
+	"T1": {Name: "DRAM", MaxGBs: 400, BaseP99Ms: 0.2, WattsPerGBs: 0.03, IdleWatts: 3, TempBaseline: 45},
+	"T2": {Name: "CXL", MaxGBs: 200, BaseP99Ms: 0.5, WattsPerGBs: 0.05, IdleWatts: 4, TempBaseline: 42},
+	"T3": {Name: "Persistent", MaxGBs: 100, BaseP99Ms: 2.0, WattsPerGBs: 0.08, IdleWatts: 6, TempBaseline: 40},
+}
+
+var persistenceModes = map[string]bool{"none": true, "write-back": true, "durable": true}
+
+// ErrNotFound marks lookups of unknown handles
+var ErrNotFound = errors.New("not found")
+
+// handleState is a stored handle plus its live counters
+type handleState struct {
+	handle    FFMHandle
+	seq       uint64    // background samples taken
+	sampledAt time.Time // when the latest was taken, or creation time
+	sla       slaState
+	// dirtyBytes are write-back writes not yet flushed
+	dirtyBytes uint64
+}
+
+// MemQoSService manages FFM handles in an in-memory, mutex-guarded store
+type MemQoSService struct {
+	mu           sync.RWMutex
+	handles      *bounded.BoundedStore[*handleState]
+	reservations *reservations.Table[FFMHandle]
+	faults       *faults.Registry
+	events       *eventLog
+	waiters      []*allocWaiter // allocations waiting for capacity, oldest first
+	epoch        time.Time      // monotonic origin of sample timestamps
+	journal      *journal.Journal
+
+	// FaultsEnabled mounts the fault-injection admin API
+	FaultsEnabled bool
+
+	// Quotas caps each security domain's holdings; domains without an
+	// entry get DefaultQuota
+	Quotas       map[string]DomainQuota
+	DefaultQuota DomainQuota
+
+	// ReservationTTL is how long an uncommitted reservation holds its
+	// quota unless the request sets its own TTL
+	ReservationTTL time.Duration
+
+	// SampleInterval is the period of the background telemetry sampler
+	SampleInterval time.Duration
+
+	// TierCapacities caps the bytes and bandwidth floors each latency
+	// class backs in total; classes without an entry are unlimited
+	TierCapacities map[string]TierCapacity
+
+	// BreachSamples is how many consecutive measurements below a handle's
+	// floor publish a floor_breach event
+	BreachSamples int
+}
+
+// NewMemQoSService creates a new memqosd service
+func NewMemQoSService() *MemQoSService {
+	s := &MemQoSService{
+		handles:        bounded.New[*handleState](defaultMaxHandles),
+		faults:         newFaultRegistry(),
+		events:         newEventLog(),
+		epoch:          time.Now(),
+		Quotas:         make(map[string]DomainQuota),
+		TierCapacities: make(map[string]TierCapacity),
+		ReservationTTL: 30 * time.Second,
+		SampleInterval: time.Second,
+		BreachSamples:  defaultBreachSamples,
+	}
+	s.handles.Pinned = (*handleState).leaseActive
+	s.handles.OnEvict = s.evictHandleLocked
+	s.reservations = reservations.New(&s.mu, s.releaseReservation)
+	return s
+}
+
+// defaultMaxHandles bounds the handles held before those whose policy
+// lease has lapsed are evicted, least recently used first
+const defaultMaxHandles = 65536
+
+// leaseActive reports whether a handle is within its policy lease. Leased
+// handles are never evicted.
+func (st *handleState) leaseActive() bool {
+	lease := time.Duration(st.handle.PolicyLeaseTTLsec) * time.Second
+	return time.Since(st.handle.CreatedAt) < lease
+}
+
+// evictHandleLocked publishes the eviction of a lapsed handle. The handle
+// store calls it from the Set that evicted it, with the store lock held;
+// the capacity it frees goes to waiters on the next admission pass.
+func (s *MemQoSService) evictHandleLocked(id string, state *handleState, reason string) {
+	log.Printf("evicted ffm handle %s (%s)", id, reason)
+	s.journalLocked(opFree, state.handle)
+	s.events.publish(Event{
+		Kind:              EventLeaseEvicted,
+		HandleID:          id,
+		SecurityDomain:    state.handle.SecurityDomain,
+		At:                time.Now().UTC(),
+		BandwidthFloorGBs: state.handle.BandwidthFloorGBs,
+	})
+}
+
+// validate checks an allocation request against the tier table, filling
+// in the default persistence mode and security domain and resolving the bandwidth floor to GB/s
+func validate(req *FFMAllocRequest) (tierInfo, error) {
+	tier, ok := tiers[req.LatencyClass]
+	if !ok {
+		return tierInfo{}, fmt.Errorf("unsupported latency_class: %s (T0..T3)", req.LatencyClass)
+	}
+	floor, err := resolveFloorGBs(req.BandwidthFloorGBs, req.BandwidthFloorGbps, req.BandwidthFloorGiBps, "bandwidth_floor")
+	if err != nil {
+		return tierInfo{}, err
+	}
+	req.BandwidthFloorGBs = floor
+	if req.Bytes == 0 {
+		return tierInfo{}, fmt.Errorf("bytes must be positive")
+	}
+	if req.BandwidthFloorGBs > tier.MaxGBs {
+		return tierInfo{}, fmt.Errorf("bandwidth_floor_GBs %d exceeds %s (%s) maximum of %d", req.BandwidthFloorGBs, req.LatencyClass, tier.Name, tier.MaxGBs)
+	}
+	if req.Persistence == "" {
+		req.Persistence = "none"
+	}
+	if req.SecurityDomain == "" {
+		req.SecurityDomain = defaultSecurityDomain
+	}
+	if !persistenceModes[req.Persistence] {
+		return tierInfo{}, fmt.Errorf("unsupported persistence: %s (none|write-back|durable)", req.Persistence)
+	}
+	if req.AttestationRequired && req.AttestationTicket == "" {
+		return tierInfo{}, fmt.Errorf("attestation_ticket required")
+	}
+	if err := labels.Validate(req.Labels); err != nil {
+		return tierInfo{}, err
+	}
+	if req.WaitMs < 0 || time.Duration(req.WaitMs)*time.Millisecond > maxAllocWait {
+		return tierInfo{}, fmt.Errorf("wait_ms must be between 0 and %d", maxAllocWait.Milliseconds())
+	}
+	return tier, nil
+}
+
+// Allocate validates a request and creates a new FFM handle, charged to
+// its security domain's quota and its latency class's capacity
+func (s *MemQoSService) Allocate(req FFMAllocRequest) (*FFMHandle, error) {
+	return s.AllocateContext(context.Background(), req)
+}
+
+// prepareHandle validates a request into a handle that is not yet stored
+func prepareHandle(req FFMAllocRequest) (FFMHandle, error) {
+	if _, err := validate(&req); err != nil {
+		return FFMHandle{}, err
+	}
+
+	handle := FFMHandle{
+		ID:                generateID(),
+		Bytes:             req.Bytes,
+		LatencyClass:      req.LatencyClass,
+		BandwidthFloorGBs: req.BandwidthFloorGBs,
+		Persistence:       req.Persistence,
+		Shareable:         req.Shareable,
+		SecurityDomain:    req.SecurityDomain,
+		CreatedAt:         time.Now().UTC(),
+		PolicyLeaseTTLsec: 3600,
+		FDs:               []string{"/proc/self/fd/37"},
+		Labels:            maps.Clone(req.Labels),
+	}
+	handle.setDerivedBandwidth()
+	return handle, nil
+}
+
+// Get returns a handle by ID
+func (s *MemQoSService) Get(id string) (*FFMHandle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state, exists := s.handles.Peek(id)
+	if !exists {
+		return nil, fmt.Errorf("ffm handle %s %w", id, ErrNotFound)
+	}
+	handle := state.handle
+	return &handle, nil
+}
+
+// List returns the handles matching sel ordered by creation time
+func (s *MemQoSService) List(sel labels.Selector) []FFMHandle {
+	s.mu.RLock()
+	handles := make([]FFMHandle, 0, s.handles.Len())
+	for _, state := range s.handles.Values() {
+		if sel.Matches(state.handle.Labels) {
+			handles = append(handles, state.handle)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(handles, func(i, j int) bool {
+		if handles[i].CreatedAt.Equal(handles[j].CreatedAt) {
+			return handles[i].ID < handles[j].ID
+		}
+		return handles[i].CreatedAt.Before(handles[j].CreatedAt)
+	})
+	return handles
+}
+
+// Telemetry returns a telemetry sample for a handle. An active bandwidth
+// shortfall fault reduces the achieved bandwidth it reports.
+func (s *MemQoSService) Telemetry(id string) (*FFMTelemetry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, exists := s.handles.Peek(id)
+	if !exists {
+		return nil, fmt.Errorf("ffm handle %s %w", id, ErrNotFound)
+	}
+
+	shortfall := 0.0
+	if fault := s.faults.Take(FaultBandwidthShortfall, id); fault != nil {
+		shortfall = fault.ShortfallPercent
+	}
+
+	t := state.measure(shortfall)
+	s.observeLocked(state, t, time.Now().UTC())
+	t.Seq, t.SampledAt = state.seq, state.sampledAt
+	t.SLACompliancePercent = state.sla.compliancePercent()
+	t.DirtyBytes = state.dirtyBytes
+	return &t, nil
+}
+
+// measure takes a reading of the handle's tier with measurement jitter.
+// The caller must hold the store lock.
+func (state *handleState) measure(shortfallPercent float64) FFMTelemetry {
+	tier := tiers[state.handle.LatencyClass]
+	floor := float64(state.handle.BandwidthFloorGBs)
+
+	achieved := math.Min(floor*(1.0+mrand.Float64()*0.2), float64(tier.MaxGBs))
+	achieved *= 1 - shortfallPercent/100
+	state.handle.AchievedGBs = uint64(achieved)
+	state.handle.setDerivedBandwidth()
+	state.handle.MovedPages += uint64(mrand.Intn(64))
+	state.dirty()
+	state.handle.TailP99Ms = tier.BaseP99Ms * (1 + mrand.Float64()*0.3) * (1 + shortfallPercent/100)
+
+	return FFMTelemetry{
+		AchievedGBs:   state.handle.AchievedGBs,
+		AchievedGbps:  state.handle.AchievedGbps,
+		AchievedGiBps: state.handle.AchievedGiBps,
+		MovedPages:    state.handle.MovedPages,
+		TailP99Ms:     state.handle.TailP99Ms,
+		Temperature:   tier.TempBaseline + achieved/float64(tier.MaxGBs)*10 + (mrand.Float64() - 0.5),
+		PowerW:        tier.IdleWatts + achieved*tier.WattsPerGBs,
+		Utilization:   achieved / float64(tier.MaxGBs) * 100,
+	}
+}
+
+// AdjustBandwidth changes a handle's bandwidth floor, in GB/s
+func (s *MemQoSService) AdjustBandwidth(id string, floorGBs uint64) (*FFMHandle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, exists := s.handles.Peek(id)
+	if !exists {
+		return nil, fmt.Errorf("ffm handle %s %w", id, ErrNotFound)
+	}
+	tier := tiers[state.handle.LatencyClass]
+	if floorGBs > tier.MaxGBs {
+		return nil, fmt.Errorf("floor_GBs %d exceeds %s maximum of %d", floorGBs, state.handle.LatencyClass, tier.MaxGBs)
+	}
+	if err := s.checkCapacityLocked(state.handle.LatencyClass, state.handle.Bytes, floorGBs, id); err != nil {
+		return nil, err
+	}
+	state.handle.BandwidthFloorGBs = floorGBs
+	state.handle.setDerivedBandwidth()
+	s.journalLocked(opAdjustBandwidth, state.handle)
+	s.admitWaitersLocked()
+	handle := state.handle
+	return &handle, nil
+}
+
+// MigrateLatencyClass moves a handle to another tier
+func (s *MemQoSService) MigrateLatencyClass(id string, target string) (*FFMHandle, error) {
+	tier, ok := tiers[target]
+	if !ok {
+		return nil, fmt.Errorf("unsupported latency_class: %s (T0..T3)", target)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, exists := s.handles.Peek(id)
+	if !exists {
+		return nil, fmt.Errorf("ffm handle %s %w", id, ErrNotFound)
+	}
+	if state.handle.BandwidthFloorGBs > tier.MaxGBs {
+		return nil, fmt.Errorf("bandwidth floor %d GB/s exceeds %s maximum of %d", state.handle.BandwidthFloorGBs, target, tier.MaxGBs)
+	}
+	if state.handle.LatencyClass != target {
+		if err := s.checkCapacityLocked(target, state.handle.Bytes, state.handle.BandwidthFloorGBs, ""); err != nil {
+			return nil, err
+		}
+		state.handle.LatencyClass = target
+		state.handle.MovedPages += state.handle.Bytes / 4096
+		s.journalLocked(opMigrate, state.handle)
+		s.admitWaitersLocked()
+	}
+	handle := state.handle
+	return &handle, nil
+}
+
+// Free releases a handle
+func (s *MemQoSService) Free(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, exists := s.handles.Peek(id)
+	if !exists {
+		return fmt.Errorf("ffm handle %s %w", id, ErrNotFound)
+	}
+	s.handles.Delete(id)
+	s.journalLocked(opFree, state.handle)
+	s.admitWaitersLocked()
+	return nil
+}
+
+// generateID generates an FFM handle ID
+func generateID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("ffm-%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return "ffm-" + hex.EncodeToString(b)
+}
+
+// HTTP handlers
+func (s *MemQoSService) handleAlloc(w http.ResponseWriter, r *http.Request) {
+	var req FFMAllocRequest
+	if err := jsonbody.Decode(r.Body, &req); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	handle, err := s.AllocateContext(r.Context(), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, handle)
+}
+
+func (s *MemQoSService) handleList(w http.ResponseWriter, r *http.Request) {
+	sel, err := labels.FromQuery(r.URL.Query())
+	if err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, s.List(sel))
+}
+
+func (s *MemQoSService) handleGet(w http.ResponseWriter, r *http.Request) {
+	handle, err := s.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, handle)
+}
+
+func (s *MemQoSService) handleTelemetry(w http.ResponseWriter, r *http.Request) {
+	telemetry, err := s.Telemetry(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if protowire.Accepts(r) {
+		protowire.Write(w, http.StatusOK, telemetry)
+		return
+	}
+	writeJSON(w, http.StatusOK, telemetry)
+}
+
+func (s *MemQoSService) handleBandwidth(w http.ResponseWriter, r *http.Request) {
+	var req BandwidthRequest
+	if err := jsonbody.Decode(r.Body, &req); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	floor, err := resolveFloorGBs(req.FloorGBs, req.FloorGbps, req.FloorGiBps, "floor")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	handle, err := s.AdjustBandwidth(mux.Vars(r)["id"], floor)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, handle)
+}
+
+func (s *MemQoSService) handleLatencyClass(w http.ResponseWriter, r *http.Request) {
+	var req LatencyClassRequest
+	if err := jsonbody.Decode(r.Body, &req); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	handle, err := s.MigrateLatencyClass(mux.Vars(r)["id"], req.Target)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, handle)
+}
+
+func (s *MemQoSService) handleFree(w http.ResponseWriter, r *http.Request) {
+	if err := s.Free(mux.Vars(r)["id"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *MemQoSService) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// writeError maps service errors onto API error codes
+func writeError(w http.ResponseWriter, err error) {
+	var quotaErr *QuotaError
+	if errors.As(err, &quotaErr) {
+		apierr.Write(w, apierr.New(apierr.CodeQuotaExceeded, "%s", err.Error()).WithDetails(quotaErr.Usage))
+		return
+	}
+	var capacityErr *CapacityError
+	if errors.As(err, &capacityErr) {
+		apierr.Write(w, apierr.New(apierr.CodeOversubscribed, "%s", err.Error()).WithDetails(capacityErr.Class))
+		return
+	}
+	code := apierr.CodeValidation
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, reservations.ErrUnknown):
+		code = apierr.CodeNotFound
+	case errors.Is(err, reservations.ErrExpired):
+		code = apierr.CodeConflict
+	}
+	apierr.Respond(w, code, err.Error())
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// newRouter wires the memqosd HTTP API
+func newRouter(s *MemQoSService) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(trace.Middleware("memqosd"))
+	api := router.PathPrefix("/v1/ffm").Subrouter()
+	api.Use(s.injectFaults)
+
+	api.HandleFunc("/alloc", s.handleAlloc).Methods("POST")
+	api.HandleFunc("/estimate", s.handleEstimate).Methods("POST")
+	api.HandleFunc("/reserve", s.handleReserve).Methods("POST")
+	api.HandleFunc("/reservations/{token}/commit", s.handleCommit).Methods("POST")
+	api.HandleFunc("/reservations/{token}", s.handleAbort).Methods("DELETE")
+	api.HandleFunc("/", s.handleList).Methods("GET")
+	api.HandleFunc("/capacity", s.handleCapacity).Methods("GET")
+	api.HandleFunc("/events", s.handleEvents).Methods("GET")
+	api.HandleFunc("/domains/{domain}/usage", s.handleDomainUsage).Methods("GET")
+	api.HandleFunc("/{id}", s.handleGet).Methods("GET")
+	api.HandleFunc("/{id}", s.handleFree).Methods("DELETE")
+	api.HandleFunc("/{id}/telemetry", s.handleTelemetry).Methods("GET")
+	api.HandleFunc("/{id}/flush", s.handleFlush).Methods("POST")
+	api.HandleFunc("/{id}/bandwidth", s.handleBandwidth).Methods("PATCH")
+	api.HandleFunc("/{id}/latency_class", s.handleLatencyClass).Methods("PATCH")
+
+	admin := router.PathPrefix("/v1/admin").Subrouter()
+	if s.journal != nil {
+		admin.HandleFunc("/journal", journal.Handler(s.journal)).Methods("GET")
+	}
+	if s.FaultsEnabled {
+		admin.HandleFunc("/faults", s.handleAddFault).Methods("POST")
+		admin.HandleFunc("/faults", s.handleListFaults).Methods("GET")
+		admin.HandleFunc("/faults", s.handleClearFaults).Methods("DELETE")
+	}
+
+	router.HandleFunc("/health", s.handleHealth).Methods("GET")
+	return router
+}
+
+func main() {
+	addr := flag.String("addr", ":8081", "listen address")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate chain; serves HTTPS with HTTP/2 instead of plaintext HTTP")
+	tlsKey := flag.String("tls-key", "", "PEM private key of -tls-cert")
+	tlsEndorsement := flag.String("tls-endorsement", "", "post-quantum endorsement of -tls-cert, made with tls-endorse, sent on every response")
+	enableFaults := flag.Bool("enable-faults", false, "mount the fault-injection admin API (never in production)")
+	quotas := quotaFlag{}
+	flag.Var(quotas, "domain-quota", "per-domain quota as domain=max_bytes:max_handles, 0 for unlimited (repeatable)")
+	reservationTTL := flag.Duration("reservation-ttl", 30*time.Second, "default time an uncommitted reservation holds its quota")
+	defaultQuota := flag.String("default-quota", "0:0", "quota as max_bytes:max_handles for domains without -domain-quota")
+	sampleInterval := flag.Duration("sample-interval", time.Second, "telemetry sample interval")
+	breachSamples := flag.Int("sla-breach-samples", defaultBreachSamples, "consecutive measurements below a handle's bandwidth floor that publish a floor_breach event")
+	maxHandles := flag.Int("max-handles", defaultMaxHandles, "handles held before those with a lapsed policy lease are evicted, least recently used first")
+	capacities := capacityFlag{}
+	flag.Var(capacities, "tier-capacity", "total capacity of a latency class as class=bytes:bandwidth_GBs, 0 for unlimited (repeatable)")
+	journalPath := flag.String("journal", "", "append-only operation journal replayed on startup (empty = none)")
+	flag.Parse()
+
+	service := NewMemQoSService()
+	service.FaultsEnabled = *enableFaults
+	service.Quotas = quotas
+	service.TierCapacities = capacities
+	q, err := parseQuota(*defaultQuota)
+	if err != nil {
+		log.Fatalf("invalid -default-quota: %v", err)
+	}
+	service.DefaultQuota = q
+	if *reservationTTL <= 0 {
+		log.Fatal("reservation-ttl must be positive")
+	}
+	service.ReservationTTL = *reservationTTL
+	if *sampleInterval <= 0 {
+		log.Fatal("sample-interval must be positive")
+	}
+	service.SampleInterval = *sampleInterval
+	if *breachSamples <= 0 {
+		log.Fatal("sla-breach-samples must be positive")
+	}
+	service.BreachSamples = *breachSamples
+	if *maxHandles <= 0 {
+		log.Fatal("max-handles must be positive")
+	}
+	service.handles.MaxEntries = *maxHandles
+	if service.FaultsEnabled {
+		log.Println("WARNING: fault injection enabled")
+	}
+	if *journalPath != "" {
+		if err := service.OpenJournal(*journalPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	go service.RunSampler(context.Background())
+
+	tlsCfg := tlsserve.Config{CertFile: *tlsCert, KeyFile: *tlsKey, EndorsementFile: *tlsEndorsement}
+	log.Printf("memqosd listening on %s", *addr)
+	log.Fatal(tlsserve.ListenAndServe(*addr, newRouter(service), tlsCfg))
+}
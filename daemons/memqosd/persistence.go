@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	mrand "math/rand"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// dirtyPageSize is the granularity the write-back model dirties memory at
+const dirtyPageSize = 4096
+
+// FlushResponse reports a completed flush
+type FlushResponse struct {
+	FlushedBytes uint64    `json:"flushed_bytes"`
+	DirtyBytes   uint64    `json:"dirty_bytes"` // always 0 right after a flush
+	FlushedAt    time.Time `json:"flushed_at"`
+}
+
+// dirty models writes landing on a handle between measurements. A
+// write-back handle accumulates dirty pages, up to its size, until it is
+// flushed; durable handles write through and none-persistence handles
+// have nothing to persist, so neither ever holds dirty bytes.
+func (state *handleState) dirty() {
+	if state.handle.Persistence != "write-back" {
+		return
+	}
+	written := uint64(1+mrand.Intn(64)) * dirtyPageSize
+	state.dirtyBytes = min(state.dirtyBytes+written, state.handle.Bytes)
+}
+
+// Flush writes back a handle's pending writes. It fails for handles with
+// persistence none, which have no backing store to flush to.
+func (s *MemQoSService) Flush(id string) (*FlushResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, exists := s.handles.Peek(id)
+	if !exists {
+		return nil, fmt.Errorf("ffm handle %s %w", id, ErrNotFound)
+	}
+	if state.handle.Persistence == "none" {
+		return nil, fmt.Errorf("ffm handle %s has persistence none and cannot be flushed", id)
+	}
+	resp := &FlushResponse{FlushedBytes: state.dirtyBytes, FlushedAt: time.Now().UTC()}
+	state.dirtyBytes = 0
+	return resp, nil
+}
+
+func (s *MemQoSService) handleFlush(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.Flush(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDirtyBytesAccumulateUntilFlush(t *testing.T) {
+	s := NewMemQoSService()
+	handle, err := s.Allocate(FFMAllocRequest{Bytes: 64 << 20, LatencyClass: "T1", Persistence: "write-back"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var prev uint64
+	for i := 0; i < 5; i++ {
+		tel, err := s.Telemetry(handle.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tel.DirtyBytes <= prev || tel.DirtyBytes%dirtyPageSize != 0 {
+			t.Fatalf("read %d: dirty_bytes %d after %d", i, tel.DirtyBytes, prev)
+		}
+		prev = tel.DirtyBytes
+	}
+
+	flushed, err := s.Flush(handle.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flushed.FlushedBytes != prev || flushed.DirtyBytes != 0 {
+		t.Errorf("flush = %+v, want %d bytes flushed", flushed, prev)
+	}
+	s.mu.RLock()
+	state, _ := s.handles.Peek(handle.ID)
+	dirty := state.dirtyBytes
+	s.mu.RUnlock()
+	if dirty != 0 {
+		t.Errorf("dirty bytes after flush = %d", dirty)
+	}
+	if tel, _ := s.Telemetry(handle.ID); tel.DirtyBytes == 0 || tel.DirtyBytes > 64*dirtyPageSize {
+		t.Errorf("dirty_bytes one read after the flush = %d", tel.DirtyBytes)
+	}
+}
+
+func TestDirtyBytesCappedAtHandleSize(t *testing.T) {
+	s := NewMemQoSService()
+	handle, _ := s.Allocate(FFMAllocRequest{Bytes: 2 * dirtyPageSize, LatencyClass: "T1", Persistence: "write-back"})
+	for i := 0; i < 10; i++ {
+		if tel, _ := s.Telemetry(handle.ID); tel.DirtyBytes > handle.Bytes {
+			t.Fatalf("dirty_bytes %d exceeds the handle's %d bytes", tel.DirtyBytes, handle.Bytes)
+		}
+	}
+}
+
+func TestFlushByPersistence(t *testing.T) {
+	s := NewMemQoSService()
+	router := newRouter(s)
+	for persistence, want := range map[string]int{"none": http.StatusBadRequest, "durable": http.StatusOK, "write-back": http.StatusOK} {
+		handle, err := s.Allocate(FFMAllocRequest{Bytes: 1 << 20, LatencyClass: "T2", Persistence: persistence})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tel, _ := s.Telemetry(handle.ID); persistence != "write-back" && tel.DirtyBytes != 0 {
+			t.Errorf("%s handle reports %d dirty bytes", persistence, tel.DirtyBytes)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/ffm/"+handle.ID+"/flush", nil))
+		if rec.Code != want {
+			t.Errorf("flush %s handle: status %d, want %d: %s", persistence, rec.Code, want, rec.Body)
+		}
+		if persistence == "none" && !strings.Contains(rec.Body.String(), "persistence none") {
+			t.Errorf("flush none handle body = %s", rec.Body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/ffm/ffm-missing/flush", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("flush unknown handle: status %d", rec.Code)
+	}
+}
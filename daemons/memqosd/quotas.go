@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultSecurityDomain is the tenant of handles allocated without a
+// security_domain
+const defaultSecurityDomain = "default"
+
+// ErrQuotaExceeded marks allocations refused by a domain's quota
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// DomainQuota caps what one security domain may hold; zero means unlimited
+type DomainQuota struct {
+	MaxBytes   uint64 `json:"max_bytes"`
+	MaxHandles int    `json:"max_handles"`
+}
+
+// DomainUsage is a security domain's current consumption against its quota
+type DomainUsage struct {
+	Domain   string      `json:"domain"`
+	Bytes    uint64      `json:"bytes"`
+	Handles  int         `json:"handles"`
+	Reserved int         `json:"reserved_handles"` // of Handles, held by uncommitted reservations
+	Quota    DomainQuota `json:"quota"`
+}
+
+// QuotaError reports which limit an allocation would exceed
+type QuotaError struct {
+	Usage  DomainUsage
+	reason string
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("security domain %s %v: %s", e.Usage.Domain, ErrQuotaExceeded, e.reason)
+}
+
+func (e *QuotaError) Unwrap() error { return ErrQuotaExceeded }
+
+// quotaFor returns the quota configured for a domain, falling back to the
+// default quota
+func (s *MemQoSService) quotaFor(domain string) DomainQuota {
+	if q, ok := s.Quotas[domain]; ok {
+		return q
+	}
+	return s.DefaultQuota
+}
+
+// usageLocked totals the handles held by a domain, counting pending
+// reservations. The caller must hold the store lock.
+func (s *MemQoSService) usageLocked(domain string) DomainUsage {
+	usage := DomainUsage{Domain: domain, Quota: s.quotaFor(domain)}
+	for _, state := range s.handles.Values() {
+		if state.handle.SecurityDomain == domain {
+			usage.Bytes += state.handle.Bytes
+			usage.Handles++
+		}
+	}
+	s.reservations.Each(func(h FFMHandle) {
+		if h.SecurityDomain == domain {
+			usage.Bytes += h.Bytes
+			usage.Handles++
+			usage.Reserved++
+		}
+	})
+	return usage
+}
+
+// checkQuotaLocked refuses an allocation of size bytes that would take the
+// domain past its quota. The caller must hold the store lock.
+func (s *MemQoSService) checkQuotaLocked(domain string, bytes uint64) error {
+	usage := s.usageLocked(domain)
+	q := usage.Quota
+	if q.MaxHandles > 0 && usage.Handles+1 > q.MaxHandles {
+		return &QuotaError{Usage: usage, reason: fmt.Sprintf("holds %d of %d handles", usage.Handles, q.MaxHandles)}
+	}
+	if q.MaxBytes > 0 && usage.Bytes+bytes > q.MaxBytes {
+		return &QuotaError{Usage: usage, reason: fmt.Sprintf("%d bytes requested with %d of %d in use", bytes, usage.Bytes, q.MaxBytes)}
+	}
+	return nil
+}
+
+// Usage returns a domain's current consumption
+func (s *MemQoSService) Usage(domain string) DomainUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.usageLocked(domain)
+}
+
+func (s *MemQoSService) handleDomainUsage(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Usage(mux.Vars(r)["domain"]))
+}
+
+// parseQuota parses a "max_bytes:max_handles" quota; either side may be
+// empty or 0 for unlimited
+func parseQuota(v string) (DomainQuota, error) {
+	bytesStr, handlesStr, ok := strings.Cut(v, ":")
+	if !ok {
+		return DomainQuota{}, fmt.Errorf("quota %q is not max_bytes:max_handles", v)
+	}
+	var q DomainQuota
+	var err error
+	if bytesStr != "" {
+		if q.MaxBytes, err = strconv.ParseUint(bytesStr, 10, 64); err != nil {
+			return DomainQuota{}, fmt.Errorf("quota %q: invalid max_bytes", v)
+		}
+	}
+	if handlesStr != "" {
+		if q.MaxHandles, err = strconv.Atoi(handlesStr); err != nil || q.MaxHandles < 0 {
+			return DomainQuota{}, fmt.Errorf("quota %q: invalid max_handles", v)
+		}
+	}
+	return q, nil
+}
+
+// quotaFlag collects repeated -domain-quota domain=max_bytes:max_handles flags
+type quotaFlag map[string]DomainQuota
+
+func (f quotaFlag) String() string {
+	domains := make([]string, 0, len(f))
+	for d := range f {
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
+	parts := make([]string, 0, len(domains))
+	for _, d := range domains {
+		parts = append(parts, fmt.Sprintf("%s=%d:%d", d, f[d].MaxBytes, f[d].MaxHandles))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f quotaFlag) Set(v string) error {
+	domain, quota, ok := strings.Cut(v, "=")
+	if !ok || domain == "" {
+		return fmt.Errorf("%q is not domain=max_bytes:max_handles", v)
+	}
+	q, err := parseQuota(quota)
+	if err != nil {
+		return err
+	}
+	f[domain] = q
+	return nil
+}
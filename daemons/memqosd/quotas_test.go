@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+func TestDomainByteQuotaIsolatesTenants(t *testing.T) {
+	s := NewMemQoSService()
+	s.Quotas["tenant-a"] = DomainQuota{MaxBytes: 3 << 30}
+	alloc := func(domain string) (*FFMHandle, error) {
+		return s.Allocate(FFMAllocRequest{Bytes: 2 << 30, LatencyClass: "T1", SecurityDomain: domain})
+	}
+
+	first, err := alloc("tenant-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alloc("tenant-a"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("allocation past tenant-a's byte quota: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := alloc("tenant-b"); err != nil {
+			t.Fatalf("tenant-b allocation %d: %v", i, err)
+		}
+	}
+	if usage := s.Usage("tenant-a"); usage.Bytes != 2<<30 || usage.Handles != 1 {
+		t.Errorf("tenant-a usage = %+v", usage)
+	}
+
+	// Freeing a handle returns its bytes to the quota
+	if err := s.Free(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alloc("tenant-a"); err != nil {
+		t.Errorf("allocation after free: %v", err)
+	}
+}
+
+func TestQuotaHTTP(t *testing.T) {
+	s := NewMemQoSService()
+	s.DefaultQuota = DomainQuota{MaxHandles: 1}
+	router := newRouter(s)
+	body := `{"bytes":1024,"latency_class":"T2","security_domain":"lab"}`
+
+	for i, want := range []int{http.StatusCreated, http.StatusTooManyRequests} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/ffm/alloc", strings.NewReader(body)))
+		if rec.Code != want {
+			t.Fatalf("allocation %d: status = %d, want %d: %s", i, rec.Code, want, rec.Body)
+		}
+		if want == http.StatusTooManyRequests {
+			var e apierr.Error
+			json.Unmarshal(rec.Body.Bytes(), &e)
+			if e.Code != apierr.CodeQuotaExceeded || e.Details == nil {
+				t.Errorf("quota error body = %s", rec.Body)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/ffm/domains/lab/usage", nil))
+	var usage DomainUsage
+	if err := json.Unmarshal(rec.Body.Bytes(), &usage); err != nil {
+		t.Fatal(err)
+	}
+	if usage.Domain != "lab" || usage.Handles != 1 || usage.Bytes != 1024 || usage.Quota.MaxHandles != 1 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	for in, want := range map[string]DomainQuota{"100:2": {100, 2}, ":5": {0, 5}, "7:": {7, 0}, "0:0": {}} {
+		if got, err := parseQuota(in); err != nil || got != want {
+			t.Errorf("parseQuota(%q) = %+v, %v", in, got, err)
+		}
+	}
+	for _, in := range []string{"100", "x:1", "1:-2"} {
+		if _, err := parseQuota(in); err == nil {
+			t.Errorf("parseQuota(%q) accepted", in)
+		}
+	}
+}
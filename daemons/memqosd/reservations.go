@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/jsonbody"
+	"github.com/corridoros/pkg/reservations"
+	"github.com/gorilla/mux"
+)
+
+// ReserveRequest is an allocation to hold until it is committed or expires
+type ReserveRequest struct {
+	FFMAllocRequest
+	TTLSeconds int `json:"ttl_s,omitempty"` // 0 uses the service default
+}
+
+// Reservation is a tentatively held FFM allocation. It counts against its
+// domain's quota until the token is committed, aborted or it expires.
+type Reservation struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Handle    FFMHandle `json:"handle"` // as it will be once committed
+}
+
+// Reserve validates an allocation and holds its quota and latency class
+// capacity for the TTL without creating the handle
+func (s *MemQoSService) Reserve(req ReserveRequest) (*Reservation, error) {
+	ttl, err := reservations.TTL(req.TTLSeconds, s.ReservationTTL)
+	if err != nil {
+		return nil, err
+	}
+	if req.WaitMs != 0 {
+		return nil, fmt.Errorf("wait_ms is only supported on allocation")
+	}
+	handle, err := prepareHandle(req.FFMAllocRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkQuotaLocked(handle.SecurityDomain, handle.Bytes); err != nil {
+		return nil, err
+	}
+	if err := s.checkCapacityLocked(handle.LatencyClass, handle.Bytes, handle.BandwidthFloorGBs, ""); err != nil {
+		return nil, err
+	}
+	token, expiresAt := s.reservations.Hold(handle, ttl)
+	return &Reservation{Token: token, ExpiresAt: expiresAt, Handle: handle}, nil
+}
+
+// Commit creates a reserved handle. It fails once the TTL has passed.
+func (s *MemQoSService) Commit(token string) (*FFMHandle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	handle, err := s.reservations.Commit(token)
+	if err != nil {
+		return nil, err
+	}
+	handle.CreatedAt = time.Now().UTC()
+	s.handles.Set(handle.ID, &handleState{handle: handle, sampledAt: handle.CreatedAt})
+	s.journalLocked(opAllocate, handle)
+	return &handle, nil
+}
+
+// Abort drops a reservation without committing it
+func (s *MemQoSService) Abort(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reservations.Abort(token)
+}
+
+// releaseReservation admits waiters into the capacity of a reservation that
+// expired or was aborted. The reservation table calls it with the store
+// lock held.
+func (s *MemQoSService) releaseReservation(FFMHandle) {
+	s.admitWaitersLocked()
+}
+
+func (s *MemQoSService) handleReserve(w http.ResponseWriter, r *http.Request) {
+	var req ReserveRequest
+	if err := jsonbody.Decode(r.Body, &req); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	res, err := s.Reserve(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, res)
+}
+
+func (s *MemQoSService) handleCommit(w http.ResponseWriter, r *http.Request) {
+	handle, err := s.Commit(mux.Vars(r)["token"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, handle)
+}
+
+func (s *MemQoSService) handleAbort(w http.ResponseWriter, r *http.Request) {
+	if err := s.Abort(mux.Vars(r)["token"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/corridoros/pkg/reservations"
+)
+
+func TestReservationHoldsQuotaUntilExpiry(t *testing.T) {
+	s := NewMemQoSService()
+	s.Quotas["lab"] = DomainQuota{MaxHandles: 1}
+	s.ReservationTTL = 20 * time.Millisecond
+	req := FFMAllocRequest{Bytes: 1 << 20, LatencyClass: "T2", SecurityDomain: "lab"}
+
+	res, err := s.Reserve(ReserveRequest{FFMAllocRequest: req})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := s.Usage("lab"); usage.Handles != 1 || usage.Reserved != 1 {
+		t.Errorf("usage while reserved = %+v", usage)
+	}
+	if _, err := s.Allocate(req); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("allocation against a held quota = %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := s.Commit(res.Token); !errors.Is(err, reservations.ErrUnknown) {
+		t.Fatalf("commit after TTL = %v, want an expired reservation", err)
+	}
+	if _, err := s.Allocate(req); err != nil {
+		t.Errorf("quota still held after expiry: %v", err)
+	}
+}
+
+func TestCommitWithinTTLCreatesHandle(t *testing.T) {
+	s := NewMemQoSService()
+	res, err := s.Reserve(ReserveRequest{FFMAllocRequest: FFMAllocRequest{Bytes: 1 << 20, LatencyClass: "T1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle, err := s.Commit(res.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Get(handle.ID); err != nil || got.ID != res.Handle.ID {
+		t.Errorf("committed handle = %+v, %v", got, err)
+	}
+	if usage := s.Usage(defaultSecurityDomain); usage.Handles != 1 || usage.Reserved != 0 {
+		t.Errorf("usage after commit = %+v", usage)
+	}
+}
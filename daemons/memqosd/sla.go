@@ -0,0 +1,61 @@
+package main
+
+import "time"
+
+// defaultBreachSamples is how many samples in a row must miss a handle's
+// bandwidth floor before a breach is reported
+const defaultBreachSamples = 3
+
+// slaState tracks how often a handle's measured bandwidth met its floor
+type slaState struct {
+	samples, met uint64
+	belowRun     int  // consecutive samples below the floor
+	metRun       int  // consecutive samples at or above it
+	breached     bool // a breach was reported and has not recovered
+}
+
+// compliancePercent is the share of samples that met the floor, 100 before
+// any sample is taken
+func (sla *slaState) compliancePercent() float64 {
+	if sla.samples == 0 {
+		return 100
+	}
+	return float64(sla.met) / float64(sla.samples) * 100
+}
+
+// observeLocked scores a measurement against the handle's floor. The
+// BreachSamples-th consecutive miss publishes a floor_breach event; once
+// breached, as many consecutive samples meeting the floor publish
+// floor_recovered. The caller must hold the store lock.
+func (s *MemQoSService) observeLocked(state *handleState, t FFMTelemetry, at time.Time) {
+	sla := &state.sla
+	sla.samples++
+	if t.AchievedGBs >= state.handle.BandwidthFloorGBs {
+		sla.met++
+		sla.belowRun = 0
+		sla.metRun++
+		if sla.breached && sla.metRun >= s.BreachSamples {
+			sla.breached = false
+			s.publishSLAEvent(EventFloorRecovered, state, t, at, sla.metRun)
+		}
+		return
+	}
+	sla.metRun = 0
+	sla.belowRun++
+	if !sla.breached && sla.belowRun >= s.BreachSamples {
+		sla.breached = true
+		s.publishSLAEvent(EventFloorBreach, state, t, at, sla.belowRun)
+	}
+}
+
+func (s *MemQoSService) publishSLAEvent(kind string, state *handleState, t FFMTelemetry, at time.Time, run int) {
+	s.events.publish(Event{
+		Kind:               kind,
+		HandleID:           state.handle.ID,
+		SecurityDomain:     state.handle.SecurityDomain,
+		At:                 at,
+		BandwidthFloorGBs:  state.handle.BandwidthFloorGBs,
+		AchievedGBs:        t.AchievedGBs,
+		ConsecutiveSamples: run,
+	})
+}
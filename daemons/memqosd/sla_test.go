@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/corridoros/pkg/faults"
+)
+
+// slaServer serves a faults-enabled service holding one handle with a
+// bandwidth floor
+func slaServer(t *testing.T) (*httptest.Server, *MemQoSService, *FFMHandle) {
+	t.Helper()
+	s := NewMemQoSService()
+	s.FaultsEnabled = true
+	handle, err := s.Allocate(FFMAllocRequest{Bytes: 1 << 20, LatencyClass: "T1", BandwidthFloorGBs: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(newRouter(s))
+	t.Cleanup(srv.Close)
+	return srv, s, handle
+}
+
+func injectShortfall(t *testing.T, srv *httptest.Server, id string, count int) {
+	t.Helper()
+	body, _ := json.Marshal(faults.Request{Kind: FaultBandwidthShortfall, Target: id, Count: count, ShortfallPercent: 50})
+	resp, err := srv.Client().Post(srv.URL+"/v1/admin/faults", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("inject shortfall: status %d", resp.StatusCode)
+	}
+}
+
+func telemetry(t *testing.T, srv *httptest.Server, id string) FFMTelemetry {
+	t.Helper()
+	resp, err := srv.Client().Get(srv.URL + "/v1/ffm/" + id + "/telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out FFMTelemetry
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestShortfallDropsComplianceAndBreaches(t *testing.T) {
+	srv, s, handle := slaServer(t)
+	if got := telemetry(t, srv, handle.ID); got.SLACompliancePercent != 100 {
+		t.Fatalf("compliance before the fault = %v", got.SLACompliancePercent)
+	}
+
+	injectShortfall(t, srv, handle.ID, defaultBreachSamples)
+	var last FFMTelemetry
+	for i := 0; i < defaultBreachSamples; i++ {
+		last = telemetry(t, srv, handle.ID)
+		if last.AchievedGBs >= handle.BandwidthFloorGBs {
+			t.Fatalf("sample %d under the fault achieved %d GB/s", i, last.AchievedGBs)
+		}
+	}
+	if want := 100.0 / float64(1+defaultBreachSamples); last.SLACompliancePercent != want {
+		t.Errorf("compliance after %d misses = %v, want %v", defaultBreachSamples, last.SLACompliancePercent, want)
+	}
+
+	backlog, ch := s.events.subscribe(0)
+	s.events.unsubscribe(ch)
+	if len(backlog) != 1 || backlog[0].Kind != EventFloorBreach || backlog[0].HandleID != handle.ID || backlog[0].ConsecutiveSamples != defaultBreachSamples {
+		t.Fatalf("events after the shortfall = %+v", backlog)
+	}
+
+	// As many samples meeting the floor again report the recovery
+	for i := 0; i < defaultBreachSamples; i++ {
+		telemetry(t, srv, handle.ID)
+	}
+	backlog, ch = s.events.subscribe(backlog[0].Seq)
+	s.events.unsubscribe(ch)
+	if len(backlog) != 1 || backlog[0].Kind != EventFloorRecovered {
+		t.Errorf("events after recovery = %+v", backlog)
+	}
+}
+
+func TestShortMissRunDoesNotBreach(t *testing.T) {
+	srv, s, handle := slaServer(t)
+	injectShortfall(t, srv, handle.ID, defaultBreachSamples-1)
+	for i := 0; i < defaultBreachSamples+1; i++ {
+		telemetry(t, srv, handle.ID)
+	}
+	backlog, ch := s.events.subscribe(0)
+	s.events.unsubscribe(ch)
+	if len(backlog) != 0 {
+		t.Errorf("events after %d misses = %+v", defaultBreachSamples-1, backlog)
+	}
+}
+
+func TestEventsStreamDeliversBreach(t *testing.T) {
+	srv, _, handle := slaServer(t)
+	resp, err := srv.Client().Get(srv.URL + "/v1/ffm/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	injectShortfall(t, srv, handle.ID, defaultBreachSamples)
+	for i := 0; i < defaultBreachSamples; i++ {
+		telemetry(t, srv, handle.ID)
+	}
+
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream closed before the breach event")
+			}
+			data, found := strings.CutPrefix(line, "data: ")
+			if !found {
+				continue
+			}
+			var e Event
+			if err := json.Unmarshal([]byte(data), &e); err != nil {
+				t.Fatal(err)
+			}
+			if e.Kind != EventFloorBreach || e.HandleID != handle.ID || e.Seq != 1 {
+				t.Errorf("streamed event = %+v", e)
+			}
+			return
+		case <-timeout:
+			t.Fatal("no breach event streamed")
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// RunSampler takes a telemetry sample of every handle each SampleInterval
+// until ctx is cancelled
+func (s *MemQoSService) RunSampler(ctx context.Context) {
+	ticker := time.NewTicker(s.SampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.sampleAll(s.sampleTime(now))
+		}
+	}
+}
+
+// sampleTime converts a tick to a UTC timestamp measured on the monotonic
+// clock from the service's start, so timestamps keep advancing even if the
+// wall clock is stepped back
+func (s *MemQoSService) sampleTime(tick time.Time) time.Time {
+	return s.epoch.Add(tick.Sub(s.epoch)).UTC()
+}
+
+// sampleAll measures every handle once, advancing its sequence and
+// scoring the measurement against the handle's floor
+func (s *MemQoSService) sampleAll(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, state := range s.handles.Values() {
+		state.seq++
+		state.sampledAt = now
+		s.observeLocked(state, state.measure(0), now)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import "github.com/corridoros/pkg/protowire"
+
+// MarshalProto encodes a reading as corridoros.v1.FFMTelemetry
+func (t FFMTelemetry) MarshalProto() []byte {
+	b := make([]byte, 0, 128)
+	b = protowire.AppendUint64(b, 1, t.AchievedGBs)
+	b = protowire.AppendDouble(b, 2, t.AchievedGbps)
+	b = protowire.AppendDouble(b, 3, t.AchievedGiBps)
+	b = protowire.AppendUint64(b, 4, t.MovedPages)
+	b = protowire.AppendDouble(b, 5, t.TailP99Ms)
+	b = protowire.AppendDouble(b, 6, t.Temperature)
+	b = protowire.AppendDouble(b, 7, t.PowerW)
+	b = protowire.AppendDouble(b, 8, t.Utilization)
+	b = protowire.AppendUint64(b, 9, t.Seq)
+	b = protowire.AppendTime(b, 10, t.SampledAt)
+	b = protowire.AppendDouble(b, 11, t.SLACompliancePercent)
+	return protowire.AppendUint64(b, 12, t.DirtyBytes)
+}
+
+// UnmarshalProto decodes a corridoros.v1.FFMTelemetry
+func (t *FFMTelemetry) UnmarshalProto(b []byte) error {
+	*t = FFMTelemetry{}
+	return protowire.Range(b, func(f protowire.Field) error {
+		var err error
+		switch f.Num {
+		case 1:
+			t.AchievedGBs = f.Varint
+		case 2:
+			t.AchievedGbps = f.Double()
+		case 3:
+			t.AchievedGiBps = f.Double()
+		case 4:
+			t.MovedPages = f.Varint
+		case 5:
+			t.TailP99Ms = f.Double()
+		case 6:
+			t.Temperature = f.Double()
+		case 7:
+			t.PowerW = f.Double()
+		case 8:
+			t.Utilization = f.Double()
+		case 9:
+			t.Seq = f.Varint
+		case 10:
+			t.SampledAt, err = f.Time()
+		case 11:
+			t.SLACompliancePercent = f.Double()
+		case 12:
+			t.DirtyBytes = f.Varint
+		}
+		return err
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/corridoros/pkg/protowire"
+)
+
+func TestFFMTelemetryProtoMatchesJSON(t *testing.T) {
+	want := FFMTelemetry{
+		AchievedGBs:          48,
+		AchievedGbps:         384,
+		AchievedGiBps:        44.7,
+		MovedPages:           1 << 18,
+		TailP99Ms:            0.42,
+		Temperature:          55.5,
+		PowerW:               11.25,
+		Utilization:          72,
+		Seq:                  9,
+		SampledAt:            time.Date(2026, 3, 1, 12, 0, 0, 987654321, time.UTC),
+		SLACompliancePercent: 99.5,
+		DirtyBytes:           4096,
+	}
+	for _, want := range []FFMTelemetry{want, {}} {
+		var fromProto, fromJSON FFMTelemetry
+		if err := fromProto.UnmarshalProto(want.MarshalProto()); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(want)
+		if err := json.Unmarshal(data, &fromJSON); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(fromProto, want) || !reflect.DeepEqual(fromProto, fromJSON) {
+			t.Errorf("protobuf decoded %+v, JSON decoded %+v, want %+v", fromProto, fromJSON, want)
+		}
+	}
+}
+
+func TestTelemetryEndpointNegotiatesProtobuf(t *testing.T) {
+	s := NewMemQoSService()
+	handle, err := s.Allocate(FFMAllocRequest{Bytes: 1 << 30, LatencyClass: "T1", BandwidthFloorGBs: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newRouter(s)
+	path := "/v1/ffm/" + handle.ID + "/telemetry"
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept", protowire.ContentType)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); rec.Code != http.StatusOK || ct != protowire.ContentType {
+		t.Fatalf("protobuf telemetry = %d %q: %s", rec.Code, ct, rec.Body)
+	}
+	var fromProto FFMTelemetry
+	if err := fromProto.UnmarshalProto(rec.Body.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var fromJSON FFMTelemetry
+	if err := json.NewDecoder(rec.Body).Decode(&fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if fromProto.Seq != fromJSON.Seq || !fromProto.SampledAt.Equal(fromJSON.SampledAt) {
+		t.Errorf("protobuf reading %+v does not match JSON reading %+v", fromProto, fromJSON)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTelemetrySeqAdvancesWithSampler(t *testing.T) {
+	s := NewMemQoSService()
+	s.SampleInterval = 5 * time.Millisecond
+	handle, err := s.Allocate(FFMAllocRequest{Bytes: 1 << 20, LatencyClass: "T1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := s.Telemetry(handle.ID)
+	if first.Seq != 0 || !first.SampledAt.Equal(handle.CreatedAt) {
+		t.Errorf("before sampling: seq %d, sampled_at %v", first.Seq, first.SampledAt)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.RunSampler(ctx)
+
+	prev := *first
+	for i := 0; i < 5; i++ {
+		time.Sleep(15 * time.Millisecond)
+		got, err := s.Telemetry(handle.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Seq < prev.Seq || got.SampledAt.Before(prev.SampledAt) {
+			t.Fatalf("poll %d went backwards: seq %d at %v after %d at %v", i, got.Seq, got.SampledAt, prev.Seq, prev.SampledAt)
+		}
+		prev = *got
+	}
+	if prev.Seq == 0 || !prev.SampledAt.After(first.SampledAt) {
+		t.Errorf("sampler never advanced: seq %d, sampled_at %v", prev.Seq, prev.SampledAt)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxAllocWait bounds how long an allocation may wait for capacity
+const maxAllocWait = 30 * time.Second
+
+// allocWaiter is an allocation queued until a free makes room for it
+type allocWaiter struct {
+	handle  FFMHandle
+	granted chan struct{} // closed once the handle is stored
+	err     error         // the latest refusal, nil while queued behind others
+}
+
+// AllocateContext is Allocate for requests that may wait. When wait_ms is
+// set and the security domain's quota or the latency class's capacity is
+// full, the request is queued and admitted, strictly in arrival order, as
+// frees make room. A waiting request queues behind earlier waiters even if
+// it would fit. Once wait_ms passes it fails with the refusal that kept it
+// out; if ctx is done first it fails with ctx's error.
+func (s *MemQoSService) AllocateContext(ctx context.Context, req FFMAllocRequest) (*FFMHandle, error) {
+	handle, err := prepareHandle(req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if req.WaitMs == 0 || len(s.waiters) == 0 {
+		err = s.admitLocked(&handle)
+		if err == nil || req.WaitMs == 0 || !waitable(err) {
+			s.mu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			return &handle, nil
+		}
+	}
+	w := &allocWaiter{handle: handle, granted: make(chan struct{}), err: err}
+	s.waiters = append(s.waiters, w)
+	s.mu.Unlock()
+
+	wait := time.Duration(req.WaitMs) * time.Millisecond
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-w.granted:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-w.granted:
+		if ctx.Err() != nil {
+			// Admitted as the caller gave up: hand the capacity on
+			s.handles.Delete(w.handle.ID)
+			s.journalLocked(opFree, w.handle)
+			s.admitWaitersLocked()
+			return nil, ctx.Err()
+		}
+		handle := w.handle
+		return &handle, nil
+	default:
+	}
+	s.removeWaiterLocked(w)
+	// The departing waiter may have been holding up the queue
+	s.admitWaitersLocked()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if w.err != nil {
+		return nil, fmt.Errorf("%w (waited %v)", w.err, wait)
+	}
+	return nil, fmt.Errorf("%w: still queued behind earlier requests after %v", ErrOversubscribed, wait)
+}
+
+// admitLocked charges a handle to its domain's quota and latency class's
+// capacity and stores it. The caller must hold the store lock.
+func (s *MemQoSService) admitLocked(handle *FFMHandle) error {
+	if err := s.checkQuotaLocked(handle.SecurityDomain, handle.Bytes); err != nil {
+		return err
+	}
+	if err := s.checkCapacityLocked(handle.LatencyClass, handle.Bytes, handle.BandwidthFloorGBs, ""); err != nil {
+		return err
+	}
+	s.handles.Set(handle.ID, &handleState{handle: *handle, sampledAt: handle.CreatedAt})
+	s.journalLocked(opAllocate, *handle)
+	return nil
+}
+
+// admitWaitersLocked admits queued allocations from the front until one
+// still does not fit. Call it whenever quota or capacity is freed. The
+// caller must hold the store lock.
+func (s *MemQoSService) admitWaitersLocked() {
+	for len(s.waiters) > 0 {
+		w := s.waiters[0]
+		w.handle.CreatedAt = time.Now().UTC()
+		if err := s.admitLocked(&w.handle); err != nil {
+			w.err = err
+			return
+		}
+		s.waiters = s.waiters[1:]
+		close(w.granted)
+	}
+}
+
+// removeWaiterLocked drops a waiter from the queue. The caller must hold
+// the store lock.
+func (s *MemQoSService) removeWaiterLocked(w *allocWaiter) {
+	for i, queued := range s.waiters {
+		if queued == w {
+			s.waiters = append(s.waiters[:i:i], s.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// waitable reports whether an allocation refusal can clear as others free
+// their handles
+func waitable(err error) bool {
+	return errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrOversubscribed)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fullClass returns a service whose T1 class is filled by a single handle
+func fullClass(t *testing.T) (*MemQoSService, *FFMHandle) {
+	t.Helper()
+	s := NewMemQoSService()
+	s.TierCapacities["T1"] = TierCapacity{Bytes: 4 << 30}
+	handle, err := s.Allocate(FFMAllocRequest{Bytes: 4 << 30, LatencyClass: "T1", SecurityDomain: "lab"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, handle
+}
+
+type allocResult struct {
+	handle *FFMHandle
+	err    error
+}
+
+// allocAsync starts a waiting allocation and returns once it is queued
+func allocAsync(t *testing.T, s *MemQoSService, ctx context.Context, bytes uint64, waitMs int) <-chan allocResult {
+	t.Helper()
+	s.mu.RLock()
+	queued := len(s.waiters)
+	s.mu.RUnlock()
+
+	done := make(chan allocResult, 1)
+	go func() {
+		h, err := s.AllocateContext(ctx, FFMAllocRequest{Bytes: bytes, LatencyClass: "T1", SecurityDomain: "lab", WaitMs: waitMs})
+		done <- allocResult{h, err}
+	}()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		s.mu.RLock()
+		n := len(s.waiters)
+		s.mu.RUnlock()
+		if n > queued {
+			return done
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("allocation was not queued")
+		}
+	}
+}
+
+func TestWaiterSucceedsAfterFree(t *testing.T) {
+	s, full := fullClass(t)
+	done := allocAsync(t, s, context.Background(), 2<<30, 5000)
+
+	if err := s.Free(full.ID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("waiter failed after free: %v", r.err)
+		}
+		if _, err := s.Get(r.handle.ID); err != nil {
+			t.Errorf("admitted handle not stored: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiter not admitted within its wait")
+	}
+}
+
+func TestWaitersAdmittedInOrder(t *testing.T) {
+	s, full := fullClass(t)
+	first := allocAsync(t, s, context.Background(), 3<<30, 5000)
+	second := allocAsync(t, s, context.Background(), 2<<30, 5000)
+
+	// Only one of the two fits at a time; the first in line gets the room
+	if err := s.Free(full.ID); err != nil {
+		t.Fatal(err)
+	}
+	var r allocResult
+	select {
+	case r = <-first:
+		if r.err != nil {
+			t.Fatalf("first waiter: %v", r.err)
+		}
+	case <-second:
+		t.Fatal("second waiter admitted ahead of the first")
+	case <-time.After(5 * time.Second):
+		t.Fatal("first waiter not admitted")
+	}
+
+	if err := s.Free(r.handle.ID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-second:
+		if r.err != nil {
+			t.Fatalf("second waiter: %v", r.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second waiter not admitted")
+	}
+}
+
+func TestWaiterTimesOut(t *testing.T) {
+	s, _ := fullClass(t)
+	start := time.Now()
+	_, err := s.AllocateContext(context.Background(), FFMAllocRequest{Bytes: 1 << 30, LatencyClass: "T1", SecurityDomain: "lab", WaitMs: 50})
+	if !errors.Is(err, ErrOversubscribed) {
+		t.Fatalf("timed-out waiter error = %v", err)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("waiter gave up after %v", waited)
+	}
+	if n := len(s.waiters); n != 0 {
+		t.Errorf("%d waiters left queued", n)
+	}
+}
+
+func TestCancelledWaiterLeavesQueue(t *testing.T) {
+	s, full := fullClass(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := allocAsync(t, s, ctx, 3<<30, 5000)
+	behind := allocAsync(t, s, context.Background(), 2<<30, 5000)
+
+	cancel()
+	if r := <-cancelled; !errors.Is(r.err, context.Canceled) {
+		t.Fatalf("cancelled waiter error = %v", r.err)
+	}
+	if err := s.Free(full.ID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-behind:
+		if r.err != nil {
+			t.Fatalf("waiter behind a cancelled one: %v", r.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiter behind a cancelled one not admitted")
+	}
+}
+
+func TestExpiredReservationAdmitsWaiter(t *testing.T) {
+	s := NewMemQoSService()
+	s.TierCapacities["T1"] = TierCapacity{Bytes: 4 << 30}
+	if _, err := s.Reserve(ReserveRequest{FFMAllocRequest: FFMAllocRequest{Bytes: 4 << 30, LatencyClass: "T1", SecurityDomain: "lab"}, TTLSeconds: 1}); err != nil {
+		t.Fatal(err)
+	}
+	done := allocAsync(t, s, context.Background(), 4<<30, 5000)
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("waiter after expiry: %v", r.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reservation expiry did not admit the waiter")
+	}
+}
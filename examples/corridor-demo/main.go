@@ -56,6 +56,9 @@ type TelemetryData struct {
 	Drift              string  `json:"drift"`
 	UtilizationPercent float64 `json:"utilization_percent"`
 	ErrorCount         int     `json:"error_count"`
+	GbpsPerLane        float64 `json:"gbps_per_lane"`
+	SpectralEfficiency float64 `json:"spectral_efficiency_bps_per_hz"`
+	EfficiencyScore    float64 `json:"efficiency_score"`
 }
 
 // RecalibrateRequest represents a recalibration request
@@ -227,14 +230,13 @@ func main() {
 			continue
 		}
 		
-		efficiency := float64(corridor.AchievableGbps) / float64(corridor.Lanes) / 100.0 // Gbps per lane
-		powerEfficiency := telemetry.PowerPjPerBit
-		
-		fmt.Printf("  %s: %.1f Gbps/lane, %.2f pJ/bit, %.2e BER\n",
+		fmt.Printf("  %s: %.1f Gbps/lane, %.2f pJ/bit, %.2f b/s/Hz, %.2e BER, score %.0f\n",
 			corridor.CorridorType,
-			efficiency,
-			powerEfficiency,
-			telemetry.BER)
+			telemetry.GbpsPerLane,
+			telemetry.PowerPjPerBit,
+			telemetry.SpectralEfficiency,
+			telemetry.BER,
+			telemetry.EfficiencyScore)
 	}
 
 	fmt.Println("\nDemo completed!")
@@ -10,12 +10,14 @@ import (
 	"time"
 )
 
-// FFMHandle represents a Free-Form Memory allocation
+// FFMHandle represents a Free-Form Memory allocation. Bandwidth fields are
+// in GB/s (gigabytes per second); memqosd also reports them in Gbps.
 type FFMHandle struct {
 	ID               string    `json:"id"`
 	Bytes            uint64    `json:"bytes"`
 	LatencyClass     string    `json:"latency_class"`
 	BandwidthFloor   uint64    `json:"bandwidth_floor_GBs"`
+	BandwidthFloorGbps float64 `json:"bandwidth_floor_gbps"`
 	Persistence      string    `json:"persistence"`
 	Shareable        bool      `json:"shareable"`
 	SecurityDomain   string    `json:"security_domain"`
@@ -40,6 +42,7 @@ type AllocationRequest struct {
 // TelemetryResponse represents telemetry data
 type TelemetryResponse struct {
 	AchievedGBs  uint64  `json:"achieved_GBs"`
+	AchievedGbps float64 `json:"achieved_gbps"`
 	MovedPages   uint64  `json:"moved_pages"`
 	TailP99Ms    float64 `json:"tail_p99_ms"`
 	Temperature  float64 `json:"temperature_c"`
@@ -115,11 +118,14 @@ func main() {
 				continue
 			}
 			
+			// Both sides in GB/s
 			bandwidthRatio := float64(telemetry.AchievedGBs) / float64(handle.BandwidthFloor) * 100
-			fmt.Printf("  %s: %d/%d Gbps (%.1f%%) | P99: %.2fms | Util: %.1f%%\n",
+			fmt.Printf("  %s: %d/%d GB/s (%.0f/%.0f Gbps, %.1f%%) | P99: %.2fms | Util: %.1f%%\n",
 				handle.LatencyClass,
 				telemetry.AchievedGBs,
 				handle.BandwidthFloor,
+				telemetry.AchievedGbps,
+				handle.BandwidthFloorGbps,
 				bandwidthRatio,
 				telemetry.TailP99Ms,
 				telemetry.Utilization)
@@ -133,7 +139,7 @@ func main() {
 	
 	if len(handles) > 0 {
 		handle := handles[0]
-		fmt.Printf("Adjusting bandwidth for %s from %d to %d Gbps...\n",
+		fmt.Printf("Adjusting bandwidth for %s from %d to %d GB/s...\n",
 			handle.LatencyClass, handle.BandwidthFloor, handle.BandwidthFloor+50)
 		
 		err := adjustBandwidth(handle.ID, handle.BandwidthFloor+50)
@@ -166,12 +172,12 @@ func main() {
 	if err != nil {
 		log.Printf("Error listing allocations: %v", err)
 	} else {
-		fmt.Printf("%-12s %-8s %-12s %-8s %-8s %-12s\n", 
-			"ID", "Tier", "Size", "Bw Floor", "Achieved", "Domain")
+		fmt.Printf("%-12s %-8s %-12s %-10s %-8s %-12s\n", 
+			"ID", "Tier", "Size", "Floor GB/s", "Achieved", "Domain")
 		fmt.Println("------------------------------------------------------------")
 		
 		for _, alloc := range allAllocations {
-			fmt.Printf("%-12s %-8s %-12s %-8d %-8d %-12s\n",
+			fmt.Printf("%-12s %-8s %-12s %-10d %-8d %-12s\n",
 				alloc.ID,
 				alloc.LatencyClass,
 				formatBytes(alloc.Bytes),
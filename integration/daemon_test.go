@@ -0,0 +1,132 @@
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// daemon is a service under test: built from its module, then run as a
+// child process on an ephemeral loopback port
+type daemon struct {
+	name string
+	dir  string   // module directory, relative to the repository root
+	args []string // flags besides -addr
+	// overTLS serves HTTPS with a post-quantum certificate endorsement
+	overTLS bool
+
+	url string
+	cmd *exec.Cmd
+	log lockedBuffer
+}
+
+// lockedBuffer collects a child's output while it runs
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// healthTimeout bounds how long a daemon may take to answer /health
+const healthTimeout = 10 * time.Second
+
+// build compiles the daemon into binDir, with the race detector if race
+func (d *daemon) build(root, binDir string, race bool) (string, error) {
+	bin := filepath.Join(binDir, d.name)
+	args := []string{"build", "-o", bin}
+	if race {
+		args = append(args, "-race")
+	}
+	cmd := exec.Command("go", append(args, ".")...)
+	cmd.Dir = filepath.Join(root, d.dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("building %s: %v\n%s", d.name, err, out)
+	}
+	return bin, nil
+}
+
+// start runs the binary on a free port and waits until it is healthy
+func (d *daemon) start(bin string, files *tlsFiles) error {
+	addr, err := freeAddr()
+	if err != nil {
+		return err
+	}
+	args := append([]string{"-addr", addr}, d.args...)
+	client := http.DefaultClient
+	d.url = "http://" + addr
+	if d.overTLS {
+		args = append(args, files.args()...)
+		client = &http.Client{Transport: files.transport()}
+		d.url = "https://" + addr
+	}
+	d.cmd = exec.Command(bin, args...)
+	d.cmd.Stdout, d.cmd.Stderr = &d.log, &d.log
+	if err := d.cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s: %v", d.name, err)
+	}
+
+	deadline := time.Now().Add(healthTimeout)
+	for time.Now().Before(deadline) {
+		resp, err := client.Get(d.url + "/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("%s not healthy at %s after %v\n%s", d.name, d.url, healthTimeout, d.log.String())
+}
+
+// stop interrupts the daemon and reports a data race it logged
+func (d *daemon) stop() error {
+	if d.cmd == nil || d.cmd.Process == nil {
+		return nil
+	}
+	_ = d.cmd.Process.Signal(os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		_ = d.cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		_ = d.cmd.Process.Kill()
+		<-done
+	}
+	if strings.Contains(d.log.String(), "WARNING: DATA RACE") {
+		return fmt.Errorf("%s reported a data race:\n%s", d.name, d.log.String())
+	}
+	return nil
+}
+
+// freeAddr returns a loopback address with a port the kernel just handed
+// out, for a child to listen on
+func freeAddr() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
@@ -0,0 +1,13 @@
+// Package integration runs the CorridorOS services together and drives a
+// realistic flow through them with the Go SDK, so drift between a service's
+// API and the SDK or another service shows up as a failed test.
+//
+// TestMain builds corrd, memqosd, helio-sim and physics-decoder from this
+// checkout and starts each on an ephemeral loopback port; TestFlow then runs
+// the steps in order, stopping at the first failure. corrd serves HTTPS with
+// a post-quantum endorsement of its certificate, which the SDK client
+// verifies on every call. Under -race the services are built with the race
+// detector too and a race they report fails the run; -short skips it all.
+//
+//	cd integration && go test -race [-v] ./...
+package integration
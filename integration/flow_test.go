@@ -0,0 +1,325 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/corridoros/sdk-go/apierror"
+	"github.com/corridoros/sdk-go/clients/corridor"
+	"github.com/corridoros/sdk-go/clients/ffm"
+	"github.com/corridoros/sdk-go/signing"
+)
+
+// env is what the steps share: clients for each service and the resources
+// earlier steps created
+type env struct {
+	corridors  *corridor.Client // verifies corrd's certificate endorsement
+	memory     *ffm.Client
+	corrdURL   string
+	helioURL   string
+	physicsURL string
+	tls        *tlsFiles
+
+	corridor *corridor.Corridor
+	handle   *ffm.Handle
+}
+
+func newEnv(corrdURL, memqosdURL, helioURL, physicsURL string, files *tlsFiles) *env {
+	corridors := corridor.NewWithTransport(corrdURL, files.transport())
+	signing.EnableEndorsement(corridors.HTTP, files.endorsementKey)
+	return &env{
+		corridors:  corridors,
+		memory:     ffm.New(memqosdURL),
+		corrdURL:   corrdURL,
+		helioURL:   helioURL,
+		physicsURL: physicsURL,
+		tls:        files,
+	}
+}
+
+// step is one stage of the flow; it fails with a description of what the
+// services got wrong
+type step struct {
+	name string
+	run  func(e *env) error
+}
+
+// telemetryPolls is how many fresh samples the polling steps wait for
+const telemetryPolls = 3
+
+// steps is the flow, in order: a workload's corridor and its backing
+// memory are brought up, calibrated, watched and torn down again
+var steps = []step{
+	{"connect over TLS", func(e *env) error {
+		// A client unaware of endorsements connects as to any HTTPS server
+		resp, err := (&http.Client{Transport: e.tls.transport()}).Get(e.corrdURL + "/health")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.ProtoMajor != 2 || resp.Header.Get(signing.EndorsementHeader) == "" {
+			return fmt.Errorf("got %s with endorsement %q, want HTTP/2 with one", resp.Proto, resp.Header.Get(signing.EndorsementHeader))
+		}
+		if _, err := corridor.NewWithTransport(e.corrdURL, e.tls.transport()).Capacity(); err != nil {
+			return fmt.Errorf("client without endorsement checks: %v", err)
+		}
+		// A PQC-aware client accepts it only under the key that endorsed it
+		if _, err := e.corridors.Capacity(); err != nil {
+			return fmt.Errorf("client pinning the endorsement key: %v", err)
+		}
+		stranger := corridor.NewWithTransport(e.corrdURL, e.tls.transport())
+		signing.EnableEndorsement(stranger.HTTP, e.tls.strangerKey)
+		if _, err := stranger.Capacity(); !errors.Is(err, signing.ErrBadEndorsement) {
+			return fmt.Errorf("client pinning another key: got %v, want %v", err, signing.ErrBadEndorsement)
+		}
+		return nil
+	}},
+	{"allocate corridor", func(e *env) error {
+		c, err := e.corridors.Allocate(corridor.AllocateRequest{
+			CorridorType:    "SiCorridor",
+			Lanes:           4,
+			LambdaNm:        []int{1550, 1551, 1552, 1553},
+			MinGbps:         100,
+			LatencyBudgetNs: 250,
+			ReachMm:         50,
+			Labels:          map[string]string{"suite": "integration"},
+		})
+		if err != nil {
+			return err
+		}
+		if err := corridor.ValidateID(c.ID); err != nil {
+			return err
+		}
+		if c.Status != "active" || c.AchievableGbps < 100 {
+			return fmt.Errorf("corridor %s is %s with %d Gbps, want active with at least 100", c.ID, c.Status, c.AchievableGbps)
+		}
+		e.corridor = c
+		return nil
+	}},
+	{"allocate backing memory", func(e *env) error {
+		h, err := e.memory.Allocate(ffm.AllocateRequest{
+			Bytes:             1 << 30,
+			LatencyClass:      "T1",
+			BandwidthFloorGBs: 50,
+			Persistence:       "write-back",
+			SecurityDomain:    "integration",
+			Labels:            map[string]string{"corridor": e.corridor.ID},
+		})
+		if err != nil {
+			return err
+		}
+		if h.Bytes != 1<<30 || h.BandwidthFloorGBs != 50 || h.BandwidthFloorGbps != ffm.GBsToGbps(50) {
+			return fmt.Errorf("handle %s has %d bytes at %d GB/s (%g Gbps)", h.ID, h.Bytes, h.BandwidthFloorGBs, h.BandwidthFloorGbps)
+		}
+		listed, err := e.memory.List(ffm.WithLabel("corridor", e.corridor.ID))
+		if err != nil {
+			return err
+		}
+		if len(listed) != 1 || listed[0].ID != h.ID {
+			return fmt.Errorf("listing by corridor label found %d handles", len(listed))
+		}
+		e.handle = h
+		return nil
+	}},
+	{"simulate calibration", func(e *env) error {
+		var sim struct {
+			ID         string  `json:"id"`
+			CorridorID string  `json:"corridor_id"`
+			Converged  bool    `json:"converged"`
+			FinalBER   float64 `json:"final_ber"`
+			Iterations int     `json:"iterations"`
+		}
+		err := postJSON(e.helioURL+"/v1/helio-sim/simulate", map[string]any{
+			"corridor_id":     e.corridor.ID,
+			"target_ber":      1e-12,
+			"ambient_profile": "lab_default",
+			"lambda_count":    e.corridor.Lanes,
+			"initial_ber":     1e-9,
+		}, &sim)
+		if err != nil {
+			return err
+		}
+		if sim.CorridorID != e.corridor.ID || !sim.Converged || sim.FinalBER > 1.1e-12 {
+			return fmt.Errorf("run %s for %s: converged %t at BER %g", sim.ID, sim.CorridorID, sim.Converged, sim.FinalBER)
+		}
+		var stored struct {
+			Result struct {
+				Iterations int `json:"iterations"`
+			} `json:"result"`
+		}
+		if err := getJSON(e.helioURL+"/v1/helio-sim/results/"+sim.ID, &stored); err != nil {
+			return err
+		}
+		if stored.Result.Iterations != sim.Iterations {
+			return fmt.Errorf("stored result has %d iterations, the run %d", stored.Result.Iterations, sim.Iterations)
+		}
+		return nil
+	}},
+	{"recalibrate corridor", func(e *env) error {
+		r, err := e.corridors.Recalibrate(e.corridor.ID, corridor.RecalRequest{TargetBER: 1e-12, AmbientProfile: "lab_default"})
+		if err != nil {
+			return err
+		}
+		if !r.Converged || len(r.BiasVoltages) != e.corridor.Lanes {
+			return fmt.Errorf("converged %t with %d bias voltages for %d lanes", r.Converged, len(r.BiasVoltages), e.corridor.Lanes)
+		}
+		c, err := e.corridors.Get(e.corridor.ID)
+		if err != nil {
+			return err
+		}
+		if c.Status != "active" {
+			return fmt.Errorf("corridor is %s after recalibrating", c.Status)
+		}
+		return nil
+	}},
+	{"poll corridor telemetry", func(e *env) error {
+		// Alternate JSON and protobuf, so both decoders are exercised
+		clients := []*corridor.Client{e.corridors, e.corridors.WithProtobuf()}
+		var last uint64
+		for i := 0; i < telemetryPolls; i++ {
+			t, err := pollUntil(func() (uint64, error) {
+				t, err := clients[i%2].Telemetry(e.corridor.ID)
+				if err != nil {
+					return 0, err
+				}
+				if t.BER <= 0 || t.BER >= 1 || t.PowerPjPerBit <= 0 || t.SampledAt.IsZero() {
+					return 0, fmt.Errorf("implausible telemetry %+v", *t)
+				}
+				return t.Seq, nil
+			}, last)
+			if err != nil {
+				return err
+			}
+			last = t
+		}
+		return nil
+	}},
+	{"poll memory telemetry", func(e *env) error {
+		clients := []*ffm.Client{e.memory, e.memory.WithProtobuf()}
+		var last uint64
+		for i := 0; i < telemetryPolls; i++ {
+			t, err := pollUntil(func() (uint64, error) {
+				t, err := clients[i%2].Telemetry(e.handle.ID)
+				if err != nil {
+					return 0, err
+				}
+				if t.AchievedGBs == 0 || t.SampledAt.IsZero() {
+					return 0, fmt.Errorf("implausible telemetry %+v", *t)
+				}
+				return t.Seq, nil
+			}, last)
+			if err != nil {
+				return err
+			}
+			last = t
+		}
+		if _, err := e.memory.Flush(e.handle.ID); err != nil {
+			return err
+		}
+		return nil
+	}},
+	{"verify physics calculation", func(e *env) error {
+		var calc struct {
+			Result float64 `json:"result"`
+			Unit   string  `json:"unit"`
+			Valid  bool    `json:"valid"`
+			Error  string  `json:"error"`
+		}
+		err := postJSON(e.physicsURL+"/v1/physics/calculate", map[string]any{
+			"formula":   "E=mc^2",
+			"variables": map[string]float64{"m": 1},
+			"units":     map[string]string{"m": "kg"},
+		}, &calc)
+		if err != nil {
+			return err
+		}
+		const c = 299792458.0
+		if !calc.Valid || calc.Unit != "J" || math.Abs(calc.Result-c*c) > 1e-9*c*c {
+			return fmt.Errorf("E=mc^2 for 1 kg gave %g %s (valid %t, %s), want %g J", calc.Result, calc.Unit, calc.Valid, calc.Error, c*c)
+		}
+		return nil
+	}},
+	{"free resources", func(e *env) error {
+		if err := e.memory.Free(e.handle.ID); err != nil {
+			return err
+		}
+		if err := e.corridors.Release(e.corridor.ID); err != nil {
+			return err
+		}
+		if _, err := e.memory.Get(e.handle.ID); apierror.CodeOf(err) != apierror.CodeNotFound {
+			return fmt.Errorf("freed handle: got %v, want %s", err, apierror.CodeNotFound)
+		}
+		if _, err := e.corridors.Get(e.corridor.ID); apierror.CodeOf(err) != apierror.CodeNotFound {
+			return fmt.Errorf("released corridor: got %v, want %s", err, apierror.CodeNotFound)
+		}
+		usage, err := e.memory.Usage("integration")
+		if err != nil {
+			return err
+		}
+		if usage.Bytes != 0 || usage.Handles != 0 {
+			return fmt.Errorf("domain still charged %d bytes in %d handles", usage.Bytes, usage.Handles)
+		}
+		// The wavelengths are free again
+		c, err := e.corridors.Allocate(corridor.AllocateRequest{CorridorType: "SiCorridor", Lanes: 1, LambdaNm: e.corridor.LambdaNm[:1]})
+		if err != nil {
+			return fmt.Errorf("reallocating %d nm: %v", e.corridor.LambdaNm[0], err)
+		}
+		return e.corridors.Release(c.ID)
+	}},
+}
+
+// pollTimeout bounds the wait for a telemetry sample newer than the last
+const pollTimeout = 5 * time.Second
+
+// pollUntil reads a sequence number until it moves past last
+func pollUntil(read func() (uint64, error), last uint64) (uint64, error) {
+	deadline := time.Now().Add(pollTimeout)
+	for {
+		seq, err := read()
+		if err != nil {
+			return 0, err
+		}
+		if seq > last {
+			return seq, nil
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("no sample after seq %d within %v", last, pollTimeout)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// postJSON posts in to a service without an SDK client and decodes the
+// reply into out
+func postJSON(url string, in, out any) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apierror.FromResponse(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func getJSON(url string, out any) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apierror.FromResponse(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,16 @@
+module github.com/corridoros/integration
+
+go 1.27
+
+require (
+	github.com/corridoros/sdk-go v0.0.0
+	github.com/corridoros/security/pqc v0.0.0
+)
+
+require github.com/corridoros/pkg v0.0.0 // indirect
+
+replace github.com/corridoros/sdk-go => ../sdk/go
+
+replace github.com/corridoros/pkg => ../pkg
+
+replace github.com/corridoros/security/pqc => ../security/pqc
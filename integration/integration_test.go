@@ -0,0 +1,88 @@
+package integration
+
+import (
+	"flag"
+	"log"
+	"os"
+	"testing"
+)
+
+// services are the daemons under test, started in this order
+var services = []*daemon{
+	{name: "corrd", dir: "daemons/corrd", args: []string{"-sample-interval", "100ms", "-drift-interval", "100ms"}, overTLS: true},
+	{name: "memqosd", dir: "daemons/memqosd", args: []string{"-sample-interval", "100ms"}},
+	{name: "helio-sim", dir: "labs/helio-sim"},
+	{name: "physics-decoder", dir: "labs/physics-decoder"},
+}
+
+var root = flag.String("root", "..", "repository root the services are built from")
+
+// flow is the environment TestMain started the services in
+var flow *env
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if testing.Short() {
+		os.Exit(m.Run())
+	}
+	os.Exit(runServices(m))
+}
+
+// runServices builds and starts the services, runs the tests against them
+// and stops them again, failing the run if one reported a data race
+func runServices(m *testing.M) (code int) {
+	binDir, err := os.MkdirTemp("", "corridoros-integration")
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	defer os.RemoveAll(binDir)
+	files, err := newTLSFiles(binDir)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	defer func() {
+		for _, d := range services {
+			if err := d.stop(); err != nil {
+				log.Print(err)
+				code = 1
+			}
+			if code != 0 || testing.Verbose() {
+				log.Printf("--- %s log\n%s", d.name, d.log.String())
+			}
+		}
+	}()
+	for _, d := range services {
+		bin, err := d.build(*root, binDir, raceEnabled)
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		if err := d.start(bin, files); err != nil {
+			log.Print(err)
+			return 1
+		}
+	}
+
+	flow = newEnv(services[0].url, services[1].url, services[2].url, services[3].url, files)
+	return m.Run()
+}
+
+// TestFlow runs the steps in order, each as a subtest; a step builds on
+// the ones before it, so the first failure ends the flow
+func TestFlow(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts every service; skipped in -short mode")
+	}
+	for i, s := range steps {
+		if !t.Run(s.name, func(t *testing.T) {
+			if err := s.run(flow); err != nil {
+				t.Fatal(err)
+			}
+		}) {
+			t.Fatalf("flow stopped at step %d of %d", i+1, len(steps))
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package integration
+
+// raceEnabled builds the services with the race detector when the tests
+// run under it
+const raceEnabled = true
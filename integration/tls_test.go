@@ -0,0 +1,129 @@
+package integration
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/corridoros/sdk-go/tlsclient"
+	"github.com/corridoros/security/pqc"
+	"github.com/corridoros/security/pqc/tlsendorse"
+)
+
+// tlsFiles is the TLS material a daemon run over TLS is started with: a
+// throwaway CA, a loopback certificate it issued and a post-quantum
+// endorsement of that certificate
+type tlsFiles struct {
+	caFile, certFile, keyFile, endorsementFile string
+
+	// endorsementKey is the public key clients pin; strangerKey endorses
+	// nothing the daemons present
+	endorsementKey, strangerKey []byte
+}
+
+// args are the daemon flags serving over TLS with the endorsement
+func (f *tlsFiles) args() []string {
+	return []string{"-tls-cert", f.certFile, "-tls-key", f.keyFile, "-tls-endorsement", f.endorsementFile}
+}
+
+// transport trusts the throwaway CA
+func (f *tlsFiles) transport() *http.Transport {
+	t, err := tlsclient.NewTransport(f.caFile)
+	if err != nil {
+		panic(err) // the CA was just written
+	}
+	return t
+}
+
+// newTLSFiles writes the TLS material into dir
+func newTLSFiles(dir string) (*tlsFiles, error) {
+	f := &tlsFiles{
+		caFile:          filepath.Join(dir, "ca.pem"),
+		certFile:        filepath.Join(dir, "server.pem"),
+		keyFile:         filepath.Join(dir, "server-key.pem"),
+		endorsementFile: filepath.Join(dir, "endorsement.json"),
+	}
+	now := time.Now()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CorridorOS integration CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	endorsementKeys, err := pqc.NewDilithiumKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	stranger, err := pqc.NewDilithiumKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	endorsement, err := tlsendorse.Endorse(leafDER, endorsementKeys)
+	if err != nil {
+		return nil, err
+	}
+	f.endorsementKey, f.strangerKey = endorsementKeys.PublicKey, stranger.PublicKey
+
+	endorsementJSON, _ := json.Marshal(endorsement)
+	for _, out := range []struct {
+		path string
+		data []byte
+	}{
+		{f.caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})},
+		{f.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})},
+		{f.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})},
+		{f.endorsementFile, endorsementJSON},
+	} {
+		if err := os.WriteFile(out.path, out.data, 0600); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/corridoros/pkg/linkmodel"
+)
+
+// Eye margin model: the Q factor grows linearly with the eye opening, with
+// 0.8 UI, where calibration settles, worth the Q of a 1e-12 BER link
+const (
+	qPerEyeMarginUI  = 8.8
+	qFactorTolerance = 2.0 // widest Q gap a consistent BER and eye margin may show
+)
+
+// LinkConsistency compares the Q factors implied by a final BER and a final
+// eye margin. A wide-open eye with a poor BER, or the reverse, points at a
+// bug in the model rather than at the link.
+type LinkConsistency struct {
+	QFromBER       float64 `json:"q_from_ber"`
+	QFromEyeMargin float64 `json:"q_from_eye_margin"`
+	Tolerance      float64 `json:"tolerance"`
+	Consistent     bool    `json:"consistent"`
+	Detail         string  `json:"detail,omitempty"`
+}
+
+// checkEyeMargin verifies that a BER and an eye margin lie on the same point
+// of the Q-factor curve, within qFactorTolerance
+func checkEyeMargin(ber, eyeMargin float64) LinkConsistency {
+	c := LinkConsistency{Tolerance: qFactorTolerance}
+	if ber <= 0 || ber > 0.5 || eyeMargin <= 0 {
+		c.Detail = fmt.Sprintf("BER %.3g or eye margin %.3f UI is outside the model's range", ber, eyeMargin)
+		return c
+	}
+	c.QFromBER = linkmodel.QFactor(ber)
+	c.QFromEyeMargin = qPerEyeMarginUI * eyeMargin
+	c.Consistent = math.Abs(c.QFromBER-c.QFromEyeMargin) <= qFactorTolerance
+	if !c.Consistent {
+		c.Detail = fmt.Sprintf("eye margin %.3f UI implies Q %.2f but BER %.3g implies Q %.2f", eyeMargin, c.QFromEyeMargin, ber, c.QFromBER)
+	}
+	return c
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConsistentPairPasses(t *testing.T) {
+	c := checkEyeMargin(1e-12, 0.8)
+	if !c.Consistent || c.Detail != "" {
+		t.Fatalf("1e-12 at 0.8 UI = %+v", c)
+	}
+	if math.Abs(c.QFromBER-c.QFromEyeMargin) > 0.1 {
+		t.Errorf("Q from BER %.2f, from eye margin %.2f", c.QFromBER, c.QFromEyeMargin)
+	}
+}
+
+func TestInconsistentPairIsFlagged(t *testing.T) {
+	for _, pair := range []struct{ ber, eye float64 }{
+		{1e-3, 1.2},  // wide-open eye, terrible BER
+		{1e-15, 0.2}, // nearly closed eye, excellent BER
+		{0, 0.8},     // outside the model
+	} {
+		if c := checkEyeMargin(pair.ber, pair.eye); c.Consistent || c.Detail == "" {
+			t.Errorf("BER %g at %.1f UI = %+v, want flagged", pair.ber, pair.eye, c)
+		}
+	}
+}
+
+func TestSimulationReportsConsistency(t *testing.T) {
+	resp, err := newSeededSimulator(1).Simulate(SimulationRequest{TargetBER: 1e-12, AmbientProfile: "lab_default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := checkEyeMargin(resp.FinalBER, resp.FinalEyeMargin)
+	if resp.Consistency != want || !resp.Consistency.Consistent {
+		t.Errorf("consistency = %+v, want %+v", resp.Consistency, want)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Bias control modes
+const (
+	ControlOpenLoop = "open_loop" // follow the compensation setpoint, blind to BER
+	ControlPID      = "pid"       // trim each bias against its channel's BER error
+)
+
+// Bias control loop defaults. Voltages are modeled in volts; the step limit
+// is given in millivolts.
+const (
+	defaultBiasDamping     = 0.7
+	defaultBiasStepLimitMv = 2.0
+)
+
+// PID control defaults. The error is the BER in decades above target, so
+// the gains are in volts per decade (Kp), per decade-second (Ki) and
+// decade-seconds (Kd).
+const (
+	defaultPIDKp = 0.006
+	defaultPIDKi = 0.002
+	defaultPIDKd = 0.0
+	// biasBERSensitivity is how many decades a volt of bias correction
+	// lowers a channel's BER by
+	biasBERSensitivity = 100.0
+)
+
+// BiasControl shapes how each bias voltage is adjusted. Open-loop control
+// follows the compensation setpoint by exponential smoothing; PID control
+// drives the voltage from the channel's BER error. Either way the
+// per-iteration step is capped, as for a real slew-limited DAC.
+type BiasControl struct {
+	Mode        string  `json:"mode"`
+	Damping     float64 `json:"damping"`       // open loop: weight kept on the previous voltage, in [0, 1)
+	StepLimitMv float64 `json:"step_limit_mv"` // largest change per iteration; 0 is unlimited
+	Kp          float64 `json:"kp,omitempty"`  // PID gains
+	Ki          float64 `json:"ki,omitempty"`
+	Kd          float64 `json:"kd,omitempty"`
+}
+
+// resolveBiasControl applies the defaults to a request's control options.
+// Open loop with damping 0 and step limit 0 reproduces the undamped update.
+func resolveBiasControl(req SimulationRequest) (BiasControl, error) {
+	c := BiasControl{Mode: ControlOpenLoop, Damping: defaultBiasDamping, StepLimitMv: defaultBiasStepLimitMv}
+	switch req.ControlMode {
+	case "", ControlOpenLoop:
+		if req.Kp != nil || req.Ki != nil || req.Kd != nil {
+			return BiasControl{}, fmt.Errorf("kp, ki and kd apply to pid control only")
+		}
+		if req.Damping != nil {
+			if *req.Damping < 0 || *req.Damping >= 1 {
+				return BiasControl{}, fmt.Errorf("damping must be in [0, 1)")
+			}
+			c.Damping = *req.Damping
+		}
+	case ControlPID:
+		if req.Damping != nil {
+			return BiasControl{}, fmt.Errorf("damping applies to open_loop control only")
+		}
+		c.Mode, c.Damping = ControlPID, 0
+		c.Kp, c.Ki, c.Kd = defaultPIDKp, defaultPIDKi, defaultPIDKd
+		for _, gain := range []struct {
+			name  string
+			value *float64
+			dest  *float64
+		}{{"kp", req.Kp, &c.Kp}, {"ki", req.Ki, &c.Ki}, {"kd", req.Kd, &c.Kd}} {
+			if gain.value == nil {
+				continue
+			}
+			if *gain.value < 0 || math.IsInf(*gain.value, 0) || math.IsNaN(*gain.value) {
+				return BiasControl{}, fmt.Errorf("%s must be non-negative and finite", gain.name)
+			}
+			*gain.dest = *gain.value
+		}
+	default:
+		return BiasControl{}, fmt.Errorf("control_mode must be %s or %s", ControlOpenLoop, ControlPID)
+	}
+	if req.StepLimitMv != nil {
+		if *req.StepLimitMv < 0 {
+			return BiasControl{}, fmt.Errorf("step_limit_mv must not be negative")
+		}
+		c.StepLimitMv = *req.StepLimitMv
+	}
+	return c, nil
+}
+
+// next returns the voltage after one open-loop step from v toward target
+func (c BiasControl) next(v, target float64) float64 {
+	return v + c.limit((1-c.Damping)*(target-v))
+}
+
+// limit caps a voltage step at the step limit
+func (c BiasControl) limit(step float64) float64 {
+	if c.StepLimitMv > 0 {
+		limit := c.StepLimitMv / 1000
+		step = math.Max(-limit, math.Min(limit, step))
+	}
+	return step
+}
+
+// pidState is the memory of one channel's PID loop
+type pidState struct {
+	integral  float64
+	lastError float64
+	started   bool
+}
+
+// correct returns the bias step for a channel whose BER is errDecades
+// above target, dt seconds after the previous step
+func (c BiasControl) correct(s *pidState, errDecades, dt float64) float64 {
+	s.integral += errDecades * dt
+	derivative := 0.0
+	if s.started {
+		derivative = (errDecades - s.lastError) / dt
+	}
+	s.lastError, s.started = errDecades, true
+	return c.limit(c.Kp*errDecades + c.Ki*s.integral + c.Kd*derivative)
+}
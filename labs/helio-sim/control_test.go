@@ -0,0 +1,124 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// voltageJitter drives the bias loop with a seeded source for n iterations
+// and returns the mean squared per-iteration voltage change
+func voltageJitter(t *testing.T, control BiasControl, n int) float64 {
+	t.Helper()
+	h := NewHELIOPASSSimulator()
+	rng := rand.New(rand.NewSource(7))
+	profile := h.GetAmbientProfiles()["lab_default"]
+	voltages := []float64{1.1, 1.1, 1.1, 1.1}
+	prev := append([]float64(nil), voltages...)
+	var sum float64
+	for i := 0; i < n; i++ {
+		h.updateBiasVoltages(rng, voltages, float64(i)*0.1, profile, control)
+		for j, v := range voltages {
+			sum += (v - prev[j]) * (v - prev[j])
+			prev[j] = v
+		}
+	}
+	return sum / float64(n*len(voltages))
+}
+
+func TestDampingReducesVoltageJitter(t *testing.T) {
+	undamped := voltageJitter(t, BiasControl{}, 500)
+	damped := voltageJitter(t, BiasControl{Damping: defaultBiasDamping, StepLimitMv: defaultBiasStepLimitMv}, 500)
+	if damped >= undamped/2 {
+		t.Errorf("damped jitter %.3g is not well below undamped %.3g", damped, undamped)
+	}
+	limit := defaultBiasStepLimitMv / 1000
+	if damped > limit*limit {
+		t.Errorf("damped jitter %.3g exceeds the step limit", damped)
+	}
+}
+
+func TestDampedRunStillConverges(t *testing.T) {
+	zero := 0.0
+	for name, req := range map[string]SimulationRequest{
+		"default":  {TargetBER: 1e-12, AmbientProfile: "lab_default"},
+		"undamped": {TargetBER: 1e-12, AmbientProfile: "lab_default", Damping: &zero, StepLimitMv: &zero},
+	} {
+		resp, err := newSeededSimulator(3).Simulate(req)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !resp.Converged {
+			t.Errorf("%s run did not converge: final BER %g", name, resp.FinalBER)
+		}
+		if name == "default" && (resp.BiasControl.Damping != defaultBiasDamping || resp.BiasControl.StepLimitMv != defaultBiasStepLimitMv) {
+			t.Errorf("default bias control = %+v", resp.BiasControl)
+		}
+	}
+}
+
+func TestBiasControlValidation(t *testing.T) {
+	one, negative, gain := 1.0, -0.5, 0.01
+	for name, req := range map[string]SimulationRequest{
+		"damping 1":               {Damping: &one},
+		"negative step limit":     {StepLimitMv: &negative},
+		"gains on open loop":      {Kp: &gain},
+		"damping on pid":          {ControlMode: ControlPID, Damping: &gain},
+		"negative gain":           {ControlMode: ControlPID, Ki: &negative},
+		"unknown control mode":    {ControlMode: "bang_bang"},
+		"negative pid step limit": {ControlMode: ControlPID, StepLimitMv: &negative},
+	} {
+		if _, err := resolveBiasControl(req); err == nil {
+			t.Errorf("%s accepted", name)
+		}
+	}
+
+	c := BiasControl{Damping: 0.5, StepLimitMv: 1}
+	if got := c.next(1.0, 1.1); math.Abs(got-1.001) > 1e-12 {
+		t.Errorf("limited step from 1.0 toward 1.1 = %v, want 1.001", got)
+	}
+	if got := c.next(1.0, 1.0004); math.Abs(got-1.0002) > 1e-12 {
+		t.Errorf("smoothed step from 1.0 toward 1.0004 = %v, want 1.0002", got)
+	}
+}
+
+func TestPIDCorrection(t *testing.T) {
+	c := BiasControl{Mode: ControlPID, Kp: 0.5, Ki: 0.1, Kd: 0.2}
+	var s pidState
+	// First step: no derivative yet
+	if got, want := c.correct(&s, 2, 0.5), 0.5*2+0.1*1; math.Abs(got-want) > 1e-12 {
+		t.Errorf("first step = %v, want %v", got, want)
+	}
+	// Second: the integral accumulates and the derivative sees the change
+	if got, want := c.correct(&s, 1, 0.5), 0.5*1+0.1*1.5+0.2*(1-2)/0.5; math.Abs(got-want) > 1e-12 {
+		t.Errorf("second step = %v, want %v", got, want)
+	}
+
+	c.StepLimitMv = 1
+	if got := c.correct(&pidState{}, 10, 1); got != 0.001 {
+		t.Errorf("limited step = %v, want 0.001", got)
+	}
+}
+
+func TestPIDRunConvergesAndReportsGains(t *testing.T) {
+	kp := 0.008
+	req := SimulationRequest{TargetBER: 1e-12, AmbientProfile: "lab_default", LambdaCount: 8, ColdStart: true, ControlMode: ControlPID, Kp: &kp}
+	for seed := int64(1); seed <= 5; seed++ {
+		req.Seed = seed
+		resp, err := NewHELIOPASSSimulator().Simulate(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (BiasControl{Mode: ControlPID, StepLimitMv: defaultBiasStepLimitMv, Kp: kp, Ki: defaultPIDKi, Kd: defaultPIDKd}); resp.BiasControl != want {
+			t.Errorf("seed %d: bias control = %+v, want %+v", seed, resp.BiasControl, want)
+		}
+		if !resp.Converged {
+			t.Errorf("seed %d: pid run did not converge: final BER %g after %d iterations", seed, resp.FinalBER, resp.Iterations)
+		}
+		for _, v := range resp.BiasVoltages {
+			if v < 0.8 || v > 1.5 {
+				t.Errorf("seed %d: bias voltage %g outside [0.8, 1.5]", seed, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// BER decay fit parameters
+const (
+	decayFitWindow             = 8 // most recent samples the decay is fit to
+	minDecayFitSamples         = 3
+	defaultEarlyStopConfidence = 0.9
+)
+
+// Convergence criteria bounds; a converged run ends at or below target BER
+// × the tolerance, by default defaultConvergenceBERTolerance
+const (
+	defaultConvergenceBERTolerance = 1.1
+	maxConvergenceBERTolerance     = 10.0
+)
+
+// ConvergenceCriteria are the thresholds a run must meet to converge
+type ConvergenceCriteria struct {
+	BERTolerance float64 `json:"ber_tolerance"`     // final BER at most target × this
+	MinEyeMargin float64 `json:"min_eye_margin_ui"` // final eye margin at least this
+}
+
+// resolveConvergenceCriteria applies the defaults to a request's
+// convergence criteria: defaultConvergenceBERTolerance and the model's
+// ConvergedEyeMargin
+func resolveConvergenceCriteria(berTolerance, minEyeMargin float64, params ModelParams) (ConvergenceCriteria, error) {
+	c := ConvergenceCriteria{BERTolerance: defaultConvergenceBERTolerance, MinEyeMargin: params.ConvergedEyeMargin}
+	if berTolerance != 0 {
+		if berTolerance <= 1 || berTolerance > maxConvergenceBERTolerance {
+			return ConvergenceCriteria{}, fmt.Errorf("convergence_ber_tolerance must be in (1, %g]", maxConvergenceBERTolerance)
+		}
+		c.BERTolerance = berTolerance
+	}
+	if minEyeMargin != 0 {
+		if minEyeMargin < 0.1 || minEyeMargin > 1.5 {
+			return ConvergenceCriteria{}, fmt.Errorf("min_eye_margin_ui must be in [0.1, 1.5]")
+		}
+		c.MinEyeMargin = minEyeMargin
+	}
+	return c, nil
+}
+
+// converged reports whether a BER and eye margin meet the criteria
+func (c ConvergenceCriteria) converged(ber, target, eyeMargin float64) bool {
+	return ber <= target*c.BERTolerance && eyeMargin >= c.MinEyeMargin
+}
+
+// EarlyStop is the prediction a simulation stopped early on
+type EarlyStop struct {
+	PredictedConverged bool    `json:"predicted_converged"`
+	PredictedFinalBER  float64 `json:"predicted_final_ber"` // at the end of the simulated duration
+	Confidence         float64 `json:"confidence"`          // in [0, 1]
+	StoppedAt          float64 `json:"stopped_at_seconds"`
+}
+
+// decayFit is a least-squares fit of ln(BER - target) = Intercept + Slope·t
+// over the recent BER samples of a recovery
+type decayFit struct {
+	Intercept float64
+	Slope     float64 // per second; negative while BER decays
+	n         int
+	meanT     float64 // mean sample time
+	meanY     float64 // mean ln(BER - target)
+	stt       float64 // sum of squared time deviations
+	residual  float64 // standard deviation of the residuals
+}
+
+// fitBERDecay fits the exponential decay of the BER excess over target
+// across the last decayFitWindow samples. Samples at or below target carry
+// no decay to fit and end the window; it fails with too few samples.
+func fitBERDecay(samples []BERPoint, target float64) (decayFit, bool) {
+	var ts, ys []float64
+	for i := len(samples) - 1; i >= 0 && len(ts) < decayFitWindow; i-- {
+		excess := samples[i].BER - target
+		if excess <= 0 {
+			break
+		}
+		ts = append(ts, samples[i].Time)
+		ys = append(ys, math.Log(excess))
+	}
+	if len(ts) < minDecayFitSamples {
+		return decayFit{}, false
+	}
+
+	f := decayFit{n: len(ts)}
+	for i := range ts {
+		f.meanT += ts[i] / float64(f.n)
+		f.meanY += ys[i] / float64(f.n)
+	}
+	var sty float64
+	for i := range ts {
+		f.stt += (ts[i] - f.meanT) * (ts[i] - f.meanT)
+		sty += (ts[i] - f.meanT) * (ys[i] - f.meanY)
+	}
+	if f.stt == 0 {
+		return decayFit{}, false
+	}
+	f.Slope = sty / f.stt
+	f.Intercept = f.meanY - f.Slope*f.meanT
+
+	var sse float64
+	for i := range ts {
+		r := ys[i] - (f.Intercept + f.Slope*ts[i])
+		sse += r * r
+	}
+	f.residual = math.Sqrt(sse / float64(f.n-2))
+	return f, true
+}
+
+// timeConstant is the fitted 1/e decay time of the BER excess in seconds,
+// 0 when BER is not decaying
+func (f decayFit) timeConstant() float64 {
+	if f.Slope >= 0 {
+		return 0
+	}
+	return -1 / f.Slope
+}
+
+// predictEarlyStop decides whether the BER decay already makes the outcome
+// at end clear. A decaying excess is extrapolated exponentially; the
+// simulated decay only speeds up, so that bounds the final BER from above
+// and can call convergence but never rule it out. An excess that has
+// stopped decaying is held at its mean, which can rule convergence out.
+// Confidence is the probability, under the fit's prediction interval, that
+// the final BER falls on the predicted side of the convergence threshold.
+// Convergence also needs the eye margin, so a converging prediction is only
+// made once the eye margin reaches the criteria's minimum.
+func predictEarlyStop(f decayFit, target, now, end, eyeMargin float64, criteria ConvergenceCriteria, minConfidence float64) (EarlyStop, bool) {
+	threshold := math.Log(target * (criteria.BERTolerance - 1)) // ln of the excess allowed
+	y, spread := f.meanY, 1+1/float64(f.n)
+	if f.Slope < 0 {
+		y = f.Intercept + f.Slope*end
+		spread += (end - f.meanT) * (end - f.meanT) / f.stt
+	}
+
+	converging := y <= threshold
+	if converging != (f.Slope < 0) {
+		return EarlyStop{}, false
+	}
+	if converging && eyeMargin < criteria.MinEyeMargin {
+		return EarlyStop{}, false
+	}
+
+	confidence := 1.0
+	if se := f.residual * math.Sqrt(spread); se > 0 {
+		z := math.Abs(threshold-y) / se
+		confidence = 1 - 0.5*math.Erfc(z/math.Sqrt2)
+	}
+	stop := EarlyStop{
+		PredictedConverged: converging,
+		PredictedFinalBER:  target + math.Exp(y),
+		Confidence:         confidence,
+		StoppedAt:          now,
+	}
+	return stop, confidence >= minConfidence
+}
+
+// resolveEarlyStopConfidence applies the default to a request's
+// early-stop confidence
+func resolveEarlyStopConfidence(confidence float64) (float64, error) {
+	if confidence == 0 {
+		return defaultEarlyStopConfidence, nil
+	}
+	if confidence < 0 || confidence > 1 {
+		return 0, fmt.Errorf("early_stop_confidence must be in (0, 1]")
+	}
+	return confidence, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestEarlyStopPredictionMatchesFullRun(t *testing.T) {
+	predicted := 0
+	for seed := int64(1); seed <= 20; seed++ {
+		req := SimulationRequest{TargetBER: 1e-12, AmbientProfile: "lab_default", LambdaCount: 8, ColdStart: true}
+		full, err := newSeededSimulator(seed).Simulate(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.PredictEarlyStop = true
+		early, err := newSeededSimulator(seed).Simulate(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if early.EarlyStop == nil {
+			if early.Iterations != full.Iterations {
+				t.Errorf("seed %d: no prediction but ran %d iterations, full run %d", seed, early.Iterations, full.Iterations)
+			}
+			continue
+		}
+
+		stop := early.EarlyStop
+		if stop.PredictedConverged != full.Converged {
+			t.Errorf("seed %d: predicted converged %v, full run converged %v", seed, stop.PredictedConverged, full.Converged)
+		}
+		if stop.Confidence < defaultEarlyStopConfidence || stop.Confidence > 1 {
+			t.Errorf("seed %d: confidence %g", seed, stop.Confidence)
+		}
+		if early.Iterations >= full.Iterations || early.Status != "early_stop" {
+			t.Errorf("seed %d: early stop after %d iterations (%s), full run %d", seed, early.Iterations, early.Status, full.Iterations)
+		}
+		if stop.PredictedConverged {
+			predicted++
+			if stop.PredictedFinalBER > req.TargetBER*early.ConvergenceCriteria.BERTolerance {
+				t.Errorf("seed %d: predicted convergence with final BER %g", seed, stop.PredictedFinalBER)
+			}
+		}
+		if early.ConvergenceTimeConstant <= 0 {
+			t.Errorf("seed %d: time constant %g", seed, early.ConvergenceTimeConstant)
+		}
+	}
+	if predicted == 0 {
+		t.Fatal("no seeded run predicted convergence early")
+	}
+}
+
+func TestFitRecoversTimeConstant(t *testing.T) {
+	const target, tau = 1e-12, 4.0
+	var samples []BERPoint
+	for i := 0; i < 10; i++ {
+		tm := float64(i)
+		samples = append(samples, BERPoint{Time: tm, BER: target + 1e-9*math.Exp(-tm/tau)})
+	}
+	fit, ok := fitBERDecay(samples, target)
+	if !ok {
+		t.Fatal("fit failed")
+	}
+	if got := fit.timeConstant(); math.Abs(got-tau) > 1e-6 {
+		t.Errorf("time constant = %g, want %g", got, tau)
+	}
+	if _, ok := fitBERDecay(samples[:minDecayFitSamples-1], target); ok {
+		t.Error("fit succeeded with too few samples")
+	}
+}
+
+func TestEarlyStopConfidenceValidation(t *testing.T) {
+	for _, c := range []float64{-0.1, 1.5} {
+		req := SimulationRequest{TargetBER: 1e-12, AmbientProfile: "lab_default", PredictEarlyStop: true, EarlyStopConfidence: c}
+		if _, err := newSeededSimulator(1).Simulate(req); err == nil {
+			t.Errorf("early_stop_confidence %g accepted", c)
+		}
+	}
+}
+
+func TestConvergenceCriteriaFromRequest(t *testing.T) {
+	req := SimulationRequest{TargetBER: 1e-12, AmbientProfile: "lab_default", LambdaCount: 8, ColdStart: true, Seed: 1}
+	defaults, err := NewHELIOPASSSimulator().Simulate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ConvergenceCriteria{BERTolerance: defaultConvergenceBERTolerance, MinEyeMargin: DefaultModelParams().ConvergedEyeMargin}); defaults.ConvergenceCriteria != want {
+		t.Errorf("default criteria = %+v, want %+v", defaults.ConvergenceCriteria, want)
+	}
+
+	// The default model's eye settles near 0.8 UI, short of 0.85
+	req.MinEyeMargin, req.ConvergenceBERTolerance = 0.85, 1.5
+	tight, err := NewHELIOPASSSimulator().Simulate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tight.ConvergenceCriteria != (ConvergenceCriteria{BERTolerance: 1.5, MinEyeMargin: 0.85}) {
+		t.Errorf("criteria = %+v", tight.ConvergenceCriteria)
+	}
+	if tight.Converged || tight.FinalEyeMargin >= 0.85 {
+		t.Errorf("converged %v at eye %g against a 0.85 UI minimum", tight.Converged, tight.FinalEyeMargin)
+	}
+
+	req.ModelParams = json.RawMessage(`{"eye_margin_target_ui": 0.9}`)
+	wide, err := NewHELIOPASSSimulator().Simulate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wide.Converged || wide.FinalEyeMargin < 0.85 || wide.FinalBER > 1.5e-12 {
+		t.Errorf("a 0.9 UI eye: converged %v at eye %g, BER %g", wide.Converged, wide.FinalEyeMargin, wide.FinalBER)
+	}
+}
+
+func TestConvergenceCriteriaValidation(t *testing.T) {
+	for _, c := range []ConvergenceCriteria{{BERTolerance: 1}, {BERTolerance: 11}, {MinEyeMargin: 0.05}, {MinEyeMargin: 2}} {
+		req := SimulationRequest{TargetBER: 1e-12, AmbientProfile: "lab_default", ConvergenceBERTolerance: c.BERTolerance, MinEyeMargin: c.MinEyeMargin}
+		if _, err := NewHELIOPASSSimulator().Simulate(req); err == nil {
+			t.Errorf("criteria %+v accepted", c)
+		}
+	}
+}
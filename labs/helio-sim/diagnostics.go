@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Thresholds of the convergence failure diagnostics
+const (
+	stallBERDecades   = 0.05 // BER improvement that counts as progress
+	stallEyeMarginUI  = 0.005
+	stallIterations   = 5   // iterations without progress that make a stall
+	noisyProfileLevel = 0.2 // profile noise level past which calibration struggles
+	manyLambdas       = 8   // lane count past which the worst channel is suspect
+)
+
+// Convergence criteria a run can fail
+const (
+	criterionBER       = "ber"
+	criterionEyeMargin = "eye_margin"
+)
+
+// Diagnostics explains why a run did not converge
+type Diagnostics struct {
+	FailedCriteria []string `json:"failed_criteria"`
+	// BERGapDecades is how far the final BER is above the allowed BER,
+	// EyeMarginGapUI how far the final eye margin is below the minimum
+	BERGapDecades  float64 `json:"ber_gap_decades"`
+	EyeMarginGapUI float64 `json:"eye_margin_gap_ui"`
+	BestBER        float64 `json:"best_ber"`
+	BestEyeMargin  float64 `json:"best_eye_margin_ui"`
+	// StalledAtIteration is the last iteration that made progress, when
+	// none followed it for stallIterations; nil while still improving
+	StalledAtIteration *int `json:"stalled_at_iteration,omitempty"`
+	// BERTrend is the recent rate of change of the BER; negative improves
+	BERTrend    float64  `json:"ber_trend_decades_per_second"`
+	Suggestions []string `json:"suggestions"`
+}
+
+// progressTracker follows a run's best BER and eye margin so far and the
+// last iteration that improved either meaningfully. A disturbance restarts
+// the progress measure from the values it leaves.
+type progressTracker struct {
+	started       bool
+	bestBER       float64
+	bestEyeMargin float64
+	lastImproved  int
+	// the values progress is measured from, best since the last disturbance
+	refBER       float64
+	refEyeMargin float64
+	disturbed    bool
+}
+
+// observe records an iteration's BER and eye margin
+func (t *progressTracker) observe(iteration int, ber, eyeMargin float64) {
+	if !t.started {
+		t.started, t.bestBER, t.bestEyeMargin = true, ber, eyeMargin
+		t.disturbed = true
+	}
+	t.bestBER = math.Min(t.bestBER, ber)
+	t.bestEyeMargin = math.Max(t.bestEyeMargin, eyeMargin)
+	if t.disturbed {
+		t.refBER, t.refEyeMargin, t.lastImproved, t.disturbed = ber, eyeMargin, iteration, false
+		return
+	}
+	if math.Log10(t.refBER/ber) >= stallBERDecades || eyeMargin-t.refEyeMargin >= stallEyeMarginUI {
+		t.refBER = math.Min(t.refBER, ber)
+		t.refEyeMargin = math.Max(t.refEyeMargin, eyeMargin)
+		t.lastImproved = iteration
+	}
+}
+
+// disturb restarts the progress measure at the next observation
+func (t *progressTracker) disturb() {
+	t.disturbed = true
+}
+
+// diagnose explains the failure of a run of req that settled toward
+// eyeSettle, suggesting what to change
+func (t progressTracker) diagnose(req SimulationRequest, profile AmbientProfile, eyeSettle float64, resp *SimulationResponse) *Diagnostics {
+	criteria := resp.ConvergenceCriteria
+	allowedBER := req.TargetBER * criteria.BERTolerance
+	d := &Diagnostics{
+		FailedCriteria: []string{},
+		BestBER:        t.bestBER,
+		BestEyeMargin:  t.bestEyeMargin,
+		Suggestions:    []string{},
+	}
+	if resp.FinalBER > allowedBER {
+		d.FailedCriteria = append(d.FailedCriteria, criterionBER)
+		d.BERGapDecades = math.Log10(resp.FinalBER / allowedBER)
+	}
+	if resp.FinalEyeMargin < criteria.MinEyeMargin {
+		d.FailedCriteria = append(d.FailedCriteria, criterionEyeMargin)
+		d.EyeMarginGapUI = criteria.MinEyeMargin - resp.FinalEyeMargin
+	}
+	if last := resp.Iterations - 1; last-t.lastImproved >= stallIterations {
+		stalled := t.lastImproved
+		d.StalledAtIteration = &stalled
+	}
+	if n := len(resp.BERProfile); n >= 2 {
+		first, end := resp.BERProfile[max(0, n-decayFitWindow)], resp.BERProfile[n-1]
+		if end.Time > first.Time {
+			d.BERTrend = math.Log10(end.BER/first.BER) / (end.Time - first.Time)
+		}
+	}
+
+	berFailed := d.BERGapDecades > 0
+	if link := resp.LinkEstimate; berFailed && link != nil && link.BER > allowedBER {
+		d.suggest("the %s link model estimates a BER of %.3g, above the allowed %.3g; relax target_ber or shorten reach_mm", link.Model, link.BER, allowedBER)
+	}
+	if d.EyeMarginGapUI > 0 && criteria.MinEyeMargin > eyeSettle {
+		d.suggest("min_eye_margin_ui %.3g is above the %.3g UI the link settles to; lower it", criteria.MinEyeMargin, eyeSettle)
+	}
+	if profile.NoiseLevel >= noisyProfileLevel {
+		d.suggest("ambient profile %s is too noisy for this target; use a quieter profile or add a fallback", req.AmbientProfile)
+	}
+	if berFailed && req.LambdaCount > manyLambdas {
+		d.suggest("reduce lambda_count; the worst of %d channels sets the BER", req.LambdaCount)
+	}
+	for _, e := range resp.Events {
+		if e.Applied && e.Iteration >= resp.Iterations*3/4 {
+			d.suggest("the %s at %.1f s left too little time to recover; move it earlier", e.Kind, e.Time)
+		}
+	}
+	if d.StalledAtIteration == nil {
+		d.suggest("the run was still improving when it ended; raise model_params.max_iterations")
+	}
+	if len(d.Suggestions) == 0 {
+		d.suggest("relax target_ber or convergence_ber_tolerance")
+	}
+	return d
+}
+
+func (d *Diagnostics) suggest(format string, args ...any) {
+	d.Suggestions = append(d.Suggestions, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFailedRunIsDiagnosed(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	resp, err := h.Simulate(SimulationRequest{TargetBER: 1e-18, AmbientProfile: "field_noise_high", LambdaCount: 16, ColdStart: true, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := resp.Diagnostics
+	if resp.Converged || d == nil {
+		t.Fatalf("converged %v, diagnostics %+v", resp.Converged, d)
+	}
+	if len(d.FailedCriteria) == 0 || d.FailedCriteria[0] != criterionBER || d.BERGapDecades <= 0 {
+		t.Errorf("failed %v with a BER gap of %g decades", d.FailedCriteria, d.BERGapDecades)
+	}
+	if d.BestBER > resp.FinalBER {
+		t.Errorf("best BER %g above the final %g", d.BestBER, resp.FinalBER)
+	}
+	suggestions := strings.Join(d.Suggestions, "\n")
+	for _, want := range []string{"field_noise_high is too noisy", "reduce lambda_count"} {
+		if !strings.Contains(suggestions, want) {
+			t.Errorf("no suggestion containing %q in %q", want, d.Suggestions)
+		}
+	}
+
+	// The default model settles near 0.8 UI, short of the margin asked for
+	margin := 0.85
+	resp, err = h.Simulate(SimulationRequest{TargetBER: 1e-12, AmbientProfile: "lab_default", ColdStart: true, Seed: 1, MinEyeMargin: margin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d = resp.Diagnostics; d == nil || d.EyeMarginGapUI <= 0 || !strings.Contains(strings.Join(d.Suggestions, "\n"), "min_eye_margin_ui") {
+		t.Errorf("eye margin diagnostics = %+v", d)
+	}
+}
+
+func TestConvergedRunHasNoDiagnostics(t *testing.T) {
+	resp, err := NewHELIOPASSSimulator().Simulate(SimulationRequest{TargetBER: 1e-12, AmbientProfile: "lab_default", ColdStart: true, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Converged || resp.Diagnostics != nil {
+		t.Errorf("converged %v, diagnostics %+v", resp.Converged, resp.Diagnostics)
+	}
+}
+
+func TestProgressTrackerFindsTheStall(t *testing.T) {
+	var tr progressTracker
+	for i, ber := range []float64{1e-6, 1e-8, 1e-10, 0.99e-10, 0.98e-10, 0.98e-10, 0.97e-10, 0.97e-10, 0.97e-10} {
+		tr.observe(i, ber, 0.5)
+	}
+	if tr.lastImproved != 2 || tr.bestBER != 0.97e-10 {
+		t.Errorf("last improved at %d, best BER %g", tr.lastImproved, tr.bestBER)
+	}
+
+	// A disturbance measures progress afresh from where it leaves the run
+	tr.disturb()
+	tr.observe(9, 1e-7, 0.3)
+	tr.observe(10, 1e-9, 0.3)
+	if tr.lastImproved != 10 || tr.bestBER != 0.97e-10 {
+		t.Errorf("after a disturbance: last improved at %d, best BER %g", tr.lastImproved, tr.bestBER)
+	}
+}
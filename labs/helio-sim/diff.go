@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+// ResultDiff compares two stored simulation results. Every delta is B - A,
+// so a negative FinalBER delta means run B ended at a lower BER.
+type ResultDiff struct {
+	A        string        `json:"a"`
+	B        string        `json:"b"`
+	Metrics  MetricDeltas  `json:"metrics"`
+	Profiles ProfileDeltas `json:"profiles"`
+	// Inputs lists the request inputs the two runs did not share; none
+	// means any difference in outcome is down to noise
+	Inputs          []InputDifference `json:"inputs_differed"`
+	InputsIdentical bool              `json:"inputs_identical"`
+}
+
+// MetricDelta is one metric of both runs and its change from A to B
+type MetricDelta struct {
+	A     float64 `json:"a"`
+	B     float64 `json:"b"`
+	Delta float64 `json:"delta"`
+}
+
+// MetricDeltas are the headline metrics of a result, compared
+type MetricDeltas struct {
+	ConvergenceTime MetricDelta `json:"convergence_time_seconds"`
+	FinalBER        MetricDelta `json:"final_ber"`
+	FinalEyeMargin  MetricDelta `json:"final_eye_margin"`
+	PowerSavings    MetricDelta `json:"power_savings_percent"`
+	Iterations      MetricDelta `json:"iterations"`
+}
+
+// ProfilePointDelta is both runs' value of a profile at one time
+type ProfilePointDelta struct {
+	Time  float64 `json:"time_seconds"`
+	A     float64 `json:"a"`
+	B     float64 `json:"b"`
+	Delta float64 `json:"delta"`
+}
+
+// ProfileDeltas are the runs' profiles aligned on time rather than
+// iteration, since runs of different length or iteration count sample at
+// different times. Each holds a point at every time either run sampled; a
+// run's value between its samples is interpolated and after its last
+// sample it holds its final value, as the calibration it ended on does.
+type ProfileDeltas struct {
+	BER         []ProfilePointDelta `json:"ber"`
+	EyeMargin   []ProfilePointDelta `json:"eye_margin_ui"`
+	Temperature []ProfilePointDelta `json:"temperature_c"`
+}
+
+// InputDifference is a request input that differed between the runs
+type InputDifference struct {
+	Field string          `json:"field"`
+	A     json.RawMessage `json:"a"`
+	B     json.RawMessage `json:"b"`
+}
+
+// DiffResults compares result b against result a
+func DiffResults(a, b StoredResult) ResultDiff {
+	ra, rb := a.Result, b.Result
+	inputs := diffInputs(a, b)
+	return ResultDiff{
+		A: a.ID,
+		B: b.ID,
+		Metrics: MetricDeltas{
+			ConvergenceTime: metricDelta(ra.ConvergenceTime, rb.ConvergenceTime),
+			FinalBER:        metricDelta(ra.FinalBER, rb.FinalBER),
+			FinalEyeMargin:  metricDelta(ra.FinalEyeMargin, rb.FinalEyeMargin),
+			PowerSavings:    metricDelta(ra.PowerSavings, rb.PowerSavings),
+			Iterations:      metricDelta(float64(ra.Iterations), float64(rb.Iterations)),
+		},
+		Profiles: ProfileDeltas{
+			BER:         alignProfiles(berSeries(ra.BERProfile), berSeries(rb.BERProfile)),
+			EyeMargin:   alignProfiles(eyeMarginSeries(ra.EyeMarginProfile), eyeMarginSeries(rb.EyeMarginProfile)),
+			Temperature: alignProfiles(temperatureSeries(ra.TemperatureProfile), temperatureSeries(rb.TemperatureProfile)),
+		},
+		Inputs:          inputs,
+		InputsIdentical: len(inputs) == 0,
+	}
+}
+
+func metricDelta(a, b float64) MetricDelta {
+	return MetricDelta{A: a, B: b, Delta: b - a}
+}
+
+// series is a profile reduced to its sample times and values
+type series struct {
+	times, values []float64
+}
+
+func berSeries(points []BERPoint) series {
+	var s series
+	for _, p := range points {
+		s.times = append(s.times, p.Time)
+		s.values = append(s.values, p.BER)
+	}
+	return s
+}
+
+func eyeMarginSeries(points []EyeMarginPoint) series {
+	var s series
+	for _, p := range points {
+		s.times = append(s.times, p.Time)
+		s.values = append(s.values, p.EyeMargin)
+	}
+	return s
+}
+
+func temperatureSeries(points []TemperaturePoint) series {
+	var s series
+	for _, p := range points {
+		s.times = append(s.times, p.Time)
+		s.values = append(s.values, p.Temperature)
+	}
+	return s
+}
+
+// at returns the series' value at t, interpolating linearly between
+// samples and holding the first and last sample outside them
+func (s series) at(t float64) float64 {
+	i := sort.SearchFloat64s(s.times, t)
+	switch {
+	case i == len(s.times):
+		return s.values[len(s.values)-1]
+	case s.times[i] == t || i == 0:
+		return s.values[i]
+	}
+	t0, t1 := s.times[i-1], s.times[i]
+	v0, v1 := s.values[i-1], s.values[i]
+	return v0 + (v1-v0)*(t-t0)/(t1-t0)
+}
+
+// alignProfiles compares two profiles at the union of their sample times
+func alignProfiles(a, b series) []ProfilePointDelta {
+	if len(a.times) == 0 || len(b.times) == 0 {
+		return []ProfilePointDelta{}
+	}
+	times := append(append([]float64(nil), a.times...), b.times...)
+	sort.Float64s(times)
+	out := make([]ProfilePointDelta, 0, len(times))
+	for i, t := range times {
+		if i > 0 && t == times[i-1] {
+			continue
+		}
+		va, vb := a.at(t), b.at(t)
+		out = append(out, ProfilePointDelta{Time: t, A: va, B: vb, Delta: vb - va})
+	}
+	return out
+}
+
+// diffInputs lists the request fields, after defaults, the model
+// parameters the runs resolved and the ambient profile that differ. Event
+// outcomes are not inputs, so only an event's time, kind and magnitude count.
+func diffInputs(a, b StoredResult) []InputDifference {
+	fa, fb := inputFields(a), inputFields(b)
+	names := make([]string, 0, len(fa))
+	for name := range fa {
+		names = append(names, name)
+	}
+	for name := range fb {
+		if _, ok := fa[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	diffs := []InputDifference{}
+	for _, name := range names {
+		va, vb := fa[name], fb[name]
+		if !jsonEqual(va, vb) {
+			diffs = append(diffs, InputDifference{Field: name, A: orNull(va), B: orNull(vb)})
+		}
+	}
+	return diffs
+}
+
+// inputFields flattens a result's inputs into JSON values by field name
+func inputFields(res StoredResult) map[string]json.RawMessage {
+	req := res.Request
+	req.ModelParams = nil
+	req.Events = make([]SimulationEvent, len(res.Request.Events))
+	for i, e := range res.Request.Events {
+		req.Events[i] = SimulationEvent{Time: e.Time, Kind: e.Kind, Magnitude: e.Magnitude}
+	}
+
+	fields := map[string]json.RawMessage{}
+	flatten(fields, "", req)
+	flatten(fields, "model_params.", res.Result.ModelParams)
+	flatten(fields, "ambient_profile.", res.Profile)
+	return fields
+}
+
+// flatten adds v's top-level JSON fields to fields, prefixing their names
+func flatten(fields map[string]json.RawMessage, prefix string, v any) {
+	b, _ := json.Marshal(v)
+	var m map[string]json.RawMessage
+	json.Unmarshal(b, &m)
+	for name, value := range m {
+		fields[prefix+name] = value
+	}
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	var va, vb any
+	json.Unmarshal(a, &va)
+	json.Unmarshal(b, &vb)
+	return reflect.DeepEqual(va, vb)
+}
+
+func orNull(v json.RawMessage) json.RawMessage {
+	if v == nil {
+		return json.RawMessage("null")
+	}
+	return v
+}
+
+func (h *HELIOPASSSimulator) handleDiffResults(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	idA, idB := q.Get("a"), q.Get("b")
+	if idA == "" || idB == "" {
+		apierr.Respond(w, apierr.CodeValidation, "a and b must both name a simulation result")
+		return
+	}
+	a, ok := h.Result(idA)
+	if !ok {
+		apierr.Respond(w, apierr.CodeNotFound, "simulation result not found: "+idA)
+		return
+	}
+	b, ok := h.Result(idB)
+	if !ok {
+		apierr.Respond(w, apierr.CodeNotFound, "simulation result not found: "+idB)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DiffResults(a, b))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+)
+
+// simulateSeeded runs req on h with the given seed and returns the stored
+// result
+func simulateSeeded(t *testing.T, h *HELIOPASSSimulator, seed int64, req SimulationRequest) StoredResult {
+	t.Helper()
+	req.Seed = seed
+	resp, err := h.Simulate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, ok := h.Result(resp.ID)
+	if !ok {
+		t.Fatalf("result %s was not stored", resp.ID)
+	}
+	return res
+}
+
+func diffRequest() SimulationRequest {
+	return SimulationRequest{TargetBER: 1e-12, AmbientProfile: "field_noise_high", LambdaCount: 8}
+}
+
+func TestDiffAgainstItselfIsZero(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	res := simulateSeeded(t, h, 1, diffRequest())
+	d := DiffResults(res, res)
+
+	m := d.Metrics
+	for name, delta := range map[string]MetricDelta{
+		"convergence time": m.ConvergenceTime, "final BER": m.FinalBER, "eye margin": m.FinalEyeMargin,
+		"power savings": m.PowerSavings, "iterations": m.Iterations,
+	} {
+		if delta.Delta != 0 || delta.A != delta.B {
+			t.Errorf("%s: %+v", name, delta)
+		}
+	}
+	for name, points := range map[string][]ProfilePointDelta{"BER": d.Profiles.BER, "eye": d.Profiles.EyeMargin, "temperature": d.Profiles.Temperature} {
+		if len(points) == 0 {
+			t.Errorf("%s profile is empty", name)
+		}
+		for _, p := range points {
+			if p.Delta != 0 {
+				t.Errorf("%s at %gs: %+v", name, p.Time, p)
+			}
+		}
+	}
+	if !d.InputsIdentical || len(d.Inputs) != 0 {
+		t.Errorf("inputs = %+v", d.Inputs)
+	}
+}
+
+func TestDiffOfTwoSeedsIsSigned(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	a := simulateSeeded(t, h, 1, diffRequest())
+	b := simulateSeeded(t, h, 2, diffRequest())
+	d := DiffResults(a, b)
+
+	nonzero := 0
+	for name, delta := range map[string]MetricDelta{
+		"convergence time": d.Metrics.ConvergenceTime, "final BER": d.Metrics.FinalBER,
+		"eye margin": d.Metrics.FinalEyeMargin, "power savings": d.Metrics.PowerSavings,
+	} {
+		if delta.Delta != delta.B-delta.A {
+			t.Errorf("%s: delta %g, want B - A = %g", name, delta.Delta, delta.B-delta.A)
+		}
+		if delta.Delta != 0 {
+			nonzero++
+		}
+	}
+	if d.Metrics.FinalBER.A != a.Result.FinalBER || d.Metrics.FinalBER.B != b.Result.FinalBER {
+		t.Errorf("final BER %+v is not A = %g, B = %g", d.Metrics.FinalBER, a.Result.FinalBER, b.Result.FinalBER)
+	}
+	if nonzero == 0 {
+		t.Error("two seeds gave identical metrics")
+	}
+	if d.InputsIdentical || len(d.Inputs) != 1 || d.Inputs[0].Field != "seed" {
+		t.Errorf("inputs = %+v, want only the seed to differ", d.Inputs)
+	}
+}
+
+func TestDiffAlignsRunsOfDifferentLength(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	short := diffRequest()
+	short.ModelParams = json.RawMessage(`{"max_iterations":3}`)
+	a := simulateSeeded(t, h, 1, short)
+	b := simulateSeeded(t, h, 1, diffRequest())
+	if len(a.Result.BERProfile) == len(b.Result.BERProfile) {
+		t.Fatalf("both runs sampled %d points", len(a.Result.BERProfile))
+	}
+	d := DiffResults(a, b)
+
+	points := d.Profiles.BER
+	if !sort.SliceIsSorted(points, func(i, j int) bool { return points[i].Time < points[j].Time }) {
+		t.Error("aligned profile is not in time order")
+	}
+	times := map[float64]bool{}
+	for _, p := range points {
+		times[p.Time] = true
+	}
+	for _, run := range []StoredResult{a, b} {
+		for _, p := range run.Result.BERProfile {
+			if !times[p.Time] {
+				t.Errorf("%s sample at %gs missing from the aligned profile", run.ID, p.Time)
+			}
+		}
+	}
+	last := points[len(points)-1]
+	if last.A != a.Result.BERProfile[len(a.Result.BERProfile)-1].BER {
+		t.Errorf("the shorter run does not hold its final BER: %+v", last)
+	}
+
+	var differed []string
+	for _, in := range d.Inputs {
+		differed = append(differed, in.Field)
+	}
+	if len(differed) != 1 || differed[0] != "model_params.max_iterations" {
+		t.Errorf("inputs differed = %v, want only model_params.max_iterations", differed)
+	}
+}
+
+func TestDiffEndpoint(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	a := simulateSeeded(t, h, 1, diffRequest())
+	b := simulateSeeded(t, h, 2, diffRequest())
+
+	for query, status := range map[string]int{
+		"?a=" + a.ID + "&b=" + b.ID:  http.StatusOK,
+		"?a=" + a.ID:                 http.StatusBadRequest,
+		"?a=" + a.ID + "&b=sim-gone": http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		h.handleDiffResults(rec, httptest.NewRequest(http.MethodGet, "/v1/helio-sim/results/diff"+query, nil))
+		if rec.Code != status {
+			t.Errorf("%s: status = %d, want %d: %s", query, rec.Code, status, rec.Body)
+			continue
+		}
+		if status == http.StatusOK {
+			var d ResultDiff
+			if err := json.NewDecoder(rec.Body).Decode(&d); err != nil || d.A != a.ID || d.B != b.ID {
+				t.Errorf("diff = %+v, %v", d, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+func TestErrorCodes(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	for _, tc := range []struct {
+		name   string
+		body   string
+		code   apierr.Code
+		status int
+	}{
+		{"malformed body", "{", apierr.CodeBadRequest, http.StatusBadRequest},
+		{"two objects", `{"target_ber":1e-12}{"target_ber":1e-9}`, apierr.CodeBadRequest, http.StatusBadRequest},
+		{"unknown field", `{"target_ber":1e-12,"lamda_count":8}`, apierr.CodeBadRequest, http.StatusBadRequest},
+		{"unknown profile", `{"corridor_id":"cor-1","target_ber":1e-12,"ambient_profile":"mars","lambda_count":8}`, apierr.CodeValidation, http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		h.handleSimulate(rec, httptest.NewRequest(http.MethodPost, "/v1/helio-sim/simulate", bytes.NewBufferString(tc.body)))
+		var e apierr.Error
+		if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+			t.Fatalf("%s: error body %q: %v", tc.name, rec.Body, err)
+		}
+		if rec.Code != tc.status || e.Code != tc.code {
+			t.Errorf("%s: %d %+v, want %d %s", tc.name, rec.Code, e, tc.status, tc.code)
+		}
+	}
+}
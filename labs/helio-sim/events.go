@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Environmental event kinds
+const (
+	EventTemperatureStep = "temperature_step"
+	EventVibrationSpike  = "vibration_spike"
+)
+
+// SimulationEvent is a step disturbance applied during a simulation
+type SimulationEvent struct {
+	Time      float64 `json:"time_seconds"`
+	Kind      string  `json:"kind"`      // temperature_step | vibration_spike
+	Magnitude float64 `json:"magnitude"` // °C for a step, µm RMS for a spike
+	Applied   bool    `json:"applied"`   // false if the simulation ended first
+	Iteration int     `json:"iteration"` // iteration the event was applied at
+}
+
+// validateEvents checks events against the simulation duration and orders them by time
+func validateEvents(events []SimulationEvent, duration int) ([]SimulationEvent, error) {
+	out := append([]SimulationEvent(nil), events...)
+	for _, e := range out {
+		if e.Kind != EventTemperatureStep && e.Kind != EventVibrationSpike {
+			return nil, fmt.Errorf("unknown event kind: %s (%s|%s)", e.Kind, EventTemperatureStep, EventVibrationSpike)
+		}
+		if e.Time < 0 || e.Time > float64(duration) {
+			return nil, fmt.Errorf("event at %.1fs is outside the %ds simulation", e.Time, duration)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time < out[j].Time })
+	return out, nil
+}
+
+// eventSeverity is the number of decades an event pushes BER away from target
+func eventSeverity(e SimulationEvent) float64 {
+	switch e.Kind {
+	case EventTemperatureStep:
+		return math.Abs(e.Magnitude) * 0.3
+	case EventVibrationSpike:
+		return math.Abs(e.Magnitude) * 0.2
+	}
+	return 0
+}
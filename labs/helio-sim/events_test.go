@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTemperatureStepSpikesBERThenReconverges(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	resp, err := h.Simulate(SimulationRequest{
+		TargetBER:      1e-12,
+		AmbientProfile: "lab_default",
+		Duration:       60,
+		Events:         []SimulationEvent{{Time: 30, Kind: EventTemperatureStep, Magnitude: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := resp.Events[0]
+	if !e.Applied || e.Iteration == 0 {
+		t.Fatalf("event = %+v, want applied mid-simulation", e)
+	}
+	before := resp.BERProfile[e.Iteration-1].BER
+	spike := resp.BERProfile[e.Iteration].BER
+	if spike < before*100 {
+		t.Errorf("BER at the event = %g, before = %g; want a spike of at least two decades", spike, before)
+	}
+	if dT := resp.TemperatureProfile[e.Iteration].Temperature - resp.TemperatureProfile[e.Iteration-1].Temperature; dT < 8 {
+		t.Errorf("temperature rose %.2f °C at the event, want about 10", dT)
+	}
+
+	if !resp.Converged || resp.Iterations <= e.Iteration+1 {
+		t.Fatalf("converged %v after %d iterations, event at %d", resp.Converged, resp.Iterations, e.Iteration)
+	}
+	if last := resp.BERProfile[len(resp.BERProfile)-1].BER; last > 1.1e-12 {
+		t.Errorf("final BER = %g, want re-convergence to 1e-12", last)
+	}
+}
+
+func TestEventsValidated(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	for _, events := range [][]SimulationEvent{
+		{{Time: 10, Kind: "earthquake", Magnitude: 1}},
+		{{Time: 61, Kind: EventVibrationSpike, Magnitude: 1}},
+		{{Time: -1, Kind: EventTemperatureStep, Magnitude: 1}},
+	} {
+		if _, err := h.Simulate(SimulationRequest{TargetBER: 1e-12, AmbientProfile: "lab_default", Duration: 60, Events: events}); err == nil {
+			t.Errorf("events %+v accepted", events)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Attempt names in SimulationResponse.Attempts
+const (
+	attemptPrimary  = "primary"
+	attemptFallback = "fallback"
+)
+
+// Fallback is a retry with relaxed settings for when the primary run fails
+// to converge. Unset fields keep the primary run's values.
+type Fallback struct {
+	TargetBER      float64 `json:"target_ber,omitempty"`      // looser than the primary target
+	AmbientProfile string  `json:"ambient_profile,omitempty"` // no noisier than the primary profile
+}
+
+// AttemptSummary is the outcome of one run of a simulation with a fallback
+type AttemptSummary struct {
+	Attempt        string  `json:"attempt"` // primary or fallback
+	ID             string  `json:"id"`
+	AmbientProfile string  `json:"ambient_profile"`
+	TargetBER      float64 `json:"target_ber"`
+	Status         string  `json:"status"`
+	Converged      bool    `json:"converged"`
+	FinalBER       float64 `json:"final_ber"`
+	FinalEyeMargin float64 `json:"final_eye_margin"`
+	Iterations     int     `json:"iterations"`
+}
+
+// fallbackRequest is the request the fallback attempt runs: the primary
+// request with the fallback's settings in place
+func (h *HELIOPASSSimulator) fallbackRequest(req SimulationRequest) (SimulationRequest, error) {
+	f := *req.Fallback
+	if f.TargetBER == 0 && f.AmbientProfile == "" {
+		return req, fmt.Errorf("fallback needs a target_ber or an ambient_profile")
+	}
+	if f.TargetBER != 0 && f.TargetBER < req.TargetBER {
+		return req, fmt.Errorf("fallback target_ber %.3g is stricter than the primary %.3g", f.TargetBER, req.TargetBER)
+	}
+	if f.AmbientProfile != "" {
+		profiles := h.GetAmbientProfiles()
+		fallback, exists := profiles[f.AmbientProfile]
+		if !exists {
+			return req, fmt.Errorf("unknown fallback ambient profile: %s", f.AmbientProfile)
+		}
+		if fallback.NoiseLevel > profiles[req.AmbientProfile].NoiseLevel {
+			return req, fmt.Errorf("fallback ambient profile %s is noisier than %s", f.AmbientProfile, req.AmbientProfile)
+		}
+	}
+
+	retry := req
+	retry.Fallback = nil
+	if f.TargetBER != 0 {
+		retry.TargetBER = f.TargetBER
+	}
+	if f.AmbientProfile != "" {
+		retry.AmbientProfile = f.AmbientProfile
+	}
+	return retry, nil
+}
+
+// failedToConverge reports whether a run ended without converging or a
+// prediction that it would
+func failedToConverge(res *SimulationResponse) bool {
+	return !res.Converged && (res.EarlyStop == nil || !res.EarlyStop.PredictedConverged)
+}
+
+// summarizeAttempt summarizes a run of req
+func summarizeAttempt(attempt string, req SimulationRequest, res *SimulationResponse) AttemptSummary {
+	return AttemptSummary{
+		Attempt:        attempt,
+		ID:             res.ID,
+		AmbientProfile: req.AmbientProfile,
+		TargetBER:      req.TargetBER,
+		Status:         res.Status,
+		Converged:      res.Converged,
+		FinalBER:       res.FinalBER,
+		FinalEyeMargin: res.FinalEyeMargin,
+		Iterations:     res.Iterations,
+	}
+}
+
+// simulateWithFallback runs req and, if it fails to converge, its fallback.
+// The fallback's result is returned when it converges and the primary's
+// otherwise; either way it lists both attempts. Each attempt is stored as
+// its own result.
+func (h *HELIOPASSSimulator) simulateWithFallback(ctx context.Context, req SimulationRequest, progress func(SimulationProgress)) (*SimulationResponse, error) {
+	if _, exists := h.GetAmbientProfiles()[req.AmbientProfile]; !exists {
+		return nil, fmt.Errorf("unknown ambient profile: %s", req.AmbientProfile)
+	}
+	retry, err := h.fallbackRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	primary, err := h.simulate(ctx, req, progressOf(attemptPrimary, progress))
+	if err != nil {
+		return nil, err
+	}
+	attempts := []AttemptSummary{summarizeAttempt(attemptPrimary, req, primary)}
+	if !failedToConverge(primary) {
+		h.setAttempts(primary, attempts, attemptPrimary)
+		return primary, nil
+	}
+
+	fallback, err := h.simulate(ctx, retry, progressOf(attemptFallback, progress))
+	if err != nil {
+		return nil, err
+	}
+	attempts = append(attempts, summarizeAttempt(attemptFallback, retry, fallback))
+	if failedToConverge(fallback) {
+		h.setAttempts(primary, attempts, attemptPrimary)
+		return primary, nil
+	}
+	h.setAttempts(fallback, attempts, attemptFallback)
+	return fallback, nil
+}
+
+// setAttempts records the attempts behind an effective result, on it and
+// on its stored copy
+func (h *HELIOPASSSimulator) setAttempts(res *SimulationResponse, attempts []AttemptSummary, effective string) {
+	res.Attempts = attempts
+	res.EffectiveAttempt = effective
+
+	if stored, ok := h.results.Peek(res.ID); ok {
+		stored.Result.Attempts = attempts
+		stored.Result.EffectiveAttempt = effective
+		h.results.Set(res.ID, stored)
+	}
+}
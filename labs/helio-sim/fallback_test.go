@@ -0,0 +1,84 @@
+package main
+
+import "testing"
+
+// unreachableBER is below the BER floor of every profile, so a primary run
+// targeting it never converges
+const unreachableBER = 1e-18
+
+func TestFallbackBecomesEffectiveWhenItConverges(t *testing.T) {
+	h := newSeededSimulator(3)
+	resp, err := h.Simulate(SimulationRequest{
+		TargetBER:      unreachableBER,
+		AmbientProfile: "field_noise_high",
+		ColdStart:      true,
+		Fallback:       &Fallback{TargetBER: 1e-12, AmbientProfile: "space_sim"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Attempts) != 2 {
+		t.Fatalf("attempts = %+v", resp.Attempts)
+	}
+	primary, fallback := resp.Attempts[0], resp.Attempts[1]
+	if primary.Attempt != attemptPrimary || primary.Converged || primary.Status != "partial_convergence" {
+		t.Errorf("primary attempt = %+v", primary)
+	}
+	if fallback.Attempt != attemptFallback || !fallback.Converged || fallback.TargetBER != 1e-12 || fallback.AmbientProfile != "space_sim" {
+		t.Errorf("fallback attempt = %+v", fallback)
+	}
+	if resp.EffectiveAttempt != attemptFallback || resp.ID != fallback.ID || !resp.Converged {
+		t.Errorf("effective result = %s %s converged %v, want the fallback %s", resp.EffectiveAttempt, resp.ID, resp.Converged, fallback.ID)
+	}
+
+	// Both attempts are stored; the effective one carries the attempt list
+	stored, ok := h.Result(fallback.ID)
+	if !ok || stored.Result.EffectiveAttempt != attemptFallback || len(stored.Result.Attempts) != 2 {
+		t.Errorf("stored fallback result = %+v, %v", stored.Result, ok)
+	}
+	if _, ok := h.Result(primary.ID); !ok {
+		t.Error("the primary attempt was not stored")
+	}
+}
+
+func TestFallbackSkippedWhenPrimaryConverges(t *testing.T) {
+	resp, err := newSeededSimulator(3).Simulate(SimulationRequest{
+		TargetBER:      1e-12,
+		AmbientProfile: "lab_default",
+		Fallback:       &Fallback{TargetBER: 1e-9},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Attempts) != 1 || resp.EffectiveAttempt != attemptPrimary || !resp.Converged {
+		t.Errorf("attempts = %+v, effective %s", resp.Attempts, resp.EffectiveAttempt)
+	}
+}
+
+func TestPrimaryKeptWhenFallbackFails(t *testing.T) {
+	resp, err := newSeededSimulator(3).Simulate(SimulationRequest{
+		TargetBER:      unreachableBER,
+		AmbientProfile: "field_noise_high",
+		Fallback:       &Fallback{AmbientProfile: "space_sim"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Attempts) != 2 || resp.EffectiveAttempt != attemptPrimary || resp.ID != resp.Attempts[0].ID {
+		t.Errorf("attempts = %+v, effective %s", resp.Attempts, resp.EffectiveAttempt)
+	}
+}
+
+func TestFallbackValidation(t *testing.T) {
+	for name, f := range map[string]Fallback{
+		"empty":           {},
+		"stricter target": {TargetBER: 1e-15},
+		"unknown profile": {AmbientProfile: "mars"},
+		"noisier profile": {AmbientProfile: "field_noise_high"},
+	} {
+		req := SimulationRequest{TargetBER: 1e-12, AmbientProfile: "datacenter", Fallback: &f}
+		if _, err := newSeededSimulator(3).Simulate(req); err == nil {
+			t.Errorf("%s fallback accepted", name)
+		}
+	}
+}
@@ -1,5 +1,13 @@
 module github.com/corridoros/helio-sim
 
-go 1.21
+go 1.27
 
-require github.com/gorilla/mux v1.8.1
+require (
+	github.com/corridoros/pkg v0.0.0
+	github.com/corridoros/security/pqc v0.0.0
+	github.com/gorilla/mux v1.8.1
+)
+
+replace github.com/corridoros/pkg => ../../pkg
+
+replace github.com/corridoros/security/pqc => ../../security/pqc
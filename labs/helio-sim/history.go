@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxHistoryPerCorridor bounds the calibrations retained per corridor
+const maxHistoryPerCorridor = 32
+
+// maxHistoryCorridors bounds the corridors with calibration history kept;
+// the least recently calibrated loses its history, and with it warm starts
+const maxHistoryCorridors = 1024
+
+// CalibrationRecord is the outcome of one calibration of a corridor
+type CalibrationRecord struct {
+	Timestamp        time.Time `json:"timestamp"`
+	AmbientProfile   string    `json:"ambient_profile"`
+	Converged        bool      `json:"converged"`
+	Iterations       int       `json:"iterations"`
+	WarmStart        bool      `json:"warm_start"`
+	FinalBER         float64   `json:"final_ber"`
+	FinalEyeMargin   float64   `json:"final_eye_margin"`
+	BiasVoltages     []float64 `json:"bias_voltages_mv"`
+	LambdaShifts     []float64 `json:"lambda_shifts_nm"`
+	LaserPowerAdjust []float64 `json:"laser_power_adjust_db"`
+}
+
+// lastConverged returns the most recent converged calibration of a corridor
+// with a matching lane count
+func (h *HELIOPASSSimulator) lastConverged(corridorID string, lambdaCount int) (CalibrationRecord, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	records, _ := h.history.Peek(corridorID)
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Converged && len(records[i].BiasVoltages) == lambdaCount {
+			return records[i], true
+		}
+	}
+	return CalibrationRecord{}, false
+}
+
+// recordCalibration appends a calibration outcome to a corridor's history
+func (h *HELIOPASSSimulator) recordCalibration(corridorID string, rec CalibrationRecord) {
+	rec.BiasVoltages = append([]float64(nil), rec.BiasVoltages...)
+	rec.LambdaShifts = append([]float64(nil), rec.LambdaShifts...)
+	rec.LaserPowerAdjust = append([]float64(nil), rec.LaserPowerAdjust...)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	records, _ := h.history.Peek(corridorID)
+	records = append(records, rec)
+	if len(records) > maxHistoryPerCorridor {
+		records = records[len(records)-maxHistoryPerCorridor:]
+	}
+	h.history.Set(corridorID, records)
+}
+
+// History returns the calibration history of a corridor, oldest first
+func (h *HELIOPASSSimulator) History(corridorID string) []CalibrationRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	records, _ := h.history.Peek(corridorID)
+	return append([]CalibrationRecord{}, records...)
+}
+
+func (h *HELIOPASSSimulator) handleGetHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.History(mux.Vars(r)["corridor_id"]))
+}
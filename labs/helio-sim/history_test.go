@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func calibrate(t *testing.T, h *HELIOPASSSimulator, corridorID string) *SimulationResponse {
+	t.Helper()
+	resp, err := h.Simulate(SimulationRequest{
+		CorridorID:     corridorID,
+		TargetBER:      1e-12,
+		AmbientProfile: "lab_default",
+		LambdaCount:    8,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestRepeatCalibrationConvergesFaster(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	first := calibrate(t, h, "cor-1")
+	if !first.Converged || first.WarmStart {
+		t.Fatalf("first calibration: converged %v, warm start %v", first.Converged, first.WarmStart)
+	}
+	second := calibrate(t, h, "cor-1")
+	if !second.WarmStart {
+		t.Fatal("second calibration of the same corridor did not warm start")
+	}
+	if second.Iterations >= first.Iterations {
+		t.Errorf("warm start took %d iterations, cold start %d", second.Iterations, first.Iterations)
+	}
+
+	if other := calibrate(t, h, "cor-2"); other.WarmStart {
+		t.Error("a different corridor warm started from cor-1's history")
+	}
+}
+
+func TestHistoryEndpoint(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	calibrate(t, h, "cor-1")
+	calibrate(t, h, "cor-1")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/helio-sim/history/{corridor_id}", h.handleGetHistory)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/helio-sim/history/cor-1", nil))
+
+	var records []CalibrationRecord
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].WarmStart || !records[1].WarmStart {
+		t.Errorf("history = %+v", records)
+	}
+	if len(records[0].BiasVoltages) != 8 {
+		t.Errorf("recorded %d bias voltages, want 8", len(records[0].BiasVoltages))
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/corridoros/pkg/linkmodel"
+)
+
+// An ideal link, simulated when no link model is selected, calibrates down
+// to any target BER above idealBERFloor and opens its eye to the model's
+// EyeMarginTarget
+const idealBERFloor = 1e-15
+
+// LinkEstimate is what a link model estimates the simulated corridor can
+// achieve; a run settles on its BER and eye margin instead of the ideal
+// link's
+type LinkEstimate struct {
+	Model string `json:"model"`
+	linkmodel.Estimate
+}
+
+// estimateLink estimates the link of a run with the request's link model,
+// or the simulator's, under the run's temperature and ambient noise. It
+// returns nil when neither selects a model.
+func (h *HELIOPASSSimulator) estimateLink(req SimulationRequest, profile AmbientProfile) (*LinkEstimate, error) {
+	name := req.LinkModel
+	if name == "" {
+		name = h.LinkModel
+	}
+	if name == "" {
+		return nil, nil
+	}
+	model, err := linkmodel.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	est, err := model.Estimate(linkmodel.Config{
+		Modulation: req.Modulation,
+		ReachMm:    req.ReachMm,
+		Lanes:      req.LambdaCount,
+	}, linkmodel.Conditions{TempC: req.Temperature, NoiseLevel: profile.NoiseLevel})
+	if err != nil {
+		return nil, fmt.Errorf("link model %s: %v", model.Name(), err)
+	}
+	return &LinkEstimate{Model: model.Name(), Estimate: est}, nil
+}
@@ -1,14 +1,27 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"math"
 	"math/rand"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/bounded"
+	"github.com/corridoros/pkg/jsonbody"
+	"github.com/corridoros/pkg/linkmodel"
+	"github.com/corridoros/pkg/tlsserve"
+	"github.com/corridoros/pkg/trace"
+	"github.com/corridoros/security/pqc/httpsign"
 	"github.com/gorilla/mux"
 )
 
@@ -21,8 +34,29 @@ type HELIOPASSSimulator struct {
 	BaseEMI            float64
 	DriftRate          float64
 	NoiseLevel         float64
-	ConvergenceRate    float64
-	MaxIterations      int
+
+	// Params are the model constants runs use unless a request overrides them
+	Params ModelParams
+
+	// Calibration history per corridor, used to warm-start repeat calibrations
+	mu      sync.Mutex
+	history *bounded.BoundedStore[[]CalibrationRecord]
+
+	// Ambient profiles by name: the built-in defaults and those registered
+	// at runtime
+	profilesMu sync.RWMutex
+	profiles   map[string]AmbientProfile
+
+	// Finished simulations, kept for retrieval and reports
+	results *bounded.BoundedStore[StoredResult]
+
+	// seeds draws the seeds of requests that give none; nil seeds them
+	// from the clock
+	seeds *rand.Rand
+
+	// LinkModel estimates the link runs settle on for requests that do
+	// not choose a model; empty simulates an ideal link
+	LinkModel string
 }
 
 // SimulationRequest represents a HELIOPASS simulation request
@@ -35,10 +69,44 @@ type SimulationRequest struct {
 	InitialEyeMargin float64   `json:"initial_eye_margin,omitempty"`
 	Temperature      float64   `json:"temperature_c,omitempty"`
 	Duration         int       `json:"duration_seconds,omitempty"`
+	ColdStart        bool      `json:"cold_start,omitempty"` // ignore calibration history
+	// Seed makes the run reproducible: a cold-started request with the same
+	// seed gives the same result. Zero seeds the run from the clock.
+	Seed             int64     `json:"seed,omitempty"`
+	Events           []SimulationEvent `json:"events,omitempty"`
+	// ControlMode is how bias voltages are adjusted: open_loop (default)
+	// or pid, with gains Kp, Ki and Kd
+	ControlMode      string    `json:"control_mode,omitempty"`
+	Kp               *float64  `json:"kp,omitempty"`
+	Ki               *float64  `json:"ki,omitempty"`
+	Kd               *float64  `json:"kd,omitempty"`
+	Damping          *float64  `json:"damping,omitempty"`       // open-loop bias smoothing, default 0.7
+	StepLimitMv      *float64  `json:"step_limit_mv,omitempty"` // bias step cap per iteration, default 2
+	// PredictEarlyStop ends the run once the BER decay makes the outcome
+	// clear with at least EarlyStopConfidence (default 0.9)
+	PredictEarlyStop    bool    `json:"predict_early_stop,omitempty"`
+	EarlyStopConfidence float64 `json:"early_stop_confidence,omitempty"`
+	// Convergence criteria: the final BER at most target × the tolerance
+	// (default 1.1) and the eye margin at least the minimum (default the
+	// model's converged_eye_margin_ui)
+	ConvergenceBERTolerance float64 `json:"convergence_ber_tolerance,omitempty"`
+	MinEyeMargin            float64 `json:"min_eye_margin_ui,omitempty"`
+	// Fallback is retried if this run fails to converge
+	Fallback *Fallback `json:"fallback,omitempty"`
+	// LinkModel names the model estimating the corridor's achievable BER
+	// and eye margin from its modulation and reach
+	LinkModel  string `json:"link_model,omitempty"`
+	Modulation string `json:"modulation,omitempty"` // NRZ (default) or PAM4
+	ReachMm    int    `json:"reach_mm,omitempty"`
+	// ModelParams overrides some of the simulator's model parameters for
+	// this run, as a partial ModelParams object
+	ModelParams json.RawMessage `json:"model_params,omitempty"`
 }
 
 // SimulationResponse represents the simulation results
 type SimulationResponse struct {
+	ID                 string                 `json:"id"`
+	Seed               int64                  `json:"seed"` // reproduces the run
 	CorridorID         string                 `json:"corridor_id"`
 	Status             string                 `json:"status"`
 	Converged          bool                   `json:"converged"`
@@ -46,16 +114,48 @@ type SimulationResponse struct {
 	FinalEyeMargin     float64                `json:"final_eye_margin"`
 	ConvergenceTime    float64                `json:"convergence_time_seconds"`
 	Iterations         int                    `json:"iterations"`
+	WarmStart          bool                   `json:"warm_start"`
 	BiasVoltages       []float64              `json:"bias_voltages_mv"`
 	LambdaShifts       []float64              `json:"lambda_shifts_nm"`
 	LaserPowerAdjust   []float64              `json:"laser_power_adjust_db"`
 	PowerSavings       float64                `json:"power_savings_percent"`
+	PowerSavingsDetail PowerSavingsBreakdown  `json:"power_savings_breakdown"`
 	TemperatureProfile []TemperaturePoint     `json:"temperature_profile"`
-	BERProfile         []BERPoint             `json:"ber_profile"`
+	BERProfile         []BERPoint             `json:"ber_profile"` // of the worst channel
+	PerLambdaBER       [][]BERPoint           `json:"per_lambda_ber"` // per channel, in lane order
 	EyeMarginProfile   []EyeMarginPoint       `json:"eye_margin_profile"`
+	Consistency        LinkConsistency        `json:"consistency"` // final BER against final eye margin
+	Events             []SimulationEvent      `json:"events,omitempty"`
+	BiasControl        BiasControl            `json:"bias_control"`
+	ConvergenceCriteria ConvergenceCriteria   `json:"convergence_criteria"`
+	// Fitted 1/e decay time of the BER excess over target, at the end of the run
+	ConvergenceTimeConstant float64           `json:"convergence_time_constant_seconds,omitempty"`
+	EarlyStop          *EarlyStop             `json:"early_stop,omitempty"`
+	Diagnostics        *Diagnostics           `json:"diagnostics,omitempty"` // of a run that did not converge
+	// Runs of a request with a fallback, and which of them this result is
+	Attempts           []AttemptSummary       `json:"attempts,omitempty"`
+	EffectiveAttempt   string                 `json:"effective_attempt,omitempty"`
+	LinkEstimate       *LinkEstimate          `json:"link_estimate,omitempty"`
+	ModelParams        ModelParams            `json:"model_params"` // the parameters the run used
 	Error              string                 `json:"error,omitempty"`
 }
 
+// PowerSavingsBreakdown splits PowerSavings into its contributions and the
+// inputs behind them. BiasVoltageContribution + LaserPowerContribution +
+// CapAdjustment == Total, where CapAdjustment clamps the total to [0, CapPercent].
+type PowerSavingsBreakdown struct {
+	MeanBiasVoltage         float64 `json:"mean_bias_voltage"`
+	ReferenceBiasVoltage    float64 `json:"reference_bias_voltage"`
+	PercentPerVolt          float64 `json:"percent_per_volt"`
+	BiasVoltageContribution float64 `json:"bias_voltage_contribution_percent"`
+	MeanLaserReductionDb    float64 `json:"mean_laser_reduction_db"`
+	PercentPerDbReduction   float64 `json:"percent_per_db_reduction"`
+	LaserPowerContribution  float64 `json:"laser_power_contribution_percent"`
+	CapPercent              float64 `json:"cap_percent"`
+	CapAdjustment           float64 `json:"cap_adjustment_percent"`
+	Total                   float64 `json:"total_percent"`
+}
+
 // TemperaturePoint represents a temperature measurement
 type TemperaturePoint struct {
 	Time        float64 `json:"time_seconds"`
@@ -66,6 +166,8 @@ type TemperaturePoint struct {
 type BERPoint struct {
 	Time float64 `json:"time_seconds"`
 	BER  float64 `json:"ber"`
+	// Running estimate of the convergence time constant, once enough of the decay is seen
+	TimeConstant float64 `json:"time_constant_seconds,omitempty"`
 }
 
 // EyeMarginPoint represents an eye margin measurement
@@ -86,8 +188,15 @@ type AmbientProfile struct {
 	NoiseLevel     float64 `json:"noise_level"`
 }
 
-// NewHELIOPASSSimulator creates a new HELIOPASS simulator
+// NewHELIOPASSSimulator creates a new HELIOPASS simulator with the default
+// model parameters
 func NewHELIOPASSSimulator() *HELIOPASSSimulator {
+	return NewHELIOPASSSimulatorWithParams(DefaultModelParams())
+}
+
+// NewHELIOPASSSimulatorWithParams creates a HELIOPASS simulator with the
+// given model parameters
+func NewHELIOPASSSimulatorWithParams(params ModelParams) *HELIOPASSSimulator {
 	return &HELIOPASSSimulator{
 		BaseTemperature: 22.0,
 		BaseHumidity:    45.0,
@@ -95,13 +204,44 @@ func NewHELIOPASSSimulator() *HELIOPASSSimulator {
 		BaseEMI:         -80.0,
 		DriftRate:       0.001,
 		NoiseLevel:      0.1,
-		ConvergenceRate: 0.8,
-		MaxIterations:   50,
+		Params:          params,
+		history:         bounded.New[[]CalibrationRecord](maxHistoryCorridors),
+		profiles:        defaultAmbientProfiles(),
+		results:         newResultStore(maxStoredResults),
 	}
 }
 
+// newSeededSimulator creates a simulator whose runs are reproducible from
+// seed, which seeds the runs of requests that give none. It is not safe for
+// concurrent use.
+func newSeededSimulator(seed int64) *HELIOPASSSimulator {
+	h := NewHELIOPASSSimulator()
+	h.seeds = rand.New(rand.NewSource(seed))
+	return h
+}
+
+// newSeed returns the seed of a run whose request gives none
+func (h *HELIOPASSSimulator) newSeed() int64 {
+	if h.seeds != nil {
+		return h.seeds.Int63() | 1
+	}
+	return time.Now().UnixNano()
+}
+
 // GetAmbientProfiles returns available ambient profiles
 func (h *HELIOPASSSimulator) GetAmbientProfiles() map[string]AmbientProfile {
+	h.profilesMu.RLock()
+	defer h.profilesMu.RUnlock()
+	profiles := make(map[string]AmbientProfile, len(h.profiles))
+	for name, profile := range h.profiles {
+		profiles[name] = profile
+	}
+	return profiles
+}
+
+// defaultAmbientProfiles returns the built-in ambient profiles, which
+// cannot be deleted
+func defaultAmbientProfiles() map[string]AmbientProfile {
 	return map[string]AmbientProfile{
 		"lab_default": {
 			Name:           "Laboratory Default",
@@ -156,10 +296,26 @@ func (h *HELIOPASSSimulator) GetAmbientProfiles() map[string]AmbientProfile {
 	}
 }
 
-// Simulate performs HELIOPASS simulation
+// Simulate performs HELIOPASS simulation, retrying with the request's
+// fallback if it fails to converge
 func (h *HELIOPASSSimulator) Simulate(req SimulationRequest) (*SimulationResponse, error) {
-	profiles := h.GetAmbientProfiles()
-	profile, exists := profiles[req.AmbientProfile]
+	return h.SimulateWithProgress(context.Background(), req, nil)
+}
+
+// SimulateWithProgress performs a simulation as Simulate does, calling
+// progress, if not nil, with each iteration of each run as it is computed.
+// It gives up with ctx's error once ctx is done, storing nothing.
+func (h *HELIOPASSSimulator) SimulateWithProgress(ctx context.Context, req SimulationRequest, progress func(SimulationProgress)) (*SimulationResponse, error) {
+	if req.Fallback != nil {
+		return h.simulateWithFallback(ctx, req, progress)
+	}
+	return h.simulate(ctx, req, progress)
+}
+
+// simulate runs a single HELIOPASS simulation and stores its result,
+// reporting each iteration to progress if it is not nil
+func (h *HELIOPASSSimulator) simulate(ctx context.Context, req SimulationRequest, progress func(SimulationProgress)) (*SimulationResponse, error) {
+	profile, exists := h.ambientProfile(req.AmbientProfile)
 	if !exists {
 		return nil, fmt.Errorf("unknown ambient profile: %s", req.AmbientProfile)
 	}
@@ -168,6 +324,21 @@ func (h *HELIOPASSSimulator) Simulate(req SimulationRequest) (*SimulationRespons
 	if req.LambdaCount == 0 {
 		req.LambdaCount = 8
 	}
+
+	// A corridor calibrated before starts from its last converged operating point
+	var last CalibrationRecord
+	warmStart := false
+	if req.CorridorID != "" && !req.ColdStart {
+		last, warmStart = h.lastConverged(req.CorridorID, req.LambdaCount)
+	}
+	if warmStart {
+		if req.InitialBER == 0 {
+			req.InitialBER = last.FinalBER
+		}
+		if req.InitialEyeMargin == 0 {
+			req.InitialEyeMargin = last.FinalEyeMargin
+		}
+	}
 	if req.InitialBER == 0 {
 		req.InitialBER = 1e-9
 	}
@@ -180,8 +351,44 @@ func (h *HELIOPASSSimulator) Simulate(req SimulationRequest) (*SimulationRespons
 	if req.Duration == 0 {
 		req.Duration = 60
 	}
+	if req.Seed == 0 {
+		req.Seed = h.newSeed()
+	}
+	rng := rand.New(rand.NewSource(req.Seed))
+	events, err := validateEvents(req.Events, req.Duration)
+	if err != nil {
+		return nil, err
+	}
+	control, err := resolveBiasControl(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.EarlyStopConfidence, err = resolveEarlyStopConfidence(req.EarlyStopConfidence); err != nil {
+		return nil, err
+	}
+	params, err := h.resolveModelParams(req.ModelParams)
+	if err != nil {
+		return nil, err
+	}
+	criteria, err := resolveConvergenceCriteria(req.ConvergenceBERTolerance, req.MinEyeMargin, params)
+	if err != nil {
+		return nil, err
+	}
+	link, err := h.estimateLink(req, profile)
+	if err != nil {
+		return nil, err
+	}
+	berFloor, eyeSettle := idealBERFloor, params.EyeMarginTarget
+	if link != nil {
+		berFloor, eyeSettle = link.BER, link.EyeMarginUI
+	}
 
-	// Initialize simulation state
+	// Initialize simulation state. BER is tracked per lambda channel;
+	// currentBER is the worst channel's.
+	laneBER := make([]float64, req.LambdaCount)
+	for j := range laneBER {
+		laneBER[j] = req.InitialBER
+	}
 	currentBER := req.InitialBER
 	currentEyeMargin := req.InitialEyeMargin
 	targetBER := req.TargetBER
@@ -191,53 +398,112 @@ func (h *HELIOPASSSimulator) Simulate(req SimulationRequest) (*SimulationRespons
 	lambdaShifts := make([]float64, req.LambdaCount)
 	laserPowerAdjust := make([]float64, req.LambdaCount)
 
-	for i := range biasVoltages {
-		biasVoltages[i] = 1.2 + (rand.Float64()-0.5)*0.2
-		lambdaShifts[i] = (rand.Float64() - 0.5) * 0.02
-		laserPowerAdjust[i] = (rand.Float64() - 0.5) * 0.5
+	if warmStart {
+		copy(biasVoltages, last.BiasVoltages)
+		copy(lambdaShifts, last.LambdaShifts)
+		copy(laserPowerAdjust, last.LaserPowerAdjust)
+	} else {
+		for i := range biasVoltages {
+			biasVoltages[i] = 1.2 + (rng.Float64()-0.5)*0.2
+			lambdaShifts[i] = (rng.Float64() - 0.5) * 0.02
+			laserPowerAdjust[i] = (rng.Float64() - 0.5) * 0.5
+		}
 	}
 
 	// Simulation profiles
 	temperatureProfile := []TemperaturePoint{}
 	berProfile := []BERPoint{}
+	perLambdaBER := make([][]BERPoint, req.LambdaCount)
+	pid := make([]pidState, req.LambdaCount)
+	var tracker progressTracker
 	eyeMarginProfile := []EyeMarginPoint{}
 
 	// Run simulation
 	converged := false
 	iterations := 0
-	dt := float64(req.Duration) / float64(h.MaxIterations)
-
-	for i := 0; i < h.MaxIterations; i++ {
+	dt := float64(req.Duration) / float64(params.MaxIterations)
+	nextEvent := 0
+	recoveryStart := 0
+	temperatureOffset := 0.0
+	timeConstant := 0.0
+	var earlyStop *EarlyStop
+
+	for i := 0; i < params.MaxIterations; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		iterations++
 		time := float64(i) * dt
 
+		// Apply due events: each knocks BER and eye margin off their
+		// calibrated values and restarts the recovery
+		for nextEvent < len(events) && events[nextEvent].Time <= time {
+			e := &events[nextEvent]
+			e.Applied = true
+			e.Iteration = i
+			if e.Kind == EventTemperatureStep {
+				temperatureOffset += e.Magnitude
+			}
+			severity := eventSeverity(*e)
+			for j := range laneBER {
+				laneBER[j] = math.Min(math.Max(laneBER[j], targetBER)*math.Pow(10, severity), 0.5)
+			}
+			currentEyeMargin -= math.Min(0.3, severity*0.1)
+			recoveryStart = i
+			tracker.disturb()
+			nextEvent++
+		}
+
 		// Update temperature with ambient profile and noise
-		temperature := profile.Temperature + h.simulateTemperatureNoise(time, profile)
+		temperature := profile.Temperature + temperatureOffset + h.simulateTemperatureNoise(rng, time, profile)
 		temperatureProfile = append(temperatureProfile, TemperaturePoint{
 			Time:        time,
 			Temperature: temperature,
 		})
 
-		// Simulate BER improvement
-		improvement := h.calculateImprovement(i, profile.NoiseLevel)
-		currentBER = targetBER + (currentBER-targetBER)*improvement
-
-		// Add noise
-		berNoise := h.calculateBERNoise(time, profile)
-		currentBER += berNoise
-		currentBER = math.Max(currentBER, 1e-15) // Minimum BER
+		// Simulate BER improvement and noise, independently per channel
+		currentBER = 0
+		for j := range laneBER {
+			if control.Mode == ControlPID {
+				// The loop trims the channel's bias against its BER error,
+				// and the BER follows the correction the clamp lets through
+				previous := biasVoltages[j]
+				step := control.correct(&pid[j], math.Log10(laneBER[j]/targetBER), dt)
+				biasVoltages[j] = math.Max(0.8, math.Min(1.5, previous+step))
+				decades := -biasBERSensitivity*(biasVoltages[j]-previous) + (rng.Float64()-0.5)*profile.NoiseLevel*0.1
+				laneBER[j] = math.Min(laneBER[j]*math.Pow(10, decades), 0.5)
+			} else {
+				improvement := h.calculateImprovement(rng, i-recoveryStart, profile.NoiseLevel, params)
+				laneBER[j] = targetBER + (laneBER[j]-targetBER)*improvement
+			}
+			laneBER[j] += h.calculateBERNoise(rng, time, profile)
+			laneBER[j] = math.Max(laneBER[j], berFloor) // the link's achievable BER
+
+			perLambdaBER[j] = append(perLambdaBER[j], BERPoint{
+				Time: time,
+				BER:  laneBER[j],
+			})
+			currentBER = math.Max(currentBER, laneBER[j])
+		}
 
 		berProfile = append(berProfile, BERPoint{
 			Time: time,
 			BER:  currentBER,
 		})
 
+		// Fit the decay of this recovery so far
+		fit, fitted := fitBERDecay(berProfile[recoveryStart:], targetBER)
+		if fitted {
+			timeConstant = fit.timeConstant()
+			berProfile[len(berProfile)-1].TimeConstant = timeConstant
+		}
+
 		// Simulate eye margin improvement
-		eyeImprovement := h.calculateEyeImprovement(i, profile.NoiseLevel)
-		currentEyeMargin = 0.8 + (currentEyeMargin-0.8)*eyeImprovement
+		eyeImprovement := h.calculateEyeImprovement(rng, i-recoveryStart, profile.NoiseLevel, params)
+		currentEyeMargin = eyeSettle + (currentEyeMargin-eyeSettle)*eyeImprovement
 
 		// Add noise to eye margin
-		eyeNoise := h.calculateEyeNoise(time, profile)
+		eyeNoise := h.calculateEyeNoise(rng, time, profile)
 		currentEyeMargin += eyeNoise
 		currentEyeMargin = math.Max(0.1, math.Min(1.5, currentEyeMargin))
 
@@ -245,29 +511,71 @@ func (h *HELIOPASSSimulator) Simulate(req SimulationRequest) (*SimulationRespons
 			Time:      time,
 			EyeMargin: currentEyeMargin,
 		})
+		tracker.observe(i, currentBER, currentEyeMargin)
+
+		if progress != nil {
+			progress(SimulationProgress{
+				Iteration:   i,
+				Temperature: temperatureProfile[len(temperatureProfile)-1],
+				BER:         berProfile[len(berProfile)-1],
+				LambdaBER:   append([]float64(nil), laneBER...),
+				EyeMargin:   eyeMarginProfile[len(eyeMarginProfile)-1],
+			})
+		}
 
-		// Update bias voltages and lambda shifts
-		h.updateBiasVoltages(biasVoltages, time, profile)
-		h.updateLambdaShifts(lambdaShifts, time, profile)
-		h.updateLaserPower(laserPowerAdjust, time, profile)
+		// Update bias voltages, unless the PID loop did, and lambda shifts
+		if control.Mode == ControlOpenLoop {
+			h.updateBiasVoltages(rng, biasVoltages, time, profile, control)
+		}
+		h.updateLambdaShifts(rng, lambdaShifts, time, profile)
+		h.updateLaserPower(rng, laserPowerAdjust, time, profile)
 
-		// Check convergence
-		if currentBER <= targetBER*1.1 && currentEyeMargin >= 0.7 {
-			converged = true
+		// Check convergence; keep running while events are still pending
+		converged = criteria.converged(currentBER, targetBER, currentEyeMargin)
+		if converged && nextEvent == len(events) {
 			break
 		}
+
+		// Stop early once the outcome is clear; pending events could still change it
+		if req.PredictEarlyStop && fitted && nextEvent == len(events) {
+			end := float64(params.MaxIterations-1) * dt
+			if stop, ok := predictEarlyStop(fit, targetBER, time, end, currentEyeMargin, criteria, req.EarlyStopConfidence); ok {
+				earlyStop = &stop
+				break
+			}
+		}
 	}
 
 	// Calculate final metrics
 	convergenceTime := float64(iterations) * dt
-	powerSavings := h.calculatePowerSavings(biasVoltages, laserPowerAdjust)
+	savings := h.calculatePowerSavings(biasVoltages, laserPowerAdjust, params)
 
 	status := "converged"
 	if !converged {
 		status = "partial_convergence"
 	}
+	if earlyStop != nil {
+		status = "early_stop"
+	}
 
-	return &SimulationResponse{
+	if req.CorridorID != "" {
+		h.recordCalibration(req.CorridorID, CalibrationRecord{
+			Timestamp:        time.Now().UTC(),
+			AmbientProfile:   req.AmbientProfile,
+			Converged:        converged,
+			Iterations:       iterations,
+			WarmStart:        warmStart,
+			FinalBER:         currentBER,
+			FinalEyeMargin:   currentEyeMargin,
+			BiasVoltages:     biasVoltages,
+			LambdaShifts:     lambdaShifts,
+			LaserPowerAdjust: laserPowerAdjust,
+		})
+	}
+
+	response := &SimulationResponse{
+		ID:                 newResultID(),
+		Seed:               req.Seed,
 		CorridorID:         req.CorridorID,
 		Status:             status,
 		Converged:          converged,
@@ -275,124 +583,170 @@ func (h *HELIOPASSSimulator) Simulate(req SimulationRequest) (*SimulationRespons
 		FinalEyeMargin:     currentEyeMargin,
 		ConvergenceTime:    convergenceTime,
 		Iterations:         iterations,
+		WarmStart:          warmStart,
 		BiasVoltages:       biasVoltages,
 		LambdaShifts:       lambdaShifts,
 		LaserPowerAdjust:   laserPowerAdjust,
-		PowerSavings:       powerSavings,
+		PowerSavings:       savings.Total,
+		PowerSavingsDetail: savings,
 		TemperatureProfile: temperatureProfile,
 		BERProfile:         berProfile,
+		PerLambdaBER:       perLambdaBER,
 		EyeMarginProfile:   eyeMarginProfile,
-	}, nil
+		Consistency:        checkEyeMargin(currentBER, currentEyeMargin),
+		Events:             events,
+		BiasControl:        control,
+		ConvergenceCriteria: criteria,
+		ConvergenceTimeConstant: timeConstant,
+		EarlyStop:          earlyStop,
+		LinkEstimate:       link,
+		ModelParams:        params,
+	}
+	if failedToConverge(response) {
+		response.Diagnostics = tracker.diagnose(req, profile, eyeSettle, response)
+	}
+	req.Events = events
+	h.storeResult(StoredResult{
+		ID:        response.ID,
+		CreatedAt: time.Now().UTC(),
+		Request:   req,
+		Profile:   profile,
+		Result:    *response,
+	})
+	return response, nil
 }
 
 // Helper methods for simulation
-func (h *HELIOPASSSimulator) simulateTemperatureNoise(time float64, profile AmbientProfile) float64 {
+func (h *HELIOPASSSimulator) simulateTemperatureNoise(rng *rand.Rand, time float64, profile AmbientProfile) float64 {
 	// Simulate temperature drift and noise
 	drift := math.Sin(time*0.1) * 0.5
-	noise := (rand.Float64() - 0.5) * profile.NoiseLevel * 2
+	noise := (rng.Float64() - 0.5) * profile.NoiseLevel * 2
 	return drift + noise
 }
 
-func (h *HELIOPASSSimulator) calculateImprovement(iteration int, noiseLevel float64) float64 {
+func (h *HELIOPASSSimulator) calculateImprovement(rng *rand.Rand, iteration int, noiseLevel float64, params ModelParams) float64 {
 	// Exponential improvement with noise
-	baseImprovement := math.Exp(-float64(iteration) * h.ConvergenceRate)
-	noise := (rand.Float64() - 0.5) * noiseLevel
+	baseImprovement := math.Exp(-float64(iteration) * params.ConvergenceRate)
+	noise := (rng.Float64() - 0.5) * noiseLevel
 	return baseImprovement + noise
 }
 
-func (h *HELIOPASSSimulator) calculateBERNoise(time float64, profile AmbientProfile) float64 {
+func (h *HELIOPASSSimulator) calculateBERNoise(rng *rand.Rand, time float64, profile AmbientProfile) float64 {
 	// BER noise based on environmental conditions
 	baseNoise := profile.NoiseLevel * 1e-12
 	timeNoise := math.Sin(time*0.5) * baseNoise * 0.5
-	randomNoise := (rand.Float64() - 0.5) * baseNoise
+	randomNoise := (rng.Float64() - 0.5) * baseNoise
 	return timeNoise + randomNoise
 }
 
-func (h *HELIOPASSSimulator) calculateEyeImprovement(iteration int, noiseLevel float64) float64 {
+func (h *HELIOPASSSimulator) calculateEyeImprovement(rng *rand.Rand, iteration int, noiseLevel float64, params ModelParams) float64 {
 	// Similar to BER improvement but for eye margin
-	baseImprovement := math.Exp(-float64(iteration) * h.ConvergenceRate * 0.8)
-	noise := (rand.Float64() - 0.5) * noiseLevel * 0.1
+	baseImprovement := math.Exp(-float64(iteration) * params.ConvergenceRate * params.EyeConvergenceRatio)
+	noise := (rng.Float64() - 0.5) * noiseLevel * 0.1
 	return baseImprovement + noise
 }
 
-func (h *HELIOPASSSimulator) calculateEyeNoise(time float64, profile AmbientProfile) float64 {
+func (h *HELIOPASSSimulator) calculateEyeNoise(rng *rand.Rand, time float64, profile AmbientProfile) float64 {
 	// Eye margin noise
 	baseNoise := profile.NoiseLevel * 0.01
 	timeNoise := math.Sin(time*0.3) * baseNoise * 0.5
-	randomNoise := (rand.Float64() - 0.5) * baseNoise
+	randomNoise := (rng.Float64() - 0.5) * baseNoise
 	return timeNoise + randomNoise
 }
 
-func (h *HELIOPASSSimulator) updateBiasVoltages(voltages []float64, time float64, profile AmbientProfile) {
+// updateBiasVoltages moves each voltage toward its compensated setpoint
+// through the control loop
+func (h *HELIOPASSSimulator) updateBiasVoltages(rng *rand.Rand, voltages []float64, time float64, profile AmbientProfile, control BiasControl) {
 	for i := range voltages {
 		// Temperature compensation
 		tempFactor := 1.0 + (profile.Temperature-h.BaseTemperature)*0.001
 		// Drift compensation
 		driftFactor := 1.0 + math.Sin(time*0.2)*profile.DriftRate*0.1
 		// Random adjustment
-		randomAdjust := (rand.Float64() - 0.5) * 0.01
+		randomAdjust := (rng.Float64() - 0.5) * 0.01
 		
-		voltages[i] = voltages[i] * tempFactor * driftFactor + randomAdjust
+		setpoint := voltages[i] * tempFactor * driftFactor + randomAdjust
+		voltages[i] = control.next(voltages[i], setpoint)
 		voltages[i] = math.Max(0.8, math.Min(1.5, voltages[i])) // Clamp to valid range
 	}
 }
 
-func (h *HELIOPASSSimulator) updateLambdaShifts(shifts []float64, time float64, profile AmbientProfile) {
+func (h *HELIOPASSSimulator) updateLambdaShifts(rng *rand.Rand, shifts []float64, time float64, profile AmbientProfile) {
 	for i := range shifts {
 		// Drift over time
 		drift := math.Sin(time*0.15) * profile.DriftRate * 0.01
 		// Random adjustment
-		randomAdjust := (rand.Float64() - 0.5) * 0.001
+		randomAdjust := (rng.Float64() - 0.5) * 0.001
 		
 		shifts[i] = shifts[i] + drift + randomAdjust
 		shifts[i] = math.Max(-0.1, math.Min(0.1, shifts[i])) // Clamp to valid range
 	}
 }
 
-func (h *HELIOPASSSimulator) updateLaserPower(powerAdjust []float64, time float64, profile AmbientProfile) {
+func (h *HELIOPASSSimulator) updateLaserPower(rng *rand.Rand, powerAdjust []float64, time float64, profile AmbientProfile) {
 	for i := range powerAdjust {
 		// Temperature compensation
 		tempFactor := 1.0 + (profile.Temperature-h.BaseTemperature)*0.0005
 		// Random adjustment
-		randomAdjust := (rand.Float64() - 0.5) * 0.1
+		randomAdjust := (rng.Float64() - 0.5) * 0.1
 		
 		powerAdjust[i] = powerAdjust[i] * tempFactor + randomAdjust
 		powerAdjust[i] = math.Max(-2.0, math.Min(2.0, powerAdjust[i])) // Clamp to valid range
 	}
 }
 
-func (h *HELIOPASSSimulator) calculatePowerSavings(biasVoltages []float64, laserPowerAdjust []float64) float64 {
-	// Calculate power savings based on optimized bias voltages and laser power
-	voltageSavings := 0.0
+func (h *HELIOPASSSimulator) calculatePowerSavings(biasVoltages []float64, laserPowerAdjust []float64, params ModelParams) PowerSavingsBreakdown {
+	b := PowerSavingsBreakdown{
+		ReferenceBiasVoltage:  params.ReferenceBiasVoltage,
+		PercentPerVolt:        params.PercentPerVolt,
+		PercentPerDbReduction: params.PercentPerDbReduction,
+		CapPercent:            params.SavingsCapPercent,
+	}
+
+	// Lower voltages generally mean lower power
 	for _, v := range biasVoltages {
-		// Lower voltages generally mean lower power
-		voltageSavings += (1.2 - v) * 10.0 // 10% per 0.1V difference
+		b.MeanBiasVoltage += v
 	}
-	voltageSavings = voltageSavings / float64(len(biasVoltages))
+	b.MeanBiasVoltage /= float64(len(biasVoltages))
+	b.BiasVoltageContribution = (b.ReferenceBiasVoltage - b.MeanBiasVoltage) * b.PercentPerVolt
 
-	laserSavings := 0.0
+	// Negative adjustments mean power savings
 	for _, p := range laserPowerAdjust {
-		// Negative adjustments mean power savings
 		if p < 0 {
-			laserSavings += math.Abs(p) * 5.0 // 5% per dB reduction
+			b.MeanLaserReductionDb += math.Abs(p)
 		}
 	}
-	laserSavings = laserSavings / float64(len(laserPowerAdjust))
+	b.MeanLaserReductionDb /= float64(len(laserPowerAdjust))
+	b.LaserPowerContribution = b.MeanLaserReductionDb * b.PercentPerDbReduction
 
-	return math.Max(0, math.Min(20, voltageSavings+laserSavings)) // Cap at 20%
+	raw := b.BiasVoltageContribution + b.LaserPowerContribution
+	b.Total = math.Max(0, math.Min(b.CapPercent, raw))
+	b.CapAdjustment = b.Total - raw
+	return b
 }
 
 // HTTP handlers
 func (h *HELIOPASSSimulator) handleSimulate(w http.ResponseWriter, r *http.Request) {
 	var req SimulationRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := jsonbody.Decode(r.Body, &req, jsonbody.Strict); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
 		return
 	}
+	if v := r.URL.Query().Get("stream"); v != "" {
+		stream, err := strconv.ParseBool(v)
+		if err != nil {
+			apierr.Respond(w, apierr.CodeBadRequest, "stream must be true or false")
+			return
+		}
+		if stream {
+			h.streamSimulation(w, r, req)
+			return
+		}
+	}
 
 	response, err := h.Simulate(req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierr.Respond(w, apierr.CodeValidation, err.Error())
 		return
 	}
 
@@ -412,25 +766,74 @@ func (h *HELIOPASSSimulator) handleHealth(w http.ResponseWriter, r *http.Request
 }
 
 func main() {
-	// Initialize random seed
-	rand.Seed(time.Now().UnixNano())
+	addr := flag.String("addr", ":8086", "listen address")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate chain; serves HTTPS with HTTP/2 instead of plaintext HTTP")
+	tlsKey := flag.String("tls-key", "", "PEM private key of -tls-cert")
+	tlsEndorsement := flag.String("tls-endorsement", "", "post-quantum endorsement of -tls-cert, made with tls-endorse, sent on every response")
+	signResponses := flag.Bool("sign-responses", false, "sign response bodies with an ML-DSA-65 key (public key at /v1/helio-sim/pubkey)")
+	maxResults := flag.Int("max-results", maxStoredResults, "simulation results kept for retrieval; the least recently used is evicted first")
+	resultTTL := flag.Duration("result-ttl", 0, "evict simulation results not read for this long (0 = keep until evicted for space)")
+	linkModel := flag.String("link-model", "", "link model runs settle on when a request names none ("+strings.Join(linkmodel.Names(), "|")+"); empty simulates an ideal link")
+	modelParams := flag.String("model-params", "", "JSON file of model parameters overriding the defaults (see /v1/helio-sim/model-params)")
+	flag.Parse()
+	if *maxResults <= 0 || *resultTTL < 0 {
+		log.Fatal("max-results must be positive and result-ttl must not be negative")
+	}
+	if *linkModel != "" {
+		if _, err := linkmodel.Lookup(*linkModel); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	// Create HELIOPASS simulator
 	simulator := NewHELIOPASSSimulator()
+	if *modelParams != "" {
+		data, err := os.ReadFile(*modelParams)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if simulator.Params, err = simulator.resolveModelParams(data); err != nil {
+			log.Fatal(err)
+		}
+	}
+	simulator.LinkModel = *linkModel
+	simulator.results.MaxEntries = *maxResults
+	simulator.results.TTL = *resultTTL
 
 	// Set up HTTP router
 	router := mux.NewRouter()
+	router.Use(trace.Middleware("helio-sim"))
 	api := router.PathPrefix("/v1/helio-sim").Subrouter()
 
 	// API endpoints
 	api.HandleFunc("/simulate", simulator.handleSimulate).Methods("POST")
 	api.HandleFunc("/profiles", simulator.handleGetProfiles).Methods("GET")
+	api.HandleFunc("/profiles", simulator.handleRegisterProfile).Methods("POST")
+	api.HandleFunc("/profiles/{name}", simulator.handleDeleteProfile).Methods("DELETE")
+	api.HandleFunc("/model-params", simulator.handleGetModelParams).Methods("GET")
+	api.HandleFunc("/history/{corridor_id}", simulator.handleGetHistory).Methods("GET")
+	api.HandleFunc("/results/diff", simulator.handleDiffResults).Methods("GET")
+	api.HandleFunc("/results/{id}", simulator.handleGetResult).Methods("GET")
+	api.HandleFunc("/results/{id}/report", simulator.handleGetReport).Methods("GET")
+	api.HandleFunc("/validate", simulator.handleValidate).Methods("GET")
 	api.HandleFunc("/health", simulator.handleHealth).Methods("GET")
 
+	// Optional response signing; clients verify against the published key
+	if *signResponses {
+		signer, err := httpsign.NewSigner()
+		if err != nil {
+			log.Fatalf("Failed to create response signer: %v", err)
+		}
+		router.Use(signer.Middleware)
+		api.HandleFunc("/pubkey", signer.HandlePublicKey).Methods("GET")
+		log.Printf("Signing responses with key %s", signer.PublicKey().KeyID)
+	}
+
 	// Health check
 	router.HandleFunc("/health", simulator.handleHealth).Methods("GET")
 
 	// Start server
-	log.Println("Starting HELIOPASS Simulator on :8086")
-	log.Fatal(http.ListenAndServe(":8086", router))
+	tlsCfg := tlsserve.Config{CertFile: *tlsCert, KeyFile: *tlsKey, EndorsementFile: *tlsEndorsement}
+	log.Printf("Starting HELIOPASS Simulator on %s", *addr)
+	log.Fatal(tlsserve.ListenAndServe(*addr, router, tlsCfg))
 }
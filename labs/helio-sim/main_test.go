@@ -0,0 +1,132 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestProfilesOutputIsStable(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	var first string
+	for i := 0; i < 5; i++ {
+		rec := httptest.NewRecorder()
+		h.handleGetProfiles(rec, httptest.NewRequest(http.MethodGet, "/v1/helio-sim/profiles", nil))
+		if i == 0 {
+			first = rec.Body.String()
+		} else if rec.Body.String() != first {
+			t.Fatalf("response %d differs:\n%s\n%s", i, first, rec.Body)
+		}
+	}
+}
+
+// sameRun reports whether two responses are the same run, apart from the
+// IDs they were stored under
+func sameRun(a, b *SimulationResponse) bool {
+	x, y := *a, *b
+	x.ID, y.ID = "", ""
+	return reflect.DeepEqual(x, y)
+}
+
+func TestSameSeedReproducesTheRun(t *testing.T) {
+	for _, profile := range []string{"lab_default", "field_noise_high"} {
+		req := SimulationRequest{TargetBER: 1e-12, AmbientProfile: profile, LambdaCount: 4, ColdStart: true, Seed: 42}
+		a, err := NewHELIOPASSSimulator().Simulate(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := NewHELIOPASSSimulator().Simulate(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameRun(a, b) {
+			t.Errorf("%s: seed 42 gave two different runs", profile)
+		}
+
+		req.Seed = 43
+		c, err := NewHELIOPASSSimulator().Simulate(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.DeepEqual(a.BERProfile, c.BERProfile) {
+			t.Errorf("%s: seeds 42 and 43 gave the same BER profile", profile)
+		}
+	}
+}
+
+func TestUnseededRunReportsItsSeed(t *testing.T) {
+	req := SimulationRequest{TargetBER: 1e-12, AmbientProfile: "field_noise_low", ColdStart: true}
+	first, err := NewHELIOPASSSimulator().Simulate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Seed == 0 {
+		t.Fatal("an unseeded run reported no seed")
+	}
+	req.Seed = first.Seed
+	again, err := NewHELIOPASSSimulator().Simulate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameRun(first, again) {
+		t.Error("the reported seed does not reproduce the run")
+	}
+}
+
+func TestConcurrentRunsWithOneSeedAgree(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	req := SimulationRequest{TargetBER: 1e-12, AmbientProfile: "lab_default", LambdaCount: 8, ColdStart: true, Seed: 7}
+	runs := make([]*SimulationResponse, 8)
+	var wg sync.WaitGroup
+	for i := range runs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			runs[i], _ = h.Simulate(req)
+		}(i)
+	}
+	wg.Wait()
+	for i, run := range runs {
+		if run == nil || !sameRun(run, runs[0]) {
+			t.Fatalf("run %d differs from run 0", i)
+		}
+	}
+}
+
+func TestConvergenceWaitsForEveryLambda(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		req := SimulationRequest{TargetBER: 1e-12, AmbientProfile: "field_noise_high", LambdaCount: 16, ColdStart: true, Seed: seed}
+		resp, err := NewHELIOPASSSimulator().Simulate(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.PerLambdaBER) != req.LambdaCount {
+			t.Fatalf("seed %d: %d channels, want %d", seed, len(resp.PerLambdaBER), req.LambdaCount)
+		}
+		threshold := req.TargetBER * resp.ConvergenceCriteria.BERTolerance
+		for i, aggregate := range resp.BERProfile {
+			worst, met := 0.0, true
+			for lane, profile := range resp.PerLambdaBER {
+				if len(profile) != resp.Iterations {
+					t.Fatalf("seed %d: lambda %d has %d points for %d iterations", seed, lane, len(profile), resp.Iterations)
+				}
+				worst = math.Max(worst, profile[i].BER)
+				met = met && profile[i].BER <= threshold
+			}
+			if aggregate.BER != worst {
+				t.Errorf("seed %d, iteration %d: BER %g is not the worst channel's %g", seed, i, aggregate.BER, worst)
+			}
+			// Only the last iteration may meet the criteria on every channel
+			met = met && resp.EyeMarginProfile[i].EyeMargin >= resp.ConvergenceCriteria.MinEyeMargin
+			if last := i == len(resp.BERProfile)-1; met && !last || last && met != resp.Converged {
+				t.Errorf("seed %d, iteration %d of %d: every channel met the target %v, converged %v", seed, i, resp.Iterations, met, resp.Converged)
+			}
+		}
+		if resp.FinalBER != resp.BERProfile[len(resp.BERProfile)-1].BER {
+			t.Errorf("seed %d: final BER %g is not the worst channel's", seed, resp.FinalBER)
+		}
+	}
+}
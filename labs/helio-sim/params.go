@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/corridoros/pkg/jsonbody"
+)
+
+// ModelParams are the tunable constants of the calibration model. A
+// simulator runs with its Params unless a request overrides some of them.
+type ModelParams struct {
+	// ConvergenceRate is the exponent of the per-iteration BER recovery,
+	// exp(-rate·n)
+	ConvergenceRate float64 `json:"convergence_rate"`
+	// EyeConvergenceRatio scales ConvergenceRate for the eye margin's
+	// recovery, which lags the BER's
+	EyeConvergenceRatio float64 `json:"eye_convergence_ratio"`
+	// EyeMarginTarget is the eye margin an ideal link opens to; a link
+	// model's estimate replaces it
+	EyeMarginTarget float64 `json:"eye_margin_target_ui"`
+	// ConvergedEyeMargin is the eye margin a converged run holds at least
+	ConvergedEyeMargin float64 `json:"converged_eye_margin_ui"`
+	MaxIterations      int     `json:"max_iterations"`
+
+	// Power savings model; see PowerSavingsBreakdown
+	ReferenceBiasVoltage  float64 `json:"reference_bias_voltage"`
+	PercentPerVolt        float64 `json:"percent_per_volt"`
+	PercentPerDbReduction float64 `json:"percent_per_db_reduction"`
+	SavingsCapPercent     float64 `json:"savings_cap_percent"`
+}
+
+// maxModelIterations bounds MaxIterations so one request cannot pin a core
+const maxModelIterations = 10000
+
+// DefaultModelParams returns the parameters the simulator is calibrated with
+func DefaultModelParams() ModelParams {
+	return ModelParams{
+		ConvergenceRate:       0.8,
+		EyeConvergenceRatio:   0.8,
+		EyeMarginTarget:       0.8,
+		ConvergedEyeMargin:    0.7,
+		MaxIterations:         50,
+		ReferenceBiasVoltage:  1.2,
+		PercentPerVolt:        10.0,
+		PercentPerDbReduction: 5.0,
+		SavingsCapPercent:     20.0,
+	}
+}
+
+// Validate checks the parameters are in range
+func (p ModelParams) Validate() error {
+	switch {
+	case p.ConvergenceRate <= 0:
+		return fmt.Errorf("convergence_rate must be positive")
+	case p.EyeConvergenceRatio <= 0:
+		return fmt.Errorf("eye_convergence_ratio must be positive")
+	case p.EyeMarginTarget < 0.1 || p.EyeMarginTarget > 1.5:
+		return fmt.Errorf("eye_margin_target_ui must be in [0.1, 1.5]")
+	case p.ConvergedEyeMargin < 0.1 || p.ConvergedEyeMargin > 1.5:
+		return fmt.Errorf("converged_eye_margin_ui must be in [0.1, 1.5]")
+	case p.MaxIterations <= 0 || p.MaxIterations > maxModelIterations:
+		return fmt.Errorf("max_iterations must be in [1, %d]", maxModelIterations)
+	case p.ReferenceBiasVoltage <= 0:
+		return fmt.Errorf("reference_bias_voltage must be positive")
+	case p.PercentPerVolt < 0 || p.PercentPerDbReduction < 0 || p.SavingsCapPercent < 0:
+		return fmt.Errorf("percent_per_volt, percent_per_db_reduction and savings_cap_percent must not be negative")
+	}
+	return nil
+}
+
+// resolveModelParams applies a request's overrides, a partial ModelParams
+// object, on top of the simulator's parameters
+func (h *HELIOPASSSimulator) resolveModelParams(overrides json.RawMessage) (ModelParams, error) {
+	p := h.Params
+	if len(overrides) > 0 {
+		if err := jsonbody.Decode(bytes.NewReader(overrides), &p, jsonbody.Strict); err != nil {
+			return ModelParams{}, fmt.Errorf("model_params: %v", err)
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return ModelParams{}, fmt.Errorf("model_params: %v", err)
+	}
+	return p, nil
+}
+
+func (h *HELIOPASSSimulator) handleGetModelParams(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Params)
+}
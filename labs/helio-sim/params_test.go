@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// baseline is the outcome of seeded runs of the default model, recorded
+// when BER became per lambda channel
+var baseline = []struct {
+	seed           int64
+	iterations     int
+	converged      bool
+	finalBER       float64
+	finalEyeMargin float64
+	powerSavings   float64
+}{
+	{1, 6, true, 1.0389145384357528e-12, 0.8004618919200748, 0.4857096850252369},
+	{2, 6, true, 1.0263328510817256e-12, 0.8004035250341173, 0.6897007724853963},
+	{3, 6, true, 1.0464254081145259e-12, 0.8004748229132098, 0},
+	{4, 6, true, 1.0271853567489894e-12, 0.8001798454694315, 0.2422977917100242},
+}
+
+func baselineRequest() SimulationRequest {
+	return SimulationRequest{TargetBER: 1e-12, AmbientProfile: "lab_default", LambdaCount: 8, ColdStart: true}
+}
+
+func TestDefaultParamsReproduceBaseline(t *testing.T) {
+	defaults, _ := json.Marshal(DefaultModelParams())
+	for _, b := range baseline {
+		for name, req := range map[string]SimulationRequest{
+			"no overrides":      baselineRequest(),
+			"explicit defaults": func() SimulationRequest { r := baselineRequest(); r.ModelParams = defaults; return r }(),
+		} {
+			req.Seed = b.seed
+			r, err := NewHELIOPASSSimulator().Simulate(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Iterations != b.iterations || r.Converged != b.converged || r.FinalBER != b.finalBER ||
+				r.FinalEyeMargin != b.finalEyeMargin || r.PowerSavings != b.powerSavings {
+				t.Errorf("seed %d, %s: %d iterations, converged %v, BER %g, eye %g, savings %g; want %+v",
+					b.seed, name, r.Iterations, r.Converged, r.FinalBER, r.FinalEyeMargin, r.PowerSavings, b)
+			}
+			if r.ModelParams != DefaultModelParams() {
+				t.Errorf("seed %d, %s: ran with %+v", b.seed, name, r.ModelParams)
+			}
+		}
+	}
+}
+
+func TestConvergenceRateChangesIterations(t *testing.T) {
+	iterations := func(rate float64) int {
+		total := 0
+		for seed := int64(1); seed <= 8; seed++ {
+			req := baselineRequest()
+			req.ModelParams = json.RawMessage(fmt.Sprintf(`{"convergence_rate":%g}`, rate))
+			req.Seed = seed
+			r, err := NewHELIOPASSSimulator().Simulate(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.ModelParams.ConvergenceRate != rate {
+				t.Fatalf("rate %g: ran with %+v", rate, r.ModelParams)
+			}
+			total += r.Iterations
+		}
+		return total
+	}
+	slow, baseline, fast := iterations(0.2), iterations(0.8), iterations(2)
+	if !(slow > baseline && baseline > fast) {
+		t.Errorf("iterations over 8 seeds at rates 0.2/0.8/2 = %d/%d/%d, want strictly decreasing", slow, baseline, fast)
+	}
+}
+
+func TestModelParamsValidation(t *testing.T) {
+	for _, overrides := range []string{
+		`{"convergence_rate":0}`,
+		`{"max_iterations":100000}`,
+		`{"eye_margin_target_ui":2}`,
+		`{"convergence_rte":0.5}`,
+	} {
+		req := baselineRequest()
+		req.ModelParams = json.RawMessage(overrides)
+		if _, err := NewHELIOPASSSimulator().Simulate(req); err == nil {
+			t.Errorf("%s: accepted", overrides)
+		}
+	}
+}
+
+func TestModelParamsEndpoint(t *testing.T) {
+	params := DefaultModelParams()
+	params.ConvergenceRate = 1.1
+	h := NewHELIOPASSSimulatorWithParams(params)
+	rec := httptest.NewRecorder()
+	h.handleGetModelParams(rec, httptest.NewRequest(http.MethodGet, "/v1/helio-sim/model-params", nil))
+	var got ModelParams
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got != params {
+		t.Errorf("model-params = %+v, want %+v", got, params)
+	}
+}
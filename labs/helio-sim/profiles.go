@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/jsonbody"
+	"github.com/gorilla/mux"
+)
+
+// profileNamePattern is the form of the names profiles are registered
+// under, as the built-in ones are
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// stabilityClasses are the stability classes a profile may declare
+var stabilityClasses = map[string]bool{"excellent": true, "good": true, "fair": true, "poor": true}
+
+// Errors of RegisterProfile and DeleteProfile
+var (
+	errProfileExists  = errors.New("ambient profile already exists")
+	errProfileBuiltin = errors.New("built-in ambient profiles cannot be deleted")
+	errProfileUnknown = errors.New("ambient profile not found")
+)
+
+// ProfileRegistration registers an ambient profile under a name that
+// simulation requests then give as their ambient_profile
+type ProfileRegistration struct {
+	Name    string         `json:"name"`
+	Profile AmbientProfile `json:"profile"`
+}
+
+// Validate checks the profile's conditions are within what the model
+// simulates sensibly
+func (p AmbientProfile) Validate() error {
+	switch {
+	case p.Temperature < -100 || p.Temperature > 150:
+		return fmt.Errorf("temperature_c must be in [-100, 150]")
+	case p.Humidity < 0 || p.Humidity > 100:
+		return fmt.Errorf("humidity_percent must be in [0, 100]")
+	case p.VibrationRMS < 0 || p.VibrationRMS > 100:
+		return fmt.Errorf("vibration_rms_um must be in [0, 100]")
+	case p.EMINoise < -150 || p.EMINoise > 0:
+		return fmt.Errorf("emi_noise_db must be in [-150, 0]")
+	case p.DriftRate < 0 || p.DriftRate > 10:
+		return fmt.Errorf("drift_rate_nm_per_hour must be in [0, 10]")
+	case p.NoiseLevel < 0 || p.NoiseLevel > 1:
+		return fmt.Errorf("noise_level must be in [0, 1]")
+	case !stabilityClasses[p.StabilityClass]:
+		return fmt.Errorf("stability_class must be excellent, good, fair or poor")
+	}
+	return nil
+}
+
+// ambientProfile returns the profile registered under name
+func (h *HELIOPASSSimulator) ambientProfile(name string) (AmbientProfile, bool) {
+	h.profilesMu.RLock()
+	defer h.profilesMu.RUnlock()
+	profile, ok := h.profiles[name]
+	return profile, ok
+}
+
+// RegisterProfile adds an ambient profile under name, which must not be
+// taken. A profile without a display name is given name.
+func (h *HELIOPASSSimulator) RegisterProfile(name string, profile AmbientProfile) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("profile name must be 1-64 lowercase letters, digits, '_' or '-'")
+	}
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	if profile.Name == "" {
+		profile.Name = name
+	}
+
+	h.profilesMu.Lock()
+	defer h.profilesMu.Unlock()
+	if _, exists := h.profiles[name]; exists {
+		return errProfileExists
+	}
+	h.profiles[name] = profile
+	return nil
+}
+
+// DeleteProfile removes a registered ambient profile. Stored results keep
+// the profile they ran with.
+func (h *HELIOPASSSimulator) DeleteProfile(name string) error {
+	if _, builtin := defaultAmbientProfiles()[name]; builtin {
+		return errProfileBuiltin
+	}
+	h.profilesMu.Lock()
+	defer h.profilesMu.Unlock()
+	if _, exists := h.profiles[name]; !exists {
+		return errProfileUnknown
+	}
+	delete(h.profiles, name)
+	return nil
+}
+
+func (h *HELIOPASSSimulator) handleRegisterProfile(w http.ResponseWriter, r *http.Request) {
+	var req ProfileRegistration
+	if err := jsonbody.Decode(r.Body, &req, jsonbody.Strict); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+	if err := h.RegisterProfile(req.Name, req.Profile); errors.Is(err, errProfileExists) {
+		apierr.Respond(w, apierr.CodeConflict, fmt.Sprintf("ambient profile %s already exists", req.Name))
+		return
+	} else if err != nil {
+		apierr.Respond(w, apierr.CodeValidation, err.Error())
+		return
+	}
+
+	profile, _ := h.ambientProfile(req.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(profile)
+}
+
+func (h *HELIOPASSSimulator) handleDeleteProfile(w http.ResponseWriter, r *http.Request) {
+	switch err := h.DeleteProfile(mux.Vars(r)["name"]); {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, errProfileBuiltin):
+		apierr.Respond(w, apierr.CodeForbidden, err.Error())
+	default:
+		apierr.Respond(w, apierr.CodeNotFound, err.Error())
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+const datacenterProfile = `{"name": "datacenter_row", "profile": {
+	"temperature_c": 27, "humidity_percent": 40, "vibration_rms_um": 0.5,
+	"emi_noise_db": -70, "drift_rate_nm_per_hour": 0.01,
+	"stability_class": "good", "noise_level": 0.08}}`
+
+func TestRegisteredProfileSimulatesUntilDeleted(t *testing.T) {
+	h := newSeededSimulator(1)
+	rec := httptest.NewRecorder()
+	h.handleRegisterProfile(rec, httptest.NewRequest(http.MethodPost, "/v1/helio-sim/profiles", strings.NewReader(datacenterProfile)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: status %d: %s", rec.Code, rec.Body)
+	}
+	if got := h.GetAmbientProfiles()["datacenter_row"]; got.Name != "datacenter_row" || got.Temperature != 27 {
+		t.Errorf("registered profile = %+v", got)
+	}
+	if _, err := h.Simulate(SimulationRequest{TargetBER: 1e-12, AmbientProfile: "datacenter_row"}); err != nil {
+		t.Fatalf("simulate with the registered profile: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.handleRegisterProfile(rec, httptest.NewRequest(http.MethodPost, "/v1/helio-sim/profiles", strings.NewReader(datacenterProfile)))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("second registration: status %d, want 409", rec.Code)
+	}
+
+	for name, want := range map[string]int{"datacenter_row": http.StatusNoContent, "lab_default": http.StatusForbidden, "nowhere": http.StatusNotFound} {
+		rec = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/v1/helio-sim/profiles/"+name, nil)
+		h.handleDeleteProfile(rec, mux.SetURLVars(req, map[string]string{"name": name}))
+		if rec.Code != want {
+			t.Errorf("delete %s: status %d, want %d", name, rec.Code, want)
+		}
+	}
+	if _, err := h.Simulate(SimulationRequest{TargetBER: 1e-12, AmbientProfile: "datacenter_row"}); err == nil {
+		t.Error("a deleted profile still simulates")
+	}
+	if _, ok := h.ambientProfile("lab_default"); !ok {
+		t.Error("built-in profile lost")
+	}
+}
+
+func TestProfileRegistrationIsValidated(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	good := AmbientProfile{Temperature: 20, Humidity: 50, EMINoise: -80, StabilityClass: "fair"}
+	if err := h.RegisterProfile("Bad Name", good); err == nil {
+		t.Error("a name with spaces and capitals was registered")
+	}
+	for field, profile := range map[string]AmbientProfile{
+		"temperature_c":    {Temperature: 200, EMINoise: -80, StabilityClass: "fair"},
+		"humidity_percent": {Humidity: 120, EMINoise: -80, StabilityClass: "fair"},
+		"emi_noise_db":     {EMINoise: 10, StabilityClass: "fair"},
+		"noise_level":      {EMINoise: -80, NoiseLevel: 2, StabilityClass: "fair"},
+		"stability_class":  {EMINoise: -80, StabilityClass: "stormy"},
+	} {
+		if err := h.RegisterProfile("bad", profile); err == nil || !strings.Contains(err.Error(), field) {
+			t.Errorf("%s: err = %v", field, err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.handleRegisterProfile(rec, httptest.NewRequest(http.MethodPost, "/v1/helio-sim/profiles", strings.NewReader(`{"name": "hot", "profile": {"temperature_c": 500}}`)))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "temperature_c") {
+		t.Errorf("out-of-range profile: status %d: %s", rec.Code, rec.Body)
+	}
+}
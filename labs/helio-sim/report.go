@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/gorilla/mux"
+)
+
+// Report formats, chosen by the Accept header or a format query parameter
+const (
+	reportMarkdown = "text/markdown"
+	reportHTML     = "text/html"
+	reportJSON     = "application/json"
+)
+
+var reportFormats = map[string]string{
+	"markdown": reportMarkdown,
+	"md":       reportMarkdown,
+	"html":     reportHTML,
+	"json":     reportJSON,
+}
+
+// LambdaRow is one lambda channel's final calibration state
+type LambdaRow struct {
+	Channel          int
+	BiasVoltage      float64
+	LambdaShiftNm    float64
+	LaserPowerAdjust float64
+}
+
+// calibrationReport is the view the report templates render
+type calibrationReport struct {
+	StoredResult
+	Lambdas []LambdaRow
+}
+
+func newCalibrationReport(res StoredResult) calibrationReport {
+	rows := make([]LambdaRow, len(res.Result.BiasVoltages))
+	for i := range rows {
+		rows[i] = LambdaRow{
+			Channel:          i,
+			BiasVoltage:      res.Result.BiasVoltages[i],
+			LambdaShiftNm:    res.Result.LambdaShifts[i],
+			LaserPowerAdjust: res.Result.LaserPowerAdjust[i],
+		}
+	}
+	return calibrationReport{StoredResult: res, Lambdas: rows}
+}
+
+var reportFuncs = map[string]any{
+	"corridor": func(id string) string {
+		if id == "" {
+			return "(none)"
+		}
+		return id
+	},
+	"join": strings.Join,
+}
+
+var markdownReport = template.Must(template.New("report").Funcs(reportFuncs).Parse(`# HELIOPASS Calibration Report
+
+- **Result:** {{.ID}}
+- **Corridor:** {{corridor .Request.CorridorID}}
+- **Generated from run at:** {{.CreatedAt.Format "2006-01-02 15:04:05 MST"}}
+
+## Inputs
+
+| Parameter | Value |
+|---|---|
+| Target BER | {{printf "%.3g" .Request.TargetBER}} |
+| Lambda channels | {{.Request.LambdaCount}} |
+| Initial BER | {{printf "%.3g" .Request.InitialBER}} |
+| Initial eye margin | {{printf "%.3f" .Request.InitialEyeMargin}} UI |
+| Duration | {{.Request.Duration}} s |
+| Seed | {{.Request.Seed}} |
+| Start | {{if .Result.WarmStart}}warm (from last converged calibration){{else}}cold{{end}} |
+| Bias control | {{.Result.BiasControl.Mode}}{{if eq .Result.BiasControl.Mode "pid"}} (Kp {{.Result.BiasControl.Kp}}, Ki {{.Result.BiasControl.Ki}}, Kd {{.Result.BiasControl.Kd}}){{end}} |
+| Bias damping | {{printf "%.2f" .Result.BiasControl.Damping}} |
+| Bias step limit | {{printf "%.2f" .Result.BiasControl.StepLimitMv}} mV |
+| Convergence BER tolerance | {{printf "%.2f" .Result.ConvergenceCriteria.BERTolerance}} × target |
+| Minimum eye margin | {{printf "%.3f" .Result.ConvergenceCriteria.MinEyeMargin}} UI |
+{{- range .Result.Events}}
+| Event at {{printf "%.1f" .Time}} s | {{.Kind}}, magnitude {{printf "%.2f" .Magnitude}}{{if not .Applied}} (not reached){{end}} |
+{{- end}}
+
+## Ambient Profile
+
+| Property | Value |
+|---|---|
+| Profile | {{.Profile.Name}} ({{.Request.AmbientProfile}}) |
+| Temperature | {{printf "%.1f" .Profile.Temperature}} °C |
+| Humidity | {{printf "%.1f" .Profile.Humidity}} % |
+| Vibration | {{printf "%.2f" .Profile.VibrationRMS}} µm RMS |
+| EMI noise | {{printf "%.1f" .Profile.EMINoise}} dB |
+| Drift rate | {{printf "%.4f" .Profile.DriftRate}} nm/h |
+| Stability class | {{.Profile.StabilityClass}} |
+
+## Convergence
+
+**Status:** {{.Result.Status}} after {{.Result.Iterations}} iterations ({{printf "%.1f" .Result.ConvergenceTime}} s)
+{{- with .Result.EarlyStop}}
+
+Stopped early at {{printf "%.1f" .StoppedAt}} s: predicted {{if .PredictedConverged}}to converge{{else}}not to converge{{end}}, final BER {{printf "%.3g" .PredictedFinalBER}}, confidence {{printf "%.3f" .Confidence}}
+{{- end}}
+{{- with .Result.Diagnostics}}
+
+Failed {{join .FailedCriteria ", "}}{{with .StalledAtIteration}}; progress stalled after iteration {{.}}{{end}}. Suggestions:
+{{range .Suggestions}}
+- {{.}}
+{{- end}}
+{{- end}}
+{{- with .Result.Attempts}}
+
+Result of the {{$.Result.EffectiveAttempt}} attempt:
+
+| Attempt | Result | Profile | Target BER | Status | Final BER |
+|---|---|---|---|---|---|
+{{- range .}}
+| {{.Attempt}} | {{.ID}} | {{.AmbientProfile}} | {{printf "%.3g" .TargetBER}} | {{.Status}} | {{printf "%.3g" .FinalBER}} |
+{{- end}}
+{{- end}}
+
+| Metric | Final |
+|---|---|
+| BER | {{printf "%.3g" .Result.FinalBER}} |
+| Eye margin | {{printf "%.3f" .Result.FinalEyeMargin}} UI |
+| Power savings | {{printf "%.2f" .Result.PowerSavings}} % |
+{{- if .Result.ConvergenceTimeConstant}}
+| BER time constant | {{printf "%.3f" .Result.ConvergenceTimeConstant}} s |
+{{- end}}
+
+## Lambda Channels
+
+| Channel | Bias (V) | Lambda shift (nm) | Laser adjust (dB) |
+|---|---|---|---|
+{{- range .Lambdas}}
+| λ{{.Channel}} | {{printf "%.4f" .BiasVoltage}} | {{printf "%+.4f" .LambdaShiftNm}} | {{printf "%+.3f" .LaserPowerAdjust}} |
+{{- end}}
+
+## Power Savings
+
+{{with .Result.PowerSavingsDetail -}}
+| Contribution | Inputs | Percent |
+|---|---|---|
+| Bias voltage | mean {{printf "%.4f" .MeanBiasVoltage}} V vs {{printf "%.2f" .ReferenceBiasVoltage}} V reference, {{printf "%.1f" .PercentPerVolt}} %/V | {{printf "%+.2f" .BiasVoltageContribution}} |
+| Laser power | mean {{printf "%.3f" .MeanLaserReductionDb}} dB reduction, {{printf "%.1f" .PercentPerDbReduction}} %/dB | {{printf "%+.2f" .LaserPowerContribution}} |
+| Cap adjustment | clamped to [0, {{printf "%.0f" .CapPercent}}] % | {{printf "%+.2f" .CapAdjustment}} |
+| **Total** | | **{{printf "%.2f" .Total}}** |
+{{- end}}
+`))
+
+var htmlReport = htmltemplate.Must(htmltemplate.New("report").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>HELIOPASS Calibration Report {{.ID}}</title>
+</head>
+<body>
+<h1>HELIOPASS Calibration Report</h1>
+<ul>
+<li><strong>Result:</strong> {{.ID}}</li>
+<li><strong>Corridor:</strong> {{corridor .Request.CorridorID}}</li>
+<li><strong>Generated from run at:</strong> {{.CreatedAt.Format "2006-01-02 15:04:05 MST"}}</li>
+</ul>
+
+<h2>Inputs</h2>
+<table>
+<tr><th>Parameter</th><th>Value</th></tr>
+<tr><td>Target BER</td><td>{{printf "%.3g" .Request.TargetBER}}</td></tr>
+<tr><td>Lambda channels</td><td>{{.Request.LambdaCount}}</td></tr>
+<tr><td>Initial BER</td><td>{{printf "%.3g" .Request.InitialBER}}</td></tr>
+<tr><td>Initial eye margin</td><td>{{printf "%.3f" .Request.InitialEyeMargin}} UI</td></tr>
+<tr><td>Duration</td><td>{{.Request.Duration}} s</td></tr>
+<tr><td>Seed</td><td>{{.Request.Seed}}</td></tr>
+<tr><td>Start</td><td>{{if .Result.WarmStart}}warm (from last converged calibration){{else}}cold{{end}}</td></tr>
+<tr><td>Bias control</td><td>{{.Result.BiasControl.Mode}}{{if eq .Result.BiasControl.Mode "pid"}} (Kp {{.Result.BiasControl.Kp}}, Ki {{.Result.BiasControl.Ki}}, Kd {{.Result.BiasControl.Kd}}){{end}}</td></tr>
+<tr><td>Bias damping</td><td>{{printf "%.2f" .Result.BiasControl.Damping}}</td></tr>
+<tr><td>Bias step limit</td><td>{{printf "%.2f" .Result.BiasControl.StepLimitMv}} mV</td></tr>
+<tr><td>Convergence BER tolerance</td><td>{{printf "%.2f" .Result.ConvergenceCriteria.BERTolerance}} × target</td></tr>
+<tr><td>Minimum eye margin</td><td>{{printf "%.3f" .Result.ConvergenceCriteria.MinEyeMargin}} UI</td></tr>
+{{- range .Result.Events}}
+<tr><td>Event at {{printf "%.1f" .Time}} s</td><td>{{.Kind}}, magnitude {{printf "%.2f" .Magnitude}}{{if not .Applied}} (not reached){{end}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Ambient Profile</h2>
+<table>
+<tr><th>Property</th><th>Value</th></tr>
+<tr><td>Profile</td><td>{{.Profile.Name}} ({{.Request.AmbientProfile}})</td></tr>
+<tr><td>Temperature</td><td>{{printf "%.1f" .Profile.Temperature}} °C</td></tr>
+<tr><td>Humidity</td><td>{{printf "%.1f" .Profile.Humidity}} %</td></tr>
+<tr><td>Vibration</td><td>{{printf "%.2f" .Profile.VibrationRMS}} µm RMS</td></tr>
+<tr><td>EMI noise</td><td>{{printf "%.1f" .Profile.EMINoise}} dB</td></tr>
+<tr><td>Drift rate</td><td>{{printf "%.4f" .Profile.DriftRate}} nm/h</td></tr>
+<tr><td>Stability class</td><td>{{.Profile.StabilityClass}}</td></tr>
+</table>
+
+<h2>Convergence</h2>
+<p><strong>Status:</strong> {{.Result.Status}} after {{.Result.Iterations}} iterations ({{printf "%.1f" .Result.ConvergenceTime}} s)</p>
+{{- with .Result.EarlyStop}}
+<p>Stopped early at {{printf "%.1f" .StoppedAt}} s: predicted {{if .PredictedConverged}}to converge{{else}}not to converge{{end}}, final BER {{printf "%.3g" .PredictedFinalBER}}, confidence {{printf "%.3f" .Confidence}}</p>
+{{- end}}
+{{- with .Result.Diagnostics}}
+<p>Failed {{join .FailedCriteria ", "}}{{with .StalledAtIteration}}; progress stalled after iteration {{.}}{{end}}. Suggestions:</p>
+<ul>
+{{- range .Suggestions}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- with .Result.Attempts}}
+<p>Result of the {{$.Result.EffectiveAttempt}} attempt:</p>
+<table>
+<tr><th>Attempt</th><th>Result</th><th>Profile</th><th>Target BER</th><th>Status</th><th>Final BER</th></tr>
+{{- range .}}
+<tr><td>{{.Attempt}}</td><td>{{.ID}}</td><td>{{.AmbientProfile}}</td><td>{{printf "%.3g" .TargetBER}}</td><td>{{.Status}}</td><td>{{printf "%.3g" .FinalBER}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<table>
+<tr><th>Metric</th><th>Final</th></tr>
+<tr><td>BER</td><td>{{printf "%.3g" .Result.FinalBER}}</td></tr>
+<tr><td>Eye margin</td><td>{{printf "%.3f" .Result.FinalEyeMargin}} UI</td></tr>
+<tr><td>Power savings</td><td>{{printf "%.2f" .Result.PowerSavings}} %</td></tr>
+{{- if .Result.ConvergenceTimeConstant}}
+<tr><td>BER time constant</td><td>{{printf "%.3f" .Result.ConvergenceTimeConstant}} s</td></tr>
+{{- end}}
+</table>
+
+<h2>Lambda Channels</h2>
+<table>
+<tr><th>Channel</th><th>Bias (V)</th><th>Lambda shift (nm)</th><th>Laser adjust (dB)</th></tr>
+{{- range .Lambdas}}
+<tr><td>λ{{.Channel}}</td><td>{{printf "%.4f" .BiasVoltage}}</td><td>{{printf "%+.4f" .LambdaShiftNm}}</td><td>{{printf "%+.3f" .LaserPowerAdjust}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Power Savings</h2>
+{{with .Result.PowerSavingsDetail -}}
+<table>
+<tr><th>Contribution</th><th>Inputs</th><th>Percent</th></tr>
+<tr><td>Bias voltage</td><td>mean {{printf "%.4f" .MeanBiasVoltage}} V vs {{printf "%.2f" .ReferenceBiasVoltage}} V reference, {{printf "%.1f" .PercentPerVolt}} %/V</td><td>{{printf "%+.2f" .BiasVoltageContribution}}</td></tr>
+<tr><td>Laser power</td><td>mean {{printf "%.3f" .MeanLaserReductionDb}} dB reduction, {{printf "%.1f" .PercentPerDbReduction}} %/dB</td><td>{{printf "%+.2f" .LaserPowerContribution}}</td></tr>
+<tr><td>Cap adjustment</td><td>clamped to [0, {{printf "%.0f" .CapPercent}}] %</td><td>{{printf "%+.2f" .CapAdjustment}}</td></tr>
+<tr><th>Total</th><td></td><th>{{printf "%.2f" .Total}}</th></tr>
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// negotiateReport picks the report format: an explicit format parameter,
+// else the first Accept entry naming a supported format, else Markdown
+func negotiateReport(r *http.Request) (string, error) {
+	if f := r.URL.Query().Get("format"); f != "" {
+		format, ok := reportFormats[strings.ToLower(f)]
+		if !ok {
+			return "", fmt.Errorf("unsupported report format: %s (markdown|html|json)", f)
+		}
+		return format, nil
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case reportMarkdown, reportHTML, reportJSON:
+			return mediaType, nil
+		}
+	}
+	return reportMarkdown, nil
+}
+
+// WriteReport renders the report of a stored result in the given format
+func WriteReport(w io.Writer, res StoredResult, format string) error {
+	switch format {
+	case reportHTML:
+		return htmlReport.Execute(w, newCalibrationReport(res))
+	case reportJSON:
+		return json.NewEncoder(w).Encode(res)
+	default:
+		return markdownReport.Execute(w, newCalibrationReport(res))
+	}
+}
+
+func (h *HELIOPASSSimulator) handleGetReport(w http.ResponseWriter, r *http.Request) {
+	res, ok := h.Result(mux.Vars(r)["id"])
+	if !ok {
+		apierr.Respond(w, apierr.CodeNotFound, "simulation result not found")
+		return
+	}
+	format, err := negotiateReport(r)
+	if err != nil {
+		apierr.Respond(w, apierr.CodeValidation, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", format+"; charset=utf-8")
+	w.Header().Set("Vary", "Accept")
+	WriteReport(w, res, format)
+}
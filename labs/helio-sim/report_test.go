@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// reportRouter serves the stored-result routes of h
+func reportRouter(h *HELIOPASSSimulator) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/helio-sim/results/{id}", h.handleGetResult)
+	router.HandleFunc("/v1/helio-sim/results/{id}/report", h.handleGetReport)
+	return router
+}
+
+func getReport(t *testing.T, router *mux.Router, path, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReportCoversResult(t *testing.T) {
+	h := newSeededSimulator(5)
+	resp, err := h.Simulate(SimulationRequest{CorridorID: "cor-report", TargetBER: 1e-12, AmbientProfile: "lab_default", LambdaCount: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Converged {
+		t.Fatalf("seeded run did not converge: %+v", resp)
+	}
+	router := reportRouter(h)
+	path := "/v1/helio-sim/results/" + resp.ID + "/report"
+
+	for accept, format := range map[string]struct{ contentType, status, row string }{
+		"":                           {"text/markdown", "**Status:** converged", "| λ%d |"},
+		"text/html, */*;q=0.8":       {"text/html", "<strong>Status:</strong> converged", "<td>λ%d</td>"},
+		"text/markdown":              {"text/markdown", "**Status:** converged", "| λ%d |"},
+		"application/xml, text/html": {"text/html", "<strong>Status:</strong> converged", "<td>λ%d</td>"},
+	} {
+		rec := getReport(t, router, path, accept)
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), format.contentType) {
+			t.Fatalf("Accept %q: %d %s", accept, rec.Code, rec.Header().Get("Content-Type"))
+		}
+		body := rec.Body.String()
+		for _, want := range []string{"cor-report", resp.ID, format.status} {
+			if !strings.Contains(body, want) {
+				t.Errorf("Accept %q: report lacks %q", accept, want)
+			}
+		}
+		for ch := 0; ch < 6; ch++ {
+			if row := fmt.Sprintf(format.row, ch); strings.Count(body, row) != 1 {
+				t.Errorf("Accept %q: %d rows %q", accept, strings.Count(body, row), row)
+			}
+		}
+		if row := fmt.Sprintf(format.row, 6); strings.Contains(body, row) {
+			t.Errorf("Accept %q: report has a seventh lambda row", accept)
+		}
+	}
+}
+
+func TestReportJSONAndErrors(t *testing.T) {
+	h := newSeededSimulator(5)
+	resp, err := h.Simulate(SimulationRequest{CorridorID: "cor-report", TargetBER: 1e-12, AmbientProfile: "lab_default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := reportRouter(h)
+
+	rec := getReport(t, router, "/v1/helio-sim/results/"+resp.ID+"/report?format=json", "text/html")
+	var stored StoredResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &stored); err != nil {
+		t.Fatalf("json report: %v: %s", err, rec.Body)
+	}
+	if stored.ID != resp.ID || stored.Result.Status != resp.Status || stored.Request.CorridorID != "cor-report" {
+		t.Errorf("json report = %+v", stored)
+	}
+
+	if rec := getReport(t, router, "/v1/helio-sim/results/"+resp.ID+"/report?format=pdf", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("unsupported format: status %d", rec.Code)
+	}
+	if rec := getReport(t, router, "/v1/helio-sim/results/sim-missing/report", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown result: status %d", rec.Code)
+	}
+}
+
+func TestHTMLReportEscapesInputs(t *testing.T) {
+	h := newSeededSimulator(5)
+	resp, err := h.Simulate(SimulationRequest{CorridorID: "<script>x</script>", TargetBER: 1e-12, AmbientProfile: "lab_default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := getReport(t, reportRouter(h), "/v1/helio-sim/results/"+resp.ID+"/report?format=html", "")
+	if strings.Contains(rec.Body.String(), "<script>") {
+		t.Error("HTML report renders the corridor ID unescaped")
+	}
+}
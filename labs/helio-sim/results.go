@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/bounded"
+	"github.com/gorilla/mux"
+)
+
+// maxStoredResults is the default bound on the simulation results kept
+// for retrieval; the least recently read or written is dropped first
+const maxStoredResults = 256
+
+// StoredResult is a finished simulation kept for later retrieval: the
+// inputs after defaults were applied, the ambient profile they named, and
+// the outcome
+type StoredResult struct {
+	ID        string             `json:"id"`
+	CreatedAt time.Time          `json:"created_at"`
+	Request   SimulationRequest  `json:"request"`
+	Profile   AmbientProfile     `json:"ambient_profile"`
+	Result    SimulationResponse `json:"result"`
+}
+
+// newResultID returns a random simulation result ID
+func newResultID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "sim-" + hex.EncodeToString(b)
+}
+
+// newResultStore creates the store of simulation results, logging each
+// result it evicts
+func newResultStore(maxEntries int) *bounded.BoundedStore[StoredResult] {
+	store := bounded.New[StoredResult](maxEntries)
+	store.OnEvict = func(id string, _ StoredResult, reason string) {
+		log.Printf("evicted simulation result %s (%s)", id, reason)
+	}
+	return store
+}
+
+// storeResult keeps a simulation result, evicting the least recently used
+// beyond the store's cap
+func (h *HELIOPASSSimulator) storeResult(res StoredResult) {
+	h.results.Set(res.ID, res)
+}
+
+// Result returns a stored simulation result
+func (h *HELIOPASSSimulator) Result(id string) (StoredResult, bool) {
+	return h.results.Get(id)
+}
+
+func (h *HELIOPASSSimulator) handleGetResult(w http.ResponseWriter, r *http.Request) {
+	res, ok := h.Result(mux.Vars(r)["id"])
+	if !ok {
+		apierr.Respond(w, apierr.CodeNotFound, "simulation result not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSavingsBreakdownSumsToTotal(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	for _, tc := range []struct {
+		bias, laser []float64
+	}{
+		{[]float64{1.1, 1.15}, []float64{-0.2, 0.1}},
+		{[]float64{1.3, 1.4}, []float64{0.1, 0.2}}, // negative raw, clamped to 0
+		{[]float64{0.5, 0.6}, []float64{-2, -3}},   // above the cap
+	} {
+		b := h.calculatePowerSavings(tc.bias, tc.laser, h.Params)
+		sum := b.BiasVoltageContribution + b.LaserPowerContribution + b.CapAdjustment
+		if math.Abs(sum-b.Total) > 1e-12 {
+			t.Errorf("%+v: contributions sum to %g, total %g", tc, sum, b.Total)
+		}
+		if b.Total < 0 || b.Total > b.CapPercent {
+			t.Errorf("%+v: total %g outside [0, %g]", tc, b.Total, b.CapPercent)
+		}
+	}
+
+	resp, err := h.Simulate(SimulationRequest{TargetBER: 1e-12, AmbientProfile: "lab_default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.PowerSavings != resp.PowerSavingsDetail.Total {
+		t.Errorf("power_savings %g != breakdown total %g", resp.PowerSavings, resp.PowerSavingsDetail.Total)
+	}
+}
+
+func TestNoLaserReductionContributesNothing(t *testing.T) {
+	b := NewHELIOPASSSimulator().calculatePowerSavings([]float64{1.1, 1.1}, []float64{0, 0.3}, DefaultModelParams())
+	if b.MeanLaserReductionDb != 0 || b.LaserPowerContribution != 0 {
+		t.Errorf("laser contribution = %g%% from %g dB", b.LaserPowerContribution, b.MeanLaserReductionDb)
+	}
+	if math.Abs(b.BiasVoltageContribution-1) > 1e-9 {
+		t.Errorf("bias contribution = %g%%, want 1%%", b.BiasVoltageContribution)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+// Server-sent event kinds of a streamed simulation
+const (
+	streamEventIteration = "iteration"
+	streamEventDone      = "done"
+	streamEventError     = "error"
+)
+
+// SimulationProgress is one iteration of a run, reported as it is computed
+type SimulationProgress struct {
+	// Attempt is primary or fallback for a request with a fallback
+	Attempt     string           `json:"attempt,omitempty"`
+	Iteration   int              `json:"iteration"`
+	Temperature TemperaturePoint `json:"temperature"`
+	BER         BERPoint         `json:"ber"`        // of the worst channel
+	LambdaBER   []float64        `json:"lambda_ber"` // per channel, in lane order
+	EyeMargin   EyeMarginPoint   `json:"eye_margin"`
+}
+
+// progressOf labels the progress of one attempt of a request with a fallback
+func progressOf(attempt string, progress func(SimulationProgress)) func(SimulationProgress) {
+	if progress == nil {
+		return nil
+	}
+	return func(p SimulationProgress) {
+		p.Attempt = attempt
+		progress(p)
+	}
+}
+
+// streamSimulation runs a simulation, streaming each iteration as an
+// iteration server-sent event and ending with a done event carrying the
+// SimulationResponse. The run stops, unstored, once the client goes away. A
+// request rejected before its first iteration gets an ordinary error
+// response; one failing later, an error event. Signed responses are
+// buffered whole, so with -sign-responses the events arrive together at
+// the end.
+func (h *HELIOPASSSimulator) streamSimulation(w http.ResponseWriter, r *http.Request, req SimulationRequest) {
+	rc := http.NewResponseController(w)
+	started := false
+	send := func(kind string, v any) {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		writeStreamEvent(w, kind, v)
+		_ = rc.Flush()
+	}
+
+	ctx := r.Context()
+	sent := 0
+	response, err := h.SimulateWithProgress(ctx, req, func(p SimulationProgress) {
+		send(streamEventIteration, p)
+		sent++
+	})
+	if ctx.Err() != nil {
+		log.Printf("simulation stream cancelled after %d iterations: %v", sent, ctx.Err())
+		return
+	}
+	if err != nil {
+		if !started {
+			apierr.Respond(w, apierr.CodeValidation, err.Error())
+			return
+		}
+		send(streamEventError, apierr.New(apierr.CodeValidation, "%s", err.Error()))
+		return
+	}
+	send(streamEventDone, response)
+}
+
+func writeStreamEvent(w http.ResponseWriter, kind string, v any) {
+	data, _ := json.Marshal(v)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", kind, data)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type streamEvent struct {
+	kind string
+	data string
+}
+
+// parseEvents splits a server-sent event stream into its events
+func parseEvents(t *testing.T, body string) []streamEvent {
+	t.Helper()
+	var events []streamEvent
+	for _, block := range strings.Split(strings.TrimSuffix(body, "\n\n"), "\n\n") {
+		kind, data, ok := strings.Cut(block, "\n")
+		if !ok || !strings.HasPrefix(kind, "event: ") || !strings.HasPrefix(data, "data: ") {
+			t.Fatalf("malformed event %q", block)
+		}
+		events = append(events, streamEvent{strings.TrimPrefix(kind, "event: "), strings.TrimPrefix(data, "data: ")})
+	}
+	return events
+}
+
+func streamRequest(ctx context.Context, query, body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/v1/helio-sim/simulate"+query, strings.NewReader(body)).WithContext(ctx)
+}
+
+func TestStreamEmitsEachIterationThenDone(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	rec := httptest.NewRecorder()
+	h.handleSimulate(rec, streamRequest(context.Background(), "?stream=1", `{"target_ber": 1e-12, "ambient_profile": "field_noise_low", "lambda_count": 4, "cold_start": true, "seed": 9}`))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+
+	events := parseEvents(t, rec.Body.String())
+	last := events[len(events)-1]
+	if last.kind != streamEventDone {
+		t.Fatalf("last event is %s, want done", last.kind)
+	}
+	var done SimulationResponse
+	if err := json.Unmarshal([]byte(last.data), &done); err != nil {
+		t.Fatal(err)
+	}
+	if len(events)-1 != done.Iterations {
+		t.Fatalf("%d iteration events for %d iterations", len(events)-1, done.Iterations)
+	}
+	for i, e := range events[:len(events)-1] {
+		var p SimulationProgress
+		if e.kind != streamEventIteration || json.Unmarshal([]byte(e.data), &p) != nil {
+			t.Fatalf("event %d = %+v", i, e)
+		}
+		if p.Iteration != i || p.BER != done.BERProfile[i] || p.EyeMargin != done.EyeMarginProfile[i] || p.Temperature != done.TemperatureProfile[i] {
+			t.Errorf("iteration event %d = %+v, not the run's points", i, p)
+		}
+		if len(p.LambdaBER) != len(done.PerLambdaBER) {
+			t.Fatalf("iteration event %d has %d channels, the run %d", i, len(p.LambdaBER), len(done.PerLambdaBER))
+		}
+		for lane, ber := range p.LambdaBER {
+			if ber != done.PerLambdaBER[lane][i].BER {
+				t.Errorf("iteration event %d: lambda %d BER %g, the run's %g", i, lane, ber, done.PerLambdaBER[lane][i].BER)
+			}
+		}
+	}
+
+	// The streamed run is the run Simulate gives for the same seed
+	plain, err := NewHELIOPASSSimulator().Simulate(SimulationRequest{TargetBER: 1e-12, AmbientProfile: "field_noise_low", LambdaCount: 4, ColdStart: true, Seed: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.FinalBER != plain.FinalBER || done.Iterations != plain.Iterations {
+		t.Errorf("streamed run ended at BER %g after %d iterations, Simulate at %g after %d", done.FinalBER, done.Iterations, plain.FinalBER, plain.Iterations)
+	}
+}
+
+func TestStreamLabelsFallbackAttempts(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHELIOPASSSimulator().handleSimulate(rec, streamRequest(context.Background(), "?stream=true",
+		`{"target_ber": 1e-18, "ambient_profile": "field_noise_high", "cold_start": true, "seed": 3, "fallback": {"target_ber": 1e-12, "ambient_profile": "space_sim"}}`))
+	attempt := attemptPrimary
+	for _, e := range parseEvents(t, rec.Body.String()) {
+		if e.kind != streamEventIteration {
+			continue
+		}
+		var p SimulationProgress
+		json.Unmarshal([]byte(e.data), &p)
+		if p.Attempt == attemptFallback {
+			attempt = attemptFallback
+		}
+		if p.Attempt != attempt {
+			t.Fatalf("iteration of attempt %q after the %s began", p.Attempt, attempt)
+		}
+	}
+	if attempt != attemptFallback {
+		t.Error("no iterations of the fallback were streamed")
+	}
+}
+
+// cancellingWriter cancels the request's context once n events are written,
+// as a client going away mid-stream does
+type cancellingWriter struct {
+	*httptest.ResponseRecorder
+	n      int
+	cancel context.CancelFunc
+}
+
+func (w *cancellingWriter) Write(b []byte) (int, error) {
+	if w.n--; w.n == 0 {
+		w.cancel()
+	}
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestStreamStopsWhenClientGoesAway(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &cancellingWriter{ResponseRecorder: httptest.NewRecorder(), n: 3, cancel: cancel}
+	h.handleSimulate(w, streamRequest(ctx, "?stream=1", `{"target_ber": 1e-12, "ambient_profile": "lab_default", "cold_start": true, "seed": 1}`))
+
+	events := parseEvents(t, w.Body.String())
+	if len(events) != 3 {
+		t.Errorf("%d events after the client went away at 3", len(events))
+	}
+	for _, e := range events {
+		if e.kind != streamEventIteration {
+			t.Errorf("%s event sent to a client that went away", e.kind)
+		}
+	}
+	if n := h.results.Len(); n != 0 {
+		t.Errorf("%d results stored for a cancelled run", n)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	rec := httptest.NewRecorder()
+	h.handleSimulate(rec, streamRequest(context.Background(), "?stream=maybe", `{"target_ber": 1e-12, "ambient_profile": "lab_default"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("stream=maybe: status %d, want 400", rec.Code)
+	}
+
+	// Rejected before the first iteration: an ordinary error response
+	rec = httptest.NewRecorder()
+	h.handleSimulate(rec, streamRequest(context.Background(), "?stream=1", `{"target_ber": 1e-12, "ambient_profile": "nowhere"}`))
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") == "text/event-stream" {
+		t.Errorf("unknown profile: status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+// Validation battery parameters
+const (
+	defaultValidationSeeds = 8
+	maxValidationSeeds     = 64
+)
+
+// validationTargets are the target BERs each profile is simulated against
+var validationTargets = []float64{1e-12, 1e-10}
+
+// validationEvents are the disturbance scenarios each profile is simulated
+// under, the first being an undisturbed run
+var validationEvents = [][]SimulationEvent{
+	nil,
+	{{Time: 20, Kind: EventTemperatureStep, Magnitude: 5}},
+	{{Time: 10, Kind: EventVibrationSpike, Magnitude: 3}, {Time: 40, Kind: EventTemperatureStep, Magnitude: -4}},
+}
+
+// invariant is a property every simulation result must satisfy. check
+// returns a description of the violation, or "" if the result holds.
+type invariant struct {
+	name  string
+	check func(req SimulationRequest, resp *SimulationResponse) string
+}
+
+var invariants = []invariant{
+	{"ber_non_negative", func(req SimulationRequest, resp *SimulationResponse) string {
+		if resp.FinalBER < 0 {
+			return fmt.Sprintf("final BER %g is negative", resp.FinalBER)
+		}
+		for _, p := range resp.BERProfile {
+			if p.BER < 0 {
+				return fmt.Sprintf("BER %g at %.1fs is negative", p.BER, p.Time)
+			}
+		}
+		for lane, profile := range resp.PerLambdaBER {
+			for _, p := range profile {
+				if p.BER < 0 {
+					return fmt.Sprintf("lambda %d BER %g at %.1fs is negative", lane, p.BER, p.Time)
+				}
+			}
+		}
+		return ""
+	}},
+	{"final_ber_is_worst_lambda", func(req SimulationRequest, resp *SimulationResponse) string {
+		worst := 0.0
+		for _, profile := range resp.PerLambdaBER {
+			worst = math.Max(worst, profile[len(profile)-1].BER)
+		}
+		if resp.FinalBER != worst {
+			return fmt.Sprintf("final BER %g is not the worst channel's %g", resp.FinalBER, worst)
+		}
+		return ""
+	}},
+	{"eye_margin_in_range", func(req SimulationRequest, resp *SimulationResponse) string {
+		for _, p := range resp.EyeMarginProfile {
+			if p.EyeMargin < 0.1 || p.EyeMargin > 1.5 {
+				return fmt.Sprintf("eye margin %.3f UI at %.1fs is outside [0.1, 1.5]", p.EyeMargin, p.Time)
+			}
+		}
+		return ""
+	}},
+	{"power_savings_in_range", func(req SimulationRequest, resp *SimulationResponse) string {
+		if cap := resp.ModelParams.SavingsCapPercent; resp.PowerSavings < 0 || resp.PowerSavings > cap {
+			return fmt.Sprintf("power savings %.2f%% is outside [0, %g]", resp.PowerSavings, cap)
+		}
+		return ""
+	}},
+	{"eye_margin_matches_ber", func(req SimulationRequest, resp *SimulationResponse) string {
+		return resp.Consistency.Detail
+	}},
+	{"converged_below_target", func(req SimulationRequest, resp *SimulationResponse) string {
+		tolerance := resp.ConvergenceCriteria.BERTolerance
+		if resp.Converged && resp.FinalBER > req.TargetBER*tolerance {
+			return fmt.Sprintf("converged with final BER %g above %g × target %g", resp.FinalBER, tolerance, req.TargetBER)
+		}
+		return ""
+	}},
+}
+
+// InvariantViolation is one invariant failing for one simulation
+type InvariantViolation struct {
+	Invariant      string  `json:"invariant"`
+	AmbientProfile string  `json:"ambient_profile"`
+	Seed           int64   `json:"seed"`
+	TargetBER      float64 `json:"target_ber"`
+	Events         int     `json:"events"`
+	Detail         string  `json:"detail"`
+}
+
+// ValidationReport summarizes a validation battery
+type ValidationReport struct {
+	Passed     bool                 `json:"passed"`
+	Runs       int                  `json:"runs"`
+	Seeds      int                  `json:"seeds"`
+	Invariants []string             `json:"invariants"`
+	Violations []InvariantViolation `json:"violations"`
+}
+
+// Validate runs seeded simulations of every ambient profile, target BER and
+// event scenario and checks each result against the model invariants. Runs
+// use fresh simulators, so the report is reproducible and leaves this
+// simulator's calibration history untouched.
+func (h *HELIOPASSSimulator) Validate(seeds int) ValidationReport {
+	return runValidation(seeds, invariants)
+}
+
+func runValidation(seeds int, checks []invariant) ValidationReport {
+	report := ValidationReport{Seeds: seeds, Violations: []InvariantViolation{}}
+	for _, inv := range checks {
+		report.Invariants = append(report.Invariants, inv.name)
+	}
+
+	profiles := make([]string, 0)
+	for name := range NewHELIOPASSSimulator().GetAmbientProfiles() {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+
+	for _, profile := range profiles {
+		for _, target := range validationTargets {
+			for _, events := range validationEvents {
+				for seed := int64(1); seed <= int64(seeds); seed++ {
+					req := SimulationRequest{
+						TargetBER:      target,
+						AmbientProfile: profile,
+						Events:         events,
+						Seed:           seed,
+					}
+					resp, err := NewHELIOPASSSimulator().Simulate(req)
+					report.Runs++
+					if err != nil {
+						report.Violations = append(report.Violations, InvariantViolation{
+							Invariant: "simulation_succeeds", AmbientProfile: profile, Seed: seed,
+							TargetBER: target, Events: len(events), Detail: err.Error(),
+						})
+						continue
+					}
+					for _, inv := range checks {
+						if detail := inv.check(req, resp); detail != "" {
+							report.Violations = append(report.Violations, InvariantViolation{
+								Invariant: inv.name, AmbientProfile: profile, Seed: seed,
+								TargetBER: target, Events: len(events), Detail: detail,
+							})
+						}
+					}
+				}
+			}
+		}
+	}
+	report.Passed = len(report.Violations) == 0
+	return report
+}
+
+func (h *HELIOPASSSimulator) handleValidate(w http.ResponseWriter, r *http.Request) {
+	seeds := defaultValidationSeeds
+	if v := r.URL.Query().Get("seeds"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxValidationSeeds {
+			apierr.Respond(w, apierr.CodeValidation, fmt.Sprintf("seeds must be an integer in [1, %d]", maxValidationSeeds))
+			return
+		}
+		seeds = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Validate(seeds))
+}
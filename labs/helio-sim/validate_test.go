@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestValidationPassesForCurrentModel(t *testing.T) {
+	report := NewHELIOPASSSimulator().Validate(2)
+	if !report.Passed || len(report.Violations) != 0 {
+		t.Fatalf("violations: %+v", report.Violations)
+	}
+	profiles := len(NewHELIOPASSSimulator().GetAmbientProfiles())
+	if want := profiles * len(validationTargets) * len(validationEvents) * 2; report.Runs != want {
+		t.Errorf("runs = %d, want %d", report.Runs, want)
+	}
+	if len(report.Invariants) != len(invariants) {
+		t.Errorf("invariants = %v", report.Invariants)
+	}
+	if again := NewHELIOPASSSimulator().Validate(2); !reflect.DeepEqual(again, report) {
+		t.Error("seeded validation is not reproducible")
+	}
+}
+
+func TestValidationCatchesBrokenInvariant(t *testing.T) {
+	// Claim a convergence tolerance far tighter than the model delivers
+	strict := invariant{"converged_far_below_target", func(req SimulationRequest, resp *SimulationResponse) string {
+		if resp.Converged && resp.FinalBER > req.TargetBER*1e-6 {
+			return fmt.Sprintf("final BER %g", resp.FinalBER)
+		}
+		return ""
+	}}
+	report := runValidation(1, append(append([]invariant{}, invariants...), strict))
+	if report.Passed || len(report.Violations) == 0 {
+		t.Fatal("a violated invariant passed validation")
+	}
+	for _, v := range report.Violations {
+		if v.Invariant != strict.name {
+			t.Errorf("unexpected violation %+v", v)
+		}
+	}
+}
+
+func TestValidateEndpointRejectsBadSeeds(t *testing.T) {
+	h := NewHELIOPASSSimulator()
+	for _, seeds := range []string{"0", "65", "x"} {
+		rec := httptest.NewRecorder()
+		h.handleValidate(rec, httptest.NewRequest(http.MethodGet, "/v1/helio-sim/validate?seeds="+seeds, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("seeds=%s: status = %d, want 400", seeds, rec.Code)
+		}
+	}
+}
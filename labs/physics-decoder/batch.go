@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/jsonbody"
+	"github.com/corridoros/pkg/protowire"
+)
+
+// defaultMaxBatch is the default cap on the requests of one batch
+const defaultMaxBatch = 256
+
+// BatchRequest is a set of independent calculations
+type BatchRequest struct {
+	Requests []DecoderRequest `json:"requests"`
+}
+
+// BatchResponse carries one response per request, in request order; a
+// failed calculation has Valid false and its own Error
+type BatchResponse struct {
+	Responses []DecoderResponse `json:"responses"`
+}
+
+// CalculateBatch runs each request of a batch as Calculate would
+func (p *PhysicsDecoderService) CalculateBatch(req BatchRequest) (*BatchResponse, error) {
+	resp := &BatchResponse{Responses: make([]DecoderResponse, 0, len(req.Requests))}
+	for _, r := range req.Requests {
+		result, err := p.Calculate(r)
+		if err != nil {
+			return nil, err
+		}
+		resp.Responses = append(resp.Responses, *result)
+	}
+	return resp, nil
+}
+
+// handleBatch runs a batch given as JSON or, with Content-Type
+// application/x-protobuf, as a corridoros.v1.BatchRequest. The results are
+// JSON unless the request asks for protobuf in Accept.
+func (p *PhysicsDecoderService) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := decodeBatch(r, &req); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+	if len(req.Requests) > p.MaxBatch {
+		apierr.Write(w, apierr.New(apierr.CodeTooLarge, "batch of %d requests exceeds the maximum of %d", len(req.Requests), p.MaxBatch))
+		return
+	}
+
+	if raw := r.URL.Query().Get("stream"); raw != "" {
+		stream, err := strconv.ParseBool(raw)
+		if err != nil {
+			apierr.Respond(w, apierr.CodeValidation, "stream must be a boolean")
+			return
+		}
+		if stream {
+			p.streamBatch(w, r, req)
+			return
+		}
+	}
+
+	response, err := p.CalculateBatch(req)
+	if err != nil {
+		apierr.Respond(w, apierr.CodeInternal, err.Error())
+		return
+	}
+	if protowire.Accepts(r) {
+		protowire.Write(w, http.StatusOK, response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// decodeBatch reads a batch request body in the encoding its Content-Type
+// names
+func decodeBatch(r *http.Request, req *BatchRequest) error {
+	if !protowire.Sent(r) {
+		return jsonbody.Decode(r.Body, req, jsonbody.Strict)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return req.UnmarshalProto(body)
+}
+
+// streamBatch writes a batch's responses as newline-delimited JSON, one
+// DecoderResponse per line in request order, flushing each as it is
+// calculated; Accept does not change the encoding. It stops at the first
+// request after the client goes away. A calculation failing before the
+// first line gets an ordinary error response; one failing later ends the
+// stream with an apierr.Error line. Signed responses are buffered whole, so
+// with -sign-responses the lines arrive together at the end.
+func (p *PhysicsDecoderService) streamBatch(w http.ResponseWriter, r *http.Request, req BatchRequest) {
+	ctx := r.Context()
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	for i, dr := range req.Requests {
+		if ctx.Err() != nil {
+			log.Printf("batch stream cancelled after %d of %d results: %v", i, len(req.Requests), ctx.Err())
+			return
+		}
+		result, err := p.Calculate(dr)
+		if err != nil {
+			if i == 0 {
+				apierr.Respond(w, apierr.CodeInternal, err.Error())
+				return
+			}
+			_ = enc.Encode(apierr.New(apierr.CodeInternal, "request %d: %s", i, err.Error()))
+			return
+		}
+		if i == 0 {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+		}
+		if err := enc.Encode(result); err != nil {
+			return
+		}
+		_ = rc.Flush()
+	}
+	if len(req.Requests) == 0 {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+	}
+}
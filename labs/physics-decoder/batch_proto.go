@@ -0,0 +1,270 @@
+package main
+
+import "github.com/corridoros/pkg/protowire"
+
+// MarshalProto encodes a batch as corridoros.v1.BatchRequest
+func (b BatchRequest) MarshalProto() []byte {
+	var out []byte
+	for _, r := range b.Requests {
+		out = protowire.AppendMessage(out, 1, r.MarshalProto())
+	}
+	return out
+}
+
+// UnmarshalProto decodes a corridoros.v1.BatchRequest
+func (b *BatchRequest) UnmarshalProto(buf []byte) error {
+	*b = BatchRequest{}
+	return protowire.Range(buf, func(f protowire.Field) error {
+		if f.Num != 1 {
+			return nil
+		}
+		var r DecoderRequest
+		if err := r.UnmarshalProto(f.Bytes); err != nil {
+			return err
+		}
+		b.Requests = append(b.Requests, r)
+		return nil
+	})
+}
+
+// MarshalProto encodes a batch's results as corridoros.v1.BatchResponse
+func (b BatchResponse) MarshalProto() []byte {
+	var out []byte
+	for _, r := range b.Responses {
+		out = protowire.AppendMessage(out, 1, r.MarshalProto())
+	}
+	return out
+}
+
+// UnmarshalProto decodes a corridoros.v1.BatchResponse
+func (b *BatchResponse) UnmarshalProto(buf []byte) error {
+	*b = BatchResponse{Responses: []DecoderResponse{}}
+	return protowire.Range(buf, func(f protowire.Field) error {
+		if f.Num != 1 {
+			return nil
+		}
+		var r DecoderResponse
+		if err := r.UnmarshalProto(f.Bytes); err != nil {
+			return err
+		}
+		b.Responses = append(b.Responses, r)
+		return nil
+	})
+}
+
+// MarshalProto encodes a request as corridoros.v1.DecoderRequest
+func (r DecoderRequest) MarshalProto() []byte {
+	b := make([]byte, 0, 128)
+	b = protowire.AppendString(b, 1, r.Formula)
+	b = protowire.AppendDoubleMap(b, 2, r.Variables)
+	b = protowire.AppendStringMap(b, 3, r.Units)
+	b = protowire.AppendString(b, 4, r.Context)
+	b = protowire.AppendBool(b, 5, r.Hypothesis)
+	b = protowire.AppendDoubleMap(b, 6, r.ConstantOverrides)
+	b = protowire.AppendDoubleMap(b, 7, r.Uncertainties)
+	b = protowire.AppendOptionalDouble(b, 8, r.Expected)
+	b = protowire.AppendString(b, 9, r.ExpectedUnit)
+	b = protowire.AppendDouble(b, 10, r.Tolerance)
+	b = protowire.AppendInt64(b, 11, int64(r.SigFigs))
+	b = protowire.AppendString(b, 12, r.OutputUnit)
+	return protowire.AppendDoubleMap(b, 13, r.Constants)
+}
+
+// UnmarshalProto decodes a corridoros.v1.DecoderRequest
+func (r *DecoderRequest) UnmarshalProto(b []byte) error {
+	*r = DecoderRequest{}
+	return protowire.Range(b, func(f protowire.Field) error {
+		switch f.Num {
+		case 1:
+			r.Formula = string(f.Bytes)
+		case 2:
+			return decodeDoubleEntry(f, &r.Variables)
+		case 3:
+			return decodeStringEntry(f, &r.Units)
+		case 4:
+			r.Context = string(f.Bytes)
+		case 5:
+			r.Hypothesis = f.Varint != 0
+		case 6:
+			return decodeDoubleEntry(f, &r.ConstantOverrides)
+		case 7:
+			return decodeDoubleEntry(f, &r.Uncertainties)
+		case 8:
+			expected := f.Double()
+			r.Expected = &expected
+		case 9:
+			r.ExpectedUnit = string(f.Bytes)
+		case 10:
+			r.Tolerance = f.Double()
+		case 11:
+			r.SigFigs = int(int32(f.Varint))
+		case 12:
+			r.OutputUnit = string(f.Bytes)
+		case 13:
+			return decodeDoubleEntry(f, &r.Constants)
+		}
+		return nil
+	})
+}
+
+// MarshalProto encodes a result as corridoros.v1.DecoderResponse
+func (r DecoderResponse) MarshalProto() []byte {
+	b := make([]byte, 0, 256)
+	b = protowire.AppendDouble(b, 1, r.Result)
+	b = protowire.AppendString(b, 2, r.Unit)
+	b = protowire.AppendDouble(b, 3, r.ResultUncertainty)
+	b = protowire.AppendString(b, 4, r.Formula)
+	b = protowire.AppendString(b, 5, r.CanonicalFormula)
+	b = protowire.AppendBool(b, 6, r.FormulaValidated)
+	b = protowire.AppendString(b, 7, r.Confidence)
+	for _, s := range r.Steps {
+		var sb []byte
+		sb = protowire.AppendString(sb, 1, s.Description)
+		sb = protowire.AppendDouble(sb, 2, s.Value)
+		sb = protowire.AppendString(sb, 3, s.Unit)
+		sb = protowire.AppendString(sb, 4, s.Formula)
+		sb = protowire.AppendDouble(sb, 5, s.Uncertainty)
+		b = protowire.AppendMessage(b, 8, sb)
+	}
+	b = protowire.AppendBool(b, 9, r.Valid)
+	b = protowire.AppendString(b, 10, r.Error)
+	for _, w := range r.Warnings {
+		var wb []byte
+		wb = protowire.AppendString(wb, 1, w.Code)
+		wb = protowire.AppendString(wb, 2, w.Message)
+		wb = protowire.AppendString(wb, 3, w.Field)
+		b = protowire.AppendMessage(b, 11, wb)
+	}
+	b = protowire.AppendRepeatedString(b, 12, r.WarningMessages)
+	b = protowire.AppendStringMap(b, 13, r.Dimensions)
+	b = protowire.AppendString(b, 14, r.Context)
+	b = protowire.AppendBool(b, 15, r.Hypothesis)
+	b = protowire.AppendOptionalBool(b, 16, r.Matches)
+	b = protowire.AppendOptionalDouble(b, 17, r.RelativeError)
+	if f := r.Formatted; f != nil {
+		var fb []byte
+		fb = protowire.AppendString(fb, 1, f.Scientific)
+		fb = protowire.AppendString(fb, 2, f.Engineering)
+		fb = protowire.AppendString(fb, 3, f.SigFigs)
+		b = protowire.AppendMessage(b, 18, fb)
+	}
+	return protowire.AppendString(b, 19, r.ID)
+}
+
+// UnmarshalProto decodes a corridoros.v1.DecoderResponse. Steps and
+// Dimensions come back empty rather than nil, as the JSON encoding has them.
+func (r *DecoderResponse) UnmarshalProto(b []byte) error {
+	*r = DecoderResponse{Steps: []CalculationStep{}, Dimensions: map[string]string{}}
+	return protowire.Range(b, func(f protowire.Field) error {
+		switch f.Num {
+		case 1:
+			r.Result = f.Double()
+		case 2:
+			r.Unit = string(f.Bytes)
+		case 3:
+			r.ResultUncertainty = f.Double()
+		case 4:
+			r.Formula = string(f.Bytes)
+		case 5:
+			r.CanonicalFormula = string(f.Bytes)
+		case 6:
+			r.FormulaValidated = f.Varint != 0
+		case 7:
+			r.Confidence = string(f.Bytes)
+		case 8:
+			var s CalculationStep
+			err := protowire.Range(f.Bytes, func(sf protowire.Field) error {
+				switch sf.Num {
+				case 1:
+					s.Description = string(sf.Bytes)
+				case 2:
+					s.Value = sf.Double()
+				case 3:
+					s.Unit = string(sf.Bytes)
+				case 4:
+					s.Formula = string(sf.Bytes)
+				case 5:
+					s.Uncertainty = sf.Double()
+				}
+				return nil
+			})
+			r.Steps = append(r.Steps, s)
+			return err
+		case 9:
+			r.Valid = f.Varint != 0
+		case 10:
+			r.Error = string(f.Bytes)
+		case 11:
+			var w Warning
+			err := protowire.Range(f.Bytes, func(wf protowire.Field) error {
+				switch wf.Num {
+				case 1:
+					w.Code = string(wf.Bytes)
+				case 2:
+					w.Message = string(wf.Bytes)
+				case 3:
+					w.Field = string(wf.Bytes)
+				}
+				return nil
+			})
+			r.Warnings = append(r.Warnings, w)
+			return err
+		case 12:
+			r.WarningMessages = append(r.WarningMessages, string(f.Bytes))
+		case 13:
+			return decodeStringEntry(f, &r.Dimensions)
+		case 14:
+			r.Context = string(f.Bytes)
+		case 15:
+			r.Hypothesis = f.Varint != 0
+		case 16:
+			matches := f.Varint != 0
+			r.Matches = &matches
+		case 17:
+			relErr := f.Double()
+			r.RelativeError = &relErr
+		case 18:
+			r.Formatted = &FormattedResult{}
+			return protowire.Range(f.Bytes, func(ff protowire.Field) error {
+				switch ff.Num {
+				case 1:
+					r.Formatted.Scientific = string(ff.Bytes)
+				case 2:
+					r.Formatted.Engineering = string(ff.Bytes)
+				case 3:
+					r.Formatted.SigFigs = string(ff.Bytes)
+				}
+				return nil
+			})
+		case 19:
+			r.ID = string(f.Bytes)
+		}
+		return nil
+	})
+}
+
+// decodeDoubleEntry adds a map<string, double> entry to *m
+func decodeDoubleEntry(f protowire.Field, m *map[string]float64) error {
+	key, value, err := f.MapEntry()
+	if err != nil {
+		return err
+	}
+	if *m == nil {
+		*m = make(map[string]float64)
+	}
+	(*m)[key] = value.Double()
+	return nil
+}
+
+// decodeStringEntry adds a map<string, string> entry to *m
+func decodeStringEntry(f protowire.Field, m *map[string]string) error {
+	key, value, err := f.MapEntry()
+	if err != nil {
+		return err
+	}
+	if *m == nil {
+		*m = make(map[string]string)
+	}
+	(*m)[key] = string(value.Bytes)
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/corridoros/pkg/protowire"
+)
+
+// mixedBatch exercises every DecoderRequest field and both valid and
+// invalid results
+func mixedBatch() BatchRequest {
+	expected, zero := 8.987551787368176e16, 0.0
+	return BatchRequest{Requests: []DecoderRequest{
+		{Formula: "E = mc^2", Variables: map[string]float64{"m": 1}, Units: map[string]string{"m": "kg"}, Expected: &expected, OutputUnit: "eV", SigFigs: 6},
+		{Formula: "E = hf", Variables: map[string]float64{"f": 5e14}, Uncertainties: map[string]float64{"f": 1e12}, Context: "visible", Tolerance: 1e-3},
+		{Formula: "E = kT", Variables: map[string]float64{"T": 300}, Hypothesis: true, Constants: map[string]float64{"k": 1.5e-23}},
+		{Formula: "E = mc^2", Variables: map[string]float64{"m": 2}, ConstantOverrides: map[string]float64{"c": 3e8}, Expected: &zero, ExpectedUnit: "J"},
+		{Formula: "no such formula ("},
+	}}
+}
+
+func TestBatchProtoRoundTrip(t *testing.T) {
+	req := mixedBatch()
+	var decodedReq BatchRequest
+	if err := decodedReq.UnmarshalProto(req.MarshalProto()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decodedReq, req) {
+		t.Errorf("request round trip:\n got %+v\nwant %+v", decodedReq, req)
+	}
+
+	resp, err := NewPhysicsDecoderService().CalculateBatch(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON := jsonRoundTrip(t, resp)
+	var fromProto BatchResponse
+	if err := fromProto.UnmarshalProto(resp.MarshalProto()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromProto, fromJSON) {
+		t.Errorf("protobuf and JSON decode differently:\nproto %+v\n json %+v", fromProto, fromJSON)
+	}
+}
+
+func jsonRoundTrip(t testing.TB, resp *BatchResponse) BatchResponse {
+	t.Helper()
+	b, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out BatchResponse
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestBatchEndpointProtobuf(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	req := mixedBatch()
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/physics/batch", bytes.NewReader(req.MarshalProto()))
+	r.Header.Set("Content-Type", protowire.ContentType)
+	r.Header.Set("Accept", protowire.ContentType)
+	rec := httptest.NewRecorder()
+	p.handleBatch(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != protowire.ContentType {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var binary BatchResponse
+	if err := binary.UnmarshalProto(rec.Body.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	rec = postBatch(t, p, context.Background(), "", req)
+	var text BatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &text); err != nil {
+		t.Fatal(err)
+	}
+	// History ids differ between the two runs
+	for i := range binary.Responses {
+		binary.Responses[i].ID, text.Responses[i].ID = "", ""
+	}
+	if !reflect.DeepEqual(binary, text) {
+		t.Errorf("protobuf and JSON endpoints disagree:\nproto %+v\n json %+v", binary, text)
+	}
+}
+
+// largeBatch is a full batch of valid results to encode
+func largeBatch(b *testing.B) *BatchResponse {
+	b.Helper()
+	resp, err := NewPhysicsDecoderService().CalculateBatch(massBatch(defaultMaxBatch))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return resp
+}
+
+func BenchmarkBatchResponseJSON(b *testing.B) {
+	resp := largeBatch(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(resp); err != nil {
+			b.Fatal(err)
+		}
+		var out BatchResponse
+		if err := json.NewDecoder(&buf).Decode(&out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBatchResponseProtobuf(b *testing.B) {
+	resp := largeBatch(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		encoded := resp.MarshalProto()
+		var out BatchResponse
+		if err := out.UnmarshalProto(encoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
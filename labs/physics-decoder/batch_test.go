@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// massBatch is a batch of energy_mass requests whose results, m·c², are in
+// request order and distinct
+func massBatch(n int) BatchRequest {
+	var req BatchRequest
+	for i := 0; i < n; i++ {
+		req.Requests = append(req.Requests, DecoderRequest{
+			Formula:   "E = mc^2",
+			Variables: map[string]float64{"m": float64(i + 1)},
+			Units:     map[string]string{"m": "kg"},
+		})
+	}
+	return req
+}
+
+func postBatch(t *testing.T, p *PhysicsDecoderService, ctx context.Context, query string, req BatchRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/v1/physics/batch"+query, bytes.NewReader(body)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	p.handleBatch(rec, r)
+	return rec
+}
+
+func postBatchBody(p *PhysicsDecoderService, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	p.handleBatch(rec, httptest.NewRequest(http.MethodPost, "/v1/physics/batch", bytes.NewBufferString(body)))
+	return rec
+}
+
+func TestBatchKeepsOrderAndIsolatesFailures(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	body, _ := json.Marshal(BatchRequest{Requests: []DecoderRequest{
+		{Formula: "E=mc^2", Variables: map[string]float64{"m": 1}},
+		{Formula: "E=hf"}, // missing f
+		{Formula: "λ=c/f", Variables: map[string]float64{"f": 193.4}, Units: map[string]string{"f": "THz"}},
+	}})
+	rec := postBatchBody(p, string(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp BatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Responses) != 3 {
+		t.Fatalf("%d responses, want 3", len(resp.Responses))
+	}
+
+	for i, want := range []struct {
+		formula string
+		valid   bool
+	}{{"E=mc^2", true}, {"E=hf", false}, {"λ=c/f", true}} {
+		got := resp.Responses[i]
+		if got.Formula != want.formula || got.Valid != want.valid {
+			t.Errorf("response %d = %s valid %v, want %s valid %v", i, got.Formula, got.Valid, want.formula, want.valid)
+		}
+	}
+	if !strings.Contains(resp.Responses[1].Error, "'f' not provided") {
+		t.Errorf("failed request error = %q", resp.Responses[1].Error)
+	}
+	single, _ := p.Calculate(DecoderRequest{Formula: "E=mc^2", Variables: map[string]float64{"m": 1}})
+	if resp.Responses[0].Result != single.Result {
+		t.Errorf("batch result %g, single %g", resp.Responses[0].Result, single.Result)
+	}
+}
+
+func TestBatchLimitsAndBadBodies(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	p.MaxBatch = 2
+	for body, want := range map[string]int{
+		`{"requests":[]}`: http.StatusOK,
+		`{"requests":[{"formula":"E=mc^2"},{"formula":"E=mc^2"}]}`:                      http.StatusOK,
+		`{"requests":[{"formula":"E=mc^2"},{"formula":"E=mc^2"},{"formula":"E=mc^2"}]}`: http.StatusRequestEntityTooLarge,
+		`{"request":[]}`: http.StatusBadRequest,
+		`{"requests":[`:  http.StatusBadRequest,
+	} {
+		if rec := postBatchBody(p, body); rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", body, rec.Code, want)
+		}
+	}
+}
+
+func TestBatchStreamMatchesBatch(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	req := massBatch(50)
+
+	rec := postBatch(t, p, context.Background(), "", req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("batch: status %d: %s", rec.Code, rec.Body)
+	}
+	var batch BatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &batch); err != nil {
+		t.Fatal(err)
+	}
+
+	rec = postBatch(t, p, context.Background(), "?stream=1", req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("stream: status %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+	}
+	var streamed []DecoderResponse
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var resp DecoderResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			t.Fatalf("line %d: %v", len(streamed)+1, err)
+		}
+		streamed = append(streamed, resp)
+	}
+	if len(streamed) != len(batch.Responses) {
+		t.Fatalf("streamed %d results, batch %d", len(streamed), len(batch.Responses))
+	}
+	for i, got := range streamed {
+		want := batch.Responses[i]
+		if !got.Valid || got.Result != want.Result || got.Unit != want.Unit {
+			t.Errorf("result %d: streamed %v %s (valid %v), batch %v %s", i, got.Result, got.Unit, got.Valid, want.Result, want.Unit)
+		}
+		if i > 0 && got.Result <= streamed[i-1].Result {
+			t.Errorf("result %d out of request order", i)
+		}
+	}
+}
+
+func TestBatchStreamStopsOnCancel(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	calculated := 0
+	err := p.RegisterFormula(FormulaInfo{ID: "counted", Formula: "counted calculation"}, "1", func(map[string]float64, map[string]string) (float64, []CalculationStep, error) {
+		calculated++
+		return 1, []CalculationStep{{Description: "Counted", Value: 1}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := massBatch(10)
+	for i := range req.Requests {
+		req.Requests[i].Formula = "counted calculation"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := postBatch(t, p, ctx, "?stream=true", req)
+	if rec.Body.Len() != 0 {
+		t.Errorf("cancelled stream wrote %q", rec.Body)
+	}
+	if calculated != 0 {
+		t.Errorf("cancelled stream calculated %d requests", calculated)
+	}
+	if postBatch(t, p, context.Background(), "?stream=true", req); calculated != len(req.Requests) {
+		t.Errorf("uncancelled stream calculated %d of %d requests", calculated, len(req.Requests))
+	}
+}
+
+func TestBatchStreamRejectsBadFlag(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	if rec := postBatch(t, p, context.Background(), "?stream=maybe", massBatch(1)); rec.Code != http.StatusBadRequest {
+		t.Errorf("status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// operatorVariants maps other spellings of operators to the ASCII one
+var operatorVariants = strings.NewReplacer(
+	"**", "^",
+	"−", "-", // minus sign
+	"÷", "/",
+	"∕", "/",
+	"＝", "=",
+)
+
+// multiplicationSigns are dropped from equations in favor of juxtaposition
+const multiplicationSigns = "*×·⋅"
+
+// formulaSynonyms are rewritten in the canonical form, in order, after
+// spacing and case are normalized
+var formulaSynonyms = strings.NewReplacer(
+	"lambda", "λ",
+	"ν", "f", // frequency
+	"k_b", "k", // Boltzmann constant
+	"mc2", "mc^2",
+)
+
+// canonicalFormula normalizes how a formula is typed so that surface
+// variants match the same named formula: superscripts become ^ exponents,
+// operator spellings become ASCII, case is folded (all named formulas are
+// case-insensitive) and common synonyms are mapped. In an equation, that is
+// any formula with an =, whitespace and multiplication signs are dropped
+// so "E = m·c²" reads e=mc^2; one is kept as · only where dropping it would
+// run a factor into a following number. Prose keeps single spaces.
+func canonicalFormula(formula string) string {
+	formula = operatorVariants.Replace(strings.TrimSpace(formula))
+
+	var b strings.Builder
+	inSuperscript := false
+	for _, r := range formula {
+		if raised, ok := superscripts[r]; ok {
+			if !inSuperscript {
+				b.WriteByte('^')
+			}
+			b.WriteRune(raised)
+			inSuperscript = true
+			continue
+		}
+		inSuperscript = false
+		b.WriteRune(unicode.ToLower(r))
+	}
+	formula = b.String()
+
+	if strings.Contains(formula, "=") {
+		formula = joinFactors(formula)
+	} else {
+		formula = strings.Join(strings.Fields(formula), " ")
+	}
+	return formulaSynonyms.Replace(formula)
+}
+
+// joinFactors drops whitespace and multiplication signs from an equation,
+// writing a single · where the next factor starts with a digit
+func joinFactors(formula string) string {
+	runes := []rune(formula)
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if !unicode.IsSpace(r) && !strings.ContainsRune(multiplicationSigns, r) {
+			b.WriteRune(r)
+			continue
+		}
+
+		// Collapse the whole run of separators
+		explicit := strings.ContainsRune(multiplicationSigns, r)
+		for i+1 < len(runes) && (unicode.IsSpace(runes[i+1]) || strings.ContainsRune(multiplicationSigns, runes[i+1])) {
+			i++
+			explicit = explicit || strings.ContainsRune(multiplicationSigns, runes[i])
+		}
+		if b.Len() == 0 || i+1 == len(runes) {
+			continue
+		}
+		prev, _ := utf8.DecodeLastRuneInString(b.String())
+		next := runes[i+1]
+		if unicode.IsDigit(next) && (explicit || unicode.IsLetter(prev) || unicode.IsDigit(prev) || prev == '_') {
+			b.WriteRune('·')
+		}
+	}
+	return b.String()
+}
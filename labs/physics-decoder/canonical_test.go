@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnergyMassVariantsResolveTheSame(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	calculate := func(formula string) *DecoderResponse {
+		t.Helper()
+		resp, err := p.Calculate(DecoderRequest{
+			Formula:   formula,
+			Variables: map[string]float64{"m": 2},
+			Units:     map[string]string{"m": "g"},
+		})
+		if err != nil || !resp.Valid {
+			t.Fatalf("%q: %+v, %v", formula, resp, err)
+		}
+		return resp
+	}
+
+	want := calculate("E=mc^2")
+	if want.CanonicalFormula != "e=mc^2" {
+		t.Fatalf("canonical form of E=mc^2 = %q", want.CanonicalFormula)
+	}
+	for _, formula := range []string{"e = m c²", "E=MC2", "E = m·c²", "  E=m*c**2 ", "E = m × c^2"} {
+		got := calculate(formula)
+		if got.CanonicalFormula != want.CanonicalFormula {
+			t.Errorf("%q canonicalizes to %q, want %q", formula, got.CanonicalFormula, want.CanonicalFormula)
+		}
+		if got.Result != want.Result || got.Unit != want.Unit || !reflect.DeepEqual(got.Steps, want.Steps) {
+			t.Errorf("%q = %g %s, want %g %s", formula, got.Result, got.Unit, want.Result, want.Unit)
+		}
+	}
+}
+
+func TestCanonicalFormula(t *testing.T) {
+	for in, want := range map[string]string{
+		"λ = c / f":            "λ=c/f",
+		"lambda=c/f":           "λ=c/f",
+		"E = h ν":              "e=hf",
+		"E = k_B T":            "e=kt",
+		"P = E / t":            "p=e/t",
+		"x = 2 · 3":            "x=2·3",
+		"  Thermal   energy  ": "thermal energy",
+		"P_out = P_in−L":       "p_out=p_in-l",
+	} {
+		if got := canonicalFormula(in); got != want {
+			t.Errorf("canonicalFormula(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// kineticEnergy is a user formula the decoder does not implement itself
+func kineticEnergy(vars map[string]float64, units map[string]string) (float64, []CalculationStep, error) {
+	m, okM := vars["m"]
+	v, okV := vars["v"]
+	if !okM || !okV {
+		return 0, nil, fmt.Errorf("variables 'm' and 'v' are required")
+	}
+	result := m * v * v / 2
+	return result, []CalculationStep{{Description: "Kinetic energy", Value: result, Unit: "J", Formula: "KE = mv²/2"}}, nil
+}
+
+func TestValidatedBuiltinFormula(t *testing.T) {
+	resp, err := NewPhysicsDecoderService().Calculate(DecoderRequest{Formula: "E = mc²", Variables: map[string]float64{"m": 1}})
+	if err != nil || !resp.Valid {
+		t.Fatalf("E = mc²: %+v, %v", resp, err)
+	}
+	if !resp.FormulaValidated || resp.Confidence != "established" {
+		t.Errorf("built-in formula: validated %v, confidence %q", resp.FormulaValidated, resp.Confidence)
+	}
+
+	resp, _ = NewPhysicsDecoderService().Calculate(DecoderRequest{Formula: "E = mc²", Variables: map[string]float64{"m": 1}, Hypothesis: true})
+	if !resp.FormulaValidated || resp.Confidence != "hypothesis" {
+		t.Errorf("hypothesis request: validated %v, confidence %q", resp.FormulaValidated, resp.Confidence)
+	}
+}
+
+func TestRegisteredUserFormulaIsUnvalidated(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	info := FormulaInfo{ID: "kinetic_energy", Name: "Kinetic Energy", Formula: "KE = m·v^2/2", Validated: true}
+	if err := p.RegisterFormula(info, "J", kineticEnergy); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := p.Calculate(DecoderRequest{Formula: "ke = m v²/2", Variables: map[string]float64{"m": 2, "v": 3}})
+	if err != nil || !resp.Valid {
+		t.Fatalf("user formula: %+v, %v", resp, err)
+	}
+	if resp.Result != 9 || resp.Unit != "J" {
+		t.Errorf("user formula = %g %s, want 9 J", resp.Result, resp.Unit)
+	}
+	if resp.FormulaValidated || resp.Confidence != "experimental" {
+		t.Errorf("user formula: validated %v, confidence %q", resp.FormulaValidated, resp.Confidence)
+	}
+
+	formulas := p.GetFormulas()
+	if last := formulas[len(formulas)-1]; last.ID != "kinetic_energy" || last.Validated {
+		t.Errorf("GetFormulas lists the user formula as %+v", last)
+	}
+}
+
+func TestRegisterFormulaRejectsClashes(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	for name, info := range map[string]FormulaInfo{
+		"builtin id":      {ID: "energy_mass", Formula: "x = y"},
+		"builtin formula": {ID: "einstein", Formula: "E = m c^2"},
+		"no id":           {Formula: "x = y"},
+	} {
+		if err := p.RegisterFormula(info, "", kineticEnergy); err == nil {
+			t.Errorf("%s: registered", name)
+		}
+	}
+}
+
+func TestUnrecognizedFormulaIsUnvalidated(t *testing.T) {
+	resp, _ := NewPhysicsDecoderService().Calculate(DecoderRequest{Formula: "F = ma"})
+	if resp.Valid || resp.FormulaValidated {
+		t.Errorf("unrecognized formula = %+v", resp)
+	}
+}
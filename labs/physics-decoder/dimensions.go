@@ -0,0 +1,493 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/jsonbody"
+)
+
+// baseDimensions are the SI base dimension symbols in the order they are
+// written, e.g. ML²T⁻² for energy
+var baseDimensions = []rune{'M', 'L', 'T', 'Θ', 'I', 'N', 'J'}
+
+// Dimension holds the exponent of each base dimension, indexed as
+// baseDimensions
+type Dimension [7]int
+
+var superscripts = map[rune]rune{
+	'⁰': '0', '¹': '1', '²': '2', '³': '3', '⁴': '4',
+	'⁵': '5', '⁶': '6', '⁷': '7', '⁸': '8', '⁹': '9', '⁻': '-',
+}
+
+var superscriptDigits = []rune("⁰¹²³⁴⁵⁶⁷⁸⁹")
+
+// ParseDimension parses base-dimension notation such as "ML²T⁻²". Exponents
+// may also be written with a caret ("ML^2T^-2"); "1" or "" is dimensionless.
+func ParseDimension(s string) (Dimension, error) {
+	var d Dimension
+	s = strings.ReplaceAll(s, " ", "")
+	if s == "" || s == "1" {
+		return d, nil
+	}
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		idx := -1
+		for j, b := range baseDimensions {
+			if runes[i] == b {
+				idx = j
+			}
+		}
+		if idx < 0 {
+			return Dimension{}, fmt.Errorf("dimension %q: unknown base dimension %q (use M, L, T, Θ, I, N, J)", s, runes[i])
+		}
+		i++
+
+		var exp strings.Builder
+		if i < len(runes) && runes[i] == '^' {
+			i++
+			for i < len(runes) && (runes[i] == '-' || unicode.IsDigit(runes[i])) {
+				exp.WriteRune(runes[i])
+				i++
+			}
+		} else {
+			for i < len(runes) {
+				r, ok := superscripts[runes[i]]
+				if !ok {
+					break
+				}
+				exp.WriteRune(r)
+				i++
+			}
+		}
+		n := 1
+		if exp.Len() > 0 {
+			var err error
+			if n, err = strconv.Atoi(exp.String()); err != nil {
+				return Dimension{}, fmt.Errorf("dimension %q: invalid exponent %q", s, exp.String())
+			}
+		}
+		d[idx] += n
+	}
+	return d, nil
+}
+
+// String writes d in base-dimension notation, "1" when dimensionless
+func (d Dimension) String() string {
+	var b strings.Builder
+	for i, n := range d {
+		if n == 0 {
+			continue
+		}
+		b.WriteRune(baseDimensions[i])
+		if n != 1 {
+			for _, r := range strconv.Itoa(n) {
+				if r == '-' {
+					b.WriteRune('⁻')
+				} else {
+					b.WriteRune(superscriptDigits[r-'0'])
+				}
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return "1"
+	}
+	return b.String()
+}
+
+func (d Dimension) mul(o Dimension, sign int) Dimension {
+	for i := range d {
+		d[i] += sign * o[i]
+	}
+	return d
+}
+
+func (d Dimension) pow(n int) Dimension {
+	for i := range d {
+		d[i] *= n
+	}
+	return d
+}
+
+// DimensionsRequest asks for the dimension of a formula given the
+// dimension of each variable. The formula may be an expression or an
+// equation "lhs = rhs", whose sides must agree.
+type DimensionsRequest struct {
+	Formula    string            `json:"formula"`
+	Dimensions map[string]string `json:"dimensions"`
+}
+
+// DimensionsResponse carries a formula's derived dimension
+type DimensionsResponse struct {
+	Formula   string `json:"formula"`
+	Dimension string `json:"dimension"`
+}
+
+// AnalyzeDimensions derives the dimension of a formula without evaluating it
+func (p *PhysicsDecoderService) AnalyzeDimensions(req DimensionsRequest) (*DimensionsResponse, error) {
+	vars := make(map[string]Dimension, len(req.Dimensions))
+	for name, notation := range req.Dimensions {
+		d, err := ParseDimension(notation)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %v", name, err)
+		}
+		vars[name] = d
+	}
+
+	sides := strings.Split(req.Formula, "=")
+	if len(sides) > 2 {
+		return nil, fmt.Errorf("formula may contain at most one '='")
+	}
+	var dims []Dimension
+	for _, side := range sides {
+		d, err := dimensionOf(side, vars)
+		if err != nil {
+			return nil, err
+		}
+		dims = append(dims, d)
+	}
+	if len(dims) == 2 && dims[0] != dims[1] {
+		return nil, fmt.Errorf("dimension mismatch: left side is %s, right side is %s", dims[0], dims[1])
+	}
+	return &DimensionsResponse{Formula: req.Formula, Dimension: dims[0].String()}, nil
+}
+
+// dimensionOf parses an arithmetic expression over vars, as
+// parseExpression reads it, and returns its dimension
+func dimensionOf(expr string, vars map[string]Dimension) (Dimension, error) {
+	tree, err := parseExpression(expr)
+	if err != nil {
+		return Dimension{}, err
+	}
+	return tree.dimension(tree.root, vars)
+}
+
+// dimension derives the dimension of a node from those of its operands
+func (t *exprTree) dimension(n *exprNode, vars map[string]Dimension) (Dimension, error) {
+	switch n.kind {
+	case exprNumber:
+		return Dimension{}, nil
+	case exprName:
+		d, ok := vars[n.name]
+		if !ok {
+			return d, fmt.Errorf("no dimension given for variable %s", n.name)
+		}
+		return d, nil
+	}
+
+	d, err := t.dimension(n.args[0], vars)
+	if err != nil {
+		return d, err
+	}
+	switch n.kind {
+	case exprNegate:
+		return d, nil
+	case exprCall:
+		return functionDimension(n.name, d)
+	}
+
+	rhs, err := t.dimension(n.args[1], vars)
+	if err != nil {
+		return d, err
+	}
+	if n.kind == exprPower {
+		if rhs != (Dimension{}) {
+			return d, fmt.Errorf("exponent of %s must be dimensionless, got %s", t.text(n), rhs)
+		}
+		if d == (Dimension{}) {
+			return d, nil
+		}
+		exp, ok := constantValue(n.args[1])
+		if !ok {
+			return d, fmt.Errorf("exponent of %s must be a number", t.text(n))
+		}
+		return powerDimension(t.text(n), d, exp)
+	}
+	switch n.op {
+	case '+', '-':
+		if rhs != d {
+			return d, fmt.Errorf("dimension mismatch: cannot %s %s and %s", map[rune]string{'+': "add", '-': "subtract"}[n.op], d, rhs)
+		}
+		return d, nil
+	case '/':
+		return d.mul(rhs, -1), nil
+	}
+	return d.mul(rhs, 1), nil
+}
+
+// powerDimension is the dimension of expr, a quantity of dimension d raised
+// to exp; only a dimensionless quantity takes a non-integer power
+func powerDimension(expr string, d Dimension, exp float64) (Dimension, error) {
+	if d == (Dimension{}) {
+		return d, nil
+	}
+	n := int(exp)
+	if float64(n) != exp {
+		return Dimension{}, fmt.Errorf("%s raises a dimensional quantity to the non-integer power %g", expr, exp)
+	}
+	return d.pow(n), nil
+}
+
+// functionDimension is the dimension of a function of exprFunctions applied
+// to an argument of dimension arg
+func functionDimension(name string, arg Dimension) (Dimension, error) {
+	if name != "sqrt" {
+		if arg != (Dimension{}) {
+			return Dimension{}, fmt.Errorf("%s needs a dimensionless argument, got %s", name, arg)
+		}
+		return arg, nil
+	}
+	var half Dimension
+	for i, n := range arg {
+		if n%2 != 0 {
+			return half, fmt.Errorf("sqrt of %s has a fractional dimension", arg)
+		}
+		half[i] = n / 2
+	}
+	return half, nil
+}
+
+func (p *PhysicsDecoderService) handleDimensions(w http.ResponseWriter, r *http.Request) {
+	var req DimensionsRequest
+	if err := jsonbody.Decode(r.Body, &req, jsonbody.Strict); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	response, err := p.AnalyzeDimensions(req)
+	if err != nil {
+		apierr.Respond(w, apierr.CodeValidation, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// namedUnitDimensions are unit symbols outside unitTable that compound
+// units are commonly written with
+var namedUnitDimensions = map[string]string{
+	"N":   "MLT⁻²",
+	"Pa":  "ML⁻¹T⁻²",
+	"A":   "I",
+	"C":   "TI",
+	"V":   "ML²T⁻³I⁻¹",
+	"mol": "N",
+	"cd":  "J",
+}
+
+// UnitDimension returns the dimension of a unit: a symbol of unitTable, or
+// a product or quotient of symbols with integer powers, such as "m/s",
+// "J⋅s", "W/m²" or "kg·m^2/s^2"
+func UnitDimension(unit string) (Dimension, error) {
+	_, d, err := unitScale(unit)
+	return d, err
+}
+
+// unitScale returns the factor taking a value in unit to SI, with the
+// unit's dimension. The factor of a logarithmic unit is 0 and offsets are
+// ignored, so it converts only linear units and temperature differences.
+func unitScale(unit string) (float64, Dimension, error) {
+	unit = strings.ReplaceAll(unit, " ", "")
+	if scale, d, ok := symbolUnit(unit); ok {
+		return scale, d, nil
+	}
+
+	scale, d := 1.0, Dimension{}
+	sign := 1
+	start := 0
+	runes := []rune(unit)
+	for i := 0; i <= len(runes); i++ {
+		if i < len(runes) && !strings.ContainsRune("/⋅·*", runes[i]) {
+			continue
+		}
+		factor, dim, err := unitFactor(string(runes[start:i]))
+		if err != nil {
+			return 0, Dimension{}, fmt.Errorf("unit %q: %v", unit, err)
+		}
+		scale *= math.Pow(factor, float64(sign))
+		d = d.mul(dim, sign)
+		if i < len(runes) && runes[i] == '/' {
+			sign = -1
+		}
+		start = i + 1
+	}
+	return scale, d, nil
+}
+
+// symbolUnit looks up a single unit symbol
+func symbolUnit(symbol string) (float64, Dimension, bool) {
+	scale := 1.0
+	notation, ok := namedUnitDimensions[symbol]
+	if !ok {
+		quantity, known := quantityOf(symbol)
+		if !known {
+			return 0, Dimension{}, false
+		}
+		u, _ := lookupUnit(quantity, symbol)
+		scale, notation = u.SIFactor, quantityDimensions[quantity]
+	}
+	d, err := ParseDimension(notation)
+	return scale, d, err == nil
+}
+
+// unitFactor is one symbol of a compound unit, raised to the power that
+// follows it as superscripts or after a caret
+func unitFactor(factor string) (float64, Dimension, error) {
+	runes := []rune(factor)
+	end := len(runes)
+	for end > 0 {
+		if _, ok := superscripts[runes[end-1]]; !ok {
+			break
+		}
+		end--
+	}
+	symbol, exponent := string(runes[:end]), ""
+	for _, r := range runes[end:] {
+		exponent += string(superscripts[r])
+	}
+	if i := strings.IndexRune(symbol, '^'); i >= 0 {
+		symbol, exponent = symbol[:i], symbol[i+1:]
+	}
+
+	scale, d, ok := symbolUnit(symbol)
+	if !ok {
+		return 0, Dimension{}, fmt.Errorf("unknown unit symbol %q", symbol)
+	}
+	if exponent == "" {
+		return scale, d, nil
+	}
+	n, err := strconv.Atoi(exponent)
+	if err != nil {
+		return 0, Dimension{}, fmt.Errorf("invalid exponent %q of %s", exponent, symbol)
+	}
+	return math.Pow(scale, float64(n)), d.pow(n), nil
+}
+
+// siUnitSymbols are the base units written for each of baseDimensions
+var siUnitSymbols = []string{"kg", "m", "s", "K", "A", "mol", "cd"}
+
+// siUnit names the SI unit of a dimension: the SI unit of a quantity of
+// unitTable or a named unit where one has it, otherwise the product of
+// base units, e.g. "kg·m·s⁻¹"
+func siUnit(d Dimension) string {
+	quantities := make([]string, 0, len(unitTable))
+	for quantity := range unitTable {
+		quantities = append(quantities, quantity)
+	}
+	sort.Strings(quantities)
+	for _, quantity := range quantities {
+		if q, err := ParseDimension(quantityDimensions[quantity]); err == nil && q == d {
+			return unitTable[quantity][0].Symbol
+		}
+	}
+	symbols := make([]string, 0, len(namedUnitDimensions))
+	for symbol := range namedUnitDimensions {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		if n, err := ParseDimension(namedUnitDimensions[symbol]); err == nil && n == d {
+			return symbol
+		}
+	}
+
+	var factors []string
+	for i, n := range d {
+		if n == 0 {
+			continue
+		}
+		var unit Dimension
+		unit[i] = n
+		// String writes the exponent; swap the base dimension for the unit
+		factors = append(factors, siUnitSymbols[i]+strings.TrimPrefix(unit.String(), string(baseDimensions[i])))
+	}
+	return strings.Join(factors, "·")
+}
+
+// formulaSignature is the dimensional form of one variant of a formula
+type formulaSignature struct {
+	requires string // the variable whose presence selects this variant, as the calculator checks
+	expr     string // over the formula's variables and physicalConstants
+	result   string // the dimension the result must have
+}
+
+// formulaSignatures are the variants of each formula, in the order the
+// calculators try them
+var formulaSignatures = map[string][]formulaSignature{
+	"energy_mass":              {{"m", "m*c^2", "ML²T⁻²"}},
+	"relativistic_energy":      {{"m", "sqrt((p*c)^2+(m*c^2)^2)", "ML²T⁻²"}},
+	"wavelength_frequency":     {{"f", "c/f", "L"}, {"λ", "c/λ", "T⁻¹"}},
+	"photon_energy":            {{"f", "h*f", "ML²T⁻²"}},
+	"photon_energy_wavelength": {{"λ", "h*c/λ", "ML²T⁻²"}},
+	"thermal_energy":           {{"T", "k*T", "ML²T⁻²"}},
+	"optical_power":            {{"E", "E/t", "ML²T⁻³"}, {"I", "I*A", "ML²T⁻³"}},
+	"insertion_loss":           {{"P_in", "log(P_in/P_out)", "1"}},
+	"attenuated_power":         {{"P_in", "P_in*exp(L)", "ML²T⁻³"}},
+}
+
+// physicalConstants are the dimensions of the constants the calculators
+// supply themselves, whatever unit a request gives them
+var physicalConstants = map[string]string{
+	"c": "LT⁻¹",
+	"h": "ML²T⁻¹",
+	"k": "ML²T⁻²Θ⁻¹",
+}
+
+// checkDimensions derives each input's dimension from its unit, falling
+// back to the formula's unit for inputs without one, and checks that they
+// combine to the dimension of the formula's result. Units it does not
+// know are left to the calculators to reject.
+func (p *PhysicsDecoderService) checkDimensions(formula string, req DecoderRequest) error {
+	var sig *formulaSignature
+	for i, s := range formulaSignatures[formula] {
+		if _, ok := req.Variables[s.requires]; ok {
+			sig = &formulaSignatures[formula][i]
+			break
+		}
+	}
+	if sig == nil {
+		return nil // the calculator reports the missing variable
+	}
+
+	var defaults map[string]string
+	for _, info := range p.GetFormulas() {
+		if info.ID == formula {
+			defaults = info.Units
+			break
+		}
+	}
+	vars := make(map[string]Dimension, len(defaults))
+	for name, unit := range defaults {
+		if d, err := UnitDimension(unit); err == nil {
+			vars[name] = d
+		}
+	}
+	for name, unit := range req.Units {
+		if _, constant := physicalConstants[name]; constant {
+			continue
+		}
+		if d, err := UnitDimension(unit); err == nil {
+			vars[name] = d
+		}
+	}
+	for name, notation := range physicalConstants {
+		vars[name], _ = ParseDimension(notation)
+	}
+
+	got, err := dimensionOf(sig.expr, vars)
+	if err != nil {
+		return fmt.Errorf("dimensional mismatch: %s", strings.TrimPrefix(err.Error(), "dimension mismatch: "))
+	}
+	if expected, _ := ParseDimension(sig.result); got != expected {
+		return fmt.Errorf("dimensional mismatch: got %s, expected %s", got, expected)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseDimensionRoundTrip(t *testing.T) {
+	for in, want := range map[string]string{
+		"ML²T⁻²":   "ML²T⁻²",
+		"ML^2T^-2": "ML²T⁻²",
+		"LT⁻¹":     "LT⁻¹",
+		"1":        "1",
+		"":         "1",
+	} {
+		d, err := ParseDimension(in)
+		if err != nil {
+			t.Errorf("ParseDimension(%q): %v", in, err)
+			continue
+		}
+		if got := d.String(); got != want {
+			t.Errorf("ParseDimension(%q) = %s, want %s", in, got, want)
+		}
+	}
+	if _, err := ParseDimension("MX"); err == nil {
+		t.Error("unknown base dimension accepted")
+	}
+}
+
+func TestAnalyzeDimensions(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	for _, tc := range []struct {
+		formula string
+		dims    map[string]string
+		want    string
+	}{
+		{"m*c^2", map[string]string{"m": "M", "c": "LT⁻¹"}, "ML²T⁻²"},
+		{"E = m*c^2", map[string]string{"E": "ML²T⁻²", "m": "M", "c": "LT⁻¹"}, "ML²T⁻²"},
+		{"sqrt(2*g*h)", map[string]string{"g": "LT⁻²", "h": "L"}, "LT⁻¹"},
+		{"exp(-t/tau)", map[string]string{"t": "T", "tau": "T"}, "1"},
+		{"1/(x^(-2))", map[string]string{"x": "L"}, "L²"},
+	} {
+		resp, err := p.AnalyzeDimensions(DimensionsRequest{Formula: tc.formula, Dimensions: tc.dims})
+		if err != nil {
+			t.Errorf("%s: %v", tc.formula, err)
+			continue
+		}
+		if resp.Dimension != tc.want {
+			t.Errorf("%s = %s, want %s", tc.formula, resp.Dimension, tc.want)
+		}
+	}
+}
+
+func TestAnalyzeDimensionsRejectsMismatches(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	for formula, wantErr := range map[string]string{
+		"m + c":        "mismatch",
+		"E = m*c":      "mismatch",
+		"sin(m)":       "dimensionless",
+		"sqrt(m)":      "fractional",
+		"m * unknownv": "unknownv",
+		"m *":          "",
+	} {
+		_, err := p.AnalyzeDimensions(DimensionsRequest{Formula: formula, Dimensions: map[string]string{"m": "M", "c": "LT⁻¹", "E": "ML²T⁻²"}})
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%s: err = %v, want one mentioning %q", formula, err, wantErr)
+		}
+	}
+}
+
+func TestDimensionsEndpoint(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	for body, want := range map[string]int{
+		`{"formula":"m*c^2","dimensions":{"m":"M","c":"LT⁻¹"}}`: http.StatusOK,
+		`{"formula":"m+c","dimensions":{"m":"M","c":"LT⁻¹"}}`:   http.StatusBadRequest,
+		`{"formula":`: http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		p.handleDimensions(rec, httptest.NewRequest(http.MethodPost, "/v1/physics/dimensions", bytes.NewBufferString(body)))
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", body, rec.Code, want)
+			continue
+		}
+		if want == http.StatusOK {
+			var resp DimensionsResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Dimension != "ML²T⁻²" {
+				t.Errorf("response = %s", rec.Body)
+			}
+		}
+	}
+}
+
+func TestUnitDimension(t *testing.T) {
+	for unit, want := range map[string]string{
+		"kg":         "M",
+		"THz":        "T⁻¹",
+		"m/s":        "LT⁻¹",
+		"J⋅s":        "ML²T⁻¹",
+		"W/m²":       "MT⁻³",
+		"kg·m^2/s^2": "ML²T⁻²",
+		"dBm":        "ML²T⁻³",
+	} {
+		d, err := UnitDimension(unit)
+		if err != nil {
+			t.Errorf("UnitDimension(%q): %v", unit, err)
+			continue
+		}
+		if got := d.String(); got != want {
+			t.Errorf("UnitDimension(%q) = %s, want %s", unit, got, want)
+		}
+	}
+	for _, unit := range []string{"furlong", "m^x", "kg/"} {
+		if _, err := UnitDimension(unit); err == nil {
+			t.Errorf("UnitDimension(%q) accepted", unit)
+		}
+	}
+}
+
+func TestCalculateChecksInputDimensions(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	resp, err := p.Calculate(DecoderRequest{Formula: "E = mc²", Variables: map[string]float64{"m": 1}, Units: map[string]string{"m": "Hz"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Valid || resp.Error != "dimensional mismatch: got L²T⁻³, expected ML²T⁻²" {
+		t.Errorf("frequency as mass: valid %v, error %q", resp.Valid, resp.Error)
+	}
+
+	for name, req := range map[string]DecoderRequest{
+		"default units":  {Formula: "E = mc²", Variables: map[string]float64{"m": 1}},
+		"scaled unit":    {Formula: "E=hf", Variables: map[string]float64{"f": 193}, Units: map[string]string{"f": "THz"}},
+		"second variant": {Formula: "λ=c/f", Variables: map[string]float64{"λ": 1550}, Units: map[string]string{"λ": "nm"}},
+	} {
+		resp, err := p.Calculate(req)
+		if err != nil || !resp.Valid {
+			t.Errorf("%s: %v %q", name, err, resp.Error)
+		}
+	}
+}
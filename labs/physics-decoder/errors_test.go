@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+func TestErrorCodes(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	for _, tc := range []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		code    apierr.Code
+		status  int
+	}{
+		{"malformed calculate", p.handleCalculate, "{", apierr.CodeBadRequest, http.StatusBadRequest},
+		{"malformed convert", p.handleConvert, `{"value":"x"}`, apierr.CodeBadRequest, http.StatusBadRequest},
+		{"mismatched quantities", p.handleConvert, `{"value":1,"from":"J","to":"m"}`, apierr.CodeValidation, http.StatusBadRequest},
+		{"unknown unit", p.handleConvert, `{"value":1,"from":"furlong","to":"m"}`, apierr.CodeValidation, http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		tc.handler(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tc.body)))
+		var e apierr.Error
+		if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+			t.Fatalf("%s: error body %q: %v", tc.name, rec.Body, err)
+		}
+		if rec.Code != tc.status || e.Code != tc.code {
+			t.Errorf("%s: %d %+v, want %d %s", tc.name, rec.Code, e, tc.status, tc.code)
+		}
+	}
+}
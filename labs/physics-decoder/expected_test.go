@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+)
+
+func graded(t *testing.T, expected float64, unit string, tolerance float64) *DecoderResponse {
+	t.Helper()
+	resp, err := NewPhysicsDecoderService().Calculate(DecoderRequest{
+		Formula:      "E=mc^2",
+		Variables:    map[string]float64{"m": 1},
+		Expected:     &expected,
+		ExpectedUnit: unit,
+		Tolerance:    tolerance,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestExpectedWithinTolerance(t *testing.T) {
+	resp := graded(t, 8.99e16, "", 1e-3)
+	if !resp.Valid || resp.Matches == nil || !*resp.Matches {
+		t.Fatalf("8.99e16 J within 0.1%%: %+v", resp)
+	}
+	if *resp.RelativeError > 1e-3 {
+		t.Errorf("relative error = %g", *resp.RelativeError)
+	}
+
+	// The expected value is converted into the result's unit first.
+	if resp := graded(t, 8.98755178737e13, "kJ", 1e-9); resp.Matches == nil || !*resp.Matches {
+		t.Errorf("expected given in kJ did not match: %+v", resp)
+	}
+}
+
+func TestExpectedOutOfTolerance(t *testing.T) {
+	resp := graded(t, 9.2e16, "", 1e-3)
+	if !resp.Valid || resp.Matches == nil || *resp.Matches {
+		t.Fatalf("9.2e16 J within 0.1%%: %+v", resp)
+	}
+	if *resp.RelativeError < 0.02 {
+		t.Errorf("relative error = %g, want about 0.024", *resp.RelativeError)
+	}
+}
+
+func TestExpectedRejectsIncompatibleUnit(t *testing.T) {
+	if resp := graded(t, 1, "kg", 0); resp.Valid || resp.Matches != nil {
+		t.Errorf("expected in kg compared against joules: %+v", resp)
+	}
+}
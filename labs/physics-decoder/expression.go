@@ -0,0 +1,540 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// expressionConstants are the constants an expression may name without
+// supplying them as variables, with the step description of each
+var expressionConstants = map[string]string{
+	"c":   "Speed of light",
+	"h":   "Planck constant",
+	"k":   "Boltzmann constant",
+	"e":   "Electron charge",
+	"N_A": "Avogadro number",
+	"π":   "Pi",
+	"pi":  "Pi",
+}
+
+// exprFunction is a function an expression may apply to a parenthesized
+// argument
+type exprFunction struct {
+	description string
+	apply       func(float64) float64
+}
+
+var exprFunctions = map[string]exprFunction{
+	"sqrt":  {"Square root", math.Sqrt},
+	"exp":   {"Exponential", math.Exp},
+	"ln":    {"Natural logarithm", math.Log},
+	"log":   {"Common logarithm", math.Log10},
+	"log10": {"Common logarithm", math.Log10},
+	"sin":   {"Sine", math.Sin},
+	"cos":   {"Cosine", math.Cos},
+	"tan":   {"Tangent", math.Tan},
+}
+
+// exprKind is what a node of a parsed expression is
+type exprKind int
+
+const (
+	exprNumber exprKind = iota
+	exprName
+	exprNegate // of args[0]
+	exprBinary // args[0] op args[1]
+	exprPower  // args[0] raised to args[1]
+	exprCall   // function name applied to args[0]
+)
+
+// exprNode is a node of a parsed expression. Its source runs from start to
+// end, so steps and errors can quote the sub-expression.
+type exprNode struct {
+	kind       exprKind
+	op         rune // + - * or / of a binary node
+	value      float64
+	name       string
+	args       []*exprNode
+	start, end int
+}
+
+// exprTree is a parsed expression, both evaluated by exprEvaluator and
+// given a dimension by dimensionOf
+type exprTree struct {
+	src  []rune
+	root *exprNode
+}
+
+// text is the source of a node
+func (t *exprTree) text(n *exprNode) string {
+	return strings.TrimSpace(string(t.src[n.start:n.end]))
+}
+
+// parseExpression parses an arithmetic expression over numbers, names,
+// + - * / ^, superscript powers, parentheses and exprFunctions
+func parseExpression(expr string) (*exprTree, error) {
+	ps := &exprParser{src: []rune(operatorVariants.Replace(expr))}
+	root, err := ps.expr()
+	if err != nil {
+		return nil, err
+	}
+	if r := ps.peek(); r != 0 {
+		return nil, fmt.Errorf("unexpected %q at position %d", r, ps.pos)
+	}
+	return &exprTree{src: ps.src, root: root}, nil
+}
+
+// parseEquation parses an expression or an equation "name = expression",
+// returning the name an equation assigns
+func parseEquation(formula string) (string, *exprTree, error) {
+	lhs, rhs, ok := strings.Cut(operatorVariants.Replace(formula), "=")
+	if !ok {
+		tree, err := parseExpression(formula)
+		return "", tree, err
+	}
+	if lhs = strings.TrimSpace(lhs); !isIdentifier(lhs) {
+		return "", nil, fmt.Errorf("left side of %q must be a single name", strings.TrimSpace(formula))
+	}
+	tree, err := parseExpression(rhs)
+	return lhs, tree, err
+}
+
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// exprParser is a recursive-descent parser building an exprTree
+type exprParser struct {
+	src []rune
+	pos int
+}
+
+// peek returns the next non-space rune, 0 at the end
+func (ps *exprParser) peek() rune {
+	for ps.pos < len(ps.src) && unicode.IsSpace(ps.src[ps.pos]) {
+		ps.pos++
+	}
+	if ps.pos < len(ps.src) {
+		return ps.src[ps.pos]
+	}
+	return 0
+}
+
+// node returns a node whose source runs from start to the current position
+func (ps *exprParser) node(n exprNode, start int) *exprNode {
+	n.start, n.end = start, ps.pos
+	return &n
+}
+
+// expr := term (('+' | '-') term)*
+func (ps *exprParser) expr() (*exprNode, error) {
+	start := ps.pos
+	n, err := ps.term()
+	if err != nil {
+		return nil, err
+	}
+	for op := ps.peek(); op == '+' || op == '-'; op = ps.peek() {
+		ps.pos++
+		rhs, err := ps.term()
+		if err != nil {
+			return nil, err
+		}
+		n = ps.node(exprNode{kind: exprBinary, op: op, args: []*exprNode{n, rhs}}, start)
+	}
+	return n, nil
+}
+
+// term := unary (('*' | '/' | '×' | '·' | '⋅') unary)*
+func (ps *exprParser) term() (*exprNode, error) {
+	start := ps.pos
+	n, err := ps.unary()
+	if err != nil {
+		return nil, err
+	}
+	for op := ps.peek(); op == '/' || strings.ContainsRune(multiplicationSigns, op); op = ps.peek() {
+		ps.pos++
+		rhs, err := ps.unary()
+		if err != nil {
+			return nil, err
+		}
+		if op != '/' {
+			op = '*'
+		}
+		n = ps.node(exprNode{kind: exprBinary, op: op, args: []*exprNode{n, rhs}}, start)
+	}
+	return n, nil
+}
+
+// unary := ('-' | '+') unary | power
+func (ps *exprParser) unary() (*exprNode, error) {
+	op := ps.peek()
+	if op != '-' && op != '+' {
+		return ps.power()
+	}
+	start := ps.pos
+	ps.pos++
+	n, err := ps.unary()
+	if err != nil || op == '+' {
+		return n, err
+	}
+	return ps.node(exprNode{kind: exprNegate, args: []*exprNode{n}}, start), nil
+}
+
+// power := primary ('^' unary | superscripts)?, right-associative so that
+// a^b^c is a^(b^c)
+func (ps *exprParser) power() (*exprNode, error) {
+	start := ps.pos
+	base, err := ps.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	var exp *exprNode
+	switch r := ps.peek(); {
+	case r == '^':
+		ps.pos++
+		if exp, err = ps.unary(); err != nil {
+			return nil, err
+		}
+	case superscripts[r] != 0:
+		expStart := ps.pos
+		var digits strings.Builder
+		for ps.pos < len(ps.src) && superscripts[ps.src[ps.pos]] != 0 {
+			digits.WriteRune(superscripts[ps.src[ps.pos]])
+			ps.pos++
+		}
+		n, err := strconv.Atoi(digits.String())
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent %q at position %d", digits.String(), start)
+		}
+		exp = ps.node(exprNode{kind: exprNumber, value: float64(n)}, expStart)
+	default:
+		return base, nil
+	}
+	return ps.node(exprNode{kind: exprPower, args: []*exprNode{base, exp}}, start), nil
+}
+
+// primary := number | name | function '(' expr ')' | '(' expr ')'
+func (ps *exprParser) primary() (*exprNode, error) {
+	r := ps.peek()
+	start := ps.pos
+	switch {
+	case r == '(':
+		ps.pos++
+		n, err := ps.expr()
+		if err != nil {
+			return nil, err
+		}
+		if ps.peek() != ')' {
+			return nil, fmt.Errorf("missing ')' at position %d", ps.pos)
+		}
+		ps.pos++
+		return n, nil
+
+	case unicode.IsDigit(r) || r == '.':
+		for ps.pos < len(ps.src) && (unicode.IsDigit(ps.src[ps.pos]) || strings.ContainsRune(".eE", ps.src[ps.pos]) ||
+			(strings.ContainsRune("+-", ps.src[ps.pos]) && strings.ContainsRune("eE", ps.src[ps.pos-1]))) {
+			ps.pos++
+		}
+		value, err := strconv.ParseFloat(string(ps.src[start:ps.pos]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", string(ps.src[start:ps.pos]), start)
+		}
+		return ps.node(exprNode{kind: exprNumber, value: value}, start), nil
+
+	case unicode.IsLetter(r) || r == '_':
+		for ps.pos < len(ps.src) && (unicode.IsLetter(ps.src[ps.pos]) || unicode.IsDigit(ps.src[ps.pos]) || ps.src[ps.pos] == '_') {
+			ps.pos++
+		}
+		name := string(ps.src[start:ps.pos])
+		if ps.peek() != '(' {
+			return ps.node(exprNode{kind: exprName, name: name}, start), nil
+		}
+		if _, ok := exprFunctions[name]; !ok {
+			return nil, fmt.Errorf("unknown function %s", name)
+		}
+		arg, err := ps.primary()
+		if err != nil {
+			return nil, err
+		}
+		return ps.node(exprNode{kind: exprCall, name: name, args: []*exprNode{arg}}, start), nil
+
+	case r == 0:
+		return nil, fmt.Errorf("unexpected end of formula")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", r, ps.pos)
+}
+
+// constantValue evaluates a node built only from numbers, for exponents
+// that must be known without the variables
+func constantValue(n *exprNode) (float64, bool) {
+	switch n.kind {
+	case exprNumber:
+		return n.value, true
+	case exprNegate:
+		v, ok := constantValue(n.args[0])
+		return -v, ok
+	case exprBinary, exprPower:
+		a, okA := constantValue(n.args[0])
+		b, okB := constantValue(n.args[1])
+		if !okA || !okB {
+			return 0, false
+		}
+		switch {
+		case n.kind == exprPower:
+			return math.Pow(a, b), true
+		case n.op == '+':
+			return a + b, true
+		case n.op == '-':
+			return a - b, true
+		case n.op == '*':
+			return a * b, true
+		}
+		return a / b, true
+	}
+	return 0, false
+}
+
+// exprValue is the value of a sub-expression in SI, with its dimension
+// when every variable it uses has a unit
+type exprValue struct {
+	value float64
+	dim   Dimension
+	known bool
+}
+
+// exprEvaluator evaluates an exprTree over a request's variables,
+// recording a calculation step per input and per operation
+type exprEvaluator struct {
+	p     *PhysicsDecoderService
+	tree  *exprTree
+	vars  map[string]float64
+	units map[string]string
+	seen  map[string]bool
+	steps []CalculationStep
+}
+
+// calculateExpression evaluates an arbitrary algebraic expression, or an
+// equation "name = expression", over the request's variables. Variables
+// with a unit are converted to SI first; the result has an SI unit only
+// when every variable it uses has one.
+func (p *PhysicsDecoderService) calculateExpression(formula string, vars map[string]float64, units map[string]string) (float64, []CalculationStep, error) {
+	lhs, tree, err := parseEquation(formula)
+	if err != nil {
+		return 0, nil, err
+	}
+	ev := &exprEvaluator{p: p, tree: tree, vars: vars, units: units, seen: make(map[string]bool)}
+	v, err := ev.eval(tree.root)
+	if err != nil {
+		return 0, nil, err
+	}
+	if math.IsNaN(v.value) || math.IsInf(v.value, 0) {
+		return 0, nil, fmt.Errorf("expression %s is not finite", strings.TrimSpace(formula))
+	}
+
+	// The last operation is the whole expression unless it is a lone
+	// name or number
+	whole := strings.TrimSpace(string(tree.src))
+	if len(ev.steps) == 0 || ev.steps[len(ev.steps)-1].Formula != whole {
+		ev.step("Expression result", tree.root, v)
+		ev.steps[len(ev.steps)-1].Formula = whole
+	}
+	if lhs != "" {
+		ev.steps[len(ev.steps)-1].Description = lhs + " calculation"
+	}
+	return v.value, ev.steps, nil
+}
+
+// step records the operation of a node
+func (ev *exprEvaluator) step(description string, n *exprNode, v exprValue) {
+	unit := ""
+	if v.known {
+		unit = siUnit(v.dim)
+	}
+	ev.steps = append(ev.steps, CalculationStep{
+		Description: description,
+		Value:       v.value,
+		Unit:        unit,
+		Formula:     ev.tree.text(n),
+	})
+}
+
+// eval evaluates a node, its operands first
+func (ev *exprEvaluator) eval(n *exprNode) (exprValue, error) {
+	switch n.kind {
+	case exprNumber:
+		return exprValue{value: n.value, known: true}, nil
+	case exprName:
+		return ev.variable(n.name)
+	}
+
+	args := make([]exprValue, len(n.args))
+	for i, arg := range n.args {
+		v, err := ev.eval(arg)
+		if err != nil {
+			return v, err
+		}
+		args[i] = v
+	}
+	switch n.kind {
+	case exprNegate:
+		v := args[0]
+		v.value = -v.value
+		ev.step("Negation", n, v)
+		return v, nil
+	case exprPower:
+		return ev.power(n, args[0], args[1])
+	case exprCall:
+		return ev.call(n, args[0])
+	}
+
+	v, rhs := args[0], args[1]
+	switch n.op {
+	case '+', '-':
+		if v.known && rhs.known && v.dim != rhs.dim {
+			return v, fmt.Errorf("dimensional mismatch: cannot %s %s and %s", map[rune]string{'+': "add", '-': "subtract"}[n.op], v.dim, rhs.dim)
+		}
+		v.known = v.known && rhs.known
+		if n.op == '+' {
+			v.value += rhs.value
+			ev.step("Sum", n, v)
+		} else {
+			v.value -= rhs.value
+			ev.step("Difference", n, v)
+		}
+	case '/':
+		if rhs.value == 0 {
+			return v, fmt.Errorf("division by zero in %s", ev.tree.text(n))
+		}
+		v.value /= rhs.value
+		v.dim = v.dim.mul(rhs.dim, -1)
+		v.known = v.known && rhs.known
+		ev.step("Quotient", n, v)
+	default:
+		v.value *= rhs.value
+		v.dim = v.dim.mul(rhs.dim, 1)
+		v.known = v.known && rhs.known
+		ev.step("Product", n, v)
+	}
+	return v, nil
+}
+
+func (ev *exprEvaluator) power(n *exprNode, base, exp exprValue) (exprValue, error) {
+	if exp.known && exp.dim != (Dimension{}) {
+		return base, fmt.Errorf("exponent of %s must be dimensionless, got %s", ev.tree.text(n), exp.dim)
+	}
+	v := exprValue{value: math.Pow(base.value, exp.value), known: base.known && exp.known}
+	if base.known {
+		dim, err := powerDimension(ev.tree.text(n), base.dim, exp.value)
+		if err != nil {
+			return base, err
+		}
+		v.dim = dim
+	}
+	ev.step("Power", n, v)
+	return v, nil
+}
+
+func (ev *exprEvaluator) call(n *exprNode, arg exprValue) (exprValue, error) {
+	v := exprValue{value: exprFunctions[n.name].apply(arg.value), known: arg.known}
+	if arg.known {
+		dim, err := functionDimension(n.name, arg.dim)
+		if err != nil {
+			return arg, err
+		}
+		v.dim = dim
+	}
+	ev.step(exprFunctions[n.name].description, n, v)
+	return v, nil
+}
+
+// variable looks a name up among the request's variables, converting it to
+// SI by its unit, and then among expressionConstants
+func (ev *exprEvaluator) variable(name string) (exprValue, error) {
+	value, ok := ev.vars[name]
+	if !ok {
+		return ev.constant(name)
+	}
+
+	v := exprValue{value: value}
+	unit, given := ev.units[name]
+	if given {
+		si, dim, err := expressionToSI(value, unit)
+		if err != nil {
+			return v, fmt.Errorf("variable %s: %v", name, err)
+		}
+		v = exprValue{value: si, dim: dim, known: true}
+	}
+	if !ev.seen[name] {
+		ev.seen[name] = true
+		step := CalculationStep{Description: name, Value: v.value}
+		if v.known {
+			step.Unit = siUnit(v.dim)
+			step.Description = fmt.Sprintf("%s in %s", name, step.Unit)
+		}
+		ev.steps = append(ev.steps, step)
+	}
+	return v, nil
+}
+
+// constant supplies a name of expressionConstants the request did not give
+// as a variable
+func (ev *exprEvaluator) constant(name string) (exprValue, error) {
+	description, ok := expressionConstants[name]
+	if !ok {
+		return exprValue{}, fmt.Errorf("variable '%s' not provided", name)
+	}
+
+	var value float64
+	var notation string
+	switch name {
+	case "c":
+		value, notation = ev.p.SpeedOfLight, physicalConstants["c"]
+	case "h":
+		value, notation = ev.p.PlanckConstant, physicalConstants["h"]
+	case "k":
+		value, notation = ev.p.BoltzmannConstant, physicalConstants["k"]
+	case "e":
+		value, notation = ev.p.ElectronCharge, "TI"
+	case "N_A":
+		value, notation = ev.p.AvogadroNumber, "N⁻¹"
+	default:
+		value, notation = math.Pi, "1"
+	}
+	dim, _ := ParseDimension(notation)
+	if !ev.seen[name] {
+		ev.seen[name] = true
+		ev.steps = append(ev.steps, CalculationStep{Description: description, Value: value, Unit: siUnit(dim)})
+	}
+	return exprValue{value: value, dim: dim, known: true}, nil
+}
+
+// expressionToSI converts a variable of an expression to SI: a unit of
+// unitTable as the named formulas convert it, or a compound of linear units
+// such as "m/s" or "kg·m²"
+func expressionToSI(value float64, unit string) (float64, Dimension, error) {
+	if quantity, ok := quantityOf(unit); ok {
+		si, err := toSI(quantity, value, unit)
+		if err != nil {
+			return 0, Dimension{}, err
+		}
+		dim, err := ParseDimension(quantityDimensions[quantity])
+		return si, dim, err
+	}
+	scale, dim, err := unitScale(unit)
+	if err != nil {
+		return 0, Dimension{}, err
+	}
+	if scale == 0 {
+		return 0, Dimension{}, fmt.Errorf("unit %q: a logarithmic unit cannot be part of a compound unit", unit)
+	}
+	return value * scale, dim, nil
+}
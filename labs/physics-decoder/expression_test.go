@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestExpressionKineticEnergy(t *testing.T) {
+	resp, err := NewPhysicsDecoderService().Calculate(DecoderRequest{
+		Formula:   "0.5*m*v^2",
+		Variables: map[string]float64{"m": 2, "v": 3},
+		Units:     map[string]string{"m": "kg", "v": "m/s"},
+	})
+	if err != nil || !resp.Valid {
+		t.Fatalf("calculate: %v %q", err, resp.Error)
+	}
+	if resp.Result != 9 || resp.Unit != "J" || resp.Dimensions["result"] != "ML²T⁻²" {
+		t.Errorf("result = %g %s %v, want 9 J ML²T⁻²", resp.Result, resp.Unit, resp.Dimensions)
+	}
+
+	var got []string
+	for _, s := range resp.Steps {
+		got = append(got, s.Description+" "+s.Formula)
+	}
+	want := []string{"m in kg ", "Product 0.5*m", "v in m·s⁻¹ ", "Power v^2", "Product 0.5*m*v^2"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("steps = %q, want %q", got, want)
+	}
+}
+
+func TestOnlyExactSpellingsAreNamedFormulas(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	named, err := p.Calculate(DecoderRequest{Formula: "E = m*c^2", Variables: map[string]float64{"m": 2}})
+	if err != nil || !named.Valid || named.Steps[len(named.Steps)-1].Description != "Energy calculation" {
+		t.Fatalf("E = m*c^2 did not match energy_mass: %v %+v", err, named)
+	}
+
+	// Each contains a named formula's spelling or keyword, so none may be
+	// read as that formula
+	for formula, want := range map[string]float64{
+		"E = m*c^2/2":       named.Result / 2,
+		"P_x = power_in*2":  6,
+		"photon_count + 1":  4,
+		"wavelength_nm/100": 15.5,
+	} {
+		resp, err := p.Calculate(DecoderRequest{Formula: formula, Variables: map[string]float64{"m": 2, "power_in": 3, "photon_count": 3, "wavelength_nm": 1550}})
+		if err != nil || !resp.Valid {
+			t.Errorf("%s: %v %q", formula, err, resp.Error)
+			continue
+		}
+		if math.Abs(resp.Result-want) > 1e-9*math.Abs(want) {
+			t.Errorf("%s = %g, want %g", formula, resp.Result, want)
+		}
+	}
+}
+
+func TestExpressionFunctionsAndConstants(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	for formula, want := range map[string]float64{
+		"sqrt(2*g*h)":           math.Sqrt(2 * 9.81 * 20),
+		"10*log10(P_in/P_out)":  10 * math.Log10(4),
+		"exp(-t/tau)":           math.Exp(-0.5),
+		"c/λ":                   299792458 / 1550e-9,
+		"2^3^2":                 512,
+		"-(g - 1.81)×2":         -16,
+		"g² − 81":               9.81*9.81 - 81,
+		"pi*tau**2":             math.Pi * 4,
+		"ln(exp(3)) + sin(0.0)": 3,
+	} {
+		resp, err := p.Calculate(DecoderRequest{
+			Formula:   formula,
+			Variables: map[string]float64{"g": 9.81, "h": 20, "P_in": 4, "P_out": 1, "t": 1, "tau": 2, "λ": 1550e-9},
+		})
+		if err != nil || !resp.Valid {
+			t.Errorf("%s: %v %q", formula, err, resp.Error)
+			continue
+		}
+		if math.Abs(resp.Result-want) > 1e-9*math.Abs(want) {
+			t.Errorf("%s = %g, want %g", formula, resp.Result, want)
+		}
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	for formula, want := range map[string]string{
+		"m *":         "unexpected end of formula",
+		"m + (v":      "missing ')'",
+		"foo(m)":      "unknown function foo",
+		"m + x":       "variable 'x' not provided",
+		"m + t":       "dimensional mismatch: cannot add M and T",
+		"m / (v - v)": "division by zero in m / (v - v)",
+		"m^0.5":       "m^0.5 raises a dimensional quantity to the non-integer power 0.5",
+		"sin(m)":      "sin needs a dimensionless argument, got M",
+		"2*m = v":     "must be a single name",
+	} {
+		resp, err := p.Calculate(DecoderRequest{
+			Formula:   formula,
+			Variables: map[string]float64{"m": 2, "v": 3, "t": 1},
+			Units:     map[string]string{"m": "kg", "t": "s"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Valid || !strings.Contains(resp.Error, want) {
+			t.Errorf("%s: valid %v, error %q, want %q", formula, resp.Valid, resp.Error, want)
+		}
+	}
+}
+
+// The dimensional analysis endpoint reads formulas with the same parser as
+// the evaluator, so both accept the same notation
+func TestDimensionsShareTheExpressionParser(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	dims := map[string]string{"m": "M", "v": "LT⁻¹", "c": "LT⁻¹"}
+	for formula, want := range map[string]string{
+		"0.5×m·v²":      "ML²T⁻²",
+		"m*c**2":        "ML²T⁻²",
+		"m⋅v^(4/2)":     "ML²T⁻²",
+		"log10(v/c)":    "1",
+		"sqrt(m²)−m":    "M",
+		"m*v^-1*c":      "M",
+		"E = m*c^2 + 0": "",
+	} {
+		resp, err := p.AnalyzeDimensions(DimensionsRequest{Formula: formula, Dimensions: dims})
+		if want == "" {
+			if err == nil {
+				t.Errorf("%s: no error for an undeclared E", formula)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", formula, err)
+			continue
+		}
+		if resp.Dimension != want {
+			t.Errorf("%s = %s, want %s", formula, resp.Dimension, want)
+		}
+	}
+	if _, err := p.AnalyzeDimensions(DimensionsRequest{Formula: "m^x", Dimensions: map[string]string{"m": "M", "x": "1"}}); err == nil || !strings.Contains(err.Error(), "must be a number") {
+		t.Errorf("variable exponent of a dimensional base: err = %v", err)
+	}
+}
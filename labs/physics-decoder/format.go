@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// defaultSigFigs is the significant figures results are formatted to when
+// a request does not ask for a number
+const defaultSigFigs = 4
+
+// maxSigFigs is the precision of a float64, past which more figures are noise
+const maxSigFigs = 17
+
+// FormattedResult renders a result, rounded to a number of significant
+// figures and followed by its unit, in three notations: scientific
+// ("8.988×10¹⁶ J"), engineering, whose exponent is a multiple of three
+// ("89.88×10¹⁵ J"), and plain decimal ("89880000000000000 J")
+type FormattedResult struct {
+	Scientific  string `json:"scientific"`
+	Engineering string `json:"engineering"`
+	SigFigs     string `json:"sig_figs"`
+}
+
+// formatResult renders value in unit to sigFigs significant figures,
+// defaultSigFigs when zero
+func formatResult(value float64, unit string, sigFigs int) (*FormattedResult, error) {
+	if sigFigs == 0 {
+		sigFigs = defaultSigFigs
+	}
+	if sigFigs < 0 || sigFigs > maxSigFigs {
+		return nil, fmt.Errorf("sig_figs must be between 1 and %d", maxSigFigs)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("result %g cannot be formatted", value)
+	}
+
+	// strconv rounds correctly; the notations only move the decimal point
+	// within its digits
+	sign := ""
+	if value < 0 {
+		sign, value = "-", -value
+	}
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(value, 'e', sigFigs-1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	exp, _ := strconv.Atoi(exponent)
+
+	engExp := exp - ((exp%3)+3)%3
+	suffix := ""
+	if unit != "" {
+		suffix = " " + unit
+	}
+	return &FormattedResult{
+		Scientific:  sign + placePoint(digits, 1) + timesTenTo(exp) + suffix,
+		Engineering: sign + placePoint(digits, exp-engExp+1) + timesTenTo(engExp) + suffix,
+		SigFigs:     sign + placePoint(digits, exp+1) + suffix,
+	}, nil
+}
+
+// placePoint writes digits with the decimal point after the first n of
+// them, padding with zeros on either side as needed
+func placePoint(digits string, n int) string {
+	switch {
+	case n <= 0:
+		return "0." + strings.Repeat("0", -n) + digits
+	case n >= len(digits):
+		return digits + strings.Repeat("0", n-len(digits))
+	}
+	return digits[:n] + "." + digits[n:]
+}
+
+// timesTenTo writes a power of ten as ×10 with a superscript exponent,
+// nothing for 10⁰
+func timesTenTo(exp int) string {
+	if exp == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("×10")
+	for _, r := range strconv.Itoa(exp) {
+		if r == '-' {
+			b.WriteRune('⁻')
+		} else {
+			b.WriteRune(superscriptDigits[r-'0'])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFormatResult(t *testing.T) {
+	for _, tc := range []struct {
+		value           float64
+		unit            string
+		sigFigs         int
+		sci, eng, plain string
+	}{
+		{8.987551787368176e16, "J", 0, "8.988×10¹⁶ J", "89.88×10¹⁵ J", "89880000000000000 J"},
+		// BER-scale values keep their figures behind the leading zeros
+		{1e-12, "", 4, "1.000×10⁻¹²", "1.000×10⁻¹²", "0.000000000001000"},
+		{2.34567e-15, "", 3, "2.35×10⁻¹⁵", "2.35×10⁻¹⁵", "0.00000000000000235"},
+		{3.8e-5, "", 2, "3.8×10⁻⁵", "38×10⁻⁶", "0.000038"},
+		// Exact powers of ten
+		{1000, "m", 4, "1.000×10³ m", "1.000×10³ m", "1000 m"},
+		{1, "", 4, "1.000", "1.000", "1.000"},
+		{0.01, "s", 1, "1×10⁻² s", "10×10⁻³ s", "0.01 s"},
+		// Rounding carries into the next power of ten
+		{999.96, "Hz", 4, "1.000×10³ Hz", "1.000×10³ Hz", "1000 Hz"},
+		{12345, "", 2, "1.2×10⁴", "12×10³", "12000"},
+		{-0.00123456, "V", 3, "-1.23×10⁻³ V", "-1.23×10⁻³ V", "-0.00123 V"},
+		{0, "W", 2, "0.0 W", "0.0 W", "0.0 W"},
+	} {
+		f, err := formatResult(tc.value, tc.unit, tc.sigFigs)
+		if err != nil {
+			t.Errorf("%g: %v", tc.value, err)
+			continue
+		}
+		if f.Scientific != tc.sci || f.Engineering != tc.eng || f.SigFigs != tc.plain {
+			t.Errorf("%g to %d figures = %q %q %q, want %q %q %q", tc.value, tc.sigFigs, f.Scientific, f.Engineering, f.SigFigs, tc.sci, tc.eng, tc.plain)
+		}
+	}
+}
+
+func TestFormatResultErrors(t *testing.T) {
+	for _, sigFigs := range []int{-1, maxSigFigs + 1} {
+		if _, err := formatResult(1, "", sigFigs); err == nil {
+			t.Errorf("sig_figs %d accepted", sigFigs)
+		}
+	}
+	if _, err := formatResult(math.Inf(1), "", 4); err == nil {
+		t.Error("an infinite result was formatted")
+	}
+}
+
+func TestCalculateFormatsTheResult(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	resp, err := p.Calculate(DecoderRequest{Formula: "E=mc^2", Variables: map[string]float64{"m": 1}})
+	if err != nil || !resp.Valid {
+		t.Fatalf("calculate: %v %q", err, resp.Error)
+	}
+	if resp.Formatted == nil || resp.Formatted.Scientific != "8.988×10¹⁶ J" {
+		t.Errorf("formatted = %+v, want 8.988×10¹⁶ J by default", resp.Formatted)
+	}
+
+	resp, _ = p.Calculate(DecoderRequest{Formula: "E=mc^2", Variables: map[string]float64{"m": 1}, SigFigs: 2})
+	if resp.Formatted == nil || resp.Formatted.Engineering != "90×10¹⁵ J" {
+		t.Errorf("formatted to 2 figures = %+v", resp.Formatted)
+	}
+
+	resp, _ = p.Calculate(DecoderRequest{Formula: "E=mc^2", Variables: map[string]float64{"m": 1}, SigFigs: 40})
+	if resp.Valid || resp.Formatted != nil {
+		t.Errorf("sig_figs 40: valid %v, formatted %+v", resp.Valid, resp.Formatted)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/journal"
+	"github.com/gorilla/mux"
+)
+
+// defaultHistoryLength is the default number of calculations kept; the
+// oldest is dropped first
+const defaultHistoryLength = 1000
+
+// defaultHistoryLimit is how many entries GET /history returns unless
+// asked for more
+const defaultHistoryLimit = 50
+
+// Journaled history operations; a calculate entry carries its HistoryEntry
+const (
+	opCalculate    = "calculate"
+	opClearHistory = "clear"
+)
+
+// HistoryEntry is a recorded calculation
+type HistoryEntry struct {
+	ID       string          `json:"id"`
+	Time     time.Time       `json:"time"`
+	Request  DecoderRequest  `json:"request"`
+	Response DecoderResponse `json:"response"`
+}
+
+// calculationHistory keeps the latest calculations, in memory and, once
+// opened on a journal, on disk. It is safe for concurrent use; a nil
+// history records nothing.
+type calculationHistory struct {
+	// MaxEntries caps the calculations held
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries []HistoryEntry // oldest first
+	journal *journal.Journal
+}
+
+// newCalculationID returns a random calculation ID
+func newCalculationID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "calc-" + hex.EncodeToString(b)
+}
+
+// record keeps a calculation, giving its response the entry's ID
+func (h *calculationHistory) record(req DecoderRequest, response *DecoderResponse) {
+	if h == nil {
+		return
+	}
+	response.ID = newCalculationID()
+	entry := HistoryEntry{ID: response.ID, Time: time.Now().UTC(), Request: req, Response: *response}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.appendLocked(entry)
+	if _, err := h.journal.Append(opCalculate, entry.ID, entry); err != nil {
+		log.Printf("journal: %s %s: %v", opCalculate, entry.ID, err)
+	}
+}
+
+// appendLocked adds an entry, dropping the oldest beyond MaxEntries
+func (h *calculationHistory) appendLocked(entry HistoryEntry) {
+	h.entries = append(h.entries, entry)
+	if over := len(h.entries) - h.MaxEntries; h.MaxEntries > 0 && over > 0 {
+		h.entries = append(h.entries[:0:0], h.entries[over:]...)
+	}
+}
+
+// Latest returns up to limit calculations, newest first
+func (h *calculationHistory) Latest(limit int) []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	latest := make([]HistoryEntry, 0, min(limit, len(h.entries)))
+	for i := len(h.entries) - 1; i >= 0 && len(latest) < limit; i-- {
+		latest = append(latest, h.entries[i])
+	}
+	return latest
+}
+
+// Get returns a recorded calculation
+func (h *calculationHistory) Get(id string) (HistoryEntry, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, e := range h.entries {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return HistoryEntry{}, false
+}
+
+// Clear drops every recorded calculation
+func (h *calculationHistory) Clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = nil
+	if _, err := h.journal.Append(opClearHistory, "", nil); err != nil {
+		log.Printf("journal: %s: %v", opClearHistory, err)
+	}
+}
+
+// OpenHistory opens the journal at path, restores the calculation history
+// from it and records every later calculation there. Call it before
+// serving requests.
+func (p *PhysicsDecoderService) OpenHistory(path string) error {
+	j, err := journal.Open(path)
+	if err != nil {
+		return err
+	}
+	h := p.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	err = j.Replay(func(e journal.Entry) error {
+		if e.Op == opClearHistory {
+			h.entries = nil
+			return nil
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(e.Data, &entry); err != nil {
+			return err
+		}
+		h.appendLocked(entry)
+		return nil
+	})
+	if err != nil {
+		j.Close()
+		return fmt.Errorf("replaying %s: %w", path, err)
+	}
+	h.journal = j
+	if len(h.entries) > 0 {
+		log.Printf("journal: restored %d calculations", len(h.entries))
+	}
+	return nil
+}
+
+func (p *PhysicsDecoderService) handleListHistory(w http.ResponseWriter, r *http.Request) {
+	limit := defaultHistoryLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > p.history.MaxEntries {
+			apierr.Respond(w, apierr.CodeBadRequest, fmt.Sprintf("limit must be 1-%d", p.history.MaxEntries))
+			return
+		}
+		limit = n
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"entries": p.history.Latest(limit)})
+}
+
+func (p *PhysicsDecoderService) handleGetHistory(w http.ResponseWriter, r *http.Request) {
+	entry, ok := p.history.Get(mux.Vars(r)["id"])
+	if !ok {
+		apierr.Respond(w, apierr.CodeNotFound, "calculation not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+func (p *PhysicsDecoderService) handleClearHistory(w http.ResponseWriter, r *http.Request) {
+	p.history.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func energy(t *testing.T, p *PhysicsDecoderService, req DecoderRequest) *DecoderResponse {
+	t.Helper()
+	resp, err := p.Calculate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Valid {
+		t.Fatalf("%s: %s", req.Formula, resp.Error)
+	}
+	return resp
+}
+
+func TestOverridingCScalesEnergy(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	req := DecoderRequest{Formula: "E=mc^2", Variables: map[string]float64{"m": 2}}
+	base := energy(t, p, req)
+
+	req.Hypothesis = true
+	req.ConstantOverrides = map[string]float64{"c": p.SpeedOfLight / 2}
+	halved := energy(t, p, req)
+
+	if ratio := halved.Result / base.Result; math.Abs(ratio-0.25) > 1e-12 {
+		t.Errorf("E(c/2)/E(c) = %g, want 0.25", ratio)
+	}
+	if !containsWarning(halved.Warnings, WarnConstantOverridden) {
+		t.Errorf("warnings %+v do not list the overridden constant", halved.Warnings)
+	}
+	if p.SpeedOfLight != NewPhysicsDecoderService().SpeedOfLight {
+		t.Error("an override leaked into the service constants")
+	}
+}
+
+func TestOverridesIgnoredWithoutHypothesis(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	req := DecoderRequest{Formula: "E=mc^2", Variables: map[string]float64{"m": 2}}
+	base := energy(t, p, req)
+
+	req.ConstantOverrides = map[string]float64{"c": 1}
+	got := energy(t, p, req)
+	if got.Result != base.Result {
+		t.Errorf("override applied without hypothesis: %g != %g", got.Result, base.Result)
+	}
+	if !containsWarning(got.Warnings, WarnOverridesIgnored) {
+		t.Errorf("warnings %+v do not note the ignored overrides", got.Warnings)
+	}
+}
+
+func TestInvalidOverrideRejected(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	for _, overrides := range []map[string]float64{{"G": 1}, {"c": -1}, {"h": math.Inf(1)}} {
+		resp, err := p.Calculate(DecoderRequest{
+			Formula:           "E=mc^2",
+			Variables:         map[string]float64{"m": 1},
+			Hypothesis:        true,
+			ConstantOverrides: overrides,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Valid || resp.Error == "" {
+			t.Errorf("overrides %v accepted", overrides)
+		}
+	}
+}
+
+func containsWarning(warnings []Warning, code string) bool {
+	for _, w := range warnings {
+		if w.Code == code {
+			return true
+		}
+	}
+	return false
+}
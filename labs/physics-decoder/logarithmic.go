@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// positiveVar reads a variable of the given quantity in SI, converting from
+// its unit if one is given. Values headed for a logarithm must be
+// positive, whatever unit they arrive in.
+func positiveVar(vars map[string]float64, units map[string]string, name, quantity string) (float64, error) {
+	value, ok := vars[name]
+	if !ok {
+		return 0, fmt.Errorf("%s variable '%s' not provided", quantity, name)
+	}
+	if unit, exists := units[name]; exists {
+		converted, err := toSI(quantity, value, unit)
+		if err != nil {
+			return 0, err
+		}
+		value = converted
+	}
+	if value <= 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("%s '%s' must be positive and finite, got %g", quantity, name, value)
+	}
+	return value, nil
+}
+
+// calculateInsertionLoss calculates IL = 10·log10(P_in/P_out) in dB
+func (p *PhysicsDecoderService) calculateInsertionLoss(vars map[string]float64, units map[string]string) (float64, []CalculationStep, error) {
+	in, err := positiveVar(vars, units, "P_in", "power")
+	if err != nil {
+		return 0, nil, err
+	}
+	out, err := positiveVar(vars, units, "P_out", "power")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	result := 10 * math.Log10(in/out)
+
+	steps := []CalculationStep{
+		{
+			Description: "Input power in W",
+			Value:       in,
+			Unit:        "W",
+		},
+		{
+			Description: "Output power in W",
+			Value:       out,
+			Unit:        "W",
+		},
+		{
+			Description: "Insertion loss calculation",
+			Value:       result,
+			Unit:        "dB",
+			Formula:     "IL = 10·log10(P_in/P_out)",
+		},
+	}
+
+	return result, steps, nil
+}
+
+// calculateAttenuatedPower calculates P_out = P_in·10^(-L/10) for a loss L
+// in dB
+func (p *PhysicsDecoderService) calculateAttenuatedPower(vars map[string]float64, units map[string]string) (float64, []CalculationStep, error) {
+	in, err := positiveVar(vars, units, "P_in", "power")
+	if err != nil {
+		return 0, nil, err
+	}
+	loss, ok := vars["L"]
+	if !ok {
+		return 0, nil, fmt.Errorf("loss variable 'L' not provided")
+	}
+	if unit, exists := units["L"]; exists && unit != "dB" {
+		return 0, nil, fmt.Errorf("loss 'L' must be given in dB, got %s", unit)
+	}
+
+	result := in * math.Pow(10, -loss/10)
+
+	steps := []CalculationStep{
+		{
+			Description: "Input power in W",
+			Value:       in,
+			Unit:        "W",
+		},
+		{
+			Description: "Loss",
+			Value:       loss,
+			Unit:        "dB",
+		},
+		{
+			Description: "Attenuated power calculation",
+			Value:       result,
+			Unit:        "W",
+			Formula:     "P_out = P_in·10^(-L/10)",
+		},
+	}
+
+	return result, steps, nil
+}
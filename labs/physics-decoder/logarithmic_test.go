@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLogarithmicConversions(t *testing.T) {
+	for _, tc := range []struct {
+		req  ConvertRequest
+		want float64
+	}{
+		{ConvertRequest{Value: 10, From: "dBm", To: "mW"}, 10},
+		{ConvertRequest{Value: 10, From: "mW", To: "dBm"}, 10},
+		{ConvertRequest{Value: 0, From: "dBW", To: "dBm"}, 30},
+		{ConvertRequest{Value: -3, From: "dBm", To: "W"}, 1e-3 * math.Pow(10, -0.3)},
+		{ConvertRequest{Value: 2, From: "1", To: "dB"}, 10 * math.Log10(2)},
+		{ConvertRequest{Value: 7, From: "pH", To: "mol/L"}, 1e-7},
+		{ConvertRequest{Value: 1e-4, From: "mol/L", To: "pH"}, 4},
+	} {
+		code, resp := convert(t, tc.req)
+		if code != http.StatusOK {
+			t.Errorf("%+v: status = %d", tc.req, code)
+			continue
+		}
+		if math.Abs(resp.Result-tc.want) > 1e-9*math.Max(math.Abs(tc.want), 1) {
+			t.Errorf("%g %s → %s = %g, want %g", tc.req.Value, tc.req.From, tc.req.To, resp.Result, tc.want)
+		}
+	}
+}
+
+func TestLogarithmicConversionRejectsNonPositive(t *testing.T) {
+	for _, req := range []ConvertRequest{
+		{Value: 0, From: "mW", To: "dBm"},
+		{Value: -1, From: "W", To: "dBW"},
+		{Value: 0, From: "mol/L", To: "pH"},
+	} {
+		if code, _ := convert(t, req); code != http.StatusBadRequest {
+			t.Errorf("%g %s → %s: status = %d, want 400", req.Value, req.From, req.To, code)
+		}
+	}
+}
+
+func TestThreeDBLossHalvesPower(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	resp, err := p.Calculate(DecoderRequest{
+		Formula:   "P_out = P_in·10^(-L/10)",
+		Variables: map[string]float64{"P_in": 10, "L": 3},
+		Units:     map[string]string{"P_in": "dBm", "L": "dB"},
+	})
+	if err != nil || !resp.Valid {
+		t.Fatalf("attenuated power = %+v, %v", resp, err)
+	}
+	if want := 5e-3; math.Abs(resp.Result-want) > 0.01*want {
+		t.Errorf("10 mW after 3 dB = %g W, want about %g", resp.Result, want)
+	}
+
+	resp, err = p.Calculate(DecoderRequest{
+		Formula:   "insertion loss",
+		Variables: map[string]float64{"P_in": 10, "P_out": 5},
+		Units:     map[string]string{"P_in": "mW", "P_out": "mW"},
+	})
+	if err != nil || !resp.Valid {
+		t.Fatalf("insertion loss = %+v, %v", resp, err)
+	}
+	if math.Abs(resp.Result-3.0103) > 1e-4 || resp.Unit != "dB" {
+		t.Errorf("insertion loss halving power = %g %s, want 3.01 dB", resp.Result, resp.Unit)
+	}
+}
+
+func TestLossFormulasRejectNonPositivePower(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	for name, req := range map[string]DecoderRequest{
+		"zero output":    {Formula: "insertion loss", Variables: map[string]float64{"P_in": 1, "P_out": 0}},
+		"negative input": {Formula: "insertion loss", Variables: map[string]float64{"P_in": -1, "P_out": 1}},
+		"zero input":     {Formula: "attenuated power", Variables: map[string]float64{"P_in": 0, "L": 3}},
+		"loss not in dB": {Formula: "attenuated power", Variables: map[string]float64{"P_in": 1, "L": 3}, Units: map[string]string{"L": "mW"}},
+	} {
+		resp, err := p.Calculate(req)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if resp.Valid || resp.Error == "" || math.IsNaN(resp.Result) || math.IsInf(resp.Result, 0) {
+			t.Errorf("%s: %+v, want a graceful error", name, resp)
+		}
+		if name != "loss not in dB" && !strings.Contains(resp.Error, "positive") {
+			t.Errorf("%s: error %q", name, resp.Error)
+		}
+	}
+}
@@ -2,11 +2,19 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"sort"
 	"strings"
 
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/jsonbody"
+	"github.com/corridoros/pkg/tlsserve"
+	"github.com/corridoros/pkg/trace"
+	"github.com/corridoros/security/pqc/httpsign"
 	"github.com/gorilla/mux"
 )
 
@@ -18,6 +26,13 @@ type PhysicsDecoderService struct {
 	BoltzmannConstant float64 // J/K
 	ElectronCharge   float64 // C
 	AvogadroNumber   float64 // mol^-1
+
+	// userFormulas are the formulas added with RegisterFormula, by ID
+	userFormulas map[string]userFormula
+	// MaxBatch caps the requests of one batch calculation
+	MaxBatch int
+
+	history *calculationHistory
 }
 
 // DecoderRequest represents a physics calculation request
@@ -27,20 +42,56 @@ type DecoderRequest struct {
 	Units      map[string]string      `json:"units"`
 	Context    string                 `json:"context,omitempty"`
 	Hypothesis bool                   `json:"hypothesis,omitempty"`
+	// Constants replaces named constants (c, h, k, e, N_A) for this
+	// calculation only; the result's confidence is then hypothesis
+	Constants map[string]float64 `json:"constants,omitempty"`
+	// Deprecated: ConstantOverrides replaces named constants when
+	// Hypothesis is set and is ignored otherwise; use Constants. It will be
+	// removed next release.
+	ConstantOverrides map[string]float64 `json:"constant_overrides,omitempty"`
+	// Uncertainties are the standard uncertainties of Variables, in the
+	// same units, propagated to every step and the result
+	Uncertainties map[string]float64 `json:"uncertainties,omitempty"`
+	// Expected, when set, is compared against the result within Tolerance
+	// (relative, default 1e-6); ExpectedUnit defaults to the result's unit
+	Expected     *float64 `json:"expected,omitempty"`
+	ExpectedUnit string   `json:"expected_unit,omitempty"`
+	Tolerance    float64  `json:"tolerance,omitempty"`
+	// OutputUnit converts the result to another unit of its dimension,
+	// e.g. "eV" for an energy
+	OutputUnit string `json:"output_unit,omitempty"`
+	// SigFigs is the significant figures of Formatted, default 4
+	SigFigs int `json:"sig_figs,omitempty"`
 }
 
 // DecoderResponse represents the calculation result
 type DecoderResponse struct {
+	ID          string             `json:"id,omitempty"` // of the calculation in the history
 	Result      float64            `json:"result"`
 	Unit        string             `json:"unit"`
+	// ResultUncertainty is the standard uncertainty of Result propagated
+	// from the request's Uncertainties
+	ResultUncertainty float64      `json:"result_uncertainty,omitempty"`
+	Formatted   *FormattedResult   `json:"formatted,omitempty"`
 	Formula     string             `json:"formula"`
+	CanonicalFormula string        `json:"canonical_formula"`
+	// FormulaValidated echoes the matched formula's FormulaInfo.Validated;
+	// Confidence is established, experimental (unvalidated formula) or
+	// hypothesis (hypothesis request)
+	FormulaValidated bool          `json:"formula_validated"`
+	Confidence  string             `json:"confidence,omitempty"`
 	Steps       []CalculationStep  `json:"steps"`
 	Valid       bool               `json:"valid"`
 	Error       string             `json:"error,omitempty"`
-	Warnings    []string           `json:"warnings,omitempty"`
+	Warnings    []Warning          `json:"warnings,omitempty"`
+	// Deprecated: WarningMessages repeats the messages of Warnings as the
+	// flat list earlier releases returned; it will be removed next release
+	WarningMessages []string       `json:"warning_messages,omitempty"`
 	Dimensions  map[string]string  `json:"dimensions"`
 	Context     string             `json:"context,omitempty"`
 	Hypothesis  bool               `json:"hypothesis,omitempty"`
+	Matches       *bool    `json:"matches,omitempty"`
+	RelativeError *float64 `json:"relative_error,omitempty"`
 }
 
 // CalculationStep represents a step in the calculation
@@ -49,10 +100,12 @@ type CalculationStep struct {
 	Value       float64 `json:"value"`
 	Unit        string  `json:"unit"`
 	Formula     string  `json:"formula,omitempty"`
+	Uncertainty float64 `json:"uncertainty,omitempty"` // propagated, in Unit
 }
 
 // FormulaInfo represents information about a physics formula
 type FormulaInfo struct {
+	ID          string            `json:"id"` // formula a calculation matched, as parseFormula names it
 	Name        string            `json:"name"`
 	Formula     string            `json:"formula"`
 	Description string            `json:"description"`
@@ -70,126 +123,363 @@ func NewPhysicsDecoderService() *PhysicsDecoderService {
 		BoltzmannConstant: 1.380649e-23,                  // J/K
 		ElectronCharge:   1.602176634e-19,                // C
 		AvogadroNumber:   6.02214076e23,                  // mol^-1
+		MaxBatch:         defaultMaxBatch,
+		history:          &calculationHistory{MaxEntries: defaultHistoryLength},
 	}
 }
 
-// Calculate performs physics calculations
+// Calculate performs physics calculations, recording each in the history
 func (p *PhysicsDecoderService) Calculate(req DecoderRequest) (*DecoderResponse, error) {
+	response, err := p.decode(req)
+	if err != nil {
+		return nil, err
+	}
+	p.history.record(req, response)
+	return response, nil
+}
+
+// decode performs a physics calculation
+func (p *PhysicsDecoderService) decode(req DecoderRequest) (*DecoderResponse, error) {
 	response := &DecoderResponse{
 		Formula:    req.Formula,
 		Context:    req.Context,
 		Hypothesis: req.Hypothesis,
 		Steps:      []CalculationStep{},
 		Dimensions: make(map[string]string),
-		Warnings:   []string{},
 	}
 
 	// Parse and validate formula
-	formula, err := p.parseFormula(req.Formula)
+	response.CanonicalFormula = canonicalFormula(req.Formula)
+	formula, err := p.parseFormula(response.CanonicalFormula)
 	if err != nil {
+		// Anything that is not a named formula may be an expression over
+		// the variables
+		if _, _, exprErr := parseEquation(req.Formula); exprErr != nil {
+			response.Error = fmt.Sprintf("%v; not an expression either: %v", err, exprErr)
+			response.Valid = false
+			return response, nil
+		}
+		formula = "expression"
+	}
+	// E = hf given a wavelength rather than a frequency is E = hc/λ
+	if _, hasF := req.Variables["f"]; formula == "photon_energy" && !hasF {
+		if _, hasLambda := req.Variables["λ"]; hasLambda {
+			formula = "photon_energy_wavelength"
+		}
+	}
+	p.warnInputUnits(formula, req, response)
+	if err := p.checkDimensions(formula, req); err != nil {
 		response.Error = err.Error()
 		response.Valid = false
 		return response, nil
 	}
 
-	// Perform calculation based on formula type
-	switch formula {
-	case "energy_mass":
-		result, steps, err := p.calculateEnergyMass(req.Variables, req.Units)
-		if err != nil {
-			response.Error = err.Error()
+	// Constants swap in for this calculation only; the deprecated
+	// ConstantOverrides do so for hypothesis requests alone
+	calc := p
+	overrides, field := req.Constants, "constants"
+	if len(req.ConstantOverrides) > 0 {
+		switch {
+		case len(req.Constants) > 0:
+			response.Error = "give constants or the deprecated constant_overrides, not both"
 			response.Valid = false
 			return response, nil
+		case !req.Hypothesis:
+			response.warn(WarnOverridesIgnored, "constant_overrides", "constant_overrides ignored: hypothesis is false")
+		default:
+			overrides, field = req.ConstantOverrides, "constant_overrides"
 		}
-		response.Result = result
-		response.Unit = "J"
-		response.Steps = steps
-		response.Dimensions = map[string]string{"energy": "ML²T⁻²"}
-
-	case "wavelength_frequency":
-		result, steps, err := p.calculateWavelengthFrequency(req.Variables, req.Units)
+	}
+	if len(overrides) > 0 {
+		overridden, warnings, err := p.withOverrides(overrides, field)
 		if err != nil {
 			response.Error = err.Error()
 			response.Valid = false
 			return response, nil
 		}
-		response.Result = result
-		response.Unit = "m"
-		response.Steps = steps
-		response.Dimensions = map[string]string{"wavelength": "L"}
+		calc = overridden
+		for _, w := range warnings {
+			response.warn(w.Code, w.Field, "%s", w.Message)
+		}
+	}
+	// Non-standard constants make any result a hypothesis
+	response.FormulaValidated, response.Confidence = p.formulaConfidence(formula, req.Hypothesis || len(overrides) > 0)
 
-	case "photon_energy":
-		result, steps, err := p.calculatePhotonEnergy(req.Variables, req.Units)
-		if err != nil {
+	// Perform calculation based on formula type
+	result, steps, err := calc.calculate(formula, req)
+	if err != nil {
+		response.Error = err.Error()
+		response.Valid = false
+		return response, nil
+	}
+	response.Result = result
+	response.Steps = p.markOverridden(steps, overrides)
+	response.Unit, response.Dimensions = calc.resultUnit(formula, req, steps)
+	if req.OutputUnit != "" {
+		response.Unit = req.OutputUnit
+	}
+
+	if len(req.Uncertainties) > 0 {
+		if err := calc.propagateUncertainties(formula, req, response); err != nil {
 			response.Error = err.Error()
 			response.Valid = false
 			return response, nil
 		}
-		response.Result = result
-		response.Unit = "J"
-		response.Steps = steps
-		response.Dimensions = map[string]string{"energy": "ML²T⁻²"}
+	}
 
-	case "thermal_energy":
-		result, steps, err := p.calculateThermalEnergy(req.Variables, req.Units)
-		if err != nil {
+	formatted, err := formatResult(response.Result, response.Unit, req.SigFigs)
+	if err != nil {
+		response.Error = err.Error()
+		response.Valid = false
+		return response, nil
+	}
+	response.Formatted = formatted
+
+	response.Valid = true
+	warnResult(response)
+	warnInputRanges(response)
+
+	if req.Expected != nil {
+		if err := p.compareExpected(req, response); err != nil {
 			response.Error = err.Error()
 			response.Valid = false
 			return response, nil
 		}
-		response.Result = result
-		response.Unit = "J"
-		response.Steps = steps
-		response.Dimensions = map[string]string{"energy": "ML²T⁻²"}
+	}
 
-	case "optical_power":
-		result, steps, err := p.calculateOpticalPower(req.Variables, req.Units)
+	if momentum, ok := req.Variables["p"]; formula == "relativistic_energy" && ok && momentum == 0 {
+		response.warn(WarnRestEnergy, "variables.p", "momentum is zero; the total energy is the rest energy mc²")
+	}
+
+	// Add warnings for hypothesis formulas
+	if req.Hypothesis || len(overrides) > 0 {
+		message := "This calculation uses a hypothesis formula - verify results independently"
+		if len(overrides) > 0 {
+			message += "; constants overridden: " + strings.Join(sortedKeys(overrides), ", ")
+		}
+		response.warn(WarnHypothesis, "hypothesis", "%s", message)
+	}
+
+	return response, nil
+}
+
+// compareExpected checks the result against the request's expected value,
+// converting it into the result's unit first
+func (p *PhysicsDecoderService) compareExpected(req DecoderRequest, response *DecoderResponse) error {
+	tolerance := req.Tolerance
+	if tolerance == 0 {
+		tolerance = 1e-6
+	}
+	if tolerance < 0 {
+		return fmt.Errorf("tolerance must not be negative")
+	}
+
+	expected := *req.Expected
+	if req.ExpectedUnit != "" && req.ExpectedUnit != response.Unit {
+		converted, err := p.Convert(ConvertRequest{Value: expected, From: req.ExpectedUnit, To: response.Unit})
 		if err != nil {
-			response.Error = err.Error()
-			response.Valid = false
-			return response, nil
+			return fmt.Errorf("expected_unit: %w", err)
 		}
-		response.Result = result
-		response.Unit = "W"
-		response.Steps = steps
-		response.Dimensions = map[string]string{"power": "ML²T⁻³"}
+		expected = converted.Result
+	}
 
-	default:
-		response.Error = "Unknown formula: " + formula
-		response.Valid = false
-		return response, nil
+	relErr := math.Abs(response.Result - expected)
+	if expected != 0 {
+		relErr /= math.Abs(expected)
 	}
+	matches := relErr <= tolerance
+	response.Matches = &matches
+	response.RelativeError = &relErr
+	return nil
+}
 
-	response.Valid = true
+// withOverrides returns a copy of the service with the named constants
+// replaced, plus a warning per overridden constant
+func (p *PhysicsDecoderService) withOverrides(overrides map[string]float64, field string) (*PhysicsDecoderService, []Warning, error) {
+	calc := *p
+	fields := map[string]*float64{
+		"c":   &calc.SpeedOfLight,
+		"h":   &calc.PlanckConstant,
+		"k":   &calc.BoltzmannConstant,
+		"e":   &calc.ElectronCharge,
+		"N_A": &calc.AvogadroNumber,
+	}
 
-	// Add warnings for hypothesis formulas
-	if req.Hypothesis {
-		response.Warnings = append(response.Warnings, "This calculation uses a hypothesis formula - verify results independently")
+	names := sortedKeys(overrides)
+	warnings := make([]Warning, 0, len(names))
+	for _, name := range names {
+		constant, ok := fields[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown constant override: %s (c|h|k|e|N_A)", name)
+		}
+		value := overrides[name]
+		if value <= 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+			return nil, nil, fmt.Errorf("constant override %s must be positive and finite", name)
+		}
+		warnings = append(warnings, Warning{
+			Code:    WarnConstantOverridden,
+			Message: fmt.Sprintf("constant %s overridden: %g -> %g", name, *constant, value),
+			Field:   field + "." + name,
+		})
+		*constant = value
 	}
+	return &calc, warnings, nil
+}
 
-	return response, nil
+// markOverridden notes on the steps that give an overridden constant its
+// standard value, which p holds
+func (p *PhysicsDecoderService) markOverridden(steps []CalculationStep, overrides map[string]float64) []CalculationStep {
+	standard := map[string]float64{
+		"c":   p.SpeedOfLight,
+		"h":   p.PlanckConstant,
+		"k":   p.BoltzmannConstant,
+		"e":   p.ElectronCharge,
+		"N_A": p.AvogadroNumber,
+	}
+	for name, value := range overrides {
+		for i, step := range steps {
+			if step.Description == expressionConstants[name] && step.Value == value {
+				steps[i].Description = fmt.Sprintf("%s (non-standard; standard value %g)", step.Description, standard[name])
+			}
+		}
+	}
+	return steps
 }
 
-// parseFormula determines the type of formula from the input
-func (p *PhysicsDecoderService) parseFormula(formula string) (string, error) {
-	formula = strings.ToLower(strings.TrimSpace(formula))
-	
-	if strings.Contains(formula, "e=mc²") || strings.Contains(formula, "e=mc^2") {
-		return "energy_mass", nil
+// sortedKeys returns the names of a map of values in order
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// runCalculator evaluates a parsed formula over the request's variables
+func (p *PhysicsDecoderService) runCalculator(formula string, req DecoderRequest) (float64, []CalculationStep, error) {
+	switch formula {
+	case "energy_mass":
+		return p.calculateEnergyMass(req.Variables, req.Units)
+	case "relativistic_energy":
+		return p.calculateRelativisticEnergy(req.Variables, req.Units)
+	case "wavelength_frequency":
+		return p.calculateWavelengthFrequency(req.Variables, req.Units)
+	case "photon_energy":
+		return p.calculatePhotonEnergy(req.Variables, req.Units)
+	case "photon_energy_wavelength":
+		return p.calculatePhotonEnergyFromWavelength(req.Variables, req.Units)
+	case "thermal_energy":
+		return p.calculateThermalEnergy(req.Variables, req.Units)
+	case "optical_power":
+		return p.calculateOpticalPower(req.Variables, req.Units)
+	case "insertion_loss":
+		return p.calculateInsertionLoss(req.Variables, req.Units)
+	case "attenuated_power":
+		return p.calculateAttenuatedPower(req.Variables, req.Units)
+	case "expression":
+		return p.calculateExpression(req.Formula, req.Variables, req.Units)
 	}
-	if strings.Contains(formula, "λ=c/f") || strings.Contains(formula, "wavelength") {
-		return "wavelength_frequency", nil
+	user, ok := p.userFormulas[formula]
+	if !ok {
+		return 0, nil, fmt.Errorf("Unknown formula: %s", formula)
 	}
-	if strings.Contains(formula, "e=hf") || strings.Contains(formula, "photon") {
-		return "photon_energy", nil
+	return user.calculate(req.Variables, req.Units)
+}
+
+// namedFormulas are the canonical forms of the ways a request may name each
+// built-in formula: its equations and its name. Only these match exactly;
+// any other formula is read as an expression over the variables.
+var namedFormulas = canonicalSpellings(map[string][]string{
+	"energy_mass":              {"E = mc²", "mc²", "mass-energy equivalence"},
+	"relativistic_energy":      {"E² = (pc)² + (mc²)²", "E = √((pc)² + (mc²)²)", "E = sqrt((pc)² + (mc²)²)", "relativistic energy"},
+	"wavelength_frequency":     {"λ = c/f", "f = c/λ", "wavelength", "wavelength-frequency relationship"},
+	"photon_energy":            {"E = hf", "photon energy"},
+	"photon_energy_wavelength": {"E = hc/λ", "photon energy from wavelength"},
+	"thermal_energy":           {"E = kT", "thermal energy"},
+	"optical_power":            {"P = E/t", "P = I*A", "optical power"},
+	"insertion_loss":           {"IL = 10·log10(P_in/P_out)", "insertion loss"},
+	"attenuated_power":         {"P_out = P_in·10^(-L/10)", "attenuated power"},
+})
+
+func canonicalSpellings(spellings map[string][]string) map[string][]string {
+	canonical := make(map[string][]string, len(spellings))
+	for id, forms := range spellings {
+		for _, form := range forms {
+			canonical[id] = append(canonical[id], canonicalFormula(form))
+		}
 	}
-	if strings.Contains(formula, "e=kt") || strings.Contains(formula, "thermal") {
-		return "thermal_energy", nil
+	return canonical
+}
+
+// calculate runs a formula's calculator and converts its result to the
+// request's output unit, if any, in a closing step
+func (p *PhysicsDecoderService) calculate(formula string, req DecoderRequest) (float64, []CalculationStep, error) {
+	result, steps, err := p.runCalculator(formula, req)
+	if err != nil || req.OutputUnit == "" {
+		return result, steps, err
+	}
+	unit, _ := p.resultUnit(formula, req, steps)
+	if unit == req.OutputUnit {
+		return result, steps, nil
 	}
-	if strings.Contains(formula, "p=") || strings.Contains(formula, "power") {
-		return "optical_power", nil
+	if unit == "" {
+		return 0, nil, fmt.Errorf("output_unit %s: the result has no unit to convert from", req.OutputUnit)
+	}
+	converted, err := convertResult(result, unit, req.OutputUnit)
+	if err != nil {
+		return 0, nil, fmt.Errorf("output_unit %s: %v", req.OutputUnit, err)
+	}
+	steps = append(steps, CalculationStep{
+		Description: "Result in " + req.OutputUnit,
+		Value:       converted,
+		Unit:        req.OutputUnit,
+	})
+	return converted, steps, nil
+}
+
+// resultUnit returns the unit a formula's calculator produces, with its
+// dimension
+func (p *PhysicsDecoderService) resultUnit(formula string, req DecoderRequest, steps []CalculationStep) (string, map[string]string) {
+	switch formula {
+	case "energy_mass", "relativistic_energy", "photon_energy", "photon_energy_wavelength", "thermal_energy":
+		return "J", map[string]string{"energy": "ML²T⁻²"}
+
+	case "wavelength_frequency":
+		// Only a wavelength given asks for the frequency
+		if _, hasF := req.Variables["f"]; !hasF {
+			return "Hz", map[string]string{"frequency": "T⁻¹"}
+		}
+		return "m", map[string]string{"wavelength": "L"}
+
+	case "optical_power", "attenuated_power":
+		return "W", map[string]string{"power": "ML²T⁻³"}
+
+	case "insertion_loss":
+		return "dB", map[string]string{"ratio": "1"}
+
+	case "expression":
+		if unit := steps[len(steps)-1].Unit; unit != "" {
+			dim, _ := UnitDimension(unit)
+			return unit, map[string]string{"result": dim.String()}
+		}
+	}
+	return p.userFormulas[formula].unit, map[string]string{}
+}
+
+// parseFormula determines the type of formula from its canonical form
+func (p *PhysicsDecoderService) parseFormula(formula string) (string, error) {
+	if id, ok := p.matchUserFormula(formula); ok {
+		return id, nil
+	}
+
+	for id, spellings := range namedFormulas {
+		for _, spelling := range spellings {
+			if formula == spelling {
+				return id, nil
+			}
+		}
 	}
-	
 	return "", fmt.Errorf("unrecognized formula: %s", formula)
 }
 
@@ -202,14 +492,14 @@ func (p *PhysicsDecoderService) calculateEnergyMass(vars map[string]float64, uni
 	
 	// Convert mass to kg if needed
 	if unit, exists := units["m"]; exists {
-		switch unit {
-		case "g":
-			mass = mass / 1000.0
-		case "kg":
-			// already in kg
-		default:
-			return 0, nil, fmt.Errorf("unsupported mass unit: %s", unit)
+		converted, err := toSI("mass", mass, unit)
+		if err != nil {
+			return 0, nil, err
 		}
+		mass = converted
+	}
+	if mass < 0 {
+		return 0, nil, fmt.Errorf("mass 'm' must not be negative")
 	}
 	
 	c := p.SpeedOfLight
@@ -237,29 +527,102 @@ func (p *PhysicsDecoderService) calculateEnergyMass(vars map[string]float64, uni
 	return result, steps, nil
 }
 
-// calculateWavelengthFrequency calculates λ = c/f
+// calculateRelativisticEnergy calculates the total energy of a moving
+// mass, E = √((pc)² + (mc²)²), which is the rest energy mc² at p = 0
+func (p *PhysicsDecoderService) calculateRelativisticEnergy(vars map[string]float64, units map[string]string) (float64, []CalculationStep, error) {
+	mass, ok := vars["m"]
+	if !ok {
+		return 0, nil, fmt.Errorf("mass variable 'm' not provided")
+	}
+	momentum, ok := vars["p"]
+	if !ok {
+		return 0, nil, fmt.Errorf("momentum variable 'p' not provided")
+	}
+
+	// Convert mass to kg and momentum to kg·m/s if needed
+	if unit, exists := units["m"]; exists {
+		converted, err := toSI("mass", mass, unit)
+		if err != nil {
+			return 0, nil, err
+		}
+		mass = converted
+	}
+	if unit, exists := units["p"]; exists {
+		converted, err := toSI("momentum", momentum, unit)
+		if err != nil {
+			return 0, nil, err
+		}
+		momentum = converted
+	}
+	if mass < 0 {
+		return 0, nil, fmt.Errorf("mass 'm' must not be negative")
+	}
+
+	c := p.SpeedOfLight
+	restEnergy := mass * c * c
+	momentumEnergy := momentum * c
+	result := math.Hypot(momentumEnergy, restEnergy)
+
+	steps := []CalculationStep{
+		{
+			Description: "Mass in kg",
+			Value:       mass,
+			Unit:        "kg",
+		},
+		{
+			Description: "Momentum in kg·m/s",
+			Value:       momentum,
+			Unit:        "kg·m/s",
+		},
+		{
+			Description: "Speed of light",
+			Value:       c,
+			Unit:        "m/s",
+		},
+		{
+			Description: "Rest energy term",
+			Value:       restEnergy,
+			Unit:        "J",
+			Formula:     "mc²",
+		},
+		{
+			Description: "Momentum term",
+			Value:       momentumEnergy,
+			Unit:        "J",
+			Formula:     "pc",
+		},
+		{
+			Description: "Total energy calculation",
+			Value:       result,
+			Unit:        "J",
+			Formula:     "E = √((pc)² + (mc²)²)",
+		},
+	}
+
+	return result, steps, nil
+}
+
+// calculateWavelengthFrequency calculates λ = c/f, or f = c/λ when only the
+// wavelength is given
 func (p *PhysicsDecoderService) calculateWavelengthFrequency(vars map[string]float64, units map[string]string) (float64, []CalculationStep, error) {
 	frequency, ok := vars["f"]
 	if !ok {
+		if _, hasLambda := vars["λ"]; hasLambda {
+			return p.calculateFrequencyFromWavelength(vars, units)
+		}
 		return 0, nil, fmt.Errorf("frequency variable 'f' not provided")
 	}
 	
 	// Convert frequency to Hz if needed
 	if unit, exists := units["f"]; exists {
-		switch unit {
-		case "kHz":
-			frequency = frequency * 1000
-		case "MHz":
-			frequency = frequency * 1000000
-		case "GHz":
-			frequency = frequency * 1000000000
-		case "THz":
-			frequency = frequency * 1000000000000
-		case "Hz":
-			// already in Hz
-		default:
-			return 0, nil, fmt.Errorf("unsupported frequency unit: %s", unit)
+		converted, err := toSI("frequency", frequency, unit)
+		if err != nil {
+			return 0, nil, err
 		}
+		frequency = converted
+	}
+	if frequency <= 0 {
+		return 0, nil, fmt.Errorf("frequency 'f' must be positive")
 	}
 	
 	c := p.SpeedOfLight
@@ -287,6 +650,45 @@ func (p *PhysicsDecoderService) calculateWavelengthFrequency(vars map[string]flo
 	return result, steps, nil
 }
 
+// calculateFrequencyFromWavelength calculates f = c/λ
+func (p *PhysicsDecoderService) calculateFrequencyFromWavelength(vars map[string]float64, units map[string]string) (float64, []CalculationStep, error) {
+	wavelength := vars["λ"]
+	if unit, exists := units["λ"]; exists {
+		converted, err := toSI("length", wavelength, unit)
+		if err != nil {
+			return 0, nil, err
+		}
+		wavelength = converted
+	}
+	if wavelength <= 0 {
+		return 0, nil, fmt.Errorf("wavelength 'λ' must be positive")
+	}
+
+	c := p.SpeedOfLight
+	result := c / wavelength
+
+	steps := []CalculationStep{
+		{
+			Description: "Wavelength in m",
+			Value:       wavelength,
+			Unit:        "m",
+		},
+		{
+			Description: "Speed of light",
+			Value:       c,
+			Unit:        "m/s",
+		},
+		{
+			Description: "Frequency calculation",
+			Value:       result,
+			Unit:        "Hz",
+			Formula:     "f = c/λ",
+		},
+	}
+
+	return result, steps, nil
+}
+
 // calculatePhotonEnergy calculates E = hf
 func (p *PhysicsDecoderService) calculatePhotonEnergy(vars map[string]float64, units map[string]string) (float64, []CalculationStep, error) {
 	frequency, ok := vars["f"]
@@ -296,20 +698,14 @@ func (p *PhysicsDecoderService) calculatePhotonEnergy(vars map[string]float64, u
 	
 	// Convert frequency to Hz if needed
 	if unit, exists := units["f"]; exists {
-		switch unit {
-		case "kHz":
-			frequency = frequency * 1000
-		case "MHz":
-			frequency = frequency * 1000000
-		case "GHz":
-			frequency = frequency * 1000000000
-		case "THz":
-			frequency = frequency * 1000000000000
-		case "Hz":
-			// already in Hz
-		default:
-			return 0, nil, fmt.Errorf("unsupported frequency unit: %s", unit)
+		converted, err := toSI("frequency", frequency, unit)
+		if err != nil {
+			return 0, nil, err
 		}
+		frequency = converted
+	}
+	if frequency <= 0 {
+		return 0, nil, fmt.Errorf("frequency 'f' must be positive")
 	}
 	
 	h := p.PlanckConstant
@@ -337,6 +733,63 @@ func (p *PhysicsDecoderService) calculatePhotonEnergy(vars map[string]float64, u
 	return result, steps, nil
 }
 
+// calculatePhotonEnergyFromWavelength calculates E = hc/λ, closing with
+// the energy in eV as photon energies are usually quoted
+func (p *PhysicsDecoderService) calculatePhotonEnergyFromWavelength(vars map[string]float64, units map[string]string) (float64, []CalculationStep, error) {
+	wavelength, ok := vars["λ"]
+	if !ok {
+		return 0, nil, fmt.Errorf("wavelength variable 'λ' not provided")
+	}
+
+	// Convert wavelength to m if needed
+	if unit, exists := units["λ"]; exists {
+		converted, err := toSI("length", wavelength, unit)
+		if err != nil {
+			return 0, nil, err
+		}
+		wavelength = converted
+	}
+	if wavelength <= 0 {
+		return 0, nil, fmt.Errorf("wavelength 'λ' must be positive")
+	}
+
+	h := p.PlanckConstant
+	c := p.SpeedOfLight
+	result := h * c / wavelength
+
+	steps := []CalculationStep{
+		{
+			Description: "Wavelength in m",
+			Value:       wavelength,
+			Unit:        "m",
+		},
+		{
+			Description: "Planck constant",
+			Value:       h,
+			Unit:        "J⋅s",
+		},
+		{
+			Description: "Speed of light",
+			Value:       c,
+			Unit:        "m/s",
+		},
+		{
+			Description: "Photon energy calculation",
+			Value:       result,
+			Unit:        "J",
+			Formula:     "E = hc/λ",
+		},
+		{
+			Description: "Photon energy in eV",
+			Value:       result / p.ElectronCharge,
+			Unit:        "eV",
+			Formula:     "E/e",
+		},
+	}
+
+	return result, steps, nil
+}
+
 // calculateThermalEnergy calculates E = kT
 func (p *PhysicsDecoderService) calculateThermalEnergy(vars map[string]float64, units map[string]string) (float64, []CalculationStep, error) {
 	temperature, ok := vars["T"]
@@ -346,16 +799,14 @@ func (p *PhysicsDecoderService) calculateThermalEnergy(vars map[string]float64,
 	
 	// Convert temperature to K if needed
 	if unit, exists := units["T"]; exists {
-		switch unit {
-		case "°C":
-			temperature = temperature + 273.15
-		case "°F":
-			temperature = (temperature - 32) * 5/9 + 273.15
-		case "K":
-			// already in K
-		default:
-			return 0, nil, fmt.Errorf("unsupported temperature unit: %s", unit)
+		converted, err := toSI("temperature", temperature, unit)
+		if err != nil {
+			return 0, nil, err
 		}
+		temperature = converted
+	}
+	if temperature < 0 {
+		return 0, nil, fmt.Errorf("temperature 'T' is %g K, below absolute zero", temperature)
 	}
 	
 	k := p.BoltzmannConstant
@@ -391,6 +842,9 @@ func (p *PhysicsDecoderService) calculateOpticalPower(vars map[string]float64, u
 		if !ok {
 			return 0, nil, fmt.Errorf("time variable 't' not provided for P = E/t")
 		}
+		if time <= 0 {
+			return 0, nil, fmt.Errorf("time 't' must be positive")
+		}
 		
 		result := energy / time
 		
@@ -422,6 +876,9 @@ func (p *PhysicsDecoderService) calculateOpticalPower(vars map[string]float64, u
 		if !ok {
 			return 0, nil, fmt.Errorf("area variable 'A' not provided for P = I*A")
 		}
+		if area < 0 {
+			return 0, nil, fmt.Errorf("area 'A' must not be negative")
+		}
 		
 		result := intensity * area
 		
@@ -452,8 +909,14 @@ func (p *PhysicsDecoderService) calculateOpticalPower(vars map[string]float64, u
 
 // GetFormulas returns available physics formulas
 func (p *PhysicsDecoderService) GetFormulas() []FormulaInfo {
+	return append(builtinFormulas(), p.userFormulaInfos()...)
+}
+
+// builtinFormulas describes the formulas the decoder implements itself
+func builtinFormulas() []FormulaInfo {
 	return []FormulaInfo{
 		{
+			ID:          "energy_mass",
 			Name:        "Mass-Energy Equivalence",
 			Formula:     "E = mc²",
 			Description: "Einstein's mass-energy equivalence",
@@ -463,6 +926,17 @@ func (p *PhysicsDecoderService) GetFormulas() []FormulaInfo {
 			Validated:   true,
 		},
 		{
+			ID:          "relativistic_energy",
+			Name:        "Relativistic Energy",
+			Formula:     "E² = (pc)² + (mc²)²",
+			Description: "Total energy of a mass moving with momentum p; the rest energy at p = 0",
+			Variables:   map[string]string{"E": "total energy", "p": "momentum", "m": "mass", "c": "speed of light"},
+			Units:       map[string]string{"E": "J", "p": "kg·m/s", "m": "kg", "c": "m/s"},
+			Category:    "Relativity",
+			Validated:   true,
+		},
+		{
+			ID:          "wavelength_frequency",
 			Name:        "Wavelength-Frequency Relationship",
 			Formula:     "λ = c/f",
 			Description: "Relationship between wavelength and frequency",
@@ -472,6 +946,7 @@ func (p *PhysicsDecoderService) GetFormulas() []FormulaInfo {
 			Validated:   true,
 		},
 		{
+			ID:          "photon_energy",
 			Name:        "Photon Energy",
 			Formula:     "E = hf",
 			Description: "Energy of a photon",
@@ -481,6 +956,17 @@ func (p *PhysicsDecoderService) GetFormulas() []FormulaInfo {
 			Validated:   true,
 		},
 		{
+			ID:          "photon_energy_wavelength",
+			Name:        "Photon Energy from Wavelength",
+			Formula:     "E = hc/λ",
+			Description: "Energy of a photon of a given wavelength, also given in eV",
+			Variables:   map[string]string{"E": "energy", "h": "Planck constant", "c": "speed of light", "λ": "wavelength"},
+			Units:       map[string]string{"E": "J", "h": "J⋅s", "c": "m/s", "λ": "m"},
+			Category:    "Quantum Mechanics",
+			Validated:   true,
+		},
+		{
+			ID:          "thermal_energy",
 			Name:        "Thermal Energy",
 			Formula:     "E = kT",
 			Description: "Average thermal energy per degree of freedom",
@@ -490,6 +976,7 @@ func (p *PhysicsDecoderService) GetFormulas() []FormulaInfo {
 			Validated:   true,
 		},
 		{
+			ID:          "optical_power",
 			Name:        "Optical Power",
 			Formula:     "P = E/t or P = I*A",
 			Description: "Power calculation from energy/time or intensity*area",
@@ -498,20 +985,61 @@ func (p *PhysicsDecoderService) GetFormulas() []FormulaInfo {
 			Category:    "Optics",
 			Validated:   true,
 		},
+		{
+			ID:          "insertion_loss",
+			Name:        "Insertion Loss",
+			Formula:     "IL = 10·log10(P_in/P_out)",
+			Description: "Loss in dB between input and output power; powers may be given in W, mW or dBm",
+			Variables:   map[string]string{"IL": "insertion loss", "P_in": "input power", "P_out": "output power"},
+			Units:       map[string]string{"IL": "dB", "P_in": "W", "P_out": "W"},
+			Category:    "Optics",
+			Validated:   true,
+		},
+		{
+			ID:          "attenuated_power",
+			Name:        "Attenuated Power",
+			Formula:     "P_out = P_in·10^(-L/10)",
+			Description: "Power left after a loss in dB",
+			Variables:   map[string]string{"P_out": "output power", "P_in": "input power", "L": "loss"},
+			Units:       map[string]string{"P_out": "W", "P_in": "W", "L": "dB"},
+			Category:    "Optics",
+			Validated:   true,
+		},
+	}
+}
+
+// formulaConfidence reports whether a matched formula is validated and how
+// far its result can be trusted: a hypothesis request is only ever a
+// hypothesis, and an unvalidated formula is experimental
+func (p *PhysicsDecoderService) formulaConfidence(id string, hypothesis bool) (bool, string) {
+	validated := false
+	for _, info := range p.GetFormulas() {
+		if info.ID == id {
+			validated = info.Validated
+			break
+		}
+	}
+	switch {
+	case hypothesis:
+		return validated, "hypothesis"
+	case !validated:
+		return validated, "experimental"
+	default:
+		return validated, "established"
 	}
 }
 
 // HTTP handlers
 func (p *PhysicsDecoderService) handleCalculate(w http.ResponseWriter, r *http.Request) {
 	var req DecoderRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := jsonbody.Decode(r.Body, &req, jsonbody.Strict); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
 		return
 	}
 
 	response, err := p.Calculate(req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierr.Respond(w, apierr.CodeInternal, err.Error())
 		return
 	}
 
@@ -531,22 +1059,64 @@ func (p *PhysicsDecoderService) handleHealth(w http.ResponseWriter, r *http.Requ
 }
 
 func main() {
+	addr := flag.String("addr", ":8085", "listen address")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate chain; serves HTTPS with HTTP/2 instead of plaintext HTTP")
+	tlsKey := flag.String("tls-key", "", "PEM private key of -tls-cert")
+	tlsEndorsement := flag.String("tls-endorsement", "", "post-quantum endorsement of -tls-cert, made with tls-endorse, sent on every response")
+	signResponses := flag.Bool("sign-responses", false, "sign response bodies with an ML-DSA-65 key (public key at /v1/physics/pubkey)")
+	maxBatch := flag.Int("max-batch", defaultMaxBatch, "requests accepted in one /v1/physics/batch call; larger batches get 413")
+	historyLength := flag.Int("history-length", defaultHistoryLength, "calculations kept in /v1/physics/history; the oldest is dropped first")
+	historyFile := flag.String("history-file", "", "journal the calculation history is kept in and restored from on startup (empty = memory only)")
+	flag.Parse()
+	if *maxBatch <= 0 || *historyLength <= 0 {
+		log.Fatal("max-batch and history-length must be positive")
+	}
+
 	// Create physics decoder service
 	service := NewPhysicsDecoderService()
+	service.MaxBatch = *maxBatch
+	service.history.MaxEntries = *historyLength
+	if *historyFile != "" {
+		if err := service.OpenHistory(*historyFile); err != nil {
+			log.Fatalf("Failed to open calculation history: %v", err)
+		}
+	}
 
 	// Set up HTTP router
 	router := mux.NewRouter()
+	router.Use(trace.Middleware("physics-decoder"))
 	api := router.PathPrefix("/v1/physics").Subrouter()
 
 	// API endpoints
 	api.HandleFunc("/calculate", service.handleCalculate).Methods("POST")
+	api.HandleFunc("/batch", service.handleBatch).Methods("POST")
+	api.HandleFunc("/history", service.handleListHistory).Methods("GET")
+	api.HandleFunc("/history", service.handleClearHistory).Methods("DELETE")
+	api.HandleFunc("/history/{id}", service.handleGetHistory).Methods("GET")
 	api.HandleFunc("/formulas", service.handleGetFormulas).Methods("GET")
+	api.HandleFunc("/units", service.handleGetUnits).Methods("GET")
+	api.HandleFunc("/convert", service.handleConvert).Methods("POST")
+	api.HandleFunc("/pipeline", service.handlePipeline).Methods("POST")
+	api.HandleFunc("/dimensions", service.handleDimensions).Methods("POST")
+	api.HandleFunc("/wavelength-info", service.handleWavelengthInfo).Methods("GET")
 	api.HandleFunc("/health", service.handleHealth).Methods("GET")
 
+	// Optional response signing; clients verify against the published key
+	if *signResponses {
+		signer, err := httpsign.NewSigner()
+		if err != nil {
+			log.Fatalf("Failed to create response signer: %v", err)
+		}
+		router.Use(signer.Middleware)
+		api.HandleFunc("/pubkey", signer.HandlePublicKey).Methods("GET")
+		log.Printf("Signing responses with key %s", signer.PublicKey().KeyID)
+	}
+
 	// Health check
 	router.HandleFunc("/health", service.handleHealth).Methods("GET")
 
 	// Start server
-	log.Println("Starting Physics Decoder service on :8085")
-	log.Fatal(http.ListenAndServe(":8085", router))
+	tlsCfg := tlsserve.Config{CertFile: *tlsCert, KeyFile: *tlsKey, EndorsementFile: *tlsEndorsement}
+	log.Printf("Starting Physics Decoder service on %s", *addr)
+	log.Fatal(tlsserve.ListenAndServe(*addr, router, tlsCfg))
 }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/jsonbody"
+)
+
+// PipelineStep is one calculation in a pipeline. Inputs binds a calculator
+// variable to the Output of another step; Variables and Units hold literals.
+type PipelineStep struct {
+	Output    string             `json:"output"`
+	Formula   string             `json:"formula"`
+	Variables map[string]float64 `json:"variables,omitempty"`
+	Units     map[string]string  `json:"units,omitempty"`
+	Inputs    map[string]string  `json:"inputs,omitempty"`
+}
+
+// PipelineRequest is a set of chained calculations
+type PipelineRequest struct {
+	Steps []PipelineStep `json:"steps"`
+}
+
+// PipelineStepResult is the outcome of one pipeline step
+type PipelineStepResult struct {
+	Output string             `json:"output"`
+	Inputs map[string]float64 `json:"inputs,omitempty"`
+	Result DecoderResponse    `json:"result"`
+}
+
+// PipelineResponse carries every intermediate result and the final one
+type PipelineResponse struct {
+	Order  []string             `json:"order"`
+	Steps  []PipelineStepResult `json:"steps"`
+	Output string               `json:"output"`
+	Result float64              `json:"result"`
+	Unit   string               `json:"unit"`
+}
+
+// planPipeline validates step outputs and input references and returns the
+// steps in dependency order, preferring request order among independent steps
+func planPipeline(steps []PipelineStep) ([]int, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("pipeline has no steps")
+	}
+	producer := make(map[string]int, len(steps))
+	for i, step := range steps {
+		if step.Output == "" {
+			return nil, fmt.Errorf("step %d: output name required", i)
+		}
+		if _, dup := producer[step.Output]; dup {
+			return nil, fmt.Errorf("step %d: output %q defined more than once", i, step.Output)
+		}
+		producer[step.Output] = i
+	}
+
+	pending := make([]int, len(steps)) // unresolved dependencies per step
+	dependents := make([][]int, len(steps))
+	for i, step := range steps {
+		variables := make([]string, 0, len(step.Inputs))
+		for variable := range step.Inputs {
+			variables = append(variables, variable)
+		}
+		sort.Strings(variables)
+		for _, variable := range variables {
+			ref := step.Inputs[variable]
+			from, ok := producer[ref]
+			if !ok {
+				return nil, fmt.Errorf("step %d (%s): input %s references undefined variable %q", i, step.Output, variable, ref)
+			}
+			pending[i]++
+			dependents[from] = append(dependents[from], i)
+		}
+	}
+
+	var ready, order []int
+	for i := range steps {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		next := ready[0]
+		ready = ready[1:]
+		order = append(order, next)
+		for _, d := range dependents[next] {
+			if pending[d]--; pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	if len(order) != len(steps) {
+		var cyclic []string
+		for i, n := range pending {
+			if n > 0 {
+				cyclic = append(cyclic, steps[i].Output)
+			}
+		}
+		return nil, fmt.Errorf("dependency cycle among steps %v", cyclic)
+	}
+	return order, nil
+}
+
+// RunPipeline evaluates chained calculations, feeding each step's SI result
+// into the steps that reference it
+func (p *PhysicsDecoderService) RunPipeline(req PipelineRequest) (*PipelineResponse, error) {
+	order, err := planPipeline(req.Steps)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]float64, len(req.Steps))
+	resultUnits := make(map[string]string, len(req.Steps))
+	resp := &PipelineResponse{}
+	for _, i := range order {
+		step := req.Steps[i]
+		vars := make(map[string]float64, len(step.Variables)+len(step.Inputs))
+		for name, v := range step.Variables {
+			vars[name] = v
+		}
+		units := make(map[string]string, len(step.Units))
+		for name, u := range step.Units {
+			units[name] = u
+		}
+		inputs := make(map[string]float64, len(step.Inputs))
+		for variable, ref := range step.Inputs {
+			vars[variable] = values[ref]
+			inputs[variable] = values[ref]
+			// Chained values are already SI; their unit is kept so a result
+			// fed to a variable of another dimension is caught. An
+			// expression over variables without units has none.
+			if unit := resultUnits[ref]; unit != "" {
+				units[variable] = unit
+			} else {
+				delete(units, variable)
+			}
+		}
+
+		result, err := p.Calculate(DecoderRequest{Formula: step.Formula, Variables: vars, Units: units})
+		if err != nil {
+			return nil, err
+		}
+		if !result.Valid {
+			return nil, fmt.Errorf("step %d (%s): %s", i, step.Output, result.Error)
+		}
+
+		values[step.Output] = result.Result
+		resultUnits[step.Output] = result.Unit
+		resp.Order = append(resp.Order, step.Output)
+		resp.Steps = append(resp.Steps, PipelineStepResult{Output: step.Output, Inputs: inputs, Result: *result})
+		resp.Output, resp.Result, resp.Unit = step.Output, result.Result, result.Unit
+	}
+	return resp, nil
+}
+
+func (p *PhysicsDecoderService) handlePipeline(w http.ResponseWriter, r *http.Request) {
+	var req PipelineRequest
+	if err := jsonbody.Decode(r.Body, &req, jsonbody.Strict); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	response, err := p.RunPipeline(req)
+	if err != nil {
+		apierr.Respond(w, apierr.CodeValidation, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
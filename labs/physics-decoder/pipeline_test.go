@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// photonSteps chains wavelength → frequency → photon energy, listed out of
+// dependency order
+func photonSteps() []PipelineStep {
+	return []PipelineStep{
+		{Output: "E", Formula: "E=hf", Inputs: map[string]string{"f": "f"}},
+		{Output: "f", Formula: "λ=c/f", Variables: map[string]float64{"λ": 1550}, Units: map[string]string{"λ": "nm"}},
+	}
+}
+
+func TestPipelineMatchesDirectComputation(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	resp, err := p.RunPipeline(PipelineRequest{Steps: photonSteps()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(resp.Order, ",") != "f,E" || resp.Output != "E" || resp.Unit != "J" {
+		t.Fatalf("pipeline = order %v, output %s [%s]", resp.Order, resp.Output, resp.Unit)
+	}
+
+	wantF := p.SpeedOfLight / 1550e-9
+	if f := resp.Steps[0].Result.Result; math.Abs(f-wantF)/wantF > 1e-12 || resp.Steps[0].Result.Unit != "Hz" {
+		t.Errorf("intermediate f = %g %s, want %g Hz", f, resp.Steps[0].Result.Unit, wantF)
+	}
+	if resp.Steps[1].Inputs["f"] != resp.Steps[0].Result.Result {
+		t.Errorf("E step inputs = %v, want f from the first step", resp.Steps[1].Inputs)
+	}
+
+	direct, err := p.Calculate(DecoderRequest{Formula: "E=hf", Variables: map[string]float64{"f": wantF}})
+	if err != nil || !direct.Valid {
+		t.Fatalf("direct calculation: %v %+v", err, direct)
+	}
+	if math.Abs(resp.Result-direct.Result)/direct.Result > 1e-12 {
+		t.Errorf("pipeline E = %g, direct E = %g", resp.Result, direct.Result)
+	}
+}
+
+func TestPipelineRejectsBadGraphs(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	for name, tc := range map[string]struct {
+		steps []PipelineStep
+		want  string
+	}{
+		"undefined reference": {
+			steps: []PipelineStep{{Output: "E", Formula: "E=hf", Inputs: map[string]string{"f": "nu"}}},
+			want:  `undefined variable "nu"`,
+		},
+		"cycle": {
+			steps: []PipelineStep{
+				{Output: "a", Formula: "E=hf", Inputs: map[string]string{"f": "b"}},
+				{Output: "b", Formula: "E=hf", Inputs: map[string]string{"f": "a"}},
+			},
+			want: "dependency cycle",
+		},
+		"duplicate output": {
+			steps: []PipelineStep{{Output: "E", Formula: "E=hf"}, {Output: "E", Formula: "E=hf"}},
+			want:  "defined more than once",
+		},
+		"chained dimension mismatch": {
+			steps: []PipelineStep{
+				{Output: "E", Formula: "E=hf", Variables: map[string]float64{"f": 193e12}},
+				{Output: "P", Formula: "E=mc²", Inputs: map[string]string{"m": "E"}},
+			},
+			want: "dimensional mismatch",
+		},
+	} {
+		if _, err := p.RunPipeline(PipelineRequest{Steps: tc.steps}); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: error = %v, want %q", name, err, tc.want)
+		}
+	}
+}
+
+func TestPipelineEndpoint(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	body, _ := json.Marshal(PipelineRequest{Steps: photonSteps()})
+	rec := httptest.NewRecorder()
+	p.handlePipeline(rec, httptest.NewRequest(http.MethodPost, "/v1/physics/pipeline", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp PipelineResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Steps) != 2 || resp.Result <= 0 {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestPipelineErrorsAreDeterministic(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	req := PipelineRequest{Steps: []PipelineStep{{
+		Output:  "E",
+		Formula: "E=hf",
+		Inputs:  map[string]string{"f": "nu", "a": "x", "m": "y", "z": "w"},
+	}}}
+	_, first := p.RunPipeline(req)
+	for i := 0; i < 20; i++ {
+		if _, err := p.RunPipeline(req); err == nil || err.Error() != first.Error() {
+			t.Fatalf("run %d error = %v, first run %v", i, err, first)
+		}
+	}
+	if !strings.Contains(first.Error(), "input a references") {
+		t.Errorf("error = %v, want the first input by name", first)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// derivativeStep is the relative step of the central differences taken to
+// propagate uncertainties, scaled by the larger of a variable's value and
+// its uncertainty
+const derivativeStep = 1e-6
+
+// propagateUncertainties applies first-order error propagation for
+// independent inputs, σ² = Σ (∂f/∂xᵢ · σᵢ)², to the result and to every
+// step. The partial derivatives are central differences of the formula's
+// calculator, so the sum, product and power rules fall out for any
+// formula, including expressions and logarithmic units.
+func (p *PhysicsDecoderService) propagateUncertainties(formula string, req DecoderRequest, response *DecoderResponse) error {
+	names := make([]string, 0, len(req.Uncertainties))
+	for name, sigma := range req.Uncertainties {
+		if _, ok := req.Variables[name]; !ok {
+			return fmt.Errorf("uncertainty given for %s, which is not a variable", name)
+		}
+		if sigma < 0 || math.IsInf(sigma, 0) || math.IsNaN(sigma) {
+			return fmt.Errorf("uncertainty of %s must be non-negative and finite", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resultVariance := 0.0
+	stepVariances := make([]float64, len(response.Steps))
+	for _, name := range names {
+		sigma := req.Uncertainties[name]
+		if sigma == 0 {
+			continue
+		}
+		x := req.Variables[name]
+		h := derivativeStep * math.Max(math.Abs(x), sigma)
+
+		up, upSteps, err := p.calculate(formula, withVariable(req, name, x+h))
+		if err != nil {
+			return fmt.Errorf("cannot propagate the uncertainty of %s: %v", name, err)
+		}
+		down, downSteps, err := p.calculate(formula, withVariable(req, name, x-h))
+		if err != nil {
+			return fmt.Errorf("cannot propagate the uncertainty of %s: %v", name, err)
+		}
+		if len(upSteps) != len(response.Steps) || len(downSteps) != len(response.Steps) {
+			return fmt.Errorf("cannot propagate the uncertainty of %s: the calculation changes within it", name)
+		}
+
+		resultVariance += math.Pow((up-down)/(2*h)*sigma, 2)
+		for i := range stepVariances {
+			stepVariances[i] += math.Pow((upSteps[i].Value-downSteps[i].Value)/(2*h)*sigma, 2)
+		}
+	}
+
+	response.ResultUncertainty = math.Sqrt(resultVariance)
+	for i, variance := range stepVariances {
+		response.Steps[i].Uncertainty = math.Sqrt(variance)
+	}
+	return nil
+}
+
+// withVariable returns a copy of the request with one variable replaced
+func withVariable(req DecoderRequest, name string, value float64) DecoderRequest {
+	vars := make(map[string]float64, len(req.Variables))
+	for k, v := range req.Variables {
+		vars[k] = v
+	}
+	vars[name] = value
+	req.Variables = vars
+	return req
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// relClose reports whether got is within rel of want
+func relClose(got, want, rel float64) bool {
+	return math.Abs(got-want) <= rel*math.Abs(want)
+}
+
+func TestUncertaintyOfNamedFormulas(t *testing.T) {
+	p := NewPhysicsDecoderService()
+
+	// E = mc²: a 1% uncertainty on m is 1% on E
+	resp, err := p.Calculate(DecoderRequest{Formula: "E=mc^2", Variables: map[string]float64{"m": 2}, Uncertainties: map[string]float64{"m": 0.02}})
+	if err != nil || !resp.Valid {
+		t.Fatalf("E=mc^2: %v %q", err, resp.Error)
+	}
+	if !relClose(resp.ResultUncertainty, 0.01*resp.Result, 1e-6) {
+		t.Errorf("σE = %g, want 1%% of %g", resp.ResultUncertainty, resp.Result)
+	}
+	if !relClose(resp.Steps[0].Uncertainty, 0.02, 1e-6) || resp.Steps[1].Uncertainty != 0 {
+		t.Errorf("step uncertainties: mass %g, c %g", resp.Steps[0].Uncertainty, resp.Steps[1].Uncertainty)
+	}
+
+	// λ = c/f: σλ/λ = σf/f through the division, in the units converted
+	resp, err = p.Calculate(DecoderRequest{Formula: "λ=c/f", Variables: map[string]float64{"f": 193.4}, Units: map[string]string{"f": "THz"}, Uncertainties: map[string]float64{"f": 0.5}})
+	if err != nil || !resp.Valid {
+		t.Fatalf("λ=c/f: %v %q", err, resp.Error)
+	}
+	if want := resp.Result * 0.5 / 193.4; !relClose(resp.ResultUncertainty, want, 1e-6) {
+		t.Errorf("σλ = %g, want %g", resp.ResultUncertainty, want)
+	}
+}
+
+func TestUncertaintyPropagationRules(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	vars := map[string]float64{"a": 3, "b": 4}
+	sigmas := map[string]float64{"a": 0.3, "b": 0.2}
+	for formula, want := range map[string]float64{
+		// σ² = σa² + σb²
+		"a + b": math.Hypot(0.3, 0.2),
+		"a - b": math.Hypot(0.3, 0.2),
+		// (σ/ab)² = (σa/a)² + (σb/b)²
+		"a * b": 12 * math.Hypot(0.3/3, 0.2/4),
+		"a / b": 0.75 * math.Hypot(0.3/3, 0.2/4),
+		// σ/|aⁿ| = |n| σa/|a|
+		"a^3":    27 * 3 * 0.3 / 3,
+		"b^-2":   1.0 / 16 * 2 * 0.2 / 4,
+		"2*a²+b": math.Hypot(4*3*0.3, 0.2),
+	} {
+		resp, err := p.Calculate(DecoderRequest{Formula: formula, Variables: vars, Uncertainties: sigmas})
+		if err != nil || !resp.Valid {
+			t.Errorf("%s: %v %q", formula, err, resp.Error)
+			continue
+		}
+		if !relClose(resp.ResultUncertainty, want, 1e-6) {
+			t.Errorf("σ(%s) = %g, want %g", formula, resp.ResultUncertainty, want)
+		}
+		if last := resp.Steps[len(resp.Steps)-1]; !relClose(last.Uncertainty, want, 1e-6) {
+			t.Errorf("%s: last step σ = %g, want %g", formula, last.Uncertainty, want)
+		}
+	}
+
+	// Only the uncertain input contributes
+	resp, _ := p.Calculate(DecoderRequest{Formula: "a * b", Variables: vars, Uncertainties: map[string]float64{"a": 0.3}})
+	if !relClose(resp.ResultUncertainty, 4*0.3, 1e-6) {
+		t.Errorf("σ(a*b) with exact b = %g, want %g", resp.ResultUncertainty, 4*0.3)
+	}
+}
+
+func TestUncertaintyErrors(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	for name, sigmas := range map[string]map[string]float64{
+		"not a variable": {"x": 1},
+		"non-negative":   {"a": -1},
+		"finite":         {"a": math.Inf(1)},
+	} {
+		resp, err := p.Calculate(DecoderRequest{Formula: "a * 2", Variables: map[string]float64{"a": 3}, Uncertainties: sigmas})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Valid || !strings.Contains(resp.Error, name) {
+			t.Errorf("%s: valid %v, error %q", name, resp.Valid, resp.Error)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/corridoros/pkg/apierr"
+	"github.com/corridoros/pkg/jsonbody"
+)
+
+// UnitInfo describes a unit symbol and its conversion to SI:
+// si = value*SIFactor + SIOffset, or for a logarithmic unit as given by Log
+type UnitInfo struct {
+	Symbol   string    `json:"symbol"`
+	SIFactor float64   `json:"si_factor,omitempty"`
+	SIOffset float64   `json:"si_offset,omitempty"`
+	Log      *LogScale `json:"log,omitempty"`
+}
+
+// LogScale defines a logarithmic unit over a linear quantity:
+// value = Multiplier * log10(si / Reference), so dBm has Multiplier 10 and
+// Reference 1 mW, and pH has Multiplier -1 and Reference 1 mol/L. Only
+// positive SI values can be expressed in a logarithmic unit.
+type LogScale struct {
+	Multiplier float64 `json:"multiplier"`
+	Reference  float64 `json:"reference"` // in the quantity's SI unit
+}
+
+// ConvertRequest asks for a value to be converted between two units
+type ConvertRequest struct {
+	Value float64 `json:"value"`
+	From  string  `json:"from"`
+	To    string  `json:"to"`
+}
+
+// ConvertResponse carries a converted value
+type ConvertResponse struct {
+	Quantity string  `json:"quantity"`
+	Value    float64 `json:"value"`
+	From     string  `json:"from"`
+	Result   float64 `json:"result"`
+	To       string  `json:"to"`
+}
+
+// unitTable is the centralized converter: per quantity, the accepted unit
+// symbols, with the SI unit first
+var unitTable = map[string][]UnitInfo{
+	"mass": {
+		{Symbol: "kg", SIFactor: 1},
+		{Symbol: "g", SIFactor: 1e-3},
+		{Symbol: "mg", SIFactor: 1e-6},
+	},
+	"frequency": {
+		{Symbol: "Hz", SIFactor: 1},
+		{Symbol: "kHz", SIFactor: 1e3},
+		{Symbol: "MHz", SIFactor: 1e6},
+		{Symbol: "GHz", SIFactor: 1e9},
+		{Symbol: "THz", SIFactor: 1e12},
+	},
+	"temperature": {
+		{Symbol: "K", SIFactor: 1},
+		{Symbol: "°C", SIFactor: 1, SIOffset: 273.15},
+		{Symbol: "°F", SIFactor: 5.0 / 9.0, SIOffset: 273.15 - 32*5.0/9.0},
+	},
+	"energy": {
+		{Symbol: "J", SIFactor: 1},
+		{Symbol: "kJ", SIFactor: 1e3},
+		{Symbol: "eV", SIFactor: 1.602176634e-19},
+		{Symbol: "meV", SIFactor: 1.602176634e-22},
+	},
+	"length": {
+		{Symbol: "m", SIFactor: 1},
+		{Symbol: "mm", SIFactor: 1e-3},
+		{Symbol: "µm", SIFactor: 1e-6},
+		{Symbol: "nm", SIFactor: 1e-9},
+	},
+	"time": {
+		{Symbol: "s", SIFactor: 1},
+		{Symbol: "ms", SIFactor: 1e-3},
+		{Symbol: "µs", SIFactor: 1e-6},
+		{Symbol: "ns", SIFactor: 1e-9},
+	},
+	"power": {
+		{Symbol: "W", SIFactor: 1},
+		{Symbol: "mW", SIFactor: 1e-3},
+		{Symbol: "kW", SIFactor: 1e3},
+		{Symbol: "dBm", Log: &LogScale{Multiplier: 10, Reference: 1e-3}},
+		{Symbol: "dBW", Log: &LogScale{Multiplier: 10, Reference: 1}},
+	},
+	// eV/c is the momentum of a 1 eV photon
+	"momentum": {
+		{Symbol: "kg·m/s", SIFactor: 1},
+		{Symbol: "N·s", SIFactor: 1},
+		{Symbol: "eV/c", SIFactor: 1.602176634e-19 / 299792458.0},
+		{Symbol: "keV/c", SIFactor: 1.602176634e-16 / 299792458.0},
+		{Symbol: "MeV/c", SIFactor: 1.602176634e-13 / 299792458.0},
+		{Symbol: "GeV/c", SIFactor: 1.602176634e-10 / 299792458.0},
+	},
+	// ratio is a dimensionless power ratio; dB is 10·log10 of it
+	"ratio": {
+		{Symbol: "1", SIFactor: 1},
+		{Symbol: "%", SIFactor: 1e-2},
+		{Symbol: "dB", Log: &LogScale{Multiplier: 10, Reference: 1}},
+	},
+	"concentration": {
+		{Symbol: "mol/m³", SIFactor: 1},
+		{Symbol: "mol/L", SIFactor: 1e3},
+		{Symbol: "mmol/L", SIFactor: 1},
+		{Symbol: "µmol/L", SIFactor: 1e-3},
+		{Symbol: "pH", Log: &LogScale{Multiplier: -1, Reference: 1e3}}, // of H⁺
+	},
+}
+
+// quantityDimensions is the dimension each quantity of unitTable measures,
+// whatever the unit; a logarithmic unit has its linear quantity's
+var quantityDimensions = map[string]string{
+	"mass":          "M",
+	"frequency":     "T⁻¹",
+	"temperature":   "Θ",
+	"energy":        "ML²T⁻²",
+	"length":        "L",
+	"time":          "T",
+	"power":         "ML²T⁻³",
+	"momentum":      "MLT⁻¹",
+	"ratio":         "1",
+	"concentration": "NL⁻³",
+}
+
+// lookupUnit finds a unit symbol under a quantity
+func lookupUnit(quantity, symbol string) (UnitInfo, bool) {
+	for _, u := range unitTable[quantity] {
+		if u.Symbol == symbol {
+			return u, true
+		}
+	}
+	return UnitInfo{}, false
+}
+
+// quantityOf returns the quantity a unit symbol measures
+func quantityOf(symbol string) (string, bool) {
+	for quantity := range unitTable {
+		if _, ok := lookupUnit(quantity, symbol); ok {
+			return quantity, true
+		}
+	}
+	return "", false
+}
+
+// toSI converts a value in the given unit of quantity to SI
+func toSI(quantity string, value float64, symbol string) (float64, error) {
+	u, ok := lookupUnit(quantity, symbol)
+	if !ok {
+		return 0, fmt.Errorf("unsupported %s unit: %s", quantity, symbol)
+	}
+	if u.Log != nil {
+		return u.Log.Reference * math.Pow(10, value/u.Log.Multiplier), nil
+	}
+	return value*u.SIFactor + u.SIOffset, nil
+}
+
+// fromSI converts an SI value of a quantity to the given unit, refusing to
+// take the logarithm of a value that is not positive
+func fromSI(u UnitInfo, si float64) (float64, error) {
+	if u.Log != nil {
+		if si <= 0 {
+			return 0, fmt.Errorf("only positive values can be expressed in %s", u.Symbol)
+		}
+		return u.Log.Multiplier * math.Log10(si/u.Log.Reference), nil
+	}
+	return (si - u.SIOffset) / u.SIFactor, nil
+}
+
+// convertResult converts a value between two units of one dimension:
+// within a quantity of unitTable as Convert does, otherwise between
+// compound linear units such as "J" and "kg·m²/s²"
+func convertResult(value float64, from, to string) (float64, error) {
+	fromScale, fromDim, err := unitScale(from)
+	if err != nil {
+		return 0, err
+	}
+	toScale, toDim, err := unitScale(to)
+	if err != nil {
+		return 0, err
+	}
+	if fromDim != toDim {
+		return 0, fmt.Errorf("dimension %s does not match the result's unit %s (%s)", toDim, from, fromDim)
+	}
+
+	if quantity, ok := quantityOf(from); ok {
+		if u, ok := lookupUnit(quantity, to); ok {
+			si, err := toSI(quantity, value, from)
+			if err != nil {
+				return 0, err
+			}
+			return fromSI(u, si)
+		}
+	}
+	if fromScale == 0 || toScale == 0 {
+		return 0, fmt.Errorf("cannot convert between %s and %s", from, to)
+	}
+	return value * fromScale / toScale, nil
+}
+
+// Convert converts a value between two units of the same quantity
+func (p *PhysicsDecoderService) Convert(req ConvertRequest) (*ConvertResponse, error) {
+	quantity, ok := quantityOf(req.From)
+	if !ok {
+		return nil, fmt.Errorf("unknown unit: %s", req.From)
+	}
+	to, ok := lookupUnit(quantity, req.To)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %s (%s) to %s", req.From, quantity, req.To)
+	}
+	si, err := toSI(quantity, req.Value, req.From)
+	if err != nil {
+		return nil, err
+	}
+	result, err := fromSI(to, si)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %g %s to %s: %w", req.Value, req.From, req.To, err)
+	}
+	return &ConvertResponse{
+		Quantity: quantity,
+		Value:    req.Value,
+		From:     req.From,
+		Result:   result,
+		To:       req.To,
+	}, nil
+}
+
+func (p *PhysicsDecoderService) handleGetUnits(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(unitTable)
+}
+
+func (p *PhysicsDecoderService) handleConvert(w http.ResponseWriter, r *http.Request) {
+	var req ConvertRequest
+	if err := jsonbody.Decode(r.Body, &req, jsonbody.Strict); err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+		return
+	}
+
+	response, err := p.Convert(req)
+	if err != nil {
+		apierr.Respond(w, apierr.CodeValidation, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
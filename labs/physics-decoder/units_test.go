@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUnitsEndpointListsSymbols(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	rec := httptest.NewRecorder()
+	p.handleGetUnits(rec, httptest.NewRequest("GET", "/v1/physics/units", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+
+	var units map[string][]UnitInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &units); err != nil {
+		t.Fatal(err)
+	}
+	for quantity, symbols := range map[string][]string{
+		"mass":        {"kg", "g"},
+		"frequency":   {"Hz", "THz"},
+		"temperature": {"K", "°C", "°F"},
+		"energy":      {"J", "eV"},
+	} {
+		for _, symbol := range symbols {
+			found := false
+			for _, u := range units[quantity] {
+				found = found || u.Symbol == symbol
+			}
+			if !found {
+				t.Errorf("%s units lack %q", quantity, symbol)
+			}
+		}
+		if units[quantity][0].SIFactor != 1 || units[quantity][0].SIOffset != 0 {
+			t.Errorf("%s does not list its SI unit first: %+v", quantity, units[quantity][0])
+		}
+	}
+}
+
+func convert(t *testing.T, req ConvertRequest) (int, ConvertResponse) {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	rec := httptest.NewRecorder()
+	NewPhysicsDecoderService().handleConvert(rec, httptest.NewRequest("POST", "/v1/physics/convert", bytes.NewReader(body)))
+	var resp ConvertResponse
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func TestConvert(t *testing.T) {
+	for _, tc := range []struct {
+		req  ConvertRequest
+		want float64
+	}{
+		{ConvertRequest{Value: 1, From: "eV", To: "J"}, 1.602176634e-19},
+		{ConvertRequest{Value: 1, From: "J", To: "eV"}, 1 / 1.602176634e-19},
+		{ConvertRequest{Value: 100, From: "°C", To: "°F"}, 212},
+		{ConvertRequest{Value: 1550, From: "nm", To: "µm"}, 1.55},
+	} {
+		code, resp := convert(t, tc.req)
+		if code != http.StatusOK {
+			t.Errorf("%+v: status = %d", tc.req, code)
+			continue
+		}
+		if math.Abs(resp.Result-tc.want) > 1e-9*math.Abs(tc.want) {
+			t.Errorf("%g %s → %s = %g, want %g", tc.req.Value, tc.req.From, tc.req.To, resp.Result, tc.want)
+		}
+	}
+}
+
+func TestConvertRejectsMismatchedQuantities(t *testing.T) {
+	for _, req := range []ConvertRequest{
+		{Value: 1, From: "kg", To: "J"},
+		{Value: 1, From: "furlong", To: "m"},
+	} {
+		if code, _ := convert(t, req); code != http.StatusBadRequest {
+			t.Errorf("%s → %s: status = %d, want 400", req.From, req.To, code)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FormulaFunc computes a formula from its variables and their units,
+// returning the result and the steps taken
+type FormulaFunc func(vars map[string]float64, units map[string]string) (float64, []CalculationStep, error)
+
+// userFormula is a formula added with RegisterFormula
+type userFormula struct {
+	info      FormulaInfo
+	canonical string
+	unit      string
+	calculate FormulaFunc
+}
+
+// RegisterFormula adds a formula the decoder does not implement itself. A
+// request matches it only when its canonical form equals that of
+// info.Formula. User formulas are never validated, whatever info says, so
+// their results are reported as experimental. Register formulas before the
+// service starts handling requests.
+func (p *PhysicsDecoderService) RegisterFormula(info FormulaInfo, unit string, calculate FormulaFunc) error {
+	if info.ID == "" || info.Formula == "" || calculate == nil {
+		return fmt.Errorf("a user formula needs an id, a formula and a calculation")
+	}
+	canonical := canonicalFormula(info.Formula)
+	for _, existing := range p.GetFormulas() {
+		if existing.ID == info.ID {
+			return fmt.Errorf("formula %s is already registered", info.ID)
+		}
+	}
+	if id, err := p.parseFormula(canonical); err == nil {
+		return fmt.Errorf("formula %q already matches %s", info.Formula, id)
+	}
+
+	info.Validated = false
+	if p.userFormulas == nil {
+		p.userFormulas = make(map[string]userFormula)
+	}
+	p.userFormulas[info.ID] = userFormula{info: info, canonical: canonical, unit: unit, calculate: calculate}
+	return nil
+}
+
+// matchUserFormula returns the ID of the user formula with the given
+// canonical form
+func (p *PhysicsDecoderService) matchUserFormula(canonical string) (string, bool) {
+	for id, f := range p.userFormulas {
+		if f.canonical == canonical {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// userFormulaInfos describes the user formulas, sorted by ID
+func (p *PhysicsDecoderService) userFormulaInfos() []FormulaInfo {
+	infos := make([]FormulaInfo, 0, len(p.userFormulas))
+	for _, f := range p.userFormulas {
+		infos = append(infos, f.info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Warning codes clients can react to
+const (
+	WarnUnitAssumed        = "UNIT_ASSUMED"        // a variable without a unit was taken in its formula's unit
+	WarnHypothesis         = "HYPOTHESIS"          // the result rests on a hypothesis
+	WarnLargeMagnitude     = "LARGE_MAGNITUDE"     // the result is implausibly large
+	WarnLossyConversion    = "LOSSY_CONVERSION"    // converting an input to SI lost precision
+	WarnConstantOverridden = "CONSTANT_OVERRIDDEN" // a hypothesis replaced a physical constant
+	WarnOverridesIgnored   = "OVERRIDES_IGNORED"   // constant overrides outside a hypothesis
+	WarnRestEnergy         = "REST_ENERGY"         // zero momentum reduced the total energy to the rest energy
+	WarnUnusualInput       = "UNUSUAL_INPUT"       // a legal input at the edge of the physical range
+)
+
+// Warning is a non-fatal remark on a calculation. Field, when set, is the
+// request field it concerns, e.g. "variables.m".
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+// Bounds past which a result or conversion is flagged
+const (
+	largeMagnitude  = 1e30
+	lossyTolerance  = 1e-9  // relative round-trip error of a unit conversion
+	gammaFrequency  = 3e19  // Hz; gamma rays lie above
+	gammaWavelength = 1e-11 // m; gamma rays lie below
+)
+
+// warn adds a warning to the response, and its message to the deprecated
+// flat list
+func (r *DecoderResponse) warn(code, field, format string, args ...any) {
+	w := Warning{Code: code, Message: fmt.Sprintf(format, args...), Field: field}
+	r.Warnings = append(r.Warnings, w)
+	r.WarningMessages = append(r.WarningMessages, w.Message)
+}
+
+// warnInputUnits flags the variables of a request that were taken in their
+// formula's unit for lack of one, and those whose conversion to SI does not
+// round-trip
+func (p *PhysicsDecoderService) warnInputUnits(formula string, req DecoderRequest, response *DecoderResponse) {
+	var defaults map[string]string
+	for _, info := range p.GetFormulas() {
+		if info.ID == formula {
+			defaults = info.Units
+			break
+		}
+	}
+
+	names := make([]string, 0, len(req.Variables))
+	for name := range req.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := "variables." + name
+		value := req.Variables[name]
+		symbol, given := req.Units[name]
+		if !given {
+			if unit, known := defaults[name]; known {
+				response.warn(WarnUnitAssumed, field, "no unit given for %s; assumed %s", name, unit)
+			}
+			continue
+		}
+		if back, lossy := lossyConversion(value, symbol); lossy {
+			response.warn(WarnLossyConversion, field, "%s = %g %s does not survive conversion to SI (reads back as %g)", name, value, symbol, back)
+		}
+	}
+}
+
+// lossyConversion reports whether converting value from the unit symbol to
+// SI and back loses more than lossyTolerance, with the value read back.
+// Unknown units are left to the calculators to reject.
+func lossyConversion(value float64, symbol string) (float64, bool) {
+	quantity, ok := quantityOf(symbol)
+	if !ok {
+		return value, false
+	}
+	u, _ := lookupUnit(quantity, symbol)
+	si, err := toSI(quantity, value, symbol)
+	if err != nil {
+		return value, false
+	}
+	back, err := fromSI(u, si)
+	if err != nil {
+		return back, true
+	}
+	return back, !(math.Abs(back-value) <= lossyTolerance*math.Abs(value))
+}
+
+// warnResult flags a result too large to be a plausible physical answer,
+// usually a sign of inputs in the wrong unit
+func warnResult(response *DecoderResponse) {
+	if math.Abs(response.Result) >= largeMagnitude {
+		response.warn(WarnLargeMagnitude, "result", "result %g %s exceeds %g in magnitude; check the input units", response.Result, response.Unit, largeMagnitude)
+	}
+}
+
+// warnInputRanges flags legal but unusual values among a calculation's
+// steps, which hold its inputs in SI: a massless body, absolute zero, and
+// gamma-ray frequencies or wavelengths
+func warnInputRanges(response *DecoderResponse) {
+	for _, step := range response.Steps {
+		switch {
+		case step.Description == "Mass in kg" && step.Value == 0:
+			response.warn(WarnUnusualInput, "variables.m", "mass is zero")
+		case step.Description == "Temperature in K" && step.Value == 0:
+			response.warn(WarnUnusualInput, "variables.T", "temperature is absolute zero")
+		case step.Description == "Frequency in Hz" && step.Value > gammaFrequency:
+			response.warn(WarnUnusualInput, "variables.f", "frequency %g Hz is in the gamma-ray range", step.Value)
+		case step.Description == "Wavelength in m" && step.Value < gammaWavelength:
+			response.warn(WarnUnusualInput, "variables.λ", "wavelength %g m is in the gamma-ray range", step.Value)
+		}
+	}
+}
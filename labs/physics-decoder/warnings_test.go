@@ -0,0 +1,83 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// codes lists the warning codes of a response, each with its field
+func codes(resp *DecoderResponse) map[string]string {
+	out := make(map[string]string, len(resp.Warnings))
+	for _, w := range resp.Warnings {
+		out[w.Code] = w.Field
+	}
+	return out
+}
+
+func TestHypothesisWarning(t *testing.T) {
+	resp := energy(t, NewPhysicsDecoderService(), DecoderRequest{
+		Formula:    "E=mc^2",
+		Variables:  map[string]float64{"m": 1},
+		Units:      map[string]string{"m": "kg"},
+		Hypothesis: true,
+	})
+	got := codes(resp)
+	if field, ok := got[WarnHypothesis]; !ok || field != "hypothesis" {
+		t.Errorf("warnings = %+v, want HYPOTHESIS on hypothesis", resp.Warnings)
+	}
+	if _, ok := got[WarnUnitAssumed]; ok {
+		t.Errorf("UNIT_ASSUMED with every unit given: %+v", resp.Warnings)
+	}
+}
+
+func TestUnitAssumedWarning(t *testing.T) {
+	resp := energy(t, NewPhysicsDecoderService(), DecoderRequest{Formula: "E=mc^2", Variables: map[string]float64{"m": 1}})
+	if field, ok := codes(resp)[WarnUnitAssumed]; !ok || field != "variables.m" {
+		t.Errorf("warnings = %+v, want UNIT_ASSUMED on variables.m", resp.Warnings)
+	}
+	if _, ok := codes(resp)[WarnHypothesis]; ok {
+		t.Errorf("HYPOTHESIS without a hypothesis: %+v", resp.Warnings)
+	}
+}
+
+func TestMagnitudeAndLossyWarnings(t *testing.T) {
+	p := NewPhysicsDecoderService()
+	huge := energy(t, p, DecoderRequest{Formula: "E=mc^2", Variables: map[string]float64{"m": 1e20}, Units: map[string]string{"m": "kg"}})
+	if field, ok := codes(huge)[WarnLargeMagnitude]; !ok || field != "result" {
+		t.Errorf("1e20 kg: warnings = %+v, want LARGE_MAGNITUDE", huge.Warnings)
+	}
+
+	// The smallest denormal in grams underflows to zero in kilograms
+	tiny, _ := p.Calculate(DecoderRequest{Formula: "E=mc^2", Variables: map[string]float64{"m": 5e-324}, Units: map[string]string{"m": "g"}})
+	if field, ok := codes(tiny)[WarnLossyConversion]; !ok || field != "variables.m" {
+		t.Errorf("5e-324 g: warnings = %+v, want LOSSY_CONVERSION", tiny.Warnings)
+	}
+}
+
+func TestDeprecatedWarningMessages(t *testing.T) {
+	resp := energy(t, NewPhysicsDecoderService(), DecoderRequest{Formula: "E=mc^2", Variables: map[string]float64{"m": 1}, Hypothesis: true})
+	var messages []string
+	for _, w := range resp.Warnings {
+		messages = append(messages, w.Message)
+	}
+	if len(messages) < 2 || !slices.Equal(messages, resp.WarningMessages) {
+		t.Errorf("warning_messages = %q, want %q", resp.WarningMessages, messages)
+	}
+}
+
+func TestPipelineDropsUnitAssumedForChainedInputs(t *testing.T) {
+	resp, err := NewPhysicsDecoderService().RunPipeline(PipelineRequest{Steps: photonSteps()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range resp.Steps {
+		for _, w := range step.Result.Warnings {
+			if w.Code == WarnUnitAssumed {
+				t.Errorf("step %s: %+v", step.Output, w)
+			}
+		}
+		if len(step.Result.WarningMessages) != len(step.Result.Warnings) {
+			t.Errorf("step %s: %d messages for %d warnings", step.Output, len(step.Result.WarningMessages), len(step.Result.Warnings))
+		}
+	}
+}
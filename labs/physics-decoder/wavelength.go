@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+// opticalBand is a named wavelength range [MinNm, MaxNm)
+type opticalBand struct {
+	Name  string
+	MinNm float64
+	MaxNm float64
+}
+
+// telecomBands are the ITU-T bands, matching the bands corrd offers
+// corridors on
+var telecomBands = []opticalBand{
+	{Name: "O", MinNm: 1260, MaxNm: 1360},
+	{Name: "E", MinNm: 1360, MaxNm: 1460},
+	{Name: "S", MinNm: 1460, MaxNm: 1530},
+	{Name: "C", MinNm: 1530, MaxNm: 1565},
+	{Name: "L", MinNm: 1565, MaxNm: 1625},
+	{Name: "U", MinNm: 1625, MaxNm: 1675},
+}
+
+// visibleColors divide the visible range, 380-750 nm, by perceived color
+var visibleColors = []opticalBand{
+	{Name: "violet", MinNm: 380, MaxNm: 450},
+	{Name: "blue", MinNm: 450, MaxNm: 495},
+	{Name: "green", MinNm: 495, MaxNm: 570},
+	{Name: "yellow", MinNm: 570, MaxNm: 590},
+	{Name: "orange", MinNm: 590, MaxNm: 620},
+	{Name: "red", MinNm: 620, MaxNm: 750},
+}
+
+// WavelengthInfo classifies a vacuum wavelength and gives its photon's
+// frequency and energy
+type WavelengthInfo struct {
+	WavelengthNm   float64 `json:"wavelength_nm"`
+	Region         string  `json:"region"`          // ultraviolet, visible, infrared
+	Band           string  `json:"band,omitempty"`  // telecom band
+	Color          string  `json:"color,omitempty"` // visible color name
+	RGB            string  `json:"rgb,omitempty"`   // approximate sRGB, #rrggbb
+	FrequencyHz    float64 `json:"frequency_hz"`
+	PhotonEnergyJ  float64 `json:"photon_energy_j"`
+	PhotonEnergyEV float64 `json:"photon_energy_ev"`
+	// CorridorBand reports whether the wavelength lies in a band corrd
+	// allocates corridors in
+	CorridorBand bool `json:"corridor_band"`
+}
+
+// findBand returns the band of bands containing nm
+func findBand(bands []opticalBand, nm float64) (string, bool) {
+	for _, b := range bands {
+		if nm >= b.MinNm && nm < b.MaxNm {
+			return b.Name, true
+		}
+	}
+	return "", false
+}
+
+// WavelengthInfo classifies a wavelength in nm, deriving its frequency and
+// photon energy with the λ = c/f and E = hf calculators
+func (p *PhysicsDecoderService) WavelengthInfo(nm float64) (*WavelengthInfo, error) {
+	if nm <= 0 || math.IsInf(nm, 0) || math.IsNaN(nm) {
+		return nil, fmt.Errorf("wavelength must be a positive number of nm")
+	}
+
+	frequency, _, err := p.calculateFrequencyFromWavelength(map[string]float64{"λ": nm}, map[string]string{"λ": "nm"})
+	if err != nil {
+		return nil, err
+	}
+	energy, _, err := p.calculatePhotonEnergy(map[string]float64{"f": frequency}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &WavelengthInfo{
+		WavelengthNm:   nm,
+		FrequencyHz:    frequency,
+		PhotonEnergyJ:  energy,
+		PhotonEnergyEV: energy / p.ElectronCharge,
+	}
+	switch {
+	case nm < visibleColors[0].MinNm:
+		info.Region = "ultraviolet"
+	case nm < visibleColors[len(visibleColors)-1].MaxNm:
+		info.Region = "visible"
+		info.Color, _ = findBand(visibleColors, nm)
+		info.RGB = wavelengthRGB(nm)
+	default:
+		info.Region = "infrared"
+	}
+	info.Band, info.CorridorBand = findBand(telecomBands, nm)
+	return info, nil
+}
+
+// wavelengthRGB approximates the sRGB color of monochromatic light in the
+// visible range, after Bruton's piecewise-linear model, dimming toward the
+// ends of the range where the eye is less sensitive
+func wavelengthRGB(nm float64) string {
+	var r, g, b float64
+	switch {
+	case nm < 440:
+		r, b = (440-nm)/(440-380), 1
+	case nm < 490:
+		g, b = (nm-440)/(490-440), 1
+	case nm < 510:
+		g, b = 1, (510-nm)/(510-490)
+	case nm < 580:
+		r, g = (nm-510)/(580-510), 1
+	case nm < 645:
+		r, g = 1, (645-nm)/(645-580)
+	default:
+		r = 1
+	}
+
+	intensity := 1.0
+	switch {
+	case nm < 420:
+		intensity = 0.3 + 0.7*(nm-380)/(420-380)
+	case nm > 700:
+		intensity = 0.3 + 0.7*(750-nm)/(750-700)
+	}
+	channel := func(v float64) int {
+		if v == 0 {
+			return 0
+		}
+		return int(math.Round(255 * math.Pow(v*intensity, 0.8)))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", channel(r), channel(g), channel(b))
+}
+
+func (p *PhysicsDecoderService) handleWavelengthInfo(w http.ResponseWriter, r *http.Request) {
+	nm, err := strconv.ParseFloat(r.URL.Query().Get("nm"), 64)
+	if err != nil {
+		apierr.Respond(w, apierr.CodeBadRequest, "nm must be a wavelength in nm")
+		return
+	}
+
+	info, err := p.WavelengthInfo(nm)
+	if err != nil {
+		apierr.Respond(w, apierr.CodeValidation, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// wavelengthInfo looks up nm through the HTTP handler
+func wavelengthInfo(t *testing.T, nm string) (int, WavelengthInfo) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	NewPhysicsDecoderService().handleWavelengthInfo(rec, httptest.NewRequest("GET", "/v1/physics/wavelength-info?nm="+nm, nil))
+	var info WavelengthInfo
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, info
+}
+
+func TestWavelengthInfoCBand(t *testing.T) {
+	code, info := wavelengthInfo(t, "1550")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if info.Band != "C" || !info.CorridorBand || info.Region != "infrared" || info.Color != "" {
+		t.Errorf("1550 nm = %+v", info)
+	}
+	if want := 299792458 / 1550e-9; math.Abs(info.FrequencyHz-want) > 1e-9*want {
+		t.Errorf("frequency = %g Hz, want %g", info.FrequencyHz, want)
+	}
+	if math.Abs(info.PhotonEnergyEV-0.8) > 0.001 {
+		t.Errorf("photon energy = %g eV, want about 0.8", info.PhotonEnergyEV)
+	}
+}
+
+func TestWavelengthInfoVisibleGreen(t *testing.T) {
+	_, info := wavelengthInfo(t, "550")
+	if info.Region != "visible" || info.Color != "green" || info.Band != "" || info.CorridorBand {
+		t.Errorf("550 nm = %+v", info)
+	}
+	if info.RGB == "" || info.RGB[3:5] != "ff" {
+		t.Errorf("550 nm RGB = %q, want full green", info.RGB)
+	}
+}
+
+func TestWavelengthInfoBoundariesAndErrors(t *testing.T) {
+	for nm, band := range map[string]string{"1260": "O", "1529.9": "S", "1530": "C", "1565": "L", "1674": "U", "1700": ""} {
+		if _, info := wavelengthInfo(t, nm); info.Band != band {
+			t.Errorf("%s nm band = %q, want %q", nm, info.Band, band)
+		}
+	}
+	if _, info := wavelengthInfo(t, "300"); info.Region != "ultraviolet" {
+		t.Errorf("300 nm region = %q", info.Region)
+	}
+	for nm, want := range map[string]int{"": http.StatusBadRequest, "abc": http.StatusBadRequest, "0": http.StatusBadRequest, "-5": http.StatusBadRequest} {
+		if code, _ := wavelengthInfo(t, nm); code != want {
+			t.Errorf("nm=%q: status = %d, want %d", nm, code, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"testing"
+)
+
+func TestBootstrapIntervals(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const points = 200
+	a, b, c := make([]float64, points), make([]float64, points), make([]float64, points)
+	for i := range a {
+		x := float64(i) * 0.1
+		a[i] = math.Sin(x)
+		b[i] = math.Sin(x) + 0.1*rng.NormFloat64() // strongly coupled to a
+		c[i] = rng.NormFloat64()                   // independent of a
+	}
+
+	pairCI, gsiCI := bootstrapCIs([][]float64{zscore(a), zscore(b), zscore(c)}, []string{"a", "b", "c"}, 1000, rand.New(rand.NewSource(2)))
+	if len(pairCI) != 3 {
+		t.Fatalf("intervals for %d pairs, want 3", len(pairCI))
+	}
+
+	strong := pairCI["a|b"]
+	if strong.Lower < 0.9 || strong.Upper > 1 || strong.Upper-strong.Lower > 0.05 {
+		t.Errorf("strong pair interval = %+v, want tight and near 1", strong)
+	}
+	weak := pairCI["a|c"]
+	if weak.Lower >= 0 || weak.Upper <= 0 {
+		t.Errorf("weak pair interval = %+v, want one straddling 0", weak)
+	}
+	if gsiCI.Lower > gsiCI.Upper {
+		t.Errorf("group interval = %+v", gsiCI)
+	}
+}
+
+func TestMetricsBootstrapParam(t *testing.T) {
+	svc := NewService()
+	id := startSession(t, svc, "p1", "p2", "p3")
+	ingest(t, svc, id, wave("p1", 0, 120, 0), wave("p2", 0, 120, 0.2), wave("p3", 0, 120, 0.4))
+
+	code, resp := metrics(t, svc, id, "bootstrap=200&precision=4")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if !hasNote(resp.Notes, "bootstrap:200") || resp.GroupSynchronyCI == nil {
+		t.Fatalf("response lacks bootstrap output: %+v", resp)
+	}
+	if ci := resp.GroupSynchronyCI; ci.Lower > ci.Upper || ci.Upper > 1 {
+		t.Errorf("group interval = %+v", ci)
+	}
+	for _, pc := range resp.Pairs {
+		if pc.CI == nil || pc.CI.Lower > pc.CI.Upper {
+			t.Errorf("pair %s interval = %+v", pc.Pair, pc.CI)
+		}
+	}
+
+	if _, plain := metrics(t, svc, id, ""); plain.GroupSynchronyCI != nil || plain.Pairs[0].CI != nil {
+		t.Error("intervals reported without bootstrap")
+	}
+	for _, q := range []string{"bootstrap=0", "bootstrap=10001", "bootstrap=many"} {
+		if code, _ := metrics(t, svc, id, q); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, code)
+		}
+	}
+}
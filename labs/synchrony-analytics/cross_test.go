@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"testing"
+)
+
+// rrFromBreath is an RR-interval series coupled to a breath wave, as in
+// respiratory sinus arrhythmia: RR shortens on inhalation
+func rrFromBreath(breath Series, noise float64) Series {
+	rr := Series{Pseudonym: breath.Pseudonym}
+	for i, t := range breath.T {
+		rr.T = append(rr.T, t+0.1)
+		rr.V = append(rr.V, 0.8-0.05*breath.V[i]+noise*math.Sin(7.3*t))
+	}
+	return rr
+}
+
+func crossMetrics(t *testing.T, svc *Service, id, query string) (int, CrossMetricsResponse) {
+	t.Helper()
+	var resp CrossMetricsResponse
+	code := call(t, svc.handleCrossMetrics, http.MethodGet, "/v1/synchrony/session/"+id+"/metrics/cross?"+query, nil, &resp)
+	return code, resp
+}
+
+func TestCrossStreamCoupledSignals(t *testing.T) {
+	s := NewService()
+	s.MinGroupSize = 2 // pairs are enough here
+	id := startSession(t, s, "alice", "bob", "carol")
+	alice, bob := wave("alice", 0, 120, 0), wave("bob", 0, 120, 0.7)
+	ingestStream(t, s, id, "breath", alice, bob, wave("carol", 0, 120, 0.3))
+	ingestStream(t, s, id, "rr", rrFromBreath(alice, 0.01), rrFromBreath(bob, 0.01))
+
+	code, res := crossMetrics(t, s, id, "streams=breath,rr")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if len(res.Participants) != 2 || res.Participants[0] != "alice" || res.Participants[1] != "bob" {
+		t.Fatalf("participants = %q, want [alice bob]", res.Participants)
+	}
+	// RR falls as breath rises, so coupling shows as strong anticorrelation
+	for name, c := range res.CrossCorrelation {
+		if c > -0.9 {
+			t.Errorf("%s cross-stream correlation = %.3f, want < -0.9", name, c)
+		}
+	}
+	if res.GroupCrossIndex > -0.9 {
+		t.Errorf("group cross index = %.3f", res.GroupCrossIndex)
+	}
+	if !hasNote(res.Notes, "carol: missing rr stream, excluded") {
+		t.Errorf("notes %q do not record carol's exclusion", res.Notes)
+	}
+}
+
+func TestCrossStreamRejectsBadStreams(t *testing.T) {
+	s := NewService()
+	id := startSession(t, s, "alice")
+	for _, q := range []string{"streams=breath", "streams=breath,breath", "streams=breath,rr,ecg"} {
+		if code, _ := crossMetrics(t, s, id, q); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, code)
+		}
+	}
+	if code, _ := crossMetrics(t, s, id, "streams=breath,rr"); code != http.StatusBadRequest {
+		t.Errorf("no overlapping participants: status = %d, want 400", code)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+func TestErrorCodes(t *testing.T) {
+	svc := NewService()
+	id := startSession(t, svc, "p1", "p2")
+
+	noConsent := manifest("p1", "p2")
+	noConsent.Participants[1].Consent = false
+	offline := manifest("p1")
+	offline.CaptureMode = "live"
+
+	for _, tc := range []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		path    string
+		body    any
+		code    apierr.Code
+		status  int
+	}{
+		{"malformed body", svc.handleStartSession, http.MethodPost, "/v1/synchrony/session/start", "{", apierr.CodeBadRequest, http.StatusBadRequest},
+		{"missing consent", svc.handleStartSession, http.MethodPost, "/v1/synchrony/session/start", StartSessionRequest{Manifest: noConsent}, apierr.CodeForbidden, http.StatusForbidden},
+		{"live capture", svc.handleStartSession, http.MethodPost, "/v1/synchrony/session/start", StartSessionRequest{Manifest: offline}, apierr.CodeForbidden, http.StatusForbidden},
+		{"unknown session", svc.handleMetrics, http.MethodGet, "/v1/synchrony/session/missing/metrics", nil, apierr.CodeNotFound, http.StatusNotFound},
+		{"unsupported interp", svc.handleMetrics, http.MethodGet, "/v1/synchrony/session/" + id + "/metrics?interp=spline", nil, apierr.CodeValidation, http.StatusBadRequest},
+	} {
+		var buf bytes.Buffer
+		if s, ok := tc.body.(string); ok {
+			buf.WriteString(s)
+		} else if tc.body != nil {
+			json.NewEncoder(&buf).Encode(tc.body)
+		}
+		rec := httptest.NewRecorder()
+		tc.handler(rec, httptest.NewRequest(tc.method, tc.path, &buf))
+		var e apierr.Error
+		if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+			t.Fatalf("%s: error body %q: %v", tc.name, rec.Body, err)
+		}
+		if rec.Code != tc.status || e.Code != tc.code {
+			t.Errorf("%s: %d %+v, want %d %s", tc.name, rec.Code, e, tc.status, tc.code)
+		}
+	}
+}
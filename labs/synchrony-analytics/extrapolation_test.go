@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"testing"
+)
+
+// TestClampExtrapolationBiasesCorrelation correlates two identical signals,
+// one of which stops early. Clamping flattens its tail and drags the
+// correlation down; nan-exclude drops the tail and keeps it at one.
+func TestClampExtrapolationBiasesCorrelation(t *testing.T) {
+	svc := NewService()
+	svc.MinGroupSize = 2
+	id := startSession(t, svc, "full", "short")
+	ingest(t, svc, id, wave("full", 0, 160, 0), wave("short", 0, 80, 0))
+
+	code, clamped := metrics(t, svc, id, "extrapolation=clamp")
+	if code != http.StatusOK {
+		t.Fatalf("clamp: status = %d", code)
+	}
+	code, excluded := metrics(t, svc, id, "")
+	if code != http.StatusOK {
+		t.Fatalf("default: status = %d", code)
+	}
+	if !hasNote(excluded.Notes, "extrapolation:nan-exclude") {
+		t.Errorf("default policy notes %q lack extrapolation:nan-exclude", excluded.Notes)
+	}
+
+	biased := clamped.PairwiseCorrelation["full|short"]
+	unbiased := excluded.PairwiseCorrelation["full|short"]
+	if math.Abs(unbiased-1) > 1e-6 {
+		t.Errorf("nan-exclude correlation = %g, want 1", unbiased)
+	}
+	if biased > 0.9 {
+		t.Errorf("clamp correlation = %g, want visibly biased below 0.9", biased)
+	}
+}
+
+func TestExtrapolationErrorPolicy(t *testing.T) {
+	svc := NewService()
+	svc.MinGroupSize = 2
+	id := startSession(t, svc, "full", "short")
+	ingest(t, svc, id, wave("full", 0, 160, 0), wave("short", 0, 80, 0))
+	if code, _ := metrics(t, svc, id, "extrapolation=error"); code != http.StatusBadRequest {
+		t.Errorf("error policy past a series' end: status = %d, want 400", code)
+	}
+	if code, _ := metrics(t, svc, id, "extrapolation=zero"); code != http.StatusBadRequest {
+		t.Errorf("unknown policy: status = %d, want 400", code)
+	}
+
+	// Equal supports need no extrapolation, so even the error policy passes
+	id = startSession(t, svc, "a", "b")
+	ingest(t, svc, id, wave("a", 0, 80, 0), wave("b", 0, 80, 0.4))
+	if code, _ := metrics(t, svc, id, "extrapolation=error"); code != http.StatusOK {
+		t.Errorf("error policy within support: status = %d, want 200", code)
+	}
+}
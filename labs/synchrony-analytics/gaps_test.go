@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestMaskExcludesLongGap drops 20 s from the middle of one of two
+// in-phase series; interpolating across the dropout pairs a straight line
+// with the other's oscillation, masking leaves only the real samples
+func TestMaskExcludesLongGap(t *testing.T) {
+	svc := NewService()
+	svc.MinGroupSize = 2
+	id := startSession(t, svc, "p1", "p2")
+	gapped := wave("p2", 0, 40, 0)
+	tail := wave("p2", 120, 240, 0)
+	gapped.T = append(gapped.T, tail.T...)
+	gapped.V = append(gapped.V, tail.V...)
+	ingest(t, svc, id, wave("p1", 0, 240, 0), gapped)
+
+	code, interpolated := metrics(t, svc, id, "gap=interpolate")
+	if code != http.StatusOK {
+		t.Fatalf("interpolate: status %d", code)
+	}
+	code, masked := metrics(t, svc, id, "gap=mask")
+	if code != http.StatusOK {
+		t.Fatalf("mask: status %d", code)
+	}
+	if masked.GroupSynchronyIndex < 0.999 {
+		t.Errorf("masked GSI = %v, want ~1 for identical samples", masked.GroupSynchronyIndex)
+	}
+	if interpolated.GroupSynchronyIndex > masked.GroupSynchronyIndex-0.1 {
+		t.Errorf("interpolated GSI %v is not clearly below masked %v", interpolated.GroupSynchronyIndex, masked.GroupSynchronyIndex)
+	}
+	if !hasNote(masked.Notes, "gap:mask") {
+		t.Errorf("notes = %v", masked.Notes)
+	}
+
+	// A threshold above the dropout interpolates across it again
+	_, wide := metrics(t, svc, id, "gap=mask&gap_seconds=30")
+	if wide.GroupSynchronyIndex != interpolated.GroupSynchronyIndex {
+		t.Errorf("gap_seconds=30 GSI %v, want the interpolated %v", wide.GroupSynchronyIndex, interpolated.GroupSynchronyIndex)
+	}
+}
+
+func TestMinOverlapSeconds(t *testing.T) {
+	svc := NewService()
+	svc.MinGroupSize = 2
+	id := startSession(t, svc, "p1", "p2")
+	// 3.75 s of common support, under the 5 s default
+	ingest(t, svc, id, wave("p1", 0, 16, 0), wave("p2", 0, 16, 0.2))
+
+	if code, _ := metrics(t, svc, id, ""); code != http.StatusBadRequest {
+		t.Errorf("default overlap: status %d, want %d", code, http.StatusBadRequest)
+	}
+	if code, _ := metrics(t, svc, id, "min_overlap_seconds=3"); code != http.StatusOK {
+		t.Errorf("min_overlap_seconds=3: status %d, want %d", code, http.StatusOK)
+	}
+	for _, q := range []string{"min_overlap_seconds=-1", "min_overlap_seconds=x", "gap=drop", "gap=mask&gap_seconds=0"} {
+		if code, _ := metrics(t, svc, id, q); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d", q, code, http.StatusBadRequest)
+		}
+	}
+}
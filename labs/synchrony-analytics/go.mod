@@ -1,4 +1,7 @@
 module synchrony-analytics
 
-go 1.21
+go 1.27
 
+require github.com/corridoros/pkg v0.0.0
+
+replace github.com/corridoros/pkg => ../../pkg
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// runningStats is a stream's incremental synchrony state. Each participant's
+// samples are linearly resampled onto a fixed grid as they arrive, and every
+// pair keeps the sums Pearson's r is computed from over the grid points both
+// cover, so an ingest costs O(new samples) and reading the group synchrony
+// index costs O(pairs). The grid is anchored at the stream's first sample
+// (floored at 0, as the batch grid is), so it matches the batch metrics
+// whenever no participant starts before that sample.
+type runningStats struct {
+	anchor       float64
+	anchored     bool
+	participants map[string]*runningSeries
+	pairs        map[[2]string]*pairSums // key: pseudonyms in sorted order
+}
+
+// runningSeries is one participant's resampled values and the last sample
+// the next grid points are interpolated from
+type runningSeries struct {
+	first, lastT, lastV float64
+	start               int       // grid index of vals[0]
+	vals                []float64 // resampled values from grid index start
+}
+
+// pairSums are the sufficient statistics of one pair's correlation; x is
+// the first pseudonym of the pair key, y the second
+type pairSums struct {
+	n, sx, sy, sxx, syy, sxy float64
+}
+
+func newRunningStats() *runningStats {
+	return &runningStats{
+		participants: make(map[string]*runningSeries),
+		pairs:        make(map[[2]string]*pairSums),
+	}
+}
+
+// incrementalStep is the grid spacing, the batch metrics' step
+const incrementalStep = 0.5 // seconds
+
+func (rs *runningStats) gridPoint(k int) float64 {
+	return rs.anchor + float64(k)*incrementalStep
+}
+
+// add feeds a participant's newly appended samples, which must follow
+// their earlier ones in time; samples that do not are skipped
+func (rs *runningStats) add(srs Series) {
+	for i := range srs.T {
+		rs.addSample(srs.Pseudonym, srs.T[i], srs.V[i])
+	}
+}
+
+func (rs *runningStats) addSample(name string, t, v float64) {
+	if !rs.anchored {
+		rs.anchor, rs.anchored = math.Max(t, 0), true
+	}
+	p, ok := rs.participants[name]
+	if !ok {
+		// The first grid point at or after t, never before the anchor
+		k := int(math.Ceil((t - rs.anchor) / incrementalStep))
+		for rs.gridPoint(k-1) >= t {
+			k--
+		}
+		for rs.gridPoint(k) < t {
+			k++
+		}
+		p = &runningSeries{first: t, lastT: t, lastV: v, start: max(k, 0)}
+		rs.participants[name] = p
+		if rs.gridPoint(p.start) == t {
+			rs.emit(name, p, v)
+		}
+		return
+	}
+	if t <= p.lastT {
+		return
+	}
+	for k := p.start + len(p.vals); rs.gridPoint(k) <= t; k++ {
+		alpha := (rs.gridPoint(k) - p.lastT) / (t - p.lastT)
+		rs.emit(name, p, p.lastV+alpha*(v-p.lastV))
+	}
+	p.lastT, p.lastV = t, v
+}
+
+// emit appends the participant's value at its next grid point, pairing it
+// with every other participant that already has a value there
+func (rs *runningStats) emit(name string, p *runningSeries, x float64) {
+	k := p.start + len(p.vals)
+	for other, q := range rs.participants {
+		if other == name || k < q.start || k >= q.start+len(q.vals) {
+			continue
+		}
+		key, a, b := [2]string{name, other}, x, q.vals[k-q.start]
+		if other < name {
+			key, a, b = [2]string{other, name}, b, a
+		}
+		sums, ok := rs.pairs[key]
+		if !ok {
+			sums = &pairSums{}
+			rs.pairs[key] = sums
+		}
+		sums.n++
+		sums.sx += a
+		sums.sy += b
+		sums.sxx += a * a
+		sums.syy += b * b
+		sums.sxy += a * b
+	}
+	p.vals = append(p.vals, x)
+}
+
+// remove forgets a participant and every pair they are part of
+func (rs *runningStats) remove(name string) {
+	delete(rs.participants, name)
+	for key := range rs.pairs {
+		if key[0] == name || key[1] == name {
+			delete(rs.pairs, key)
+		}
+	}
+}
+
+// names returns the participants in sorted order
+func (rs *runningStats) names() []string {
+	names := make([]string, 0, len(rs.participants))
+	for name := range rs.participants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// overlap returns the common support of the participants, the window the
+// batch metrics gate on
+func (rs *runningStats) overlap() float64 {
+	start, end := -math.MaxFloat64, math.MaxFloat64
+	for _, p := range rs.participants {
+		start = math.Max(start, p.first)
+		end = math.Min(end, p.lastT)
+	}
+	return math.Max(end, 0) - math.Max(start, 0)
+}
+
+// span returns the window the grid covers so far
+func (rs *runningStats) span() float64 {
+	end := rs.anchor
+	for _, p := range rs.participants {
+		end = math.Max(end, p.lastT)
+	}
+	return end - rs.anchor
+}
+
+// correlations returns every pair's Pearson r, 0 for pairs with no common
+// points or a constant side, as the batch pearson does
+func (rs *runningStats) correlations() map[string]float64 {
+	names := rs.names()
+	out := map[string]float64{}
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			key := [2]string{names[i], names[j]}
+			out[pairKey(key[0], key[1])] = rs.pairs[key].pearson()
+		}
+	}
+	return out
+}
+
+func (ps *pairSums) pearson() float64 {
+	if ps == nil || ps.n == 0 {
+		return 0
+	}
+	cov := ps.sxy - ps.sx*ps.sy/ps.n
+	va := ps.sxx - ps.sx*ps.sx/ps.n
+	vb := ps.syy - ps.sy*ps.sy/ps.n
+	// Cancellation leaves a constant series a tiny residual variance
+	if va <= 1e-12*ps.sxx || vb <= 1e-12*ps.syy {
+		return 0
+	}
+	return cov / math.Sqrt(va*vb)
+}
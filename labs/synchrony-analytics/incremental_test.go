@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func incrementalMetrics(t *testing.T, svc *Service, id string) MetricsResponse {
+	t.Helper()
+	var resp MetricsResponse
+	if code := call(t, svc.handleIncrementalMetrics, http.MethodGet, "/v1/synchrony/session/"+id+"/metrics/incremental", nil, &resp); code != http.StatusOK {
+		t.Fatalf("incremental metrics: status %d", code)
+	}
+	return resp
+}
+
+func TestIncrementalMatchesBatchAfterAppends(t *testing.T) {
+	svc := NewService()
+	names := []string{"p1", "p2", "p3", "p4"}
+	id := startSession(t, svc, names...)
+
+	// Participants arrive in uneven chunks; a re-sent chunk is ignored
+	chunks := [][2]int{{0, 30}, {30, 47}, {40, 90}, {90, 91}, {91, 160}}
+	for _, c := range chunks {
+		var series []Series
+		for i, name := range names {
+			series = append(series, wave(name, c[0], c[1]+i, float64(i)*0.4))
+		}
+		ingest(t, svc, id, series...)
+
+		code, batch := metrics(t, svc, id, "")
+		if code != http.StatusOK {
+			t.Fatalf("batch metrics: status %d", code)
+		}
+		incremental := incrementalMetrics(t, svc, id)
+		if d := math.Abs(batch.GroupSynchronyIndex - incremental.GroupSynchronyIndex); d > 1e-9 {
+			t.Errorf("after samples to %d: incremental GSI %v, batch %v", c[1], incremental.GroupSynchronyIndex, batch.GroupSynchronyIndex)
+		}
+		for key, want := range batch.PairwiseCorrelation {
+			if got := incremental.PairwiseCorrelation[key]; math.Abs(got-want) > 1e-9 {
+				t.Errorf("after samples to %d: pair %s incremental %v, batch %v", c[1], key, got, want)
+			}
+		}
+		if len(incremental.PairwiseCorrelation) != len(batch.PairwiseCorrelation) {
+			t.Errorf("after samples to %d: %d incremental pairs, %d batch", c[1], len(incremental.PairwiseCorrelation), len(batch.PairwiseCorrelation))
+		}
+	}
+}
+
+func TestIncrementalDropsRevokedParticipant(t *testing.T) {
+	svc := NewService()
+	id := startSession(t, svc, "p1", "p2", "p3", "p4")
+	ingest(t, svc, id, wave("p1", 0, 80, 0), wave("p2", 0, 80, 0.3), wave("p3", 0, 80, 0.6), wave("p4", 0, 80, 0.9))
+	call(t, svc.handleRevoke, http.MethodPost, "/v1/synchrony/session/"+id+"/revoke", RevokeRequest{Pseudonym: "p2"}, nil)
+
+	_, batch := metrics(t, svc, id, "")
+	incremental := incrementalMetrics(t, svc, id)
+	if len(incremental.PairwiseCorrelation) != 3 {
+		t.Fatalf("incremental pairs = %v, want the 3 without p2", incremental.PairwiseCorrelation)
+	}
+	for key := range incremental.PairwiseCorrelation {
+		if strings.Contains(key, "p2") {
+			t.Errorf("revoked participant in incremental pair %s", key)
+		}
+	}
+	if d := math.Abs(batch.GroupSynchronyIndex - incremental.GroupSynchronyIndex); d > 1e-9 {
+		t.Errorf("after revocation: incremental GSI %v, batch %v", incremental.GroupSynchronyIndex, batch.GroupSynchronyIndex)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// sineError resamples a coarsely sampled sine with method and returns the
+// worst absolute error against the true sine on a fine grid
+func sineError(t *testing.T, method string) float64 {
+	t.Helper()
+	var ts, vs []float64
+	for x := 0.0; x <= 2*math.Pi+1e-9; x += 0.4 {
+		ts = append(ts, x)
+		vs = append(vs, math.Sin(x))
+	}
+	grid := makeGrid(ts[1], ts[len(ts)-2], 0.01)
+	y, err := resample(grid, ts, vs, method, extrapClamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worst := 0.0
+	for i, x := range grid {
+		worst = math.Max(worst, math.Abs(y[i]-math.Sin(x)))
+	}
+	return worst
+}
+
+func TestCubicBeatsLinearOnSine(t *testing.T) {
+	linear := sineError(t, interpLinear)
+	cubic := sineError(t, interpCubic)
+	nearest := sineError(t, interpNearest)
+	if cubic >= linear {
+		t.Errorf("cubic error %.4g is not below linear error %.4g", cubic, linear)
+	}
+	if linear >= nearest {
+		t.Errorf("linear error %.4g is not below nearest error %.4g", linear, nearest)
+	}
+}
+
+func TestResampleHitsSamples(t *testing.T) {
+	ts := []float64{0, 1, 2, 3, 4}
+	vs := []float64{0, 1, 0, -1, 0}
+	for _, method := range []string{interpLinear, interpNearest, interpCubic} {
+		y, err := resample(ts, ts, vs, method, extrapClamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range ts {
+			if math.Abs(y[i]-vs[i]) > 1e-12 {
+				t.Errorf("%s: y(%g) = %g, want sample %g", method, ts[i], y[i], vs[i])
+			}
+		}
+	}
+}
+
+func TestMetricsInterpParam(t *testing.T) {
+	s := NewService()
+	s.MinGroupSize = 2 // pairs are enough here
+	id := startSession(t, s, "alice", "bob")
+	ingest(t, s, id, wave("alice", 0, 80, 0), wave("bob", 0, 80, 0.2))
+
+	for interp, want := range map[string]int{"": 200, "linear": 200, "cubic": 200, "nearest": 200, "spline": 400} {
+		code, res := metrics(t, s, id, "interp="+interp)
+		if code != want {
+			t.Errorf("interp=%q: status = %d, want %d", interp, code, want)
+			continue
+		}
+		if code != 200 {
+			continue
+		}
+		expect := interp
+		if expect == "" {
+			expect = interpLinear
+		}
+		if !hasNote(res.Notes, "interp:"+expect) {
+			t.Errorf("interp=%q: notes %q lack interp:%s", interp, res.Notes, expect)
+		}
+	}
+}
+
+func TestCubicFallsBackWithFewSamples(t *testing.T) {
+	s := NewService()
+	s.MinGroupSize = 2 // pairs are enough here
+	id := startSession(t, s, "alice", "bob")
+	ingest(t, s, id, wave("alice", 0, 80, 0), Series{Pseudonym: "bob", T: []float64{0, 10, 20}, V: []float64{0, 1, 0}})
+
+	code, res := metrics(t, s, id, "interp=cubic")
+	if code != 200 {
+		t.Fatalf("status = %d", code)
+	}
+	if !hasNote(res.Notes, "bob: fewer than 4 samples, linear interpolation used") {
+		t.Errorf("notes %q lack the linear fallback", res.Notes)
+	}
+}
+
+func hasNote(notes []string, want string) bool {
+	for _, n := range notes {
+		if n == want {
+			return true
+		}
+	}
+	return false
+}
@@ -5,13 +5,23 @@ import (
     "encoding/hex"
     "encoding/json"
     "errors"
+    "flag"
+    "fmt"
     "log"
     "math"
+    "math/rand"
     "net/http"
     "sort"
+    "strconv"
     "strings"
     "sync"
     "time"
+
+    "github.com/corridoros/pkg/apierr"
+    "github.com/corridoros/pkg/bounded"
+    "github.com/corridoros/pkg/jsonbody"
+    "github.com/corridoros/pkg/tlsserve"
+    "github.com/corridoros/pkg/trace"
 )
 
 // Consent and governance
@@ -21,10 +31,11 @@ type Governance struct {
 }
 
 type Participant struct {
-    Pseudonym     string   `json:"pseudonym"`
-    Consent       bool     `json:"consent"`
-    Scope         []string `json:"scope"`
-    RetentionDays int      `json:"retention_days"`
+    Pseudonym     string     `json:"pseudonym"`
+    Consent       bool       `json:"consent"`
+    Scope         []string   `json:"scope"`
+    RetentionDays int        `json:"retention_days"`
+    RevokedAt     *time.Time `json:"revoked_at,omitempty"` // set when consent is withdrawn
 }
 
 type ConsentManifest struct {
@@ -39,10 +50,13 @@ type ConsentManifest struct {
 
 // Synchrony session store
 type Session struct {
-    ID         string
-    Manifest   ConsentManifest
-    CreatedAt  time.Time
-    Streams    map[string][]Series // key: stream type ("breath" or "rr")
+    ID            string
+    AttestationID string
+    Manifest      ConsentManifest
+    CreatedAt     time.Time
+    Streams       map[string][]Series // key: stream type ("breath" or "rr")
+    running       map[string]*runningStats // incremental metrics per stream
+    salt          []byte // set when pseudonyms are hashed on ingest
 }
 
 type Series struct {
@@ -54,13 +68,29 @@ type Series struct {
 // Requests / responses
 type StartSessionRequest struct {
     Manifest ConsentManifest `json:"manifest"`
+    // HashPseudonyms stores and reports each participant under an HMAC of
+    // their pseudonym keyed by a salt drawn for this session, so the same
+    // participant cannot be linked across sessions
+    HashPseudonyms bool `json:"hash_pseudonyms,omitempty"`
 }
 
 type StartSessionResponse struct {
-    SessionID      string `json:"session_id"`
-    AttestationID  string `json:"attestation_id"`
-    ManifestHash   string `json:"manifest_hash"`
-    Flags          []string `json:"flags"`
+    SessionID        string   `json:"session_id"`
+    AttestationID    string   `json:"attestation_id"`
+    ManifestHash     string   `json:"manifest_hash"`
+    Flags            []string `json:"flags"`
+    HashedPseudonyms bool     `json:"hashed_pseudonyms"`
+}
+
+type RevokeRequest struct {
+    Pseudonym string `json:"pseudonym"`
+}
+
+type RevokeResponse struct {
+    Pseudonym     string          `json:"pseudonym"`
+    RevokedAt     time.Time       `json:"revoked_at"`
+    SeriesDeleted int             `json:"series_deleted"` // across all streams
+    Manifest      ConsentManifest `json:"manifest"`
 }
 
 type IngestRequest struct {
@@ -69,22 +99,88 @@ type IngestRequest struct {
 }
 
 type MetricsResponse struct {
-    Stream              string             `json:"stream"`
-    Participants        []string           `json:"participants"`
-    WindowSeconds       float64            `json:"window_seconds"`
-    PairwiseCorrelation map[string]float64 `json:"pairwise_correlation"`
-    GroupSynchronyIndex float64            `json:"group_synchrony_index"`
-    Notes               []string           `json:"notes"`
+    Stream              string              `json:"stream"`
+    Participants        []string            `json:"participants"`
+    WindowSeconds       float64             `json:"window_seconds"`
+    PairwiseCorrelation map[string]float64  `json:"pairwise_correlation"` // keyed by pairKey
+    Pairs               []PairCorrelation   `json:"pairs"` // pairwise_correlation sorted by pair
+    GroupSynchronyIndex float64             `json:"group_synchrony_index"`
+    GroupSynchronyCI    *ConfidenceInterval `json:"group_synchrony_ci,omitempty"` // with bootstrap
+    Notes               []string            `json:"notes"`
+}
+
+type PairCorrelation struct {
+    A     string              `json:"a"`
+    B     string              `json:"b"`
+    Pair  string              `json:"pair"` // pairKey(a, b), the pairwise_correlation key
+    Value float64             `json:"value"`
+    CI    *ConfidenceInterval `json:"ci,omitempty"` // with bootstrap
+}
+
+// ConfidenceInterval is a 95% percentile bootstrap interval
+type ConfidenceInterval struct {
+    Lower float64 `json:"lower"` // 2.5th percentile
+    Upper float64 `json:"upper"` // 97.5th percentile
+}
+
+type CrossMetricsResponse struct {
+    Streams          []string           `json:"streams"`
+    Participants     []string           `json:"participants"`
+    CrossCorrelation map[string]float64 `json:"cross_correlation"` // per participant
+    GroupCrossIndex  float64            `json:"group_cross_index"`
+    Notes            []string           `json:"notes"`
+}
+
+// maxPrecision is the most decimal places a float64 carries meaningfully
+const maxPrecision = 15
+
+// maxBootstrap caps the bootstrap resamples a metrics request may ask for
+const maxBootstrap = 10000
+
+// defaultMinGroupSize is the smallest group metrics are reported for;
+// smaller groups can deanonymize individuals
+const defaultMinGroupSize = 3
+
+// GroupPrivacyDetails explains a refusal by the minimum-group-size guard
+type GroupPrivacyDetails struct {
+    GroupSize    int    `json:"group_size"`
+    MinGroupSize int    `json:"min_group_size"`
+    Note         string `json:"note"`
 }
 
 // Service implementation
 type Service struct {
     mu       sync.RWMutex
-    sessions map[string]*Session
+    sessions *bounded.BoundedStore[*Session] // least recently used evicted past the cap
+
+    // MinGroupSize is the fewest participants, after exclusions and
+    // revocations, that group metrics are computed for
+    MinGroupSize int
 }
 
+// defaultMaxSessions bounds the sessions held in memory
+const defaultMaxSessions = 1024
+
 func NewService() *Service {
-    return &Service{sessions: make(map[string]*Session)}
+    sessions := bounded.New[*Session](defaultMaxSessions)
+    sessions.OnEvict = func(id string, _ *Session, reason string) {
+        log.Printf("evicted synchrony session %s (%s)", id, reason)
+    }
+    return &Service{sessions: sessions, MinGroupSize: defaultMinGroupSize}
+}
+
+// groupLargeEnough refuses with 403 when fewer than MinGroupSize
+// participants remain for a metric
+func (s *Service) groupLargeEnough(w http.ResponseWriter, size int) bool {
+    if size >= s.MinGroupSize {
+        return true
+    }
+    apierr.Write(w, apierr.New(apierr.CodeForbidden, "group of %d is below the minimum of %d", size, s.MinGroupSize).WithDetails(GroupPrivacyDetails{
+        GroupSize:    size,
+        MinGroupSize: s.MinGroupSize,
+        Note:         "privacy: metrics are withheld for small groups, which could deanonymize participants",
+    }))
+    return false
 }
 
 // Handlers
@@ -94,27 +190,27 @@ func (s *Service) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 func (s *Service) handleStartSession(w http.ResponseWriter, r *http.Request) {
     var req StartSessionRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        http.Error(w, "invalid request", http.StatusBadRequest)
+    if err := jsonbody.Decode(r.Body, &req); err != nil {
+        apierr.Respond(w, apierr.CodeBadRequest, err.Error())
         return
     }
 
     // Enforce governance and scope
     if !req.Manifest.CommunityGovernance.WomenLed {
-        http.Error(w, "women_led governance required", http.StatusForbidden)
+        apierr.Respond(w, apierr.CodeForbidden, "women_led governance required")
         return
     }
     if !req.Manifest.DataMinimization {
-        http.Error(w, "data_minimization must be true", http.StatusForbidden)
+        apierr.Respond(w, apierr.CodeForbidden, "data_minimization must be true")
         return
     }
     if strings.ToLower(req.Manifest.CaptureMode) != "offline" {
-        http.Error(w, "only offline capture_mode supported", http.StatusForbidden)
+        apierr.Respond(w, apierr.CodeForbidden, "only offline capture_mode supported")
         return
     }
     for _, p := range req.Manifest.Participants {
         if !p.Consent {
-            http.Error(w, "all participants must consent", http.StatusForbidden)
+            apierr.Respond(w, apierr.CodeForbidden, "all participants must consent")
             return
         }
     }
@@ -127,20 +223,27 @@ func (s *Service) handleStartSession(w http.ResponseWriter, r *http.Request) {
     sessionID := "sync-" + manifestHash[:8]
     attestationID := "eth-" + manifestHash[:12]
 
-    s.mu.Lock()
-    s.sessions[sessionID] = &Session{
-        ID:        sessionID,
-        Manifest:  req.Manifest,
-        CreatedAt: now,
-        Streams:   make(map[string][]Series),
+    sess := &Session{
+        ID:            sessionID,
+        AttestationID: attestationID,
+        Manifest:      req.Manifest,
+        CreatedAt:     now,
+        Streams:       make(map[string][]Series),
+        running:       make(map[string]*runningStats),
+    }
+    if req.HashPseudonyms {
+        sess.salt = newSalt()
     }
+    s.mu.Lock()
+    s.sessions.Set(sessionID, sess)
     s.mu.Unlock()
 
     resp := StartSessionResponse{
-        SessionID:     sessionID,
-        AttestationID: attestationID,
-        ManifestHash:  manifestHash,
-        Flags:         []string{"offline", "simulation"},
+        SessionID:        sessionID,
+        AttestationID:    attestationID,
+        ManifestHash:     manifestHash,
+        Flags:            []string{"offline", "simulation"},
+        HashedPseudonyms: req.HashPseudonyms,
     }
     writeJSON(w, http.StatusCreated, resp)
 }
@@ -148,71 +251,240 @@ func (s *Service) handleStartSession(w http.ResponseWriter, r *http.Request) {
 func (s *Service) handleIngest(w http.ResponseWriter, r *http.Request) {
     sessionID := pathParam(r.URL.Path, 3) // /v1/synchrony/session/{id}/ingest
     if sessionID == "" {
-        http.Error(w, "missing session id", http.StatusBadRequest)
+        apierr.Respond(w, apierr.CodeBadRequest, "missing session id")
         return
     }
 
     var req IngestRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        http.Error(w, "invalid request", http.StatusBadRequest)
+    if err := jsonbody.Decode(r.Body, &req); err != nil {
+        apierr.Respond(w, apierr.CodeBadRequest, err.Error())
         return
     }
 
     if req.Stream != "breath" && req.Stream != "rr" {
-        http.Error(w, "unsupported stream (breath|rr)", http.StatusBadRequest)
+        apierr.Respond(w, apierr.CodeValidation, "unsupported stream (breath|rr)")
         return
     }
+    for _, srs := range req.Participants {
+        if len(srs.T) != len(srs.V) {
+            apierr.Respond(w, apierr.CodeValidation, srs.Pseudonym+": t and v differ in length")
+            return
+        }
+    }
 
     s.mu.Lock()
     defer s.mu.Unlock()
-    sess, ok := s.sessions[sessionID]
+    sess, ok := s.sessions.Get(sessionID)
     if !ok {
-        http.Error(w, "session not found", http.StatusNotFound)
+        apierr.Respond(w, apierr.CodeNotFound, "session not found")
         return
     }
-    // Store anonymized series (pseudonyms only)
-    sess.Streams[req.Stream] = append(sess.Streams[req.Stream], req.Participants...)
+    // Store anonymized series (pseudonyms, or their hashed handles, only),
+    // dropping revoked participants. A participant's later ingests extend
+    // their series with only the samples past its last, so re-sent history
+    // is ignored.
+    stored := sess.Streams[req.Stream]
+    index := make(map[string]int, len(stored))
+    for i, srs := range stored {
+        index[srs.Pseudonym] = i
+    }
+    running := sess.running[req.Stream]
+    if running == nil {
+        running = newRunningStats()
+        sess.running[req.Stream] = running
+    }
+    for _, srs := range req.Participants {
+        if sess.revoked(srs.Pseudonym) {
+            continue
+        }
+        srs.Pseudonym = sess.handle(srs.Pseudonym)
+        i, ok := index[srs.Pseudonym]
+        if !ok {
+            index[srs.Pseudonym] = len(stored)
+            stored = append(stored, srs)
+            running.add(srs)
+            continue
+        }
+        added := samplesAfter(srs, stored[i])
+        stored[i].T = append(stored[i].T, added.T...)
+        stored[i].V = append(stored[i].V, added.V...)
+        running.add(added)
+    }
+    sess.Streams[req.Stream] = stored
     writeJSON(w, http.StatusAccepted, map[string]string{"status": "ingested"})
 }
 
+// handleRevoke withdraws a participant's consent: their series are deleted
+// from every stream of the session, later ingests of them are dropped and
+// the manifest records the revocation
+func (s *Service) handleRevoke(w http.ResponseWriter, r *http.Request) {
+    sessionID := pathParam(r.URL.Path, 3) // /v1/synchrony/session/{id}/revoke
+    var req RevokeRequest
+    if err := jsonbody.Decode(r.Body, &req); err != nil {
+        apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+        return
+    }
+    if req.Pseudonym == "" {
+        apierr.Respond(w, apierr.CodeBadRequest, "pseudonym required")
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    sess, ok := s.sessions.Get(sessionID)
+    if !ok {
+        apierr.Respond(w, apierr.CodeNotFound, "session not found")
+        return
+    }
+    idx := -1
+    for i, p := range sess.Manifest.Participants {
+        if p.Pseudonym == req.Pseudonym {
+            idx = i
+        }
+    }
+    if idx < 0 {
+        apierr.Respond(w, apierr.CodeNotFound, "participant not in manifest")
+        return
+    }
+    participant := &sess.Manifest.Participants[idx]
+    if participant.RevokedAt != nil {
+        apierr.Respond(w, apierr.CodeConflict, "consent already revoked")
+        return
+    }
+
+    now := time.Now().UTC()
+    participant.Consent = false
+    participant.RevokedAt = &now
+
+    stored := sess.handle(req.Pseudonym)
+    deleted := 0
+    for stream, series := range sess.Streams {
+        kept := make([]Series, 0, len(series))
+        for _, srs := range series {
+            if srs.Pseudonym == stored {
+                deleted++
+                continue
+            }
+            kept = append(kept, srs)
+        }
+        sess.Streams[stream] = kept
+    }
+    for _, running := range sess.running {
+        running.remove(stored)
+    }
+
+    writeJSON(w, http.StatusOK, RevokeResponse{
+        Pseudonym:     req.Pseudonym,
+        RevokedAt:     now,
+        SeriesDeleted: deleted,
+        Manifest:      sess.Manifest,
+    })
+}
+
+// samplesAfter returns the samples of srs later than the last of prev
+func samplesAfter(srs, prev Series) Series {
+    if len(prev.T) == 0 {
+        return srs
+    }
+    last := prev.T[len(prev.T)-1]
+    added := Series{Pseudonym: srs.Pseudonym}
+    for i, t := range srs.T {
+        if t > last {
+            added.T = append(added.T, t)
+            added.V = append(added.V, srs.V[i])
+        }
+    }
+    return added
+}
+
+// revoked reports whether a participant has withdrawn consent
+func (sess *Session) revoked(pseudonym string) bool {
+    for _, p := range sess.Manifest.Participants {
+        if p.Pseudonym == pseudonym {
+            return p.RevokedAt != nil
+        }
+    }
+    return false
+}
+
 func (s *Service) handleMetrics(w http.ResponseWriter, r *http.Request) {
     sessionID := pathParam(r.URL.Path, 3) // /v1/synchrony/session/{id}/metrics
     stream := r.URL.Query().Get("stream")
     if stream == "" {
         stream = "breath"
     }
+    interp, ok := interpParam(w, r)
+    if !ok {
+        return
+    }
+    policy, ok := extrapolationParam(w, r)
+    if !ok {
+        return
+    }
+    places, ok := precisionParam(w, r)
+    if !ok {
+        return
+    }
+    resamples, ok := bootstrapParam(w, r)
+    if !ok {
+        return
+    }
+    minOverlap, ok := overlapParam(w, r)
+    if !ok {
+        return
+    }
+    gap, maxGap, ok := gapParam(w, r)
+    if !ok {
+        return
+    }
 
+    // Copy the stream under the lock; ingest and revoke replace it
     s.mu.RLock()
-    sess, ok := s.sessions[sessionID]
+    sess, ok := s.sessions.Get(sessionID)
+    var series []Series
+    if ok {
+        series = append([]Series(nil), sess.Streams[stream]...)
+    }
     s.mu.RUnlock()
     if !ok {
-        http.Error(w, "session not found", http.StatusNotFound)
+        apierr.Respond(w, apierr.CodeNotFound, "session not found")
         return
     }
 
-    series := sess.Streams[stream]
-    if len(series) < 2 {
-        http.Error(w, "need at least two participants", http.StatusBadRequest)
+    if !s.groupLargeEnough(w, len(byPseudonym(series))) {
         return
     }
 
-    // Compute pairwise Pearson correlations on a uniform grid
+    // Compute pairwise Pearson correlations on a uniform grid. The common
+    // bounds gate the request; the grid spans every series and the
+    // extrapolation policy handles points outside each one's support
     step := 0.5 // seconds
     start, end := commonTimeBounds(series)
-    if end-start < step*10 {
-        http.Error(w, "insufficient overlap for analysis", http.StatusBadRequest)
+    if end-start < minOverlap {
+        apierr.Respond(w, apierr.CodeValidation, "insufficient overlap for analysis")
         return
     }
+    start, end = spanTimeBounds(series)
     grid := makeGrid(start, end, step)
     resampled := make([][]float64, len(series))
     names := make([]string, len(series))
+    notes := []string{"offline", "anonymized", "women_led_required", "interp:" + interp, "extrapolation:" + policy, "gap:" + gap}
     for i, srs := range series {
         names[i] = srs.Pseudonym
-        y, err := resample(grid, srs.T, srs.V)
+        method := interp
+        if method == interpCubic && len(srs.T) < minCubicPoints {
+            method = interpLinear
+            notes = append(notes, srs.Pseudonym+": fewer than 4 samples, linear interpolation used")
+        }
+        t, v := sortedSamples(srs)
+        y, err := resample(grid, t, v, method, policy)
         if err != nil {
-            http.Error(w, "resampling error", http.StatusBadRequest)
+            apierr.Respond(w, apierr.CodeValidation, srs.Pseudonym+": "+err.Error())
             return
         }
+        if gap == gapMask {
+            maskGaps(grid, y, t, maxGap)
+        }
         resampled[i] = zscore(y)
     }
 
@@ -222,7 +494,7 @@ func (s *Service) handleMetrics(w http.ResponseWriter, r *http.Request) {
     for i := 0; i < len(resampled); i++ {
         for j := i + 1; j < len(resampled); j++ {
             c := pearson(resampled[i], resampled[j])
-            key := names[i] + "|" + names[j]
+            key := pairKey(names[i], names[j])
             pairCorr[key] = c
             sum += c
             count++
@@ -230,18 +502,433 @@ func (s *Service) handleMetrics(w http.ResponseWriter, r *http.Request) {
     }
     gsi := sum / float64(count) // simple group synchrony index
 
+    // Round only what is serialized; gsi above used full precision
+    pairCorr = roundValues(pairCorr, places)
     resp := MetricsResponse{
         Stream:              stream,
         Participants:        names,
         WindowSeconds:       end - start,
         PairwiseCorrelation: pairCorr,
-        GroupSynchronyIndex: gsi,
-        Notes:               []string{"offline", "anonymized", "women_led_required"},
+        Pairs:               sortedPairs(pairCorr),
+        GroupSynchronyIndex: round(gsi, places),
+        Notes:               notes,
+    }
+    if resamples > 0 {
+        pairCI, gsiCI := bootstrapCIs(resampled, names, resamples, rand.New(rand.NewSource(time.Now().UnixNano())))
+        for i := range resp.Pairs {
+            resp.Pairs[i].CI = pairCI[resp.Pairs[i].Pair].rounded(places)
+        }
+        resp.GroupSynchronyCI = gsiCI.rounded(places)
+        resp.Notes = append(resp.Notes, fmt.Sprintf("bootstrap:%d", resamples))
+    }
+    writeJSON(w, http.StatusOK, resp)
+}
+
+// handleIncrementalMetrics reports the group synchrony index from the
+// stream's running statistics, kept current by each ingest, instead of
+// recomputing over every sample. It uses linear interpolation, the
+// nan-exclude extrapolation policy and the interpolate gap policy, and
+// agrees with the batch metrics under those settings up to float rounding.
+func (s *Service) handleIncrementalMetrics(w http.ResponseWriter, r *http.Request) {
+    sessionID := pathParam(r.URL.Path, 3) // /v1/synchrony/session/{id}/metrics/incremental
+    stream := r.URL.Query().Get("stream")
+    if stream == "" {
+        stream = "breath"
+    }
+    places, ok := precisionParam(w, r)
+    if !ok {
+        return
+    }
+    minOverlap, ok := overlapParam(w, r)
+    if !ok {
+        return
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    sess, ok := s.sessions.Get(sessionID)
+    if !ok {
+        apierr.Respond(w, apierr.CodeNotFound, "session not found")
+        return
+    }
+    running := sess.running[stream]
+    if running == nil {
+        running = newRunningStats()
+    }
+    names := running.names()
+    if !s.groupLargeEnough(w, len(names)) {
+        return
+    }
+    if running.overlap() < minOverlap {
+        apierr.Respond(w, apierr.CodeValidation, "insufficient overlap for analysis")
+        return
+    }
+
+    pairCorr := running.correlations()
+    var sum float64
+    for _, c := range pairCorr {
+        sum += c
+    }
+    gsi := sum / float64(len(pairCorr))
+
+    pairCorr = roundValues(pairCorr, places)
+    resp := MetricsResponse{
+        Stream:              stream,
+        Participants:        names,
+        WindowSeconds:       running.span(),
+        PairwiseCorrelation: pairCorr,
+        Pairs:               sortedPairs(pairCorr),
+        GroupSynchronyIndex: round(gsi, places),
+        Notes:               []string{"offline", "anonymized", "women_led_required", "interp:" + interpLinear, "extrapolation:" + extrapNaNExclude, "gap:" + gapInterpolate, "incremental"},
+    }
+    writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Service) handleCrossMetrics(w http.ResponseWriter, r *http.Request) {
+    sessionID := pathParam(r.URL.Path, 3) // /v1/synchrony/session/{id}/metrics/cross
+    streams := strings.Split(r.URL.Query().Get("streams"), ",")
+    if len(streams) == 1 && streams[0] == "" {
+        streams = []string{"breath", "rr"}
+    }
+    if len(streams) != 2 || streams[0] == streams[1] {
+        apierr.Respond(w, apierr.CodeValidation, "streams must name two distinct streams (e.g. breath,rr)")
+        return
+    }
+    interp, ok := interpParam(w, r)
+    if !ok {
+        return
+    }
+    policy, ok := extrapolationParam(w, r)
+    if !ok {
+        return
+    }
+    minOverlap, ok := overlapParam(w, r)
+    if !ok {
+        return
+    }
+    gap, maxGap, ok := gapParam(w, r)
+    if !ok {
+        return
+    }
+
+    // Index both streams under the lock; ingest and revoke replace them
+    s.mu.RLock()
+    sess, ok := s.sessions.Get(sessionID)
+    var first, second map[string]Series
+    if ok {
+        first = byPseudonym(sess.Streams[streams[0]])
+        second = byPseudonym(sess.Streams[streams[1]])
+    }
+    s.mu.RUnlock()
+    if !ok {
+        apierr.Respond(w, apierr.CodeNotFound, "session not found")
+        return
+    }
+
+    notes := []string{"offline", "anonymized", "women_led_required", "interp:" + interp, "extrapolation:" + policy, "gap:" + gap}
+
+    var names, excluded []string
+    for name := range first {
+        if _, ok := second[name]; ok {
+            names = append(names, name)
+        } else {
+            excluded = append(excluded, name+": missing "+streams[1]+" stream, excluded")
+        }
+    }
+    for name := range second {
+        if _, ok := first[name]; !ok {
+            excluded = append(excluded, name+": missing "+streams[0]+" stream, excluded")
+        }
+    }
+    sort.Strings(names)
+    sort.Strings(excluded)
+    notes = append(notes, excluded...)
+
+    // Correlate each participant's two streams on their own common grid
+    step := 0.5 // seconds
+    crossCorr := map[string]float64{}
+    included := []string{}
+    var sum float64
+    for _, name := range names {
+        pair := []Series{first[name], second[name]}
+        start, end := commonTimeBounds(pair)
+        if end-start < minOverlap {
+            notes = append(notes, name+": insufficient overlap between streams, excluded")
+            continue
+        }
+        start, end = spanTimeBounds(pair)
+        grid := makeGrid(start, end, step)
+        resampled := make([][]float64, len(pair))
+        for i, srs := range pair {
+            method := interp
+            if method == interpCubic && len(srs.T) < minCubicPoints {
+                method = interpLinear
+            }
+            t, v := sortedSamples(srs)
+            y, err := resample(grid, t, v, method, policy)
+            if err != nil {
+                apierr.Respond(w, apierr.CodeValidation, name+": "+err.Error())
+                return
+            }
+            if gap == gapMask {
+                maskGaps(grid, y, t, maxGap)
+            }
+            resampled[i] = zscore(y)
+        }
+        c := pearson(resampled[0], resampled[1])
+        crossCorr[name] = c
+        included = append(included, name)
+        sum += c
+    }
+    if len(included) == 0 {
+        apierr.Respond(w, apierr.CodeValidation, "no participant has both streams with sufficient overlap")
+        return
+    }
+    if !s.groupLargeEnough(w, len(included)) {
+        return
+    }
+
+    resp := CrossMetricsResponse{
+        Streams:          streams,
+        Participants:     included,
+        CrossCorrelation: crossCorr,
+        GroupCrossIndex:  sum / float64(len(included)),
+        Notes:            notes,
     }
     writeJSON(w, http.StatusOK, resp)
 }
 
 // Utilities
+func interpParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+    interp := r.URL.Query().Get("interp")
+    if interp == "" {
+        return interpLinear, true
+    }
+    if interp != interpLinear && interp != interpNearest && interp != interpCubic {
+        apierr.Respond(w, apierr.CodeValidation, "unsupported interp (linear|nearest|cubic)")
+        return "", false
+    }
+    return interp, true
+}
+
+// extrapolationParam parses the out-of-support policy, nan-exclude by default
+func extrapolationParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+    policy := r.URL.Query().Get("extrapolation")
+    if policy == "" {
+        return extrapNaNExclude, true
+    }
+    if policy != extrapClamp && policy != extrapNaNExclude && policy != extrapError {
+        apierr.Respond(w, apierr.CodeValidation, "unsupported extrapolation (clamp|nan-exclude|error)")
+        return "", false
+    }
+    return policy, true
+}
+
+// overlapParam parses the shortest common window, in seconds, the series
+// must share for metrics to be computed
+func overlapParam(w http.ResponseWriter, r *http.Request) (float64, bool) {
+    raw := r.URL.Query().Get("min_overlap_seconds")
+    if raw == "" {
+        return defaultMinOverlapSeconds, true
+    }
+    v, err := strconv.ParseFloat(raw, 64)
+    if err != nil || v < 0 || math.IsInf(v, 0) {
+        apierr.Respond(w, apierr.CodeValidation, "min_overlap_seconds must be a non-negative number")
+        return 0, false
+    }
+    return v, true
+}
+
+// gapParam parses the gap policy and the gap length, in seconds, masking
+// applies to; interpolate by default
+func gapParam(w http.ResponseWriter, r *http.Request) (string, float64, bool) {
+    q := r.URL.Query()
+    gap := q.Get("gap")
+    if gap == "" {
+        gap = gapInterpolate
+    }
+    if gap != gapInterpolate && gap != gapMask {
+        apierr.Respond(w, apierr.CodeValidation, "unsupported gap (interpolate|mask)")
+        return "", 0, false
+    }
+    maxGap := defaultMaxGapSeconds
+    if raw := q.Get("gap_seconds"); raw != "" {
+        v, err := strconv.ParseFloat(raw, 64)
+        if err != nil || v <= 0 || math.IsInf(v, 0) {
+            apierr.Respond(w, apierr.CodeValidation, "gap_seconds must be a positive number")
+            return "", 0, false
+        }
+        maxGap = v
+    }
+    return gap, maxGap, true
+}
+
+// precisionParam parses the decimal places outputs are rounded to; -1,
+// when the parameter is absent, keeps full precision
+func precisionParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+    raw := r.URL.Query().Get("precision")
+    if raw == "" {
+        return -1, true
+    }
+    places, err := strconv.Atoi(raw)
+    if err != nil || places < 0 || places > maxPrecision {
+        apierr.Respond(w, apierr.CodeValidation, "precision must be an integer between 0 and 15")
+        return 0, false
+    }
+    return places, true
+}
+
+// bootstrapParam parses the bootstrap resample count; 0, when the
+// parameter is absent, skips confidence intervals
+func bootstrapParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+    raw := r.URL.Query().Get("bootstrap")
+    if raw == "" {
+        return 0, true
+    }
+    n, err := strconv.Atoi(raw)
+    if err != nil || n < 1 || n > maxBootstrap {
+        apierr.Respond(w, apierr.CodeValidation, fmt.Sprintf("bootstrap must be an integer between 1 and %d", maxBootstrap))
+        return 0, false
+    }
+    return n, true
+}
+
+// bootstrapCIs resamples the grid points with replacement n times, keeping
+// each point's values paired across participants, and returns the 95%
+// percentile intervals of every pair's Pearson correlation and of the group
+// synchrony index
+func bootstrapCIs(series [][]float64, names []string, n int, rng *rand.Rand) (map[string]*ConfidenceInterval, *ConfidenceInterval) {
+    points := len(series[0])
+    keys := []string{}
+    pairReps := map[string][]float64{}
+    gsiReps := make([]float64, n)
+    sample := make([][]float64, len(series))
+    for i := range sample {
+        sample[i] = make([]float64, points)
+    }
+    for b := 0; b < n; b++ {
+        for k := 0; k < points; k++ {
+            idx := rng.Intn(points)
+            for i := range series {
+                sample[i][k] = series[i][idx]
+            }
+        }
+        var sum float64
+        var count int
+        for i := 0; i < len(sample); i++ {
+            for j := i + 1; j < len(sample); j++ {
+                key := pairKey(names[i], names[j])
+                if b == 0 {
+                    keys = append(keys, key)
+                }
+                c := pearson(sample[i], sample[j])
+                pairReps[key] = append(pairReps[key], c)
+                sum += c
+                count++
+            }
+        }
+        gsiReps[b] = sum / float64(count)
+    }
+
+    pairCI := make(map[string]*ConfidenceInterval, len(keys))
+    for _, key := range keys {
+        pairCI[key] = percentileInterval(pairReps[key])
+    }
+    return pairCI, percentileInterval(gsiReps)
+}
+
+// percentileInterval sorts reps and returns their 2.5th and 97.5th percentiles
+func percentileInterval(reps []float64) *ConfidenceInterval {
+    sort.Float64s(reps)
+    return &ConfidenceInterval{Lower: percentile(reps, 0.025), Upper: percentile(reps, 0.975)}
+}
+
+// percentile linearly interpolates the p-quantile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+    pos := p * float64(len(sorted)-1)
+    lo := int(math.Floor(pos))
+    hi := int(math.Ceil(pos))
+    return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+// rounded returns the interval with both bounds rounded to places
+func (ci *ConfidenceInterval) rounded(places int) *ConfidenceInterval {
+    return &ConfidenceInterval{Lower: round(ci.Lower, places), Upper: round(ci.Upper, places)}
+}
+
+// round rounds v to places decimal places; negative places leaves it as is
+func round(v float64, places int) float64 {
+    if places < 0 {
+        return v
+    }
+    scale := math.Pow(10, float64(places))
+    return math.Round(v*scale) / scale
+}
+
+// roundValues returns a copy of m with every value rounded to places
+func roundValues(m map[string]float64, places int) map[string]float64 {
+    out := make(map[string]float64, len(m))
+    for k, v := range m {
+        out[k] = round(v, places)
+    }
+    return out
+}
+
+// sortedPairs flattens pairwise correlations into a slice ordered by pair key
+func sortedPairs(pairCorr map[string]float64) []PairCorrelation {
+    pairs := make([]PairCorrelation, 0, len(pairCorr))
+    for pair, value := range pairCorr {
+        a, b := splitPairKey(pair)
+        pairs = append(pairs, PairCorrelation{A: a, B: b, Pair: pair, Value: value})
+    }
+    sort.Slice(pairs, func(i, j int) bool {
+        if pairs[i].A != pairs[j].A {
+            return pairs[i].A < pairs[j].A
+        }
+        return pairs[i].B < pairs[j].B
+    })
+    return pairs
+}
+
+// pairKeyEscaper escapes the pair separator and the escape character itself
+var pairKeyEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`)
+
+// pairKey joins two pseudonyms as "a|b", escaping "|" and "\" inside them
+// with a backslash so any two pseudonyms give a distinct key. Pseudonyms
+// without either character keep the plain "a|b" form earlier clients parse.
+func pairKey(a, b string) string {
+    return pairKeyEscaper.Replace(a) + "|" + pairKeyEscaper.Replace(b)
+}
+
+// splitPairKey splits a pairKey back into its pseudonyms
+func splitPairKey(key string) (string, string) {
+    var parts [2]strings.Builder
+    part, escaped := 0, false
+    for _, r := range key {
+        switch {
+        case escaped:
+            parts[part].WriteRune(r)
+            escaped = false
+        case r == '\\':
+            escaped = true
+        case r == '|' && part == 0:
+            part = 1
+        default:
+            parts[part].WriteRune(r)
+        }
+    }
+    return parts[0].String(), parts[1].String()
+}
+
+// byPseudonym indexes a stream's series by participant, keeping the latest ingest
+func byPseudonym(series []Series) map[string]Series {
+    out := make(map[string]Series, len(series))
+    for _, srs := range series {
+        out[srs.Pseudonym] = srs
+    }
+    return out
+}
+
 func writeJSON(w http.ResponseWriter, code int, v any) {
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(code)
@@ -269,6 +956,22 @@ func commonTimeBounds(series []Series) (float64, float64) {
     return start, end
 }
 
+// spanTimeBounds returns the union of the series' supports, the window
+// the analysis grid covers
+func spanTimeBounds(series []Series) (float64, float64) {
+    start := math.MaxFloat64
+    end := -math.MaxFloat64
+    for _, s := range series {
+        for _, t := range s.T {
+            if t < start { start = t }
+            if t > end { end = t }
+        }
+    }
+    if start < 0 { start = 0 }
+    if end < start { end = start }
+    return start, end
+}
+
 func makeGrid(start, end, step float64) []float64 {
     n := int(math.Floor((end-start)/step)) + 1
     g := make([]float64, n)
@@ -278,18 +981,89 @@ func makeGrid(start, end, step float64) []float64 {
     return g
 }
 
-func resample(grid, t, v []float64) ([]float64, error) {
-    if len(t) != len(v) || len(t) == 0 { return nil, errors.New("invalid series") }
-    // Ensure sorted
+// Interpolation methods for resampling onto the analysis grid
+const (
+    interpLinear  = "linear"
+    interpNearest = "nearest"
+    interpCubic   = "cubic"
+)
+
+// minCubicPoints is the fewest samples a cubic spline is fitted over
+const minCubicPoints = 4
+
+// sortedSamples returns a series' samples ordered by time. It sorts copies:
+// the series' own slices are shared with the session store.
+func sortedSamples(srs Series) ([]float64, []float64) {
+    if len(srs.T) != len(srs.V) { return srs.T, srs.V } // rejected by resample
     type tv struct{ t, v float64 }
-    arr := make([]tv, len(t))
-    for i := range t { arr[i] = tv{t: t[i], v: v[i]} }
+    arr := make([]tv, len(srs.T))
+    for i := range srs.T { arr[i] = tv{t: srs.T[i], v: srs.V[i]} }
     sort.Slice(arr, func(i, j int) bool { return arr[i].t < arr[j].t })
-    for i := range t { t[i], v[i] = arr[i].t, arr[i].v }
+    t, v := make([]float64, len(arr)), make([]float64, len(arr))
+    for i := range arr { t[i], v[i] = arr[i].t, arr[i].v }
+    return t, v
+}
+
+// Extrapolation policies for grid points outside a series' support. The
+// analysis grid spans the union of all series once the common (intersection)
+// bounds pass the overlap check, so the policy decides every point beyond
+// one series' first or last sample: clamp holds the nearest end value, which
+// flattens the tail and biases correlations; nan-exclude marks the point
+// invalid and drops it from each pair it appears in; error rejects the request.
+const (
+    extrapClamp      = "clamp"
+    extrapNaNExclude = "nan-exclude"
+    extrapError      = "error"
+)
+
+// defaultMinOverlapSeconds is the common window metrics need by default,
+// ten grid steps
+const defaultMinOverlapSeconds = 5.0
+
+// Gap policies for grid points between two samples further apart than the
+// gap length: interpolate draws a line across the gap as for any other
+// points, which fabricates data over long dropouts; mask marks them NaN so
+// they are excluded from each pair they appear in, as nan-exclude does.
+const (
+    gapInterpolate = "interpolate"
+    gapMask        = "mask"
+)
+
+// defaultMaxGapSeconds is the longest sample spacing mask interpolates over
+const defaultMaxGapSeconds = 5.0
+
+// maskGaps sets to NaN the points of y strictly inside a gap of sorted
+// sample times t longer than maxGap
+func maskGaps(grid, y, t []float64, maxGap float64) {
+    j := 0
+    for i, x := range grid {
+        for j < len(t)-1 && t[j+1] < x { j++ }
+        if j < len(t)-1 && x > t[j] && x < t[j+1] && t[j+1]-t[j] > maxGap {
+            y[i] = math.NaN()
+        }
+    }
+}
+
+// resample interpolates a series, sorted by time, onto grid, applying
+// policy to grid points outside its support; under nan-exclude those
+// points are NaN
+func resample(grid, t, v []float64, method, policy string) ([]float64, error) {
+    if len(t) != len(v) || len(t) == 0 { return nil, errors.New("invalid series") }
 
     out := make([]float64, len(grid))
     j := 0
     for i, x := range grid {
+        if x < t[0] || x > t[len(t)-1] {
+            switch policy {
+            case extrapError:
+                return nil, fmt.Errorf("grid point %.2fs is outside the series support [%.2fs, %.2fs]", x, t[0], t[len(t)-1])
+            case extrapClamp:
+                if x < t[0] { out[i] = v[0] } else { out[i] = v[len(v)-1] }
+            default:
+                out[i] = math.NaN()
+            }
+            continue
+        }
         for j < len(t)-1 && t[j+1] < x { j++ }
         if j == len(t)-1 { out[i] = v[j]; continue }
         // Linear interpolation
@@ -297,20 +1071,50 @@ func resample(grid, t, v []float64) ([]float64, error) {
         v0, v1 := v[j], v[j+1]
         if t1 == t0 { out[i] = v0; continue }
         alpha := (x - t0) / (t1 - t0)
-        out[i] = v0 + alpha*(v1 - v0)
+        switch method {
+        case interpNearest:
+            if alpha < 0.5 { out[i] = v0 } else { out[i] = v1 }
+        case interpCubic:
+            // Catmull-Rom: cubic Hermite with finite-difference tangents
+            h := t1 - t0
+            m0, m1 := tangent(t, v, j), tangent(t, v, j+1)
+            a2, a3 := alpha*alpha, alpha*alpha*alpha
+            out[i] = (2*a3-3*a2+1)*v0 + (a3-2*a2+alpha)*h*m0 + (-2*a3+3*a2)*v1 + (a3-a2)*h*m1
+        default:
+            out[i] = v0 + alpha*(v1 - v0)
+        }
     }
     return out, nil
 }
 
+// tangent estimates the slope at sample k from its neighbours, one-sided at the ends
+func tangent(t, v []float64, k int) float64 {
+    lo, hi := k-1, k+1
+    if lo < 0 { lo = 0 }
+    if hi > len(t)-1 { hi = len(t) - 1 }
+    if t[hi] == t[lo] { return 0 }
+    return (v[hi] - v[lo]) / (t[hi] - t[lo])
+}
+
+// zscore standardizes x over its valid points; NaNs stay NaN
 func zscore(x []float64) []float64 {
-    m := mean(x)
-    s := stddev(x, m)
+    valid := dropNaN(x)
+    m := mean(valid)
+    s := stddev(valid, m)
     if s == 0 { s = 1 }
     y := make([]float64, len(x))
     for i := range x { y[i] = (x[i]-m)/s }
     return y
 }
 
+func dropNaN(x []float64) []float64 {
+    out := make([]float64, 0, len(x))
+    for _, v := range x {
+        if !math.IsNaN(v) { out = append(out, v) }
+    }
+    return out
+}
+
 func mean(x []float64) float64 {
     var s float64
     for _, v := range x { s += v }
@@ -323,8 +1127,18 @@ func stddev(x []float64, m float64) float64 {
     return math.Sqrt(s / float64(len(x)))
 }
 
+// pearson correlates a and b over the points valid (non-NaN) in both
 func pearson(a, b []float64) float64 {
-    if len(a) != len(b) || len(a) == 0 { return 0 }
+    if len(a) != len(b) { return 0 }
+    var pa, pb []float64
+    for i := range a {
+        if !math.IsNaN(a[i]) && !math.IsNaN(b[i]) {
+            pa = append(pa, a[i])
+            pb = append(pb, b[i])
+        }
+    }
+    a, b = pa, pb
+    if len(a) == 0 { return 0 }
     ma := mean(a)
     mb := mean(b)
     var num, da, db float64
@@ -341,16 +1155,46 @@ func pearson(a, b []float64) float64 {
 
 func main() {
     svc := NewService()
+    flag.IntVar(&svc.MinGroupSize, "min-group-size", defaultMinGroupSize, "fewest participants group metrics are reported for")
+    flag.IntVar(&svc.sessions.MaxEntries, "max-sessions", defaultMaxSessions, "sessions held in memory; the least recently used is evicted first")
+    flag.DurationVar(&svc.sessions.TTL, "session-ttl", 0, "evict sessions idle for this long (0 = keep until evicted for space)")
+    tlsCert := flag.String("tls-cert", "", "PEM certificate chain; serves HTTPS with HTTP/2 instead of plaintext HTTP")
+    tlsKey := flag.String("tls-key", "", "PEM private key of -tls-cert")
+    tlsEndorsement := flag.String("tls-endorsement", "", "post-quantum endorsement of -tls-cert, made with tls-endorse, sent on every response")
+    flag.Parse()
+    if svc.MinGroupSize < 2 {
+        log.Fatalf("-min-group-size must be at least 2")
+    }
+    if svc.sessions.MaxEntries <= 0 || svc.sessions.TTL < 0 {
+        log.Fatalf("-max-sessions must be positive and -session-ttl must not be negative")
+    }
 
     mux := http.NewServeMux()
     mux.HandleFunc("/health", svc.handleHealth)
     mux.HandleFunc("/v1/synchrony/session/start", svc.handleStartSession)
     mux.HandleFunc("/v1/synchrony/session/", func(w http.ResponseWriter, r *http.Request) {
-        // Routes: /v1/synchrony/session/{id}/ingest or /metrics
+        // Routes: /v1/synchrony/session/{id}/ingest, /revoke, /pseudonyms,
+        // /metrics, /metrics/cross or /metrics/incremental
         if strings.HasSuffix(r.URL.Path, "/ingest") && r.Method == http.MethodPost {
             svc.handleIngest(w, r)
             return
         }
+        if strings.HasSuffix(r.URL.Path, "/revoke") && r.Method == http.MethodPost {
+            svc.handleRevoke(w, r)
+            return
+        }
+        if strings.HasSuffix(r.URL.Path, "/pseudonyms") && r.Method == http.MethodPost {
+            svc.handlePseudonymMap(w, r)
+            return
+        }
+        if strings.HasSuffix(r.URL.Path, "/metrics/cross") && r.Method == http.MethodGet {
+            svc.handleCrossMetrics(w, r)
+            return
+        }
+        if strings.HasSuffix(r.URL.Path, "/metrics/incremental") && r.Method == http.MethodGet {
+            svc.handleIncrementalMetrics(w, r)
+            return
+        }
         if strings.HasSuffix(r.URL.Path, "/metrics") && r.Method == http.MethodGet {
             svc.handleMetrics(w, r)
             return
@@ -359,6 +1203,7 @@ func main() {
     })
 
     addr := ":8090"
+    tlsCfg := tlsserve.Config{CertFile: *tlsCert, KeyFile: *tlsKey, EndorsementFile: *tlsEndorsement}
     log.Printf("Starting Synchrony Analytics (offline) on %s", addr)
-    log.Fatal(http.ListenAndServe(addr, mux))
+    log.Fatal(tlsserve.ListenAndServe(addr, trace.Middleware("synchrony-analytics")(mux), tlsCfg))
 }
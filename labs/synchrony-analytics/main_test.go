@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// manifest returns a manifest that passes the governance checks, for the
+// given participants
+func manifest(pseudonyms ...string) ConsentManifest {
+	m := ConsentManifest{
+		StudyID:             "study-test",
+		Version:             "1",
+		CommunityGovernance: Governance{WomenLed: true, Contact: "lead@example.org"},
+		DataMinimization:    true,
+		CaptureMode:         "offline",
+	}
+	for _, p := range pseudonyms {
+		m.Participants = append(m.Participants, Participant{Pseudonym: p, Consent: true, Scope: []string{"breath", "rr"}})
+	}
+	return m
+}
+
+// call runs handler on a JSON request and decodes a JSON response into out
+// when out is not nil
+func call(t *testing.T, handler http.HandlerFunc, method, path string, body, out any) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(method, path, &buf))
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return rec.Code
+}
+
+// startSession starts a session over the pseudonyms and returns its id
+func startSession(t *testing.T, svc *Service, pseudonyms ...string) string {
+	t.Helper()
+	var resp StartSessionResponse
+	if code := call(t, svc.handleStartSession, http.MethodPost, "/v1/synchrony/session/start", StartSessionRequest{Manifest: manifest(pseudonyms...)}, &resp); code != http.StatusCreated {
+		t.Fatalf("start session: status %d", code)
+	}
+	return resp.SessionID
+}
+
+// wave is a participant's breath series over samples [from, to) at 4 Hz, a
+// sine shifted by phase
+func wave(pseudonym string, from, to int, phase float64) Series {
+	srs := Series{Pseudonym: pseudonym}
+	for i := from; i < to; i++ {
+		t := float64(i) * 0.25
+		srs.T = append(srs.T, t)
+		srs.V = append(srs.V, math.Sin(t+phase)+0.1*math.Cos(3*t*(1+phase)))
+	}
+	return srs
+}
+
+func ingest(t *testing.T, svc *Service, id string, series ...Series) {
+	t.Helper()
+	ingestStream(t, svc, id, "breath", series...)
+}
+
+func ingestStream(t *testing.T, svc *Service, id, stream string, series ...Series) {
+	t.Helper()
+	if code := call(t, svc.handleIngest, http.MethodPost, "/v1/synchrony/session/"+id+"/ingest", IngestRequest{Stream: stream, Participants: series}, nil); code != http.StatusAccepted {
+		t.Fatalf("ingest %s: status %d", stream, code)
+	}
+}
+
+// metrics fetches breath metrics with the extra query and returns the status
+func metrics(t *testing.T, svc *Service, id, query string) (int, MetricsResponse) {
+	t.Helper()
+	var resp MetricsResponse
+	code := call(t, svc.handleMetrics, http.MethodGet, "/v1/synchrony/session/"+id+"/metrics?stream=breath&"+query, nil, &resp)
+	return code, resp
+}
+
+func TestMetricsOutputIsStable(t *testing.T) {
+	svc := NewService()
+	id := startSession(t, svc, "p3", "p1", "p4", "p2")
+	ingest(t, svc, id, wave("p3", 0, 240, 0.3), wave("p1", 0, 240, 0), wave("p4", 0, 240, 0.9), wave("p2", 0, 240, 0.6))
+
+	var bodies []string
+	for i := 0; i < 5; i++ {
+		rec := httptest.NewRecorder()
+		svc.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/v1/synchrony/session/"+id+"/metrics?stream=breath", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		bodies = append(bodies, rec.Body.String())
+	}
+	for i, body := range bodies[1:] {
+		if body != bodies[0] {
+			t.Fatalf("response %d differs:\n%s\n%s", i+1, bodies[0], body)
+		}
+	}
+
+	var resp MetricsResponse
+	if err := json.Unmarshal([]byte(bodies[0]), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Pairs) != 6 || len(resp.Pairs) != len(resp.PairwiseCorrelation) {
+		t.Fatalf("pairs = %+v", resp.Pairs)
+	}
+	for i, pc := range resp.Pairs {
+		if i > 0 && resp.Pairs[i-1].Pair >= pc.Pair {
+			t.Errorf("pairs not sorted: %q before %q", resp.Pairs[i-1].Pair, pc.Pair)
+		}
+		if resp.PairwiseCorrelation[pc.Pair] != pc.Value {
+			t.Errorf("pair %s = %g, map has %g", pc.Pair, pc.Value, resp.PairwiseCorrelation[pc.Pair])
+		}
+	}
+}
+
+func TestRevokeRemovesParticipant(t *testing.T) {
+	svc := NewService()
+	id := startSession(t, svc, "p1", "p2", "p3", "p4")
+	ingest(t, svc, id, wave("p1", 0, 80, 0), wave("p2", 0, 80, 0.3), wave("p3", 0, 80, 0.6), wave("p4", 0, 80, 0.9))
+	ingestStream(t, svc, id, "rr", wave("p2", 0, 80, 0.1))
+
+	var revoked RevokeResponse
+	if code := call(t, svc.handleRevoke, http.MethodPost, "/v1/synchrony/session/"+id+"/revoke", RevokeRequest{Pseudonym: "p2"}, &revoked); code != http.StatusOK {
+		t.Fatalf("revoke: status %d", code)
+	}
+	if revoked.SeriesDeleted != 2 {
+		t.Errorf("series_deleted = %d, want 2 across both streams", revoked.SeriesDeleted)
+	}
+	for _, p := range revoked.Manifest.Participants {
+		if revoked := p.RevokedAt != nil; revoked != (p.Pseudonym == "p2") || revoked == p.Consent {
+			t.Errorf("manifest participant %+v", p)
+		}
+	}
+	if code := call(t, svc.handleRevoke, http.MethodPost, "/v1/synchrony/session/"+id+"/revoke", RevokeRequest{Pseudonym: "p2"}, nil); code != http.StatusConflict {
+		t.Errorf("second revoke: status %d, want %d", code, http.StatusConflict)
+	}
+	if code := call(t, svc.handleRevoke, http.MethodPost, "/v1/synchrony/session/"+id+"/revoke", RevokeRequest{Pseudonym: "p9"}, nil); code != http.StatusNotFound {
+		t.Errorf("unknown participant: status %d, want %d", code, http.StatusNotFound)
+	}
+
+	// Later ingests of the revoked participant are dropped
+	ingest(t, svc, id, wave("p2", 80, 120, 0.3))
+	svc.mu.RLock()
+	sess, _ := svc.sessions.Peek(id)
+	for stream, series := range sess.Streams {
+		for _, srs := range series {
+			if srs.Pseudonym == "p2" {
+				t.Errorf("%s stream still holds the revoked participant's data", stream)
+			}
+		}
+	}
+	svc.mu.RUnlock()
+
+	code, resp := metrics(t, svc, id, "")
+	if code != http.StatusOK {
+		t.Fatalf("metrics: status %d", code)
+	}
+	for _, p := range resp.Participants {
+		if p == "p2" {
+			t.Error("revoked participant still reported")
+		}
+	}
+	for key := range resp.PairwiseCorrelation {
+		if strings.Contains(key, "p2") {
+			t.Errorf("revoked participant in pair %s", key)
+		}
+	}
+	if len(resp.PairwiseCorrelation) != 3 {
+		t.Errorf("%d pairs, want 3", len(resp.PairwiseCorrelation))
+	}
+}
+
+// TestMetricsConcurrentWithIngestAndRevoke is meant for -race: metrics
+// handlers must not read the session's streams while ingest or revoke
+// replace them
+func TestMetricsConcurrentWithIngestAndRevoke(t *testing.T) {
+	svc := NewService()
+	names := []string{"p0", "p1", "p2", "p3", "p4", "p5", "p6", "p7"}
+	id := startSession(t, svc, names...)
+	for i, name := range names {
+		ingest(t, svc, id, wave(name, 0, 40, float64(i)*0.2))
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for k := 1; k < 20; k++ {
+			for i, name := range names {
+				srs := wave(name, 0, 40, float64(i+k)*0.2)
+				if code := call(t, svc.handleIngest, http.MethodPost, "/v1/synchrony/session/"+id+"/ingest", IngestRequest{Stream: "rr", Participants: []Series{srs}}, nil); code != http.StatusAccepted {
+					t.Errorf("ingest: status %d", code)
+				}
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for _, name := range names[:4] {
+			call(t, svc.handleRevoke, http.MethodPost, "/v1/synchrony/session/"+id+"/revoke", RevokeRequest{Pseudonym: name}, nil)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for k := 0; k < 50; k++ {
+			call(t, svc.handleMetrics, http.MethodGet, "/v1/synchrony/session/"+id+"/metrics", nil, nil)
+			call(t, svc.handleCrossMetrics, http.MethodGet, "/v1/synchrony/session/"+id+"/metrics/cross", nil, nil)
+			call(t, svc.handleIncrementalMetrics, http.MethodGet, "/v1/synchrony/session/"+id+"/metrics/incremental", nil, nil)
+		}
+	}()
+	wg.Wait()
+
+	_, resp := metrics(t, svc, id, "")
+	if got := strings.Join(resp.Participants, ","); got != "p4,p5,p6,p7" {
+		t.Errorf("participants = %s, want p4,p5,p6,p7", got)
+	}
+	if n := len(resp.PairwiseCorrelation); n != 6 {
+		t.Errorf("%d pairs, want 6", n)
+	}
+}
+
+func TestMinGroupSize(t *testing.T) {
+	svc := NewService()
+	id := startSession(t, svc, "p1", "p2", "p3")
+	ingest(t, svc, id, wave("p1", 0, 80, 0), wave("p2", 0, 80, 0.3))
+
+	rec := httptest.NewRecorder()
+	svc.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/v1/synchrony/session/"+id+"/metrics", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("group of 2: status = %d, want 403", rec.Code)
+	}
+	var refusal struct {
+		Details GroupPrivacyDetails `json:"details"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &refusal); err != nil {
+		t.Fatal(err)
+	}
+	if d := refusal.Details; d.GroupSize != 2 || d.MinGroupSize != 3 || d.Note == "" {
+		t.Errorf("refusal details = %+v: %s", d, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "p1|p2") {
+		t.Errorf("refusal exposes a pairwise correlation: %s", rec.Body)
+	}
+
+	ingest(t, svc, id, wave("p3", 0, 80, 0.6))
+	if code, resp := metrics(t, svc, id, ""); code != http.StatusOK || len(resp.Participants) != 3 {
+		t.Fatalf("group of 3: status = %d, participants %v", code, resp.Participants)
+	}
+
+	// Revocation shrinks the effective group back below the threshold
+	call(t, svc.handleRevoke, http.MethodPost, "/v1/synchrony/session/"+id+"/revoke", RevokeRequest{Pseudonym: "p3"}, nil)
+	if code, _ := metrics(t, svc, id, ""); code != http.StatusForbidden {
+		t.Errorf("after revocation: status = %d, want 403", code)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPairKeyIsUnambiguous(t *testing.T) {
+	// Joined with a bare "|", both pairs read a|b|c
+	if pairKey("a|b", "c") == pairKey("a", "b|c") {
+		t.Errorf("pairKey collides: %q", pairKey("a|b", "c"))
+	}
+	for _, pair := range [][2]string{{"a|b", "c"}, {"a", "b|c"}, {`a\`, "b"}, {`a\|`, `|b\`}, {"", "|"}, {"alice", "bob"}} {
+		if a, b := splitPairKey(pairKey(pair[0], pair[1])); a != pair[0] || b != pair[1] {
+			t.Errorf("splitPairKey(pairKey(%q, %q)) = %q, %q", pair[0], pair[1], a, b)
+		}
+	}
+	if got := pairKey("alice", "bob"); got != "alice|bob" {
+		t.Errorf("plain pseudonyms keep the old key: got %q", got)
+	}
+}
+
+func TestMetricsWithPipePseudonyms(t *testing.T) {
+	svc := NewService()
+	id := startSession(t, svc, "a|b", "c", "a", "b|c")
+	ingest(t, svc, id, wave("a|b", 0, 80, 0), wave("c", 0, 80, 0.3), wave("a", 0, 80, 0.6), wave("b|c", 0, 80, 0.9))
+
+	code, resp := metrics(t, svc, id, "")
+	if code != http.StatusOK {
+		t.Fatalf("metrics: status %d", code)
+	}
+	if len(resp.PairwiseCorrelation) != 6 || len(resp.Pairs) != 6 {
+		t.Fatalf("got %d correlations and %d pairs for 4 participants, want 6", len(resp.PairwiseCorrelation), len(resp.Pairs))
+	}
+	seen := make(map[[2]string]bool)
+	for _, p := range resp.Pairs {
+		if p.Pair != pairKey(p.A, p.B) || resp.PairwiseCorrelation[p.Pair] != p.Value {
+			t.Errorf("pair %+v does not match its key", p)
+		}
+		seen[[2]string{p.A, p.B}] = true
+	}
+	for _, pair := range [][2]string{{"a|b", "c"}, {"a", "b|c"}} {
+		if !seen[pair] && !seen[[2]string{pair[1], pair[0]}] {
+			t.Errorf("no entry for the pair %q, %q", pair[0], pair[1])
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestPrecisionRoundsOutputs(t *testing.T) {
+	svc := NewService()
+	id := startSession(t, svc, "p1", "p2", "p3")
+	ingest(t, svc, id, wave("p1", 0, 120, 0), wave("p2", 0, 120, 0.35), wave("p3", 0, 120, 0.8))
+
+	code, full := metrics(t, svc, id, "")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	code, rounded := metrics(t, svc, id, "precision=3")
+	if code != http.StatusOK {
+		t.Fatalf("precision=3: status = %d", code)
+	}
+
+	for pair, v := range full.PairwiseCorrelation {
+		got := rounded.PairwiseCorrelation[pair]
+		if want := math.Round(v*1000) / 1000; got != want {
+			t.Errorf("%s = %v, want %v", pair, got, want)
+		}
+	}
+	for _, pc := range rounded.Pairs {
+		if pc.Value != rounded.PairwiseCorrelation[pc.Pair] {
+			t.Errorf("pairs list %s = %v, map has %v", pc.Pair, pc.Value, rounded.PairwiseCorrelation[pc.Pair])
+		}
+	}
+	// The index is rounded from the full-precision mean, not the rounded pairs
+	if want := math.Round(full.GroupSynchronyIndex*1000) / 1000; rounded.GroupSynchronyIndex != want {
+		t.Errorf("gsi = %v, want %v", rounded.GroupSynchronyIndex, want)
+	}
+	if full.GroupSynchronyIndex == rounded.GroupSynchronyIndex {
+		t.Errorf("omitting precision rounded the index: %v", full.GroupSynchronyIndex)
+	}
+}
+
+func TestPrecisionRejectsBadValues(t *testing.T) {
+	svc := NewService()
+	id := startSession(t, svc, "p1", "p2", "p3")
+	ingest(t, svc, id, wave("p1", 0, 80, 0), wave("p2", 0, 80, 0.3), wave("p3", 0, 80, 0.6))
+	for _, q := range []string{"precision=-1", "precision=16", "precision=two"} {
+		if code, _ := metrics(t, svc, id, q); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, code)
+		}
+	}
+}
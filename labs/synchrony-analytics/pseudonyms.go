@@ -0,0 +1,85 @@
+package main
+
+import (
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/hex"
+    "net/http"
+
+    "github.com/corridoros/pkg/apierr"
+    "github.com/corridoros/pkg/jsonbody"
+)
+
+// saltBytes is the length of a session's pseudonym salt
+const saltBytes = 32
+
+// handleHexDigits is how much of the HMAC a hashed handle keeps: 64 bits,
+// ample to keep a session's participants apart
+const handleHexDigits = 16
+
+// PseudonymMapRequest proves the caller owns a session
+type PseudonymMapRequest struct {
+    AttestationID string `json:"attestation_id"`
+}
+
+// PseudonymMapResponse maps each manifest pseudonym to the handle its
+// series are stored and reported under
+type PseudonymMapResponse struct {
+    SessionID string            `json:"session_id"`
+    Hashed    bool              `json:"hashed"`
+    Handles   map[string]string `json:"handles"`
+}
+
+// newSalt returns a random pseudonym salt
+func newSalt() []byte {
+    salt := make([]byte, saltBytes)
+    rand.Read(salt)
+    return salt
+}
+
+// handle returns the name a participant's series are stored under: with a
+// salt, "h-" and a truncated HMAC-SHA256 of the pseudonym, stable within
+// the session but unlinkable across sessions; without, the pseudonym
+func (sess *Session) handle(pseudonym string) string {
+    if sess.salt == nil {
+        return pseudonym
+    }
+    mac := hmac.New(sha256.New, sess.salt)
+    mac.Write([]byte(pseudonym))
+    return "h-" + hex.EncodeToString(mac.Sum(nil))[:handleHexDigits]
+}
+
+// handlePseudonymMap tells the session owner, identified by the session's
+// attestation ID, which handle stands for which pseudonym
+func (s *Service) handlePseudonymMap(w http.ResponseWriter, r *http.Request) {
+    sessionID := pathParam(r.URL.Path, 3) // /v1/synchrony/session/{id}/pseudonyms
+    var req PseudonymMapRequest
+    if err := jsonbody.Decode(r.Body, &req); err != nil {
+        apierr.Respond(w, apierr.CodeBadRequest, err.Error())
+        return
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    sess, ok := s.sessions.Get(sessionID)
+    if !ok {
+        apierr.Respond(w, apierr.CodeNotFound, "session not found")
+        return
+    }
+    if subtle.ConstantTimeCompare([]byte(req.AttestationID), []byte(sess.AttestationID)) != 1 {
+        apierr.Respond(w, apierr.CodeForbidden, "attestation_id does not match the session")
+        return
+    }
+
+    handles := make(map[string]string, len(sess.Manifest.Participants))
+    for _, p := range sess.Manifest.Participants {
+        handles[p.Pseudonym] = sess.handle(p.Pseudonym)
+    }
+    writeJSON(w, http.StatusOK, PseudonymMapResponse{
+        SessionID: sess.ID,
+        Hashed:    sess.salt != nil,
+        Handles:   handles,
+    })
+}
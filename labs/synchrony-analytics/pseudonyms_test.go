@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// startHashedSession starts a session that hashes pseudonyms and returns
+// its response; study keeps sessions over the same participants apart
+func startHashedSession(t *testing.T, svc *Service, study string, pseudonyms ...string) StartSessionResponse {
+	t.Helper()
+	m := manifest(pseudonyms...)
+	m.StudyID = study
+	var resp StartSessionResponse
+	if code := call(t, svc.handleStartSession, http.MethodPost, "/v1/synchrony/session/start", StartSessionRequest{Manifest: m, HashPseudonyms: true}, &resp); code != http.StatusCreated {
+		t.Fatalf("start session: status %d", code)
+	}
+	if !resp.HashedPseudonyms {
+		t.Fatalf("hashed_pseudonyms = false")
+	}
+	return resp
+}
+
+// session returns the stored session, for inspecting what was kept
+func session(t *testing.T, svc *Service, id string) *Session {
+	t.Helper()
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+	sess, ok := svc.sessions.Peek(id)
+	if !ok {
+		t.Fatalf("session %s not found", id)
+	}
+	return sess
+}
+
+func TestHandlesAreStableWithinASessionOnly(t *testing.T) {
+	svc := NewService()
+	a := session(t, svc, startHashedSession(t, svc, "study-a", "p1", "p2").SessionID)
+	b := session(t, svc, startHashedSession(t, svc, "study-b", "p1", "p2").SessionID)
+
+	if !strings.HasPrefix(a.handle("p1"), "h-") || len(a.handle("p1")) != len("h-")+handleHexDigits {
+		t.Errorf("handle = %q", a.handle("p1"))
+	}
+	if a.handle("p1") == a.handle("p2") {
+		t.Errorf("p1 and p2 share handle %s", a.handle("p1"))
+	}
+	if a.handle("p1") == b.handle("p1") {
+		t.Errorf("p1 has handle %s in both sessions", a.handle("p1"))
+	}
+
+	plain := session(t, svc, startSession(t, svc, "p1", "p2"))
+	if plain.handle("p1") != "p1" {
+		t.Errorf("unhashed session stores p1 as %q", plain.handle("p1"))
+	}
+}
+
+func TestHashedIngestStoresAndReportsHandlesOnly(t *testing.T) {
+	svc := NewService()
+	id := startHashedSession(t, svc, "study-a", "p1", "p2", "p3").SessionID
+	ingest(t, svc, id, wave("p1", 0, 80, 0), wave("p2", 0, 80, 0.3), wave("p3", 0, 80, 0.6))
+	// A later ingest extends the same handle's series rather than adding one
+	ingest(t, svc, id, wave("p1", 80, 120, 0))
+
+	sess := session(t, svc, id)
+	stored := sess.Streams["breath"]
+	if len(stored) != 3 {
+		t.Fatalf("stored %d series, want 3", len(stored))
+	}
+	for _, srs := range stored {
+		if !strings.HasPrefix(srs.Pseudonym, "h-") {
+			t.Errorf("series stored under %q", srs.Pseudonym)
+		}
+		if srs.Pseudonym == sess.handle("p1") && len(srs.T) != 120 {
+			t.Errorf("p1 has %d samples, want 120", len(srs.T))
+		}
+	}
+
+	code, resp := metrics(t, svc, id, "")
+	if code != http.StatusOK {
+		t.Fatalf("metrics: status %d", code)
+	}
+	if len(resp.Participants) != 3 {
+		t.Errorf("metrics participants = %v", resp.Participants)
+	}
+	for _, p := range resp.Participants {
+		if !strings.HasPrefix(p, "h-") {
+			t.Errorf("metrics report participant %q", p)
+		}
+	}
+}
+
+func TestPseudonymMapNeedsTheSessionAttestation(t *testing.T) {
+	svc := NewService()
+	started := startHashedSession(t, svc, "study-a", "p1", "p2")
+	id := started.SessionID
+	path := "/v1/synchrony/session/" + id + "/pseudonyms"
+
+	var mapping PseudonymMapResponse
+	if code := call(t, svc.handlePseudonymMap, http.MethodPost, path, PseudonymMapRequest{AttestationID: started.AttestationID}, &mapping); code != http.StatusOK {
+		t.Fatalf("pseudonym map: status %d", code)
+	}
+	sess := session(t, svc, id)
+	if !mapping.Hashed || len(mapping.Handles) != 2 || mapping.Handles["p1"] != sess.handle("p1") || mapping.Handles["p2"] != sess.handle("p2") {
+		t.Errorf("mapping = %+v", mapping)
+	}
+
+	if code := call(t, svc.handlePseudonymMap, http.MethodPost, path, PseudonymMapRequest{AttestationID: "eth-000000000000"}, nil); code != http.StatusForbidden {
+		t.Errorf("wrong attestation: status %d, want %d", code, http.StatusForbidden)
+	}
+	if code := call(t, svc.handlePseudonymMap, http.MethodPost, "/v1/synchrony/session/sync-missing/pseudonyms", PseudonymMapRequest{AttestationID: started.AttestationID}, nil); code != http.StatusNotFound {
+		t.Errorf("unknown session: status %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestRevokeDeletesHashedSeries(t *testing.T) {
+	svc := NewService()
+	id := startHashedSession(t, svc, "study-a", "p1", "p2", "p3").SessionID
+	ingest(t, svc, id, wave("p1", 0, 80, 0), wave("p2", 0, 80, 0.3), wave("p3", 0, 80, 0.6))
+
+	var revoked RevokeResponse
+	if code := call(t, svc.handleRevoke, http.MethodPost, "/v1/synchrony/session/"+id+"/revoke", RevokeRequest{Pseudonym: "p2"}, &revoked); code != http.StatusOK {
+		t.Fatalf("revoke: status %d", code)
+	}
+	if revoked.SeriesDeleted != 1 {
+		t.Errorf("series_deleted = %d, want 1", revoked.SeriesDeleted)
+	}
+	ingest(t, svc, id, wave("p2", 80, 120, 0.3))
+
+	sess := session(t, svc, id)
+	gone := sess.handle("p2")
+	for _, srs := range sess.Streams["breath"] {
+		if srs.Pseudonym == gone {
+			t.Errorf("revoked participant's series %s kept", gone)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Package apierr defines the error codes and JSON error body shared by the
+// CorridorOS services, so clients can tell failures apart programmatically.
+package apierr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Code identifies a class of failure
+type Code string
+
+// Error codes returned by the services
+const (
+	CodeValidation     Code = "VALIDATION_FAILED"
+	CodeBadRequest     Code = "BAD_REQUEST" // malformed body or parameters
+	CodeNotFound       Code = "NOT_FOUND"
+	CodeConflict       Code = "CONFLICT"
+	CodeOversubscribed Code = "OVERSUBSCRIBED"
+	CodeQuotaExceeded  Code = "QUOTA_EXCEEDED"
+	CodeTooLarge       Code = "PAYLOAD_TOO_LARGE"
+	CodeInfeasible     Code = "INFEASIBLE"
+	CodeForbidden      Code = "FORBIDDEN"
+	CodeUpstream       Code = "UPSTREAM_FAILURE"
+	CodeInjectedFault  Code = "INJECTED_FAULT"
+	CodeInternal       Code = "INTERNAL"
+)
+
+// statuses maps each code onto its HTTP status
+var statuses = map[Code]int{
+	CodeValidation:     http.StatusBadRequest,
+	CodeBadRequest:     http.StatusBadRequest,
+	CodeNotFound:       http.StatusNotFound,
+	CodeConflict:       http.StatusConflict,
+	CodeOversubscribed: http.StatusConflict,
+	CodeQuotaExceeded:  http.StatusTooManyRequests,
+	CodeTooLarge:       http.StatusRequestEntityTooLarge,
+	CodeInfeasible:     http.StatusUnprocessableEntity,
+	CodeForbidden:      http.StatusForbidden,
+	CodeUpstream:       http.StatusBadGateway,
+	CodeInjectedFault:  http.StatusInternalServerError,
+	CodeInternal:       http.StatusInternalServerError,
+}
+
+// Status returns the HTTP status for a code, 500 for unknown codes
+func Status(code Code) int {
+	if status, ok := statuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is the JSON error body every service returns
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// New builds an error with a formatted message
+func New(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// WithDetails attaches structured details to the error
+func (e *Error) WithDetails(details any) *Error {
+	e.Details = details
+	return e
+}
+
+// Write sends err as a JSON error body with the status of its code
+func Write(w http.ResponseWriter, err *Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(Status(err.Code))
+	_ = json.NewEncoder(w).Encode(err)
+}
+
+// Respond writes a JSON error body built from a code and message
+func Respond(w http.ResponseWriter, code Code, message string) {
+	Write(w, &Error{Code: code, Message: message})
+}
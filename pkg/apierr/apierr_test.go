@@ -0,0 +1,59 @@
+package apierr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	for code, want := range map[Code]int{
+		CodeValidation:     http.StatusBadRequest,
+		CodeNotFound:       http.StatusNotFound,
+		CodeOversubscribed: http.StatusConflict,
+		CodeTooLarge:       http.StatusRequestEntityTooLarge,
+		CodeInfeasible:     http.StatusUnprocessableEntity,
+		CodeForbidden:      http.StatusForbidden,
+		CodeUpstream:       http.StatusBadGateway,
+		"SOMETHING_NEW":    http.StatusInternalServerError,
+	} {
+		if got := Status(code); got != want {
+			t.Errorf("Status(%s) = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestWriteBody(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Write(rec, New(CodeOversubscribed, "pool %s is full", "t1").WithDetails(map[string]int{"free": 0}))
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var body struct {
+		Code    Code           `json:"code"`
+		Message string         `json:"message"`
+		Details map[string]int `json:"details"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != CodeOversubscribed || body.Message != "pool t1 is full" || body.Details["free"] != 0 {
+		t.Errorf("body = %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	Respond(rec, CodeNotFound, "gone")
+	if rec.Code != http.StatusNotFound || !json.Valid(rec.Body.Bytes()) {
+		t.Errorf("Respond = %d %s", rec.Code, rec.Body)
+	}
+	var raw map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &raw)
+	if _, ok := raw["details"]; ok {
+		t.Errorf("empty details serialised: %s", rec.Body)
+	}
+}
@@ -0,0 +1,254 @@
+// Package bounded provides BoundedStore, the keyed in-memory store the
+// CorridorOS services keep sessions, results and allocations in, so a
+// busy instance cannot grow without limit and run a shared host out of
+// memory.
+//
+// A store holds at most MaxEntries entries. Inserting past the cap evicts
+// the least recently used entry, and entries idle for longer than TTL
+// expire. Entries the Pinned predicate reports as long-lived resources,
+// such as an active corridor, are never evicted: a store full of pinned
+// entries grows past its cap rather than drop one.
+package bounded
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Reasons an entry is evicted
+const (
+	ReasonCapacity = "capacity" // least recently used past MaxEntries
+	ReasonExpired  = "expired"  // idle for longer than TTL
+)
+
+// Stats is a snapshot of a store for observability
+type Stats struct {
+	Entries    int    `json:"entries"`
+	Pinned     int    `json:"pinned"`
+	MaxEntries int    `json:"max_entries"`
+	Evicted    uint64 `json:"evicted"` // past the cap
+	Expired    uint64 `json:"expired"`
+}
+
+type entry[V any] struct {
+	key     string
+	value   V
+	touched time.Time
+}
+
+// BoundedStore is a map from string keys to values with LRU and idle-time
+// eviction. It is safe for concurrent use. Set the exported fields before
+// first use.
+type BoundedStore[V any] struct {
+	// MaxEntries caps the entries held; zero leaves the store unbounded
+	MaxEntries int
+	// TTL expires entries not read or written for that long; zero keeps
+	// them until evicted for capacity
+	TTL time.Duration
+	// Pinned exempts long-lived entries from eviction; nil pins none. It
+	// is evaluated when eviction is considered, so it may depend on the
+	// entry's current state.
+	Pinned func(V) bool
+	// OnEvict is called for each evicted entry, after the store's lock is
+	// released, in the goroutine whose call evicted it
+	OnEvict func(key string, value V, reason string)
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+	evicted uint64
+	expired uint64
+	now     func() time.Time
+}
+
+// New creates a store capped at maxEntries
+func New[V any](maxEntries int) *BoundedStore[V] {
+	return &BoundedStore[V]{MaxEntries: maxEntries}
+}
+
+// eviction is an entry removed by an operation, reported once it unlocks
+type eviction[V any] struct {
+	key    string
+	value  V
+	reason string
+}
+
+func (s *BoundedStore[V]) init() {
+	if s.entries == nil {
+		s.entries = make(map[string]*list.Element)
+		s.order = list.New()
+	}
+	if s.now == nil {
+		s.now = time.Now
+	}
+}
+
+// Get returns the value of key, marking it recently used. An expired
+// entry is evicted and reported missing.
+func (s *BoundedStore[V]) Get(key string) (V, bool) {
+	s.mu.Lock()
+	s.init()
+	var zero V
+	el, ok := s.entries[key]
+	if !ok {
+		s.mu.Unlock()
+		return zero, false
+	}
+	e := el.Value.(*entry[V])
+	now := s.now()
+	if s.expiredAt(e, now) {
+		s.remove(el)
+		s.expired++
+		s.mu.Unlock()
+		s.notify([]eviction[V]{{key, e.value, ReasonExpired}})
+		return zero, false
+	}
+	e.touched = now
+	s.order.MoveToFront(el)
+	s.mu.Unlock()
+	return e.value, true
+}
+
+// Peek returns the value of key without marking it used
+func (s *BoundedStore[V]) Peek(key string) (V, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+	var zero V
+	el, ok := s.entries[key]
+	if !ok || s.expiredAt(el.Value.(*entry[V]), s.now()) {
+		return zero, false
+	}
+	return el.Value.(*entry[V]).value, true
+}
+
+// Set stores value under key as the most recently used entry, then evicts
+// expired entries and, past MaxEntries, the least recently used unpinned
+// ones
+func (s *BoundedStore[V]) Set(key string, value V) {
+	s.mu.Lock()
+	s.init()
+	now := s.now()
+	if el, ok := s.entries[key]; ok {
+		e := el.Value.(*entry[V])
+		e.value, e.touched = value, now
+		s.order.MoveToFront(el)
+	} else {
+		s.entries[key] = s.order.PushFront(&entry[V]{key: key, value: value, touched: now})
+	}
+	evictions := s.evict(now)
+	s.mu.Unlock()
+	s.notify(evictions)
+}
+
+// Delete removes key, reporting whether it was present. Deletion is not
+// an eviction and is not passed to OnEvict.
+func (s *BoundedStore[V]) Delete(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+	el, ok := s.entries[key]
+	if ok {
+		s.remove(el)
+	}
+	return ok
+}
+
+// Len returns the number of entries held, expired or not
+func (s *BoundedStore[V]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Values returns the unexpired values, most recently used first, without
+// marking them used
+func (s *BoundedStore[V]) Values() []V {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+	now := s.now()
+	values := make([]V, 0, len(s.entries))
+	for el := s.order.Front(); el != nil; el = el.Next() {
+		if e := el.Value.(*entry[V]); !s.expiredAt(e, now) {
+			values = append(values, e.value)
+		}
+	}
+	return values
+}
+
+// Sweep evicts expired entries, for owners that want memory back without
+// waiting for the next Set
+func (s *BoundedStore[V]) Sweep() {
+	s.mu.Lock()
+	s.init()
+	evictions := s.evict(s.now())
+	s.mu.Unlock()
+	s.notify(evictions)
+}
+
+// Stats returns a snapshot of the store
+func (s *BoundedStore[V]) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+	st := Stats{Entries: len(s.entries), MaxEntries: s.MaxEntries, Evicted: s.evicted, Expired: s.expired}
+	for el := s.order.Front(); el != nil; el = el.Next() {
+		if s.pinned(el.Value.(*entry[V]).value) {
+			st.Pinned++
+		}
+	}
+	return st
+}
+
+func (s *BoundedStore[V]) pinned(v V) bool {
+	return s.Pinned != nil && s.Pinned(v)
+}
+
+// expiredAt reports whether an entry has outlived TTL at now
+func (s *BoundedStore[V]) expiredAt(e *entry[V], now time.Time) bool {
+	return s.TTL > 0 && now.Sub(e.touched) > s.TTL && !s.pinned(e.value)
+}
+
+func (s *BoundedStore[V]) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.entries, el.Value.(*entry[V]).key)
+}
+
+// evict removes expired entries, then unpinned entries from the least
+// recently used end while the store is over its cap. The caller must hold
+// the lock.
+func (s *BoundedStore[V]) evict(now time.Time) []eviction[V] {
+	var evictions []eviction[V]
+	if s.TTL > 0 {
+		for el := s.order.Back(); el != nil; {
+			prev := el.Prev()
+			if e := el.Value.(*entry[V]); s.expiredAt(e, now) {
+				s.remove(el)
+				s.expired++
+				evictions = append(evictions, eviction[V]{e.key, e.value, ReasonExpired})
+			}
+			el = prev
+		}
+	}
+	for el := s.order.Back(); el != nil && s.MaxEntries > 0 && len(s.entries) > s.MaxEntries; {
+		prev := el.Prev()
+		if e := el.Value.(*entry[V]); !s.pinned(e.value) {
+			s.remove(el)
+			s.evicted++
+			evictions = append(evictions, eviction[V]{e.key, e.value, ReasonCapacity})
+		}
+		el = prev
+	}
+	return evictions
+}
+
+func (s *BoundedStore[V]) notify(evictions []eviction[V]) {
+	if s.OnEvict == nil {
+		return
+	}
+	for _, ev := range evictions {
+		s.OnEvict(ev.key, ev.value, ev.reason)
+	}
+}
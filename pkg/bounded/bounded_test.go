@@ -0,0 +1,141 @@
+package bounded
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// clock is a settable time source for a store
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+func withClock[V any](s *BoundedStore[V]) *clock {
+	c := &clock{t: time.Unix(1_700_000_000, 0)}
+	s.now = c.now
+	return c
+}
+
+type evicted struct{ key, reason string }
+
+// recordEvictions collects the store's evictions in order
+func recordEvictions[V any](s *BoundedStore[V]) *[]evicted {
+	var got []evicted
+	s.OnEvict = func(key string, _ V, reason string) { got = append(got, evicted{key, reason}) }
+	return &got
+}
+
+func TestLeastRecentlyUsedEvictedPastCap(t *testing.T) {
+	s := New[int](3)
+	got := recordEvictions(s)
+	s.Set("a", 1)
+	s.Set("b", 2)
+	s.Set("c", 3)
+	s.Get("a") // b is now the least recently used
+	s.Peek("b")
+	s.Set("d", 4)
+
+	if _, ok := s.Peek("b"); ok {
+		t.Error("b survived past the cap")
+	}
+	for _, k := range []string{"a", "c", "d"} {
+		if _, ok := s.Peek(k); !ok {
+			t.Errorf("%s was evicted", k)
+		}
+	}
+	if want := []evicted{{"b", ReasonCapacity}}; !slices.Equal(*got, want) {
+		t.Errorf("evictions = %v, want %v", *got, want)
+	}
+	if v := s.Values(); !slices.Equal(v, []int{4, 1, 3}) {
+		t.Errorf("values = %v, want most recently used first", v)
+	}
+	if st := s.Stats(); st.Entries != 3 || st.Evicted != 1 || st.MaxEntries != 3 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestIdleEntriesExpire(t *testing.T) {
+	s := New[int](0)
+	s.TTL = time.Minute
+	c := withClock(s)
+	got := recordEvictions(s)
+	s.Set("old", 1)
+	c.advance(45 * time.Second)
+	s.Set("new", 2)
+	c.advance(30 * time.Second)
+
+	if _, ok := s.Get("old"); ok {
+		t.Error("an entry idle past the TTL was returned")
+	}
+	if _, ok := s.Get("new"); !ok {
+		t.Error("an entry within the TTL expired")
+	}
+	c.advance(2 * time.Minute)
+	if v := s.Values(); len(v) != 0 {
+		t.Errorf("values after expiry = %v", v)
+	}
+	s.Sweep()
+	if s.Len() != 0 {
+		t.Errorf("%d entries held after a sweep", s.Len())
+	}
+	if want := []evicted{{"old", ReasonExpired}, {"new", ReasonExpired}}; !slices.Equal(*got, want) {
+		t.Errorf("evictions = %v, want %v", *got, want)
+	}
+	if st := s.Stats(); st.Expired != 2 || st.Evicted != 0 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestPinnedEntriesAreNeverEvicted(t *testing.T) {
+	type corridor struct{ active bool }
+	s := New[*corridor](2)
+	s.TTL = time.Minute
+	s.Pinned = func(c *corridor) bool { return c.active }
+	c := withClock(s)
+	got := recordEvictions(s)
+
+	active := &corridor{active: true}
+	s.Set("active", active)
+	s.Set("idle", &corridor{})
+	s.Set("busy", &corridor{active: true})
+	if _, ok := s.Peek("active"); !ok {
+		t.Fatal("a pinned entry was evicted for capacity")
+	}
+	if want := []evicted{{"idle", ReasonCapacity}}; !slices.Equal(*got, want) {
+		t.Errorf("evictions = %v, want %v", *got, want)
+	}
+
+	// A store full of pinned entries grows past its cap
+	s.Set("third", &corridor{active: true})
+	if s.Len() != 3 {
+		t.Errorf("%d entries held, want 3 pinned past a cap of 2", s.Len())
+	}
+
+	c.advance(time.Hour)
+	if _, ok := s.Get("active"); !ok {
+		t.Error("a pinned entry expired")
+	}
+
+	// Pinning is evaluated at eviction time, so a released entry goes
+	active.active = false
+	s.Sweep()
+	if _, ok := s.Peek("active"); ok {
+		t.Error("an unpinned entry idle past the TTL survived a sweep")
+	}
+	if st := s.Stats(); st.Entries != 2 || st.Pinned != 2 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestDeleteIsNotAnEviction(t *testing.T) {
+	s := New[int](1)
+	got := recordEvictions(s)
+	s.Set("a", 1)
+	if !s.Delete("a") || s.Delete("a") {
+		t.Error("Delete did not report presence")
+	}
+	if len(*got) != 0 {
+		t.Errorf("evictions = %v", *got)
+	}
+}
@@ -0,0 +1,169 @@
+// Package faults is the fault-injection registry behind the daemons'
+// /v1/admin/faults API. A fault targets one resource (or every resource) and
+// is consumed one request at a time until its count runs out.
+package faults
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+// Fault kinds every registry supports
+const (
+	KindDelay = "delay"
+	KindError = "error"
+)
+
+// Request configures an injected fault. BER and ShortfallPercent are the
+// kind-specific parameters of corrd's and memqosd's own fault kinds.
+type Request struct {
+	Kind             string  `json:"kind"`
+	Target           string  `json:"target,omitempty"` // resource ID; empty matches every resource
+	Count            int     `json:"count"`            // requests to affect
+	BER              float64 `json:"ber,omitempty"`
+	ShortfallPercent float64 `json:"shortfall_percent,omitempty"`
+	DelayMs          int     `json:"delay_ms,omitempty"`
+}
+
+// Fault is an active injected fault
+type Fault struct {
+	ID               string  `json:"id"`
+	Kind             string  `json:"kind"`
+	Target           string  `json:"target,omitempty"`
+	Remaining        int     `json:"remaining"`
+	BER              float64 `json:"ber,omitempty"`
+	ShortfallPercent float64 `json:"shortfall_percent,omitempty"`
+	DelayMs          int     `json:"delay_ms,omitempty"`
+}
+
+// Validator checks the kind-specific parameters of a fault request
+type Validator func(Request) error
+
+// Registry holds active faults, each consumed one request at a time
+type Registry struct {
+	kinds map[string]Validator
+
+	mu     sync.Mutex
+	faults []*Fault
+	nextID int
+}
+
+// NewRegistry creates a registry accepting the delay and error kinds plus
+// the daemon-specific kinds in extra
+func NewRegistry(extra map[string]Validator) *Registry {
+	kinds := map[string]Validator{
+		KindDelay: func(req Request) error {
+			if req.DelayMs <= 0 || req.DelayMs > 60000 {
+				return fmt.Errorf("delay_ms must be in (0, 60000]")
+			}
+			return nil
+		},
+		KindError: func(Request) error { return nil },
+	}
+	for kind, validate := range extra {
+		kinds[kind] = validate
+	}
+	return &Registry{kinds: kinds}
+}
+
+// Add validates and registers a fault
+func (r *Registry) Add(req Request) (*Fault, error) {
+	if req.Count <= 0 {
+		return nil, fmt.Errorf("count must be positive")
+	}
+	validate, ok := r.kinds[req.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unsupported fault kind: %s (%s)", req.Kind, strings.Join(r.Kinds(), "|"))
+	}
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	fault := &Fault{
+		ID:               "fault-" + strconv.Itoa(r.nextID),
+		Kind:             req.Kind,
+		Target:           req.Target,
+		Remaining:        req.Count,
+		BER:              req.BER,
+		ShortfallPercent: req.ShortfallPercent,
+		DelayMs:          req.DelayMs,
+	}
+	r.faults = append(r.faults, fault)
+	copied := *fault
+	return &copied, nil
+}
+
+// Kinds returns the supported fault kinds in order
+func (r *Registry) Kinds() []string {
+	kinds := make([]string, 0, len(r.kinds))
+	for kind := range r.kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Take consumes one request from the first fault matching kind and target,
+// dropping the fault once exhausted. It returns nil when none applies.
+func (r *Registry) Take(kind, target string) *Fault {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, fault := range r.faults {
+		if fault.Kind != kind || (fault.Target != "" && fault.Target != target) {
+			continue
+		}
+		fault.Remaining--
+		if fault.Remaining <= 0 {
+			r.faults = append(r.faults[:i], r.faults[i+1:]...)
+		}
+		copied := *fault
+		return &copied
+	}
+	return nil
+}
+
+// List returns the active faults
+func (r *Registry) List() []Fault {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Fault, 0, len(r.faults))
+	for _, fault := range r.faults {
+		out = append(out, *fault)
+	}
+	return out
+}
+
+// Clear drops every active fault
+func (r *Registry) Clear() {
+	r.mu.Lock()
+	r.faults = nil
+	r.mu.Unlock()
+}
+
+// Middleware applies delay and error faults to the resource that target
+// extracts from each request before the next handler runs
+func (r *Registry) Middleware(target func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			id := target(req)
+			if fault := r.Take(KindDelay, id); fault != nil {
+				time.Sleep(time.Duration(fault.DelayMs) * time.Millisecond)
+			}
+			if fault := r.Take(KindError, id); fault != nil {
+				apierr.Respond(w, apierr.CodeInjectedFault, "injected fault "+fault.ID)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package faults
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTakeConsumesCount(t *testing.T) {
+	r := NewRegistry(nil)
+	if _, err := r.Add(Request{Kind: KindError, Target: "a", Count: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Take(KindError, "b") != nil {
+		t.Error("a fault targeting a matched b")
+	}
+	if r.Take(KindDelay, "a") != nil {
+		t.Error("an error fault matched a delay lookup")
+	}
+	for i := 2; i > 0; i-- {
+		fault := r.Take(KindError, "a")
+		if fault == nil || fault.Remaining != i-1 {
+			t.Fatalf("take %d = %+v", 3-i, fault)
+		}
+	}
+	if r.Take(KindError, "a") != nil || len(r.List()) != 0 {
+		t.Error("fault survived past its count")
+	}
+}
+
+func TestUntargetedMatchesEveryResource(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Add(Request{Kind: KindError, Count: 2})
+	if r.Take(KindError, "a") == nil || r.Take(KindError, "b") == nil {
+		t.Error("an untargeted fault did not match every resource")
+	}
+}
+
+func TestAddValidates(t *testing.T) {
+	r := NewRegistry(map[string]Validator{
+		"custom": func(req Request) error {
+			if req.BER == 0 {
+				return errors.New("ber required")
+			}
+			return nil
+		},
+	})
+	for _, req := range []Request{
+		{Kind: KindError, Count: 0},
+		{Kind: KindDelay, Count: 1, DelayMs: 0},
+		{Kind: KindDelay, Count: 1, DelayMs: 60001},
+		{Kind: "custom", Count: 1},
+	} {
+		if _, err := r.Add(req); err == nil {
+			t.Errorf("Add(%+v) succeeded", req)
+		}
+	}
+	_, err := r.Add(Request{Kind: "bogus", Count: 1})
+	if err == nil || !strings.Contains(err.Error(), "custom|delay|error") {
+		t.Errorf("unsupported kind error = %v", err)
+	}
+	if _, err := r.Add(Request{Kind: "custom", Count: 1, BER: 1e-3}); err != nil {
+		t.Errorf("valid custom fault: %v", err)
+	}
+}
+
+func TestMiddlewareForcesErrors(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Add(Request{Kind: KindError, Target: "x", Count: 1})
+	h := r.Middleware(func(req *http.Request) string { return req.URL.Query().Get("id") })(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	for _, tc := range []struct {
+		id   string
+		want int
+	}{
+		{"y", http.StatusOK},
+		{"x", http.StatusInternalServerError},
+		{"x", http.StatusOK},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/?id="+tc.id, nil))
+		if rec.Code != tc.want {
+			t.Errorf("id %s: status = %d, want %d", tc.id, rec.Code, tc.want)
+		}
+	}
+}
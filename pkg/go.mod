@@ -0,0 +1,3 @@
+module github.com/corridoros/pkg
+
+go 1.27
@@ -0,0 +1,254 @@
+// Package journal is the append-only record of mutating operations the
+// CorridorOS daemons keep for debugging, compliance and crash recovery.
+// Each entry carries the state of the resource it changed, so replaying
+// the journal in order rebuilds a daemon's in-memory store.
+//
+// Entries are stored one JSON object per line and numbered from 1. A line
+// torn by a crash mid-write is dropped when the journal is reopened.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/corridoros/pkg/apierr"
+)
+
+// Entry is one journaled operation
+type Entry struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	Op   string    `json:"op"`
+	ID   string    `json:"id"` // resource the operation changed
+	// Data is the resource after the operation; empty when the operation
+	// removed it
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Limits on a Read
+const (
+	DefaultReadLimit = 100
+	MaxReadLimit     = 1000
+)
+
+// Journal is a file-backed journal, safe for concurrent use. A nil
+// *Journal records nothing, so services can journal unconditionally.
+type Journal struct {
+	mu   sync.Mutex
+	f    *os.File
+	last uint64
+}
+
+// Open opens the journal at path, creating it if needed, and positions it
+// after its last complete entry
+func Open(path string) (*Journal, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{f: f}
+	end, err := j.scan(func(e Entry) error {
+		j.last = e.Seq
+		return nil
+	})
+	if err == nil {
+		err = f.Truncate(end)
+	}
+	if err == nil {
+		_, err = f.Seek(end, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("journal %s: %w", path, err)
+	}
+	return j, nil
+}
+
+// Append records an operation on resource id with the resource's state
+// after it, nil for a removal, and syncs it to disk before returning
+func (j *Journal) Append(op, id string, data any) (Entry, error) {
+	if j == nil {
+		return Entry{}, nil
+	}
+	e := Entry{Time: time.Now().UTC(), Op: op, ID: id}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return Entry{}, err
+		}
+		e.Data = raw
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	e.Seq = j.last + 1
+	line, err := json.Marshal(e)
+	if err != nil {
+		return Entry{}, err
+	}
+	if _, err := j.f.Write(append(line, '\n')); err != nil {
+		return Entry{}, err
+	}
+	if err := j.f.Sync(); err != nil {
+		return Entry{}, err
+	}
+	j.last = e.Seq
+	return e, nil
+}
+
+// Log appends like Append, logging a failed write instead of returning
+// it, for operations that have already taken effect. A daemon calls it
+// under its store lock, which keeps entries in the order the operations
+// were applied.
+func (j *Journal) Log(op, id string, data any) {
+	if _, err := j.Append(op, id, data); err != nil {
+		log.Printf("journal: %s %s: %v", op, id, err)
+	}
+}
+
+// Record is a resource live at the end of a journal
+type Record[T any] struct {
+	ID    string
+	Value T // as of the resource's last entry
+}
+
+// Live replays j and returns the resources live at its end, in the order
+// they were journaled into existence. An entry with op removeOp removes
+// its resource; any other entry's data is the resource's new state.
+func Live[T any](j *Journal, removeOp string) ([]Record[T], error) {
+	var records []*Record[T]
+	live := make(map[string]*Record[T])
+	err := j.Replay(func(e Entry) error {
+		if e.Op == removeOp {
+			delete(live, e.ID)
+			return nil
+		}
+		var v T
+		if err := json.Unmarshal(e.Data, &v); err != nil {
+			return err
+		}
+		if rec, ok := live[e.ID]; ok {
+			rec.Value = v
+			return nil
+		}
+		rec := &Record[T]{ID: e.ID, Value: v}
+		live[e.ID] = rec
+		records = append(records, rec)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Record[T], 0, len(live))
+	for _, rec := range records {
+		if live[rec.ID] == rec {
+			out = append(out, *rec)
+		}
+	}
+	return out, nil
+}
+
+// Replay calls fn with every entry in order, stopping at its first error
+func (j *Journal) Replay(fn func(Entry) error) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err := j.scan(fn)
+	return err
+}
+
+// Read returns up to limit entries from sequence number from on
+func (j *Journal) Read(from uint64, limit int) ([]Entry, error) {
+	entries := []Entry{}
+	if j == nil {
+		return entries, nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err := j.scan(func(e Entry) error {
+		if e.Seq >= from && len(entries) < limit {
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Close closes the journal's file
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	return j.f.Close()
+}
+
+// scan reads the journal from the start, calling fn with each complete
+// entry, and returns the offset just past the last one. Only the final
+// line may be incomplete; corruption before it is an error. The caller
+// must hold the lock, or own the journal as Open does.
+func (j *Journal) scan(fn func(Entry) error) (int64, error) {
+	r := bufio.NewReader(io.NewSectionReader(j.f, 0, 1<<62))
+	var offset int64
+	var prev uint64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return offset, nil // a torn final line, if any, is dropped
+		}
+		if err != nil {
+			return offset, err
+		}
+		var e Entry
+		if err := json.Unmarshal(bytes.TrimSpace(line), &e); err != nil || e.Seq != prev+1 {
+			return offset, fmt.Errorf("corrupt entry at offset %d", offset)
+		}
+		if err := fn(e); err != nil {
+			return offset, fmt.Errorf("entry %d: %w", e.Seq, err)
+		}
+		prev = e.Seq
+		offset += int64(len(line))
+	}
+}
+
+// Handler serves GET ?from=&limit=, the entries from sequence number from
+// (default 1) on
+func Handler(j *Journal) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, limit := uint64(1), DefaultReadLimit
+		if raw := r.URL.Query().Get("from"); raw != "" {
+			n, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				apierr.Respond(w, apierr.CodeBadRequest, "from must be a sequence number")
+				return
+			}
+			from = n
+		}
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n <= 0 || n > MaxReadLimit {
+				apierr.Respond(w, apierr.CodeBadRequest, fmt.Sprintf("limit must be 1-%d", MaxReadLimit))
+				return
+			}
+			limit = n
+		}
+
+		entries, err := j.Read(from, limit)
+		if err != nil {
+			apierr.Respond(w, apierr.CodeInternal, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"entries": entries})
+	}
+}
@@ -0,0 +1,145 @@
+package journal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type widget struct {
+	Size int `json:"size"`
+}
+
+// open opens the journal at path, failing the test on error
+func open(t *testing.T, path string) *Journal {
+	t.Helper()
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { j.Close() })
+	return j
+}
+
+func TestAppendNumbersEntriesAcrossReopens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "j")
+	j := open(t, path)
+	for _, op := range []string{"create", "resize"} {
+		if _, err := j.Append(op, "w-1", widget{Size: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j.Close()
+
+	j = open(t, path)
+	e, err := j.Append("delete", "w-1", nil)
+	if err != nil || e.Seq != 3 || e.Data != nil {
+		t.Fatalf("append after reopen = %+v, %v; want seq 3 without data", e, err)
+	}
+	entries, err := j.Read(2, 10)
+	if err != nil || len(entries) != 2 || entries[0].Op != "resize" || entries[1].Op != "delete" {
+		t.Errorf("Read(2, 10) = %+v, %v", entries, err)
+	}
+	if entries, _ := j.Read(1, 1); len(entries) != 1 || entries[0].Seq != 1 {
+		t.Errorf("Read(1, 1) = %+v", entries)
+	}
+}
+
+func TestTornFinalLineIsDropped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "j")
+	j := open(t, path)
+	j.Append("create", "w-1", widget{Size: 1})
+	j.Close()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":2,"op":"cre`)
+	f.Close()
+
+	j = open(t, path)
+	if e, err := j.Append("resize", "w-1", widget{Size: 2}); err != nil || e.Seq != 2 {
+		t.Fatalf("append after a torn line = %+v, %v", e, err)
+	}
+	var ops []string
+	if err := j.Replay(func(e Entry) error { ops = append(ops, e.Op); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[1] != "resize" {
+		t.Errorf("replayed ops = %v", ops)
+	}
+}
+
+func TestCorruptionBeforeTheEndFailsOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "j")
+	os.WriteFile(path, []byte(`{"seq":1,"op":"create","id":"a"}`+"\n"+`{"seq":3,"op":"create","id":"b"}`+"\n"), 0o600)
+	if _, err := Open(path); err == nil {
+		t.Error("opened a journal with a gap in its sequence numbers")
+	}
+}
+
+func TestLiveFoldsToTheLatestState(t *testing.T) {
+	j := open(t, filepath.Join(t.TempDir(), "j"))
+	for _, step := range []struct {
+		op, id string
+		size   int
+	}{
+		{"create", "a", 1},
+		{"create", "b", 1},
+		{"create", "c", 1},
+		{"resize", "a", 5},
+		{"delete", "b", 0},
+		{"delete", "a", 0},
+		{"create", "a", 7}, // recreated: now after c
+	} {
+		var data any
+		if step.op != "delete" {
+			data = widget{Size: step.size}
+		}
+		if _, err := j.Append(step.op, step.id, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	live, err := Live[widget](j, "delete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Record[widget]{{"c", widget{1}}, {"a", widget{7}}}
+	if len(live) != len(want) || live[0] != want[0] || live[1] != want[1] {
+		t.Errorf("Live = %+v, want %+v", live, want)
+	}
+	if live, err := Live[widget](nil, "delete"); err != nil || len(live) != 0 {
+		t.Errorf("Live(nil) = %+v, %v", live, err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	j := open(t, filepath.Join(t.TempDir(), "j"))
+	for i := 0; i < 5; i++ {
+		j.Append("create", "w", widget{Size: i})
+	}
+	get := func(query string) (int, []Entry) {
+		rec := httptest.NewRecorder()
+		Handler(j)(rec, httptest.NewRequest(http.MethodGet, "/journal"+query, nil))
+		var body struct{ Entries []Entry }
+		json.NewDecoder(rec.Body).Decode(&body)
+		return rec.Code, body.Entries
+	}
+
+	if code, entries := get("?from=2&limit=2"); code != http.StatusOK || len(entries) != 2 || entries[0].Seq != 2 {
+		t.Errorf("from=2&limit=2: %d %+v", code, entries)
+	}
+	if code, entries := get(""); code != http.StatusOK || len(entries) != 5 {
+		t.Errorf("defaults: %d %d entries", code, len(entries))
+	}
+	for _, q := range []string{"?from=x", "?limit=0", "?limit=1001"} {
+		if code, _ := get(q); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, code)
+		}
+	}
+}
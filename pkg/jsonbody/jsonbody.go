@@ -0,0 +1,89 @@
+// Package jsonbody decodes the JSON request bodies of the CorridorOS
+// services. Unlike a bare json.Decoder it rejects anything after the first
+// value, so a body of two concatenated objects is an error rather than
+// silently half-read, and it words errors after the field at fault.
+package jsonbody
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Errors for bodies that are not exactly one JSON value
+var (
+	ErrEmpty        = errors.New("request body is empty")
+	ErrTruncated    = errors.New("request body ends in the middle of a JSON value")
+	ErrTrailingData = errors.New("request body has data after the JSON value")
+)
+
+// Option adjusts how a body is decoded
+type Option func(*json.Decoder)
+
+// Strict rejects fields the target type does not have, so a misspelt
+// optional field fails instead of silently taking its default
+func Strict(d *json.Decoder) { d.DisallowUnknownFields() }
+
+// Decode reads exactly one JSON value from r into v
+func Decode(r io.Reader, v any, opts ...Option) error {
+	dec := json.NewDecoder(r)
+	for _, opt := range opts {
+		opt(dec)
+	}
+	if err := dec.Decode(v); err != nil {
+		return describe(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return ErrTrailingData
+	}
+	return nil
+}
+
+// describe rewords a decoding error after the part of the body at fault
+func describe(err error) error {
+	var syntax *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return ErrEmpty
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrTruncated
+	case errors.As(err, &syntax):
+		return fmt.Errorf("malformed JSON at byte %d: %s", syntax.Offset, strings.TrimPrefix(syntax.Error(), "json: "))
+	case errors.As(err, &typ):
+		if typ.Field == "" {
+			return fmt.Errorf("request body must be a JSON %s, not %s", jsonKind(typ.Type.String()), valueKind(typ.Value))
+		}
+		return fmt.Errorf("field %q must be a JSON %s, not %s", typ.Field, jsonKind(typ.Type.String()), valueKind(typ.Value))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return fmt.Errorf("unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	}
+	return err
+}
+
+// jsonKind names the JSON kind a Go type decodes from
+func jsonKind(goType string) string {
+	switch {
+	case strings.HasPrefix(goType, "[]"):
+		return "array"
+	case strings.HasPrefix(goType, "map["), strings.Contains(goType, "."):
+		return "object"
+	case goType == "string":
+		return "string"
+	case goType == "bool":
+		return "boolean"
+	case strings.HasPrefix(goType, "int"), strings.HasPrefix(goType, "uint"), strings.HasPrefix(goType, "float"):
+		return "number"
+	}
+	return goType
+}
+
+// valueKind names the kind of JSON value encoding/json reports finding
+func valueKind(value string) string {
+	if value == "bool" {
+		return "boolean"
+	}
+	return value
+}
@@ -0,0 +1,67 @@
+package jsonbody
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type request struct {
+	Bytes  uint64            `json:"bytes"`
+	Class  string            `json:"latency_class"`
+	Lanes  []int             `json:"lanes"`
+	Labels map[string]string `json:"labels"`
+}
+
+func TestDecodeAcceptsOneValue(t *testing.T) {
+	var req request
+	body := " {\"bytes\": 1024, \"latency_class\": \"T1\", \"lanes\": [1, 2]}\n\n"
+	if err := Decode(strings.NewReader(body), &req, Strict); err != nil {
+		t.Fatal(err)
+	}
+	if req.Bytes != 1024 || req.Class != "T1" || len(req.Lanes) != 2 {
+		t.Errorf("decoded %+v", req)
+	}
+}
+
+func TestDecodeRejectsBadBodies(t *testing.T) {
+	for _, tc := range []struct {
+		name, body string
+		is         error  // sentinel the error matches, if any
+		says       string // text the error contains
+	}{
+		{"two objects", `{"bytes":1}{"bytes":2}`, ErrTrailingData, ""},
+		{"trailing garbage", `{"bytes":1} xyz`, ErrTrailingData, ""},
+		{"empty", "", ErrEmpty, ""},
+		{"whitespace", " \n", ErrEmpty, ""},
+		{"truncated", `{"bytes":1,"latency_class":"T`, ErrTruncated, ""},
+		{"malformed", `{"bytes":1,,}`, nil, "malformed JSON at byte"},
+		{"mistyped field", `{"bytes":"lots"}`, nil, `field "bytes" must be a JSON number, not string`},
+		{"mistyped element", `{"lanes":[true]}`, nil, `must be a JSON number, not boolean`},
+		{"mistyped map", `{"labels":[]}`, nil, `field "labels" must be a JSON object, not array`},
+		{"not an object", `[1,2]`, nil, "request body must be a JSON object, not array"},
+	} {
+		var req request
+		err := Decode(strings.NewReader(tc.body), &req)
+		switch {
+		case err == nil:
+			t.Errorf("%s: accepted", tc.name)
+		case tc.is != nil && !errors.Is(err, tc.is):
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.is)
+		case !strings.Contains(err.Error(), tc.says):
+			t.Errorf("%s: err = %q, want it to say %q", tc.name, err, tc.says)
+		}
+	}
+}
+
+func TestUnknownFieldsOnlyFailWhenStrict(t *testing.T) {
+	body := `{"bytes":1,"latncy_class":"T1"}`
+	var req request
+	if err := Decode(strings.NewReader(body), &req); err != nil {
+		t.Errorf("lenient decode: %v", err)
+	}
+	err := Decode(strings.NewReader(body), &req, Strict)
+	if err == nil || err.Error() != `unknown field "latncy_class"` {
+		t.Errorf("strict decode: err = %v, want the unknown field named", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Package labels validates the key/value labels operators attach to
+// CorridorOS resources and selects resources by them, as in
+// GET /v1/corridors?label.team=alpha.
+package labels
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Limits on a resource's labels
+const (
+	MaxLabels      = 32
+	MaxKeyLength   = 63
+	MaxValueLength = 255
+)
+
+// QueryPrefix marks the query parameters that form a Selector
+const QueryPrefix = "label."
+
+// Validate checks a resource's labels against the limits. Keys must be
+// non-empty and use only letters, digits and "-_./" so they survive as
+// query parameter names.
+func Validate(labels map[string]string) error {
+	if len(labels) > MaxLabels {
+		return fmt.Errorf("at most %d labels are allowed, got %d", MaxLabels, len(labels))
+	}
+	for key, value := range labels {
+		if err := validateKey(key); err != nil {
+			return err
+		}
+		if len(value) > MaxValueLength {
+			return fmt.Errorf("label %s: value longer than %d bytes", key, MaxValueLength)
+		}
+	}
+	return nil
+}
+
+func validateKey(key string) error {
+	if key == "" || len(key) > MaxKeyLength {
+		return fmt.Errorf("label key %q must be 1 to %d bytes", key, MaxKeyLength)
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./", r)) {
+			return fmt.Errorf("label key %q may only contain letters, digits and -_./", key)
+		}
+	}
+	return nil
+}
+
+// Selector requires each of its keys to be present with the given value.
+// A nil or empty Selector matches everything.
+type Selector map[string]string
+
+// FromQuery builds a Selector from the label.<key>=<value> parameters of a
+// query, ignoring the others
+func FromQuery(q url.Values) (Selector, error) {
+	var sel Selector
+	for param, values := range q {
+		key, ok := strings.CutPrefix(param, QueryPrefix)
+		if !ok {
+			continue
+		}
+		if err := validateKey(key); err != nil {
+			return nil, err
+		}
+		if len(values) != 1 {
+			return nil, fmt.Errorf("label %s selected more than once", key)
+		}
+		if sel == nil {
+			sel = make(Selector)
+		}
+		sel[key] = values[0]
+	}
+	return sel, nil
+}
+
+// Matches reports whether labels satisfies every requirement; resources
+// without a selected key never match
+func (s Selector) Matches(labels map[string]string) bool {
+	for key, want := range s {
+		if got, ok := labels[key]; !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package labels
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	if err := Validate(map[string]string{"team": "alpha", "cost-center/id": "42", "app.name_v2": ""}); err != nil {
+		t.Errorf("valid labels rejected: %v", err)
+	}
+	tooMany := map[string]string{}
+	for i := 0; i <= MaxLabels; i++ {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+	for name, labels := range map[string]map[string]string{
+		"empty key":  {"": "v"},
+		"long key":   {strings.Repeat("k", MaxKeyLength+1): "v"},
+		"bad rune":   {"team name": "v"},
+		"long value": {"team": strings.Repeat("v", MaxValueLength+1)},
+		"too many":   tooMany,
+	} {
+		if err := Validate(labels); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	q, _ := url.ParseQuery("label.team=alpha&label.env=prod&limit=5")
+	sel, err := FromQuery(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sel) != 2 || sel["team"] != "alpha" || sel["env"] != "prod" {
+		t.Errorf("selector = %v", sel)
+	}
+
+	if sel, err := FromQuery(url.Values{"limit": {"5"}}); err != nil || sel != nil {
+		t.Errorf("no label params = %v, %v; want a nil selector", sel, err)
+	}
+	for _, raw := range []string{"label.team=a&label.team=b", "label.=x", "label.bad%20key=x"} {
+		q, _ := url.ParseQuery(raw)
+		if _, err := FromQuery(q); err == nil {
+			t.Errorf("%s: accepted", raw)
+		}
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"team": "alpha", "env": "prod"}
+	for _, tc := range []struct {
+		sel  Selector
+		want bool
+	}{
+		{nil, true},
+		{Selector{"team": "alpha"}, true},
+		{Selector{"team": "alpha", "env": "prod"}, true},
+		{Selector{"team": "beta"}, false},
+		{Selector{"owner": ""}, false},
+	} {
+		if got := tc.sel.Matches(labels); got != tc.want {
+			t.Errorf("%v.Matches = %v, want %v", tc.sel, got, tc.want)
+		}
+	}
+	if (Selector{"team": "alpha"}).Matches(nil) {
+		t.Error("an unlabeled resource matched a label query")
+	}
+}
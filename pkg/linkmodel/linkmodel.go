@@ -0,0 +1,207 @@
+// Package linkmodel estimates what an optical corridor link achieves: its
+// BER, eye margin and energy per bit under given ambient conditions.
+// corrd models allocations and their telemetry with it and helio-sim
+// settles simulated calibrations on its estimates, so a model registered
+// here is available to both.
+package linkmodel
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Config is the corridor configuration a link is estimated for
+type Config struct {
+	Modulation string  // NRZ or PAM4; empty means NRZ
+	BaudGBd    float64 // per-lane symbol rate; zero means ReferenceBaudGBd
+	ReachMm    int
+	Lanes      int
+}
+
+// Conditions are the ambient conditions of a link
+type Conditions struct {
+	TempC      float64
+	NoiseLevel float64 // relative ambient noise, 0 for a quiet link
+}
+
+// Estimate is a model's estimate of a link
+type Estimate struct {
+	BER           float64 `json:"ber"`
+	EyeMarginUI   float64 `json:"eye_margin_ui"`
+	PowerPjPerBit float64 `json:"power_pj_per_bit"`
+}
+
+// LinkModel estimates a link from its configuration and conditions
+type LinkModel interface {
+	Name() string
+	Estimate(Config, Conditions) (Estimate, error)
+}
+
+// ReferenceBaudGBd is the symbol rate models are calibrated at
+const ReferenceBaudGBd = 53.125
+
+// Default is the model used when none is selected
+const Default = "heuristic"
+
+// models are the built-in models by name
+var models = map[string]LinkModel{
+	"heuristic": Heuristic{},
+	"qfactor":   QFactorModel{},
+}
+
+// Lookup returns the model registered under name; empty selects Default
+func Lookup(name string) (LinkModel, error) {
+	if name == "" {
+		name = Default
+	}
+	m, ok := models[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown link model: %s (%s)", name, strings.Join(Names(), "|"))
+	}
+	return m, nil
+}
+
+// Register adds a model, replacing any of the same name. It is not safe
+// for concurrent use; register models from init functions.
+func Register(m LinkModel) {
+	models[strings.ToLower(m.Name())] = m
+}
+
+// Names lists the registered models, sorted
+func Names() []string {
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Eye margin model: each UI of eye opening is worth BERDecadesPerUI decades
+// of BER, clamped to [MinEyeMarginUI, MaxEyeMarginUI]
+const (
+	BERDecadesPerUI = 15.0
+	MinEyeMarginUI  = 0.1
+	MaxEyeMarginUI  = 1.0
+)
+
+// EyeMarginFromBER maps a BER to an eye opening in UI
+func EyeMarginFromBER(ber float64) float64 {
+	return math.Max(MinEyeMarginUI, math.Min(MaxEyeMarginUI, -math.Log10(ber)/BERDecadesPerUI))
+}
+
+// QFactor inverts BER = ½·erfc(Q/√2) by bisection; math.Erfcinv loses
+// all precision below BERs of about 1e-16
+func QFactor(ber float64) float64 {
+	lo, hi := 0.0, 40.0
+	for i := 0; i < 64; i++ {
+		mid := (lo + hi) / 2
+		if 0.5*math.Erfc(mid/math.Sqrt2) > ber {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// minBER is the floor of estimated BERs, below which error counters
+// cannot resolve a rate
+const minBER = 1e-15
+
+// modulation is what the models need to know of a modulation format
+type modulation struct {
+	BitsPerSymbol  float64
+	BERPenalty     float64 // heuristic BER multiplier relative to NRZ
+	EyeFraction    float64 // eye height relative to NRZ at equal power
+	SerDesPjPerBit float64
+}
+
+var modulations = map[string]modulation{
+	"NRZ":  {BitsPerSymbol: 1, BERPenalty: 1, EyeFraction: 1, SerDesPjPerBit: 0.7},
+	"PAM4": {BitsPerSymbol: 2, BERPenalty: 10, EyeFraction: 1.0 / 3, SerDesPjPerBit: 1.0},
+}
+
+func lookupModulation(name string) (modulation, error) {
+	if name == "" {
+		name = "NRZ"
+	}
+	m, ok := modulations[strings.ToUpper(name)]
+	if !ok {
+		return modulation{}, fmt.Errorf("unsupported modulation: %s (NRZ|PAM4)", name)
+	}
+	return m, nil
+}
+
+// heuristicPjPerBit is the flat link energy of the heuristic model
+const heuristicPjPerBit = 0.9
+
+// Heuristic is corrd's original model: BER grows a decade per 100 mm of
+// reach from 1e-12, with a fixed penalty for PAM4, and energy per bit is
+// flat. It ignores ambient conditions.
+type Heuristic struct{}
+
+func (Heuristic) Name() string { return "heuristic" }
+
+func (Heuristic) Estimate(cfg Config, _ Conditions) (Estimate, error) {
+	m, err := lookupModulation(cfg.Modulation)
+	if err != nil {
+		return Estimate{}, err
+	}
+	ber := math.Min(1e-12*math.Pow(10, float64(cfg.ReachMm)/100.0)*m.BERPenalty, 0.5)
+	return Estimate{BER: ber, EyeMarginUI: EyeMarginFromBER(ber), PowerPjPerBit: heuristicPjPerBit}, nil
+}
+
+// Q-factor model link budget: a thermal-noise-limited receiver whose Q
+// scales with received optical power, reaching referenceQ (BER 1e-12) at
+// rxSensitivityDbm for NRZ at ReferenceBaudGBd and referenceTempC
+const (
+	txPowerDbm       = 0.0
+	lossDbPerMm      = 0.1
+	connectorLossDb  = 2.0
+	rxSensitivityDbm = -10.0
+	referenceTempC   = 25.0
+	laserWallPlug    = 0.1 // optical power out per electrical power in
+)
+
+// referenceQ is the Q at the receiver's sensitivity, the Q of BER 1e-12
+var referenceQ = QFactor(1e-12)
+
+// QFactorModel derives BER from a link budget. Q grows linearly with
+// received power, shrinks with the eye height of the modulation, and with
+// the square root of the noise bandwidth (baud) and of absolute
+// temperature; ambient noise divides it by 1 + NoiseLevel. Energy per bit
+// is the SerDes cost plus the laser's electrical power over the bit rate.
+type QFactorModel struct{}
+
+func (QFactorModel) Name() string { return "qfactor" }
+
+func (QFactorModel) Estimate(cfg Config, cond Conditions) (Estimate, error) {
+	m, err := lookupModulation(cfg.Modulation)
+	if err != nil {
+		return Estimate{}, err
+	}
+	baud := cfg.BaudGBd
+	if baud == 0 {
+		baud = ReferenceBaudGBd
+	}
+	if baud < 0 || cond.TempC <= -273.15 || cond.NoiseLevel < 0 {
+		return Estimate{}, fmt.Errorf("baud, absolute temperature and noise level must not be negative")
+	}
+
+	rxDbm := txPowerDbm - lossDbPerMm*float64(cfg.ReachMm) - connectorLossDb
+	q := referenceQ * math.Pow(10, (rxDbm-rxSensitivityDbm)/10) * m.EyeFraction
+	q /= math.Sqrt(baud/ReferenceBaudGBd) * math.Sqrt((cond.TempC+273.15)/(referenceTempC+273.15))
+	q /= 1 + cond.NoiseLevel
+
+	ber := math.Max(minBER, math.Min(0.5, 0.5*math.Erfc(q/math.Sqrt2)))
+	laserMw := math.Pow(10, txPowerDbm/10) / laserWallPlug
+	laserPjPerBit := laserMw * 1e-3 / (baud * m.BitsPerSymbol * 1e9) * 1e12
+	return Estimate{
+		BER:           ber,
+		EyeMarginUI:   EyeMarginFromBER(ber),
+		PowerPjPerBit: m.SerDesPjPerBit + laserPjPerBit,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package linkmodel
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+var (
+	_ LinkModel = Heuristic{}
+	_ LinkModel = QFactorModel{}
+)
+
+func TestQFactorMatchesKnownPoints(t *testing.T) {
+	for ber, want := range map[float64]float64{1e-3: 3.09, 1e-9: 6.00, 1e-12: 7.03, 1e-15: 7.94} {
+		if got := QFactor(ber); math.Abs(got-want) > 0.01 {
+			t.Errorf("QFactor(%g) = %.3f, want %.2f", ber, got, want)
+		}
+	}
+}
+
+func TestBuiltinModelsAreSelfConsistent(t *testing.T) {
+	if names := Names(); !slices.Equal(names, []string{"heuristic", "qfactor"}) {
+		t.Fatalf("Names() = %v", names)
+	}
+	cond := Conditions{TempC: 25, NoiseLevel: 0.05}
+	for _, name := range Names() {
+		model, err := Lookup(name)
+		if err != nil || model.Name() != name {
+			t.Fatalf("Lookup(%s) = %v, %v", name, model, err)
+		}
+
+		prev := Estimate{}
+		for _, reach := range []int{0, 10, 50, 100, 200, 400} {
+			for _, mod := range []string{"NRZ", "PAM4"} {
+				est, err := model.Estimate(Config{Modulation: mod, ReachMm: reach, Lanes: 4}, cond)
+				if err != nil {
+					t.Fatalf("%s %s %d mm: %v", name, mod, reach, err)
+				}
+				if est.BER <= 0 || est.BER > 0.5 || est.PowerPjPerBit <= 0 {
+					t.Errorf("%s %s %d mm: %+v", name, mod, reach, est)
+				}
+				// Eye margin and BER sit on the same curve
+				if est.EyeMarginUI != EyeMarginFromBER(est.BER) {
+					t.Errorf("%s %s %d mm: eye margin %g for BER %g", name, mod, reach, est.EyeMarginUI, est.BER)
+				}
+				if mod == "NRZ" {
+					if est.BER < prev.BER {
+						t.Errorf("%s: BER improved from %g to %g at %d mm", name, prev.BER, est.BER, reach)
+					}
+					prev = est
+				} else if est.BER < prev.BER {
+					t.Errorf("%s: PAM4 BER %g beats NRZ %g at %d mm", name, est.BER, prev.BER, reach)
+				}
+			}
+		}
+	}
+}
+
+func TestQFactorModelConditions(t *testing.T) {
+	cfg := Config{ReachMm: 80} // at the receiver sensitivity: BER 1e-12
+	quiet, _ := QFactorModel{}.Estimate(cfg, Conditions{TempC: 25})
+	noisy, _ := QFactorModel{}.Estimate(cfg, Conditions{TempC: 25, NoiseLevel: 0.5})
+	hot, _ := QFactorModel{}.Estimate(cfg, Conditions{TempC: 85})
+	if noisy.BER <= quiet.BER || hot.BER <= quiet.BER {
+		t.Errorf("BER quiet %g, noisy %g, hot %g", quiet.BER, noisy.BER, hot.BER)
+	}
+	if _, err := (QFactorModel{}).Estimate(cfg, Conditions{TempC: 25, NoiseLevel: -1}); err == nil {
+		t.Error("negative noise level accepted")
+	}
+}
+
+type flatModel struct{}
+
+func (flatModel) Name() string { return "Flat" }
+
+func (flatModel) Estimate(Config, Conditions) (Estimate, error) {
+	return Estimate{BER: 1e-12, EyeMarginUI: EyeMarginFromBER(1e-12), PowerPjPerBit: 1}, nil
+}
+
+func TestLookupAndRegister(t *testing.T) {
+	if m, err := Lookup(""); err != nil || m.Name() != Default {
+		t.Errorf("Lookup(\"\") = %v, %v", m, err)
+	}
+	if _, err := Lookup("QFACTOR"); err != nil {
+		t.Errorf("lookup is case-sensitive: %v", err)
+	}
+	if _, err := Lookup("ideal"); err == nil {
+		t.Error("Lookup accepted an unknown model")
+	}
+	if _, err := (Heuristic{}).Estimate(Config{Modulation: "QAM16"}, Conditions{}); err == nil {
+		t.Error("unsupported modulation accepted")
+	}
+
+	Register(flatModel{})
+	t.Cleanup(func() { delete(models, "flat") })
+	if m, err := Lookup("flat"); err != nil || m.Name() != "Flat" {
+		t.Errorf("registered model: %v, %v", m, err)
+	}
+}
@@ -0,0 +1,334 @@
+// Package protowire encodes and decodes the protobuf wire format for the
+// CorridorOS endpoints that offer a binary encoding next to JSON, so hot
+// polling paths can skip JSON parsing. The message schemas are in
+// proto/corridoros/v1; each service mirrors them with hand-written
+// MarshalProto and UnmarshalProto methods on its existing structs.
+//
+// Encoders follow proto3: fields holding their zero value are omitted, and
+// decoders skip fields they do not know.
+package protowire
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ContentType selects the protobuf encoding in Accept and Content-Type
+const ContentType = "application/x-protobuf"
+
+// Wire types
+const (
+	TypeVarint  = 0
+	TypeFixed64 = 1
+	TypeBytes   = 2
+	TypeFixed32 = 5
+)
+
+// Message is a type with a protobuf encoding
+type Message interface {
+	MarshalProto() []byte
+}
+
+// Unmarshaler is a type decodable from the protobuf encoding
+type Unmarshaler interface {
+	UnmarshalProto([]byte) error
+}
+
+// Accepts reports whether a request's Accept header asks for protobuf.
+// JSON stays the default: */* and absent headers do not select it.
+func Accepts(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			if mt, _, err := mime.ParseMediaType(part); err == nil && mt == ContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Sent reports whether a request's body is protobuf, by its Content-Type
+func Sent(r *http.Request) bool {
+	return isContentType(r.Header.Get("Content-Type"))
+}
+
+// IsProto reports whether a response carries a protobuf body
+func IsProto(resp *http.Response) bool {
+	return isContentType(resp.Header.Get("Content-Type"))
+}
+
+func isContentType(value string) bool {
+	mt, _, err := mime.ParseMediaType(value)
+	return err == nil && mt == ContentType
+}
+
+// Decode reads a protobuf response body into m
+func Decode(resp *http.Response, m Unmarshaler) error {
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return m.UnmarshalProto(b)
+}
+
+// Write sends m with the given status as a protobuf body
+func Write(w http.ResponseWriter, code int, m Message) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(code)
+	_, _ = w.Write(m.MarshalProto())
+}
+
+// AppendTag appends a field's key
+func AppendTag(b []byte, field, wireType int) []byte {
+	return AppendVarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+// AppendVarint appends v as a base-128 varint
+func AppendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// AppendUint64 appends a uint64 field, omitting zero
+func AppendUint64(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return AppendVarint(AppendTag(b, field, TypeVarint), v)
+}
+
+// AppendInt64 appends an int64 field, omitting zero. Negative values take
+// ten bytes, as in protobuf's int64.
+func AppendInt64(b []byte, field int, v int64) []byte {
+	return AppendUint64(b, field, uint64(v))
+}
+
+// AppendBool appends a bool field, omitting false
+func AppendBool(b []byte, field int, v bool) []byte {
+	if !v {
+		return b
+	}
+	return AppendUint64(b, field, 1)
+}
+
+// AppendDouble appends a double field, omitting zero
+func AppendDouble(b []byte, field int, v float64) []byte {
+	if v == 0 && !math.Signbit(v) {
+		return b
+	}
+	return appendFixed64(AppendTag(b, field, TypeFixed64), math.Float64bits(v))
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	for i := 0; i < 8; i++ {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+// AppendString appends a string field, omitting the empty string
+func AppendString(b []byte, field int, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = AppendVarint(AppendTag(b, field, TypeBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+// AppendMessage appends an embedded message field from its encoding; an
+// empty message is still written, so a set field stays distinguishable
+func AppendMessage(b []byte, field int, encoded []byte) []byte {
+	b = AppendVarint(AppendTag(b, field, TypeBytes), uint64(len(encoded)))
+	return append(b, encoded...)
+}
+
+// AppendOptionalDouble appends a proto3 optional double field, written
+// whenever v is set, zero included
+func AppendOptionalDouble(b []byte, field int, v *float64) []byte {
+	if v == nil {
+		return b
+	}
+	return appendFixed64(AppendTag(b, field, TypeFixed64), math.Float64bits(*v))
+}
+
+// AppendOptionalBool appends a proto3 optional bool field, written
+// whenever v is set, false included
+func AppendOptionalBool(b []byte, field int, v *bool) []byte {
+	if v == nil {
+		return b
+	}
+	var x uint64
+	if *v {
+		x = 1
+	}
+	return AppendVarint(AppendTag(b, field, TypeVarint), x)
+}
+
+// AppendRepeatedString appends each element of a repeated string field,
+// empty strings included
+func AppendRepeatedString(b []byte, field int, vs []string) []byte {
+	for _, v := range vs {
+		b = AppendMessage(b, field, []byte(v))
+	}
+	return b
+}
+
+// AppendDoubleMap appends a map<string, double> field, its entries in key
+// order so equal maps encode identically
+func AppendDoubleMap(b []byte, field int, m map[string]float64) []byte {
+	for _, k := range sortedKeys(m) {
+		var entry []byte
+		entry = AppendString(entry, 1, k)
+		entry = AppendDouble(entry, 2, m[k])
+		b = AppendMessage(b, field, entry)
+	}
+	return b
+}
+
+// AppendStringMap appends a map<string, string> field, its entries in key
+// order
+func AppendStringMap(b []byte, field int, m map[string]string) []byte {
+	for _, k := range sortedKeys(m) {
+		var entry []byte
+		entry = AppendString(entry, 1, k)
+		entry = AppendString(entry, 2, m[k])
+		b = AppendMessage(b, field, entry)
+	}
+	return b
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AppendTime appends a google.protobuf.Timestamp field, omitting the zero
+// time
+func AppendTime(b []byte, field int, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	var ts []byte
+	ts = AppendInt64(ts, 1, t.Unix())
+	ts = AppendInt64(ts, 2, int64(t.Nanosecond()))
+	return AppendMessage(b, field, ts)
+}
+
+// Field is one decoded field: Varint holds varint and fixed values, Bytes
+// length-delimited ones
+type Field struct {
+	Num    int
+	Type   int
+	Varint uint64
+	Bytes  []byte
+}
+
+// ErrTruncated marks a message that ends inside a field
+var ErrTruncated = errors.New("protowire: truncated message")
+
+// Range calls fn with each field of an encoded message, in order
+func Range(b []byte, fn func(Field) error) error {
+	for len(b) > 0 {
+		key, n := consumeVarint(b)
+		if n == 0 {
+			return ErrTruncated
+		}
+		b = b[n:]
+		f := Field{Num: int(key >> 3), Type: int(key & 7)}
+		switch f.Type {
+		case TypeVarint:
+			if f.Varint, n = consumeVarint(b); n == 0 {
+				return ErrTruncated
+			}
+		case TypeFixed64, TypeFixed32:
+			n = 8
+			if f.Type == TypeFixed32 {
+				n = 4
+			}
+			if len(b) < n {
+				return ErrTruncated
+			}
+			for i := 0; i < n; i++ {
+				f.Varint |= uint64(b[i]) << (8 * i)
+			}
+		case TypeBytes:
+			size, m := consumeVarint(b)
+			if m == 0 || uint64(len(b)-m) < size {
+				return ErrTruncated
+			}
+			f.Bytes, n = b[m:m+int(size)], m+int(size)
+		default:
+			return fmt.Errorf("protowire: field %d has unsupported wire type %d", f.Num, f.Type)
+		}
+		b = b[n:]
+		if f.Num == 0 {
+			return fmt.Errorf("protowire: invalid field number 0")
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consumeVarint decodes a varint, returning 0 bytes read if b ends first
+// or the varint overflows 64 bits
+func consumeVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * i)
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// Double returns a fixed64 field as a double
+func (f Field) Double() float64 { return math.Float64frombits(f.Varint) }
+
+// Time decodes a google.protobuf.Timestamp field, in UTC
+func (f Field) Time() (time.Time, error) {
+	var sec, nsec int64
+	err := Range(f.Bytes, func(ts Field) error {
+		switch ts.Num {
+		case 1:
+			sec = int64(ts.Varint)
+		case 2:
+			nsec = int64(ts.Varint)
+		}
+		return nil
+	})
+	return time.Unix(sec, nsec).UTC(), err
+}
+
+// MapEntry decodes a map field's entry into its key and value; a value
+// holding its zero value decodes as the zero Field
+func (f Field) MapEntry() (string, Field, error) {
+	var key string
+	var value Field
+	err := Range(f.Bytes, func(e Field) error {
+		switch e.Num {
+		case 1:
+			key = string(e.Bytes)
+		case 2:
+			value = e
+		}
+		return nil
+	})
+	return key, value, err
+}
@@ -0,0 +1,123 @@
+package protowire
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	zero, yes := 0.0, false
+	sampled := time.Date(2026, 3, 1, 12, 30, 0, 500, time.UTC)
+	var b []byte
+	b = AppendDouble(b, 1, -1.5)
+	b = AppendInt64(b, 2, -7)
+	b = AppendString(b, 3, "corridor")
+	b = AppendBool(b, 4, true)
+	b = AppendOptionalDouble(b, 5, &zero)
+	b = AppendOptionalBool(b, 6, &yes)
+	b = AppendRepeatedString(b, 7, []string{"a", ""})
+	b = AppendDoubleMap(b, 8, map[string]float64{"y": 2, "x": 0})
+	b = AppendStringMap(b, 9, map[string]string{"m": "kg"})
+	b = AppendTime(b, 10, sampled)
+	b = AppendUint64(b, 99, 1) // unknown to the reader below, skipped
+
+	var (
+		double   float64
+		integer  int64
+		str      string
+		flag     bool
+		optional *float64
+		optFlag  *bool
+		repeated []string
+		doubles  = map[string]float64{}
+		strs     = map[string]string{}
+		at       time.Time
+	)
+	err := Range(b, func(f Field) error {
+		var err error
+		switch f.Num {
+		case 1:
+			double = f.Double()
+		case 2:
+			integer = int64(f.Varint)
+		case 3:
+			str = string(f.Bytes)
+		case 4:
+			flag = f.Varint != 0
+		case 5:
+			v := f.Double()
+			optional = &v
+		case 6:
+			v := f.Varint != 0
+			optFlag = &v
+		case 7:
+			repeated = append(repeated, string(f.Bytes))
+		case 8:
+			var k string
+			var v Field
+			k, v, err = f.MapEntry()
+			doubles[k] = v.Double()
+		case 9:
+			var k string
+			var v Field
+			k, v, err = f.MapEntry()
+			strs[k] = string(v.Bytes)
+		case 10:
+			at, err = f.Time()
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if double != -1.5 || integer != -7 || str != "corridor" || !flag {
+		t.Errorf("scalars: %v %v %q %v", double, integer, str, flag)
+	}
+	if optional == nil || *optional != 0 || optFlag == nil || *optFlag {
+		t.Errorf("set optional fields holding zero values were dropped")
+	}
+	if !reflect.DeepEqual(repeated, []string{"a", ""}) {
+		t.Errorf("repeated = %q", repeated)
+	}
+	if !reflect.DeepEqual(doubles, map[string]float64{"x": 0, "y": 2}) || !reflect.DeepEqual(strs, map[string]string{"m": "kg"}) {
+		t.Errorf("maps = %v %v", doubles, strs)
+	}
+	if !at.Equal(sampled) {
+		t.Errorf("time = %v, want %v", at, sampled)
+	}
+}
+
+func TestZeroValuesOmitted(t *testing.T) {
+	var b []byte
+	b = AppendDouble(b, 1, 0)
+	b = AppendInt64(b, 2, 0)
+	b = AppendString(b, 3, "")
+	b = AppendBool(b, 4, false)
+	b = AppendOptionalDouble(b, 5, nil)
+	b = AppendTime(b, 6, time.Time{})
+	if len(b) != 0 {
+		t.Errorf("zero values encoded as % x", b)
+	}
+	if b = AppendDouble(nil, 1, math.Copysign(0, -1)); len(b) == 0 {
+		t.Errorf("negative zero omitted")
+	}
+}
+
+func TestMapEncodingIsDeterministic(t *testing.T) {
+	m := map[string]float64{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5}
+	first := AppendDoubleMap(nil, 1, m)
+	for i := 0; i < 20; i++ {
+		if got := AppendDoubleMap(nil, 1, m); string(got) != string(first) {
+			t.Fatal("equal maps encoded differently")
+		}
+	}
+}
+
+func TestRangeRejectsTruncated(t *testing.T) {
+	b := AppendString(nil, 1, "truncated")
+	if err := Range(b[:len(b)-1], func(Field) error { return nil }); err != ErrTruncated {
+		t.Errorf("err = %v, want ErrTruncated", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Package reservations holds tentative allocations behind tokens for the
+// daemons' reserve/commit/abort protocol. A reservation is held until it is
+// committed or aborted, or until its TTL passes and it expires on its own.
+package reservations
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MaxTTL bounds how long a reservation may hold resources
+const MaxTTL = 5 * time.Minute
+
+var (
+	// ErrUnknown marks tokens that were never issued or are no longer held
+	ErrUnknown = errors.New("unknown reservation")
+	// ErrExpired marks a commit arriving after the reservation's TTL
+	ErrExpired = errors.New("reservation expired")
+)
+
+// TTL resolves a requested TTL in seconds against def, which applies when
+// seconds is 0
+func TTL(seconds int, def time.Duration) (time.Duration, error) {
+	if seconds < 0 {
+		return 0, fmt.Errorf("ttl_s must not be negative")
+	}
+	ttl := def
+	if seconds > 0 {
+		ttl = time.Duration(seconds) * time.Second
+	}
+	if ttl > MaxTTL {
+		return 0, fmt.Errorf("ttl_s must not exceed %d", int(MaxTTL/time.Second))
+	}
+	return ttl, nil
+}
+
+// entry is a held value and its expiry timer
+type entry[T any] struct {
+	value     T
+	expiresAt time.Time
+	timer     *time.Timer
+}
+
+// Table is a set of pending reservations of T. It shares its owner's store
+// lock: callers hold lock around every method, and expiry timers take it
+// before dropping a reservation.
+type Table[T any] struct {
+	lock     sync.Locker
+	release  func(T)
+	now      func() time.Time
+	reserved map[string]*entry[T]
+}
+
+// New creates a table guarded by lock. release, if non-nil, is called with
+// the lock held for every reservation that expires or is aborted, to hand
+// back whatever it was holding.
+func New[T any](lock sync.Locker, release func(T)) *Table[T] {
+	if release == nil {
+		release = func(T) {}
+	}
+	return &Table[T]{lock: lock, release: release, now: time.Now, reserved: make(map[string]*entry[T])}
+}
+
+// Hold reserves v for ttl and returns its token and expiry time
+func (t *Table[T]) Hold(v T, ttl time.Duration) (string, time.Time) {
+	token := generateToken()
+	e := &entry[T]{value: v, expiresAt: t.now().UTC().Add(ttl)}
+	e.timer = time.AfterFunc(ttl, func() { t.expire(token) })
+	t.reserved[token] = e
+	return token, e.expiresAt
+}
+
+// Commit removes a reservation and returns its value for the caller to
+// install. A reservation past its TTL is released instead and ErrExpired
+// returned.
+func (t *Table[T]) Commit(token string) (T, error) {
+	e, err := t.take(token)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if t.now().After(e.expiresAt) {
+		t.release(e.value)
+		var zero T
+		return zero, fmt.Errorf("%w: %s expired at %s", ErrExpired, token, e.expiresAt.Format(time.RFC3339Nano))
+	}
+	return e.value, nil
+}
+
+// Abort releases a reservation without committing it
+func (t *Table[T]) Abort(token string) error {
+	e, err := t.take(token)
+	if err != nil {
+		return err
+	}
+	t.release(e.value)
+	return nil
+}
+
+// Each calls fn with every pending reservation
+func (t *Table[T]) Each(fn func(T)) {
+	for _, e := range t.reserved {
+		fn(e.value)
+	}
+}
+
+// Len returns the number of pending reservations
+func (t *Table[T]) Len() int { return len(t.reserved) }
+
+// take removes a pending reservation and stops its expiry timer
+func (t *Table[T]) take(token string) (*entry[T], error) {
+	e, exists := t.reserved[token]
+	if !exists {
+		return nil, fmt.Errorf("%w %s (expired, committed or aborted)", ErrUnknown, token)
+	}
+	e.timer.Stop()
+	delete(t.reserved, token)
+	return e, nil
+}
+
+// expire releases a reservation whose TTL passed without a commit
+func (t *Table[T]) expire(token string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if e, exists := t.reserved[token]; exists {
+		delete(t.reserved, token)
+		t.release(e.value)
+	}
+}
+
+// generateToken generates a reservation token
+func generateToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("res-%016x", time.Now().UnixNano())
+	}
+	return "res-" + hex.EncodeToString(b)
+}
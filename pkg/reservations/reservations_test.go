@@ -0,0 +1,124 @@
+package reservations
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// table returns a table whose releases are recorded in released
+func table(mu *sync.Mutex, released *[]string) *Table[string] {
+	return New(mu, func(v string) { *released = append(*released, v) })
+}
+
+func TestCommitWithinTTL(t *testing.T) {
+	var mu sync.Mutex
+	var released []string
+	tbl := table(&mu, &released)
+
+	mu.Lock()
+	defer mu.Unlock()
+	token, expiresAt := tbl.Hold("corridor", time.Minute)
+	if time.Until(expiresAt) < 59*time.Second || tbl.Len() != 1 {
+		t.Fatalf("hold: expires %v, %d pending", expiresAt, tbl.Len())
+	}
+	v, err := tbl.Commit(token)
+	if err != nil || v != "corridor" {
+		t.Fatalf("Commit = %q, %v", v, err)
+	}
+	if len(released) != 0 || tbl.Len() != 0 {
+		t.Errorf("committed reservation released %v, %d pending", released, tbl.Len())
+	}
+	if _, err := tbl.Commit(token); !errors.Is(err, ErrUnknown) {
+		t.Errorf("second commit = %v, want ErrUnknown", err)
+	}
+}
+
+func TestCommitAfterTTLFails(t *testing.T) {
+	var mu sync.Mutex
+	var released []string
+	tbl := table(&mu, &released)
+
+	mu.Lock()
+	defer mu.Unlock()
+	token, _ := tbl.Hold("late", time.Minute)
+	// The clock passes the TTL before the expiry timer has run
+	tbl.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := tbl.Commit(token); !errors.Is(err, ErrExpired) {
+		t.Fatalf("late commit = %v, want ErrExpired", err)
+	}
+	if len(released) != 1 || released[0] != "late" {
+		t.Errorf("released = %v, want the expired reservation", released)
+	}
+}
+
+func TestUncommittedReservationExpires(t *testing.T) {
+	var mu sync.Mutex
+	var released []string
+	tbl := table(&mu, &released)
+
+	mu.Lock()
+	token, _ := tbl.Hold("idle", 20*time.Millisecond)
+	mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := tbl.Len()
+		mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("reservation never expired")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(released) != 1 || released[0] != "idle" {
+		t.Errorf("released = %v", released)
+	}
+	if _, err := tbl.Commit(token); !errors.Is(err, ErrUnknown) {
+		t.Errorf("commit after expiry = %v, want ErrUnknown", err)
+	}
+}
+
+func TestAbortReleases(t *testing.T) {
+	var mu sync.Mutex
+	var released []string
+	tbl := table(&mu, &released)
+
+	mu.Lock()
+	defer mu.Unlock()
+	token, _ := tbl.Hold("a", time.Minute)
+	tbl.Hold("b", time.Minute)
+	if err := tbl.Abort(token); err != nil {
+		t.Fatal(err)
+	}
+	var pending []string
+	tbl.Each(func(v string) { pending = append(pending, v) })
+	if len(released) != 1 || released[0] != "a" || len(pending) != 1 || pending[0] != "b" {
+		t.Errorf("released %v, pending %v", released, pending)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	for _, tc := range []struct {
+		seconds int
+		want    time.Duration
+		ok      bool
+	}{
+		{0, 30 * time.Second, true},
+		{10, 10 * time.Second, true},
+		{-1, 0, false},
+		{int(MaxTTL/time.Second) + 1, 0, false},
+	} {
+		got, err := TTL(tc.seconds, 30*time.Second)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("TTL(%d) = %v, %v", tc.seconds, got, err)
+		}
+	}
+}
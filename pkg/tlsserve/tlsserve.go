@@ -0,0 +1,123 @@
+// Package tlsserve runs a CorridorOS service's HTTP server: in plaintext by
+// default, for local demos, or over TLS with HTTP/2 given a certificate.
+//
+// A TLS server can also present a post-quantum endorsement of its
+// certificate, an ML-DSA-65 signature over the certificate made offline
+// with the operator's Dilithium key (see security/pqc/tlsendorse). It rides
+// on every response in EndorsementHeader; PQC-aware clients verify it on
+// top of the classical chain and other clients ignore it.
+package tlsserve
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Response headers carrying the certificate endorsement
+const (
+	EndorsementHeader      = "X-Corridoros-Cert-Endorsement" // base64 ML-DSA-65 signature over the leaf certificate
+	EndorsementKeyIDHeader = "X-Corridoros-Cert-Endorsement-Key-Id"
+)
+
+// Config chooses how a server listens
+type Config struct {
+	// CertFile and KeyFile are the PEM certificate chain and private key;
+	// both empty serves plaintext HTTP/1.1
+	CertFile string
+	KeyFile  string
+	// EndorsementFile is the JSON Endorsement of the leaf certificate;
+	// optional
+	EndorsementFile string
+}
+
+// Endorsement is the file security/pqc/tlsendorse writes
+type Endorsement struct {
+	Algorithm  string `json:"algorithm"`
+	KeyID      string `json:"key_id"`
+	CertSHA256 string `json:"cert_sha256"` // hex, of the leaf certificate's DER
+	Signature  string `json:"signature"`   // base64
+}
+
+// TLS reports whether cfg serves over TLS
+func (cfg Config) TLS() bool {
+	return cfg.CertFile != "" || cfg.KeyFile != ""
+}
+
+// ListenAndServe serves handler on addr as cfg says
+func ListenAndServe(addr string, handler http.Handler, cfg Config) error {
+	if !cfg.TLS() {
+		if cfg.EndorsementFile != "" {
+			return errors.New("a certificate endorsement needs a TLS certificate and key")
+		}
+		return http.ListenAndServe(addr, handler)
+	}
+	srv, err := NewServer(addr, handler, cfg)
+	if err != nil {
+		return err
+	}
+	return srv.ListenAndServeTLS("", "")
+}
+
+// NewServer returns a server for addr that speaks TLS 1.2 or later, with
+// HTTP/2 negotiated by ALPN, and attaches cfg's endorsement if it has one
+func NewServer(addr string, handler http.Handler, cfg Config) (*http.Server, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("TLS needs both a certificate and a key")
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %v", err)
+	}
+	if cfg.EndorsementFile != "" {
+		e, err := loadEndorsement(cfg.EndorsementFile, cert.Certificate[0])
+		if err != nil {
+			return nil, err
+		}
+		handler = endorse(handler, e)
+	}
+	return &http.Server{
+		Addr:    addr,
+		Handler: handler,
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+			NextProtos:   []string{"h2", "http/1.1"},
+		},
+	}, nil
+}
+
+// loadEndorsement reads an endorsement, refusing one made for another
+// certificate, as happens when the certificate is renewed without it
+func loadEndorsement(path string, leaf []byte) (Endorsement, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Endorsement{}, fmt.Errorf("read certificate endorsement: %v", err)
+	}
+	var e Endorsement
+	if err := json.Unmarshal(b, &e); err != nil {
+		return Endorsement{}, fmt.Errorf("parse certificate endorsement: %v", err)
+	}
+	if e.Signature == "" {
+		return Endorsement{}, errors.New("certificate endorsement has no signature")
+	}
+	sum := sha256.Sum256(leaf)
+	if served := hex.EncodeToString(sum[:]); e.CertSHA256 != served {
+		return Endorsement{}, fmt.Errorf("certificate endorsement is for certificate %s, not the served %s", e.CertSHA256, served)
+	}
+	return e, nil
+}
+
+// endorse attaches e to every response
+func endorse(next http.Handler, e Endorsement) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(EndorsementHeader, e.Signature)
+		w.Header().Set(EndorsementKeyIDHeader, e.KeyID)
+		next.ServeHTTP(w, r)
+	})
+}
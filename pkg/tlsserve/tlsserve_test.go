@@ -0,0 +1,130 @@
+package tlsserve
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// certFiles writes a self-signed loopback certificate and its key into a
+// temporary directory and returns their paths and the certificate's DER
+func certFiles(t *testing.T) (certFile, keyFile string, der []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err = x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}, &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "127.0.0.1"}}, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return certFile, keyFile, der
+}
+
+// endorsementFile writes an endorsement naming the certificate with the
+// given DER; tlsserve only matches it, so the signature need not be real
+func endorsementFile(t *testing.T, der []byte) string {
+	t.Helper()
+	sum := sha256.Sum256(der)
+	b, _ := json.Marshal(Endorsement{Algorithm: "ML-DSA-65", KeyID: "key-1", CertSHA256: hex.EncodeToString(sum[:]), Signature: "c2lnbmF0dXJl"})
+	path := filepath.Join(t.TempDir(), "endorsement.json")
+	writeFile(t, path, b)
+	return path
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServesHTTP2WithTheEndorsement(t *testing.T) {
+	certFile, keyFile, der := certFiles(t)
+	srv, err := NewServer("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}), Config{CertFile: certFile, KeyFile: keyFile, EndorsementFile: endorsementFile(t, der)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewUnstartedServer(srv.Handler)
+	ts.TLS = srv.TLSConfig
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	cert, _ := x509.ParseCertificate(der)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("protocol = %s, want HTTP/2", resp.Proto)
+	}
+	if resp.Header.Get(EndorsementHeader) != "c2lnbmF0dXJl" || resp.Header.Get(EndorsementKeyIDHeader) != "key-1" {
+		t.Errorf("endorsement headers = %v", resp.Header)
+	}
+}
+
+func TestRefusesAnotherCertificatesEndorsement(t *testing.T) {
+	certFile, keyFile, _ := certFiles(t)
+	_, _, otherDER := certFiles(t)
+	_, err := NewServer("", http.NotFoundHandler(), Config{CertFile: certFile, KeyFile: keyFile, EndorsementFile: endorsementFile(t, otherDER)})
+	if err == nil || !strings.Contains(err.Error(), "not the served") {
+		t.Fatalf("err = %v, want a stale endorsement refused", err)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	certFile, _, der := certFiles(t)
+	if (Config{}).TLS() {
+		t.Error("empty config serves TLS")
+	}
+	if _, err := NewServer("", http.NotFoundHandler(), Config{CertFile: certFile}); err == nil {
+		t.Error("a certificate without a key was accepted")
+	}
+	err := ListenAndServe("127.0.0.1:0", http.NotFoundHandler(), Config{EndorsementFile: endorsementFile(t, der)})
+	if err == nil || !strings.Contains(err.Error(), "needs a TLS certificate") {
+		t.Errorf("endorsement without TLS: err = %v", err)
+	}
+}
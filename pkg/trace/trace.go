@@ -0,0 +1,120 @@
+// Package trace carries a trace ID through a request and on to downstream
+// calls, so one logical operation can be followed across the CorridorOS
+// services' logs.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Header carries the trace ID on requests and responses
+const Header = "X-Corridoros-Trace-Id"
+
+// maxIDLength bounds caller-supplied trace IDs
+const maxIDLength = 128
+
+type contextKey struct{}
+
+// NewID generates a random 128-bit trace ID
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// NewContext returns a copy of ctx carrying id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the trace ID in ctx, or "" if there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// valid accepts IDs of letters, digits, '-', '_' and '.', keeping
+// caller-controlled text out of log lines
+func valid(id string) bool {
+	if id == "" || len(id) > maxIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// statusRecorder captures the status a handler writes
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController, so
+// streaming handlers can still flush
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Middleware takes the trace ID from the request header, generating one if
+// it is missing or malformed, puts it in the request context, echoes it in
+// the response header and logs the request under it
+func Middleware(service string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(Header)
+			if !valid(id) {
+				id = NewID()
+			}
+			w.Header().Set(Header, id)
+
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r.WithContext(NewContext(r.Context(), id)))
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			log.Printf("%s trace=%s %s %s %d %s", service, id, r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Microsecond))
+		})
+	}
+}
+
+// Inject copies the trace ID in req's context onto its header, for calls
+// made on behalf of a traced request
+func Inject(req *http.Request) {
+	if id := FromContext(req.Context()); id != "" {
+		req.Header.Set(Header, id)
+	}
+}
+
+// Logf logs a message tagged with the trace ID in ctx
+func Logf(ctx context.Context, format string, args ...any) {
+	log.Printf("trace=%s "+format, append([]any{FromContext(ctx)}, args...)...)
+}
@@ -0,0 +1,80 @@
+package trace
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureLog redirects the standard logger for the rest of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestMiddlewareLogsAndForwardsClientID(t *testing.T) {
+	logs := captureLog(t)
+
+	// The downstream service records the ID it was called with
+	var downstream string
+	backend := httptest.NewServer(Middleware("backend")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = FromContext(r.Context())
+	})))
+	defer backend.Close()
+
+	front := Middleware("front")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Logf(r.Context(), "calling backend")
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, backend.URL, nil)
+		Inject(req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/op", nil)
+	req.Header.Set(Header, "client-abc.1")
+	front.ServeHTTP(rec, req)
+
+	if rec.Header().Get(Header) != "client-abc.1" {
+		t.Errorf("response trace header = %q", rec.Header().Get(Header))
+	}
+	if downstream != "client-abc.1" {
+		t.Errorf("downstream trace ID = %q", downstream)
+	}
+	for _, want := range []string{
+		"trace=client-abc.1 calling backend",
+		"front trace=client-abc.1 POST /v1/op 202",
+		"backend trace=client-abc.1 GET / 200",
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log lacks %q:\n%s", want, logs)
+		}
+	}
+}
+
+func TestMiddlewareReplacesMalformedID(t *testing.T) {
+	captureLog(t)
+	var got string
+	h := Middleware("svc")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+	for _, id := range []string{"", "bad id\nforged=1", strings.Repeat("a", maxIDLength+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(Header, id)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if got == id || !valid(got) || len(got) != 32 {
+			t.Errorf("header %q: context trace ID = %q", id, got)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Package apierror decodes the structured error bodies CorridorOS services
+// return, so callers can branch on the error code.
+package apierror
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+)
+
+// Error codes returned by the services
+const (
+    CodeValidation     = "VALIDATION_FAILED"
+    CodeBadRequest     = "BAD_REQUEST"
+    CodeNotFound       = "NOT_FOUND"
+    CodeConflict       = "CONFLICT"
+    CodeOversubscribed = "OVERSUBSCRIBED"
+    CodeQuotaExceeded  = "QUOTA_EXCEEDED"
+    CodeTooLarge       = "PAYLOAD_TOO_LARGE"
+    CodeInfeasible     = "INFEASIBLE"
+    CodeForbidden      = "FORBIDDEN"
+    CodeUpstream       = "UPSTREAM_FAILURE"
+    CodeInjectedFault  = "INJECTED_FAULT"
+    CodeInternal       = "INTERNAL"
+)
+
+// Error is a non-success response from a service
+type Error struct {
+    StatusCode int             `json:"-"`
+    Code       string          `json:"code"`
+    Message    string          `json:"message"`
+    Details    json.RawMessage `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+    if e.Code == "" {
+        return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+    }
+    return fmt.Sprintf("HTTP %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// FromResponse reads a failed response into an *Error. Bodies that are not
+// structured errors are kept verbatim as the message with an empty Code.
+func FromResponse(resp *http.Response) error {
+    body, _ := io.ReadAll(resp.Body)
+    e := &Error{}
+    if err := json.Unmarshal(body, e); err != nil || e.Code == "" {
+        e = &Error{Message: strings.TrimSpace(string(body))}
+    }
+    e.StatusCode = resp.StatusCode
+    return e
+}
+
+// CodeOf returns the error code carried by err, or "" if it has none
+func CodeOf(err error) string {
+    var e *Error
+    if errors.As(err, &e) {
+        return e.Code
+    }
+    return ""
+}
+
+// IsNotFound reports whether err is a NOT_FOUND response
+func IsNotFound(err error) bool { return CodeOf(err) == CodeNotFound }
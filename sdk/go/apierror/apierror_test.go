@@ -0,0 +1,38 @@
+package apierror
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func response(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestFromResponseParsesCode(t *testing.T) {
+	err := FromResponse(response(http.StatusNotFound, `{"code":"NOT_FOUND","message":"corridor cor-1 not found"}`))
+	if !IsNotFound(err) {
+		t.Fatalf("IsNotFound(%v) = false", err)
+	}
+	wrapped := fmt.Errorf("get corridor: %w", err)
+	if CodeOf(wrapped) != CodeNotFound {
+		t.Errorf("CodeOf(wrapped) = %q", CodeOf(wrapped))
+	}
+	if got := err.Error(); got != "HTTP 404 NOT_FOUND: corridor cor-1 not found" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestFromResponseKeepsPlainBodies(t *testing.T) {
+	err := FromResponse(response(http.StatusBadGateway, "upstream reset\n"))
+	e, ok := err.(*Error)
+	if !ok || e.Code != "" || e.Message != "upstream reset" || e.StatusCode != http.StatusBadGateway {
+		t.Fatalf("FromResponse(plain) = %#v", err)
+	}
+	if CodeOf(err) != "" || IsNotFound(err) {
+		t.Errorf("plain body reported code %q", CodeOf(err))
+	}
+}
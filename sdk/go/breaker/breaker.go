@@ -0,0 +1,161 @@
+// Package breaker adds circuit breaking to the SDK clients, so a service
+// that is down or flapping is given a rest instead of a call per request.
+//
+// A Breaker starts closed. After FailureThreshold consecutive failures
+// (transport errors or 5xx responses) it opens and fails every call fast
+// with ErrCircuitOpen. Once Cooldown has passed it goes half-open and lets
+// a single probe through: success closes it, failure reopens it.
+package breaker
+
+import (
+    "errors"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// ErrCircuitOpen is returned, wrapped in the client's *url.Error, for calls
+// refused while the breaker is open
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Defaults used when a Breaker field is zero
+const (
+    DefaultFailureThreshold = 5
+    DefaultCooldown         = 30 * time.Second
+)
+
+type State int
+
+const (
+    Closed State = iota
+    Open
+    HalfOpen
+)
+
+func (s State) String() string {
+    switch s {
+    case Closed:
+        return "closed"
+    case Open:
+        return "open"
+    case HalfOpen:
+        return "half-open"
+    }
+    return "unknown"
+}
+
+// Stats is a snapshot of a breaker for observability
+type Stats struct {
+    State               string    `json:"state"`
+    ConsecutiveFailures int       `json:"consecutive_failures"`
+    OpenedAt            time.Time `json:"opened_at"` // zero while closed
+    RejectedCalls       uint64    `json:"rejected_calls"`
+}
+
+// Breaker is an http.RoundTripper guarding Base with a circuit breaker
+type Breaker struct {
+    FailureThreshold int
+    Cooldown         time.Duration
+    Base             http.RoundTripper // nil means http.DefaultTransport
+
+    mu       sync.Mutex
+    state    State
+    failures int
+    openedAt time.Time
+    probing  bool
+    rejected uint64
+}
+
+// New creates a breaker that opens after threshold consecutive failures and
+// probes again after cooldown
+func New(threshold int, cooldown time.Duration) *Breaker {
+    return &Breaker{FailureThreshold: threshold, Cooldown: cooldown}
+}
+
+// Enable routes every call of client through b and returns b
+func Enable(client *http.Client, b *Breaker) *Breaker {
+    if b.Base == nil { b.Base = client.Transport }
+    client.Transport = b
+    return b
+}
+
+func (b *Breaker) threshold() int {
+    if b.FailureThreshold > 0 { return b.FailureThreshold }
+    return DefaultFailureThreshold
+}
+
+func (b *Breaker) cooldown() time.Duration {
+    if b.Cooldown > 0 { return b.Cooldown }
+    return DefaultCooldown
+}
+
+// State returns the breaker's current state, moving an open breaker whose
+// cooldown has passed to half-open
+func (b *Breaker) State() State {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.advanceLocked()
+    return b.state
+}
+
+// Stats returns a snapshot of the breaker
+func (b *Breaker) Stats() Stats {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.advanceLocked()
+    return Stats{State: b.state.String(), ConsecutiveFailures: b.failures, OpenedAt: b.openedAt, RejectedCalls: b.rejected}
+}
+
+func (b *Breaker) advanceLocked() {
+    if b.state == Open && time.Now().Sub(b.openedAt) >= b.cooldown() {
+        b.state = HalfOpen
+        b.probing = false
+    }
+}
+
+// allow reports whether a call may proceed, claiming the probe slot when
+// half-open
+func (b *Breaker) allow() bool {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.advanceLocked()
+    switch b.state {
+    case Open:
+        b.rejected++
+        return false
+    case HalfOpen:
+        if b.probing {
+            b.rejected++
+            return false
+        }
+        b.probing = true
+    }
+    return true
+}
+
+func (b *Breaker) record(success bool) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if success {
+        b.state, b.failures, b.probing = Closed, 0, false
+        b.openedAt = time.Time{}
+        return
+    }
+    b.failures++
+    if b.state == HalfOpen || b.failures >= b.threshold() {
+        b.state, b.probing = Open, false
+        b.openedAt = time.Now()
+    }
+}
+
+func (b *Breaker) RoundTrip(req *http.Request) (*http.Response, error) {
+    if !b.allow() {
+        if req.Body != nil { req.Body.Close() }
+        return nil, ErrCircuitOpen
+    }
+    base := b.Base
+    if base == nil { base = http.DefaultTransport }
+    resp, err := base.RoundTrip(req)
+    b.record(err == nil && resp.StatusCode < 500)
+    return resp, err
+}
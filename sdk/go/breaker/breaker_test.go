@@ -0,0 +1,92 @@
+package breaker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensFailsFastAndRecovers(t *testing.T) {
+	var healthy atomic.Bool
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	client := srv.Client()
+	b := Enable(client, New(3, 50*time.Millisecond))
+	get := func() error {
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := get(); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if b.State() != Open {
+		t.Fatalf("state after 3 failures = %s, want open", b.State())
+	}
+
+	// Open: calls fail fast without reaching the server
+	if err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("call while open: %v, want ErrCircuitOpen", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("server saw %d calls, want 3", calls.Load())
+	}
+	if stats := b.Stats(); stats.State != "open" || stats.RejectedCalls != 1 || stats.OpenedAt.IsZero() {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// Half-open: a failed probe reopens the breaker
+	time.Sleep(60 * time.Millisecond)
+	if b.State() != HalfOpen {
+		t.Fatalf("state after cooldown = %s, want half-open", b.State())
+	}
+	get()
+	if b.State() != Open {
+		t.Fatalf("state after failed probe = %s, want open", b.State())
+	}
+
+	// Half-open: a successful probe closes it
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if err := get(); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if stats := b.Stats(); stats.State != "closed" || stats.ConsecutiveFailures != 0 {
+		t.Errorf("stats after recovery = %+v", stats)
+	}
+}
+
+func TestBreakerCountsTransportErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	client := &http.Client{}
+	b := Enable(client, New(2, time.Minute))
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(url); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d to a closed server: %v", i, err)
+		}
+	}
+	if _, err := client.Get(url); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("third call: %v, want ErrCircuitOpen", err)
+	}
+	if b.State() != Open {
+		t.Errorf("state = %s, want open", b.State())
+	}
+}
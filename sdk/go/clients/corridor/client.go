@@ -2,10 +2,18 @@ package corridor
 
 import (
     "bytes"
+    "context"
     "encoding/json"
-    "fmt"
-    "io"
+    "errors"
     "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/corridoros/pkg/protowire"
+    "github.com/corridoros/sdk-go/apierror"
+    "github.com/corridoros/sdk-go/failover"
+    "github.com/corridoros/sdk-go/trace"
 )
 
 type QoSConfig struct {
@@ -24,6 +32,11 @@ type AllocateRequest struct {
     QoS                QoSConfig `json:"qos"`
     AttestationRequired bool     `json:"attestation_required"`
     AttestationTicket   *string  `json:"attestation_ticket,omitempty"`
+    Modulation          string   `json:"modulation,omitempty"` // NRZ or PAM4
+    BaudGBd             float64  `json:"baud_gbd,omitempty"`
+    Domain              string   `json:"domain,omitempty"` // fiber the lanes share
+    LinkModel           string   `json:"link_model,omitempty"` // heuristic or qfactor
+    Labels              map[string]string `json:"labels,omitempty"`
 }
 
 type Corridor struct {
@@ -31,14 +44,32 @@ type Corridor struct {
     CorridorType    string    `json:"corridor_type"`
     Lanes           int       `json:"lanes"`
     LambdaNm        []int     `json:"lambda_nm"`
+    Domain          string    `json:"domain"`
+    Modulation      string    `json:"modulation"`
+    BaudGBd         float64   `json:"baud_gbd"`
+    LinkModel       string    `json:"link_model"`
     AchievableGbps  int       `json:"achievable_gbps"`
     Status          string    `json:"status"`
+    StatusChangedAt time.Time `json:"status_changed_at"`
+    Labels          map[string]string `json:"labels,omitempty"`
 }
 
 type Telemetry struct {
-    BER           float64 `json:"ber"`
-    TempC         float64 `json:"temp_c"`
-    PowerPjPerBit float64 `json:"power_pj_per_bit"`
+    BER                float64 `json:"ber"`
+    TempC              float64 `json:"temp_c"`
+    PowerPjPerBit      float64 `json:"power_pj_per_bit"`
+    // Derived server-side from the corridor config and live state
+    GbpsPerLane        float64 `json:"gbps_per_lane"`
+    SpectralEfficiency float64 `json:"spectral_efficiency_bps_per_hz"`
+    EfficiencyScore    float64 `json:"efficiency_score"` // 0-100
+    // Seq numbers corrd's background samples; equal seqs are the same sample
+    Seq                uint64    `json:"seq"`
+    SampledAt          time.Time `json:"sampled_at"`
+}
+
+type TelemetrySample struct {
+    Timestamp time.Time `json:"timestamp"`
+    Telemetry
 }
 
 type RecalRequest struct {
@@ -52,26 +83,146 @@ type RecalResponse struct {
     BiasVoltages      []float64 `json:"bias_voltages_mv"`
 }
 
-type Client struct { BaseURL string; HTTP *http.Client }
+type Client struct { BaseURL string; HTTP *http.Client; protobuf bool }
+
+// New creates a client that tags each request with a fresh trace ID
+func New(base string) *Client { return &Client{BaseURL: base, HTTP: &http.Client{Transport: &trace.Transport{}}} }
 
-func New(base string) *Client { return &Client{BaseURL: base, HTTP: &http.Client{}} }
+// NewWithTransport is New sending requests over t, e.g. a transport from
+// tlsclient.NewTransport for a service whose certificate a private CA issued
+func NewWithTransport(base string, t http.RoundTripper) *Client { return &Client{BaseURL: base, HTTP: &http.Client{Transport: &trace.Transport{Base: t}}} }
+
+// NewMulti creates a client for several corrd endpoints, in order of
+// preference, that fails over between them; see package failover
+func NewMulti(urls []string, opts ...failover.Option) (*Client, error) {
+    t, err := failover.New(urls, opts...)
+    if err != nil { return nil, err }
+    return &Client{BaseURL: strings.TrimSuffix(urls[0], "/"), HTTP: &http.Client{Transport: &trace.Transport{Base: t}}}, nil
+}
+
+// WithTraceID returns a copy of c whose requests all carry id, so a
+// multi-call operation shows up under one trace in the service logs
+func (c *Client) WithTraceID(id string) *Client {
+    cp := *c
+    cp.HTTP = trace.WithID(c.HTTP, id)
+    return &cp
+}
+
+// WithProtobuf returns a copy of c that asks for telemetry in the binary
+// protobuf encoding, which is cheaper to decode when polling at high rates
+func (c *Client) WithProtobuf() *Client {
+    cp := *c
+    cp.protobuf = true
+    return &cp
+}
 
 func (c *Client) Allocate(req AllocateRequest) (*Corridor, error) {
     b, _ := json.Marshal(req)
     resp, err := c.HTTP.Post(c.BaseURL+"/v1/corridors", "application/json", bytes.NewBuffer(b))
     if err != nil { return nil, err }
     defer resp.Body.Close()
-    if resp.StatusCode != http.StatusCreated { body,_ := io.ReadAll(resp.Body); return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body)) }
+    if resp.StatusCode != http.StatusCreated { return nil, apierror.FromResponse(resp) }
+    var cor Corridor
+    return &cor, json.NewDecoder(resp.Body).Decode(&cor)
+}
+
+// ConstraintCheck is one constraint corrd checked an allocation against
+type ConstraintCheck struct {
+    Constraint string  `json:"constraint"`
+    Requested  float64 `json:"requested"`
+    Limit      float64 `json:"limit"`
+    Bound      string  `json:"bound"` // min | max
+    Unit       string  `json:"unit"`
+    Passed     bool    `json:"passed"`
+    Detail     string  `json:"detail,omitempty"`
+}
+
+// Feasibility is corrd's verdict on an allocation it was asked to check
+type Feasibility struct {
+    Feasible       bool              `json:"feasible"`
+    AchievableGbps int               `json:"achievable_gbps"`
+    Checks         []ConstraintCheck `json:"checks"`
+}
+
+// CheckAllocation dry-runs req: corrd runs every constraint check and
+// reports them without allocating anything
+func (c *Client) CheckAllocation(req AllocateRequest) (*Feasibility, error) {
+    b, _ := json.Marshal(req)
+    resp, err := c.HTTP.Post(c.BaseURL+"/v1/corridors?dry_run=true", "application/json", bytes.NewBuffer(b))
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
+    var out Feasibility
+    return &out, json.NewDecoder(resp.Body).Decode(&out)
+}
+
+// RejectedChecks returns the constraint checks an allocation rejected with
+// err listed, or nil if err carries none
+func RejectedChecks(err error) []ConstraintCheck {
+    var e *apierror.Error
+    if !errors.As(err, &e) || len(e.Details) == 0 { return nil }
+    var checks []ConstraintCheck
+    if json.Unmarshal(e.Details, &checks) != nil { return nil }
+    return checks
+}
+
+// Preset is a named allocation template registered with corrd
+type Preset struct {
+    Name      string          `json:"name"`
+    Template  AllocateRequest `json:"template"`
+    CreatedAt time.Time       `json:"created_at"`
+}
+
+// RegisterPreset stores tmpl under name; corrd refuses templates it could
+// never allocate and names already in use
+func (c *Client) RegisterPreset(name string, tmpl AllocateRequest) (*Preset, error) {
+    b, _ := json.Marshal(Preset{Name: name, Template: tmpl})
+    resp, err := c.HTTP.Post(c.BaseURL+"/v1/corridors/presets", "application/json", bytes.NewBuffer(b))
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusCreated { return nil, apierror.FromResponse(resp) }
+    var out Preset
+    return &out, json.NewDecoder(resp.Body).Decode(&out)
+}
+
+func (c *Client) Presets() ([]Preset, error) {
+    resp, err := c.HTTP.Get(c.BaseURL+"/v1/corridors/presets")
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
+    var out []Preset
+    return out, json.NewDecoder(resp.Body).Decode(&out)
+}
+
+// AllocateFromPreset allocates the named preset's template with overrides
+// applied, keyed by JSON field name (e.g. "lambda_nm"); objects such as
+// "qos" and "labels" are merged into the template's. nil overrides
+// allocate the template as is.
+func (c *Client) AllocateFromPreset(name string, overrides map[string]any) (*Corridor, error) {
+    if overrides == nil { overrides = map[string]any{} }
+    b, _ := json.Marshal(overrides)
+    resp, err := c.HTTP.Post(c.BaseURL+"/v1/corridors?"+url.Values{"preset": {name}}.Encode(), "application/json", bytes.NewBuffer(b))
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusCreated { return nil, apierror.FromResponse(resp) }
     var cor Corridor
     return &cor, json.NewDecoder(resp.Body).Decode(&cor)
 }
 
 func (c *Client) Telemetry(id string) (*Telemetry, error) {
-    resp, err := c.HTTP.Get(c.BaseURL+"/v1/corridors/"+id+"/telemetry")
+    return c.telemetry(context.Background(), id)
+}
+
+func (c *Client) telemetry(ctx context.Context, id string) (*Telemetry, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/v1/corridors/"+id+"/telemetry", nil)
+    if err != nil { return nil, err }
+    if c.protobuf { req.Header.Set("Accept", protowire.ContentType) }
+    resp, err := c.HTTP.Do(req)
     if err != nil { return nil, err }
     defer resp.Body.Close()
-    if resp.StatusCode != http.StatusOK { body,_ := io.ReadAll(resp.Body); return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body)) }
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
     var t Telemetry
+    if protowire.IsProto(resp) { return &t, protowire.Decode(resp, &t) }
     return &t, json.NewDecoder(resp.Body).Decode(&t)
 }
 
@@ -80,8 +231,180 @@ func (c *Client) Recalibrate(id string, r RecalRequest) (*RecalResponse, error)
     resp, err := c.HTTP.Post(c.BaseURL+"/v1/corridors/"+id+"/recalibrate", "application/json", bytes.NewBuffer(b))
     if err != nil { return nil, err }
     defer resp.Body.Close()
-    if resp.StatusCode != http.StatusOK { body,_ := io.ReadAll(resp.Body); return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body)) }
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
     var out RecalResponse
     return &out, json.NewDecoder(resp.Body).Decode(&out)
 }
 
+
+func (c *Client) Get(id string) (*Corridor, error) {
+    resp, err := c.HTTP.Get(c.BaseURL+"/v1/corridors/"+id)
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
+    var cor Corridor
+    return &cor, json.NewDecoder(resp.Body).Decode(&cor)
+}
+
+// ListOption narrows a List call
+type ListOption func(url.Values)
+
+// WithLabel keeps only corridors labelled key=value; repeat to require several
+func WithLabel(key, value string) ListOption {
+    return func(q url.Values) { q.Set("label."+key, value) }
+}
+
+func (c *Client) List(opts ...ListOption) ([]Corridor, error) {
+    q := url.Values{}
+    for _, opt := range opts { opt(q) }
+    u := c.BaseURL+"/v1/corridors"
+    if len(q) > 0 { u += "?"+q.Encode() }
+    resp, err := c.HTTP.Get(u)
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
+    var out []Corridor
+    return out, json.NewDecoder(resp.Body).Decode(&out)
+}
+
+type BandCapacity struct {
+    Band      string `json:"band"`
+    MinNm     int    `json:"min_nm"`
+    MaxNm     int    `json:"max_nm"` // inclusive
+    Total     int    `json:"total"`
+    InUse     int    `json:"in_use"`
+    Available int    `json:"available"`
+}
+
+type DomainCapacity struct {
+    Domain    string         `json:"domain"`
+    Bands     []BandCapacity `json:"bands"`
+    Available int            `json:"available_wavelengths"`
+}
+
+type Capacity struct {
+    Domains             []DomainCapacity `json:"domains"` // unlisted domains are entirely free
+    CommittedGbps       int              `json:"committed_gbps"`
+    BandwidthBudgetGbps float64          `json:"bandwidth_budget_gbps,omitempty"`
+    RemainingGbps       *float64         `json:"remaining_gbps,omitempty"` // nil without a budget
+}
+
+// Capacity reports the free wavelengths per band of each fiber domain and
+// the bandwidth left under corrd's budget, for placing corridors before
+// allocating them
+func (c *Client) Capacity() (*Capacity, error) {
+    resp, err := c.HTTP.Get(c.BaseURL+"/v1/corridors/capacity")
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
+    var out Capacity
+    return &out, json.NewDecoder(resp.Body).Decode(&out)
+}
+
+type LinkBudgetRequest struct {
+    TxPowerDbm        *float64  `json:"tx_power_dbm"`
+    LossDbPerMm       float64   `json:"loss_db_per_mm"`
+    ConnectorLossesDb []float64 `json:"connector_losses_db,omitempty"`
+    ReachMm           int       `json:"reach_mm"`
+    RxSensitivityDbm  *float64  `json:"rx_sensitivity_dbm"`
+    SensitivityBER    float64   `json:"sensitivity_ber,omitempty"`
+    TargetBER         float64   `json:"target_ber,omitempty"`
+    MinMarginDb       float64   `json:"min_margin_db,omitempty"`
+}
+
+type LinkBudget struct {
+    TxPowerDbm           float64 `json:"tx_power_dbm"`
+    PathLossDb           float64 `json:"path_loss_db"`
+    ConnectorLossDb      float64 `json:"connector_loss_db"`
+    TotalLossDb          float64 `json:"total_loss_db"`
+    RxPowerDbm           float64 `json:"rx_power_dbm"`
+    RxPowerMw            float64 `json:"rx_power_mw"`
+    TargetBER            float64 `json:"target_ber"`
+    RequiredRxPowerDbm   float64 `json:"required_rx_power_dbm"`
+    SensitivityPenaltyDb float64 `json:"sensitivity_penalty_db"`
+    MarginDb             float64 `json:"margin_db"`
+    MinMarginDb          float64 `json:"min_margin_db"`
+    Sufficient           bool    `json:"sufficient"`
+    MaxReachMm           *int    `json:"max_reach_mm,omitempty"`
+}
+
+// LinkBudget computes the received power and margin of an optical link,
+// for sizing reach_mm before allocating a corridor over it
+func (c *Client) LinkBudget(req LinkBudgetRequest) (*LinkBudget, error) {
+    b, _ := json.Marshal(req)
+    resp, err := c.HTTP.Post(c.BaseURL+"/v1/corridors/link-budget", "application/json", bytes.NewBuffer(b))
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
+    var out LinkBudget
+    return &out, json.NewDecoder(resp.Body).Decode(&out)
+}
+
+func (c *Client) Release(id string) error {
+    req, err := http.NewRequest(http.MethodDelete, c.BaseURL+"/v1/corridors/"+id, nil)
+    if err != nil { return err }
+    resp, err := c.HTTP.Do(req)
+    if err != nil { return err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusNoContent { return apierror.FromResponse(resp) }
+    return nil
+}
+
+// TelemetryHistory returns recorded telemetry samples within [from, to]; a zero time leaves that bound open.
+func (c *Client) TelemetryHistory(id string, from, to time.Time) ([]TelemetrySample, error) {
+    q := url.Values{}
+    if !from.IsZero() { q.Set("from", from.UTC().Format(time.RFC3339Nano)) }
+    if !to.IsZero() { q.Set("to", to.UTC().Format(time.RFC3339Nano)) }
+    u := c.BaseURL+"/v1/corridors/"+id+"/telemetry/history"
+    if len(q) > 0 { u += "?"+q.Encode() }
+    resp, err := c.HTTP.Get(u)
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
+    var out []TelemetrySample
+    return out, json.NewDecoder(resp.Body).Decode(&out)
+}
+
+// Reservation tentatively holds a corridor's wavelengths until Commit, Abort
+// or ExpiresAt, letting an orchestrator allocate across services atomically
+type Reservation struct {
+    Token     string    `json:"token"`
+    ExpiresAt time.Time `json:"expires_at"`
+    Corridor  Corridor  `json:"corridor"`
+}
+
+// Reserve holds an allocation for ttl (0 for the service default)
+func (c *Client) Reserve(req AllocateRequest, ttl time.Duration) (*Reservation, error) {
+    body := struct {
+        AllocateRequest
+        TTLSeconds int `json:"ttl_s,omitempty"`
+    }{req, int(ttl / time.Second)}
+    b, _ := json.Marshal(body)
+    resp, err := c.HTTP.Post(c.BaseURL+"/v1/corridors/reserve", "application/json", bytes.NewBuffer(b))
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusCreated { return nil, apierror.FromResponse(resp) }
+    var out Reservation
+    return &out, json.NewDecoder(resp.Body).Decode(&out)
+}
+
+// Commit brings a reserved corridor up; it fails once the reservation expired
+func (c *Client) Commit(token string) (*Corridor, error) {
+    resp, err := c.HTTP.Post(c.BaseURL+"/v1/corridors/reservations/"+token+"/commit", "application/json", nil)
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusCreated { return nil, apierror.FromResponse(resp) }
+    var cor Corridor
+    return &cor, json.NewDecoder(resp.Body).Decode(&cor)
+}
+
+// Abort releases a reservation without committing it
+func (c *Client) Abort(token string) error {
+    req, err := http.NewRequest(http.MethodDelete, c.BaseURL+"/v1/corridors/reservations/"+token, nil)
+    if err != nil { return err }
+    resp, err := c.HTTP.Do(req)
+    if err != nil { return err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusNoContent { return apierror.FromResponse(resp) }
+    return nil
+}
@@ -0,0 +1,36 @@
+package corridor
+
+import (
+    "encoding/base32"
+    "encoding/binary"
+    "fmt"
+    "hash/crc32"
+    "strings"
+)
+
+// idEncoding and the type codes mirror corrd's ID scheme
+var idEncoding = base32.NewEncoding("0123456789abcdefghjkmnpqrstvwxyz").WithPadding(base32.NoPadding)
+
+var idTypeCodes = map[string]bool{"si": true, "cb": true}
+
+// ValidateID checks a corridor ID of the form cor-<type>-<random>-<checksum>
+// locally, so a mistyped ID fails before any request is made. It does not
+// tell whether the corridor exists.
+func ValidateID(id string) error {
+    parts := strings.Split(id, "-")
+    if len(parts) != 4 || parts[0] != "cor" {
+        return fmt.Errorf("malformed corridor id %q: want cor-<type>-<random>-<checksum>", id)
+    }
+    if !idTypeCodes[parts[1]] {
+        return fmt.Errorf("malformed corridor id %q: unknown type code %q", id, parts[1])
+    }
+    if raw, err := idEncoding.DecodeString(parts[2]); err != nil || len(raw) != 5 {
+        return fmt.Errorf("malformed corridor id %q: invalid random part", id)
+    }
+    sum := make([]byte, 4)
+    binary.BigEndian.PutUint32(sum, crc32.ChecksumIEEE([]byte(strings.Join(parts[:3], "-"))))
+    if parts[3] != idEncoding.EncodeToString(sum) {
+        return fmt.Errorf("malformed corridor id %q: checksum mismatch (mistyped?)", id)
+    }
+    return nil
+}
@@ -0,0 +1,38 @@
+package corridor
+
+import "testing"
+
+// Issued by corrd; the SDK must accept them without a request
+var issuedIDs = []string{"cor-si-0k1zzyn6-40yxtn0", "cor-cb-q86v59x1-d32ypnr"}
+
+func TestValidateIDAcceptsIssuedIDs(t *testing.T) {
+	for _, id := range issuedIDs {
+		if err := ValidateID(id); err != nil {
+			t.Errorf("ValidateID(%s) = %v", id, err)
+		}
+	}
+}
+
+func TestValidateIDCatchesTypos(t *testing.T) {
+	const alphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+	for _, id := range issuedIDs {
+		for i := len("cor-si-"); i < len(id); i++ {
+			if id[i] == '-' {
+				continue
+			}
+			for _, c := range alphabet {
+				if byte(c) == id[i] {
+					continue
+				}
+				if typo := id[:i] + string(c) + id[i+1:]; ValidateID(typo) == nil {
+					t.Fatalf("typo %s of %s validated", typo, id)
+				}
+			}
+		}
+	}
+	for _, bad := range []string{"", "cor-1234abcd", "cor-xx-0k1zzyn6-40yxtn0", "cor-si-0k1zzyn6-40yxtn0-0"} {
+		if ValidateID(bad) == nil {
+			t.Errorf("ValidateID(%q) succeeded", bad)
+		}
+	}
+}
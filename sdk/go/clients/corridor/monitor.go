@@ -0,0 +1,124 @@
+package corridor
+
+import (
+    "context"
+    "math"
+    "time"
+)
+
+// Defaults used when a Thresholds field is zero
+const (
+    DefaultMonitorInterval = 5 * time.Second
+    DefaultHysteresis      = 0.1
+)
+
+type AnomalyKind string
+
+const (
+    AnomalyBER       AnomalyKind = "ber"        // BER above MaxBER
+    AnomalyTempDrift AnomalyKind = "temp_drift" // temperature moved TempDriftC from baseline
+    AnomalyPower     AnomalyKind = "power"      // pJ/bit rose PowerDegradation over baseline
+    AnomalyPoll      AnomalyKind = "poll_error" // telemetry could not be read
+)
+
+// Thresholds configures Monitor. The temperature and power baselines are
+// the first sample read. A zero threshold disables that check.
+type Thresholds struct {
+    Interval         time.Duration // poll period
+    MaxBER           float64
+    TempDriftC       float64 // allowed |temp - baseline| in °C
+    PowerDegradation float64 // allowed fractional rise in pJ/bit, e.g. 0.2
+    // Hysteresis is the fraction of a threshold a metric must fall back
+    // below it before the anomaly re-arms, so a value hovering at the
+    // threshold raises one event rather than one per poll
+    Hysteresis float64
+}
+
+// Anomaly is raised once when a metric crosses its threshold
+type Anomaly struct {
+    Kind      AnomalyKind `json:"kind"`
+    Value     float64     `json:"value"`
+    Threshold float64     `json:"threshold"`
+    Telemetry Telemetry   `json:"telemetry"`
+    At        time.Time   `json:"at"`
+    Err       error       `json:"-"` // set for AnomalyPoll
+}
+
+// Monitor polls a corridor's telemetry every th.Interval and sends an
+// Anomaly when BER, temperature drift or power degradation crosses its
+// threshold. An anomaly fires once and re-arms only after the metric
+// recovers past the hysteresis band; a run of failed polls likewise
+// raises a single AnomalyPoll. Polls that return a sample already seen
+// (same non-zero Seq) are skipped. The channel is closed when ctx is done.
+func (c *Client) Monitor(ctx context.Context, id string, th Thresholds) <-chan Anomaly {
+    if th.Interval <= 0 { th.Interval = DefaultMonitorInterval }
+    if th.Hysteresis <= 0 { th.Hysteresis = DefaultHysteresis }
+
+    out := make(chan Anomaly, 8)
+    go func() {
+        defer close(out)
+        ticker := time.NewTicker(th.Interval)
+        defer ticker.Stop()
+
+        var baseline *Telemetry
+        var lastSeq uint64
+        active := map[AnomalyKind]bool{}
+        emit := func(a Anomaly) bool {
+            a.At = time.Now().UTC()
+            select {
+            case out <- a:
+                return true
+            case <-ctx.Done():
+                return false
+            }
+        }
+        // check latches kind while value exceeds threshold and clears it
+        // once value falls below the hysteresis band
+        check := func(kind AnomalyKind, value, threshold float64, t Telemetry) bool {
+            if threshold <= 0 { return true }
+            switch {
+            case !active[kind] && value > threshold:
+                active[kind] = true
+                return emit(Anomaly{Kind: kind, Value: value, Threshold: threshold, Telemetry: t})
+            case active[kind] && value < threshold*(1-th.Hysteresis):
+                active[kind] = false
+            }
+            return true
+        }
+
+        for {
+            t, err := c.telemetry(ctx, id)
+            switch {
+            case ctx.Err() != nil:
+                return
+            case err != nil:
+                if !active[AnomalyPoll] {
+                    active[AnomalyPoll] = true
+                    if !emit(Anomaly{Kind: AnomalyPoll, Err: err}) { return }
+                }
+            case t.Seq != 0 && t.Seq == lastSeq:
+                active[AnomalyPoll] = false
+            default:
+                lastSeq = t.Seq
+                active[AnomalyPoll] = false
+                if baseline == nil { baseline = t }
+                var powerRise float64
+                if baseline.PowerPjPerBit > 0 {
+                    powerRise = t.PowerPjPerBit/baseline.PowerPjPerBit - 1
+                }
+                if !check(AnomalyBER, t.BER, th.MaxBER, *t) ||
+                    !check(AnomalyTempDrift, math.Abs(t.TempC-baseline.TempC), th.TempDriftC, *t) ||
+                    !check(AnomalyPower, powerRise, th.PowerDegradation, *t) {
+                    return
+                }
+            }
+
+            select {
+            case <-ticker.C:
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+    return out
+}
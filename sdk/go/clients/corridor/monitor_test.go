@@ -0,0 +1,134 @@
+package corridor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// telemetryServer serves samples in order, repeating the last one, and
+// closes done once every sample has been served
+func telemetryServer(t *testing.T, samples []Telemetry) (*httptest.Server, <-chan struct{}) {
+	t.Helper()
+	var mu sync.Mutex
+	served := 0
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/corridors/cor-1/telemetry" {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		sample := samples[min(served, len(samples)-1)]
+		if served++; served == len(samples) {
+			close(done)
+		}
+		mu.Unlock()
+		json.NewEncoder(w).Encode(sample)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, done
+}
+
+// collect runs Monitor until every sample is served, then cancels it and
+// returns the anomalies raised
+func collect(t *testing.T, samples []Telemetry, th Thresholds) []Anomaly {
+	t.Helper()
+	srv, done := telemetryServer(t, samples)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := New(srv.URL).Monitor(ctx, "cor-1", th)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("monitor stopped polling")
+	}
+	// One more poll interval lets the last sample's checks run
+	time.Sleep(3 * th.Interval)
+	cancel()
+
+	var got []Anomaly
+	for a := range events {
+		got = append(got, a)
+	}
+	return got
+}
+
+func TestMonitorBERHysteresis(t *testing.T) {
+	th := Thresholds{Interval: 5 * time.Millisecond, MaxBER: 1e-9, Hysteresis: 0.2}
+	bers := []float64{
+		1e-12,      // healthy
+		2e-9, 3e-9, // crosses: one event
+		0.95e-9, 2e-9, // dips below the threshold but not the band: no re-arm
+		1.1e-9, 1.5e-9, // hovering above
+	}
+	var samples []Telemetry
+	for _, ber := range bers {
+		samples = append(samples, Telemetry{BER: ber, TempC: 45, PowerPjPerBit: 1.2})
+	}
+
+	got := collect(t, samples, th)
+	if len(got) != 1 {
+		t.Fatalf("anomalies = %+v, want exactly one", got)
+	}
+	if a := got[0]; a.Kind != AnomalyBER || a.Value != 2e-9 || a.Threshold != th.MaxBER || a.At.IsZero() {
+		t.Errorf("anomaly = %+v", a)
+	}
+}
+
+func TestMonitorRearmsAfterRecovery(t *testing.T) {
+	th := Thresholds{Interval: 5 * time.Millisecond, MaxBER: 1e-9, Hysteresis: 0.2}
+	var samples []Telemetry
+	for _, ber := range []float64{2e-9, 0.5e-9, 2e-9} {
+		samples = append(samples, Telemetry{BER: ber, TempC: 45, PowerPjPerBit: 1.2})
+	}
+	got := collect(t, samples, th)
+	if len(got) != 2 || got[0].Kind != AnomalyBER || got[1].Kind != AnomalyBER {
+		t.Fatalf("anomalies = %+v, want two BER events", got)
+	}
+}
+
+func TestMonitorDriftAndPower(t *testing.T) {
+	th := Thresholds{Interval: 5 * time.Millisecond, TempDriftC: 5, PowerDegradation: 0.2}
+	got := collect(t, []Telemetry{
+		{BER: 1e-12, TempC: 40, PowerPjPerBit: 1.0}, // baseline
+		{BER: 1e-12, TempC: 47, PowerPjPerBit: 1.1},
+		{BER: 1e-12, TempC: 47, PowerPjPerBit: 1.3},
+	}, th)
+	if len(got) != 2 || got[0].Kind != AnomalyTempDrift || got[1].Kind != AnomalyPower {
+		t.Fatalf("anomalies = %+v, want a temperature drift then a power event", got)
+	}
+}
+
+func TestMonitorStopsOnCancel(t *testing.T) {
+	srv, _ := telemetryServer(t, []Telemetry{{BER: 1e-12}})
+	ctx, cancel := context.WithCancel(context.Background())
+	events := New(srv.URL).Monitor(ctx, "cor-1", Thresholds{Interval: time.Hour, MaxBER: 1e-9})
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("anomaly raised for a healthy link")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("monitor did not stop on cancellation")
+	}
+}
+
+func TestMonitorSkipsRepeatedSamples(t *testing.T) {
+	th := Thresholds{Interval: 5 * time.Millisecond, MaxBER: 1e-9, Hysteresis: 0.2}
+	got := collect(t, []Telemetry{
+		{Seq: 1, BER: 1e-12},
+		{Seq: 2, BER: 2e-9},
+		{Seq: 2, BER: 1e-12}, // same sample re-served: must not re-arm
+		{Seq: 3, BER: 2e-9},
+	}, th)
+	if len(got) != 1 || got[0].Telemetry.Seq != 2 {
+		t.Fatalf("anomalies = %+v, want one from seq 2", got)
+	}
+}
@@ -0,0 +1,31 @@
+package corridor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewMultiFailsOver(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Corridor{ID: r.URL.Path[len("/v1/corridors/"):]})
+	}))
+	defer up.Close()
+
+	c, err := NewMulti([]string{down.URL + "/", up.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cor, err := c.Get("cor-1")
+	if err != nil || cor.ID != "cor-1" {
+		t.Fatalf("Get through failover = %+v, %v", cor, err)
+	}
+	if _, err := NewMulti(nil); err == nil {
+		t.Error("NewMulti accepted no endpoints")
+	}
+}
@@ -0,0 +1,22 @@
+package corridor
+
+import "github.com/corridoros/pkg/protowire"
+
+// UnmarshalProto decodes a corridoros.v1.CorridorTelemetry
+func (t *Telemetry) UnmarshalProto(b []byte) error {
+    *t = Telemetry{}
+    return protowire.Range(b, func(f protowire.Field) error {
+        var err error
+        switch f.Num {
+        case 1: t.BER = f.Double()
+        case 2: t.TempC = f.Double()
+        case 3: t.PowerPjPerBit = f.Double()
+        case 7: t.GbpsPerLane = f.Double()
+        case 8: t.SpectralEfficiency = f.Double()
+        case 9: t.EfficiencyScore = f.Double()
+        case 10: t.Seq = f.Varint
+        case 11: t.SampledAt, err = f.Time()
+        }
+        return err
+    })
+}
@@ -0,0 +1,57 @@
+package corridor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/corridoros/pkg/protowire"
+)
+
+// protoServer answers telemetry in protobuf when asked and honour is
+// set, and in JSON otherwise
+func protoServer(want Telemetry, honour bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if honour && protowire.Accepts(r) {
+			b := protowire.AppendDouble(nil, 1, want.BER)
+			b = protowire.AppendDouble(b, 2, want.TempC)
+			b = protowire.AppendDouble(b, 3, want.PowerPjPerBit)
+			b = protowire.AppendString(b, 4, "stable") // not in the SDK type
+			b = protowire.AppendDouble(b, 7, want.GbpsPerLane)
+			b = protowire.AppendDouble(b, 8, want.SpectralEfficiency)
+			b = protowire.AppendDouble(b, 9, want.EfficiencyScore)
+			b = protowire.AppendUint64(b, 10, want.Seq)
+			b = protowire.AppendTime(b, 11, want.SampledAt)
+			w.Header().Set("Content-Type", protowire.ContentType)
+			w.Write(b)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(want)
+	}))
+}
+
+func TestWithProtobufDecodesTheSameTelemetry(t *testing.T) {
+	want := Telemetry{
+		BER: 3.5e-13, TempC: 40.25, PowerPjPerBit: 0.9,
+		GbpsPerLane: 52, SpectralEfficiency: 1.04, EfficiencyScore: 88,
+		Seq: 17, SampledAt: time.Date(2026, 3, 1, 12, 0, 0, 5000, time.UTC),
+	}
+	for _, honour := range []bool{true, false} {
+		srv := protoServer(want, honour)
+		fromProto, err := New(srv.URL).WithProtobuf().Telemetry("cor-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fromJSON, err := New(srv.URL).Telemetry("cor-1")
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *fromProto != want || *fromJSON != want {
+			t.Errorf("server protobuf %v: protobuf client got %+v, JSON client got %+v, want %+v", honour, *fromProto, *fromJSON, want)
+		}
+	}
+}
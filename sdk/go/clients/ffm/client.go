@@ -1,62 +1,295 @@
+// Package ffm is a client for memqosd's Free-Form Memory API.
+//
+// Bandwidth is canonically in GB/s, decimal gigabytes (10^9 bytes) per
+// second: the unit of every *GBs field. The *Gbps (gigabits/s) and *GiBps
+// (GiB/s, 2^30 bytes/s) fields are memqosd's conversions of the same values;
+// compare like with like.
 package ffm
 
 import (
     "bytes"
     "encoding/json"
-    "fmt"
-    "io"
     "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/corridoros/pkg/protowire"
+    "github.com/corridoros/sdk-go/apierror"
+    "github.com/corridoros/sdk-go/failover"
+    "github.com/corridoros/sdk-go/trace"
 )
 
 type AllocateRequest struct {
     Bytes              uint64 `json:"bytes"`
     LatencyClass       string `json:"latency_class"`
     BandwidthFloorGBs  uint64 `json:"bandwidth_floor_GBs"`
+    BandwidthFloorGbps  float64 `json:"bandwidth_floor_gbps,omitempty"`  // alternative to BandwidthFloorGBs
+    BandwidthFloorGiBps float64 `json:"bandwidth_floor_gibps,omitempty"` // alternative to BandwidthFloorGBs
     Persistence        string `json:"persistence"`
     Shareable          bool   `json:"shareable"`
     SecurityDomain     string `json:"security_domain"`
     AttestationRequired bool  `json:"attestation_required,omitempty"`
     AttestationTicket   string `json:"attestation_ticket,omitempty"`
+    Labels              map[string]string `json:"labels,omitempty"`
 }
 
 type Handle struct {
     ID    string `json:"id"`
     Bytes uint64 `json:"bytes"`
+    BandwidthFloorGBs   uint64  `json:"bandwidth_floor_GBs"`
+    BandwidthFloorGbps  float64 `json:"bandwidth_floor_gbps"`
+    BandwidthFloorGiBps float64 `json:"bandwidth_floor_gibps"`
+    Labels              map[string]string `json:"labels,omitempty"`
 }
 
 type Telemetry struct {
-    AchievedGBs uint64 `json:"achieved_GBs"`
+    AchievedGBs   uint64  `json:"achieved_GBs"`
+    AchievedGbps  float64 `json:"achieved_gbps"`
+    AchievedGiBps float64 `json:"achieved_gibps"`
+    // Seq numbers memqosd's background samples; equal seqs are the same sample
+    Seq           uint64    `json:"seq"`
+    SampledAt     time.Time `json:"sampled_at"`
+    // SLACompliancePercent is the share of measurements that met the floor
+    SLACompliancePercent float64 `json:"sla_compliance_percent"`
+    // DirtyBytes are a write-back handle's unflushed writes
+    DirtyBytes uint64 `json:"dirty_bytes"`
 }
 
-type Client struct { BaseURL string; HTTP *http.Client }
+// Unit conversions matching memqosd's
+const (
+    BitsPerByte = 8
+    BytesPerGB  = 1e9
+    BytesPerGiB = 1 << 30
+)
+
+func GBsToGbps(gbs uint64) float64 { return float64(gbs) * BitsPerByte }
+
+func GBsToGiBps(gbs uint64) float64 { return float64(gbs) * BytesPerGB / BytesPerGiB }
+
+type Client struct { BaseURL string; HTTP *http.Client; protobuf bool }
 
-func New(base string) *Client { return &Client{BaseURL: base, HTTP: &http.Client{}} }
+// New creates a client that tags each request with a fresh trace ID
+func New(base string) *Client { return &Client{BaseURL: base, HTTP: &http.Client{Transport: &trace.Transport{}}} }
+
+// NewWithTransport is New sending requests over t, e.g. a transport from
+// tlsclient.NewTransport for a service whose certificate a private CA issued
+func NewWithTransport(base string, t http.RoundTripper) *Client { return &Client{BaseURL: base, HTTP: &http.Client{Transport: &trace.Transport{Base: t}}} }
+
+// NewMulti creates a client for several memqosd endpoints, in order of
+// preference, that fails over between them; see package failover
+func NewMulti(urls []string, opts ...failover.Option) (*Client, error) {
+    t, err := failover.New(urls, opts...)
+    if err != nil { return nil, err }
+    return &Client{BaseURL: strings.TrimSuffix(urls[0], "/"), HTTP: &http.Client{Transport: &trace.Transport{Base: t}}}, nil
+}
+
+// WithTraceID returns a copy of c whose requests all carry id, so a
+// multi-call operation shows up under one trace in the service logs
+func (c *Client) WithTraceID(id string) *Client {
+    cp := *c
+    cp.HTTP = trace.WithID(c.HTTP, id)
+    return &cp
+}
 
 func (c *Client) Allocate(req AllocateRequest) (*Handle, error) {
     b, _ := json.Marshal(req)
     resp, err := c.HTTP.Post(c.BaseURL+"/v1/ffm/alloc", "application/json", bytes.NewBuffer(b))
     if err != nil { return nil, err }
     defer resp.Body.Close()
-    if resp.StatusCode != http.StatusCreated { body,_ := io.ReadAll(resp.Body); return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body)) }
+    if resp.StatusCode != http.StatusCreated { return nil, apierror.FromResponse(resp) }
     var h Handle
     return &h, json.NewDecoder(resp.Body).Decode(&h)
 }
 
+// ListOption narrows a List call
+type ListOption func(url.Values)
+
+// WithLabel keeps only handles labelled key=value; repeat to require several
+func WithLabel(key, value string) ListOption {
+    return func(q url.Values) { q.Set("label."+key, value) }
+}
+
+// List returns the handles matching every option, oldest first
+func (c *Client) List(opts ...ListOption) ([]Handle, error) {
+    q := url.Values{}
+    for _, opt := range opts { opt(q) }
+    u := c.BaseURL+"/v1/ffm/"
+    if len(q) > 0 { u += "?"+q.Encode() }
+    resp, err := c.HTTP.Get(u)
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
+    var out []Handle
+    return out, json.NewDecoder(resp.Body).Decode(&out)
+}
+
 func (c *Client) Get(id string) (*Handle, error) {
     resp, err := c.HTTP.Get(c.BaseURL+"/v1/ffm/"+id)
     if err != nil { return nil, err }
     defer resp.Body.Close()
-    if resp.StatusCode != http.StatusOK { body,_ := io.ReadAll(resp.Body); return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body)) }
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
     var h Handle
     return &h, json.NewDecoder(resp.Body).Decode(&h)
 }
 
+// WithProtobuf returns a copy of c that asks for telemetry in the binary
+// protobuf encoding, which is cheaper to decode when polling at high rates
+func (c *Client) WithProtobuf() *Client {
+    cp := *c
+    cp.protobuf = true
+    return &cp
+}
+
 func (c *Client) Telemetry(id string) (*Telemetry, error) {
-    resp, err := c.HTTP.Get(c.BaseURL+"/v1/ffm/"+id+"/telemetry")
+    req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/v1/ffm/"+id+"/telemetry", nil)
+    if err != nil { return nil, err }
+    if c.protobuf { req.Header.Set("Accept", protowire.ContentType) }
+    resp, err := c.HTTP.Do(req)
     if err != nil { return nil, err }
     defer resp.Body.Close()
-    if resp.StatusCode != http.StatusOK { body,_ := io.ReadAll(resp.Body); return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body)) }
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
     var t Telemetry
+    if protowire.IsProto(resp) { return &t, protowire.Decode(resp, &t) }
     return &t, json.NewDecoder(resp.Body).Decode(&t)
 }
 
+type FlushResult struct {
+    FlushedBytes uint64    `json:"flushed_bytes"`
+    DirtyBytes   uint64    `json:"dirty_bytes"`
+    FlushedAt    time.Time `json:"flushed_at"`
+}
+
+// Flush writes back a handle's pending writes; it fails for handles with
+// persistence "none"
+func (c *Client) Flush(id string) (*FlushResult, error) {
+    resp, err := c.HTTP.Post(c.BaseURL+"/v1/ffm/"+id+"/flush", "application/json", nil)
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
+    var out FlushResult
+    return &out, json.NewDecoder(resp.Body).Decode(&out)
+}
+
+// Free releases a handle, returning its quota and tier capacity
+func (c *Client) Free(id string) error {
+    req, err := http.NewRequest(http.MethodDelete, c.BaseURL+"/v1/ffm/"+id, nil)
+    if err != nil { return err }
+    resp, err := c.HTTP.Do(req)
+    if err != nil { return err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusNoContent { return apierror.FromResponse(resp) }
+    return nil
+}
+
+
+type DomainQuota struct {
+    MaxBytes   uint64 `json:"max_bytes"`   // 0 = unlimited
+    MaxHandles int    `json:"max_handles"` // 0 = unlimited
+}
+
+type DomainUsage struct {
+    Domain  string      `json:"domain"`
+    Bytes   uint64      `json:"bytes"`
+    Handles int         `json:"handles"`
+    Quota   DomainQuota `json:"quota"`
+}
+
+// Usage returns a security domain's consumption against its quota. Allocations
+// past the quota fail with apierror.CodeQuotaExceeded.
+func (c *Client) Usage(domain string) (*DomainUsage, error) {
+    resp, err := c.HTTP.Get(c.BaseURL+"/v1/ffm/domains/"+url.PathEscape(domain)+"/usage")
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
+    var u DomainUsage
+    return &u, json.NewDecoder(resp.Body).Decode(&u)
+}
+
+// TierCapacity is a latency class's configured total; zero is unlimited
+type TierCapacity struct {
+    Bytes uint64 `json:"bytes"`
+    GBs   uint64 `json:"bandwidth_GBs"`
+}
+
+// ClassCapacity is a latency class's committed and remaining capacity; nil
+// Available fields mean unlimited
+type ClassCapacity struct {
+    LatencyClass      string       `json:"latency_class"`
+    Tier              string       `json:"tier"`
+    Capacity          TierCapacity `json:"capacity"`
+    Handles           int          `json:"handles"`
+    Bytes             uint64       `json:"bytes"`
+    BandwidthFloorGBs uint64       `json:"bandwidth_floor_GBs"`
+    AvailableBytes    *uint64      `json:"available_bytes,omitempty"`
+    AvailableGBs      *uint64      `json:"available_bandwidth_GBs,omitempty"`
+}
+
+// DomainCapacity is a security domain's headroom under its quota; nil
+// Available fields mean unlimited
+type DomainCapacity struct {
+    DomainUsage
+    AvailableBytes   *uint64 `json:"available_bytes,omitempty"`
+    AvailableHandles *int    `json:"available_handles,omitempty"`
+}
+
+type Capacity struct {
+    LatencyClasses []ClassCapacity  `json:"latency_classes"`
+    Domains        []DomainCapacity `json:"domains"`
+    DefaultQuota   DomainQuota      `json:"default_quota"` // for domains not listed
+}
+
+// Capacity reports the headroom per latency class and security domain,
+// for placing allocations before making them
+func (c *Client) Capacity() (*Capacity, error) {
+    resp, err := c.HTTP.Get(c.BaseURL+"/v1/ffm/capacity")
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return nil, apierror.FromResponse(resp) }
+    var out Capacity
+    return &out, json.NewDecoder(resp.Body).Decode(&out)
+}
+
+// Reservation tentatively holds an allocation's quota until Commit, Abort
+// or ExpiresAt
+type Reservation struct {
+    Token     string    `json:"token"`
+    ExpiresAt time.Time `json:"expires_at"`
+    Handle    Handle    `json:"handle"`
+}
+
+// Reserve holds an allocation for ttl (0 for the service default)
+func (c *Client) Reserve(req AllocateRequest, ttl time.Duration) (*Reservation, error) {
+    body := struct {
+        AllocateRequest
+        TTLSeconds int `json:"ttl_s,omitempty"`
+    }{req, int(ttl / time.Second)}
+    b, _ := json.Marshal(body)
+    resp, err := c.HTTP.Post(c.BaseURL+"/v1/ffm/reserve", "application/json", bytes.NewBuffer(b))
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusCreated { return nil, apierror.FromResponse(resp) }
+    var out Reservation
+    return &out, json.NewDecoder(resp.Body).Decode(&out)
+}
+
+// Commit creates a reserved handle; it fails once the reservation expired
+func (c *Client) Commit(token string) (*Handle, error) {
+    resp, err := c.HTTP.Post(c.BaseURL+"/v1/ffm/reservations/"+token+"/commit", "application/json", nil)
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusCreated { return nil, apierror.FromResponse(resp) }
+    var h Handle
+    return &h, json.NewDecoder(resp.Body).Decode(&h)
+}
+
+// Abort releases a reservation without committing it
+func (c *Client) Abort(token string) error {
+    req, err := http.NewRequest(http.MethodDelete, c.BaseURL+"/v1/ffm/reservations/"+token, nil)
+    if err != nil { return err }
+    resp, err := c.HTTP.Do(req)
+    if err != nil { return err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusNoContent { return apierror.FromResponse(resp) }
+    return nil
+}
@@ -0,0 +1,21 @@
+package ffm
+
+import "github.com/corridoros/pkg/protowire"
+
+// UnmarshalProto decodes a corridoros.v1.FFMTelemetry
+func (t *Telemetry) UnmarshalProto(b []byte) error {
+    *t = Telemetry{}
+    return protowire.Range(b, func(f protowire.Field) error {
+        var err error
+        switch f.Num {
+        case 1: t.AchievedGBs = f.Varint
+        case 2: t.AchievedGbps = f.Double()
+        case 3: t.AchievedGiBps = f.Double()
+        case 9: t.Seq = f.Varint
+        case 10: t.SampledAt, err = f.Time()
+        case 11: t.SLACompliancePercent = f.Double()
+        case 12: t.DirtyBytes = f.Varint
+        }
+        return err
+    })
+}
@@ -0,0 +1,174 @@
+// Package failover spreads the SDK clients over several endpoints of one
+// service, for HA deployments with an instance per region.
+//
+// A Transport sends each request to the endpoint that last answered and
+// fails over to the next on connection errors and 5xx responses, each
+// endpoint guarded by its own circuit breaker so a dead one is skipped
+// quickly. Only requests that are safe to repeat are failed over once
+// sent: idempotent methods always, others only when they never reached the
+// endpoint (a refused connection or an open breaker), so a POST that a
+// server may have acted on is not replayed elsewhere.
+package failover
+
+import (
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/corridoros/sdk-go/breaker"
+)
+
+// Defaults for each endpoint's breaker; a dead endpoint is skipped after
+// a couple of failures and probed again shortly after
+const (
+    DefaultFailureThreshold = 2
+    DefaultCooldown         = 10 * time.Second
+)
+
+// Option configures a Transport
+type Option func(*Transport)
+
+// WithBreaker sets the failure threshold and cooldown of every endpoint's
+// breaker
+func WithBreaker(threshold int, cooldown time.Duration) Option {
+    return func(t *Transport) { t.threshold, t.cooldown = threshold, cooldown }
+}
+
+// WithBase sets the transport requests are sent over; the default is
+// http.DefaultTransport
+func WithBase(base http.RoundTripper) Option {
+    return func(t *Transport) { t.base = base }
+}
+
+type endpoint struct {
+    base    *url.URL
+    breaker *breaker.Breaker
+}
+
+// EndpointStats is a snapshot of one endpoint for observability
+type EndpointStats struct {
+    URL     string        `json:"url"`
+    Current bool          `json:"current"`
+    Breaker breaker.Stats `json:"breaker"`
+}
+
+// Transport is an http.RoundTripper failing over between endpoints.
+// Requests are addressed to the first endpoint; the Transport rewrites
+// them to the one in use.
+type Transport struct {
+    threshold int
+    cooldown  time.Duration
+    base      http.RoundTripper
+
+    endpoints []endpoint
+    mu        sync.Mutex
+    current   int // index of the last endpoint that answered
+}
+
+// New creates a transport over the endpoints' base URLs, in order of
+// preference
+func New(urls []string, opts ...Option) (*Transport, error) {
+    if len(urls) == 0 { return nil, errors.New("failover: no endpoints") }
+    t := &Transport{threshold: DefaultFailureThreshold, cooldown: DefaultCooldown}
+    for _, opt := range opts { opt(t) }
+    for _, raw := range urls {
+        u, err := url.Parse(strings.TrimSuffix(raw, "/"))
+        if err != nil || u.Scheme == "" || u.Host == "" {
+            return nil, fmt.Errorf("failover: invalid endpoint %q", raw)
+        }
+        b := breaker.New(t.threshold, t.cooldown)
+        b.Base = t.base
+        t.endpoints = append(t.endpoints, endpoint{base: u, breaker: b})
+    }
+    return t, nil
+}
+
+// Current returns the base URL requests are sent to first
+func (t *Transport) Current() string {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    return t.endpoints[t.current].base.String()
+}
+
+// Stats returns a snapshot of every endpoint
+func (t *Transport) Stats() []EndpointStats {
+    t.mu.Lock()
+    current := t.current
+    t.mu.Unlock()
+    stats := make([]EndpointStats, len(t.endpoints))
+    for i, ep := range t.endpoints {
+        stats[i] = EndpointStats{URL: ep.base.String(), Current: i == current, Breaker: ep.breaker.Stats()}
+    }
+    return stats
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+    primary := t.endpoints[0].base
+    if req.URL.Scheme != primary.Scheme || req.URL.Host != primary.Host || !strings.HasPrefix(req.URL.Path, primary.Path) {
+        base := t.base
+        if base == nil { base = http.DefaultTransport }
+        return base.RoundTrip(req)
+    }
+    path := strings.TrimPrefix(req.URL.Path, primary.Path)
+    replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+    t.mu.Lock()
+    start := t.current
+    t.mu.Unlock()
+
+    var resp *http.Response
+    var err error
+    for i := range t.endpoints {
+        idx := (start + i) % len(t.endpoints)
+        attempt, aerr := rewrite(req, t.endpoints[idx].base, path, i > 0)
+        if aerr != nil { return nil, aerr }
+        if resp != nil { resp.Body.Close() }
+        resp, err = t.endpoints[idx].breaker.RoundTrip(attempt)
+        if err == nil && resp.StatusCode < 500 {
+            t.mu.Lock()
+            t.current = idx
+            t.mu.Unlock()
+            return resp, nil
+        }
+        if !replayable || !(idempotent(req.Method) || notSent(err)) { break }
+    }
+    return resp, err
+}
+
+// rewrite addresses a copy of req to path under base, with a fresh body
+// for a repeat attempt; RoundTrip only repeats requests with GetBody
+func rewrite(req *http.Request, base *url.URL, path string, repeat bool) (*http.Request, error) {
+    r := req.Clone(req.Context())
+    u := *req.URL
+    u.Scheme, u.Host, u.Path, u.RawPath = base.Scheme, base.Host, base.Path+path, ""
+    r.URL, r.Host = &u, ""
+    if repeat && req.GetBody != nil {
+        body, err := req.GetBody()
+        if err != nil { return nil, err }
+        r.Body = body
+    }
+    return r, nil
+}
+
+// idempotent reports whether repeating a request with this method has the
+// same effect as sending it once
+func idempotent(method string) bool {
+    switch method {
+    case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+        return true
+    }
+    return false
+}
+
+// notSent reports whether a request failed before any endpoint saw it
+func notSent(err error) bool {
+    if err == nil { return false }
+    if errors.Is(err, breaker.ErrCircuitOpen) { return true }
+    var op *net.OpError
+    return errors.As(err, &op) && op.Op == "dial"
+}
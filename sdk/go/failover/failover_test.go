@@ -0,0 +1,131 @@
+package failover
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// region is an endpoint that can be taken down; while down it answers 503
+type region struct {
+	*httptest.Server
+	down  atomic.Bool
+	calls atomic.Int32
+}
+
+func newRegion(t *testing.T, name string) *region {
+	t.Helper()
+	r := &region{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.calls.Add(1)
+		if r.down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, name+" "+req.URL.Path)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// get sends a GET for path under the first endpoint through t
+func get(tb testing.TB, client *http.Client, base, path string) (int, string) {
+	tb.Helper()
+	resp, err := client.Get(base + path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestFailsOverToSecondAndRecovers(t *testing.T) {
+	east, west := newRegion(t, "east"), newRegion(t, "west")
+	tr, err := New([]string{east.URL + "/api", west.URL + "/api/"}, WithBreaker(2, 50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: tr}
+
+	east.down.Store(true)
+	if code, body := get(t, client, east.URL, "/api/v1/corridors"); code != http.StatusOK || body != "west /api/v1/corridors" {
+		t.Fatalf("with east down: %d %q", code, body)
+	}
+	if tr.Current() != west.URL+"/api" {
+		t.Errorf("current = %s, want west", tr.Current())
+	}
+
+	// West stays current; east is not tried while it answers
+	east.calls.Store(0)
+	get(t, client, east.URL, "/api/v1/corridors")
+	if east.calls.Load() != 0 {
+		t.Errorf("east was tried %d times while west answered", east.calls.Load())
+	}
+
+	// East recovers and west goes down: requests move back to east
+	east.down.Store(false)
+	west.down.Store(true)
+	if code, body := get(t, client, east.URL, "/api/v1/corridors"); code != http.StatusOK || !strings.HasPrefix(body, "east") {
+		t.Fatalf("with west down: %d %q", code, body)
+	}
+	if tr.Current() != east.URL+"/api" {
+		t.Errorf("current = %s, want east", tr.Current())
+	}
+}
+
+func TestDeadEndpointIsSkipped(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	live := newRegion(t, "live")
+	tr, _ := New([]string{dead.URL, live.URL}, WithBreaker(1, time.Minute))
+	client := &http.Client{Transport: tr}
+
+	// A refused POST never reached the dead endpoint, so it may move on
+	resp, err := client.Post(dead.URL+"/v1/corridors", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "live /v1/corridors" {
+		t.Errorf("POST with the first endpoint dead = %q", body)
+	}
+	if stats := tr.Stats(); stats[0].Current || !stats[1].Current || stats[0].Breaker.State != "open" {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestPostIsNotReplayedAfterServerError(t *testing.T) {
+	first, second := newRegion(t, "first"), newRegion(t, "second")
+	first.down.Store(true)
+	tr, _ := New([]string{first.URL, second.URL})
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Post(first.URL+"/v1/corridors", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || second.calls.Load() != 0 {
+		t.Errorf("POST answered %d with %d calls to the second endpoint; want the 503 and none", resp.StatusCode, second.calls.Load())
+	}
+}
+
+func TestOtherHostsPassThrough(t *testing.T) {
+	svc, other := newRegion(t, "svc"), newRegion(t, "other")
+	tr, _ := New([]string{svc.URL})
+	if _, body := get(t, &http.Client{Transport: tr}, other.URL, "/x"); body != "other /x" || svc.calls.Load() != 0 {
+		t.Errorf("request to another host = %q", body)
+	}
+	if _, err := New(nil); err == nil {
+		t.Error("New accepted no endpoints")
+	}
+	if _, err := New([]string{"not a url"}); err == nil {
+		t.Error("New accepted an invalid endpoint")
+	}
+}
@@ -0,0 +1,68 @@
+package signing
+
+import (
+    "crypto/mldsa"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "net/http"
+)
+
+// Headers carrying a server's post-quantum endorsement of its TLS
+// certificate, when started with -tls-endorsement
+const (
+    EndorsementHeader      = "X-Corridoros-Cert-Endorsement"
+    EndorsementKeyIDHeader = "X-Corridoros-Cert-Endorsement-Key-Id"
+)
+
+// endorsementContext is the ML-DSA context endorsements are signed under
+const endorsementContext = "corridoros-tls-endorsement-v1"
+
+var (
+    ErrNotTLS         = errors.New("response did not come over TLS")
+    ErrNotEndorsed    = errors.New("server certificate is not endorsed")
+    ErrBadEndorsement = errors.New("server certificate endorsement does not verify")
+)
+
+// VerifyEndorsement checks a base64 endorsement header value against the
+// DER leaf certificate the server presented
+func VerifyEndorsement(cert []byte, signature string, publicKey []byte) error {
+    if signature == "" { return ErrNotEndorsed }
+    raw, err := base64.StdEncoding.DecodeString(signature)
+    if err != nil { return ErrBadEndorsement }
+    pk, err := mldsa.NewPublicKey(mldsa.MLDSA65(), publicKey)
+    if err != nil { return fmt.Errorf("invalid public key: %v", err) }
+    if mldsa.Verify(pk, cert, raw, &mldsa.Options{Context: endorsementContext}) != nil { return ErrBadEndorsement }
+    return nil
+}
+
+// EndorsementTransport is an http.RoundTripper that, on top of the usual
+// TLS verification, rejects responses from a server whose certificate is
+// not endorsed by PublicKey, the operator's pinned ML-DSA-65 key.
+// Plaintext responses are rejected too.
+type EndorsementTransport struct {
+    PublicKey []byte
+    Base      http.RoundTripper // nil means http.DefaultTransport
+}
+
+func (t *EndorsementTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    base := t.Base
+    if base == nil { base = http.DefaultTransport }
+    resp, err := base.RoundTrip(req)
+    if err != nil { return nil, err }
+    err = ErrNotTLS
+    if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+        err = VerifyEndorsement(resp.TLS.PeerCertificates[0].Raw, resp.Header.Get(EndorsementHeader), t.PublicKey)
+    }
+    if err != nil {
+        resp.Body.Close()
+        return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, err)
+    }
+    return resp, nil
+}
+
+// EnableEndorsement makes client verify the server's certificate
+// endorsement against publicKey on every response
+func EnableEndorsement(client *http.Client, publicKey []byte) {
+    client.Transport = &EndorsementTransport{PublicKey: publicKey, Base: client.Transport}
+}
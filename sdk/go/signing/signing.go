@@ -0,0 +1,80 @@
+// Package signing verifies the ML-DSA-65 response signatures services attach
+// when started with -sign-responses, so callers can reject tampered bodies.
+package signing
+
+import (
+    "bytes"
+    "crypto/mldsa"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+)
+
+// SignatureHeader carries the base64 signature over the response body
+const SignatureHeader = "X-Corridoros-Signature"
+
+var (
+    ErrUnsigned     = errors.New("response is not signed")
+    ErrBadSignature = errors.New("response signature does not verify")
+)
+
+// PublicKeyInfo is the body served from a service's /pubkey endpoint
+type PublicKeyInfo struct {
+    Algorithm string `json:"algorithm"`
+    KeyID     string `json:"key_id"`
+    PublicKey string `json:"public_key"` // base64
+}
+
+// FetchPublicKey reads a service's signing key from its pubkey URL,
+// e.g. http://localhost:8085/v1/physics/pubkey. Pin the result rather than
+// fetching it over the same channel as the responses it protects.
+func FetchPublicKey(url string) ([]byte, error) {
+    resp, err := http.Get(url)
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return nil, fmt.Errorf("fetch public key: %s", resp.Status) }
+    var info PublicKeyInfo
+    if err := json.NewDecoder(resp.Body).Decode(&info); err != nil { return nil, err }
+    return base64.StdEncoding.DecodeString(info.PublicKey)
+}
+
+// Verify checks a base64 signature header value against a body
+func Verify(body []byte, signature string, publicKey []byte) error {
+    if signature == "" { return ErrUnsigned }
+    raw, err := base64.StdEncoding.DecodeString(signature)
+    if err != nil { return ErrBadSignature }
+    pk, err := mldsa.NewPublicKey(mldsa.MLDSA65(), publicKey)
+    if err != nil { return fmt.Errorf("invalid public key: %v", err) }
+    if mldsa.Verify(pk, body, raw, &mldsa.Options{}) != nil { return ErrBadSignature }
+    return nil
+}
+
+// Transport is an http.RoundTripper that rejects responses whose body does
+// not verify against PublicKey. Unsigned responses are rejected too.
+type Transport struct {
+    PublicKey []byte
+    Base      http.RoundTripper // nil means http.DefaultTransport
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+    base := t.Base
+    if base == nil { base = http.DefaultTransport }
+    resp, err := base.RoundTrip(req)
+    if err != nil { return nil, err }
+    body, err := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if err != nil { return nil, err }
+    if err := Verify(body, resp.Header.Get(SignatureHeader), t.PublicKey); err != nil {
+        return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, err)
+    }
+    resp.Body = io.NopCloser(bytes.NewReader(body))
+    return resp, nil
+}
+
+// Enable makes client verify every response against publicKey
+func Enable(client *http.Client, publicKey []byte) {
+    client.Transport = &Transport{PublicKey: publicKey, Base: client.Transport}
+}
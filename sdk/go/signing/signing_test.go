@@ -0,0 +1,62 @@
+package signing
+
+import (
+	"crypto/mldsa"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// signingServer serves body signed with sk, after tamper has had a go at it
+func signingServer(t *testing.T, sk *mldsa.PrivateKey, body string, tamper func([]byte) []byte) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature, err := sk.Sign(rand.Reader, []byte(body), &mldsa.Options{})
+		if err != nil {
+			t.Error(err)
+		}
+		w.Header().Set(SignatureHeader, base64.StdEncoding.EncodeToString(signature))
+		w.Write(tamper([]byte(body)))
+	}))
+}
+
+func TestTransportVerifiesSignedResponses(t *testing.T) {
+	sk, err := mldsa.GenerateKey(mldsa.MLDSA65())
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := sk.PublicKey().Bytes()
+
+	srv := signingServer(t, sk, `{"result":42}`, func(b []byte) []byte { return b })
+	defer srv.Close()
+	client := srv.Client()
+	Enable(client, publicKey)
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("signed response rejected: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"result":42}` {
+		t.Errorf("body = %s", body)
+	}
+
+	tampered := signingServer(t, sk, `{"result":42}`, func(b []byte) []byte { b[10] = '3'; return b })
+	defer tampered.Close()
+	client = tampered.Client()
+	Enable(client, publicKey)
+	if _, err := client.Get(tampered.URL); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("tampered response error = %v, want ErrBadSignature", err)
+	}
+}
+
+func TestVerifyRejectsUnsigned(t *testing.T) {
+	sk, _ := mldsa.GenerateKey(mldsa.MLDSA65())
+	if err := Verify([]byte("x"), "", sk.PublicKey().Bytes()); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Verify(unsigned) = %v, want ErrUnsigned", err)
+	}
+}
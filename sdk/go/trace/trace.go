@@ -0,0 +1,49 @@
+// Package trace attaches the trace ID header the CorridorOS services log
+// requests under, so a caller can follow one operation across services.
+package trace
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "fmt"
+    "net/http"
+    "time"
+)
+
+// Header carries the trace ID; services echo it on their responses
+const Header = "X-Corridoros-Trace-Id"
+
+// NewID generates a random 128-bit trace ID
+func NewID() string {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return fmt.Sprintf("%032x", time.Now().UnixNano())
+    }
+    return hex.EncodeToString(b)
+}
+
+// Transport sets the trace header on requests that lack one: ID if set,
+// otherwise a fresh ID per request
+type Transport struct {
+    ID   string
+    Base http.RoundTripper // nil means http.DefaultTransport
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+    base := t.Base
+    if base == nil { base = http.DefaultTransport }
+    if req.Header.Get(Header) == "" {
+        id := t.ID
+        if id == "" { id = NewID() }
+        req = req.Clone(req.Context())
+        req.Header.Set(Header, id)
+    }
+    return base.RoundTrip(req)
+}
+
+// WithID returns a copy of client that sends id on every request
+func WithID(client *http.Client, id string) *http.Client {
+    c := *client
+    c.Transport = &Transport{ID: id, Base: client.Transport}
+    return &c
+}
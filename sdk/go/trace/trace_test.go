@@ -0,0 +1,44 @@
+package trace
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransportSetsTraceID(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get(Header))
+	}))
+	defer srv.Close()
+
+	pinned := WithID(srv.Client(), "op-42")
+	fresh := &http.Client{Transport: &Transport{Base: srv.Client().Transport}}
+	for _, c := range []*http.Client{pinned, pinned, fresh, fresh} {
+		resp, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if seen[0] != "op-42" || seen[1] != "op-42" {
+		t.Errorf("pinned client sent %q", seen[:2])
+	}
+	if seen[2] == "" || seen[2] == seen[3] || len(seen[2]) != 32 {
+		t.Errorf("unpinned client sent %q, want a fresh ID per request", seen[2:])
+	}
+
+	// A caller-set header wins
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set(Header, "caller")
+	resp, err := pinned.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if seen[4] != "caller" {
+		t.Errorf("caller-supplied ID replaced with %q", seen[4])
+	}
+}
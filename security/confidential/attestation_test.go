@@ -0,0 +1,60 @@
+package confidential
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAttestationNonceVerifiesOnce(t *testing.T) {
+	s := NewConfidentialComputeService()
+	enclave, err := s.CreateEnclave("SGX", 1<<20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, err := s.RequestAttestation(enclave.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.VerifyAttestation(enclave.ID, nonce); err != nil || !ok {
+		t.Fatalf("fresh nonce: %v, %v", ok, err)
+	}
+	if _, err := s.VerifyAttestation(enclave.ID, nonce); !errors.Is(err, ErrNonceUnknown) {
+		t.Errorf("reused nonce: err = %v, want ErrNonceUnknown", err)
+	}
+}
+
+func TestAttestationNonceBoundToEnclave(t *testing.T) {
+	s := NewConfidentialComputeService()
+	a, _ := s.CreateEnclave("SGX", 1<<20, 1)
+	b, _ := s.CreateEnclave("SGX", 1<<20, 1)
+	nonce, _ := s.RequestAttestation(a.ID)
+	if _, err := s.VerifyAttestation(b.ID, nonce); !errors.Is(err, ErrNonceUnknown) {
+		t.Errorf("another enclave's nonce: err = %v", err)
+	}
+	if _, err := s.VerifyAttestation(a.ID, []byte("never issued")); !errors.Is(err, ErrNonceUnknown) {
+		t.Errorf("unissued nonce: err = %v", err)
+	}
+}
+
+func TestAttestationNonceExpires(t *testing.T) {
+	s := NewConfidentialComputeService()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Now = func() time.Time { return now }
+	enclave, _ := s.CreateEnclave("TDX", 1<<20, 1)
+
+	nonce, err := s.RequestAttestation(enclave.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(AttestationNonceTTL)
+	if _, err := s.VerifyAttestation(enclave.ID, nonce); !errors.Is(err, ErrNonceExpired) {
+		t.Fatalf("expired nonce: err = %v, want ErrNonceExpired", err)
+	}
+
+	nonce, _ = s.RequestAttestation(enclave.ID)
+	now = now.Add(AttestationNonceTTL - time.Second)
+	if ok, err := s.VerifyAttestation(enclave.ID, nonce); err != nil || !ok {
+		t.Errorf("nonce inside its TTL: %v, %v", ok, err)
+	}
+}
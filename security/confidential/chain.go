@@ -0,0 +1,143 @@
+package confidential
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/corridoros/security/pqc"
+)
+
+// Attestation chain failures; each makes VerifyAttestationChain refuse
+var (
+	ErrChainBroken   = errors.New("attestation chain broken")
+	ErrUntrustedRoot = errors.New("attestation chain root is not a trust anchor")
+)
+
+// PublicKeyRef is one link of an attestation chain: a Dilithium public key
+// and, for every link but the root, the Endorsement the next key up the
+// chain made of it with EndorseKey
+type PublicKeyRef struct {
+	KeyID       string `json:"key_id"`
+	PublicKey   []byte `json:"public_key"`
+	Endorsement []byte `json:"endorsement,omitempty"`
+}
+
+// Domain separation for the messages chain links sign, so an attestation
+// signature can never pass as an endorsement or the other way round
+const (
+	attestationContext = "corridoros attestation v1"
+	endorsementContext = "corridoros key endorsement v1"
+)
+
+// AddTrustAnchor trusts a Dilithium public key as the root of attestation
+// chains, returning its key ID
+func (s *ConfidentialComputeService) AddTrustAnchor(publicKey []byte) (string, error) {
+	if err := pqc.ValidateDilithiumPublicKey(publicKey); err != nil {
+		return "", err
+	}
+	keyID := pqc.GenerateKeyID(publicKey)
+	s.anchors[keyID] = bytes.Clone(publicKey)
+	return keyID, nil
+}
+
+// RemoveTrustAnchor stops trusting a root key
+func (s *ConfidentialComputeService) RemoveTrustAnchor(keyID string) {
+	delete(s.anchors, keyID)
+}
+
+// SignAttestation signs an attestation's evidence with a node's Dilithium
+// key, setting its Signature and SignerKeyID
+func SignAttestation(attestation *AttestationData, signer *pqc.DilithiumKeyPair) error {
+	signature, err := signer.Sign(attestationMessage(attestation))
+	if err != nil {
+		return fmt.Errorf("failed to sign attestation: %v", err)
+	}
+	attestation.Signature = signature
+	attestation.SignerKeyID = pqc.GenerateKeyID(signer.PublicKey)
+	return nil
+}
+
+// EndorseKey has parent vouch for a child Dilithium public key, returning
+// the child's chain link
+func EndorseKey(publicKey []byte, parent *pqc.DilithiumKeyPair) (PublicKeyRef, error) {
+	if err := pqc.ValidateDilithiumPublicKey(publicKey); err != nil {
+		return PublicKeyRef{}, err
+	}
+	endorsement, err := parent.Sign(endorsementMessage(publicKey))
+	if err != nil {
+		return PublicKeyRef{}, fmt.Errorf("failed to endorse key: %v", err)
+	}
+	return PublicKeyRef{
+		KeyID:       pqc.GenerateKeyID(publicKey),
+		PublicKey:   bytes.Clone(publicKey),
+		Endorsement: endorsement,
+	}, nil
+}
+
+// RootKeyRef returns the chain link for a root key, which carries no
+// endorsement
+func RootKeyRef(publicKey []byte) PublicKeyRef {
+	return PublicKeyRef{KeyID: pqc.GenerateKeyID(publicKey), PublicKey: bytes.Clone(publicKey)}
+}
+
+// VerifyAttestationChain checks that leaf was signed by chain[0], that each
+// chain[i] was endorsed by chain[i+1], and that the last link is a trust
+// anchor. The chain runs from the attesting node's key up to the root;
+// links out of that order fail their signature checks.
+func (s *ConfidentialComputeService) VerifyAttestationChain(leaf AttestationData, chain []PublicKeyRef) (bool, error) {
+	if len(chain) == 0 {
+		return false, fmt.Errorf("%w: empty chain", ErrChainBroken)
+	}
+	for i, link := range chain {
+		if link.KeyID != "" && link.KeyID != pqc.GenerateKeyID(link.PublicKey) {
+			return false, fmt.Errorf("%w: link %d key ID %s does not match its public key", ErrChainBroken, i, link.KeyID)
+		}
+	}
+
+	if len(leaf.Signature) == 0 {
+		return false, fmt.Errorf("%w: attestation is unsigned", ErrChainBroken)
+	}
+	if leaf.SignerKeyID != "" && leaf.SignerKeyID != pqc.GenerateKeyID(chain[0].PublicKey) {
+		return false, fmt.Errorf("%w: attestation signed by key %s, chain starts at %s", ErrChainBroken, leaf.SignerKeyID, pqc.GenerateKeyID(chain[0].PublicKey))
+	}
+	if !verifyDilithium(attestationMessage(&leaf), leaf.Signature, chain[0].PublicKey) {
+		return false, fmt.Errorf("%w: attestation signature does not verify under link 0", ErrChainBroken)
+	}
+
+	for i := 0; i+1 < len(chain); i++ {
+		if !verifyDilithium(endorsementMessage(chain[i].PublicKey), chain[i].Endorsement, chain[i+1].PublicKey) {
+			return false, fmt.Errorf("%w: link %d is not endorsed by link %d", ErrChainBroken, i, i+1)
+		}
+	}
+
+	root := chain[len(chain)-1]
+	anchor, trusted := s.anchors[pqc.GenerateKeyID(root.PublicKey)]
+	if !trusted || !bytes.Equal(anchor, root.PublicKey) {
+		return false, fmt.Errorf("%w: root key %s", ErrUntrustedRoot, pqc.GenerateKeyID(root.PublicKey))
+	}
+	return true, nil
+}
+
+func verifyDilithium(data, signature, publicKey []byte) bool {
+	return pqc.VerifySignature(data, &pqc.PQCSignature{Signature: signature, Algorithm: "dilithium"}, publicKey)
+}
+
+// attestationMessage encodes the attestation evidence a signature covers:
+// each field length-prefixed so no two attestations encode alike
+func attestationMessage(a *AttestationData) []byte {
+	var b bytes.Buffer
+	b.WriteString(attestationContext)
+	for _, field := range [][]byte{a.Quote, a.Report, a.PublicKey, a.Measurement, a.Nonce} {
+		binary.Write(&b, binary.BigEndian, uint32(len(field)))
+		b.Write(field)
+	}
+	binary.Write(&b, binary.BigEndian, a.Timestamp)
+	return b.Bytes()
+}
+
+// endorsementMessage encodes the statement that a key is endorsed
+func endorsementMessage(publicKey []byte) []byte {
+	return append([]byte(endorsementContext), publicKey...)
+}
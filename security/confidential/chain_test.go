@@ -0,0 +1,132 @@
+package confidential
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/corridoros/security/pqc"
+)
+
+// chainFixture is a root that endorses an intermediate, which endorses the
+// node key that signs an enclave's attestation
+type chainFixture struct {
+	s                  *ConfidentialComputeService
+	root, intermediate *pqc.DilithiumKeyPair
+	leaf               AttestationData
+	chain              []PublicKeyRef
+}
+
+func newChainFixture(t *testing.T) *chainFixture {
+	t.Helper()
+	keys := make([]*pqc.DilithiumKeyPair, 3)
+	for i := range keys {
+		kp, err := pqc.NewDilithiumKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = kp
+	}
+	node, intermediate, root := keys[0], keys[1], keys[2]
+
+	s := NewConfidentialComputeService()
+	enclave, err := s.CreateEnclave("SGX", 1<<20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := *enclave.Attestation
+	if err := SignAttestation(&leaf, node); err != nil {
+		t.Fatal(err)
+	}
+	nodeRef, err := EndorseKey(node.PublicKey, intermediate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intermediateRef, err := EndorseKey(intermediate.PublicKey, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddTrustAnchor(root.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	return &chainFixture{
+		s:            s,
+		root:         root,
+		intermediate: intermediate,
+		leaf:         leaf,
+		chain:        []PublicKeyRef{nodeRef, intermediateRef, RootKeyRef(root.PublicKey)},
+	}
+}
+
+func TestValidChainVerifies(t *testing.T) {
+	f := newChainFixture(t)
+	if ok, err := f.s.VerifyAttestationChain(f.leaf, f.chain); !ok || err != nil {
+		t.Fatalf("valid chain = %v, %v", ok, err)
+	}
+
+	// Two links: the node key endorsed directly by the root
+	node, _ := pqc.NewDilithiumKeyPair()
+	leaf := f.leaf
+	if err := SignAttestation(&leaf, node); err != nil {
+		t.Fatal(err)
+	}
+	nodeRef, _ := EndorseKey(node.PublicKey, f.root)
+	if ok, err := f.s.VerifyAttestationChain(leaf, []PublicKeyRef{nodeRef, RootKeyRef(f.root.PublicKey)}); !ok || err != nil {
+		t.Errorf("two-link chain = %v, %v", ok, err)
+	}
+}
+
+func TestTamperedMiddleLinkFails(t *testing.T) {
+	f := newChainFixture(t)
+	chain := append([]PublicKeyRef(nil), f.chain...)
+	middle := chain[1]
+	middle.Endorsement = append([]byte(nil), middle.Endorsement...)
+	middle.Endorsement[10] ^= 0x01
+	chain[1] = middle
+	if ok, err := f.s.VerifyAttestationChain(f.leaf, chain); ok || !errors.Is(err, ErrChainBroken) {
+		t.Errorf("tampered endorsement = %v, %v", ok, err)
+	}
+
+	// A different key in the middle breaks the link below it
+	other, _ := pqc.NewDilithiumKeyPair()
+	chain = append([]PublicKeyRef(nil), f.chain...)
+	chain[1], _ = EndorseKey(other.PublicKey, f.root)
+	if ok, err := f.s.VerifyAttestationChain(f.leaf, chain); ok || !errors.Is(err, ErrChainBroken) {
+		t.Errorf("substituted middle key = %v, %v", ok, err)
+	}
+}
+
+func TestChainOutOfOrderFails(t *testing.T) {
+	f := newChainFixture(t)
+	swapped := []PublicKeyRef{f.chain[1], f.chain[0], f.chain[2]}
+	if ok, err := f.s.VerifyAttestationChain(f.leaf, swapped); ok || !errors.Is(err, ErrChainBroken) {
+		t.Errorf("swapped links = %v, %v", ok, err)
+	}
+	if ok, err := f.s.VerifyAttestationChain(f.leaf, nil); ok || !errors.Is(err, ErrChainBroken) {
+		t.Errorf("empty chain = %v, %v", ok, err)
+	}
+
+	tampered := f.leaf
+	tampered.Measurement = append([]byte(nil), tampered.Measurement...)
+	tampered.Measurement[0] ^= 0xff
+	if ok, err := f.s.VerifyAttestationChain(tampered, f.chain); ok || !errors.Is(err, ErrChainBroken) {
+		t.Errorf("tampered attestation = %v, %v", ok, err)
+	}
+}
+
+func TestUntrustedRootFails(t *testing.T) {
+	f := newChainFixture(t)
+
+	// A well-formed chain whose root was never registered
+	rogue, _ := pqc.NewDilithiumKeyPair()
+	intermediateRef, _ := EndorseKey(f.intermediate.PublicKey, rogue)
+	chain := []PublicKeyRef{f.chain[0], intermediateRef, RootKeyRef(rogue.PublicKey)}
+	if ok, err := f.s.VerifyAttestationChain(f.leaf, chain); ok || !errors.Is(err, ErrUntrustedRoot) {
+		t.Errorf("unregistered root = %v, %v", ok, err)
+	}
+
+	// Removing the anchor untrusts the original chain
+	f.s.RemoveTrustAnchor(f.chain[2].KeyID)
+	if ok, err := f.s.VerifyAttestationChain(f.leaf, f.chain); ok || !errors.Is(err, ErrUntrustedRoot) {
+		t.Errorf("removed anchor = %v, %v", ok, err)
+	}
+}
@@ -3,10 +3,17 @@ package confidential
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hkdf"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"time"
+
+	"github.com/corridoros/security/pqc"
 )
 
 // Enclave represents a secure enclave
@@ -31,6 +38,11 @@ type AttestationData struct {
 	Nonce        []byte `json:"nonce"`
 	Timestamp    int64  `json:"timestamp"`
 	Validated    bool   `json:"validated"`
+	// Signature is a Dilithium signature over the evidence above by the
+	// key SignerKeyID, the first link of the chain VerifyAttestationChain
+	// checks; see SignAttestation
+	Signature   []byte `json:"signature,omitempty"`
+	SignerKeyID string `json:"signer_key_id,omitempty"`
 }
 
 // Secret represents a confidential secret
@@ -46,37 +58,139 @@ type Secret struct {
 	AccessCount int64             `json:"access_count"`
 }
 
+// enclaveView is the redacted JSON form of an Enclave. Secret bytes and
+// attestation evidence never leave the service through encoding.
+type enclaveView struct {
+	ID                   string `json:"id"`
+	Type                 string `json:"type"`
+	Status               string `json:"status"`
+	MemorySize           int64  `json:"memory_size"`
+	CPUCount             int    `json:"cpu_count"`
+	SecretCount          int    `json:"secret_count"`
+	AttestationValidated bool   `json:"attestation_validated"`
+	AttestationTimestamp int64  `json:"attestation_timestamp,omitempty"`
+	CreatedAt            int64  `json:"created_at"`
+	LastUsed             int64  `json:"last_used"`
+}
+
+// MarshalJSON encodes only the enclave's safe metadata
+func (e Enclave) MarshalJSON() ([]byte, error) {
+	view := enclaveView{
+		ID:          e.ID,
+		Type:        e.Type,
+		Status:      e.Status,
+		MemorySize:  e.MemorySize,
+		CPUCount:    e.CPUCount,
+		SecretCount: len(e.Secrets),
+		CreatedAt:   e.CreatedAt,
+		LastUsed:    e.LastUsed,
+	}
+	if e.Attestation != nil {
+		view.AttestationValidated = e.Attestation.Validated
+		view.AttestationTimestamp = e.Attestation.Timestamp
+	}
+	return json.Marshal(view)
+}
+
+// secretView is the redacted JSON form of a Secret
+type secretView struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`
+	EnclaveID   string            `json:"enclave_id"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	CreatedAt   int64             `json:"created_at"`
+	LastUsed    int64             `json:"last_used"`
+	AccessCount int64             `json:"access_count"`
+}
+
+// MarshalJSON encodes the secret's metadata without its encrypted value
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(secretView{
+		ID:          s.ID,
+		Name:        s.Name,
+		Type:        s.Type,
+		EnclaveID:   s.EnclaveID,
+		Metadata:    s.Metadata,
+		CreatedAt:   s.CreatedAt,
+		LastUsed:    s.LastUsed,
+		AccessCount: s.AccessCount,
+	})
+}
+
 // ConfidentialComputeService manages confidential computing
 type ConfidentialComputeService struct {
 	enclaves map[string]*Enclave
 	secrets  map[string]*Secret
 	keys     map[string][]byte // encryption keys
+	kemKeys  map[string]*pqc.KyberKeyPair
+	nonces   map[string]attestationNonce // outstanding, by hex nonce
+	anchors  map[string][]byte           // trusted root keys, by key ID
+	// splitKeys holds a digest of each master key split by SplitEnclaveKey
+	// in place of the key, by enclave ID
+	splitKeys map[string][]byte
+
+	// Rand is the entropy source for IDs, keys and nonces. It defaults to
+	// crypto/rand.Reader; tests may inject a deterministic reader.
+	Rand io.Reader
+	// Now is the clock for timestamps and nonce expiry. It defaults to
+	// time.Now; tests may inject a fixed or stepped clock.
+	Now func() time.Time
 }
 
 // NewConfidentialComputeService creates a new confidential compute service
 func NewConfidentialComputeService() *ConfidentialComputeService {
 	return &ConfidentialComputeService{
-		enclaves: make(map[string]*Enclave),
-		secrets:  make(map[string]*Secret),
-		keys:     make(map[string][]byte),
+		enclaves:  make(map[string]*Enclave),
+		secrets:   make(map[string]*Secret),
+		keys:      make(map[string][]byte),
+		kemKeys:   make(map[string]*pqc.KyberKeyPair),
+		nonces:    make(map[string]attestationNonce),
+		anchors:   make(map[string][]byte),
+		splitKeys: make(map[string][]byte),
+		Rand:      rand.Reader,
+		Now:       time.Now,
 	}
 }
 
 // CreateEnclave creates a new secure enclave
 func (s *ConfidentialComputeService) CreateEnclave(enclaveType string, memorySize int64, cpuCount int) (*Enclave, error) {
 	// Generate enclave ID
-	enclaveID := s.generateID()
+	enclaveID, err := s.generateID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate enclave ID: %v", err)
+	}
 
 	// Create attestation data (simplified)
 	attestation := &AttestationData{
-		Quote:       s.generateRandomBytes(64),
-		Report:      s.generateRandomBytes(128),
-		PublicKey:   s.generateRandomBytes(32),
-		Measurement: s.generateRandomBytes(32),
-		Nonce:       s.generateRandomBytes(16),
-		Timestamp:   s.getCurrentTimestamp(),
-		Validated:   true, // Simplified - always valid
+		Timestamp: s.getCurrentTimestamp(),
+		Validated: true, // Simplified - always valid
+	}
+	for _, field := range []struct {
+		dst    *[]byte
+		length int
+	}{
+		{&attestation.Quote, 64},
+		{&attestation.Report, 128},
+		{&attestation.Measurement, 32},
+		{&attestation.Nonce, 16},
+	} {
+		if *field.dst, err = s.generateRandomBytes(field.length); err != nil {
+			return nil, fmt.Errorf("failed to generate attestation data: %v", err)
+		}
+	}
+
+	// The attested public key is the enclave's Kyber key, used by clients
+	// to establish sessions with EstablishSession
+	seed, err := s.generateRandomBytes(64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate enclave key: %v", err)
+	}
+	kemKey, err := pqc.NewKyberKeyPairFromSeed(seed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate enclave key: %v", err)
 	}
+	attestation.PublicKey = kemKey.PublicKey
 
 	// Create enclave
 	enclave := &Enclave{
@@ -92,6 +206,7 @@ func (s *ConfidentialComputeService) CreateEnclave(enclaveType string, memorySiz
 	}
 
 	s.enclaves[enclaveID] = enclave
+	s.kemKeys[enclaveID] = kemKey
 	return enclave, nil
 }
 
@@ -143,12 +258,15 @@ func (s *ConfidentialComputeService) StoreSecret(enclaveID string, name string,
 	}
 
 	// Generate secret ID
-	secretID := s.generateID()
+	secretID, err := s.generateID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret ID: %v", err)
+	}
 
 	// Encrypt the secret
 	encryptedValue, err := s.encryptSecret(value, enclaveID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to encrypt secret: %v", err)
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
 	}
 
 	// Create secret
@@ -186,7 +304,7 @@ func (s *ConfidentialComputeService) RetrieveSecret(secretID string) ([]byte, er
 	// Decrypt the secret
 	decryptedValue, err := s.decryptSecret(secret.Value, secret.EnclaveID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt secret: %v", err)
+		return nil, fmt.Errorf("failed to decrypt secret: %w", err)
 	}
 
 	// Update access statistics
@@ -232,25 +350,176 @@ func (s *ConfidentialComputeService) DeleteSecret(secretID string) error {
 	return nil
 }
 
-// VerifyAttestation verifies enclave attestation
-func (s *ConfidentialComputeService) VerifyAttestation(enclaveID string) (bool, error) {
+// AttestationNonceTTL is how long a nonce from RequestAttestation stays
+// valid for VerifyAttestation
+const AttestationNonceTTL = time.Minute
+
+// Attestation nonce failures; each makes VerifyAttestation refuse
+var (
+	ErrNonceUnknown = errors.New("attestation nonce unknown or already used")
+	ErrNonceExpired = errors.New("attestation nonce expired")
+)
+
+// attestationNonce is an issued, unconsumed attestation challenge
+type attestationNonce struct {
+	enclaveID string
+	expiresAt time.Time
+}
+
+// RequestAttestation issues a one-time nonce that the next VerifyAttestation
+// of the enclave must present within AttestationNonceTTL, so a recorded
+// attestation cannot be replayed
+func (s *ConfidentialComputeService) RequestAttestation(enclaveID string) ([]byte, error) {
+	enclave, exists := s.enclaves[enclaveID]
+	if !exists {
+		return nil, fmt.Errorf("enclave %s not found", enclaveID)
+	}
+	if enclave.Status != "active" {
+		return nil, fmt.Errorf("enclave %s is %s", enclaveID, enclave.Status)
+	}
+
+	nonce, err := s.generateRandomBytes(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate attestation nonce: %v", err)
+	}
+	now := s.now()
+	for key, issued := range s.nonces {
+		if !now.Before(issued.expiresAt) {
+			delete(s.nonces, key)
+		}
+	}
+	s.nonces[hex.EncodeToString(nonce)] = attestationNonce{
+		enclaveID: enclaveID,
+		expiresAt: now.Add(AttestationNonceTTL),
+	}
+	return nonce, nil
+}
+
+// VerifyAttestation verifies enclave attestation against a nonce issued by
+// RequestAttestation for the same enclave. The nonce is consumed whether or
+// not verification succeeds.
+func (s *ConfidentialComputeService) VerifyAttestation(enclaveID string, nonce []byte) (bool, error) {
 	enclave, exists := s.enclaves[enclaveID]
 	if !exists {
 		return false, fmt.Errorf("enclave %s not found", enclaveID)
 	}
 
+	key := hex.EncodeToString(nonce)
+	issued, exists := s.nonces[key]
+	if !exists || issued.enclaveID != enclaveID {
+		return false, ErrNonceUnknown
+	}
+	delete(s.nonces, key)
+	if !s.now().Before(issued.expiresAt) {
+		return false, ErrNonceExpired
+	}
+
 	// Simplified verification - in production, implement proper attestation verification
 	return enclave.Attestation.Validated, nil
 }
 
-// encryptSecret encrypts a secret using AES-GCM
-func (s *ConfidentialComputeService) encryptSecret(plaintext []byte, enclaveID string) ([]byte, error) {
-	// Get or generate encryption key for enclave
+// EstablishSession establishes a session key with an enclave from its Kyber
+// public key. A fresh shared secret is encapsulated to the key, the session
+// key is derived from it via HKDF and registered for encryptSecret under
+// enclaveID. The returned KEM ciphertext is handed to the enclave, which
+// derives the same key with AcceptSession.
+func (s *ConfidentialComputeService) EstablishSession(enclaveID string, kyberPublicKey []byte) ([]byte, error) {
+	enclave, exists := s.enclaves[enclaveID]
+	if !exists {
+		return nil, fmt.Errorf("enclave %s not found", enclaveID)
+	}
+	if len(enclave.Secrets) > 0 {
+		return nil, fmt.Errorf("enclave %s already holds secrets under its current key", enclaveID)
+	}
+	if _, split := s.splitKeys[enclaveID]; split {
+		return nil, fmt.Errorf("enclave %s: %w", enclaveID, ErrKeySplit)
+	}
+
+	sharedSecret, ciphertext, err := pqc.Encapsulate(kyberPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := deriveSessionKey(sharedSecret, enclaveID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive session key: %v", err)
+	}
+	s.keys[enclaveID] = key
+
+	return ciphertext, nil
+}
+
+// AcceptSession is the enclave side of EstablishSession. It decapsulates the
+// client's ciphertext with the enclave's Kyber key and registers the derived
+// session key, returning it to the caller.
+func (s *ConfidentialComputeService) AcceptSession(enclaveID string, ciphertext []byte) ([]byte, error) {
+	enclave, exists := s.enclaves[enclaveID]
+	if !exists {
+		return nil, fmt.Errorf("enclave %s not found", enclaveID)
+	}
+	if len(enclave.Secrets) > 0 {
+		return nil, fmt.Errorf("enclave %s already holds secrets under its current key", enclaveID)
+	}
+	if _, split := s.splitKeys[enclaveID]; split {
+		return nil, fmt.Errorf("enclave %s: %w", enclaveID, ErrKeySplit)
+	}
+
+	kemKey, exists := s.kemKeys[enclaveID]
+	if !exists {
+		return nil, fmt.Errorf("kyber key for enclave %s not found", enclaveID)
+	}
+
+	sharedSecret, err := kemKey.Decapsulate(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decapsulate session secret: %v", err)
+	}
+
+	key, err := deriveSessionKey(sharedSecret, enclaveID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive session key: %v", err)
+	}
+	s.keys[enclaveID] = key
+
+	return key, nil
+}
+
+// SessionKey returns the encryption key currently registered for an enclave
+func (s *ConfidentialComputeService) SessionKey(enclaveID string) ([]byte, bool) {
+	key, exists := s.keys[enclaveID]
+	return key, exists
+}
+
+// deriveSessionKey expands a KEM shared secret into a 256-bit AES key bound
+// to the enclave ID
+func deriveSessionKey(sharedSecret []byte, enclaveID string) ([]byte, error) {
+	return hkdf.Key(sha256.New, sharedSecret, []byte(enclaveID), "corridoros confidential session v1", 32)
+}
+
+// encryptionKey returns the enclave's encryption key, generating one if it
+// has neither a session key nor an earlier generated key
+func (s *ConfidentialComputeService) encryptionKey(enclaveID string) ([]byte, error) {
+	if _, split := s.splitKeys[enclaveID]; split {
+		return nil, fmt.Errorf("enclave %s: %w", enclaveID, ErrKeySplit)
+	}
 	key, exists := s.keys[enclaveID]
 	if !exists {
-		key = s.generateRandomBytes(32) // 256-bit key
+		var err error
+		key, err = s.generateRandomBytes(32) // 256-bit key
+		if err != nil {
+			return nil, err
+		}
 		s.keys[enclaveID] = key
 	}
+	return key, nil
+}
+
+// encryptSecret encrypts a secret using AES-GCM
+func (s *ConfidentialComputeService) encryptSecret(plaintext []byte, enclaveID string) ([]byte, error) {
+	// Get or generate encryption key for enclave
+	key, err := s.encryptionKey(enclaveID)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create AES cipher
 	block, err := aes.NewCipher(key)
@@ -265,7 +534,10 @@ func (s *ConfidentialComputeService) encryptSecret(plaintext []byte, enclaveID s
 	}
 
 	// Generate nonce
-	nonce := s.generateRandomBytes(gcm.NonceSize())
+	nonce, err := s.generateRandomBytes(gcm.NonceSize())
+	if err != nil {
+		return nil, err
+	}
 
 	// Encrypt
 	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
@@ -276,6 +548,9 @@ func (s *ConfidentialComputeService) encryptSecret(plaintext []byte, enclaveID s
 // decryptSecret decrypts a secret using AES-GCM
 func (s *ConfidentialComputeService) decryptSecret(ciphertext []byte, enclaveID string) ([]byte, error) {
 	// Get encryption key for enclave
+	if _, split := s.splitKeys[enclaveID]; split {
+		return nil, fmt.Errorf("enclave %s: %w", enclaveID, ErrKeySplit)
+	}
 	key, exists := s.keys[enclaveID]
 	if !exists {
 		return nil, fmt.Errorf("encryption key for enclave %s not found", enclaveID)
@@ -311,25 +586,39 @@ func (s *ConfidentialComputeService) decryptSecret(ciphertext []byte, enclaveID
 }
 
 // generateID generates a unique ID
-func (s *ConfidentialComputeService) generateID() string {
-	randomBytes := s.generateRandomBytes(16)
-	return hex.EncodeToString(randomBytes)
+func (s *ConfidentialComputeService) generateID() (string, error) {
+	randomBytes, err := s.generateRandomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(randomBytes), nil
 }
 
-// generateRandomBytes generates cryptographically secure random bytes
-func (s *ConfidentialComputeService) generateRandomBytes(length int) []byte {
+// generateRandomBytes reads length bytes from the service entropy source.
+// An RNG failure is returned to the caller rather than masked.
+func (s *ConfidentialComputeService) generateRandomBytes(length int) ([]byte, error) {
+	source := s.Rand
+	if source == nil {
+		source = rand.Reader
+	}
 	bytes := make([]byte, length)
-	if _, err := rand.Read(bytes); err != nil {
-		// Fallback to deterministic generation for testing
-		hash := sha256.Sum256([]byte(fmt.Sprintf("random_%d", length)))
-		copy(bytes, hash[:length])
+	if _, err := io.ReadFull(source, bytes); err != nil {
+		return nil, fmt.Errorf("entropy source failure: %v", err)
 	}
-	return bytes
+	return bytes, nil
 }
 
 // getCurrentTimestamp returns current timestamp
 func (s *ConfidentialComputeService) getCurrentTimestamp() int64 {
-	return 1640995200 // Simplified - use current time in production
+	return s.now().Unix()
+}
+
+// now reads the service clock
+func (s *ConfidentialComputeService) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
 }
 
 // GetSupportedEnclaveTypes returns supported enclave types
@@ -337,50 +626,58 @@ func GetSupportedEnclaveTypes() []string {
 	return []string{"SGX", "SEV", "TDX", "ARM_CCA", "RISC-V_Keystone"}
 }
 
+// EnclaveTypeInfo describes an enclave technology
+type EnclaveTypeInfo struct {
+	Name        string   `json:"name"`
+	Vendor      string   `json:"vendor"`
+	Description string   `json:"description"`
+	Features    []string `json:"features"`
+}
+
 // GetEnclaveTypeInfo returns information about an enclave type
-func GetEnclaveTypeInfo(enclaveType string) map[string]interface{} {
+func GetEnclaveTypeInfo(enclaveType string) EnclaveTypeInfo {
 	switch enclaveType {
 	case "SGX":
-		return map[string]interface{}{
-			"name":        "Intel SGX",
-			"vendor":      "Intel",
-			"description": "Intel Software Guard Extensions",
-			"features":    []string{"memory encryption", "attestation", "sealing"},
+		return EnclaveTypeInfo{
+			Name:        "Intel SGX",
+			Vendor:      "Intel",
+			Description: "Intel Software Guard Extensions",
+			Features:    []string{"memory encryption", "attestation", "sealing"},
 		}
 	case "SEV":
-		return map[string]interface{}{
-			"name":        "AMD SEV",
-			"vendor":      "AMD",
-			"description": "AMD Secure Encrypted Virtualization",
-			"features":    []string{"VM encryption", "attestation", "key management"},
+		return EnclaveTypeInfo{
+			Name:        "AMD SEV",
+			Vendor:      "AMD",
+			Description: "AMD Secure Encrypted Virtualization",
+			Features:    []string{"VM encryption", "attestation", "key management"},
 		}
 	case "TDX":
-		return map[string]interface{}{
-			"name":        "Intel TDX",
-			"vendor":      "Intel",
-			"description": "Intel Trust Domain Extensions",
-			"features":    []string{"VM isolation", "attestation", "memory protection"},
+		return EnclaveTypeInfo{
+			Name:        "Intel TDX",
+			Vendor:      "Intel",
+			Description: "Intel Trust Domain Extensions",
+			Features:    []string{"VM isolation", "attestation", "memory protection"},
 		}
 	case "ARM_CCA":
-		return map[string]interface{}{
-			"name":        "ARM CCA",
-			"vendor":      "ARM",
-			"description": "ARM Confidential Compute Architecture",
-			"features":    []string{"realm isolation", "attestation", "secure boot"},
+		return EnclaveTypeInfo{
+			Name:        "ARM CCA",
+			Vendor:      "ARM",
+			Description: "ARM Confidential Compute Architecture",
+			Features:    []string{"realm isolation", "attestation", "secure boot"},
 		}
 	case "RISC-V_Keystone":
-		return map[string]interface{}{
-			"name":        "RISC-V Keystone",
-			"vendor":      "RISC-V",
-			"description": "RISC-V Keystone Enclave Framework",
-			"features":    []string{"enclave isolation", "attestation", "secure boot"},
+		return EnclaveTypeInfo{
+			Name:        "RISC-V Keystone",
+			Vendor:      "RISC-V",
+			Description: "RISC-V Keystone Enclave Framework",
+			Features:    []string{"enclave isolation", "attestation", "secure boot"},
 		}
 	default:
-		return map[string]interface{}{
-			"name":        "Unknown",
-			"vendor":      "Unknown",
-			"description": "Unsupported enclave type",
-			"features":    []string{},
+		return EnclaveTypeInfo{
+			Name:        "Unknown",
+			Vendor:      "Unknown",
+			Description: "Unsupported enclave type",
+			Features:    []string{},
 		}
 	}
 }
@@ -0,0 +1,203 @@
+package confidential
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+)
+
+// leaks reports whether data contains b raw, base64 or hex encoded.
+func leaks(data, b []byte) bool {
+	return bytes.Contains(data, b) ||
+		bytes.Contains(data, []byte(base64.StdEncoding.EncodeToString(b))) ||
+		bytes.Contains(data, []byte(hex.EncodeToString(b)))
+}
+
+func TestMarshalOmitsSecretBytes(t *testing.T) {
+	s := NewConfidentialComputeService()
+	enclave, err := s.CreateEnclave("SGX", 1<<20, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("correct horse battery staple")
+	secret, err := s.StoreSecret(enclave.ID, "db", "key", plaintext, map[string]string{"owner": "ops"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forbidden := map[string][]byte{
+		"plaintext":   plaintext,
+		"ciphertext":  secret.Value,
+		"key":         s.keys[enclave.ID],
+		"quote":       enclave.Attestation.Quote,
+		"report":      enclave.Attestation.Report,
+		"measurement": enclave.Attestation.Measurement,
+	}
+
+	for name, v := range map[string]any{
+		"enclave":      enclave,
+		"secret":       secret,
+		"enclave list": s.ListEnclaves(),
+		"secret value": *secret,
+	} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for what, b := range forbidden {
+			if leaks(data, b) {
+				t.Errorf("%s JSON contains the %s: %s", name, what, data)
+			}
+		}
+	}
+}
+
+func TestMarshalKeepsMetadata(t *testing.T) {
+	s := NewConfidentialComputeService()
+	enclave, _ := s.CreateEnclave("TDX", 1<<20, 1)
+	if _, err := s.StoreSecret(enclave.ID, "a", "data", []byte("x"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]any
+	data, _ := json.Marshal(enclave)
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["id"] != enclave.ID || got["secret_count"] != float64(1) || got["attestation_validated"] != true {
+		t.Errorf("enclave JSON = %s", data)
+	}
+	if _, ok := got["secrets"]; ok {
+		t.Errorf("enclave JSON exposes a secrets field: %s", data)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("entropy exhausted") }
+
+func TestCreateEnclaveFailsOnRNGFailure(t *testing.T) {
+	s := NewConfidentialComputeService()
+	s.Rand = failingReader{}
+	enclave, err := s.CreateEnclave("SGX", 1<<20, 1)
+	if err == nil || enclave != nil {
+		t.Fatalf("CreateEnclave with a failing reader = %v, %v; want an error", enclave, err)
+	}
+	if len(s.enclaves) != 0 {
+		t.Errorf("failed creation registered %d enclaves", len(s.enclaves))
+	}
+}
+
+func TestStoreSecretFailsOnRNGFailure(t *testing.T) {
+	s := NewConfidentialComputeService()
+	enclave, err := s.CreateEnclave("SGX", 1<<20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One ID's worth of entropy, then failure before the key is drawn.
+	s.Rand = io.MultiReader(bytes.NewReader(make([]byte, 16)), failingReader{})
+	if _, err := s.StoreSecret(enclave.ID, "a", "key", []byte("x"), nil); err == nil {
+		t.Fatal("StoreSecret with a failing reader succeeded")
+	}
+	if _, ok := s.keys[enclave.ID]; ok {
+		t.Error("a key was stored despite the RNG failure")
+	}
+}
+
+func TestInjectedReaderIsDeterministic(t *testing.T) {
+	ids := make([]string, 2)
+	for i := range ids {
+		s := NewConfidentialComputeService()
+		s.Rand = bytes.NewReader(bytes.Repeat([]byte{0x5a}, 4096))
+		enclave, err := s.CreateEnclave("SEV", 1<<20, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = enclave.ID
+	}
+	if ids[0] != ids[1] {
+		t.Errorf("seeded services produced different IDs %q and %q", ids[0], ids[1])
+	}
+}
+
+func TestSessionBothSidesDeriveSameKey(t *testing.T) {
+	s := NewConfidentialComputeService()
+	enclave, err := s.CreateEnclave("SGX", 1<<20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Client side: encapsulate to the attested Kyber key.
+	ciphertext, err := s.EstablishSession(enclave.ID, enclave.Attestation.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKey, ok := s.SessionKey(enclave.ID)
+	if !ok || len(clientKey) != 32 {
+		t.Fatalf("client session key = %x, %v", clientKey, ok)
+	}
+
+	// Enclave side: decapsulate the ciphertext handed back by the client.
+	enclaveKey, err := s.AcceptSession(enclave.ID, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(clientKey, enclaveKey) {
+		t.Fatalf("session keys differ:\nclient  %x\nenclave %x", clientKey, enclaveKey)
+	}
+
+	secret, err := s.StoreSecret(enclave.ID, "a", "data", []byte("payload"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.RetrieveSecret(secret.ID); err != nil || string(got) != "payload" {
+		t.Errorf("RetrieveSecret under the session key = %q, %v", got, err)
+	}
+}
+
+func TestSessionTamperedCiphertextDerivesDifferentKey(t *testing.T) {
+	s := NewConfidentialComputeService()
+	enclave, _ := s.CreateEnclave("SGX", 1<<20, 1)
+	ciphertext, err := s.EstablishSession(enclave.ID, enclave.Attestation.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKey, _ := s.SessionKey(enclave.ID)
+	clientKey = bytes.Clone(clientKey)
+
+	ciphertext[0] ^= 0xff
+	enclaveKey, err := s.AcceptSession(enclave.ID, ciphertext)
+	if err == nil && bytes.Equal(clientKey, enclaveKey) {
+		t.Error("a tampered ciphertext derived the client's session key")
+	}
+}
+
+func TestEstablishSessionUnknownEnclave(t *testing.T) {
+	s := NewConfidentialComputeService()
+	enclave, _ := s.CreateEnclave("SGX", 1<<20, 1)
+	_, err := s.EstablishSession("missing", enclave.Attestation.PublicKey)
+	if err == nil || err.Error() != "enclave missing not found" {
+		t.Fatalf("EstablishSession(missing) error = %v", err)
+	}
+	if _, ok := s.SessionKey("missing"); ok {
+		t.Error("a session key was registered for an unknown enclave")
+	}
+}
+
+func TestEnclaveTypeInfoIsStable(t *testing.T) {
+	for _, typ := range GetSupportedEnclaveTypes() {
+		first, _ := json.Marshal(GetEnclaveTypeInfo(typ))
+		for i := 0; i < 5; i++ {
+			if again, _ := json.Marshal(GetEnclaveTypeInfo(typ)); !bytes.Equal(again, first) {
+				t.Fatalf("%s: %s != %s", typ, again, first)
+			}
+		}
+	}
+	if info := GetEnclaveTypeInfo("SEV"); info.Vendor != "AMD" || len(info.Features) == 0 {
+		t.Errorf("SEV info = %+v", info)
+	}
+}
@@ -1,3 +1,7 @@
 module github.com/corridoros/security/confidential
 
-go 1.21
+go 1.27
+
+require github.com/corridoros/security/pqc v0.0.0
+
+replace github.com/corridoros/security/pqc => ../pqc
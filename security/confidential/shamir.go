@@ -0,0 +1,184 @@
+package confidential
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+// Shares of an enclave master key are laid out as
+//
+//	version | threshold | x | len(enclave ID) | enclave ID | y...
+//
+// where y holds one point of a random degree threshold-1 polynomial over
+// GF(2^8) per key byte, whose constant term is that byte.
+const (
+	shareVersion    = 1
+	shareHeaderSize = 4
+	maxKeyShares    = 255 // x coordinates are the nonzero bytes
+)
+
+// ErrKeySplit is returned for operations that need an enclave's master key
+// while it is split into shares
+var ErrKeySplit = errors.New("enclave master key is split into shares")
+
+// SplitEnclaveKey splits an enclave's master key into n Shamir shares, any
+// k of which RecombineEnclaveKey accepts to restore it. The service drops
+// the key once split and keeps only a digest to check recombinations
+// against, so its secrets cannot be read or added to until then.
+func (s *ConfidentialComputeService) SplitEnclaveKey(enclaveID string, n, k int) ([][]byte, error) {
+	if _, exists := s.enclaves[enclaveID]; !exists {
+		return nil, fmt.Errorf("enclave %s not found", enclaveID)
+	}
+	if k < 2 || k > n || n > maxKeyShares {
+		return nil, fmt.Errorf("need 2 <= k <= n <= %d shares, got k=%d n=%d", maxKeyShares, k, n)
+	}
+	if len(enclaveID) > 255 {
+		return nil, fmt.Errorf("enclave ID too long to encode in a share")
+	}
+	key, err := s.encryptionKey(enclaveID)
+	if err != nil {
+		return nil, err
+	}
+
+	// coefficients[i] are the k-1 random higher-order coefficients of the
+	// polynomial for key byte i
+	coefficients, err := s.generateRandomBytes(len(key) * (k - 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate share polynomial: %v", err)
+	}
+	defer clear(coefficients)
+
+	shares := make([][]byte, n)
+	for x := 1; x <= n; x++ {
+		share := make([]byte, 0, shareHeaderSize+len(enclaveID)+len(key))
+		share = append(share, shareVersion, byte(k), byte(x), byte(len(enclaveID)))
+		share = append(share, enclaveID...)
+		for i, secret := range key {
+			// Horner's rule from the highest coefficient down to the secret
+			y := byte(0)
+			for j := k - 2; j >= 0; j-- {
+				y = gfMul(y, byte(x)) ^ coefficients[i*(k-1)+j]
+			}
+			share = append(share, gfMul(y, byte(x))^secret)
+		}
+		shares[x-1] = share
+	}
+
+	s.splitKeys[enclaveID] = keyDigest(enclaveID, key)
+	delete(s.keys, enclaveID)
+	return shares, nil
+}
+
+// RecombineEnclaveKey restores an enclave's master key from at least the
+// threshold number of its shares. The shares name the enclave; a key that
+// does not match the one split is refused and the enclave stays split.
+func (s *ConfidentialComputeService) RecombineEnclaveKey(shares [][]byte) error {
+	if len(shares) == 0 {
+		return fmt.Errorf("no key shares given")
+	}
+	threshold, enclaveID, _, first, err := parseShare(shares[0])
+	if err != nil {
+		return fmt.Errorf("share 0: %v", err)
+	}
+	digest, split := s.splitKeys[enclaveID]
+	if !split {
+		return fmt.Errorf("enclave %s has no split master key", enclaveID)
+	}
+
+	xs := make([]byte, 0, threshold)
+	ys := make([][]byte, 0, threshold)
+	for i, share := range shares {
+		k, id, x, y, err := parseShare(share)
+		if err != nil {
+			return fmt.Errorf("share %d: %v", i, err)
+		}
+		if k != threshold || id != enclaveID || len(y) != len(first) {
+			return fmt.Errorf("share %d does not belong with share 0", i)
+		}
+		for _, seen := range xs {
+			if seen == x {
+				return fmt.Errorf("share %d duplicates an earlier share", i)
+			}
+		}
+		if len(xs) < threshold {
+			xs = append(xs, x)
+			ys = append(ys, y)
+		}
+	}
+	if len(xs) < threshold {
+		return fmt.Errorf("need %d shares to recombine the master key of enclave %s, got %d", threshold, enclaveID, len(xs))
+	}
+
+	// Lagrange interpolation at x = 0; in GF(2^8) subtraction is XOR
+	key := make([]byte, len(first))
+	for j, xj := range xs {
+		basis := byte(1)
+		for m, xm := range xs {
+			if m != j {
+				basis = gfMul(basis, gfMul(xm, gfInv(xm^xj)))
+			}
+		}
+		for i := range key {
+			key[i] ^= gfMul(ys[j][i], basis)
+		}
+	}
+
+	if subtle.ConstantTimeCompare(keyDigest(enclaveID, key), digest) != 1 {
+		clear(key)
+		return fmt.Errorf("key shares do not recombine to the master key of enclave %s", enclaveID)
+	}
+	s.keys[enclaveID] = key
+	delete(s.splitKeys, enclaveID)
+	return nil
+}
+
+// parseShare decodes a share into its threshold, enclave ID, x coordinate
+// and key bytes
+func parseShare(share []byte) (int, string, byte, []byte, error) {
+	if len(share) < shareHeaderSize || share[0] != shareVersion {
+		return 0, "", 0, nil, fmt.Errorf("not a version %d key share", shareVersion)
+	}
+	k, x, idLen := int(share[1]), share[2], int(share[3])
+	if k < 2 || x == 0 || len(share) <= shareHeaderSize+idLen {
+		return 0, "", 0, nil, fmt.Errorf("malformed key share")
+	}
+	id := string(share[shareHeaderSize : shareHeaderSize+idLen])
+	return k, id, x, share[shareHeaderSize+idLen:], nil
+}
+
+// keyDigest commits to a master key so recombinations can be checked
+// without keeping the key
+func keyDigest(enclaveID string, key []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte("corridoros enclave key check v1\x00" + enclaveID + "\x00"))
+	h.Write(key)
+	return h.Sum(nil)
+}
+
+// gfMul multiplies in GF(2^8) modulo the AES polynomial x^8+x^4+x^3+x+1
+func gfMul(a, b byte) byte {
+	var p byte
+	for b != 0 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return p
+}
+
+// gfInv is the multiplicative inverse in GF(2^8), a^254; a must be nonzero
+func gfInv(a byte) byte {
+	result := byte(1)
+	for i := 0; i < 254; i++ {
+		result = gfMul(result, a)
+	}
+	return result
+}
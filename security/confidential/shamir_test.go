@@ -0,0 +1,92 @@
+package confidential
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// splitFixture stores a secret in a new enclave and splits its master key
+// into n shares with threshold k
+func splitFixture(t *testing.T, n, k int) (*ConfidentialComputeService, *Secret, [][]byte) {
+	t.Helper()
+	s := NewConfidentialComputeService()
+	enclave, err := s.CreateEnclave("SGX", 1<<20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := s.StoreSecret(enclave.ID, "db", "key", []byte("payload"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares, err := s.SplitEnclaveKey(enclave.ID, n, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != n {
+		t.Fatalf("got %d shares, want %d", len(shares), n)
+	}
+	return s, secret, shares
+}
+
+func TestThresholdSharesRestoreKey(t *testing.T) {
+	for _, pick := range [][]int{{0, 1, 2}, {2, 4, 1}, {3, 0, 4}, {0, 1, 2, 3, 4}} {
+		s, secret, shares := splitFixture(t, 5, 3)
+		var subset [][]byte
+		for _, i := range pick {
+			subset = append(subset, shares[i])
+		}
+		if err := s.RecombineEnclaveKey(subset); err != nil {
+			t.Fatalf("shares %v: %v", pick, err)
+		}
+		if got, err := s.RetrieveSecret(secret.ID); err != nil || string(got) != "payload" {
+			t.Errorf("shares %v: RetrieveSecret = %q, %v", pick, got, err)
+		}
+	}
+}
+
+func TestFewerThanThresholdSharesFail(t *testing.T) {
+	s, secret, shares := splitFixture(t, 5, 3)
+	if err := s.RecombineEnclaveKey(shares[1:3]); err == nil {
+		t.Fatal("k-1 shares recombined the key")
+	}
+	if _, err := s.RetrieveSecret(secret.ID); !errors.Is(err, ErrKeySplit) {
+		t.Errorf("RetrieveSecret after a failed recombination = %v, want ErrKeySplit", err)
+	}
+	if _, ok := s.keys[secret.EnclaveID]; ok {
+		t.Error("the master key is held in whole while split")
+	}
+
+	// A duplicated share does not count towards the threshold
+	if err := s.RecombineEnclaveKey([][]byte{shares[0], shares[1], shares[0]}); err == nil {
+		t.Error("a duplicated share made up the threshold")
+	}
+}
+
+func TestTamperedShareIsRefused(t *testing.T) {
+	s, secret, shares := splitFixture(t, 3, 2)
+	tampered := bytes.Clone(shares[1])
+	tampered[len(tampered)-1] ^= 0x01
+	if err := s.RecombineEnclaveKey([][]byte{shares[0], tampered}); err == nil {
+		t.Fatal("a tampered share recombined")
+	}
+	if _, err := s.StoreSecret(secret.EnclaveID, "b", "key", []byte("x"), nil); !errors.Is(err, ErrKeySplit) {
+		t.Errorf("StoreSecret while split = %v, want ErrKeySplit", err)
+	}
+	if err := s.RecombineEnclaveKey([][]byte{shares[0], shares[2]}); err != nil {
+		t.Fatalf("good shares after a refused attempt: %v", err)
+	}
+}
+
+func TestSplitValidation(t *testing.T) {
+	s := NewConfidentialComputeService()
+	enclave, _ := s.CreateEnclave("SGX", 1<<20, 1)
+	for _, nk := range [][2]int{{3, 1}, {2, 3}, {256, 2}} {
+		if _, err := s.SplitEnclaveKey(enclave.ID, nk[0], nk[1]); err == nil {
+			t.Errorf("n=%d k=%d accepted", nk[0], nk[1])
+		}
+	}
+	if _, err := s.SplitEnclaveKey("missing", 3, 2); err == nil {
+		t.Error("split an unknown enclave")
+	}
+}
@@ -0,0 +1,202 @@
+package confidential
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Stream format: a header of streamMagic, the plaintext chunk size
+// (uint32) and a random base nonce, then one frame per chunk holding a
+// final-chunk flag byte, the ciphertext length (uint32) and the AES-GCM
+// ciphertext. Chunk i is sealed under the base nonce XOR i, with the header,
+// i and the flag as additional data, so reordered, dropped, duplicated or
+// truncated chunks fail authentication.
+const (
+	streamMagic = "COS1"
+
+	// DefaultStreamChunkSize is the plaintext chunk size used when none is given
+	DefaultStreamChunkSize = 64 * 1024
+	// MaxStreamChunkSize bounds the chunk size, and so the memory a
+	// decryptor commits to a single chunk
+	MaxStreamChunkSize = 16 * 1024 * 1024
+)
+
+// ErrStreamAuth marks a stream that fails authentication: tampered,
+// reordered or truncated chunks, or a wrong key
+var ErrStreamAuth = errors.New("stream authentication failed")
+
+// EncryptStream encrypts r to w in chunks of chunkSize bytes (0 for
+// DefaultStreamChunkSize) under a 128, 192 or 256-bit AES key. Only one
+// chunk is held in memory at a time.
+func EncryptStream(w io.Writer, r io.Reader, key []byte, chunkSize int) error {
+	return encryptStream(w, r, key, chunkSize, rand.Reader)
+}
+
+// DecryptStream decrypts a stream written by EncryptStream. Chunks are
+// written to w as each authenticates, so on error w may hold a prefix of
+// the plaintext that must be discarded.
+func DecryptStream(w io.Writer, r io.Reader, key []byte) error {
+	gcm, err := newStreamGCM(key)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, len(streamMagic)+4+gcm.NonceSize())
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("%w: reading header: %v", ErrStreamAuth, err)
+	}
+	if string(header[:len(streamMagic)]) != streamMagic {
+		return fmt.Errorf("not an encrypted stream")
+	}
+	chunkSize := int(binary.BigEndian.Uint32(header[len(streamMagic):]))
+	if chunkSize <= 0 || chunkSize > MaxStreamChunkSize {
+		return fmt.Errorf("stream chunk size %d out of range", chunkSize)
+	}
+	baseNonce := header[len(streamMagic)+4:]
+
+	frame := make([]byte, 5)
+	ciphertext := make([]byte, 0, chunkSize+gcm.Overhead())
+	for counter := uint64(0); ; counter++ {
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return fmt.Errorf("%w: stream truncated before final chunk", ErrStreamAuth)
+		}
+		final := frame[0]
+		size := int(binary.BigEndian.Uint32(frame[1:]))
+		if final > 1 || size > chunkSize+gcm.Overhead() {
+			return fmt.Errorf("%w: malformed chunk %d", ErrStreamAuth, counter)
+		}
+		ciphertext = ciphertext[:size]
+		if _, err := io.ReadFull(r, ciphertext); err != nil {
+			return fmt.Errorf("%w: chunk %d truncated", ErrStreamAuth, counter)
+		}
+
+		plaintext, err := gcm.Open(ciphertext[:0], chunkNonce(baseNonce, counter), ciphertext, chunkAD(header, counter, final))
+		if err != nil {
+			return fmt.Errorf("%w: chunk %d", ErrStreamAuth, counter)
+		}
+		if _, err := w.Write(plaintext); err != nil {
+			return err
+		}
+
+		if final == 1 {
+			if n, _ := r.Read(make([]byte, 1)); n != 0 {
+				return fmt.Errorf("%w: data after final chunk", ErrStreamAuth)
+			}
+			return nil
+		}
+	}
+}
+
+// EncryptStream encrypts r to w under the enclave's encryption key
+func (s *ConfidentialComputeService) EncryptStream(w io.Writer, r io.Reader, enclaveID string, chunkSize int) error {
+	if _, exists := s.enclaves[enclaveID]; !exists {
+		return fmt.Errorf("enclave %s not found", enclaveID)
+	}
+	key, err := s.encryptionKey(enclaveID)
+	if err != nil {
+		return err
+	}
+	source := s.Rand
+	if source == nil {
+		source = rand.Reader
+	}
+	return encryptStream(w, r, key, chunkSize, source)
+}
+
+// DecryptStream decrypts a stream encrypted under the enclave's key
+func (s *ConfidentialComputeService) DecryptStream(w io.Writer, r io.Reader, enclaveID string) error {
+	key, exists := s.keys[enclaveID]
+	if !exists {
+		return fmt.Errorf("encryption key for enclave %s not found", enclaveID)
+	}
+	return DecryptStream(w, r, key)
+}
+
+func encryptStream(w io.Writer, r io.Reader, key []byte, chunkSize int, entropy io.Reader) error {
+	if chunkSize == 0 {
+		chunkSize = DefaultStreamChunkSize
+	}
+	if chunkSize < 0 || chunkSize > MaxStreamChunkSize {
+		return fmt.Errorf("chunk size must be in (0, %d]", MaxStreamChunkSize)
+	}
+	gcm, err := newStreamGCM(key)
+	if err != nil {
+		return err
+	}
+
+	baseNonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(entropy, baseNonce); err != nil {
+		return fmt.Errorf("failed to generate stream nonce: %w", err)
+	}
+	var header bytes.Buffer
+	header.WriteString(streamMagic)
+	binary.Write(&header, binary.BigEndian, uint32(chunkSize))
+	header.Write(baseNonce)
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+
+	in := bufio.NewReaderSize(r, chunkSize)
+	plaintext := make([]byte, chunkSize)
+	sealed := make([]byte, 0, 5+chunkSize+gcm.Overhead())
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(in, plaintext)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		// A short read ends the input; after a full chunk, peek for more
+		final := byte(0)
+		if n < chunkSize {
+			final = 1
+		} else if _, err := in.Peek(1); err == io.EOF {
+			final = 1
+		} else if err != nil {
+			return err
+		}
+
+		sealed = append(sealed[:0], final, 0, 0, 0, 0)
+		sealed = gcm.Seal(sealed, chunkNonce(baseNonce, counter), plaintext[:n], chunkAD(header.Bytes(), counter, final))
+		binary.BigEndian.PutUint32(sealed[1:5], uint32(len(sealed)-5))
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if final == 1 {
+			return nil
+		}
+	}
+}
+
+func newStreamGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce derives chunk counter's nonce by XORing the counter into the
+// low 8 bytes of the base nonce
+func chunkNonce(base []byte, counter uint64) []byte {
+	nonce := append([]byte(nil), base...)
+	var c [8]byte
+	binary.BigEndian.PutUint64(c[:], counter)
+	for i := range c {
+		nonce[len(nonce)-8+i] ^= c[i]
+	}
+	return nonce
+}
+
+// chunkAD binds a chunk to its stream header, position and finality
+func chunkAD(header []byte, counter uint64, final byte) []byte {
+	ad := make([]byte, 0, len(header)+9)
+	ad = append(ad, header...)
+	ad = binary.BigEndian.AppendUint64(ad, counter)
+	return append(ad, final)
+}
@@ -0,0 +1,99 @@
+package confidential
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+const testChunkSize = 64 * 1024
+
+// encrypted returns size random bytes and their encryption under key
+func encrypted(t *testing.T, key []byte, size int) (plaintext, ciphertext []byte) {
+	t.Helper()
+	plaintext = make([]byte, size)
+	rand.Read(plaintext)
+	var out bytes.Buffer
+	if err := EncryptStream(&out, bytes.NewReader(plaintext), key, testChunkSize); err != nil {
+		t.Fatal(err)
+	}
+	return plaintext, out.Bytes()
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	// A multi-megabyte stream with a partial final chunk, and the edge
+	// cases of an empty stream and an exact multiple of the chunk size
+	for _, size := range []int{3<<20 + 12345, 0, 4 * testChunkSize} {
+		plaintext, ciphertext := encrypted(t, key, size)
+		var out bytes.Buffer
+		if err := DecryptStream(&out, bytes.NewReader(ciphertext), key); err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if !bytes.Equal(out.Bytes(), plaintext) {
+			t.Errorf("%d bytes: decrypted stream differs", size)
+		}
+	}
+}
+
+func TestStreamRejectsSwappedChunks(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	_, ciphertext := encrypted(t, key, 3*testChunkSize+100)
+
+	header := len(streamMagic) + 4 + 12
+	frame := 5 + testChunkSize + 16
+	first := ciphertext[header : header+frame]
+	second := ciphertext[header+frame : header+2*frame]
+	swapped := bytes.Clone(ciphertext[:header])
+	swapped = append(append(append(swapped, second...), first...), ciphertext[header+2*frame:]...)
+
+	err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(swapped), key)
+	if !errors.Is(err, ErrStreamAuth) {
+		t.Fatalf("swapped chunks: err = %v, want ErrStreamAuth", err)
+	}
+}
+
+func TestStreamRejectsTruncationAndWrongKey(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	_, ciphertext := encrypted(t, key, 2*testChunkSize+100)
+	header := len(streamMagic) + 4 + 12
+	frame := 5 + testChunkSize + 16
+
+	if err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(ciphertext[:header+2*frame]), key); !errors.Is(err, ErrStreamAuth) {
+		t.Errorf("dropped final chunk: err = %v", err)
+	}
+	if err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(append(bytes.Clone(ciphertext), 0)), key); !errors.Is(err, ErrStreamAuth) {
+		t.Errorf("trailing data: err = %v", err)
+	}
+	other := make([]byte, 32)
+	rand.Read(other)
+	if err := DecryptStream(&bytes.Buffer{}, bytes.NewReader(ciphertext), other); !errors.Is(err, ErrStreamAuth) {
+		t.Errorf("wrong key: err = %v", err)
+	}
+}
+
+func TestEnclaveStreamUsesEnclaveKey(t *testing.T) {
+	s := NewConfidentialComputeService()
+	enclave, err := s.CreateEnclave("SGX", 1<<20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := bytes.Repeat([]byte("secret "), 50000)
+	var sealed, opened bytes.Buffer
+	if err := s.EncryptStream(&sealed, bytes.NewReader(plaintext), enclave.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DecryptStream(&opened, &sealed, enclave.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened.Bytes(), plaintext) {
+		t.Error("enclave stream round trip differs")
+	}
+	if err := s.EncryptStream(&bytes.Buffer{}, bytes.NewReader(plaintext), "missing", 0); err == nil {
+		t.Error("encrypted to an unknown enclave")
+	}
+}
@@ -0,0 +1,52 @@
+package pqc
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// MaxBatchKeyPairs bounds a single GenerateKeyPairsBatch call
+const MaxBatchKeyPairs = 4096
+
+// GenerateKeyPairsBatch generates count key pairs for algorithm across a
+// pool of GOMAXPROCS workers, each pair from its own RNG reads. Pairs are
+// returned in generation-slot order. level is the NIST security level; 0
+// selects the default, and only level 3 is currently implemented.
+func GenerateKeyPairsBatch(algorithm string, level, count int) ([]*PQCKeyPair, error) {
+	// Initialize the scheme up front so workers share it
+	if _, err := schemeFor(algorithm, level); err != nil {
+		return nil, err
+	}
+	if count < 1 || count > MaxBatchKeyPairs {
+		return nil, fmt.Errorf("batch count must be between 1 and %d", MaxBatchKeyPairs)
+	}
+
+	workers := min(runtime.GOMAXPROCS(0), count)
+	pairs := make([]*PQCKeyPair, count)
+	errs := make([]error, count)
+	slots := make(chan int)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range slots {
+				pairs[i], errs[i] = GeneratePQCKeyPair(algorithm)
+			}
+		}()
+	}
+	for i := range count {
+		slots <- i
+	}
+	close(slots)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("key pair %d of %d: %v", i+1, count, err)
+		}
+	}
+	return pairs, nil
+}
@@ -0,0 +1,83 @@
+package pqc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGenerateKeyPairsBatchValidAndDistinct(t *testing.T) {
+	const count = 32
+	pairs, err := GenerateKeyPairsBatch("dilithium", 0, count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != count {
+		t.Fatalf("got %d pairs, want %d", len(pairs), count)
+	}
+	seen := map[string]bool{}
+	data := []byte("batch")
+	for i, pair := range pairs {
+		if pair == nil || pair.Algorithm != "dilithium" {
+			t.Fatalf("pair %d = %+v", i, pair)
+		}
+		if seen[string(pair.PublicKey)] || seen[string(pair.PrivateKey)] {
+			t.Fatalf("pair %d repeats an earlier key", i)
+		}
+		seen[string(pair.PublicKey)], seen[string(pair.PrivateKey)] = true, true
+
+		sig, err := SignData(data, pair.PrivateKey, "dilithium")
+		if err != nil || !VerifySignature(data, sig, pair.PublicKey) {
+			t.Errorf("pair %d does not sign and verify: %v", i, err)
+		}
+	}
+}
+
+func TestGenerateKeyPairsBatchKyber(t *testing.T) {
+	pairs, err := GenerateKeyPairsBatch("kyber", 3, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, pair := range pairs {
+		secret, ciphertext, err := Encapsulate(pair.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kp := &KyberKeyPair{PrivateKey: pair.PrivateKey, PublicKey: pair.PublicKey}
+		if got, err := kp.Decapsulate(ciphertext); err != nil || !bytes.Equal(got, secret) {
+			t.Errorf("pair %d does not round-trip a shared secret: %v", i, err)
+		}
+	}
+}
+
+func TestGenerateKeyPairsBatchRejectsBadInput(t *testing.T) {
+	for name, call := range map[string]func() ([]*PQCKeyPair, error){
+		"zero count":      func() ([]*PQCKeyPair, error) { return GenerateKeyPairsBatch("kyber", 0, 0) },
+		"absurd count":    func() ([]*PQCKeyPair, error) { return GenerateKeyPairsBatch("kyber", 0, MaxBatchKeyPairs+1) },
+		"unknown algo":    func() ([]*PQCKeyPair, error) { return GenerateKeyPairsBatch("rsa", 0, 1) },
+		"unsupported lvl": func() ([]*PQCKeyPair, error) { return GenerateKeyPairsBatch("kyber", 5, 1) },
+	} {
+		if pairs, err := call(); err == nil || pairs != nil {
+			t.Errorf("%s: %d pairs, err %v", name, len(pairs), err)
+		}
+	}
+}
+
+func BenchmarkKeyGeneration(b *testing.B) {
+	const count = 64
+	b.Run("serial", func(b *testing.B) {
+		for range b.N {
+			for range count {
+				if _, err := GeneratePQCKeyPair("dilithium"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		for range b.N {
+			if _, err := GenerateKeyPairsBatch("dilithium", 0, count); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,77 @@
+// tls-endorse makes the post-quantum endorsement a CorridorOS server
+// presents alongside its TLS certificate.
+//
+//	tls-endorse -new-key endorse.key    # prints the public key clients pin
+//	tls-endorse -key endorse.key -cert server.pem -out endorsement.json
+//
+// The server is then started with -tls-cert server.pem -tls-key
+// server-key.pem -tls-endorsement endorsement.json. Endorse again whenever
+// the certificate is renewed; the server refuses a stale endorsement.
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/corridoros/security/pqc"
+	"github.com/corridoros/security/pqc/tlsendorse"
+)
+
+func main() {
+	newKey := flag.String("new-key", "", "generate an endorsement key into this file and print its public key")
+	keyFile := flag.String("key", "", "endorsement key file")
+	certFile := flag.String("cert", "", "PEM certificate chain to endorse; its first certificate is signed")
+	out := flag.String("out", "", "endorsement file to write (default stdout)")
+	flag.Parse()
+	log.SetFlags(0)
+
+	if *newKey != "" {
+		if err := pqc.EntropyHealthCheck(); err != nil {
+			log.Fatalf("entropy source: %v", err)
+		}
+		keys, err := pqc.NewDilithiumKeyPair()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := tlsendorse.WriteKey(*newKey, keys); err != nil {
+			log.Fatal(err)
+		}
+		printPublicKey(keys)
+		return
+	}
+
+	if *keyFile == "" || *certFile == "" {
+		log.Fatal("usage: tls-endorse -new-key FILE | -key FILE -cert FILE [-out FILE]")
+	}
+	keys, err := tlsendorse.LoadKey(*keyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cert, err := tlsendorse.LeafCertificate(*certFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	e, err := tlsendorse.Endorse(cert, keys)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, _ := json.MarshalIndent(e, "", "  ")
+	b = append(b, '\n')
+	if *out == "" {
+		os.Stdout.Write(b)
+		return
+	}
+	if err := os.WriteFile(*out, b, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// printPublicKey prints what clients pin to verify endorsements
+func printPublicKey(keys *pqc.DilithiumKeyPair) {
+	fmt.Printf("algorithm:  ML-DSA-65\nkey_id:     %s\npublic_key: %s\n",
+		pqc.GenerateKeyID(keys.PublicKey), base64.StdEncoding.EncodeToString(keys.PublicKey))
+}
@@ -0,0 +1,46 @@
+package pqc
+
+import (
+	"crypto/ed25519"
+	"fmt"
+)
+
+// Ed25519 is classical, not post-quantum. It is supported so signature
+// bundles can pair Dilithium with a scheme built on unrelated assumptions:
+// a bundle then holds up if either is broken.
+
+// newEd25519KeyPair generates an Ed25519 key pair; PrivateKey holds the
+// 32-byte seed, as for Dilithium
+func newEd25519KeyPair() (*PQCKeyPair, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	if err := readEntropy(seed); err != nil {
+		return nil, err
+	}
+	return &PQCKeyPair{
+		PrivateKey: seed,
+		PublicKey:  ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey),
+		Algorithm:  "ed25519",
+		KeySize:    len(seed),
+	}, nil
+}
+
+// signEd25519 signs data with the key derived from a 32-byte seed
+func signEd25519(data, seed []byte) (*PQCSignature, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid ed25519 private key: seed must be %d bytes", ed25519.SeedSize)
+	}
+	key := ed25519.NewKeyFromSeed(seed)
+	return &PQCSignature{
+		Signature: ed25519.Sign(key, data),
+		Algorithm: "ed25519",
+		KeyID:     GenerateKeyID(key.Public().(ed25519.PublicKey)),
+	}, nil
+}
+
+// verifyEd25519 checks an Ed25519 signature against an encoded public key
+func verifyEd25519(data, signature, publicKey []byte) bool {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(publicKey, data, signature)
+}
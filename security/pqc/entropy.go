@@ -0,0 +1,101 @@
+package pqc
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Entropy health check parameters. With a uniform source the chi-squared
+// statistic over 255 degrees of freedom averages 255 and exceeds
+// maxEntropyChiSquared with probability below 1e-8; a run of
+// maxEntropyRepeat identical bytes is about as unlikely.
+const (
+	entropySampleSize    = 4096
+	maxEntropyChiSquared = 400
+	maxEntropyRepeat     = 6
+	entropyReadTimeout   = 2 * time.Second
+)
+
+var (
+	entropyMu sync.RWMutex
+	entropy   io.Reader = rand.Reader
+)
+
+// SetEntropySource sets the reader all key generation, signing and
+// GenerateRandomBytes draw randomness from; nil restores crypto/rand.
+// It is meant for constrained platforms with their own hardware source,
+// and for tests.
+func SetEntropySource(r io.Reader) {
+	if r == nil {
+		r = rand.Reader
+	}
+	entropyMu.Lock()
+	defer entropyMu.Unlock()
+	entropy = r
+}
+
+// EntropySource returns the reader randomness is drawn from
+func EntropySource() io.Reader {
+	entropyMu.RLock()
+	defer entropyMu.RUnlock()
+	return entropy
+}
+
+// readEntropy fills b from the entropy source
+func readEntropy(b []byte) error {
+	if _, err := io.ReadFull(EntropySource(), b); err != nil {
+		return fmt.Errorf("entropy source: %v", err)
+	}
+	return nil
+}
+
+// EntropyHealthCheck reads a sample from the entropy source and fails on
+// obvious breakage: a read that errors or stalls, constant output, long
+// runs of one byte, or a byte distribution far from uniform. Passing does
+// not prove the source is good, only that it is not plainly broken, so
+// services can refuse to start rather than generate weak keys.
+func EntropyHealthCheck() error {
+	sample := make([]byte, entropySampleSize)
+	done := make(chan error, 1)
+	go func() { done <- readEntropy(sample) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+	case <-time.After(entropyReadTimeout):
+		return fmt.Errorf("entropy source stalled: no %d-byte sample within %v", entropySampleSize, entropyReadTimeout)
+	}
+
+	var counts [256]int
+	run, longest := 0, 0
+	for i, b := range sample {
+		counts[b]++
+		if i > 0 && b == sample[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+	if counts[sample[0]] == len(sample) {
+		return fmt.Errorf("entropy source is constant: %d bytes all 0x%02x", len(sample), sample[0])
+	}
+	if longest >= maxEntropyRepeat {
+		return fmt.Errorf("entropy source repeated one byte %d times in a row", longest)
+	}
+
+	expected := float64(len(sample)) / 256
+	chiSquared := 0.0
+	for _, c := range counts {
+		d := float64(c) - expected
+		chiSquared += d * d / expected
+	}
+	if chiSquared > maxEntropyChiSquared {
+		return fmt.Errorf("entropy source byte distribution is not uniform: chi-squared %.1f exceeds %d", chiSquared, maxEntropyChiSquared)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package pqc
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"testing"
+)
+
+// useEntropy injects r for the rest of the test
+func useEntropy(t *testing.T, r io.Reader) {
+	t.Helper()
+	SetEntropySource(r)
+	t.Cleanup(func() { SetEntropySource(nil) })
+}
+
+// zeroReader yields zero bytes forever
+type zeroReader struct{}
+
+func (zeroReader) Read(b []byte) (int, error) {
+	clear(b)
+	return len(b), nil
+}
+
+func TestEntropyHealthCheck(t *testing.T) {
+	for name, tc := range map[string]struct {
+		source io.Reader
+		ok     bool
+	}{
+		"crypto/rand": {nil, true},
+		"chacha8":     {rand.NewChaCha8([32]byte{1}), true},
+		"zero":        {zeroReader{}, false},
+		"short":       {bytes.NewReader(make([]byte, 100)), false},
+		"repeating":   {bytes.NewReader(bytes.Repeat([]byte{1, 2, 3, 4}, entropySampleSize)), false},
+	} {
+		useEntropy(t, tc.source)
+		if err := EntropyHealthCheck(); (err == nil) != tc.ok {
+			t.Errorf("%s: EntropyHealthCheck() = %v", name, err)
+		}
+	}
+}
+
+func TestKeyGenerationUsesEntropySource(t *testing.T) {
+	generate := func(alg string) *PQCKeyPair {
+		t.Helper()
+		useEntropy(t, rand.NewChaCha8([32]byte{7}))
+		pair, err := GeneratePQCKeyPair(alg)
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		return pair
+	}
+	for _, alg := range []string{"kyber", "dilithium", "ed25519"} {
+		a, b := generate(alg), generate(alg)
+		if !bytes.Equal(a.PrivateKey, b.PrivateKey) || !bytes.Equal(a.PublicKey, b.PublicKey) {
+			t.Errorf("%s: the same injected source produced different keys", alg)
+		}
+	}
+
+	useEntropy(t, zeroReader{})
+	if _, err := GenerateRandomBytes(16); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := GenerateRandomBytes(16); !bytes.Equal(got, make([]byte, 16)) {
+		t.Errorf("GenerateRandomBytes ignored the injected source: %x", got)
+	}
+
+	useEntropy(t, bytes.NewReader(nil))
+	if _, err := GeneratePQCKeyPair("dilithium"); err == nil {
+		t.Error("key generation succeeded from an exhausted source")
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/corridoros/security/pqc
 
-go 1.21
+go 1.27
@@ -0,0 +1,154 @@
+// Package httpsign signs HTTP response bodies with a Dilithium key so
+// clients can verify they were not altered in transit.
+package httpsign
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/corridoros/security/pqc"
+)
+
+// Response headers carrying the signature
+const (
+	SignatureHeader = "X-Corridoros-Signature" // base64 ML-DSA-65 signature over the body
+	KeyIDHeader     = "X-Corridoros-Key-Id"
+)
+
+// PublicKeyInfo is served from the public key endpoint
+type PublicKeyInfo struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"` // base64
+}
+
+// Signer signs response bodies with a Dilithium key pair
+type Signer struct {
+	keys  *pqc.DilithiumKeyPair
+	keyID string
+}
+
+// NewSigner creates a signer with a freshly generated key pair, refusing
+// to when the entropy source fails its health check
+func NewSigner() (*Signer, error) {
+	if err := pqc.EntropyHealthCheck(); err != nil {
+		return nil, err
+	}
+	keys, err := pqc.NewDilithiumKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{keys: keys, keyID: pqc.GenerateKeyID(keys.PublicKey)}, nil
+}
+
+// streamingTypes are content types written incrementally; buffering them to
+// sign the whole body would hold every event until the stream ends, so they
+// are passed through unsigned
+var streamingTypes = map[string]bool{
+	"text/event-stream":    true,
+	"application/x-ndjson": true,
+}
+
+// isStreaming reports whether a Content-Type header names a streaming type
+func isStreaming(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && streamingTypes[mediaType]
+}
+
+// bufferedResponse holds a handler's output until it has been signed, unless
+// the handler declares a streaming content type, in which case it writes
+// straight through to w
+type bufferedResponse struct {
+	w      http.ResponseWriter
+	status int
+	body   bytes.Buffer
+	stream bool
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.w.Header() }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status != 0 {
+		return
+	}
+	b.status = status
+	if isStreaming(b.w.Header().Get("Content-Type")) {
+		b.stream = true
+		b.w.WriteHeader(status)
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.WriteHeader(http.StatusOK)
+	}
+	if b.stream {
+		return b.w.Write(p)
+	}
+	return b.body.Write(p)
+}
+
+// Flush forwards flushes of a streamed response; buffered responses are
+// flushed once signed
+func (b *bufferedResponse) Flush() {
+	if b.status == 0 {
+		b.WriteHeader(http.StatusOK)
+	}
+	if f, ok := b.w.(http.Flusher); ok && b.stream {
+		f.Flush()
+	}
+}
+
+// Middleware buffers each response and attaches a signature over its body.
+// Streamed responses (text/event-stream, application/x-ndjson) are not signed.
+func (s *Signer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := &bufferedResponse{w: w}
+		next.ServeHTTP(buf, r)
+		if buf.stream {
+			return
+		}
+		if buf.status == 0 {
+			buf.status = http.StatusOK
+		}
+
+		signature, err := s.keys.Sign(buf.body.Bytes())
+		if err != nil {
+			http.Error(w, "response signing failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(SignatureHeader, base64.StdEncoding.EncodeToString(signature))
+		w.Header().Set(KeyIDHeader, s.keyID)
+		w.Header().Set("Content-Length", strconv.Itoa(buf.body.Len()))
+		w.WriteHeader(buf.status)
+		_, _ = w.Write(buf.body.Bytes())
+	})
+}
+
+// PublicKey describes the key responses are signed with
+func (s *Signer) PublicKey() PublicKeyInfo {
+	return PublicKeyInfo{
+		Algorithm: "ML-DSA-65",
+		KeyID:     s.keyID,
+		PublicKey: base64.StdEncoding.EncodeToString(s.keys.PublicKey),
+	}
+}
+
+// HandlePublicKey serves the signer's public key
+func (s *Signer) HandlePublicKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.PublicKey())
+}
+
+// Verify checks a base64 signature header value against a body
+func Verify(body []byte, signature string, publicKey []byte) bool {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return pqc.VerifySignature(body, &pqc.PQCSignature{Signature: raw, Algorithm: "dilithium"}, publicKey)
+}
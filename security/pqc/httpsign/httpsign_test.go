@@ -0,0 +1,85 @@
+package httpsign
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignedResponseVerifies(t *testing.T) {
+	signer, err := NewSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(signer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"result":42}`)
+	})))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get(KeyIDHeader) != signer.PublicKey().KeyID {
+		t.Fatalf("response %d, key id %q", resp.StatusCode, resp.Header.Get(KeyIDHeader))
+	}
+
+	publicKey, _ := base64.StdEncoding.DecodeString(signer.PublicKey().PublicKey)
+	signature := resp.Header.Get(SignatureHeader)
+	if !Verify(body, signature, publicKey) {
+		t.Fatal("signed body does not verify")
+	}
+	body[len(body)-2] = '3'
+	if Verify(body, signature, publicKey) {
+		t.Error("mutated body verified")
+	}
+}
+
+func TestStreamedResponsesFlushIncrementally(t *testing.T) {
+	signer, err := NewSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, contentType := range []string{"text/event-stream", "application/x-ndjson; charset=utf-8"} {
+		release := make(chan struct{})
+		srv := httptest.NewServer(signer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			io.WriteString(w, "first\n")
+			w.(http.Flusher).Flush()
+			<-release
+			io.WriteString(w, "second\n")
+		})))
+
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Header.Get(SignatureHeader) != "" {
+			t.Errorf("%s: streamed response carries a signature", contentType)
+		}
+		lines := make(chan string, 1)
+		go func() {
+			line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+			lines <- line
+		}()
+		select {
+		case line := <-lines:
+			if line != "first\n" {
+				t.Errorf("%s: first line = %q", contentType, line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("%s: first line was held back until the handler returned", contentType)
+		}
+		close(release)
+		resp.Body.Close()
+		srv.Close()
+	}
+}
@@ -0,0 +1,60 @@
+package pqc
+
+import "fmt"
+
+// KeyRef is one private key SignMulti signs with
+type KeyRef struct {
+	Algorithm  string `json:"algorithm"`
+	PrivateKey []byte `json:"private_key"`
+}
+
+// Bundle carries signatures over the same data under several algorithms,
+// so the data stays authenticated while at least some of them hold
+type Bundle struct {
+	Signatures []PQCSignature `json:"signatures"`
+}
+
+// SignMulti signs data with every key, one signature per key. Each key must
+// use a different algorithm: a bundle hedges against a scheme being broken,
+// which a second key of the same scheme does nothing for.
+func SignMulti(data []byte, keys []KeyRef) (Bundle, error) {
+	if len(keys) == 0 {
+		return Bundle{}, fmt.Errorf("signature bundle needs at least one key")
+	}
+	bundle := Bundle{Signatures: make([]PQCSignature, 0, len(keys))}
+	seen := make(map[string]bool, len(keys))
+	for i, key := range keys {
+		if seen[key.Algorithm] {
+			return Bundle{}, fmt.Errorf("key %d: algorithm %s is already in the bundle", i, key.Algorithm)
+		}
+		seen[key.Algorithm] = true
+
+		signature, err := SignData(data, key.PrivateKey, key.Algorithm)
+		if err != nil {
+			return Bundle{}, fmt.Errorf("key %d (%s): %v", i, key.Algorithm, err)
+		}
+		bundle.Signatures = append(bundle.Signatures, *signature)
+	}
+	return bundle, nil
+}
+
+// VerifyMulti reports whether signatures in bundle under at least threshold
+// distinct algorithms verify. pubKeys maps key IDs to public keys; a
+// signature counts only if its key ID is listed and matches the public key.
+// Repeated algorithms count once, and a threshold below 1 never passes.
+func VerifyMulti(data []byte, bundle Bundle, pubKeys map[string][]byte, threshold int) bool {
+	if threshold < 1 {
+		return false
+	}
+	passed := make(map[string]bool)
+	for _, signature := range bundle.Signatures {
+		publicKey, ok := pubKeys[signature.KeyID]
+		if !ok || signature.KeyID != GenerateKeyID(publicKey) {
+			continue
+		}
+		if VerifySignature(data, &signature, publicKey) {
+			passed[signature.Algorithm] = true
+		}
+	}
+	return len(passed) >= threshold
+}
@@ -0,0 +1,95 @@
+package pqc
+
+import (
+	"slices"
+	"testing"
+)
+
+// bundleFixture signs data with a Dilithium and an Ed25519 key
+func bundleFixture(t *testing.T, data []byte) (Bundle, map[string][]byte) {
+	t.Helper()
+	var keys []KeyRef
+	pubKeys := make(map[string][]byte)
+	for _, alg := range []string{"dilithium", "ed25519"} {
+		pair, err := GeneratePQCKeyPair(alg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, KeyRef{Algorithm: alg, PrivateKey: pair.PrivateKey})
+		pubKeys[GenerateKeyID(pair.PublicKey)] = pair.PublicKey
+	}
+	bundle, err := SignMulti(data, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bundle, pubKeys
+}
+
+func TestFullBundleVerifies(t *testing.T) {
+	data := []byte("corridor manifest")
+	bundle, pubKeys := bundleFixture(t, data)
+	if len(bundle.Signatures) != 2 {
+		t.Fatalf("bundle has %d signatures", len(bundle.Signatures))
+	}
+	for _, threshold := range []int{1, 2} {
+		if !VerifyMulti(data, bundle, pubKeys, threshold) {
+			t.Errorf("full bundle fails %d-of-2", threshold)
+		}
+	}
+	if VerifyMulti(data, bundle, pubKeys, 3) {
+		t.Error("two signatures passed a threshold of 3")
+	}
+	if VerifyMulti([]byte("other manifest"), bundle, pubKeys, 1) {
+		t.Error("bundle verified over different data")
+	}
+}
+
+func TestBrokenSignaturePassesOneOfTwoOnly(t *testing.T) {
+	data := []byte("corridor manifest")
+	for broken := range 2 {
+		bundle, pubKeys := bundleFixture(t, data)
+		bundle.Signatures[broken].Signature = slices.Clone(bundle.Signatures[broken].Signature)
+		bundle.Signatures[broken].Signature[0] ^= 0xff
+
+		alg := bundle.Signatures[broken].Algorithm
+		if !VerifyMulti(data, bundle, pubKeys, 1) {
+			t.Errorf("broken %s signature: bundle fails 1-of-2", alg)
+		}
+		if VerifyMulti(data, bundle, pubKeys, 2) {
+			t.Errorf("broken %s signature: bundle passes 2-of-2", alg)
+		}
+	}
+}
+
+func TestBundleCountsAlgorithmsOnce(t *testing.T) {
+	data := []byte("corridor manifest")
+	bundle, pubKeys := bundleFixture(t, data)
+	bundle.Signatures = []PQCSignature{bundle.Signatures[0], bundle.Signatures[0]}
+	if VerifyMulti(data, bundle, pubKeys, 2) {
+		t.Error("a repeated signature counted twice")
+	}
+
+	pair, _ := GeneratePQCKeyPair("dilithium")
+	keys := []KeyRef{{"dilithium", pair.PrivateKey}, {"dilithium", pair.PrivateKey}}
+	if _, err := SignMulti(data, keys); err == nil {
+		t.Error("SignMulti accepted two keys of the same algorithm")
+	}
+}
+
+func TestBundleIgnoresUnlistedKeys(t *testing.T) {
+	data := []byte("corridor manifest")
+	bundle, pubKeys := bundleFixture(t, data)
+	delete(pubKeys, bundle.Signatures[1].KeyID)
+	if VerifyMulti(data, bundle, pubKeys, 2) {
+		t.Error("a signature under an unlisted key counted")
+	}
+	if !VerifyMulti(data, bundle, pubKeys, 1) {
+		t.Error("the remaining listed key did not verify")
+	}
+}
+
+func TestSupportedAlgorithmsArePostQuantum(t *testing.T) {
+	if slices.Contains(GetSupportedAlgorithms(), "ed25519") {
+		t.Error("classical ed25519 is listed as a supported PQC algorithm")
+	}
+}
@@ -1,7 +1,8 @@
 package pqc
 
 import (
-	"crypto/rand"
+	"crypto/mldsa"
+	"crypto/mlkem"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
@@ -22,7 +23,8 @@ type PQCSignature struct {
 	KeyID     string `json:"key_id"`
 }
 
-// KyberKeyPair represents a Kyber key pair (simplified implementation)
+// KyberKeyPair represents a Kyber (ML-KEM-768) key pair. PrivateKey holds the
+// 64-byte decapsulation seed and PublicKey the encoded encapsulation key.
 type KyberKeyPair struct {
 	PrivateKey []byte
 	PublicKey  []byte
@@ -42,7 +44,8 @@ type KyberParams struct {
 	SeedBytes int  // seed bytes
 }
 
-// DilithiumKeyPair represents a Dilithium key pair (simplified implementation)
+// DilithiumKeyPair represents a Dilithium (ML-DSA-65) key pair. PrivateKey
+// holds the 32-byte seed the signing key is derived from.
 type DilithiumKeyPair struct {
 	PrivateKey []byte
 	PublicKey  []byte
@@ -65,72 +68,70 @@ type DilithiumParams struct {
 
 // NewKyberKeyPair creates a new Kyber key pair
 func NewKyberKeyPair() (*KyberKeyPair, error) {
-	// Simplified Kyber implementation
-	// In production, use a proper PQC library like liboqs or circl
-	params := KyberParams{
-		N:         256,
-		Q:         3329,
-		K:         2,
-		Eta1:      3,
-		Eta2:      2,
-		Du:        10,
-		Dv:        4,
-		PolyBytes: 384,
-		SeedBytes: 32,
-	}
-
-	// Generate random private key
-	privateKey := make([]byte, 32)
-	if _, err := rand.Read(privateKey); err != nil {
+	seed := make([]byte, mlkem.SeedSize)
+	if err := readEntropy(seed); err != nil {
 		return nil, err
 	}
+	return NewKyberKeyPairFromSeed(seed)
+}
 
-	// Generate public key (simplified)
-	publicKey := make([]byte, 32)
-	if _, err := rand.Read(publicKey); err != nil {
+// NewKyberKeyPairFromSeed deterministically derives a Kyber key pair from a
+// 64-byte seed, letting callers supply their own entropy source
+func NewKyberKeyPairFromSeed(seed []byte) (*KyberKeyPair, error) {
+	dk, err := mlkem.NewDecapsulationKey768(seed)
+	if err != nil {
 		return nil, err
 	}
 
 	return &KyberKeyPair{
-		PrivateKey: privateKey,
-		PublicKey:  publicKey,
-		Params:     params,
+		PrivateKey: dk.Bytes(),
+		PublicKey:  dk.EncapsulationKey().Bytes(),
+		Params:     mustScheme("kyber").kyber,
 	}, nil
 }
 
-// NewDilithiumKeyPair creates a new Dilithium key pair
-func NewDilithiumKeyPair() (*DilithiumKeyPair, error) {
-	// Simplified Dilithium implementation
-	// In production, use a proper PQC library
-	params := DilithiumParams{
-		N:         256,
-		Q:         8380417,
-		K:         4,
-		L:         4,
-		Eta:       2,
-		Gamma1:    131072,
-		Gamma2:    95232,
-		Omega:     80,
-		PolyBytes: 384,
-		SeedBytes: 32,
+// Encapsulate generates a fresh shared secret for the holder of a Kyber
+// public key, returning the secret and the ciphertext to send to them
+func Encapsulate(publicKey []byte) (sharedSecret []byte, ciphertext []byte, err error) {
+	ek, err := mlkem.NewEncapsulationKey768(publicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid kyber public key: %v", err)
 	}
+	sharedSecret, ciphertext = ek.Encapsulate()
+	return sharedSecret, ciphertext, nil
+}
+
+// Decapsulate recovers the shared secret encapsulated to this key pair
+func (k *KyberKeyPair) Decapsulate(ciphertext []byte) ([]byte, error) {
+	dk, err := mlkem.NewDecapsulationKey768(k.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kyber private key: %v", err)
+	}
+	return dk.Decapsulate(ciphertext)
+}
 
-	// Generate random private key
-	privateKey := make([]byte, 64)
-	if _, err := rand.Read(privateKey); err != nil {
+// NewDilithiumKeyPair creates a new Dilithium key pair
+func NewDilithiumKeyPair() (*DilithiumKeyPair, error) {
+	seed := make([]byte, mldsa.PrivateKeySize)
+	if err := readEntropy(seed); err != nil {
 		return nil, err
 	}
+	return NewDilithiumKeyPairFromSeed(seed)
+}
 
-	// Generate public key (simplified)
-	publicKey := make([]byte, 64)
-	if _, err := rand.Read(publicKey); err != nil {
+// NewDilithiumKeyPairFromSeed deterministically derives a Dilithium key pair
+// from a 32-byte seed
+func NewDilithiumKeyPairFromSeed(seed []byte) (*DilithiumKeyPair, error) {
+	scheme := mustScheme("dilithium")
+	sk, err := mldsa.NewPrivateKey(scheme.mldsa, seed)
+	if err != nil {
 		return nil, err
 	}
 
 	return &DilithiumKeyPair{
-		PrivateKey: privateKey,
-		PublicKey:  publicKey,
-		Params:     params,
+		PrivateKey: sk.Bytes(),
+		PublicKey:  sk.PublicKey().Bytes(),
+		Params:     scheme.dilithium,
 	}, nil
 }
 
@@ -151,18 +152,34 @@ func (k *KyberKeyPair) Decrypt(ciphertext []byte) ([]byte, error) {
 
 // Sign signs data using Dilithium
 func (d *DilithiumKeyPair) Sign(data []byte) ([]byte, error) {
-	// Simplified signing
-	// In production, implement proper Dilithium signing
-	hash := sha256.Sum256(append(data, d.PrivateKey...))
-	return hash[:], nil
+	sk, err := mldsa.NewPrivateKey(mustScheme("dilithium").mldsa, d.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dilithium private key: %v", err)
+	}
+	return sk.Sign(EntropySource(), data, &mldsa.Options{})
 }
 
 // Verify verifies a Dilithium signature
 func (d *DilithiumKeyPair) Verify(data []byte, signature []byte) bool {
-	// Simplified verification
-	// In production, implement proper Dilithium verification
-	expectedHash := sha256.Sum256(append(data, d.PrivateKey...))
-	return hex.EncodeToString(signature) == hex.EncodeToString(expectedHash[:])
+	return verifyDilithium(data, signature, d.PublicKey)
+}
+
+// verifyDilithium checks an ML-DSA-65 signature against an encoded public key
+func verifyDilithium(data, signature, publicKey []byte) bool {
+	pk, err := mldsa.NewPublicKey(mustScheme("dilithium").mldsa, publicKey)
+	if err != nil {
+		return false
+	}
+	return mldsa.Verify(pk, data, signature, &mldsa.Options{}) == nil
+}
+
+// ValidateDilithiumPublicKey checks that publicKey is an encoded ML-DSA-65
+// public key
+func ValidateDilithiumPublicKey(publicKey []byte) error {
+	if _, err := mldsa.NewPublicKey(mustScheme("dilithium").mldsa, publicKey); err != nil {
+		return fmt.Errorf("invalid dilithium public key: %v", err)
+	}
+	return nil
 }
 
 // GeneratePQCKeyPair generates a PQC key pair
@@ -192,6 +209,9 @@ func GeneratePQCKeyPair(algorithm string) (*PQCKeyPair, error) {
 			KeySize:    len(dilithiumPair.PrivateKey),
 		}, nil
 
+	case "ed25519":
+		return newEd25519KeyPair()
+
 	default:
 		return nil, fmt.Errorf("unsupported PQC algorithm: %s", algorithm)
 	}
@@ -201,14 +221,23 @@ func GeneratePQCKeyPair(algorithm string) (*PQCKeyPair, error) {
 func SignData(data []byte, privateKey []byte, algorithm string) (*PQCSignature, error) {
 	switch algorithm {
 	case "dilithium":
-		// Simplified Dilithium signing
-		hash := sha256.Sum256(append(data, privateKey...))
+		keyPair, err := NewDilithiumKeyPairFromSeed(privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dilithium private key: %v", err)
+		}
+		signature, err := keyPair.Sign(data)
+		if err != nil {
+			return nil, err
+		}
 		return &PQCSignature{
-			Signature: hash[:],
+			Signature: signature,
 			Algorithm: "dilithium",
-			KeyID:     hex.EncodeToString(privateKey[:8]),
+			KeyID:     GenerateKeyID(keyPair.PublicKey),
 		}, nil
 
+	case "ed25519":
+		return signEd25519(data, privateKey)
+
 	case "kyber":
 		// Kyber is for encryption, not signing
 		return nil, fmt.Errorf("kyber is not suitable for signing")
@@ -222,9 +251,10 @@ func SignData(data []byte, privateKey []byte, algorithm string) (*PQCSignature,
 func VerifySignature(data []byte, signature *PQCSignature, publicKey []byte) bool {
 	switch signature.Algorithm {
 	case "dilithium":
-		// Simplified Dilithium verification
-		expectedHash := sha256.Sum256(append(data, publicKey...))
-		return hex.EncodeToString(signature.Signature) == hex.EncodeToString(expectedHash[:])
+		return verifyDilithium(data, signature.Signature, publicKey)
+
+	case "ed25519":
+		return verifyEd25519(data, signature.Signature, publicKey)
 
 	default:
 		return false
@@ -234,7 +264,7 @@ func VerifySignature(data []byte, signature *PQCSignature, publicKey []byte) boo
 // GenerateRandomBytes generates cryptographically secure random bytes
 func GenerateRandomBytes(length int) ([]byte, error) {
 	bytes := make([]byte, length)
-	if _, err := rand.Read(bytes); err != nil {
+	if err := readEntropy(bytes); err != nil {
 		return nil, err
 	}
 	return bytes, nil
@@ -274,32 +304,49 @@ func GetSupportedAlgorithms() []string {
 	return []string{"kyber", "dilithium"}
 }
 
+// AlgorithmInfo describes a PQC algorithm
+type AlgorithmInfo struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Security    string `json:"security"`
+	KeySize     int    `json:"key_size"`
+	Description string `json:"description"`
+}
+
 // GetAlgorithmInfo returns information about a PQC algorithm
-func GetAlgorithmInfo(algorithm string) map[string]interface{} {
+func GetAlgorithmInfo(algorithm string) AlgorithmInfo {
 	switch algorithm {
 	case "kyber":
-		return map[string]interface{}{
-			"name":        "Kyber",
-			"type":        "KEM (Key Encapsulation Mechanism)",
-			"security":    "NIST Level 1-5",
-			"key_size":    32,
-			"description": "Post-quantum key encapsulation mechanism",
+		return AlgorithmInfo{
+			Name:        "Kyber",
+			Type:        "KEM (Key Encapsulation Mechanism)",
+			Security:    "NIST Level 3 (ML-KEM-768)",
+			KeySize:     64,
+			Description: "Post-quantum key encapsulation mechanism",
 		}
 	case "dilithium":
-		return map[string]interface{}{
-			"name":        "Dilithium",
-			"type":        "Digital Signature",
-			"security":    "NIST Level 1-5",
-			"key_size":    64,
-			"description": "Post-quantum digital signature scheme",
+		return AlgorithmInfo{
+			Name:        "Dilithium",
+			Type:        "Digital Signature",
+			Security:    "NIST Level 3 (ML-DSA-65)",
+			KeySize:     32,
+			Description: "Post-quantum digital signature scheme",
+		}
+	case "ed25519":
+		return AlgorithmInfo{
+			Name:        "Ed25519",
+			Type:        "Digital Signature",
+			Security:    "Classical (~128-bit), not quantum-resistant",
+			KeySize:     32,
+			Description: "Classical signature scheme, paired with Dilithium in signature bundles",
 		}
 	default:
-		return map[string]interface{}{
-			"name":        "Unknown",
-			"type":        "Unknown",
-			"security":    "Unknown",
-			"key_size":    0,
-			"description": "Unsupported algorithm",
+		return AlgorithmInfo{
+			Name:        "Unknown",
+			Type:        "Unknown",
+			Security:    "Unknown",
+			KeySize:     0,
+			Description: "Unsupported algorithm",
 		}
 	}
 }
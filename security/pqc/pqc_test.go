@@ -0,0 +1,23 @@
+package pqc
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAlgorithmInfoIsStable(t *testing.T) {
+	for _, alg := range append(GetSupportedAlgorithms(), "unknown") {
+		first, err := json.Marshal(GetAlgorithmInfo(alg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 5; i++ {
+			if again, _ := json.Marshal(GetAlgorithmInfo(alg)); string(again) != string(first) {
+				t.Fatalf("%s: %s != %s", alg, again, first)
+			}
+		}
+	}
+	if info := GetAlgorithmInfo("kyber"); info.Name != "Kyber" || info.KeySize != 64 {
+		t.Errorf("kyber info = %+v", info)
+	}
+}
@@ -0,0 +1,182 @@
+package pqc
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Key statuses in a KeyRegistry
+const (
+	KeyStatusActive   = "active"   // signs and verifies
+	KeyStatusRetiring = "retiring" // verifies only
+	KeyStatusRevoked  = "revoked"  // unusable
+)
+
+// RegisteredKey is a key pair held by a KeyRegistry
+type RegisteredKey struct {
+	ID        string      `json:"id"`
+	KeyPair   *PQCKeyPair `json:"-"`
+	CreatedAt time.Time   `json:"created_at"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	Status    string      `json:"status"`
+}
+
+// expired reports whether the key is past its expiry at now
+func (k *RegisteredKey) expired(now time.Time) bool {
+	return !now.Before(k.ExpiresAt)
+}
+
+// KeyRegistry holds the key pairs of one algorithm by ID, tracking their
+// expiry and rotation. Exactly one key is active at a time.
+type KeyRegistry struct {
+	mu        sync.RWMutex
+	algorithm string
+	lifetime  time.Duration
+	keys      map[string]*RegisteredKey
+	activeID  string
+	now       func() time.Time // clock for creation and expiry, replaced in tests
+}
+
+// NewKeyRegistry creates a registry for algorithm ("kyber" or "dilithium")
+// whose keys expire lifetime after creation, with a first active key
+func NewKeyRegistry(algorithm string, lifetime time.Duration) (*KeyRegistry, error) {
+	if lifetime <= 0 {
+		return nil, fmt.Errorf("key lifetime must be positive")
+	}
+	r := &KeyRegistry{
+		algorithm: algorithm,
+		lifetime:  lifetime,
+		keys:      make(map[string]*RegisteredKey),
+		now:       time.Now,
+	}
+	if _, err := r.Rotate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Rotate generates a new active key and marks the previous active key
+// retiring, so signatures it made still verify until it expires
+func (r *KeyRegistry) Rotate() (*RegisteredKey, error) {
+	keyPair, err := GeneratePQCKeyPair(r.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	now := r.now().UTC()
+	key := &RegisteredKey{
+		ID:        GenerateKeyID(keyPair.PublicKey),
+		KeyPair:   keyPair,
+		CreatedAt: now,
+		ExpiresAt: now.Add(r.lifetime),
+		Status:    KeyStatusActive,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if prev, ok := r.keys[r.activeID]; ok {
+		prev.Status = KeyStatusRetiring
+	}
+	r.keys[key.ID] = key
+	r.activeID = key.ID
+	out := *key
+	return &out, nil
+}
+
+// Revoke makes a key unusable for signing and verification. Revoking the
+// active key leaves the registry without one until the next Rotate.
+func (r *KeyRegistry) Revoke(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return fmt.Errorf("unknown key: %s", id)
+	}
+	key.Status = KeyStatusRevoked
+	if r.activeID == id {
+		r.activeID = ""
+	}
+	return nil
+}
+
+// Active returns the active key, failing if it was revoked or has expired
+func (r *KeyRegistry) Active() (*RegisteredKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[r.activeID]
+	if !ok {
+		return nil, fmt.Errorf("no active %s key; rotate to create one", r.algorithm)
+	}
+	if key.expired(r.now()) {
+		return nil, fmt.Errorf("active key %s expired at %s; rotate to replace it", key.ID, key.ExpiresAt.Format(time.RFC3339))
+	}
+	out := *key
+	return &out, nil
+}
+
+// Get returns a key by ID
+func (r *KeyRegistry) Get(id string) (*RegisteredKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, false
+	}
+	out := *key
+	return &out, true
+}
+
+// Keys lists every key, oldest first
+func (r *KeyRegistry) Keys() []RegisteredKey {
+	r.mu.RLock()
+	keys := make([]RegisteredKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, *key)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// Sign signs data with the active key
+func (r *KeyRegistry) Sign(data []byte) (*PQCSignature, error) {
+	key, err := r.Active()
+	if err != nil {
+		return nil, err
+	}
+	return SignData(data, key.KeyPair.PrivateKey, r.algorithm)
+}
+
+// Verify checks a signature against the key named by its KeyID, which must
+// be active or retiring and unexpired
+func (r *KeyRegistry) Verify(data []byte, signature *PQCSignature) bool {
+	key, ok := r.Get(signature.KeyID)
+	if !ok || key.Status == KeyStatusRevoked || key.expired(r.now()) {
+		return false
+	}
+	return VerifySignature(data, signature, key.KeyPair.PublicKey)
+}
+
+// Decapsulate recovers a shared secret encapsulated to the Kyber key keyID,
+// which must be active or retiring and unexpired
+func (r *KeyRegistry) Decapsulate(keyID string, ciphertext []byte) ([]byte, error) {
+	if r.algorithm != "kyber" {
+		return nil, fmt.Errorf("%s keys do not decapsulate", r.algorithm)
+	}
+	key, ok := r.Get(keyID)
+	if !ok {
+		return nil, fmt.Errorf("unknown key: %s", keyID)
+	}
+	if key.Status == KeyStatusRevoked || key.expired(r.now()) {
+		return nil, fmt.Errorf("key %s is revoked or expired", keyID)
+	}
+	kyberPair := &KyberKeyPair{PrivateKey: key.KeyPair.PrivateKey, PublicKey: key.KeyPair.PublicKey}
+	return kyberPair.Decapsulate(ciphertext)
+}
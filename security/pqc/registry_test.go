@@ -0,0 +1,142 @@
+package pqc
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable clock for a KeyRegistry
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// registry returns a dilithium registry of hour-long keys on a fake clock
+func registry(t *testing.T) (*KeyRegistry, *fakeClock) {
+	t.Helper()
+	r, err := NewKeyRegistry("dilithium", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{t: time.Now()}
+	r.now = clock.now
+	return r, clock
+}
+
+func TestRotationKeepsOldSignaturesVerifying(t *testing.T) {
+	r, _ := registry(t)
+	data := []byte("corridor cor-1 allocated")
+	old, err := r.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := r.Active()
+
+	second, err := r.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID == first.ID {
+		t.Fatal("rotation reused the active key")
+	}
+	if key, _ := r.Get(first.ID); key.Status != KeyStatusRetiring {
+		t.Errorf("rotated-out key status = %s, want retiring", key.Status)
+	}
+	if !r.Verify(data, old) {
+		t.Error("signature from before rotation no longer verifies")
+	}
+
+	fresh, err := r.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.KeyID != second.ID {
+		t.Errorf("new signature uses key %s, want %s", fresh.KeyID, second.ID)
+	}
+	if !r.Verify(data, fresh) {
+		t.Error("signature from the new key does not verify")
+	}
+}
+
+func TestRetiringKeyVerifiesUntilExpiry(t *testing.T) {
+	r, clock := registry(t)
+	data := []byte("grace window")
+	old, _ := r.Sign(data)
+
+	clock.advance(30 * time.Minute)
+	if _, err := r.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	clock.advance(29 * time.Minute)
+	if !r.Verify(data, old) {
+		t.Fatal("retiring key rejected inside its grace window")
+	}
+
+	clock.advance(2 * time.Minute)
+	if r.Verify(data, old) {
+		t.Error("retiring key still verifies after its expiry")
+	}
+	if _, err := r.Active(); err != nil {
+		t.Errorf("active key rotated in at 30m expired early: %v", err)
+	}
+}
+
+func TestActiveKeyExpires(t *testing.T) {
+	r, clock := registry(t)
+	clock.advance(time.Hour)
+	if _, err := r.Sign([]byte("late")); err == nil {
+		t.Fatal("signed with an expired key")
+	}
+	if _, err := r.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Sign([]byte("late")); err != nil {
+		t.Errorf("sign after rotating out the expired key: %v", err)
+	}
+}
+
+func TestRevokedKeysFail(t *testing.T) {
+	r, _ := registry(t)
+	data := []byte("revoke me")
+	sig, _ := r.Sign(data)
+	first, _ := r.Active()
+	r.Rotate()
+
+	if err := r.Revoke(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if r.Verify(data, sig) {
+		t.Error("revoked retiring key still verifies")
+	}
+
+	active, _ := r.Active()
+	if err := r.Revoke(active.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Sign(data); err == nil {
+		t.Error("signed after the active key was revoked")
+	}
+	if err := r.Revoke("missing"); err == nil {
+		t.Error("revoking an unknown key succeeded")
+	}
+}
+
+func TestKyberRegistryDecapsulates(t *testing.T) {
+	r, err := NewKeyRegistry("kyber", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := r.Active()
+	secret, ciphertext, err := Encapsulate(key.KeyPair.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Decapsulate(key.ID, ciphertext)
+	if err != nil || string(got) != string(secret) {
+		t.Fatalf("Decapsulate = %x, %v; want %x", got, err, secret)
+	}
+	r.Revoke(key.ID)
+	if _, err := r.Decapsulate(key.ID, ciphertext); err == nil {
+		t.Error("revoked key decapsulated")
+	}
+}
@@ -0,0 +1,95 @@
+package pqc
+
+import (
+	"crypto/mldsa"
+	"fmt"
+	"sync"
+)
+
+// supportedLevel is the NIST security level of the ML-KEM-768 and
+// ML-DSA-65 parameter sets this package implements
+const supportedLevel = 3
+
+// scheme is the initialized parameter set of one algorithm variant, built
+// once and shared by every key generation, signature and verification
+type scheme struct {
+	kyber     KyberParams
+	dilithium DilithiumParams
+	mldsa     mldsa.Parameters
+}
+
+// schemeVariant lazily initializes a scheme exactly once
+type schemeVariant struct {
+	once  sync.Once
+	build func() *scheme
+	s     *scheme
+}
+
+func (v *schemeVariant) get() *scheme {
+	v.once.Do(func() { v.s = v.build() })
+	return v.s
+}
+
+// schemes holds the supported variants by algorithm and NIST level
+var schemes = map[string]map[int]*schemeVariant{
+	"kyber": {
+		3: {build: func() *scheme {
+			return &scheme{kyber: KyberParams{
+				N:         256,
+				Q:         3329,
+				K:         3,
+				Eta1:      2,
+				Eta2:      2,
+				Du:        10,
+				Dv:        4,
+				PolyBytes: 384,
+				SeedBytes: 32,
+			}}
+		}},
+	},
+	"dilithium": {
+		3: {build: func() *scheme {
+			return &scheme{
+				dilithium: DilithiumParams{
+					N:         256,
+					Q:         8380417,
+					K:         6,
+					L:         5,
+					Eta:       4,
+					Gamma1:    524288,
+					Gamma2:    261888,
+					Omega:     55,
+					PolyBytes: 640,
+					SeedBytes: 32,
+				},
+				mldsa: mldsa.MLDSA65(),
+			}
+		}},
+	},
+}
+
+// schemeFor returns the cached scheme for an algorithm at a NIST level; 0
+// selects the default level
+func schemeFor(algorithm string, level int) (*scheme, error) {
+	variants, ok := schemes[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported PQC algorithm: %s", algorithm)
+	}
+	if level == 0 {
+		level = supportedLevel
+	}
+	v, ok := variants[level]
+	if !ok {
+		return nil, fmt.Errorf("unsupported security level %d for %s (supported: %d)", level, algorithm, supportedLevel)
+	}
+	return v.get(), nil
+}
+
+// mustScheme returns a variant that is known to be registered
+func mustScheme(algorithm string) *scheme {
+	s, err := schemeFor(algorithm, supportedLevel)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
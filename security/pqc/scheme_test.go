@@ -0,0 +1,88 @@
+package pqc
+
+import (
+	"bytes"
+	"crypto/mldsa"
+	"testing"
+)
+
+// TestCachedSchemeMatchesFreshBuild checks that keys derived through the
+// cached scheme interoperate with keys derived from a freshly built one
+func TestCachedSchemeMatchesFreshBuild(t *testing.T) {
+	fresh := schemes["dilithium"][supportedLevel].build()
+	cached, err := schemeFor("dilithium", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached != mustScheme("dilithium") {
+		t.Error("schemeFor rebuilt a cached variant")
+	}
+	if fresh.dilithium != cached.dilithium {
+		t.Errorf("fresh params %+v, cached %+v", fresh.dilithium, cached.dilithium)
+	}
+
+	seed := bytes.Repeat([]byte{7}, 32)
+	uncachedKey, err := mldsa.NewPrivateKey(fresh.mldsa, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cachedPair, err := NewDilithiumKeyPairFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(uncachedKey.PublicKey().Bytes(), cachedPair.PublicKey) {
+		t.Fatal("cached and uncached schemes derive different keys from one seed")
+	}
+
+	data := []byte("interop")
+	sig, err := uncachedKey.Sign(nil, data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cachedPair.Verify(data, sig) {
+		t.Error("cached path rejects a signature from the uncached key")
+	}
+
+	kyberFresh := schemes["kyber"][supportedLevel].build()
+	kyberPair, err := NewKyberKeyPairFromSeed(bytes.Repeat([]byte{9}, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kyberPair.Params != kyberFresh.kyber {
+		t.Errorf("kyber params %+v, fresh %+v", kyberPair.Params, kyberFresh.kyber)
+	}
+}
+
+func TestSchemeForRejectsUnknownVariants(t *testing.T) {
+	if _, err := schemeFor("rsa", 0); err == nil {
+		t.Error("unknown algorithm accepted")
+	}
+	if _, err := schemeFor("kyber", 5); err == nil {
+		t.Error("unimplemented level accepted")
+	}
+}
+
+func BenchmarkSchemeSetup(b *testing.B) {
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		v := schemes["dilithium"][supportedLevel]
+		for range b.N {
+			_ = v.build()
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			_ = mustScheme("dilithium")
+		}
+	})
+}
+
+func BenchmarkDilithiumKeygen(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := NewDilithiumKeyPair(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
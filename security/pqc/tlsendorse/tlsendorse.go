@@ -0,0 +1,103 @@
+// Package tlsendorse makes and checks post-quantum endorsements of TLS
+// certificates. An endorsement is an ML-DSA-65 signature over a leaf
+// certificate by the operator's Dilithium key, so a client that pins that
+// key can trust the certificate even once the classical signatures on its
+// chain can be forged. Servers present it with pkg/tlsserve.
+package tlsendorse
+
+import (
+	"crypto/mldsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/corridoros/security/pqc"
+)
+
+// Context separates endorsement signatures from anything else the same key
+// signs, such as httpsign response bodies
+const Context = "corridoros-tls-endorsement-v1"
+
+// Endorsement is the signature over a certificate, as tlsserve loads it
+type Endorsement struct {
+	Algorithm  string `json:"algorithm"`
+	KeyID      string `json:"key_id"`
+	CertSHA256 string `json:"cert_sha256"` // hex, of the certificate's DER
+	Signature  string `json:"signature"`   // base64
+}
+
+// Endorse signs the DER certificate cert with keys
+func Endorse(cert []byte, keys *pqc.DilithiumKeyPair) (Endorsement, error) {
+	sk, err := mldsa.NewPrivateKey(mldsa.MLDSA65(), keys.PrivateKey)
+	if err != nil {
+		return Endorsement{}, fmt.Errorf("invalid dilithium private key: %v", err)
+	}
+	signature, err := sk.Sign(pqc.EntropySource(), cert, &mldsa.Options{Context: Context})
+	if err != nil {
+		return Endorsement{}, err
+	}
+	sum := sha256.Sum256(cert)
+	return Endorsement{
+		Algorithm:  "ML-DSA-65",
+		KeyID:      pqc.GenerateKeyID(keys.PublicKey),
+		CertSHA256: hex.EncodeToString(sum[:]),
+		Signature:  base64.StdEncoding.EncodeToString(signature),
+	}, nil
+}
+
+// Verify checks a base64 endorsement signature over the DER certificate
+// cert against publicKey
+func Verify(cert []byte, signature string, publicKey []byte) bool {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	pk, err := mldsa.NewPublicKey(mldsa.MLDSA65(), publicKey)
+	if err != nil {
+		return false
+	}
+	return mldsa.Verify(pk, cert, raw, &mldsa.Options{Context: Context}) == nil
+}
+
+// LeafCertificate returns the DER of the first certificate in a PEM file,
+// the leaf of the chain a server presents
+func LeafCertificate(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return nil, fmt.Errorf("%s has no PEM certificate", path)
+		}
+		if block.Type == "CERTIFICATE" {
+			return block.Bytes, nil
+		}
+	}
+}
+
+// WriteKey stores the seed of an endorsement key, hex encoded and readable
+// only by its owner
+func WriteKey(path string, keys *pqc.DilithiumKeyPair) error {
+	return os.WriteFile(path, []byte(hex.EncodeToString(keys.PrivateKey)+"\n"), 0600)
+}
+
+// LoadKey reads an endorsement key WriteKey stored
+func LoadKey(path string) (*pqc.DilithiumKeyPair, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(seed) != mldsa.PrivateKeySize {
+		return nil, errors.New("endorsement key must be a hex-encoded 32-byte seed")
+	}
+	return pqc.NewDilithiumKeyPairFromSeed(seed)
+}
@@ -0,0 +1,78 @@
+package tlsendorse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/corridoros/security/pqc"
+)
+
+func TestEndorsementVerifiesOnlyItsCertificateAndKey(t *testing.T) {
+	keys, err := pqc.NewDilithiumKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stranger, err := pqc.NewDilithiumKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := []byte("leaf certificate DER")
+	e, err := Endorse(cert, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256(cert)
+	if e.Algorithm != "ML-DSA-65" || e.CertSHA256 != hex.EncodeToString(sum[:]) || e.KeyID != pqc.GenerateKeyID(keys.PublicKey) {
+		t.Errorf("endorsement = %+v", e)
+	}
+	if !Verify(cert, e.Signature, keys.PublicKey) {
+		t.Error("endorsement does not verify")
+	}
+	if Verify([]byte("another certificate"), e.Signature, keys.PublicKey) {
+		t.Error("endorsement verifies for another certificate")
+	}
+	if Verify(cert, e.Signature, stranger.PublicKey) {
+		t.Error("endorsement verifies under another key")
+	}
+	if Verify(cert, "not base64!", keys.PublicKey) {
+		t.Error("malformed signature verifies")
+	}
+}
+
+func TestKeyAndCertificateFiles(t *testing.T) {
+	dir := t.TempDir()
+	keys, err := pqc.NewDilithiumKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "endorsement.key")
+	if err := WriteKey(keyFile, keys); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadKey(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(loaded.PublicKey) != string(keys.PublicKey) {
+		t.Error("loaded key differs from the one written")
+	}
+
+	chain := append(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("key")}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("leaf")})...)
+	chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("intermediate")})...)
+	certFile := filepath.Join(dir, "chain.pem")
+	if err := os.WriteFile(certFile, chain, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if leaf, err := LeafCertificate(certFile); err != nil || string(leaf) != "leaf" {
+		t.Errorf("leaf = %q, %v", leaf, err)
+	}
+	if _, err := LeafCertificate(keyFile); err == nil {
+		t.Error("a file without a certificate has a leaf")
+	}
+}